@@ -19,16 +19,28 @@ package main
 import (
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdengine"
 	"github.com/JetBrains/qodana-cli/v2024/platform/thirdpartyscan"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	log "github.com/sirupsen/logrus"
+	"io/fs"
+	"path/filepath"
 	"strings"
 )
 
+// skippedDirs are the directories not searched for solutions and projects.
+var skippedDirs = []string{"bin", "obj", "node_modules", "packages"}
+
 func (l CdnetLinter) computeCdnetArgs(c thirdpartyscan.Context) ([]string, error) {
-	target := getSolutionOrProject(c)
-	if target == "" {
+	targets := getSolutionsOrProjects(c)
+	if len(targets) == 0 {
 		return nil, fmt.Errorf("solution/project relative file path is not specified. Use --solution or --project flags or create qodana.yaml file with respective fields")
 	}
+	return computeInspectCodeArgs(c, targets[0], platform.GetSarifPath(c.ResultsDir()), c.LogDir()), nil
+}
+
+// computeInspectCodeArgs returns the inspectcode command analyzing the solution or project target.
+func computeInspectCodeArgs(c thirdpartyscan.Context, target string, sarifPath string, logDir string) []string {
 	var props = ""
 	for _, p := range c.Property() {
 		if strings.HasPrefix(p, "log.") ||
@@ -66,8 +78,6 @@ func (l CdnetLinter) computeCdnetArgs(c thirdpartyscan.Context) ([]string, error
 	}
 	mountInfo := c.MountInfo()
 
-	sarifPath := platform.GetSarifPath(c.ResultsDir())
-
 	args := []string{
 		"dotnet",
 		utils.QuoteForWindows(mountInfo.CustomTools[thirdpartyscan.Clt]),
@@ -75,7 +85,7 @@ func (l CdnetLinter) computeCdnetArgs(c thirdpartyscan.Context) ([]string, error
 		utils.QuoteForWindows(target),
 		"-o=\"" + sarifPath + "\"",
 		"-f=\"Qodana\"",
-		"--LogFolder=\"" + c.LogDir() + "\"",
+		"--LogFolder=\"" + logDir + "\"",
 	}
 	if props != "" {
 		args = append(args, "--properties:"+props)
@@ -83,25 +93,74 @@ func (l CdnetLinter) computeCdnetArgs(c thirdpartyscan.Context) ([]string, error
 	if c.NoStatistics() {
 		args = append(args, "--telemetry-optout")
 	}
-	if c.CdnetNoBuild() {
+	// Unity projects reference the assemblies of the editor and are compiled by it, not by MSBuild
+	if project, ok := qdengine.Detect(c.ProjectDir()); c.CdnetNoBuild() || (ok && project.Engine == qdengine.Unity) {
 		args = append(args, "--no-build")
 	}
-	return args, nil
+	return args
 }
 
-func getSolutionOrProject(c thirdpartyscan.Context) string {
-	var target = ""
-	paths := [4]string{
-		c.CdnetSolution(),
-		c.CdnetProject(),
-		c.QodanaYaml().DotNet.Solution,
-		c.QodanaYaml().DotNet.Project,
-	}
-	for _, path := range paths {
+// getSolutionsOrProjects returns the solutions or projects to analyze: the one set with --solution or --project,
+// else the ones of qodana.yaml, else the solutions found in the project (or its projects if it has no solutions).
+func getSolutionsOrProjects(c thirdpartyscan.Context) []string {
+	for _, path := range []string{c.CdnetSolution(), c.CdnetProject()} {
 		if path != "" {
-			target = path
-			break
+			return []string{path}
+		}
+	}
+	dotNet := c.QodanaYaml().DotNet
+	var targets []string
+	if dotNet.Solution != "" {
+		targets = append(targets, dotNet.Solution)
+	} else if dotNet.Project != "" {
+		targets = append(targets, dotNet.Project)
+	}
+	for _, path := range append(append([]string{}, dotNet.Solutions...), dotNet.Projects...) {
+		if path != "" && !utils.Contains(targets, path) {
+			targets = append(targets, path)
+		}
+	}
+	if project, ok := qdengine.Detect(c.ProjectDir()); ok && len(targets) == 0 && project.Solution != "" {
+		targets = append(targets, project.Solution)
+	}
+	if len(targets) == 0 {
+		targets = discoverSolutionsOrProjects(c.ProjectDir())
+		if len(targets) > 0 {
+			log.Debugf("Discovered solutions/projects: %s", strings.Join(targets, ", "))
 		}
 	}
-	return target
+	return targets
+}
+
+// discoverSolutionsOrProjects returns the solutions in projectDir, or the projects if there are no solutions.
+func discoverSolutionsOrProjects(projectDir string) []string {
+	var solutions, projects []string
+	_ = filepath.WalkDir(
+		projectDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d == nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != projectDir && (strings.HasPrefix(d.Name(), ".") || utils.Contains(skippedDirs, d.Name())) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			rel, err := filepath.Rel(projectDir, path)
+			if err != nil {
+				return nil
+			}
+			switch filepath.Ext(path) {
+			case ".sln":
+				solutions = append(solutions, rel)
+			case ".csproj", ".vbproj", ".fsproj":
+				projects = append(projects, rel)
+			}
+			return nil
+		},
+	)
+	if len(solutions) > 0 {
+		return solutions
+	}
+	return projects
 }
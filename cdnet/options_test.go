@@ -24,6 +24,8 @@ import (
 	"github.com/JetBrains/qodana-cli/v2024/platform/thirdpartyscan"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -427,3 +429,87 @@ func TestGetArgsThirdPartyLinters(t *testing.T) {
 		)
 	}
 }
+
+func TestGetSolutionsOrProjects(t *testing.T) {
+	projectDir := t.TempDir()
+	for _, file := range []string{
+		"Backend/Backend.sln",
+		"Frontend/Frontend.sln",
+		"Frontend/App/App.csproj",
+		"Frontend/App/bin/Copy.sln",
+		".git/Old.sln",
+	} {
+		path := filepath.Join(projectDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte{}, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unityDir := filepath.Join(t.TempDir(), "Game")
+	for _, file := range []string{
+		"Game.sln",
+		"Game.Editor.sln",
+		"Assets/Scripts/Player.cs",
+		"ProjectSettings/ProjectVersion.txt",
+		"Library/PackageCache/Cached.sln",
+	} {
+		path := filepath.Join(unityDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte{}, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		cb       thirdpartyscan.ContextBuilder
+		expected []string
+	}{
+		{
+			name: "flag overrides qodana.yaml",
+			cb: thirdpartyscan.ContextBuilder{
+				CdnetProject: "App.csproj",
+				QodanaYaml:   qdyaml.QodanaYaml{DotNet: qdyaml.DotNet{Solutions: []string{"a.sln", "b.sln"}}},
+			},
+			expected: []string{"App.csproj"},
+		},
+		{
+			name: "solutions of qodana.yaml",
+			cb: thirdpartyscan.ContextBuilder{
+				QodanaYaml: qdyaml.QodanaYaml{
+					DotNet: qdyaml.DotNet{Solution: "a.sln", Solutions: []string{"a.sln", "b.sln"}, Projects: []string{"c.csproj"}},
+				},
+			},
+			expected: []string{"a.sln", "b.sln", "c.csproj"},
+		},
+		{
+			name:     "discovered solutions",
+			cb:       thirdpartyscan.ContextBuilder{ProjectDir: projectDir},
+			expected: []string{filepath.Join("Backend", "Backend.sln"), filepath.Join("Frontend", "Frontend.sln")},
+		},
+		{
+			name:     "discovered projects",
+			cb:       thirdpartyscan.ContextBuilder{ProjectDir: filepath.Join(projectDir, "Frontend", "App")},
+			expected: []string{"App.csproj"},
+		},
+		{
+			name:     "solution generated by Unity",
+			cb:       thirdpartyscan.ContextBuilder{ProjectDir: unityDir},
+			expected: []string{"Game.sln"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				actual := getSolutionsOrProjects(tt.cb.Build())
+				if !reflect.DeepEqual(tt.expected, actual) {
+					t.Fatalf("expected %v got %v", tt.expected, actual)
+				}
+			},
+		)
+	}
+}
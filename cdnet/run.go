@@ -17,15 +17,19 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/platform"
 	"github.com/JetBrains/qodana-cli/v2024/platform/nuget"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdbootstrap"
 	"github.com/JetBrains/qodana-cli/v2024/platform/thirdpartyscan"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	log "github.com/sirupsen/logrus"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type CdnetLinter struct {
@@ -39,15 +43,21 @@ func (l CdnetLinter) ComputeNewLinterInfo(
 }
 
 func (l CdnetLinter) RunAnalysis(c thirdpartyscan.Context) error {
-	utils.Bootstrap(c.QodanaYaml().Bootstrap, c.ProjectDir())
+	if err := qdbootstrap.Bootstrap(context.Background(), c.QodanaYaml().Bootstrap, c.ProjectDir(), c.LogDir(), 0); err != nil {
+		return err
+	}
+	if targets := getSolutionsOrProjects(c); len(targets) > 1 {
+		prepareNuget()
+		if err := analyzeSolutionsOrProjects(c, targets); err != nil {
+			return err
+		}
+		return patchReport(c)
+	}
 	args, err := l.computeCdnetArgs(c)
 	if err != nil {
 		return err
 	}
-	if nuget.IsNugetConfigNeeded() {
-		nuget.PrepareNugetConfig(os.Getenv("HOME"))
-	}
-	nuget.UnsetNugetVariables()
+	prepareNuget()
 	ret, err := utils.RunCmd(
 		utils.QuoteForWindows(c.ProjectDir()),
 		args...,
@@ -62,6 +72,60 @@ func (l CdnetLinter) RunAnalysis(c thirdpartyscan.Context) error {
 	return err
 }
 
+func prepareNuget() {
+	if nuget.IsNugetConfigNeeded() {
+		nuget.PrepareNugetConfig(os.Getenv("HOME"))
+	}
+	nuget.UnsetNugetVariables()
+}
+
+// analyzeSolutionsOrProjects analyzes the targets one after another and merges their results into one report,
+// each result has the solution or project it was reported for in its "solution" property.
+func analyzeSolutionsOrProjects(c thirdpartyscan.Context, targets []string) error {
+	tmpResultsDir := platform.GetTmpResultsDir(c.ResultsDir())
+	if err := os.MkdirAll(tmpResultsDir, os.ModePerm); err != nil {
+		return err
+	}
+	reports := make([]*sarif.Report, 0, len(targets))
+	for i, target := range targets {
+		name := fmt.Sprintf("%02d-%s", i+1, strings.TrimSuffix(filepath.Base(target), filepath.Ext(target)))
+		sarifPath := filepath.Join(tmpResultsDir, name+".sarif.json")
+		log.Infof("Analyzing %s (%d/%d)", target, i+1, len(targets))
+		ret, err := utils.RunCmd(
+			utils.QuoteForWindows(c.ProjectDir()),
+			computeInspectCodeArgs(c, target, sarifPath, filepath.Join(c.LogDir(), name))...,
+		)
+		if err != nil {
+			return err
+		}
+		if ret != 0 {
+			return fmt.Errorf("analysis of %s exited with code: %d", target, ret)
+		}
+		report, err := platform.ReadReport(sarifPath)
+		if err != nil {
+			return fmt.Errorf("failed to read the report of %s: %w", target, err)
+		}
+		for _, run := range report.Runs {
+			for j := range run.Results {
+				result := &run.Results[j]
+				if result.Properties == nil {
+					result.Properties = &sarif.PropertyBag{}
+				}
+				if result.Properties.AdditionalProperties == nil {
+					result.Properties.AdditionalProperties = map[string]interface{}{}
+				}
+				result.Properties.AdditionalProperties["solution"] = filepath.ToSlash(target)
+			}
+		}
+		reports = append(reports, report)
+	}
+	finalReport, err := platform.CombineReports(reports)
+	if err != nil {
+		return err
+	}
+	return platform.WriteReport(platform.GetSarifPath(c.ResultsDir()), finalReport)
+}
+
 func (l CdnetLinter) MountTools(tempMountPath string, mountPath string, _ bool) (map[string]string, error) {
 	val := make(map[string]string)
 	val[thirdpartyscan.Clt] = filepath.Join(
@@ -9,6 +9,7 @@ require (
 	github.com/docker/docker v25.0.6+incompatible // indirect; DO NOT UPDATE: breaking changes
 	github.com/go-enry/go-enry/v2 v2.9.2 // indirect
 	github.com/google/uuid v1.6.0 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
 	github.com/liamg/clinch v1.6.6 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/otiai10/copy v1.14.1 // indirect
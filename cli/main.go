@@ -20,7 +20,10 @@ import (
 	"github.com/JetBrains/qodana-cli/v2024/cmd"
 	"github.com/JetBrains/qodana-cli/v2024/core"
 	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
 	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtemp"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	"github.com/JetBrains/qodana-cli/v2024/platform/version"
 	log "github.com/sirupsen/logrus"
 	"io"
@@ -35,12 +38,20 @@ func main() {
 	signal.Notify(commoncontext.InterruptChannel, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-commoncontext.InterruptChannel
-		msg.WarningMessage("Interrupting Qodana CLI...")
+		msg.WarningMessage("Interrupting Qodana CLI, press Ctrl+C again to exit immediately...")
+		go func() {
+			<-commoncontext.InterruptChannel
+			os.Exit(utils.QodanaCancelledExitCode)
+		}()
 		log.SetOutput(io.Discard)
 		core.CheckForUpdates(version.Version)
+		// stop the container before restoring the working tree it analyzes
 		core.ContainerCleanup()
+		exitCode := utils.RunInterruptCleanups()
+		qdtemp.Cleanup()
+		git.LOGGER.Sync()
 		_ = msg.QodanaSpinner.Stop()
-		os.Exit(0)
+		os.Exit(exitCode)
 	}()
 	cmd.InitCli()
 	cmd.Execute()
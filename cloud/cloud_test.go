@@ -17,12 +17,16 @@
 package cloud
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestGetProjectByBadToken(t *testing.T) {
@@ -101,3 +105,67 @@ func TestGetReportUrl(t *testing.T) {
 		})
 	}
 }
+
+func TestWaitForReportInfo(t *testing.T) {
+	requestServed := 0
+	svr := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				requestServed++
+				if r.URL.Path != "/analyses/42/report" {
+					t.Errorf("unexpected path '%s'", r.URL.Path)
+				}
+				switch requestServed {
+				case 1:
+					w.WriteHeader(http.StatusNotFound)
+				case 2:
+					_, _ = fmt.Fprint(w, `{"reportId":"r1","state":"PROCESSING"}`)
+				default:
+					_, _ = fmt.Fprint(w, `{"reportId":"r1","state":"READY","url":"https://qodana.cloud/reports/r1"}`)
+				}
+			},
+		),
+	)
+	defer svr.Close()
+
+	client := QdClient{httpClient: svr.Client(), apiUrl: svr.URL, token: "token"}
+	info, err := client.WaitForReportInfo("42", time.Millisecond, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.URL != "https://qodana.cloud/reports/r1" || requestServed != 3 {
+		t.Fatalf("unexpected report info %v after %d requests", info, requestServed)
+	}
+
+	requestServed = 1
+	_, err = client.WaitForReportInfo("42", time.Second, time.Millisecond)
+	if !errors.Is(err, ReportTimeoutError) {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+}
+
+func TestWaitForReportInfoCancelled(t *testing.T) {
+	svr := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprint(w, `{"reportId":"r1","state":"PROCESSING"}`)
+			},
+		),
+	)
+	defer svr.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := (&QdClient{httpClient: svr.Client(), apiUrl: svr.URL, token: "token"}).WithContext(ctx)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	_, err := client.WaitForReportInfo("42", time.Minute, time.Hour)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancellation error, got %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Fatalf("the polling didn't stop on cancellation")
+	}
+}
@@ -18,6 +18,9 @@ package cloud
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
@@ -25,6 +28,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"slices"
 	"time"
 )
@@ -50,6 +54,7 @@ type QdClient struct {
 	apiUrl     string
 	httpClient *http.Client
 	token      string
+	ctx        context.Context
 }
 
 var endpoint *QdRootEndpoint
@@ -95,25 +100,84 @@ func parseRawURL(rawUrl string) (host string, err error) {
 
 func (endpoints *QdApiEndpoints) NewCloudApiClient(token string) *QdClient {
 	return &QdClient{
-		httpClient: &http.Client{
-			Timeout: getRequestTimeout(),
-		},
-		apiUrl: endpoints.CloudApiUrl,
-		token:  token,
+		httpClient: newHttpClient(getRequestTimeout()),
+		apiUrl:     endpoints.CloudApiUrl,
+		token:      token,
 	}
 }
 
+// WithContext returns a copy of the client whose requests, retries and polling stop once ctx is done.
+func (client *QdClient) WithContext(ctx context.Context) *QdClient {
+	c := *client
+	c.ctx = ctx
+	return &c
+}
+
+// context returns the context of the requests, context.Background() for the clients created as literals.
+func (client *QdClient) context() context.Context {
+	if client.ctx == nil {
+		return context.Background()
+	}
+	return client.ctx
+}
+
+// sleep waits for d, it returns the context error if the context of the client is done first.
+func (client *QdClient) sleep(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-client.context().Done():
+		return client.context().Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// newHttpClient creates an HTTP client trusting the custom CA certificates from QODANA_CA_CERTIFICATE, if set.
+func newHttpClient(timeout time.Duration) *http.Client {
+	client := &http.Client{
+		Timeout: timeout,
+	}
+	caPath := os.Getenv(qdenv.QodanaCaCertificateEnv)
+	if caPath == "" {
+		return client
+	}
+	pool, err := loadCertPool(caPath)
+	if err != nil {
+		log.Fatalf("Failed to load CA certificates from %s: %v", caPath, err)
+	}
+	client.Transport = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}
+	return client
+}
+
+// loadCertPool returns the system certificate pool extended with the PEM certificates from the given file.
+func loadCertPool(caPath string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caPath)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no valid PEM certificates found")
+	}
+	return pool, nil
+}
+
 func getRequestTimeout() time.Duration {
 	return time.Duration(GetEnvWithDefaultInt(qdenv.QodanaCloudRequestTimeoutEnv, defaultRequestTimeout)) * time.Second
 }
 
 func (endpoints *QdApiEndpoints) NewLintersApiClient(token string) *QdClient {
 	return &QdClient{
-		httpClient: &http.Client{
-			Timeout: getRequestTimeout(),
-		},
-		apiUrl: endpoints.LintersApiUrl,
-		token:  token,
+		httpClient: newHttpClient(getRequestTimeout()),
+		apiUrl:     endpoints.LintersApiUrl,
+		token:      token,
 	}
 }
 
@@ -160,10 +224,15 @@ func (client *QdClient) doRequest(request *QdCloudRequest) ([]byte, error) {
 				return nil, err // return if accepted status code, like 401
 			}
 		}
+		if ctxErr := client.context().Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		log.Errorf("Attempt #%d of %d for request to '%s' failed. Error: %v", i, request.Retries, request.Path, err)
 		if i < request.Retries {
 			log.Printf("Next attempt in %d seconds", request.Cooldown)
-			time.Sleep(time.Duration(request.Cooldown) * time.Second)
+			if err := client.sleep(time.Duration(request.Cooldown) * time.Second); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -175,7 +244,7 @@ func (client *QdClient) doRequestAttempt(request *QdCloudRequest) ([]byte, error
 	var resp *http.Response
 	var responseErr error
 
-	req, err := http.NewRequest(request.Method, requestUrl, bytes.NewBuffer(request.Body))
+	req, err := http.NewRequestWithContext(client.context(), request.Method, requestUrl, bytes.NewBuffer(request.Body))
 	if err != nil {
 		return nil, err
 	}
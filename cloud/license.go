@@ -113,6 +113,7 @@ func (endpoints *QdApiEndpoints) RequestLicenseData(token string) ([]byte, error
 	for i := 1; i <= attempts; i++ {
 		license, err := requestLicenseDataAttempt(endpoints.LintersApiUrl, token)
 		if errors.Is(err, TokenDeclinedError) {
+			deleteCachedLicenseData(token)
 			return nil, err
 		}
 		if err != nil {
@@ -127,18 +128,29 @@ func (endpoints *QdApiEndpoints) RequestLicenseData(token string) ([]byte, error
 				time.Sleep(time.Duration(cooldown) * time.Second)
 			}
 		} else {
+			if LicenseCacheTtl > 0 {
+				if err = saveCachedLicenseData(token, license); err != nil {
+					log.Warnf("Failed to cache the license: %v", err)
+				}
+			}
 			return license, nil
 		}
 	}
+	if LicenseCacheTtl > 0 {
+		license, err := loadCachedLicenseData(token, LicenseCacheTtl)
+		if err == nil {
+			log.Warnf("Qodana Cloud licensing endpoint is unavailable, using the cached license")
+			return license, nil
+		}
+		log.Debugf("Cached license is not available: %v", err)
+	}
 	return nil, errors.New("failed to get proper response from Qodana Cloud server")
 }
 
 func requestLicenseDataAttempt(endpoint string, token string) ([]byte, error) {
 	timeout := getTimeout()
 
-	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
-	}
+	client := newHttpClient(time.Duration(timeout) * time.Second)
 
 	url := fmt.Sprintf("%s%s", endpoint, qodanaLicenseUri)
 	req, err := http.NewRequest("GET", url, nil)
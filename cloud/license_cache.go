@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LicenseCacheTtl is the time the validated license response is kept on disk to be used
+// when the licensing endpoint is unavailable. Zero disables the cache.
+var LicenseCacheTtl time.Duration
+
+type licenseCacheEntry struct {
+	SavedAt time.Time `json:"savedAt"`
+	Data    []byte    `json:"data"`
+}
+
+func licenseCachePath(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		userCacheDir = os.TempDir()
+	}
+	return filepath.Join(userCacheDir, "JetBrains", "Qodana", "license", hex.EncodeToString(sum[:])+".json")
+}
+
+// saveCachedLicenseData stores the license response obtained with the given token.
+func saveCachedLicenseData(token string, data []byte) error {
+	entry, err := json.Marshal(licenseCacheEntry{SavedAt: time.Now(), Data: data})
+	if err != nil {
+		return err
+	}
+	path := licenseCachePath(token)
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, entry, 0600)
+}
+
+// loadCachedLicenseData returns the license response cached for the given token if it is not older than ttl.
+func loadCachedLicenseData(token string, ttl time.Duration) ([]byte, error) {
+	content, err := os.ReadFile(licenseCachePath(token))
+	if err != nil {
+		return nil, err
+	}
+	var entry licenseCacheEntry
+	if err = json.Unmarshal(content, &entry); err != nil {
+		return nil, err
+	}
+	if time.Since(entry.SavedAt) > ttl {
+		return nil, fmt.Errorf("cached license obtained at %s has expired", entry.SavedAt.Format(time.RFC3339))
+	}
+	return entry.Data, nil
+}
+
+// deleteCachedLicenseData removes the license response cached for the given token.
+func deleteCachedLicenseData(token string) {
+	err := os.Remove(licenseCachePath(token))
+	if err != nil && !os.IsNotExist(err) {
+		log.Debugf("Failed to remove cached license: %v", err)
+	}
+}
@@ -277,3 +277,49 @@ func TestExtractLicenseKey(t *testing.T) {
 		)
 	}
 }
+
+func TestRequestLicenseDataCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(QodanaLicenseRequestAttemptsCountEnv, "1")
+	t.Setenv(QodanaLicenseRequestCooldownEnv, "0")
+	defer func() { LicenseCacheTtl = 0 }()
+
+	expectedLicense := `{"licensePlan":"ULTIMATE"}`
+	available := true
+	svr := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if !available {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				_, _ = fmt.Fprint(w, expectedLicense)
+			},
+		),
+	)
+	defer svr.Close()
+	apis := QdApiEndpoints{LintersApiUrl: svr.URL}
+
+	LicenseCacheTtl = time.Hour
+	if _, err := apis.RequestLicenseData("token"); err != nil {
+		t.Fatal(err)
+	}
+
+	available = false
+	res, err := apis.RequestLicenseData("token")
+	if err != nil {
+		t.Fatalf("expected the cached license to be used, got %v", err)
+	}
+	if string(res) != expectedLicense {
+		t.Errorf("expected cached response to be '%s' got '%s'", expectedLicense, string(res))
+	}
+	if _, err = apis.RequestLicenseData("other token"); err == nil {
+		t.Errorf("expected the license cache to be scoped to the token")
+	}
+
+	LicenseCacheTtl = time.Nanosecond
+	if _, err = apis.RequestLicenseData("token"); err == nil {
+		t.Errorf("expected the expired cached license to be ignored")
+	}
+}
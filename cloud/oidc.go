@@ -0,0 +1,127 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+const (
+	oidcAudience         = "qodana.cloud"
+	oidcTokenExchangeUri = "/oidc/token"
+)
+
+// NoOidcTokenError is returned when the CI environment does not provide an OIDC ID token.
+var NoOidcTokenError = fmt.Errorf(
+	"no OIDC ID token found: enable 'id-token: write' permission on GitHub Actions, or declare the %s ID token on GitLab CI",
+	qdenv.QodanaOidcTokenEnv,
+)
+
+type oidcExchangeRequest struct {
+	IdToken string `json:"idToken"`
+}
+
+type oidcExchangeResponse struct {
+	Token string `json:"token"`
+}
+
+// GetOidcIdToken returns the OIDC ID token issued by the current CI environment (GitHub Actions or GitLab CI).
+func GetOidcIdToken() (string, error) {
+	if token := os.Getenv(qdenv.QodanaOidcTokenEnv); token != "" {
+		return token, nil
+	}
+	requestUrl := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestUrl != "" && requestToken != "" {
+		return requestGitHubOidcIdToken(http.DefaultClient, requestUrl, requestToken)
+	}
+	return "", NoOidcTokenError
+}
+
+// requestGitHubOidcIdToken requests the ID token from the GitHub Actions OIDC provider.
+func requestGitHubOidcIdToken(client *http.Client, requestUrl string, requestToken string) (string, error) {
+	parsedUrl, err := url.Parse(requestUrl)
+	if err != nil {
+		return "", fmt.Errorf("invalid ACTIONS_ID_TOKEN_REQUEST_URL: %w", err)
+	}
+	query := parsedUrl.Query()
+	query.Set("audience", oidcAudience)
+	parsedUrl.RawQuery = query.Encode()
+
+	req, err := http.NewRequest("GET", parsedUrl.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("OIDC ID token request failed: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", &APIError{StatusCode: resp.StatusCode, Message: string(body)}
+	}
+	var answer struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(body, &answer); err != nil || answer.Value == "" {
+		return "", fmt.Errorf("unexpected OIDC ID token response '%s'", string(body))
+	}
+	return answer.Value, nil
+}
+
+// ExchangeOidcToken exchanges the CI OIDC ID token for a short-lived Qodana Cloud token.
+func (endpoints *QdApiEndpoints) ExchangeOidcToken(idToken string) (string, error) {
+	client := endpoints.NewCloudApiClient("")
+	return client.exchangeOidcToken(idToken)
+}
+
+func (client *QdClient) exchangeOidcToken(idToken string) (string, error) {
+	body, err := json.Marshal(oidcExchangeRequest{IdToken: idToken})
+	if err != nil {
+		return "", err
+	}
+	request := NewCloudRequest(oidcTokenExchangeUri)
+	request.Method = "POST"
+	request.Body = body
+	request.AcceptedStatuses = append(request.AcceptedStatuses, http.StatusForbidden)
+	result, err := client.doRequest(&request)
+	if err != nil {
+		return "", fmt.Errorf("OIDC token exchange failed: %w", err)
+	}
+	var answer oidcExchangeResponse
+	if err := json.Unmarshal(result, &answer); err != nil {
+		return "", fmt.Errorf("response '%s': %w", string(result), err)
+	}
+	if answer.Token == "" {
+		return "", errors.New("OIDC token exchange returned an empty token")
+	}
+	return answer.Token, nil
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOidcTokenExchange(t *testing.T) {
+	svr := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/github/token":
+					if r.Header.Get("Authorization") != "Bearer request-token" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					if r.URL.Query().Get("audience") != oidcAudience {
+						t.Errorf("unexpected audience '%s'", r.URL.Query().Get("audience"))
+					}
+					_, _ = fmt.Fprint(w, `{"value":"id-token"}`)
+				case oidcTokenExchangeUri:
+					var request oidcExchangeRequest
+					if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.IdToken != "id-token" {
+						w.WriteHeader(http.StatusForbidden)
+						return
+					}
+					_, _ = fmt.Fprint(w, `{"token":"short-lived-token"}`)
+				default:
+					t.Errorf("unexpected path '%s'", r.URL.Path)
+				}
+			},
+		),
+	)
+	defer svr.Close()
+
+	idToken, err := requestGitHubOidcIdToken(svr.Client(), svr.URL+"/github/token?api-version=2.0", "request-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idToken != "id-token" {
+		t.Fatalf("expected id-token, got %s", idToken)
+	}
+
+	client := QdClient{httpClient: svr.Client(), apiUrl: svr.URL}
+	token, err := client.exchangeOidcToken(idToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "short-lived-token" {
+		t.Fatalf("expected short-lived-token, got %s", token)
+	}
+
+	if _, err = client.exchangeOidcToken("forged-token"); err == nil {
+		t.Fatal("expected exchange of a forged token to fail")
+	}
+}
+
+func TestGetOidcIdTokenFromEnv(t *testing.T) {
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "")
+	t.Setenv("QODANA_ID_TOKEN", "")
+	if _, err := GetOidcIdToken(); err != NoOidcTokenError {
+		t.Fatalf("expected NoOidcTokenError, got %v", err)
+	}
+	t.Setenv("QODANA_ID_TOKEN", "gitlab-id-token")
+	token, err := GetOidcIdToken()
+	if err != nil || token != "gitlab-id-token" {
+		t.Fatalf("expected gitlab-id-token, got %s (%v)", token, err)
+	}
+}
@@ -18,13 +18,37 @@ package cloud
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	log "github.com/sirupsen/logrus"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 const openInIdeJson = "open-in-ide.json"
 
+const (
+	ReportStateProcessing = "PROCESSING"
+	ReportStateReady      = "READY"
+	ReportStateFailed     = "FAILED"
+)
+
+// ReportTimeoutError is returned when the report was not processed by Qodana Cloud in the given time.
+var ReportTimeoutError = errors.New("timed out waiting for the report to be processed by Qodana Cloud")
+
+// ReportInfo is the Qodana Cloud report state for the given analysis.
+type ReportInfo struct {
+	ReportId string `json:"reportId"`
+	State    string `json:"state"`
+	URL      string `json:"url"`
+}
+
+// IsProcessed returns true if Qodana Cloud has finished processing the report (successfully or not).
+func (r ReportInfo) IsProcessed() bool {
+	return r.State == ReportStateReady || r.State == ReportStateFailed
+}
+
 type cloudInfo struct {
 	URL string `json:"url"`
 }
@@ -58,3 +82,39 @@ func readOpenInIde(resultsDir, fileName string) (string, error) {
 	log.Debugf("Found report URL from (%s): %s", filePath, data.Cloud.URL)
 	return data.Cloud.URL, nil
 }
+
+// RequestReportInfo requests the report state and URL for the given analysis id.
+func (client *QdClient) RequestReportInfo(analysisId string) (ReportInfo, error) {
+	request := NewCloudRequest(fmt.Sprintf("/analyses/%s/report", analysisId))
+	result, err := client.doRequest(&request)
+	if err != nil {
+		return ReportInfo{}, err
+	}
+	var info ReportInfo
+	if err := json.Unmarshal(result, &info); err != nil {
+		return ReportInfo{}, fmt.Errorf("response '%s': %w", string(result), err)
+	}
+	return info, nil
+}
+
+// WaitForReportInfo polls Qodana Cloud until the report for the given analysis id is processed or timeout is reached.
+func (client *QdClient) WaitForReportInfo(analysisId string, interval time.Duration, timeout time.Duration) (ReportInfo, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		info, err := client.RequestReportInfo(analysisId)
+		var apiError *APIError
+		if err != nil && !(errors.As(err, &apiError) && apiError.StatusCode == 404) {
+			return info, err // 404 means the report is not uploaded yet
+		}
+		if err == nil && info.IsProcessed() {
+			return info, nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return info, ReportTimeoutError
+		}
+		log.Debugf("Report for analysis %s is not ready yet (state '%s'), next attempt in %s", analysisId, info.State, interval)
+		if err := client.sleep(interval); err != nil {
+			return info, err
+		}
+	}
+}
@@ -99,21 +99,26 @@ func selectSupportedVersion(apiDescriptions []ApiVersionDescription) string {
 }
 
 func (endpoint *QdRootEndpoint) requestApiEndpoints() (*QdApiEndpoints, error) {
-	httpClient := &http.Client{
-		Timeout: getRequestTimeout(),
-	}
+	return endpoint.requestApiEndpointsCustomClient(newHttpClient(getRequestTimeout()))
+}
 
-	return endpoint.requestApiEndpointsCustomClient(httpClient)
+// CheckHealth verifies with a single request that the endpoint is reachable and provides the supported API versions.
+func (endpoint *QdRootEndpoint) CheckHealth() (*QdApiEndpoints, error) {
+	request := NewCloudRequest(VersionsURI)
+	request.Retries = 1
+	return endpoint.requestApiEndpointsWithRequest(newHttpClient(getRequestTimeout()), request)
 }
 
 func (endpoint *QdRootEndpoint) requestApiEndpointsCustomClient(httpClient *http.Client) (*QdApiEndpoints, error) {
+	return endpoint.requestApiEndpointsWithRequest(httpClient, NewCloudRequest(VersionsURI))
+}
+
+func (endpoint *QdRootEndpoint) requestApiEndpointsWithRequest(httpClient *http.Client, request QdCloudRequest) (*QdApiEndpoints, error) {
 	client := QdClient{
 		httpClient: httpClient,
 		apiUrl:     fmt.Sprintf("https://%s", endpoint.Host),
 	}
 
-	request := NewCloudRequest(VersionsURI)
-
 	response, err := client.doRequest(&request)
 	if err != nil {
 		return nil, fmt.Errorf("request of available API versions failed: %w", err)
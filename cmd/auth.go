@@ -0,0 +1,153 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/cloud"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/tokenloader"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+// authOptions represents auth commands options.
+type authOptions struct {
+	Linter     string
+	ProjectDir string
+	ConfigName string
+	WithToken  bool
+}
+
+func (o *authOptions) computeContext() commoncontext.Context {
+	return commoncontext.Compute(
+		o.Linter,
+		"",
+		"",
+		"",
+		"",
+		"",
+		"",
+		false,
+		o.ProjectDir,
+		o.ConfigName,
+	)
+}
+
+func (o *authOptions) addFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.StringVarP(&o.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&o.ProjectDir, "project-dir", "i", ".", "Root directory of the project the token is saved for")
+	flags.StringVar(
+		&o.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+}
+
+// newAuthCommand returns a new instance of the auth command.
+func newAuthCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage Qodana Cloud credentials",
+		Long: fmt.Sprintf(
+			`Manage %s saved for the project. Tokens are stored in the OS keychain (Keychain on macOS, DPAPI on Windows, secret-service on Linux), or in an encrypted file if the keychain is not available.`,
+			msg.PrimaryBold(qdenv.QodanaToken),
+		),
+	}
+	cmd.AddCommand(newAuthLoginCommand(), newAuthLogoutCommand(), newAuthStatusCommand())
+	return cmd
+}
+
+// newAuthLoginCommand returns a new instance of the auth login command.
+func newAuthLoginCommand() *cobra.Command {
+	options := &authOptions{}
+	cmd := &cobra.Command{
+		Use:   "login",
+		Short: "Save Qodana Cloud token for the project",
+		Long:  `Validate the Qodana Cloud token and save it for the project. The token is asked interactively, or read from the standard input with --with-token.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			commonCtx := options.computeContext()
+			token := ""
+			if options.WithToken {
+				line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				if err != nil && line == "" {
+					log.Fatalf("Failed to read the token from stdin: %s", err)
+				}
+				token = strings.TrimSpace(line)
+			}
+			if err := tokenloader.LoginCloudToken(commonCtx, token); err != nil {
+				msg.ErrorMessage("%s", err)
+				os.Exit(1)
+			}
+			msg.SuccessMessage("Logged in to %s", cloud.GetCloudRootEndpoint().Host)
+		},
+	}
+	options.addFlags(cmd)
+	cmd.Flags().BoolVar(&options.WithToken, "with-token", false, "Read the token from the standard input")
+	return cmd
+}
+
+// newAuthLogoutCommand returns a new instance of the auth logout command.
+func newAuthLogoutCommand() *cobra.Command {
+	options := &authOptions{}
+	cmd := &cobra.Command{
+		Use:   "logout",
+		Short: "Remove saved Qodana Cloud token for the project",
+		Run: func(cmd *cobra.Command, args []string) {
+			commonCtx := options.computeContext()
+			if err := tokenloader.DeleteCloudToken(commonCtx.Id); err != nil {
+				log.Fatalf("Failed to remove the saved token: %s", err)
+			}
+			msg.SuccessMessage("Removed the saved token for %s", commonCtx.ProjectDir)
+		},
+	}
+	options.addFlags(cmd)
+	return cmd
+}
+
+// newAuthStatusCommand returns a new instance of the auth status command.
+func newAuthStatusCommand() *cobra.Command {
+	options := &authOptions{}
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show which Qodana Cloud token is used for the project",
+		Run: func(cmd *cobra.Command, args []string) {
+			commonCtx := options.computeContext()
+			token := os.Getenv(qdenv.QodanaToken)
+			source := fmt.Sprintf("%s environment variable", qdenv.QodanaToken)
+			if token == "" {
+				var err error
+				token, source, err = tokenloader.GetSavedCloudToken(commonCtx.Id)
+				if err != nil || token == "" {
+					msg.WarningMessage("Not logged in, run %s to save the token", msg.PrimaryBold("qodana auth login"))
+					os.Exit(1)
+				}
+			}
+			msg.SuccessMessage("Token is loaded from the %s", source)
+			tokenloader.ValidateTokenPrintProject(token)
+		},
+	}
+	options.addFlags(cmd)
+	return cmd
+}
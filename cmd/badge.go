@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdbadge"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// badgeOptions represents badge command options.
+type badgeOptions struct {
+	Linter     string
+	ProjectDir string
+	ResultsDir string
+	ConfigName string
+	Type       string
+	Push       string
+}
+
+// newBadgeCommand returns a new instance of the badge command.
+func newBadgeCommand() *cobra.Command {
+	options := &badgeOptions{}
+	cmd := &cobra.Command{
+		Use:   "badge",
+		Short: "Generate a status badge of the latest results",
+		Long: `Write an SVG badge with the number of new problems, the quality gate status or the grade of the latest results
+to qodana-badge.svg in the results directory, and publish it with --push to show the status of the scans in the README.
+
+The grade is A without problems more severe than info, then B, C, D and F for the low, moderate, high and critical ones.
+--push accepts gist://<id>[/<file>] (the token from QD_GITHUB_TOKEN or GITHUB_TOKEN) and s3://, gs:// or az:// locations.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			commonCtx := commoncontext.Compute(
+				options.Linter,
+				"",
+				"",
+				options.ResultsDir,
+				"",
+				os.Getenv(qdenv.QodanaToken),
+				os.Getenv(qdenv.QodanaLicenseOnlyToken),
+				false,
+				options.ProjectDir,
+				options.ConfigName,
+			)
+			problems, err := platform.CountNewProblems(commonCtx.ResultsDir)
+			if err != nil {
+				log.Fatalf("Failed to read the results of %s, run qodana scan first: %s", commonCtx.ResultsDir, err)
+			}
+			badge, err := qdbadge.New(options.Type, problems, qualityGate(commonCtx.ResultsDir))
+			if err != nil {
+				log.Fatal(err)
+			}
+			svg := badge.SVG()
+			path := filepath.Join(commonCtx.ResultsDir, qdbadge.FileName)
+			if err = os.WriteFile(path, svg, 0o644); err != nil {
+				log.Fatalf("Failed to write the badge: %s", err)
+			}
+			msg.SuccessMessage("Badge %q is written to %s", badge.Message, path)
+			if options.Push != "" {
+				url, err := qdbadge.Push(svg, options.Push)
+				if err != nil {
+					log.Fatalf("Failed to push the badge to %s: %s", options.Push, err)
+				}
+				msg.SuccessMessage("Badge is published to %s", url)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(
+		&options.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Override directory with Qodana inspection results (default <userCacheDir>/JetBrains/<linter>/results)",
+	)
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.StringVar(&options.Type, "type", qdbadge.TypeProblems, "Badge to generate: "+strings.Join(qdbadge.Types, ", "))
+	flags.StringVar(&options.Push, "push", "", "Publish the badge to gist://<id>[/<file>], s3://, gs:// or az://")
+	return cmd
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"os"
+	"path/filepath"
+)
+
+// baselineOptions represents baseline command options.
+type baselineOptions struct {
+	Linter     string
+	ProjectDir string
+	ResultsDir string
+	ConfigName string
+	Baseline   string
+}
+
+// newBaselineCommand returns a new instance of the baseline command.
+func newBaselineCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "baseline",
+		Short: "Manage the baseline of the project",
+		Long: `Manage the baseline: the SARIF report with the known problems, passed to qodana scan with --baseline.
+Only the problems missing from the baseline are new and counted against the fail thresholds.`,
+	}
+	cmd.AddCommand(newBaselineCreateCommand(), newBaselineAbsorbCommand(), newBaselineTrimCommand())
+	return cmd
+}
+
+// newBaselineCreateCommand returns a new instance of the baseline create command.
+func newBaselineCreateCommand() *cobra.Command {
+	options := &baselineOptions{}
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create the baseline from the latest report",
+		Run: func(cmd *cobra.Command, args []string) {
+			baseline := options.baselinePath()
+			count, err := platform.CreateBaseline(options.reportPath(), baseline)
+			if err != nil {
+				log.Fatalf("Failed to create the baseline: %s", err)
+			}
+			msg.SuccessMessage("Created the baseline %s with %d problems", baseline, count)
+			msg.SuccessMessage("Run %s to analyse only the new problems", msg.PrimaryBold("qodana scan --baseline "+baseline))
+		},
+	}
+	addBaselineFlags(cmd.Flags(), options)
+	return cmd
+}
+
+// newBaselineAbsorbCommand returns a new instance of the baseline absorb command.
+func newBaselineAbsorbCommand() *cobra.Command {
+	options := &baselineOptions{}
+	cmd := &cobra.Command{
+		Use:   "absorb",
+		Short: "Add the new problems of the latest report to the baseline",
+		Run: func(cmd *cobra.Command, args []string) {
+			baseline := options.baselinePath()
+			if _, err := os.Stat(baseline); err != nil {
+				log.Fatalf("The baseline %s is not found, create it with %s", baseline, msg.PrimaryBold("qodana baseline create"))
+			}
+			count, err := platform.AbsorbBaseline(options.reportPath(), baseline)
+			if err != nil {
+				log.Fatalf("Failed to update the baseline: %s", err)
+			}
+			msg.SuccessMessage("Added %d problems to the baseline %s", count, baseline)
+		},
+	}
+	addBaselineFlags(cmd.Flags(), options)
+	return cmd
+}
+
+// newBaselineTrimCommand returns a new instance of the baseline trim command.
+func newBaselineTrimCommand() *cobra.Command {
+	options := &baselineOptions{}
+	cmd := &cobra.Command{
+		Use:   "trim",
+		Short: "Remove the problems in the deleted files from the baseline",
+		Run: func(cmd *cobra.Command, args []string) {
+			baseline := options.baselinePath()
+			count, err := platform.TrimBaseline(baseline, options.ProjectDir)
+			if err != nil {
+				log.Fatalf("Failed to trim the baseline: %s", err)
+			}
+			msg.SuccessMessage("Removed %d problems from the baseline %s", count, baseline)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(&options.Baseline, "baseline", "b", "", "Path to the baseline (default <project-dir>/"+platform.DefaultBaselineName+")")
+	return cmd
+}
+
+func addBaselineFlags(flags *pflag.FlagSet, options *baselineOptions) {
+	flags.StringVarP(&options.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(
+		&options.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Override directory with the Qodana inspection results (default <userCacheDir>/JetBrains/<linter>/results)",
+	)
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.StringVarP(&options.Baseline, "baseline", "b", "", "Path to the baseline (default <project-dir>/"+platform.DefaultBaselineName+")")
+}
+
+// reportPath returns the SARIF report of the latest analysis of the project.
+func (o *baselineOptions) reportPath() string {
+	resultsDir := o.ResultsDir
+	if resultsDir == "" {
+		resultsDir = commoncontext.Compute(
+			o.Linter,
+			"",
+			"",
+			"",
+			"",
+			os.Getenv(qdenv.QodanaToken),
+			os.Getenv(qdenv.QodanaLicenseOnlyToken),
+			false,
+			o.ProjectDir,
+			o.ConfigName,
+		).ResultsDir
+	}
+	return platform.GetSarifPath(resultsDir)
+}
+
+func (o *baselineOptions) baselinePath() string {
+	if o.Baseline != "" {
+		return o.Baseline
+	}
+	return filepath.Join(o.ProjectDir, platform.DefaultBaselineName)
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdbatch"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// batchOptions represents batch command options.
+type batchOptions struct {
+	Repos      string
+	OutputDir  string
+	KeepClones bool
+}
+
+// newBatchCommand returns a new instance of the batch command.
+func newBatchCommand() *cobra.Command {
+	options := &batchOptions{}
+	cmd := &cobra.Command{
+		Use:   "batch --repos <repos.yaml> [-- <scan options>]",
+		Short: "Scan a list of git repositories",
+		Long: `Clone each repository listed in --repos shallowly, scan it and write the problem counts of all the repositories
+to summary.csv and summary.json in --output-dir. The options after -- are passed to every qodana scan, e.g.
+
+  qodana batch --repos repos.yaml -- --linter jetbrains/qodana-jvm-community
+
+The repositories are listed with optional names, branches and scan options added after the common ones:
+
+  args: [--fail-threshold, "100"]
+  repos:
+    - url: https://github.com/org/service.git
+      branch: main
+    - url: git@github.com:org/web.git
+      args: [--linter, jetbrains/qodana-js]
+
+The results of each repository are kept in results/<name> of --output-dir. With QODANA_TOKEN set, the reports
+are uploaded to Qodana Cloud and linked in the summary.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			repos, err := qdbatch.LoadRepos(options.Repos)
+			if err != nil {
+				log.Fatalf("Failed to read the repositories to scan: %s", err)
+			}
+			if len(repos.Repos) == 0 {
+				log.Fatalf("No repositories to scan listed in %s", options.Repos)
+			}
+			executable, err := os.Executable()
+			if err != nil {
+				log.Fatalf("Failed to find the qodana executable: %s", err)
+			}
+			results := runBatch(cmd.Context(), executable, repos, options, args)
+			qdbatch.PrintSummary(results)
+			paths, err := qdbatch.WriteReports(options.OutputDir, results)
+			if err != nil {
+				log.Fatalf("Failed to write the summary of the scans: %s", err)
+			}
+			msg.SuccessMessage("Summary of the scans saved to %s", strings.Join(paths, ", "))
+			os.Exit(qdbatch.ExitCode(results))
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&options.Repos, "repos", "", "YAML file listing the git repositories to scan")
+	flags.StringVarP(&options.OutputDir, "output-dir", "o", "qodana-batch", "Directory to save the results of the scans and their summary to")
+	flags.BoolVar(&options.KeepClones, "keep-clones", false, "Keep the cloned repositories in clones/<name> of --output-dir after their scans")
+	_ = cmd.MarkFlagRequired("repos")
+	return cmd
+}
+
+// runBatch clones and scans the repositories one after another.
+func runBatch(ctx context.Context, executable string, repos qdbatch.Repos, options *batchOptions, args []string) []qdbatch.Result {
+	clonesDir := filepath.Join(options.OutputDir, "clones")
+	var results []qdbatch.Result
+	for i, repo := range repos.Repos {
+		project := qdbatch.Project{Name: repo.Name, Dir: filepath.Join(clonesDir, repo.Name), Url: repo.Url}
+		resultsDir := filepath.Join(options.OutputDir, "results", repo.Name)
+		msg.SuccessMessage("Cloning %s (%d/%d)", msg.PrimaryBold(repo.Url), i+1, len(repos.Repos))
+		if err := os.RemoveAll(project.Dir); err != nil {
+			log.Fatal(err)
+		}
+		if err := git.Clone(ctx, repo.Url, project.Dir, repo.Branch, options.OutputDir); err != nil {
+			msg.ErrorMessage("Failed to clone %s: %s", repo.Url, err)
+			results = append(
+				results,
+				qdbatch.Result{Project: project, ResultsDir: resultsDir, ExitCode: 1, Error: "clone failed", Problems: -1},
+			)
+			continue
+		}
+		scanArgs := append(append(append([]string{}, args...), repos.Args...), repo.Args...)
+		results = append(results, runProjectScan(ctx, executable, project, resultsDir, scanArgs))
+		if !options.KeepClones {
+			if err := os.RemoveAll(project.Dir); err != nil {
+				log.Warnf("Failed to remove the clone of %s: %s", repo.Url, err)
+			}
+		}
+	}
+	return results
+}
@@ -0,0 +1,238 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdbatch"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdreport"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	benchFileName     = "bench.json"
+	stabilityFileName = "stability.json"
+)
+
+// benchOptions represents bench command options.
+type benchOptions struct {
+	Linters    []string
+	ProjectDir string
+	OutputDir  string
+	Repeat     int
+}
+
+// newBenchCommand returns a new instance of the bench command.
+func newBenchCommand() *cobra.Command {
+	options := &benchOptions{}
+	cmd := &cobra.Command{
+		Use:   "bench (--linter <base> --linter <head> | --repeat <n>) [-- <scan options>]",
+		Short: "Compare the results and the timings of two linters",
+		Long: `Scan the same revision of the project with two linters one after another, e.g. the current release and an EAP
+version, and report the problems added and resolved by the second one and the difference of the stage timings.
+The options after -- are passed to both scans, e.g.
+
+  qodana bench --linter jetbrains/qodana-jvm:2024.2 --linter jetbrains/qodana-jvm:2024.3-eap -- --baseline qodana.sarif.json
+
+The results of the scans are kept in base and head of --output-dir, the comparison is written to bench.json.
+
+With --repeat, the same analysis is run the given number of times instead, and the problems not reported by every
+run are listed in stability.json with the number of times each run reported them, to file reports about
+nondeterministic inspections. The results of the runs are kept in run-1, run-2, ... of --output-dir.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if options.Repeat != 0 {
+				runRepeatedBench(options, args)
+				return
+			}
+			if len(options.Linters) != 2 {
+				log.Fatalf("Exactly two linters to compare are expected, got %d", len(options.Linters))
+			}
+			executable, err := os.Executable()
+			if err != nil {
+				log.Fatalf("Failed to find the qodana executable: %s", err)
+			}
+			bench := runBench(cmd.Context(), executable, options, args)
+			msg.SuccessMessage(
+				"%d problems added, %d resolved, %d changed severity",
+				len(bench.Comparison.Added),
+				len(bench.Comparison.Resolved),
+				len(bench.Comparison.SeverityChanges),
+			)
+			printBenchTimings(bench)
+			path := filepath.Join(options.OutputDir, benchFileName)
+			if err = qdreport.WriteBenchmark(bench, path); err != nil {
+				log.Fatalf("Failed to write the comparison to %s: %s", path, err)
+			}
+			msg.SuccessMessage("Comparison is written to %s", path)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringArrayVarP(&options.Linters, "linter", "l", nil, "Linter to compare, set twice: the base one first, then the head one")
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the project to scan")
+	flags.StringVarP(&options.OutputDir, "output-dir", "o", "qodana-bench", "Directory to save the results of the scans and the comparison to")
+	flags.IntVar(&options.Repeat, "repeat", 0, "Run the same analysis the given number of times and report the problems found by only some of the runs")
+	return cmd
+}
+
+// runBench scans the project with both linters and compares their results.
+func runBench(ctx context.Context, executable string, options *benchOptions, args []string) *qdreport.Benchmark {
+	bench := &qdreport.Benchmark{}
+	if revision, err := git.CurrentRevision(ctx, options.ProjectDir, options.OutputDir); err == nil {
+		bench.Revision = revision
+	} else {
+		log.Debugf("Failed to get the revision of %s: %s", options.ProjectDir, err)
+	}
+	project := qdbatch.Project{Name: filepath.Base(options.ProjectDir), Dir: options.ProjectDir}
+	var outcomes [2]platform.Outcome
+	for i, run := range []*qdreport.BenchmarkRun{&bench.Base, &bench.Head} {
+		run.Linter = options.Linters[i]
+		run.ResultsDir = filepath.Join(options.OutputDir, []string{"base", "head"}[i])
+		msg.SuccessMessage("Scanning with %s (%d/2)", msg.PrimaryBold(run.Linter), i+1)
+		result := runProjectScan(ctx, executable, project, run.ResultsDir, append([]string{"--linter", run.Linter}, args...))
+		run.ExitCode = result.ExitCode
+		run.DurationMs = result.Duration.Milliseconds()
+		if result.Problems < 0 {
+			log.Fatalf("The scan with %s failed (%s), see %s", run.Linter, result.Status(), run.ResultsDir)
+		}
+		if outcome, err := platform.ReadOutcome(run.ResultsDir); err == nil {
+			outcomes[i] = outcome
+		}
+	}
+	comparison, err := qdreport.CompareResults(bench.Base.ResultsDir, bench.Head.ResultsDir)
+	if err != nil {
+		log.Fatalf("Failed to compare the results: %s", err)
+	}
+	bench.Comparison = comparison
+	bench.Stages = qdreport.CompareStages(outcomes[0].Stages, outcomes[1].Stages)
+	return bench
+}
+
+// runRepeatedBench scans the project --repeat times with the same linter and reports the unstable problems.
+func runRepeatedBench(options *benchOptions, args []string) {
+	if options.Repeat < 2 {
+		log.Fatalf("At least two runs are expected, got --repeat %d", options.Repeat)
+	}
+	if len(options.Linters) > 1 {
+		log.Fatalf("A single linter is expected with --repeat, got %d", len(options.Linters))
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to find the qodana executable: %s", err)
+	}
+	var scanArgs []string
+	if len(options.Linters) == 1 {
+		scanArgs = append(scanArgs, "--linter", options.Linters[0])
+	}
+	project := qdbatch.Project{Name: filepath.Base(options.ProjectDir), Dir: options.ProjectDir}
+	var runs []string
+	for i := 1; i <= options.Repeat; i++ {
+		resultsDir := filepath.Join(options.OutputDir, fmt.Sprintf("run-%d", i))
+		msg.SuccessMessage("Running the analysis %d/%d", i, options.Repeat)
+		result := runProjectScan(ctx, executable, project, resultsDir, append(scanArgs, args...))
+		if result.Problems < 0 {
+			log.Fatalf("Run %d failed (%s), see %s", i, result.Status(), resultsDir)
+		}
+		if i == 1 && len(options.Linters) == 1 {
+			// the image is pulled once for all the runs
+			scanArgs = append(scanArgs, "--skip-pull")
+		}
+		runs = append(runs, resultsDir)
+	}
+	stability, err := qdreport.CompareRepeatedResults(runs)
+	if err != nil {
+		log.Fatalf("Failed to compare the results: %s", err)
+	}
+	msg.SuccessMessage(
+		"%d of %d problems are not reported by every one of %d runs",
+		len(stability.Unstable),
+		stability.Problems,
+		len(runs),
+	)
+	printUnstableRules(stability)
+	path := filepath.Join(options.OutputDir, stabilityFileName)
+	if err = qdreport.WriteStability(stability, path); err != nil {
+		log.Fatalf("Failed to write the unstable problems to %s: %s", path, err)
+	}
+	msg.SuccessMessage("Unstable problems are written to %s", path)
+}
+
+// printUnstableRules prints the rules with unstable problems.
+func printUnstableRules(stability *qdreport.Stability) {
+	if len(stability.Rules) == 0 {
+		return
+	}
+	tableData := pterm.TableData{
+		[]string{msg.PrimaryBold("Rule"), msg.PrimaryBold("Unstable problems")},
+	}
+	for _, rule := range stability.Rules {
+		tableData = append(tableData, []string{rule.RuleId, strconv.Itoa(rule.Unstable)})
+	}
+
+	msg.EmptyMessage()
+	table := pterm.DefaultTable.WithData(tableData)
+	table.HeaderRowSeparator = ""
+	table.Separator = " "
+	table.Boxed = true
+	if err := table.Render(); err != nil {
+		log.Debugf("Failed to print unstable rules: %v", err)
+	}
+}
+
+// printBenchTimings prints the timings of the stages of both scans and their difference.
+func printBenchTimings(bench *qdreport.Benchmark) {
+	tableData := pterm.TableData{
+		[]string{msg.PrimaryBold("Stage"), msg.PrimaryBold("Base"), msg.PrimaryBold("Head"), msg.PrimaryBold("Delta")},
+	}
+	for _, stage := range bench.Stages {
+		name := stage.Name
+		if stage.Parent != "" {
+			name = "  " + name
+		}
+		tableData = append(tableData, benchTimingRow(name, stage.BaseMs, stage.HeadMs))
+	}
+	tableData = append(tableData, benchTimingRow(msg.PrimaryBold("Total"), bench.Base.DurationMs, bench.Head.DurationMs))
+
+	msg.EmptyMessage()
+	table := pterm.DefaultTable.WithData(tableData)
+	table.HeaderRowSeparator = ""
+	table.Separator = " "
+	table.Boxed = true
+	if err := table.Render(); err != nil {
+		log.Debugf("Failed to print stage timings: %v", err)
+	}
+}
+
+// benchTimingRow formats the timings of a stage rounded to a tenth of a second.
+func benchTimingRow(name string, baseMs int64, headMs int64) []string {
+	base := time.Duration(baseMs) * time.Millisecond
+	head := time.Duration(headMs) * time.Millisecond
+	delta := (head - base).Round(100 * time.Millisecond).String()
+	if head >= base {
+		delta = "+" + delta
+	}
+	return []string{name, base.Round(100 * time.Millisecond).String(), head.Round(100 * time.Millisecond).String(), delta}
+}
@@ -0,0 +1,333 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcache"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// cacheServeOptions represents cache serve command options.
+type cacheServeOptions struct {
+	Dir   string
+	Host  string
+	Port  int
+	Token string
+}
+
+// cacheArchiveOptions represents cache export and cache import command options, Format is used by export only.
+type cacheArchiveOptions struct {
+	Linter     string
+	Ide        string
+	ProjectDir string
+	ConfigName string
+	CacheDir   string
+	Format     string
+}
+
+// newCacheCommand returns a new instance of the cache command.
+func newCacheCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Share the Qodana cache between CI agents",
+	}
+	cmd.AddCommand(newCacheServeCommand(), newCacheExportCommand(), newCacheImportCommand())
+	return cmd
+}
+
+// newCacheServeCommand returns a new instance of the cache serve command.
+func newCacheServeCommand() *cobra.Command {
+	options := &cacheServeOptions{}
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the Qodana cache over HTTP",
+		Long: fmt.Sprintf(
+			`Start an HTTP server keeping the Qodana caches (project indexes, Maven/Gradle caches and other contents of the cache directory) of CI agents.
+
+Run qodana scan with --cache-remote http://<host>:<port> on ephemeral runners: the cache is downloaded before the analysis
+if the local cache is empty, and uploaded after the analysis. If --token is set, the scans need to set %s to the same value.`,
+			qdenv.QodanaCacheTokenEnv,
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := os.MkdirAll(options.Dir, os.ModePerm); err != nil {
+				log.Fatalf("Failed to create the cache directory %s: %s", options.Dir, err)
+			}
+			if options.Token == "" {
+				options.Token = os.Getenv(qdenv.QodanaCacheTokenEnv)
+			}
+			if options.Token == "" {
+				msg.WarningMessage("The cache server accepts requests without a token, set --token to restrict the access")
+			}
+			address := fmt.Sprintf("%s:%d", options.Host, options.Port)
+			msg.SuccessMessage("Serving the Qodana cache from %s on %s", options.Dir, address)
+			if err := http.ListenAndServe(address, qdcache.Handler(options.Dir, options.Token)); err != nil {
+				log.Fatalf("Failed to serve the cache: %s", err)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&options.Dir, "dir", defaultCacheServerDir(), "Directory to keep the cache archives in")
+	flags.StringVar(&options.Host, "host", "", "Host to listen on (default all interfaces)")
+	flags.IntVar(&options.Port, "port", 8090, "Port to listen on")
+	flags.StringVar(
+		&options.Token,
+		"token",
+		"",
+		fmt.Sprintf("Token the clients must provide with %s (default $%s)", qdenv.QodanaCacheTokenEnv, qdenv.QodanaCacheTokenEnv),
+	)
+	return cmd
+}
+
+// newCacheExportCommand returns a new instance of the cache export command.
+func newCacheExportCommand() *cobra.Command {
+	options := &cacheArchiveOptions{}
+	cmd := &cobra.Command{
+		Use:   "export <archive>",
+		Short: "Export the Qodana cache to an archive",
+		Long: `Pack the Qodana cache directory of the project into a tar.zst or tar.gz archive, e.g. to seed the cache of
+air-gapped or ephemeral runners with qodana cache import.
+
+The format is chosen by the extension of the archive (.tar.zst, .tzst, .tar.gz or .tgz) or by --format. The archive
+contains the checksums of the files, so damaged or truncated archives are rejected on import. Use - to write
+the archive to the standard output.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			archive := args[0]
+			format := archiveFormat(archive, options.Format)
+			cacheDir := options.cacheDir()
+			if _, err := os.Stat(cacheDir); err != nil {
+				log.Fatalf("Failed to export the cache %s: %s", cacheDir, err)
+			}
+			if archive == "-" {
+				if err := qdcache.Pack(os.Stdout, cacheDir, qdcache.PackOptions{Format: format}); err != nil {
+					log.Fatalf("Failed to export the cache %s: %s", cacheDir, err)
+				}
+				return
+			}
+			// the archive is written next to the target and renamed, so a failed export leaves no broken archive
+			f, err := os.CreateTemp(filepath.Dir(archive), ".qodana-cache-export-*")
+			if err != nil {
+				log.Fatalf("Failed to create %s: %s", archive, err)
+			}
+			defer func() {
+				_ = f.Close()
+				_ = os.Remove(f.Name())
+			}()
+			var progress qdcache.Progress
+			msg.PrintProcess(
+				func(spinner *pterm.SpinnerPrinter) {
+					err = qdcache.Pack(
+						f, cacheDir, qdcache.PackOptions{
+							Format:   format,
+							Progress: archiveProgress(spinner, "Exporting the cache", &progress),
+						},
+					)
+				},
+				fmt.Sprintf("Exporting the cache %s", cacheDir),
+				"",
+			)
+			if err == nil {
+				err = f.Close()
+			}
+			if err == nil {
+				err = os.Rename(f.Name(), archive)
+			}
+			if err != nil {
+				log.Fatalf("Failed to export the cache %s: %s", cacheDir, err)
+			}
+			msg.SuccessMessage(
+				"Exported %d files (%s) from %s to %s",
+				progress.Files,
+				utils.FormatSize(progress.Bytes),
+				cacheDir,
+				archive,
+			)
+		},
+	}
+	options.addFlags(cmd)
+	cmd.Flags().StringVar(
+		&options.Format,
+		"format",
+		"",
+		"Archive format: zstd or gzip (default by the archive extension, zstd for -)",
+	)
+	return cmd
+}
+
+// newCacheImportCommand returns a new instance of the cache import command.
+func newCacheImportCommand() *cobra.Command {
+	options := &cacheArchiveOptions{}
+	cmd := &cobra.Command{
+		Use:   "import <archive>",
+		Short: "Import the Qodana cache from an archive",
+		Long: `Replace the Qodana cache directory of the project with the contents of a tar.zst or tar.gz archive created
+by qodana cache export.
+
+The format is detected from the contents of the archive. The files are verified against the checksums in the archive,
+and the cache directory is replaced only if the whole archive is extracted and verified. Use - to read the archive
+from the standard input.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			archive := args[0]
+			cacheDir := options.cacheDir()
+			var r io.Reader = os.Stdin
+			if archive != "-" {
+				f, err := os.Open(archive)
+				if err != nil {
+					log.Fatalf("Failed to open %s: %s", archive, err)
+				}
+				defer func(f *os.File) {
+					_ = f.Close()
+				}(f)
+				r = f
+			}
+			if err := os.MkdirAll(filepath.Dir(cacheDir), os.ModePerm); err != nil {
+				log.Fatalf("Failed to create the cache directory %s: %s", cacheDir, err)
+			}
+			// the archive is extracted next to the cache directory, so a damaged archive keeps the cache as is
+			staging, err := os.MkdirTemp(filepath.Dir(cacheDir), ".qodana-cache-import-*")
+			if err != nil {
+				log.Fatalf("Failed to create the cache directory %s: %s", cacheDir, err)
+			}
+			defer func() {
+				_ = os.RemoveAll(staging)
+			}()
+			var progress qdcache.Progress
+			verified := false
+			msg.PrintProcess(
+				func(spinner *pterm.SpinnerPrinter) {
+					verified, err = qdcache.Unpack(
+						r, staging, qdcache.UnpackOptions{
+							Progress: archiveProgress(spinner, "Importing the cache", &progress),
+						},
+					)
+				},
+				fmt.Sprintf("Importing the cache %s", cacheDir),
+				"",
+			)
+			if err != nil {
+				log.Fatalf("Failed to import the cache from %s: %s", archive, err)
+			}
+			if err = os.RemoveAll(cacheDir); err != nil {
+				log.Fatalf("Failed to replace the cache directory %s: %s", cacheDir, err)
+			}
+			if err = os.Rename(staging, cacheDir); err != nil {
+				log.Fatalf("Failed to replace the cache directory %s: %s", cacheDir, err)
+			}
+			if !verified {
+				msg.WarningMessage("The archive %s has no checksums, the imported files were not verified", archive)
+			}
+			msg.SuccessMessage(
+				"Imported %d files (%s) from %s to %s",
+				progress.Files,
+				utils.FormatSize(progress.Bytes),
+				archive,
+				cacheDir,
+			)
+		},
+	}
+	options.addFlags(cmd)
+	return cmd
+}
+
+func (o *cacheArchiveOptions) addFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.StringVarP(&o.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVar(
+		&o.Ide,
+		"ide",
+		os.Getenv(qdenv.QodanaDistEnv),
+		"Use the cache of Qodana running without a container. Not compatible with --linter option",
+	)
+	flags.StringVarP(&o.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVar(
+		&o.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.StringVar(&o.CacheDir, "cache-dir", "", "Override cache directory")
+	cmd.MarkFlagsMutuallyExclusive("linter", "ide")
+}
+
+// cacheDir returns the cache directory of the project, as qodana scan computes it.
+func (o *cacheArchiveOptions) cacheDir() string {
+	if o.CacheDir != "" {
+		return o.CacheDir
+	}
+	commonCtx := commoncontext.Compute(
+		o.Linter,
+		o.Ide,
+		o.CacheDir,
+		"",
+		"",
+		os.Getenv(qdenv.QodanaToken),
+		os.Getenv(qdenv.QodanaLicenseOnlyToken),
+		false,
+		o.ProjectDir,
+		o.ConfigName,
+	)
+	return commonCtx.CacheDir
+}
+
+// archiveFormat returns the format set by --format or by the extension of the archive.
+func archiveFormat(archive string, name string) qdcache.Format {
+	if name != "" {
+		format, err := qdcache.ParseFormat(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return format
+	}
+	if archive == "-" {
+		return qdcache.Zstd
+	}
+	format, ok := qdcache.FormatOf(archive)
+	if !ok {
+		log.Fatalf("Unknown archive format of %s, use .tar.zst or .tar.gz extension or set --format", archive)
+	}
+	return format
+}
+
+// archiveProgress returns the progress callback updating the spinner and keeping the last progress.
+func archiveProgress(spinner *pterm.SpinnerPrinter, message string, last *qdcache.Progress) func(qdcache.Progress) {
+	return func(progress qdcache.Progress) {
+		*last = progress
+		if spinner != nil {
+			spinner.UpdateText(fmt.Sprintf("%s: %d files, %s...", message, progress.Files, utils.FormatSize(progress.Bytes)))
+		}
+	}
+}
+
+func defaultCacheServerDir() string {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		userCacheDir = os.TempDir()
+	}
+	return filepath.Join(userCacheDir, "JetBrains", "Qodana", "cache-server")
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdci"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ciGenerateOptions represents ci generate command options.
+type ciGenerateOptions struct {
+	ProjectDir string
+	ConfigName string
+	Linter     string
+	Branch     string
+	Output     string
+	NoPrMode   bool
+	NoCache    bool
+	Force      bool
+}
+
+// newCiCommand returns a new instance of the ci command.
+func newCiCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ci",
+		Short: "Set up Qodana in CI",
+	}
+	cmd.AddCommand(newCiGenerateCommand())
+	return cmd
+}
+
+// newCiGenerateCommand returns a new instance of the ci generate command.
+func newCiGenerateCommand() *cobra.Command {
+	options := &ciGenerateOptions{}
+	cmd := &cobra.Command{
+		Use:   "generate <provider>",
+		Short: "Generate the CI pipeline running Qodana",
+		Long: fmt.Sprintf(
+			`Generate the pipeline file running Qodana for one of the CI providers: %s.
+
+The linter is taken from qodana.yaml or detected like qodana init does. The pipeline analyses the pushes to the default
+branch and only the changes of pull requests, and keeps the Qodana cache between the runs.
+The pipeline is written to the conventional path of the provider in the project, use --output - to print it instead.`,
+			strings.Join(qdci.Providers(), ", "),
+		),
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: qdci.Providers(),
+		Run: func(cmd *cobra.Command, args []string) {
+			provider := args[0]
+			path, err := qdci.Path(provider)
+			if err != nil {
+				log.Fatal(err)
+			}
+			linter := options.Linter
+			if linter == "" {
+				linter = ciLinter(options.ProjectDir, options.ConfigName)
+			}
+			branch := options.Branch
+			if branch == "" {
+				branch = ciBranch(cmd.Context(), options.ProjectDir)
+			}
+			pipeline, err := qdci.Generate(
+				provider, qdci.Options{
+					Linter:  linter,
+					Version: product.ReleaseVersion,
+					Branch:  branch,
+					PrMode:  !options.NoPrMode,
+					Cache:   !options.NoCache,
+					Token:   !utils.Contains(product.AllSupportedFreeCodes, product.GuessProductCode("", linter)),
+				},
+			)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if options.Output == "-" {
+				fmt.Print(pipeline)
+				return
+			}
+			output := options.Output
+			if output == "" {
+				output = filepath.Join(options.ProjectDir, filepath.FromSlash(path))
+			}
+			if _, err = os.Stat(output); err == nil && !options.Force {
+				log.Fatalf("%s already exists, use --force to overwrite it", output)
+			}
+			if err = os.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+				log.Fatal(err)
+			}
+			if err = os.WriteFile(output, []byte(pipeline), 0o644); err != nil {
+				log.Fatalf("Failed to write the pipeline: %s", err)
+			}
+			msg.SuccessMessage("Generated %s running %s", output, msg.PrimaryBold(linter))
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the project to set up")
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.StringVarP(&options.Linter, "linter", "l", "", "Linter image to run (default the linter of qodana.yaml or the detected one)")
+	flags.StringVar(&options.Branch, "branch", "", "Default branch analysed on every push (default the default branch of origin or the current branch)")
+	flags.StringVarP(&options.Output, "output", "o", "", "File to write the pipeline to, - for the standard output (default the conventional path of the provider)")
+	flags.BoolVar(&options.NoPrMode, "no-pr-mode", false, "Analyse the whole project in pull requests instead of only the changes")
+	flags.BoolVar(&options.NoCache, "no-cache", false, "Don't keep the Qodana cache between the runs")
+	flags.BoolVarP(&options.Force, "force", "f", false, "Overwrite the existing pipeline file")
+	return cmd
+}
+
+// ciLinter returns the linter image of qodana.yaml, or of the analyzer detected in the project.
+// CI pipelines run the images, native analyzers are replaced with the image of the same product.
+func ciLinter(projectDir string, configName string) string {
+	if configName == "" {
+		configName = qdyaml.FindDefaultQodanaYaml(projectDir)
+	}
+	qodanaYaml := qdyaml.LoadQodanaYaml(projectDir, configName)
+	if qodanaYaml.Linter != "" {
+		return qodanaYaml.Linter
+	}
+	analyzer := qodanaYaml.Ide
+	if analyzer == "" {
+		analyzer = commoncontext.GetAnalyzer(projectDir, os.Getenv(qdenv.QodanaToken))
+	}
+	code := strings.TrimSuffix(analyzer, product.EapSuffix)
+	if _, ok := product.DockerImageMap[code]; !ok {
+		log.Fatalf("%s has no Docker image to run in CI, set one with --linter", analyzer)
+	}
+	return product.Image(code)
+}
+
+// ciBranch returns the default branch of origin, or the current branch.
+func ciBranch(ctx context.Context, projectDir string) string {
+	if branch, err := git.DefaultBranch(ctx, projectDir, "origin", ""); err == nil && branch != "" {
+		return branch
+	}
+	if branch, err := git.Branch(ctx, projectDir, ""); err == nil && branch != "" && branch != "HEAD" {
+		return branch
+	}
+	return "main"
+}
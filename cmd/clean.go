@@ -0,0 +1,62 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtemp"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// cleanOptions represents clean command options.
+type cleanOptions struct {
+	Temp bool
+}
+
+// newCleanCommand returns a new instance of the clean command.
+func newCleanCommand() *cobra.Command {
+	options := &cleanOptions{}
+	cmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove the files left by Qodana runs",
+		Long: fmt.Sprintf(
+			`Remove the files left by Qodana runs.
+
+With --temp, the temporary files of the runs that are no longer running are removed from the temp directory
+(--temp-dir, %s or <system temp>/qodana): every run removes its own on exit, but a killed run leaves them behind.`,
+			qdenv.QodanaTmpEnv,
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			removed, size, err := qdtemp.Clean()
+			if err != nil {
+				log.Fatalf("Failed to clean %s: %s", qdtemp.Root(), err)
+			}
+			if removed == 0 {
+				msg.SuccessMessage("No temporary files to remove in %s", qdtemp.Root())
+				return
+			}
+			msg.SuccessMessage("Removed the temporary files of %d runs (%s) from %s", removed, utils.FormatSize(size), qdtemp.Root())
+		},
+	}
+	cmd.Flags().BoolVar(&options.Temp, "temp", false, "Remove the temporary files of the runs that are no longer running")
+	_ = cmd.MarkFlagRequired("temp")
+	return cmd
+}
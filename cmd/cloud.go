@@ -0,0 +1,129 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/cloud"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/tokenloader"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+// reportUrlOptions represents report-url command options.
+type reportUrlOptions struct {
+	Linter       string
+	ProjectDir   string
+	ConfigName   string
+	AnalysisId   string
+	Wait         bool
+	Timeout      int
+	PollInterval int
+}
+
+// newCloudCommand returns a new instance of the cloud command.
+func newCloudCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cloud",
+		Short: "Interact with Qodana Cloud",
+		Long:  `Commands to query Qodana Cloud for the results of the analyses uploaded from this or other machines.`,
+	}
+	cmd.AddCommand(newReportUrlCommand())
+	return cmd
+}
+
+// newReportUrlCommand returns a new instance of the cloud report-url command.
+func newReportUrlCommand() *cobra.Command {
+	options := &reportUrlOptions{}
+	cmd := &cobra.Command{
+		Use:   "report-url",
+		Short: "Print Qodana Cloud report URL for the given analysis",
+		Long: fmt.Sprintf(
+			`Print the Qodana Cloud report URL for the analysis uploaded with the given --analysis-id.
+
+Use --wait to poll Qodana Cloud until the report processing is finished, so the printed URL can be embedded in PR comments or build descriptions.
+
+The token is taken from the %s environment variable or the system keyring.`,
+			msg.PrimaryBold(qdenv.QodanaToken),
+		),
+		Run: func(cmd *cobra.Command, args []string) {
+			commonCtx := commoncontext.Compute(
+				options.Linter,
+				"",
+				"",
+				"",
+				"",
+				os.Getenv(qdenv.QodanaToken),
+				os.Getenv(qdenv.QodanaLicenseOnlyToken),
+				false,
+				options.ProjectDir,
+				options.ConfigName,
+			)
+			token := tokenloader.LoadCloudToken(commonCtx, false, true, false)
+			if token == "" {
+				log.Fatalf("%s is required to query Qodana Cloud", qdenv.QodanaToken)
+			}
+			client := cloud.GetCloudApiEndpoints().NewCloudApiClient(token).WithContext(cmd.Context())
+
+			var info cloud.ReportInfo
+			var err error
+			if options.Wait {
+				info, err = client.WaitForReportInfo(
+					options.AnalysisId,
+					time.Duration(options.PollInterval)*time.Second,
+					time.Duration(options.Timeout)*time.Second,
+				)
+			} else {
+				info, err = client.RequestReportInfo(options.AnalysisId)
+			}
+			var apiError *cloud.APIError
+			if errors.As(err, &apiError) && apiError.StatusCode == 404 {
+				log.Fatalf("Report for analysis %s is not found on %s", options.AnalysisId, cloud.GetCloudRootEndpoint().Host)
+			}
+			if err != nil {
+				log.Fatalf("Failed to obtain the report URL: %s", err)
+			}
+			if info.State == cloud.ReportStateFailed {
+				log.Fatalf("Qodana Cloud failed to process the report for analysis %s", options.AnalysisId)
+			}
+			if _, err = fmt.Fprintln(cmd.OutOrStdout(), info.URL); err != nil {
+				log.Fatalf("Failed to write to stdout: %s", err)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.StringVarP(&options.AnalysisId, "analysis-id", "a", "", "Unique report identifier (GUID) used to upload the report to Qodana Cloud")
+	flags.BoolVarP(&options.Wait, "wait", "w", false, "Wait until Qodana Cloud finishes processing the report")
+	flags.IntVar(&options.Timeout, "timeout", 600, "Maximum time in seconds to wait for the report with --wait")
+	flags.IntVar(&options.PollInterval, "poll-interval", 10, "Interval in seconds between Qodana Cloud requests with --wait")
+	_ = cmd.MarkFlagRequired("analysis-id")
+	return cmd
+}
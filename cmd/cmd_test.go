@@ -20,6 +20,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/core"
@@ -111,6 +112,20 @@ func TestHelp(t *testing.T) {
 	}
 }
 
+func TestHelpTopic(t *testing.T) {
+	out := bytes.NewBufferString("")
+	command := newRootCommand()
+	command.SetOut(out)
+	command.SetArgs([]string{"help", "exit-codes"})
+	err := command.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "Exit codes\n") {
+		t.Fatalf("expected the exit-codes topic, got \"%s\"", out.String())
+	}
+}
+
 func TestDeprecatedScanFlags(t *testing.T) {
 	deprecations := []string{"fixes-strategy", "stub-profile"}
 
@@ -410,3 +425,72 @@ func TestScanWithIde(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestHookChanges(t *testing.T) {
+	repo, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logDir := t.TempDir()
+	gitIn := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repo
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	writeFile := func(name string, content string) {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitIn("init", "-q")
+	writeFile("a.py", "a = 1\n")
+	writeFile("b.py", "b = 1\n")
+	gitIn("add", "-A")
+	gitIn("commit", "-q", "-m", "init")
+	base := gitIn("rev-parse", "HEAD")
+
+	writeFile("a.py", "a = 2\n")
+	writeFile("b.py", "b = 2\n")
+	gitIn("add", "a.py")
+	options := &hookOptions{ProjectDir: repo, Type: "pre-commit", Remote: "origin"}
+	changes, err := hookChanges(context.Background(), options, strings.NewReader(""), logDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Files) != 1 || changes.Files[0].Path != filepath.Join(repo, "a.py") {
+		t.Errorf("expected only the staged a.py, got %v", changes.Files)
+	}
+
+	gitIn("commit", "-q", "-m", "change a")
+	head := gitIn("rev-parse", "HEAD")
+	options.Type = "pre-push"
+	stdin := fmt.Sprintf("refs/heads/main %s refs/heads/main %s\nrefs/heads/gone %s refs/heads/gone %s\n", head, base, zeroSha, base)
+	changes, err = hookChanges(context.Background(), options, strings.NewReader(stdin), logDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Files) != 1 || changes.Files[0].Path != filepath.Join(repo, "a.py") {
+		t.Errorf("expected only the pushed a.py, got %v", changes.Files)
+	}
+}
+
+func TestIsStdoutReserved(t *testing.T) {
+	for _, c := range []struct {
+		args     []string
+		expected bool
+	}{
+		{[]string{"qodana", "lsp"}, true},
+		{[]string{"qodana", "ci", "generate", "github", "-o", "-"}, true},
+		{[]string{"qodana", "ci", "generate", "--output=-", "gitlab"}, true},
+		{[]string{"qodana", "ci", "generate", "github", "-o", "ci.yml"}, false},
+		{[]string{"qodana", "scan", "-o", "-"}, false},
+	} {
+		if actual := isStdoutReserved(c.args); actual != c.expected {
+			t.Errorf("isStdoutReserved(%v) = %v, expected %v", c.args, actual, c.expected)
+		}
+	}
+}
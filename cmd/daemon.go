@@ -0,0 +1,103 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qddaemon"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdreport"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// daemonOptions represents daemon command options.
+type daemonOptions struct {
+	Dir      string
+	Port     int
+	Parallel int
+	Serve    qdreport.ServeOptions
+}
+
+// newDaemonCommand returns a new instance of the daemon command.
+func newDaemonCommand() *cobra.Command {
+	options := &daemonOptions{}
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Run scans requested over a local HTTP API",
+		Long: `Start a long-running process accepting scan requests over HTTP, so that IDE plugins and build systems can
+orchestrate scans without spawning the CLI.
+
+  POST /api/v1/scans                 start a scan: {"projectDir": "/abs/path", "args": ["--linter", "..."]}
+  GET  /api/v1/scans                 list the scans
+  GET  /api/v1/scans/{id}            the state (queued, running, succeeded, failed, cancelled) and the exit code
+  POST /api/v1/scans/{id}/cancel     cancel the scan
+  GET  /api/v1/scans/{id}/logs       the scan output, streamed until the scan finishes with ?follow=true
+
+Each project keeps its cache directory in --dir between the scans, and the linter image is pulled only by the first scan
+of a project. The daemon listens on 127.0.0.1 unless --serve-host is set.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if !cmd.Flags().Changed("serve-host") {
+				options.Serve.Host = "127.0.0.1"
+			}
+			serve := options.Serve.WithEnv()
+			if err := serve.Validate(); err != nil {
+				log.Fatal(err)
+			}
+			if err := os.MkdirAll(options.Dir, os.ModePerm); err != nil {
+				log.Fatalf("Failed to create the daemon directory %s: %s", options.Dir, err)
+			}
+			executable, err := os.Executable()
+			if err != nil {
+				log.Fatalf("Failed to find the qodana executable: %s", err)
+			}
+			manager := qddaemon.NewManager(
+				options.Dir, options.Parallel, func(args []string) *exec.Cmd {
+					return exec.Command(executable, args...)
+				},
+			)
+			listener, err := serve.Listen(options.Port)
+			if err != nil {
+				log.Fatalf("Failed to start the daemon: %s", err)
+			}
+			if serve.BasicAuth == "" && serve.Token == "" {
+				msg.WarningMessage("The daemon accepts requests without credentials, set --serve-token to restrict the access")
+			}
+			msg.SuccessMessage("Qodana daemon is listening on %s, press Ctrl+C to stop", serve.Url(options.Port))
+			if err = serve.Serve(listener, qddaemon.Handler(manager)); err != nil {
+				log.Fatalf("Failed to serve the daemon API: %s", err)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&options.Dir, "dir", defaultDaemonDir(), "Directory to keep the scan results, logs and project caches in")
+	flags.IntVarP(&options.Port, "port", "p", 8091, "Port to listen on")
+	flags.IntVar(&options.Parallel, "parallel", 1, "Number of scans to run at the same time")
+	platformcmd.AddServeFlags(flags, &options.Serve)
+	return cmd
+}
+
+func defaultDaemonDir() string {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		userCacheDir = os.TempDir()
+	}
+	return filepath.Join(userCacheDir, "JetBrains", "Qodana", "daemon")
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/core"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// doctorOptions represents doctor command options.
+type doctorOptions struct {
+	Linter     string
+	ProjectDir string
+	ConfigName string
+	Ide        string
+	CacheDir   string
+	ResultsDir string
+	Fix        bool
+}
+
+// newDoctorCommand returns a new instance of the doctor command.
+func newDoctorCommand() *cobra.Command {
+	options := &doctorOptions{}
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the environment for running Qodana",
+		Long: `Check the environment end to end before running Qodana analysis: the container engine or Java runtime,
+free disk space, real-time antivirus scanning (Windows) or Spotlight indexing (macOS) of the cache and results
+directories, git, network access to Qodana Cloud and the container registry, and the Qodana Cloud token.
+
+Every failed check is printed with a hint on how to fix it. With --fix, the checks that can be fixed automatically
+are fixed, e.g. the directories are excluded from the scanning (adding a Microsoft Defender exclusion requires
+administrator rights).`,
+		Run: func(cmd *cobra.Command, args []string) {
+			commonCtx := commoncontext.Compute(
+				options.Linter,
+				options.Ide,
+				options.CacheDir,
+				options.ResultsDir,
+				"",
+				os.Getenv(qdenv.QodanaToken),
+				os.Getenv(qdenv.QodanaLicenseOnlyToken),
+				false,
+				options.ProjectDir,
+				options.ConfigName,
+			)
+			if !core.RunDoctorChecks(core.DoctorChecks(commonCtx), options.Fix) {
+				os.Exit(1)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVar(
+		&options.Ide,
+		"ide",
+		os.Getenv(qdenv.QodanaDistEnv),
+		"Check the environment for running Qodana without a container. Not compatible with --linter option",
+	)
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.StringVar(&options.CacheDir, "cache-dir", "", "Override cache directory")
+	flags.StringVarP(&options.ResultsDir, "results-dir", "o", "", "Override directory to save Qodana inspection results to")
+	flags.BoolVar(&options.Fix, "fix", false, "Fix the failed checks where possible, e.g. exclude the cache and results directories from scanning")
+	cmd.MarkFlagsMutuallyExclusive("linter", "ide")
+	return cmd
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"crypto/ecdh"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcrypt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// encryptionDecryptOptions represents encryption decrypt command options.
+type encryptionDecryptOptions struct {
+	OutputDir    string
+	KeyFile      string
+	IdentityFile string
+}
+
+// newEncryptionCommand returns a new instance of the encryption command.
+func newEncryptionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "encryption",
+		Short: "Generate the keys for and decrypt the results encrypted by qodana scan",
+		Long: `Generate the keys for and decrypt the results encrypted by qodana scan with --encryption-key-file,
+the ` + qdcrypt.KeyEnv + ` environment variable or --encryption-recipient.
+
+The encrypted results are kept in ` + platform.EncryptedResultsName + ` of the results directory.`,
+	}
+	cmd.AddCommand(newEncryptionKeygenCommand(), newEncryptionDecryptCommand())
+	return cmd
+}
+
+// newEncryptionKeygenCommand returns a new instance of the encryption keygen command.
+func newEncryptionKeygenCommand() *cobra.Command {
+	recipient := false
+	cmd := &cobra.Command{
+		Use:   "keygen",
+		Short: "Generate a shared encryption key, or an identity and its recipient",
+		Long: `Generate a shared encryption key to pass to qodana scan with --encryption-key-file or ` + qdcrypt.KeyEnv + `.
+
+With --recipient, generate an identity, kept private to decrypt the results, and its recipient, passed to
+qodana scan with --encryption-recipient, so that the machine running the analysis can't decrypt the results.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if !recipient {
+				key, err := qdcrypt.GenerateKey()
+				if err != nil {
+					log.Fatalf("Failed to generate the key: %s", err)
+				}
+				fmt.Println(key)
+				return
+			}
+			identity, publicKey, err := qdcrypt.GenerateIdentity()
+			if err != nil {
+				log.Fatalf("Failed to generate the identity: %s", err)
+			}
+			fmt.Printf("identity: %s\nrecipient: %s\n", identity, publicKey)
+		},
+	}
+	cmd.Flags().BoolVar(&recipient, "recipient", false, "Generate an identity and its recipient instead of a shared key")
+	return cmd
+}
+
+// newEncryptionDecryptCommand returns a new instance of the encryption decrypt command.
+func newEncryptionDecryptCommand() *cobra.Command {
+	options := &encryptionDecryptOptions{}
+	cmd := &cobra.Command{
+		Use:   "decrypt <archive>",
+		Short: "Decrypt the results encrypted by qodana scan",
+		Long: `Decrypt the results encrypted by qodana scan to --output-dir with the shared key of --key-file
+or the ` + qdcrypt.KeyEnv + ` environment variable, or with the identity of --identity-file.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			key, identity, err := loadDecryptionKey(options)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err = platform.DecryptResults(args[0], key, identity, options.OutputDir); err != nil {
+				log.Fatalf("Failed to decrypt %s: %s", args[0], err)
+			}
+			msg.SuccessMessage("Results are decrypted to %s", options.OutputDir)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.OutputDir, "output-dir", "o", "qodana-results", "Directory to extract the results to")
+	flags.StringVar(&options.KeyFile, "key-file", "", "File with the shared encryption key")
+	flags.StringVar(&options.IdentityFile, "identity-file", "", "File with the identity the results are encrypted for")
+	cmd.MarkFlagsMutuallyExclusive("key-file", "identity-file")
+	return cmd
+}
+
+// loadDecryptionKey reads the shared key or the identity of the decrypt command options.
+func loadDecryptionKey(options *encryptionDecryptOptions) ([]byte, *ecdh.PrivateKey, error) {
+	if options.IdentityFile != "" {
+		data, err := os.ReadFile(options.IdentityFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read the identity: %w", err)
+		}
+		identity, err := qdcrypt.ParseIdentity(string(data))
+		return nil, identity, err
+	}
+	encoded := os.Getenv(qdcrypt.KeyEnv)
+	if options.KeyFile != "" {
+		data, err := os.ReadFile(options.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read the encryption key: %w", err)
+		}
+		encoded = string(data)
+	}
+	if encoded == "" {
+		return nil, nil, fmt.Errorf("either --key-file, --identity-file or %s is expected", qdcrypt.KeyEnv)
+	}
+	key, err := qdcrypt.ParseKey(encoded)
+	return key, nil, err
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdreport"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// explainMaxProblems is the number of the problems of the rule listed by the explain command.
+const explainMaxProblems = 10
+
+// explainOptions represents explain command options.
+type explainOptions struct {
+	SarifFile  string
+	Linter     string
+	ProjectDir string
+	ResultsDir string
+	ConfigName string
+}
+
+// newExplainCommand returns a new instance of the explain command.
+func newExplainCommand() *cobra.Command {
+	options := &explainOptions{}
+	cmd := &cobra.Command{
+		Use:   "explain <ruleId>",
+		Short: "Explain an inspection reported by Qodana",
+		Long: `Print the description, the examples and the remediation guidance of the inspection from the rules metadata
+of the latest SARIF report of the project, and the problems it reported.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			ruleId := args[0]
+			sarifPath := options.SarifFile
+			if sarifPath == "" {
+				commonCtx := commoncontext.Compute(
+					options.Linter,
+					"",
+					"",
+					options.ResultsDir,
+					"",
+					os.Getenv(qdenv.QodanaToken),
+					os.Getenv(qdenv.QodanaLicenseOnlyToken),
+					false,
+					options.ProjectDir,
+					options.ConfigName,
+				)
+				sarifPath = filepath.Join(commonCtx.ResultsDir, commoncontext.QodanaSarifName)
+			}
+			help, similar, err := qdreport.ExplainRule(sarifPath, ruleId)
+			if err != nil {
+				log.Fatalf("Failed to read the rules of %s, run qodana scan first: %s", sarifPath, err)
+			}
+			if help == nil {
+				msg.WarningMessage("%s is not described in %s", ruleId, sarifPath)
+				if len(similar) > 0 {
+					msg.WarningMessage("Similar rules: %s", strings.Join(similar, ", "))
+				}
+				os.Exit(1)
+			}
+			printRuleHelp(help)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.SarifFile, "sarif-file", "f", "", "SARIF file to read the rules from (default qodana.sarif.json of the results directory)")
+	flags.StringVarP(&options.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(
+		&options.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Override directory with Qodana inspection results (default <userCacheDir>/JetBrains/<linter>/results)",
+	)
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	return cmd
+}
+
+// printRuleHelp prints the documentation of the rule and the first problems it reported.
+func printRuleHelp(help *qdreport.RuleHelp) {
+	title := msg.PrimaryBold(help.Id)
+	if help.Name != "" && help.Name != help.Id {
+		title += " " + help.Name
+	}
+	fmt.Println(title)
+	var details []string
+	if help.Severity != "" {
+		details = append(details, "Severity: "+help.Severity)
+	}
+	if len(help.Categories) > 0 {
+		details = append(details, "Category: "+strings.Join(help.Categories, ", "))
+	}
+	if len(details) > 0 {
+		fmt.Println(strings.Join(details, "   "))
+	}
+	for _, text := range []string{help.Summary, help.Description} {
+		if text != "" {
+			msg.EmptyMessage()
+			fmt.Println(text)
+		}
+	}
+	if len(help.Problems) > 0 {
+		msg.EmptyMessage()
+		fmt.Printf("Reported %d times in the latest report:\n", len(help.Problems))
+		for i, p := range help.Problems {
+			if i == explainMaxProblems {
+				fmt.Printf("  ... and %d more\n", len(help.Problems)-explainMaxProblems)
+				break
+			}
+			fmt.Printf("  %s:%d: %s\n", p.File, p.Line, p.Message)
+		}
+	}
+	msg.EmptyMessage()
+	fmt.Printf("More at %s\n", help.HelpUri)
+}
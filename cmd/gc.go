@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdstore"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+// gcOptions represents gc command options.
+type gcOptions struct {
+	StoreDir string
+	DryRun   bool
+}
+
+// newGcCommand returns a new instance of the gc command.
+func newGcCommand() *cobra.Command {
+	options := &gcOptions{}
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove the deduplicated results files no results directory uses",
+		Long: `Remove the files of the content-addressed results store that no results directory links to anymore.
+
+qodana scan --dedup-results keeps the large files of the results, e.g. the report, in the store and links them from
+the results directories, so the runs on this machine share the identical files. Once the results directories are
+removed, run qodana gc to free the space taken by their files.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			stats, err := qdstore.GC(options.StoreDir, options.DryRun)
+			if err != nil {
+				log.Fatalf("Failed to collect the results store %s: %s", options.StoreDir, err)
+			}
+			if options.DryRun {
+				msg.SuccessMessage(
+					"%d unused files (%s) would be removed from %s, %d files are used by %d results directories",
+					stats.Removed,
+					utils.FormatSize(stats.Freed),
+					options.StoreDir,
+					stats.Kept,
+					stats.Dirs,
+				)
+				return
+			}
+			msg.SuccessMessage(
+				"Removed %d unused files (%s) from %s, %d files are used by %d results directories",
+				stats.Removed,
+				utils.FormatSize(stats.Freed),
+				options.StoreDir,
+				stats.Kept,
+				stats.Dirs,
+			)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(
+		&options.StoreDir,
+		"store-dir",
+		defaultStoreDir(),
+		"Results store to collect, the store directory two levels above --cache-dir of the scans if they set it",
+	)
+	flags.BoolVar(&options.DryRun, "dry-run", false, "Only print what would be removed")
+	return cmd
+}
+
+func defaultStoreDir() string {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		userCacheDir = os.TempDir()
+	}
+	return qdstore.Dir(filepath.Join(userCacheDir, "JetBrains", "Qodana"))
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdhelp"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"strings"
+	"time"
+)
+
+// newHelpCommand returns the help command printing the help of the commands and the long-form help topics.
+func newHelpCommand() *cobra.Command {
+	var topicLines []string
+	for _, topic := range qdhelp.Topics() {
+		topicLines = append(topicLines, fmt.Sprintf("  %-15s %s", topic.Name, topic.Summary))
+	}
+	return &cobra.Command{
+		Use:   "help [command | topic]",
+		Short: "Help about any command or topic",
+		Long: `Print the help of the command, or the help topic (list them with qodana help topics):
+
+` + strings.Join(topicLines, "\n"),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			completions := []string{"topics\tList the help topics"}
+			for _, topic := range qdhelp.Topics() {
+				completions = append(completions, topic.Name+"\t"+topic.Summary)
+			}
+			for _, c := range cmd.Root().Commands() {
+				if c.IsAvailableCommand() {
+					completions = append(completions, c.Name()+"\t"+c.Short)
+				}
+			}
+			return completions, cobra.ShellCompDirectiveNoFileComp
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 1 {
+				if args[0] == "topics" {
+					cmd.Println(strings.Join(topicLines, "\n"))
+					return
+				}
+				if topic, ok := qdhelp.Find(args[0]); ok {
+					cmd.Print(qdhelp.Render(topic))
+					return
+				}
+			}
+			target, _, err := cmd.Root().Find(args)
+			if target == nil || err != nil {
+				cmd.Printf("Unknown help topic %#q\n", args)
+				cobra.CheckErr(cmd.Root().Usage())
+				return
+			}
+			target.InitDefaultHelpFlag()
+			target.InitDefaultVersionFlag()
+			cobra.CheckErr(target.Help())
+		},
+	}
+}
+
+// newManCommand returns a new instance of the man command.
+func newManCommand() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "man",
+		Short: "Generate the man pages",
+		Long: `Write the man pages of the commands (section 1) and of the help topics (section 7) to the directory,
+e.g. to install them to /usr/local/share/man/man1 and man7.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := qdhelp.WriteMan(cmd.Root(), output, time.Now()); err != nil {
+				log.Fatalf("Failed to write the man pages: %s", err)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "man", "Directory to write the man pages to")
+	return cmd
+}
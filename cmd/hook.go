@@ -0,0 +1,285 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdhook"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdreport"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtemp"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// zeroSha is the object name git passes to hooks for a missing ref.
+const zeroSha = "0000000000000000000000000000000000000000"
+
+// hookOptions represents hook command options.
+type hookOptions struct {
+	ProjectDir string
+	Type       string
+	Severity   string
+	Force      bool
+	Remote     string
+}
+
+// newHookCommand returns a new instance of the hook command.
+func newHookCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hook",
+		Short: "Run Qodana from git hooks",
+		Long: `Run Qodana on the staged files before every commit (pre-commit) or on the pushed commits before every push (pre-push).
+
+Only the changed files are analysed, and the commit or push is stopped when problems of the gate severity or higher are found.
+Use git commit --no-verify or git push --no-verify to skip the hook once.`,
+	}
+	cmd.AddCommand(newHookInstallCommand(), newHookUninstallCommand(), newHookRunCommand())
+	return cmd
+}
+
+// newHookInstallCommand returns a new instance of the hook install command.
+func newHookInstallCommand() *cobra.Command {
+	options := &hookOptions{}
+	cmd := &cobra.Command{
+		Use:   "install [-- <scan options>]",
+		Short: "Install the git hook running Qodana",
+		Long: `Install the git hook running Qodana on the changes, the options after -- are passed to qodana scan, e.g.
+
+  qodana hook install --severity critical -- --ide QDJVM`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := qdhook.ValidateType(options.Type); err != nil {
+				log.Fatal(err)
+			}
+			if _, err := qdreport.SeveritiesAtLeast(options.Severity); err != nil {
+				log.Fatal(err)
+			}
+			hooksDir := hooksDir(cmd.Context(), options.ProjectDir)
+			executable, err := os.Executable()
+			if err != nil {
+				executable = "qodana"
+			}
+			script := qdhook.Script(filepath.ToSlash(executable), options.Type, options.Severity, args)
+			path, err := qdhook.Install(hooksDir, options.Type, script, options.Force)
+			if err != nil {
+				log.Fatalf("Failed to install the hook: %s", err)
+			}
+			msg.SuccessMessage("Installed %s", path)
+		},
+	}
+	flags := cmd.Flags()
+	options.addFlags(cmd)
+	flags.StringVar(&options.Severity, "severity", "High", "Stop the commit or push when problems of this severity or higher are found: Critical, High, Moderate, Low or Info")
+	flags.BoolVar(&options.Force, "force", false, "Replace the existing hook not installed by Qodana")
+	return cmd
+}
+
+// newHookUninstallCommand returns a new instance of the hook uninstall command.
+func newHookUninstallCommand() *cobra.Command {
+	options := &hookOptions{}
+	cmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the git hook installed by qodana hook install",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := qdhook.ValidateType(options.Type); err != nil {
+				log.Fatal(err)
+			}
+			path, err := qdhook.Uninstall(hooksDir(cmd.Context(), options.ProjectDir), options.Type)
+			if err != nil {
+				log.Fatalf("Failed to uninstall the hook: %s", err)
+			}
+			if path == "" {
+				msg.WarningMessage("No %s hook is installed", options.Type)
+				return
+			}
+			msg.SuccessMessage("Removed %s", path)
+		},
+	}
+	options.addFlags(cmd)
+	return cmd
+}
+
+// newHookRunCommand returns a new instance of the hook run command.
+func newHookRunCommand() *cobra.Command {
+	options := &hookOptions{}
+	cmd := &cobra.Command{
+		Use:   "run [-- <scan options>]",
+		Short: "Analyse the changes of the commit or the push, called by the installed hook",
+		Long: `Analyse the staged files (pre-commit) or the commits being pushed (pre-push) with qodana scan,
+exits with code 1 when problems of the gate severity or higher are found.
+
+The pushed commits are read from the standard input of the pre-push hook, or from PRE_COMMIT_FROM_REF and PRE_COMMIT_TO_REF
+set by the pre-commit framework.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := qdhook.ValidateType(options.Type); err != nil {
+				log.Fatal(err)
+			}
+			severities, err := qdreport.SeveritiesAtLeast(options.Severity)
+			if err != nil {
+				log.Fatal(err)
+			}
+			os.Exit(runHook(cmd.Context(), options, severities, args))
+		},
+	}
+	flags := cmd.Flags()
+	options.addFlags(cmd)
+	flags.StringVar(&options.Severity, "severity", "High", "Fail when problems of this severity or higher are found: Critical, High, Moderate, Low or Info")
+	flags.StringVar(&options.Remote, "remote", "origin", "Remote being pushed to, used to find the pushed commits of a new branch")
+	return cmd
+}
+
+func (o *hookOptions) addFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.StringVarP(&o.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVar(&o.Type, "type", qdhook.PreCommit, "Type of the hook: pre-commit or pre-push")
+}
+
+// hooksDir returns the git hooks directory of the project.
+func hooksDir(ctx context.Context, projectDir string) string {
+	dir, err := git.HooksDir(ctx, projectDir, "")
+	if err != nil {
+		log.Fatalf("%s is not a git repository: %s", projectDir, err)
+	}
+	return dir
+}
+
+// runHook scans the changes of the hook and returns its exit code.
+func runHook(ctx context.Context, options *hookOptions, severities []string, scanArgs []string) int {
+	logDir, err := qdtemp.MkdirTemp("qodana-hook-")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(logDir) }()
+
+	changes, err := hookChanges(ctx, options, os.Stdin, logDir)
+	if err != nil {
+		log.Fatalf("Failed to compute the changes to analyse: %s", err)
+	}
+	if len(changes.Files) == 0 {
+		msg.SuccessMessage("No changes to analyse")
+		return 0
+	}
+	scopeFile := filepath.Join(logDir, "scope.json")
+	content, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = os.WriteFile(scopeFile, content, 0o644); err != nil {
+		log.Fatal(err)
+	}
+
+	resultsDir := filepath.Join(logDir, "results")
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	scan := utils.CommandContext(
+		ctx,
+		executable,
+		append(
+			[]string{
+				"scan",
+				"--project-dir", options.ProjectDir,
+				"--results-dir", resultsDir,
+				"--script", "scoped:" + scopeFile,
+				"--print-problems",
+				"--problems-format", "compact",
+				"--problems-severity", strings.Join(severities, ","),
+			},
+			scanArgs...,
+		)...,
+	)
+	scan.Stdout, scan.Stderr = os.Stdout, os.Stderr
+	if err = scan.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != utils.QodanaFailThresholdExitCode {
+			msg.ErrorMessage("Qodana analysis failed: %s", err)
+			return 1
+		}
+	}
+
+	problems, err := qdreport.LoadProblems(platform.GetSarifPath(resultsDir))
+	if err != nil {
+		msg.ErrorMessage("Failed to read the results: %s", err)
+		return 1
+	}
+	found := 0
+	for _, p := range problems {
+		for _, s := range severities {
+			if strings.EqualFold(p.Severity, s) {
+				found++
+				break
+			}
+		}
+	}
+	if found > 0 {
+		msg.ErrorMessage(
+			"Found %d problem(s) of %s severity or higher in the changes, use --no-verify to skip the %s hook",
+			found,
+			options.Severity,
+			options.Type,
+		)
+		return 1
+	}
+	msg.SuccessMessage("No problems of %s severity or higher in the changes", options.Severity)
+	return 0
+}
+
+// hookChanges returns the staged changes for pre-commit and the pushed changes for pre-push.
+func hookChanges(ctx context.Context, options *hookOptions, stdin io.Reader, logDir string) (git.ChangedFiles, error) {
+	if options.Type == qdhook.PreCommit {
+		return git.ComputeStagedFiles(ctx, options.ProjectDir, logDir)
+	}
+	if from, to := os.Getenv("PRE_COMMIT_FROM_REF"), os.Getenv("PRE_COMMIT_TO_REF"); from != "" && to != "" {
+		return git.ComputeChangedFiles(ctx, options.ProjectDir, from, to, logDir)
+	}
+	var result git.ChangedFiles
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		// <local ref> <local sha> <remote ref> <remote sha>
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 || fields[1] == zeroSha {
+			continue
+		}
+		from := fields[3]
+		if from == zeroSha {
+			base, err := git.MergeBase(ctx, options.ProjectDir, fields[1], fmt.Sprintf("refs/remotes/%s/HEAD", options.Remote), logDir)
+			if err != nil {
+				return result, fmt.Errorf("unable to find where the new branch %s starts: %w", fields[0], err)
+			}
+			from = base
+		}
+		changes, err := git.ComputeChangedFiles(ctx, options.ProjectDir, from, fields[1], logDir)
+		if err != nil {
+			return result, err
+		}
+		result.Files = append(result.Files, changes.Files...)
+	}
+	return result, scanner.Err()
+}
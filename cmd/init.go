@@ -90,7 +90,15 @@ func newInitCommand() *cobra.Command {
 					msg.SuccessMessage("The .NET configuration was successfully set")
 				}
 			}
-			msg.PrintFile(filepath.Join(cliOptions.ProjectDir, cliOptions.ConfigName))
+			if !msg.IsQuiet() {
+				msg.PrintFile(filepath.Join(cliOptions.ProjectDir, cliOptions.ConfigName))
+			}
+			msg.PorcelainLine("config", filepath.Join(cliOptions.ProjectDir, cliOptions.ConfigName))
+			if ide != "" {
+				msg.PorcelainLine("analyzer", ide)
+			} else if linter != "" {
+				msg.PorcelainLine("analyzer", linter)
+			}
 
 			commonCtx := commoncontext.Compute(
 				linter,
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdissues"
+	"github.com/JetBrains/qodana-cli/v2024/platform/userconfig"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+// issuesExportOptions represents issues export command options.
+type issuesExportOptions struct {
+	Tracker     string
+	Url         string
+	Project     string
+	MinSeverity string
+	DryRun      bool
+	Db          string
+	SarifFile   string
+	Linter      string
+	ProjectDir  string
+	ResultsDir  string
+	ConfigName  string
+}
+
+// newIssuesCommand returns a new instance of the issues command.
+func newIssuesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "issues",
+		Short: "Export the problems to an issue tracker",
+	}
+	cmd.AddCommand(newIssuesExportCommand())
+	return cmd
+}
+
+// newIssuesExportCommand returns a new instance of the issues export command.
+func newIssuesExportCommand() *cobra.Command {
+	options := &issuesExportOptions{}
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Create the YouTrack or Jira issues of the new problems",
+		Long: `Create the YouTrack or Jira issues of the new problems of the latest SARIF report of the project.
+
+The issue of each exported problem is remembered by the problem fingerprint in the exported issues database,
+the next exports update it instead of creating another one.
+
+YouTrack is accessed with YOUTRACK_URL and a permanent token in YOUTRACK_TOKEN,
+Jira with JIRA_URL and JIRA_TOKEN: a personal access token, or an API token of the Jira Cloud user in JIRA_USER.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			sarifPath := options.SarifFile
+			if sarifPath == "" {
+				commonCtx := commoncontext.Compute(
+					options.Linter,
+					"",
+					"",
+					options.ResultsDir,
+					"",
+					os.Getenv(qdenv.QodanaToken),
+					os.Getenv(qdenv.QodanaLicenseOnlyToken),
+					false,
+					options.ProjectDir,
+					options.ConfigName,
+				)
+				sarifPath = filepath.Join(commonCtx.ResultsDir, commoncontext.QodanaSarifName)
+			}
+			tracker, err := qdissues.New(options.Tracker, options.Url, options.Project)
+			if err != nil {
+				log.Fatal(err)
+			}
+			db, err := qdissues.LoadDb(options.Db)
+			if err != nil {
+				log.Fatalf("Failed to read the exported issues: %s", err)
+			}
+			created, updated, err := platform.ExportIssues(sarifPath, tracker, db, options.MinSeverity, options.DryRun)
+			if err != nil {
+				log.Fatalf("Failed to export the issues to %s: %s", tracker.Name(), err)
+			}
+			if options.DryRun {
+				msg.SuccessMessage("%d issues would be created and %d updated in %s", created, updated, tracker.Name())
+				return
+			}
+			msg.SuccessMessage("%d issues are created and %d updated in %s", created, updated, tracker.Name())
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&options.Tracker, "tracker", "", "Issue tracker to export the problems to: youtrack or jira")
+	flags.StringVar(&options.Url, "url", "", "URL of the tracker (default YOUTRACK_URL or JIRA_URL)")
+	flags.StringVar(&options.Project, "project", "", "Short name of the YouTrack project or key of the Jira project to create the issues in")
+	flags.StringVar(&options.MinSeverity, "min-severity", "high", "Export the problems of this severity and higher: critical, high, moderate, low or info")
+	flags.BoolVar(&options.DryRun, "dry-run", false, "Print the issues that would be created or updated without exporting them")
+	flags.StringVar(
+		&options.Db,
+		"db",
+		filepath.Join(userconfig.Dir(), qdissues.DbFileName),
+		"Database of the exported issues, used to update the issues of the problems exported before",
+	)
+	flags.StringVarP(&options.SarifFile, "sarif-file", "f", "", "SARIF file to export the problems of (default qodana.sarif.json of the results directory)")
+	flags.StringVarP(&options.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(
+		&options.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Override directory with Qodana inspection results (default <userCacheDir>/JetBrains/<linter>/results)",
+	)
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	_ = cmd.MarkFlagRequired("tracker")
+	_ = cmd.MarkFlagRequired("project")
+	return cmd
+}
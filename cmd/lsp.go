@@ -0,0 +1,160 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdlsp"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtemp"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// lspOptions represents lsp command options.
+type lspOptions struct {
+	Linter       string
+	ProjectDir   string
+	ResultsDir   string
+	SarifFile    string
+	ConfigName   string
+	RescanOnSave bool
+}
+
+// newLspCommand returns a new instance of the lsp command.
+func newLspCommand() *cobra.Command {
+	options := &lspOptions{}
+	cmd := &cobra.Command{
+		Use:   "lsp [-- <scan options>]",
+		Short: "Serve the Qodana problems to editors over the Language Server Protocol",
+		Long: `Run a language server on the standard input and output publishing the problems of the latest Qodana report
+as diagnostics, so any editor supporting the Language Server Protocol shows them inline.
+
+The diagnostics are updated when the report changes, e.g. after qodana scan. With --rescan-on-save, the saved file
+is analysed again with qodana scan, the options after -- are passed to it.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			sarifPath := options.SarifFile
+			if sarifPath == "" {
+				commonCtx := commoncontext.Compute(
+					options.Linter,
+					"",
+					"",
+					options.ResultsDir,
+					"",
+					os.Getenv(qdenv.QodanaToken),
+					os.Getenv(qdenv.QodanaLicenseOnlyToken),
+					false,
+					options.ProjectDir,
+					options.ConfigName,
+				)
+				sarifPath = platform.GetSarifPath(commonCtx.ResultsDir)
+			}
+			server := &qdlsp.Server{ProjectDir: options.ProjectDir, SarifPath: sarifPath}
+			if options.RescanOnSave {
+				rescanDir, err := qdtemp.MkdirTemp("qodana-lsp-")
+				if err != nil {
+					log.Fatal(err)
+				}
+				defer func() { _ = os.RemoveAll(rescanDir) }()
+				server.Rescan = func(file string) (string, error) {
+					return rescanFile(options.ProjectDir, rescanDir, file, args)
+				}
+			}
+			if err := server.Serve(os.Stdin, os.Stdout); err != nil {
+				log.Fatalf("Language server failed: %s", err)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(
+		&options.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Override directory to read Qodana inspection results from (default <userCacheDir>/JetBrains/<linter>/results)",
+	)
+	flags.StringVarP(&options.SarifFile, "sarif-file", "f", "", "Path to the SARIF file to publish instead of the one in the results directory")
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.BoolVar(&options.RescanOnSave, "rescan-on-save", false, "Analyse the saved files again with qodana scan")
+	return cmd
+}
+
+// rescanFile analyses the file with qodana scan scoped to it and returns the path of the SARIF report.
+func rescanFile(projectDir string, rescanDir string, file string, scanArgs []string) (string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	scope := git.ChangedFiles{
+		Files: []*git.ChangedFile{
+			{
+				Path:    file,
+				Added:   []*git.ChangedRegion{{FirstLine: 1, Count: bytes.Count(content, []byte("\n")) + 1}},
+				Deleted: []*git.ChangedRegion{},
+			},
+		},
+	}
+	scopeFile := filepath.Join(rescanDir, "scope.json")
+	data, err := json.MarshalIndent(scope, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(scopeFile, data, 0o644); err != nil {
+		return "", err
+	}
+	resultsDir := filepath.Join(rescanDir, "results")
+	if err = os.RemoveAll(resultsDir); err != nil {
+		return "", err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	scan := exec.Command(
+		executable,
+		append(
+			[]string{"scan", "--project-dir", projectDir, "--results-dir", resultsDir, "--script", "scoped:" + scopeFile},
+			scanArgs...,
+		)...,
+	)
+	// the standard output is the protocol connection
+	scan.Stdout, scan.Stderr = os.Stderr, os.Stderr
+	if err = scan.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != utils.QodanaFailThresholdExitCode {
+			return "", fmt.Errorf("qodana scan: %w", err)
+		}
+	}
+	return platform.GetSarifPath(resultsDir), nil
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdoptions"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"slices"
+)
+
+// newOptionsCommand returns a new instance of the options command.
+func newOptionsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "options",
+		Short: "Inspect the options of qodana scan",
+	}
+	cmd.AddCommand(newOptionsDumpCommand())
+	return cmd
+}
+
+// newOptionsDumpCommand returns a new instance of the options dump command.
+func newOptionsDumpCommand() *cobra.Command {
+	cliOptions := &platformcmd.CliOptions{}
+	format := qdoptions.FormatText
+	all := false
+	cmd := &cobra.Command{
+		Use:   "dump [scan flags]",
+		Short: "Print the effective options of qodana scan and where their values come from",
+		Long: `Resolve the options of qodana scan run with the given flags and print them with the source of each value:
+a flag, an environment variable, a key of qodana.yaml or the default. The values of the secrets are masked.
+
+The values qodana scan derives while running, e.g. the analysis id of --reproducible, are not printed here,
+qodana scan logs all the effective options with --log-level debug.`,
+		Example: `  qodana options dump -i project --profile-name qodana.recommended
+  qodana options dump --format json --all`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			qodanaYaml := qdyaml.LoadQodanaYaml(cliOptions.ProjectDir, cliOptions.ConfigName)
+			effective := qdoptions.Resolve(scanFlags(cmd.Flags(), "format", "all"), *cliOptions, qodanaYaml)
+			if err := effective.Write(cmd.OutOrStdout(), format, all); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	if err := platformcmd.ComputeFlags(cmd, cliOptions); err != nil {
+		log.Fatal(err)
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&format, "format", qdoptions.FormatText, "Output format: text or json")
+	flags.BoolVar(&all, "all", false, "Print all the options, also the ones not set")
+	return cmd
+}
+
+// scanFlags returns the flags of qodana scan, the flags of the command except the ones named own.
+func scanFlags(flags *pflag.FlagSet, own ...string) *pflag.FlagSet {
+	scan := pflag.NewFlagSet("scan", pflag.ContinueOnError)
+	scan.SortFlags = false
+	flags.VisitAll(
+		func(f *pflag.Flag) {
+			if !slices.Contains(own, f.Name) {
+				scan.AddFlag(f)
+			}
+		},
+	)
+	return scan
+}
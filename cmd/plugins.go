@@ -0,0 +1,216 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdplugin"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"os"
+	"path/filepath"
+)
+
+// pluginsOptions represents plugins command options.
+type pluginsOptions struct {
+	Linter     string
+	ProjectDir string
+	CacheDir   string
+	ConfigName string
+	Version    string
+	Archive    string
+	Repository string
+}
+
+// newPluginsCommand returns a new instance of the plugins command.
+func newPluginsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plugins",
+		Short: "Manage the plugins installed into the linter",
+		Long: `Manage the plugins section of qodana.yaml: the plugins installed into IDE-based linters before the analysis.
+
+The plugins with a version or an archive are kept in the Qodana cache and loaded from it,
+the others are installed by the linter from JetBrains Marketplace on every run.
+
+A private plugin repository is used instead of JetBrains Marketplace with QODANA_PLUGIN_REPOSITORY,
+authorized with QODANA_PLUGIN_REPOSITORY_TOKEN (a bearer token) or QODANA_PLUGIN_REPOSITORY_HEADERS
+(comma-separated key=value headers). They are passed to the linter, also in the container.`,
+	}
+	cmd.AddCommand(newPluginsListCommand(), newPluginsAddCommand(), newPluginsRemoveCommand())
+	return cmd
+}
+
+// newPluginsListCommand returns a new instance of the plugins list command.
+func newPluginsListCommand() *cobra.Command {
+	options := &pluginsOptions{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the plugins of qodana.yaml",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			q := qdyaml.LoadQodanaYaml(options.ProjectDir, options.ConfigName)
+			if len(q.Plugins) == 0 {
+				msg.WarningMessage("No plugins are installed, add one with %s", msg.PrimaryBold("qodana plugins add"))
+				return
+			}
+			cacheDir := options.cacheDir()
+			tableData := pterm.TableData{
+				[]string{msg.PrimaryBold("Id"), msg.PrimaryBold("Version"), msg.PrimaryBold("Source"), msg.PrimaryBold("Cached")},
+			}
+			for _, p := range q.Plugins {
+				version, source, cached := p.Version, "Marketplace", "no"
+				if version == "" {
+					version = "latest"
+				}
+				if p.Archive != "" {
+					version, source = "-", p.Archive
+				}
+				if qdplugin.IsCached(cacheDir, p) {
+					cached = "yes"
+				}
+				tableData = append(tableData, []string{p.Id, version, source, cached})
+			}
+			if err := pterm.DefaultTable.WithHasHeader().WithData(tableData).Render(); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	addPluginsFlags(cmd.Flags(), options)
+	return cmd
+}
+
+// newPluginsAddCommand returns a new instance of the plugins add command.
+func newPluginsAddCommand() *cobra.Command {
+	options := &pluginsOptions{}
+	cmd := &cobra.Command{
+		Use:   "add <id>",
+		Short: "Add a plugin to qodana.yaml and download it into the cache",
+		Long: `Add a plugin to qodana.yaml, pinned to its latest version on JetBrains Marketplace unless --version is set,
+and download it into the Qodana cache. With --archive, the plugin is installed from the .zip or .jar without
+reaching Marketplace, e.g. for offline environments.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			plugin := qdyaml.Plugin{Id: args[0]}
+			if options.Archive != "" {
+				if _, err := os.Stat(options.Archive); err != nil {
+					log.Fatalf("Failed to read the plugin archive: %s", err)
+				}
+				plugin.Archive = options.Archive
+				if rel, err := filepath.Rel(options.ProjectDir, options.Archive); err == nil && filepath.IsLocal(rel) {
+					plugin.Archive = filepath.ToSlash(rel)
+				}
+			}
+			marketplace := qdplugin.NewMarketplace(options.Repository)
+			if plugin.Archive == "" {
+				release, err := marketplace.Resolve(plugin.Id, options.Version)
+				if err != nil {
+					log.Fatalf("Failed to resolve the plugin: %s", err)
+				}
+				plugin.Version = release.Version
+			}
+
+			if _, err := qdplugin.Prepare(marketplace, options.cacheDir(), options.ProjectDir, plugin); err != nil {
+				log.Fatalf("Failed to install the plugin into the cache: %s", err)
+			}
+			q := qdyaml.LoadQodanaYaml(options.ProjectDir, options.ConfigName)
+			if q.Version == "" {
+				q.Version = "1.0"
+			}
+			q.AddPlugin(plugin)
+			if err := q.Sort().WriteConfig(qdyaml.GetQodanaYamlPathWithProject(options.ProjectDir, options.ConfigName)); err != nil {
+				log.Fatalf("Failed to update qodana.yaml: %s", err)
+			}
+			if plugin.Version != "" {
+				msg.SuccessMessage("Added the plugin %s %s", plugin.Id, plugin.Version)
+			} else {
+				msg.SuccessMessage("Added the plugin %s from %s", plugin.Id, plugin.Archive)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	addPluginsFlags(flags, options)
+	flags.StringVar(&options.Version, "version", "", "Version of the plugin (default the latest version on Marketplace)")
+	flags.StringVar(&options.Archive, "archive", "", "Plugin .zip or .jar to install instead of downloading it")
+	flags.StringVar(
+		&options.Repository,
+		"repository",
+		"",
+		"URL of the plugin repository, e.g. a private Marketplace mirror (default $QODANA_PLUGIN_REPOSITORY or https://plugins.jetbrains.com)",
+	)
+	cmd.MarkFlagsMutuallyExclusive("version", "archive")
+	return cmd
+}
+
+// newPluginsRemoveCommand returns a new instance of the plugins remove command.
+func newPluginsRemoveCommand() *cobra.Command {
+	options := &pluginsOptions{}
+	cmd := &cobra.Command{
+		Use:   "remove <id>",
+		Short: "Remove a plugin from qodana.yaml and the cache",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			q := qdyaml.LoadQodanaYaml(options.ProjectDir, options.ConfigName)
+			if !q.RemovePlugin(args[0]) {
+				msg.WarningMessage("No plugin %s in qodana.yaml", args[0])
+				return
+			}
+			if err := q.WriteConfig(qdyaml.GetQodanaYamlPathWithProject(options.ProjectDir, options.ConfigName)); err != nil {
+				log.Fatalf("Failed to update qodana.yaml: %s", err)
+			}
+			_ = os.RemoveAll(filepath.Dir(qdplugin.CacheDir(options.cacheDir(), qdyaml.Plugin{Id: args[0]})))
+			msg.SuccessMessage("Removed the plugin %s", args[0])
+		},
+	}
+	addPluginsFlags(cmd.Flags(), options)
+	return cmd
+}
+
+func addPluginsFlags(flags *pflag.FlagSet, options *pluginsOptions) {
+	flags.StringVarP(&options.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVar(&options.CacheDir, "cache-dir", "", "Override cache directory (default <userCacheDir>/JetBrains/<linter>/cache)")
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+}
+
+// cacheDir returns the cache directory of the linter the plugins are installed into.
+func (o *pluginsOptions) cacheDir() string {
+	if o.CacheDir != "" {
+		return o.CacheDir
+	}
+	return commoncontext.Compute(
+		o.Linter,
+		"",
+		"",
+		"",
+		"",
+		os.Getenv(qdenv.QodanaToken),
+		os.Getenv(qdenv.QodanaLicenseOnlyToken),
+		false,
+		o.ProjectDir,
+		o.ConfigName,
+	).CacheDir
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdprofile"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+// profileOptions represents profile command options.
+type profileOptions struct {
+	ProjectDir  string
+	ConfigName  string
+	ProfileName string
+	ProfilePath string
+}
+
+// newProfileCommand returns a new instance of the profile command.
+func newProfileCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Inspect the inspection profiles of the project",
+	}
+	cmd.AddCommand(newProfileShowCommand(), newProfileDiffCommand())
+	return cmd
+}
+
+// newProfileShowCommand returns a new instance of the profile show command.
+func newProfileShowCommand() *cobra.Command {
+	options := &profileOptions{}
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the inspections of the profile used by qodana scan",
+		Long: `Show the inspections of the profile used by qodana scan: the profile of --profile-name or --profile-path,
+then the profile of qodana.yaml, then the project default profile, with the inline profile, the include and
+the exclude sections of qodana.yaml applied.
+
+The inspections not listed in the profile keep the default settings of the linter.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			q := qdyaml.LoadQodanaYaml(options.ProjectDir, options.ConfigName)
+			resolve := qdprofile.Resolve
+			if !q.Profile.Inline.IsEmpty() {
+				resolve = qdprofile.Compose
+			}
+			profile, err := resolve(options.ProjectDir, options.ProfileName, options.ProfilePath, &q)
+			if err != nil {
+				log.Fatalf("Failed to resolve the profile: %s", err)
+			}
+			profile.Apply(&q)
+
+			tableData := pterm.TableData{
+				[]string{msg.PrimaryBold("Inspection"), msg.PrimaryBold("Severity"), msg.PrimaryBold("Scopes")},
+			}
+			for _, i := range profile.Inspections {
+				tableData = append(tableData, []string{i.Id, i.String(), scopesString(i.Scopes)})
+			}
+			if profile.Path != "" {
+				msg.SuccessMessage("Profile %s (%s)", msg.PrimaryBold(profile.Name), profile.Path)
+			} else {
+				msg.SuccessMessage("Profile %s", msg.PrimaryBold(profile.Name))
+			}
+			if err = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render(); err != nil {
+				log.Fatal(err)
+			}
+			msg.SuccessMessage(
+				"%d inspections enabled, %d disabled",
+				profile.Enabled(),
+				len(profile.Inspections)-profile.Enabled(),
+			)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.StringVarP(&options.ProfileName, "profile-name", "n", "", "Profile name defined in the project")
+	flags.StringVarP(&options.ProfilePath, "profile-path", "p", "", "Path to the profile file")
+	cmd.MarkFlagsMutuallyExclusive("profile-name", "profile-path")
+	return cmd
+}
+
+// newProfileDiffCommand returns a new instance of the profile diff command.
+func newProfileDiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <a.xml> <b.xml>",
+		Short: "Show the inspections set differently in two profiles",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			before, err := qdprofile.Load(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			after, err := qdprofile.Load(args[1])
+			if err != nil {
+				log.Fatal(err)
+			}
+			changes := qdprofile.Diff(before, after)
+			if len(changes) == 0 {
+				msg.SuccessMessage("The profiles %s and %s run the same inspections", args[0], args[1])
+				return
+			}
+			tableData := pterm.TableData{
+				[]string{msg.PrimaryBold("Inspection"), msg.PrimaryBold(args[0]), msg.PrimaryBold(args[1])},
+			}
+			for _, c := range changes {
+				tableData = append(tableData, []string{c.Id, inspectionString(c.Before), inspectionString(c.After)})
+			}
+			if err = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render(); err != nil {
+				log.Fatal(err)
+			}
+			msg.SuccessMessage("%d inspections differ", len(changes))
+		},
+	}
+	return cmd
+}
+
+func inspectionString(i *qdprofile.Inspection) string {
+	if i == nil {
+		return "-"
+	}
+	if len(i.Scopes) == 0 {
+		return i.String()
+	}
+	return i.String() + " (" + scopesString(i.Scopes) + ")"
+}
+
+func scopesString(scopes []qdprofile.Scope) string {
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = scope.String()
+	}
+	return strings.Join(s, "; ")
+}
@@ -60,7 +60,7 @@ func newPullCommand() *cobra.Command {
 				if err != nil {
 					log.Fatal("couldn't connect to container engine ", err)
 				}
-				core.PullImage(containerClient, commonCtx.Linter)
+				core.PullImage(cmd.Context(), containerClient, commonCtx.Linter)
 			}
 		},
 	}
@@ -0,0 +1,515 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdowners"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdreport"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// reportCompareOptions represents report compare command options.
+type reportCompareOptions struct {
+	Output  string
+	NoServe bool
+	Port    int
+	Serve   qdreport.ServeOptions
+}
+
+// reportTuiOptions represents report tui command options.
+type reportTuiOptions struct {
+	ProjectDir string
+	SarifFile  string
+	ConfigName string
+}
+
+// reportExportOptions represents report export command options.
+type reportExportOptions struct {
+	ProjectDir string
+	SarifFile  string
+	Format     string
+	Columns    []string
+	Output     string
+	OwnersFile string
+}
+
+// reportPdfOptions represents report pdf command options.
+type reportPdfOptions struct {
+	Linter     string
+	ProjectDir string
+	ResultsDir string
+	ConfigName string
+	History    []string
+	Output     string
+	TopRules   int
+}
+
+// reportStatsOptions represents report stats command options.
+type reportStatsOptions struct {
+	Linter     string
+	ProjectDir string
+	ResultsDir string
+	ConfigName string
+	Top        int
+	Treemap    string
+}
+
+// reportDuplicatesOptions represents report duplicates command options.
+type reportDuplicatesOptions struct {
+	Linter     string
+	ProjectDir string
+	ResultsDir string
+	ConfigName string
+	Top        int
+}
+
+// newReportCommand returns a new instance of the report command.
+func newReportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Work with Qodana results",
+	}
+	cmd.AddCommand(
+		newReportCompareCommand(),
+		newReportTuiCommand(),
+		newReportExportCommand(),
+		newReportPdfCommand(),
+		newReportStatsCommand(),
+		newReportDuplicatesCommand(),
+	)
+	return cmd
+}
+
+// newReportCompareCommand returns a new instance of the report compare command.
+func newReportCompareCommand() *cobra.Command {
+	options := &reportCompareOptions{}
+	cmd := &cobra.Command{
+		Use:   "compare <base> <head>",
+		Short: "Compare the results of two Qodana runs",
+		Long: `Compare the results of two Qodana runs (results directories or SARIF files), e.g. before and after a change of the inspection profile.
+
+The added and resolved problems, the problems with changed severity and the changes of the number of problems by rule
+and by severity are served as a local page, and as JSON at /comparison.json. Use --output to write the JSON to a file.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			comparison, err := qdreport.CompareResults(args[0], args[1])
+			if err != nil {
+				log.Fatalf("Failed to compare the results: %s", err)
+			}
+			msg.SuccessMessage(
+				"%d problems added, %d resolved, %d changed severity",
+				len(comparison.Added),
+				len(comparison.Resolved),
+				len(comparison.SeverityChanges),
+			)
+			if options.Output != "" {
+				if err = qdreport.WriteComparison(comparison, options.Output); err != nil {
+					log.Fatalf("Failed to write the comparison to %s: %s", options.Output, err)
+				}
+				msg.SuccessMessage("Comparison is written to %s", options.Output)
+			}
+			if options.NoServe {
+				return
+			}
+			serve := options.Serve.WithEnv()
+			if err = serve.Validate(); err != nil {
+				log.Fatal(err)
+			}
+			listener, err := serve.Listen(options.Port)
+			if err != nil {
+				log.Fatalf("Failed to serve the comparison: %s", err)
+			}
+			msg.SuccessMessage("Showing the comparison from %s, press Ctrl+C to stop", serve.Url(options.Port))
+			go func() {
+				_ = utils.OpenBrowser(serve.Url(options.Port))
+			}()
+			if err = serve.Serve(listener, qdreport.CompareHandler(comparison)); err != nil {
+				log.Fatalf("Failed to serve the comparison: %s", err)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.Output, "output", "o", "", "File to write the comparison to as JSON")
+	flags.BoolVar(&options.NoServe, "no-serve", false, "Don't serve the comparison page, only print the summary and write --output")
+	flags.IntVarP(&options.Port, "port", "p", 8080, "Port to serve the comparison page on")
+	platformcmd.AddServeFlags(flags, &options.Serve)
+	return cmd
+}
+
+// newReportTuiCommand returns a new instance of the report tui command.
+func newReportTuiCommand() *cobra.Command {
+	options := &reportTuiOptions{}
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Browse the problems in the terminal",
+		Long: `Browse the problems of the SARIF report in the terminal, e.g. when working over SSH without a browser.
+
+Filter the problems by severity (s), rule (r), path (p) or message (/), open the problem in $VISUAL or $EDITOR (Enter)
+and suppress it (x) by excluding its rule for its file in qodana.yaml.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if !msg.IsInteractive() {
+				log.Fatal("qodana report tui requires an interactive terminal, use qodana view to print the problems")
+			}
+			if err := qdreport.Browse(options.SarifFile, options.ProjectDir, options.ConfigName); err != nil {
+				log.Fatalf("Failed to browse the problems: %s", err)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(&options.SarifFile, "sarif-file", "f", commoncontext.QodanaSarifName, "Path to the SARIF file")
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml' to suppress the problems in",
+	)
+	return cmd
+}
+
+// newReportExportCommand returns a new instance of the report export command.
+func newReportExportCommand() *cobra.Command {
+	options := &reportExportOptions{}
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the problems as a CSV or XLSX table",
+		Long: `Export the problems of the SARIF report as a CSV or XLSX table, e.g. to share them with people who work in spreadsheets.
+
+Select the columns with --columns, the owner column is read from --owners-file or from CODEOWNERS of the project.
+The report is read problem by problem, so large reports are exported without loading them into memory.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var owners *qdowners.Owners
+			if slices.Contains(options.Columns, "owner") {
+				var err error
+				if owners, err = platform.LoadOwners(options.OwnersFile, options.ProjectDir); err != nil {
+					log.Fatal(err)
+				}
+			}
+			output := options.Output
+			if output == "" {
+				output = "qodana-problems." + options.Format
+			}
+			w := os.Stdout
+			if output != "-" {
+				f, err := os.Create(output)
+				if err != nil {
+					log.Fatalf("Failed to create %s: %s", output, err)
+				}
+				defer func() { _ = f.Close() }()
+				w = f
+			}
+			written, err := platform.ExportProblems(options.SarifFile, options.Format, options.Columns, owners, w)
+			if err != nil {
+				log.Fatalf("Failed to export the problems: %s", err)
+			}
+			if output != "-" {
+				msg.SuccessMessage("%d problems are exported to %s", written, output)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(&options.SarifFile, "sarif-file", "f", commoncontext.QodanaSarifName, "Path to the SARIF file")
+	flags.StringVar(&options.Format, "format", platform.ExportCsv, "Format of the table: csv or xlsx")
+	flags.StringSliceVar(
+		&options.Columns,
+		"columns",
+		platform.ExportColumns,
+		"Columns to export: "+strings.Join(platform.ExportColumns, ", "),
+	)
+	flags.StringVarP(
+		&options.Output,
+		"output",
+		"o",
+		"",
+		"File to write the table to, qodana-problems.<format> by default, - for stdout",
+	)
+	flags.StringVar(&options.OwnersFile, "owners-file", "", "Owners file of the owner column, CODEOWNERS of the project by default")
+	return cmd
+}
+
+// newReportPdfCommand returns a new instance of the report pdf command.
+func newReportPdfCommand() *cobra.Command {
+	options := &reportPdfOptions{}
+	cmd := &cobra.Command{
+		Use:   "pdf",
+		Short: "Render an executive summary of the results as a PDF document",
+		Long: `Render an executive summary of the latest results as a single PDF document, e.g. for compliance hand-offs
+where the HTML report folder is not accepted: the quality gate status, the problems by severity, the top rules
+and the trend over the earlier results passed with --history (results directories or SARIF files, oldest first).`,
+		Run: func(cmd *cobra.Command, args []string) {
+			commonCtx := commoncontext.Compute(
+				options.Linter,
+				"",
+				"",
+				options.ResultsDir,
+				"",
+				os.Getenv(qdenv.QodanaToken),
+				os.Getenv(qdenv.QodanaLicenseOnlyToken),
+				false,
+				options.ProjectDir,
+				options.ConfigName,
+			)
+			problems, err := qdreport.LoadProblems(qdreport.SarifPath(commonCtx.ResultsDir))
+			if err != nil {
+				log.Fatalf("Failed to read the results of %s, run qodana scan first: %s", commonCtx.ResultsDir, err)
+			}
+			history := make([]qdreport.Run, 0, len(options.History))
+			for _, results := range options.History {
+				runProblems, err := qdreport.LoadProblems(qdreport.SarifPath(results))
+				if err != nil {
+					log.Fatalf("Failed to read the results of %s: %s", results, err)
+				}
+				history = append(history, qdreport.Run{Name: filepath.Base(results), Problems: runProblems})
+			}
+			project := options.ProjectDir
+			if abs, err := filepath.Abs(project); err == nil {
+				project = filepath.Base(abs)
+			}
+			summary := qdreport.Summarize(project, qualityGate(commonCtx.ResultsDir), problems, history, options.TopRules)
+
+			f, err := os.Create(options.Output)
+			if err != nil {
+				log.Fatalf("Failed to create %s: %s", options.Output, err)
+			}
+			defer func() { _ = f.Close() }()
+			if err = qdreport.WriteSummaryPdf(summary, f); err != nil {
+				log.Fatalf("Failed to write the summary to %s: %s", options.Output, err)
+			}
+			msg.SuccessMessage("Summary is written to %s", options.Output)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(
+		&options.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Override directory with Qodana inspection results (default <userCacheDir>/JetBrains/<linter>/results)",
+	)
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.StringArrayVar(&options.History, "history", nil, "Earlier results directory or SARIF file of the trend, oldest first, can be repeated")
+	flags.StringVar(&options.Output, "output-file", "qodana-summary.pdf", "File to write the summary to")
+	flags.IntVar(&options.TopRules, "top-rules", 10, "Number of the rules with the most problems to show")
+	return cmd
+}
+
+// qualityGate returns the quality gate status of the run from outcome.json of the results directory,
+// empty if the results don't have it.
+func qualityGate(resultsDir string) string {
+	outcome, err := platform.ReadOutcome(resultsDir)
+	if err != nil {
+		return ""
+	}
+	switch outcome.ExitCode {
+	case utils.QodanaSuccessExitCode:
+		return qdreport.GatePassed
+	case utils.QodanaFailThresholdExitCode:
+		return qdreport.GateFailed
+	default:
+		return outcome.Reason()
+	}
+}
+
+// newReportStatsCommand returns a new instance of the report stats command.
+func newReportStatsCommand() *cobra.Command {
+	options := &reportStatsOptions{}
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Print the hotspots of the problems",
+		Long: `Print the files with the most problems per 1000 lines, the rules with the most problems and the rules with
+the largest regressions since the baseline, to prioritize the refactorings.
+
+Use --treemap to write the problems by directory and file as JSON for treemap charts.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			commonCtx := commoncontext.Compute(
+				options.Linter,
+				"",
+				"",
+				options.ResultsDir,
+				"",
+				os.Getenv(qdenv.QodanaToken),
+				os.Getenv(qdenv.QodanaLicenseOnlyToken),
+				false,
+				options.ProjectDir,
+				options.ConfigName,
+			)
+			problems, err := qdreport.LoadProblems(qdreport.SarifPath(commonCtx.ResultsDir))
+			if err != nil {
+				log.Fatalf("Failed to read the results of %s, run qodana scan first: %s", commonCtx.ResultsDir, err)
+			}
+			printStats(qdreport.ComputeStats(problems, options.ProjectDir, options.Top))
+			if options.Treemap != "" {
+				if err = qdreport.WriteTreemap(qdreport.Treemap(problems), options.Treemap); err != nil {
+					log.Fatalf("Failed to write the treemap to %s: %s", options.Treemap, err)
+				}
+				msg.SuccessMessage("Treemap is written to %s", options.Treemap)
+			}
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(
+		&options.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Override directory with Qodana inspection results (default <userCacheDir>/JetBrains/<linter>/results)",
+	)
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.IntVar(&options.Top, "top", 10, "Number of the files and the rules to print")
+	flags.StringVar(&options.Treemap, "treemap", "", "File to write the problems by directory and file to as JSON")
+	return cmd
+}
+
+// printStats prints the hotspots as tables.
+func printStats(stats *qdreport.Stats) {
+	msg.SuccessMessage("%d problems", stats.Problems)
+
+	files := pterm.TableData{{msg.PrimaryBold("File"), msg.PrimaryBold("Problems"), msg.PrimaryBold("Lines"), msg.PrimaryBold("Per 1000 lines")}}
+	for _, f := range stats.Files {
+		lines, density := "-", "-"
+		if f.Lines > 0 {
+			lines, density = strconv.Itoa(f.Lines), strconv.FormatFloat(f.Density, 'f', 1, 64)
+		}
+		files = append(files, []string{f.File, strconv.Itoa(f.Problems), lines, density})
+	}
+	printStatsTable("Top files by problem density", files)
+
+	rules := pterm.TableData{{msg.PrimaryBold("Rule"), msg.PrimaryBold("Problems")}}
+	for _, r := range stats.Rules {
+		rules = append(rules, []string{r.Name, strconv.Itoa(r.Count)})
+	}
+	printStatsTable("Top rules", rules)
+
+	regressions := pterm.TableData{{msg.PrimaryBold("Rule"), msg.PrimaryBold("New"), msg.PrimaryBold("Resolved"), msg.PrimaryBold("Delta")}}
+	for _, r := range stats.Regressions {
+		regressions = append(regressions, []string{r.RuleId, strconv.Itoa(r.New), strconv.Itoa(r.Resolved), "+" + strconv.Itoa(r.Delta)})
+	}
+	printStatsTable("Largest regressions since the baseline", regressions)
+}
+
+func printStatsTable(title string, tableData pterm.TableData) {
+	msg.EmptyMessage()
+	fmt.Println(msg.PrimaryBold(title))
+	if len(tableData) == 1 {
+		fmt.Println("None")
+		return
+	}
+	table := pterm.DefaultTable.WithData(tableData)
+	table.HeaderRowSeparator = ""
+	table.Separator = " "
+	table.Boxed = true
+	if err := table.Render(); err != nil {
+		log.Debugf("Failed to print %s: %v", strings.ToLower(title), err)
+	}
+}
+
+// newReportDuplicatesCommand returns a new instance of the report duplicates command.
+func newReportDuplicatesCommand() *cobra.Command {
+	options := &reportDuplicatesOptions{}
+	cmd := &cobra.Command{
+		Use:   "duplicates",
+		Short: "Print the duplicated code",
+		Long: `Print the groups of the duplicated fragments found by the DuplicatedCode inspection with their files and lines,
+and the share of the duplicated lines in the lines of the project.
+
+Set failureConditions.duplicationThreshold in qodana.yaml to fail the scans above a percentage of duplicated lines.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			commonCtx := commoncontext.Compute(
+				options.Linter,
+				"",
+				"",
+				options.ResultsDir,
+				"",
+				os.Getenv(qdenv.QodanaToken),
+				os.Getenv(qdenv.QodanaLicenseOnlyToken),
+				false,
+				options.ProjectDir,
+				options.ConfigName,
+			)
+			duplication, err := platform.FindDuplicates(platform.GetSarifPath(commonCtx.ResultsDir), options.ProjectDir)
+			if err != nil {
+				log.Fatalf("Failed to read the duplicates of %s, run qodana scan first: %s", commonCtx.ResultsDir, err)
+			}
+			for i, group := range duplication.Groups {
+				if i == options.Top {
+					fmt.Printf("... and %d more groups\n", len(duplication.Groups)-options.Top)
+					break
+				}
+				msg.EmptyMessage()
+				fmt.Println(msg.PrimaryBold(fmt.Sprintf("%d lines duplicated %d times", group.Lines, len(group.Fragments))))
+				for _, f := range group.Fragments {
+					fmt.Printf("  %s:%d-%d\n", f.File, f.StartLine, f.EndLine)
+				}
+			}
+			msg.EmptyMessage()
+			msg.SuccessMessage(
+				"%d clone groups, %d duplicated lines of %d (%.1f%%)",
+				len(duplication.Groups),
+				duplication.DuplicatedLines,
+				duplication.TotalLines,
+				duplication.Percentage,
+			)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVarP(
+		&options.ResultsDir,
+		"results-dir",
+		"o",
+		"",
+		"Override directory with Qodana inspection results (default <userCacheDir>/JetBrains/<linter>/results)",
+	)
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.IntVar(&options.Top, "top", 20, "Number of the largest clone groups to print")
+	return cmd
+}
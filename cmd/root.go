@@ -19,9 +19,12 @@ package cmd
 import (
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/core"
+	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
 	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtemp"
 	"github.com/JetBrains/qodana-cli/v2024/platform/version"
+	"github.com/pterm/pterm"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -37,6 +40,22 @@ func isCompletionRequested(args []string) bool {
 	return len(args) >= 2 && args[1] == "completion"
 }
 
+// isStdoutReserved checks if the standard output is reserved for the output of the command: the language server
+// speaking the protocol, or the CI pipeline generated with --output -.
+func isStdoutReserved(args []string) bool {
+	if len(args) >= 2 && args[1] == "lsp" {
+		return true
+	}
+	if len(args) >= 3 && args[1] == "ci" && args[2] == "generate" {
+		for i, arg := range args {
+			if arg == "--output=-" || arg == "-o=-" || ((arg == "--output" || arg == "-o") && i+1 < len(args) && args[i+1] == "-") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // isCommandRequested checks if any command is requested.
 func isCommandRequested(commands []*cobra.Command, args []string) string {
 	for _, c := range commands {
@@ -62,6 +81,12 @@ func setDefaultCommandIfNeeded(rootCmd *cobra.Command, args []string) {
 
 // Execute is a main CLI entrypoint: handles user interrupt, CLI start and everything else.
 func Execute() {
+	// the messages printed before the flags are parsed follow the environment
+	_ = msg.SetLanguage(os.Getenv(qdenv.QodanaLangEnv))
+	platformcmd.ApplyAccessibility(qdenv.IsEnabled(qdenv.QodanaAsciiEnv), qdenv.IsEnabled(qdenv.QodanaScreenReaderEnv))
+	if isStdoutReserved(os.Args) {
+		pterm.SetDefaultOutput(os.Stderr)
+	}
 	if !qdenv.IsContainer() && os.Geteuid() == 0 {
 		msg.WarningMessage("Running the tool as root is dangerous: please run it as a regular user")
 	}
@@ -70,8 +95,13 @@ func Execute() {
 		msg.DisableColor()
 	}
 
+	// log.Fatal exits without running the deferred functions
+	log.RegisterExitHandler(qdtemp.Cleanup)
+	defer qdtemp.Cleanup()
+
 	setDefaultCommandIfNeeded(rootCommand, os.Args)
 	if err := rootCommand.Execute(); err != nil {
+		qdtemp.Cleanup()
 		core.CheckForUpdates(version.Version)
 		_, err = fmt.Fprintf(os.Stderr, "error running command: %s\n", err)
 		if err != nil {
@@ -85,6 +115,10 @@ func Execute() {
 
 // newRootCommand constructs root command.
 func newRootCommand() *cobra.Command {
+	var quiet, porcelain bool
+	var lang string
+	var ascii, screenReader bool
+	var tempDir string
 	rootCmd := &cobra.Command{
 		Use:     "qodana",
 		Short:   "Run Qodana CLI",
@@ -96,6 +130,15 @@ func newRootCommand() *cobra.Command {
 				log.Fatal(err)
 			}
 			log.SetLevel(logLevel)
+			msg.SetOutputMode(platformcmd.OutputMode(quiet, porcelain))
+			platformcmd.ApplyAccessibility(ascii, screenReader)
+			if err := msg.SetLanguage(lang); err != nil {
+				msg.WarningMessage("%s", err)
+			}
+			if msg.IsQuiet() {
+				core.DisableCheckUpdates = true
+			}
+			qdtemp.SetRoot(tempDir)
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) == 0 {
@@ -106,13 +149,26 @@ func newRootCommand() *cobra.Command {
 			}
 		},
 	}
+	rootCmd.SetHelpCommand(newHelpCommand())
 	rootCmd.PersistentFlags().String("log-level", "error", "Set log-level for output")
 	rootCmd.PersistentFlags().BoolVar(
 		&core.DisableCheckUpdates,
 		"disable-update-checks",
 		false,
-		"Disable check for updates",
+		fmt.Sprintf("Disable check for updates (or set %s=1)", qdenv.QodanaDisableUpdateChecksEnv),
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&tempDir,
+		"temp-dir",
+		"",
+		fmt.Sprintf(
+			"Directory for the temporary files, each run keeps them in its own subdirectory removed on exit (or set %s, default <system temp>/qodana)",
+			qdenv.QodanaTmpEnv,
+		),
 	)
+	platformcmd.AddOutputFlags(rootCmd.PersistentFlags(), &quiet, &porcelain)
+	platformcmd.AddLanguageFlag(rootCmd.PersistentFlags(), &lang)
+	platformcmd.AddAccessibilityFlags(rootCmd.PersistentFlags(), &ascii, &screenReader)
 	if err := viper.BindPFlag("log-level", rootCmd.PersistentFlags().Lookup("log-level")); err != nil {
 		log.Fatal(err)
 	}
@@ -132,6 +188,33 @@ func InitCli() {
 		newViewCommand(),
 		newContributorsCommand(),
 		newClocCommand(),
+		newCloudCommand(),
+		newAuthCommand(),
+		newDoctorCommand(),
+		newSupportBundleCommand(),
+		newTelemetryCommand(),
+		newCacheCommand(),
+		newReportCommand(),
+		newHookCommand(),
+		newLspCommand(),
+		newDaemonCommand(),
+		newScheduleCommand(),
+		newBatchCommand(),
+		newBenchCommand(),
+		newCiCommand(),
+		newBaselineCommand(),
+		newProfileCommand(),
+		newPluginsCommand(),
+		newEncryptionCommand(),
+		newVerifyResultsCommand(),
+		newExplainCommand(),
+		newIssuesCommand(),
+		newBadgeCommand(),
+		newManCommand(),
+		newSelfUpdateCommand(),
+		newOptionsCommand(),
+		newCleanCommand(),
+		newGcCommand(),
 	)
 }
 
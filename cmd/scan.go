@@ -17,6 +17,8 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/cloud"
 	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
@@ -24,13 +26,28 @@ import (
 	"github.com/JetBrains/qodana-cli/v2024/platform"
 	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
 	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
 	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdbatch"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcodemetrics"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcrypt"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdoptions"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdscope"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdstore"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtemp"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtiming"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/platform/tokenloader"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	log "github.com/sirupsen/logrus"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/JetBrains/qodana-cli/v2024/core"
 	"github.com/spf13/cobra"
@@ -46,31 +63,268 @@ func newScanCommand() *cobra.Command {
 
 Note that most options can be configured via qodana.yaml (https://www.jetbrains.com/help/qodana/qodana-yaml.html) file.
 But you can always override qodana.yaml options with the following command-line options.
+Every option can be set with the QODANA_<OPTION> environment variable as well, e.g. QODANA_FAIL_THRESHOLD=10 for --fail-threshold 10:
+the command-line options take precedence over the environment variables, which take precedence over qodana.yaml.
 `,
 		Run: func(cmd *cobra.Command, args []string) {
+			if cliOptions.ProjectsFile != "" {
+				os.Exit(scanProjects(cmd, cliOptions))
+			}
 			ctx := cmd.Context()
+			start := time.Now()
+			platform.StartAudit(cliOptions.AuditLog, cmd)
+			platform.EnableResultsManifest(*cliOptions)
+			var problemsOutput *platform.ProblemsOutput
+			if cliOptions.PrintProblems {
+				problemsOutput = &platform.ProblemsOutput{
+					Format:     cliOptions.ProblemsFormat,
+					Severities: cliOptions.ProblemsSeverities,
+					ProjectDir: cliOptions.ProjectDir,
+				}
+				if err := problemsOutput.Validate(); err != nil {
+					log.Fatal(err)
+				}
+			}
+			if err := platformcmd.ValidateFixesOptions(cliOptions); err != nil {
+				log.Fatal(err)
+			}
+			encryption, err := qdcrypt.Load(cliOptions.EncryptionKeyFile, cliOptions.EncryptionRecipient)
+			if err != nil {
+				log.Fatalf("Invalid encryption options: %s", err)
+			}
+			rootSpan := qdtrace.Init("qodana scan")
 
+			configSpan := qdtrace.Start("preparation")
 			qodanaYaml := qdyaml.LoadQodanaYaml(cliOptions.ProjectDir, cliOptions.ConfigName)
+			if err := platform.CheckCliVersion(qodanaYaml); err != nil {
+				log.Fatal(err)
+			}
+			effective := qdoptions.Resolve(cmd.Flags(), *cliOptions, qodanaYaml)
+			if err := core.ValidateVmOptions(qodanaYaml.VmOptions, cliOptions.VmOptions); err != nil {
+				log.Fatal(err)
+			}
+			excluded := core.ApplyGameEngine(cliOptions.ProjectDir, &qodanaYaml)
+			configName, removeIgnoreConfig, err := core.ApplyIgnoreFile(cliOptions.ProjectDir, cliOptions.ConfigName, &qodanaYaml, excluded)
+			if err != nil {
+				log.Fatalf("Failed to read %s: %s", qdyaml.IgnoreFileName, err)
+			}
+			stopRemovingIgnoreConfig := utils.OnInterrupt(removeIgnoreConfig)
+			if configName != cliOptions.ConfigName {
+				effective = effective.With(
+					"config", configName, qdyaml.IgnoreFileName, func(o *platformcmd.CliOptions) {
+						o.ConfigName = configName
+					},
+				)
+			}
+			var scope *qdscope.Scope
+			if len(cliOptions.Scopes) > 0 {
+				scope, effective = selectScope(effective)
+			}
+			severityMapping, err := platform.NewSeverityMapping(qodanaYaml.SeverityMapping, cliOptions.SeverityMap)
+			if err != nil {
+				log.Fatal(err)
+			}
+			remediation, err := platform.NewRemediation(qodanaYaml.Remediation, cliOptions.EstimateDebt)
+			if err != nil {
+				log.Fatal(err)
+			}
+			metricConditions, err := qdcodemetrics.ParseConditions(cliOptions.FailOnMetric)
+			if err != nil {
+				log.Fatal(err)
+			}
+			options := effective.Options()
+			profilePath, removeInlineProfile, err := core.ApplyInlineProfile(
+				options.ProjectDir,
+				options.ProfileName,
+				options.ProfilePath,
+				&qodanaYaml,
+			)
+			if err != nil {
+				log.Fatalf("Failed to generate the inline profile: %s", err)
+			}
+			stopRemovingInlineProfile := utils.OnInterrupt(removeInlineProfile)
+			if profilePath != options.ProfilePath {
+				effective = effective.With(
+					"profile-path", profilePath, "profile.inline", func(o *platformcmd.CliOptions) {
+						o.ProfileName, o.ProfilePath = "", profilePath
+					},
+				)
+				options = effective.Options()
+			}
+			cloud.LicenseCacheTtl = options.LicenseCacheTtl
 
 			commonCtx := commoncontext.Compute(
-				cliOptions.Linter,
-				cliOptions.Ide,
-				cliOptions.CacheDir,
-				cliOptions.ResultsDir,
-				cliOptions.ReportDir,
-				platform.GetEnvWithOsEnv(cliOptions, qdenv.QodanaToken),
-				platform.GetEnvWithOsEnv(cliOptions, qdenv.QodanaLicenseOnlyToken),
-				cliOptions.ClearCache,
-				cliOptions.ProjectDir,
-				cliOptions.ConfigName,
+				options.Linter,
+				options.Ide,
+				options.CacheDir,
+				options.ResultsDir,
+				options.ReportDir,
+				tokenloader.ResolveCloudToken(options.Auth, platform.GetEnvWithOsEnv(options, qdenv.QodanaToken)),
+				platform.GetEnvWithOsEnv(options, qdenv.QodanaLicenseOnlyToken),
+				options.ClearCache,
+				options.ProjectDir,
+				options.ConfigName,
 			)
+			if err := platform.EnforcePolicy(options, commonCtx, qodanaYaml); err != nil {
+				log.Fatal(err)
+			}
+			if err := platform.EnableSignatureVerification(options); err != nil {
+				log.Fatal(err)
+			}
+			platform.PrepareLogDir(commonCtx.LogDir(), qodanaYaml.Logs, options.Resume)
+			if qdenv.IsGithubActions() {
+				platform.MaskGithubSecrets(os.Stdout, commonCtx.QodanaToken, commonCtx.QodanaLicenseOnlyToken)
+				if base := githubDiffStart(ctx, options, commonCtx.ProjectDir, commonCtx.LogDir()); base != "" {
+					effective = effective.With(
+						"diff-start", base, "pull request base", func(o *platformcmd.CliOptions) {
+							o.DiffStart = base
+						},
+					)
+				}
+			}
 			oldReportUrl := cloud.GetReportUrl(commonCtx.ResultsDir)
 			checkProjectDir(commonCtx.ProjectDir)
+			if options.BaselinesDir != "" {
+				baseline := platform.BaselineOfBranch(options.BaselinesDir, commonCtx.ProjectDir, commonCtx.LogDir())
+				if baseline != "" {
+					effective = effective.With(
+						"baseline", baseline, "--baselines-dir", func(o *platformcmd.CliOptions) {
+							o.Baseline = baseline
+						},
+					)
+				}
+			}
 
+			if options.Resume && commonCtx.IsClearCache {
+				msg.WarningMessage("--clear-cache is ignored with --resume, the indexes of the interrupted scan are kept")
+				commonCtx.IsClearCache = false
+			}
+			platform.RecordCacheHit(commonCtx.CacheDir)
 			preparedHost := startup.PrepareHost(commonCtx)
-			scanContext := corescan.CreateContext(*cliOptions, commonCtx, preparedHost, qodanaYaml)
+			if options.FilesFrom != "" {
+				script, err := core.FilesFromScript(options.FilesFrom, commonCtx)
+				if err != nil {
+					log.Fatal(err)
+				}
+				effective = effective.With(
+					"script", script, "--files-from", func(o *platformcmd.CliOptions) {
+						o.Script = script
+					},
+				)
+			}
+			if options.Reproducible && !cmd.Flags().Changed("analysis-id") {
+				analysisId := reproducibleAnalysisId(ctx, commonCtx)
+				effective = effective.With(
+					"analysis-id", analysisId, "--reproducible", func(o *platformcmd.CliOptions) {
+						o.AnalysisId = analysisId
+					},
+				)
+			}
+			if options.ProfileInspections {
+				property := append(options.Property, qdtiming.IdeProperty+"=true")
+				effective = effective.With(
+					"property", "["+strings.Join(property, ",")+"]", "--profile-inspections", func(o *platformcmd.CliOptions) {
+						o.Property = property
+					},
+				)
+			}
+			options = effective.Options()
+			if log.IsLevelEnabled(log.DebugLevel) {
+				var table strings.Builder
+				_ = effective.Write(&table, qdoptions.FormatText, false)
+				log.Debugf("Effective options:\n%s", table.String())
+			}
+			scanContext := corescan.CreateContext(options, commonCtx, preparedHost, qodanaYaml)
+			platform.AuditContext(commonCtx, scanContext.AnalysisId())
+			configSpan.SetAttribute("qodana.linter", scanContext.Linter())
+			configSpan.SetAttribute("qodana.ide", scanContext.Ide())
+			configSpan.End(nil)
+			if options.Resume {
+				if completed := scanContext.Checkpoints().Completed(); len(completed) > 0 {
+					msg.SuccessMessage("Resuming the interrupted scan, completed stages: %s", strings.Join(completed, ", "))
+				} else {
+					msg.WarningMessage("No interrupted scan to resume, starting from scratch")
+				}
+			}
 
+			analyzer := scanContext.Linter()
+			if analyzer == "" {
+				analyzer = scanContext.Ide()
+			}
+			remoteCache, err := platform.NewRemoteCache(
+				options.CacheRemote,
+				options.CacheKey,
+				options.CacheRestoreKeys,
+				analyzer,
+				scanContext.ProjectDir(),
+				scanContext.LogDir(),
+			)
+			if err != nil {
+				log.Fatalf("Failed to set up the remote cache: %s", err)
+			}
+			remoteCache.Restore(scanContext.CacheDir())
+
+			unshareResults(scanContext)
+			stopCancelling := utils.OnInterrupt(func() { platform.CancelRun(scanContext.ResultsDir()) })
 			exitCode := core.RunAnalysis(ctx, scanContext)
+			stopRemovingIgnoreConfig()
+			removeIgnoreConfig()
+			stopRemovingInlineProfile()
+			removeInlineProfile()
+			newCodeSince := qodanaYaml.NewCode.Since
+			if (newCodeSince != "" || severityMapping != nil) && (exitCode == utils.QodanaSuccessExitCode || exitCode == utils.QodanaFailThresholdExitCode) {
+				if newCodeSince != "" {
+					classifyNewCode(ctx, scanContext, newCodeSince)
+				}
+				if severityMapping != nil {
+					applySeverityMapping(scanContext.ResultsDir(), severityMapping)
+				}
+				exitCode = checkThresholds(scanContext.ResultsDir(), failureThresholds(qodanaYaml, options.FailThreshold))
+			}
+			if scope != nil && (exitCode == utils.QodanaSuccessExitCode || exitCode == utils.QodanaFailThresholdExitCode) {
+				exitCode = applyScope(scanContext.ResultsDir(), scope, scopeThresholds(scope, qodanaYaml, options.FailThreshold))
+			}
+			if pathThresholds := qodanaYaml.FailureConditions.PathThresholds; len(pathThresholds) > 0 && exitCode == utils.QodanaSuccessExitCode {
+				exitCode = checkPathThresholds(scanContext.ResultsDir(), pathThresholds)
+			}
+			if threshold := qodanaYaml.FailureConditions.DuplicationThreshold; threshold != nil && exitCode == utils.QodanaSuccessExitCode {
+				exitCode = checkDuplication(scanContext.ResultsDir(), scanContext.ProjectDir(), *threshold)
+			}
+			if options.CodeMetricsEnabled() && (exitCode == utils.QodanaSuccessExitCode || exitCode == utils.QodanaFailThresholdExitCode) {
+				exceeded, err := platform.CollectCodeMetrics(scanContext.ProjectDir(), scanContext.ResultsDir(), metricConditions)
+				if err != nil {
+					log.Fatal(err)
+				}
+				if exceeded {
+					exitCode = utils.QodanaFailThresholdExitCode
+				}
+			}
+			if options.Reproducible && (exitCode == utils.QodanaSuccessExitCode || exitCode == utils.QodanaFailThresholdExitCode) {
+				makeReproducible(scanContext)
+			}
+			if options.RedactionEnabled() {
+				err := platform.RedactResults(
+					options.RedactionRules,
+					scanContext.ProjectDir(),
+					scanContext.ResultsDir(),
+					scanContext.ReportDir(),
+				)
+				if err != nil {
+					log.Fatalf("Failed to redact the results: %s", err)
+				}
+			}
+			if exitCode == utils.QodanaSuccessExitCode || exitCode == utils.QodanaFailThresholdExitCode {
+				remoteCache.Save(scanContext.CacheDir())
+				scanContext.Checkpoints().Clear()
+			}
+			rootSpan.SetAttribute("qodana.exit_code", strconv.Itoa(exitCode))
+			platform.RecordRunMetrics(
+				scanContext.ResultsDir(),
+				scanContext.ProjectDir(),
+				start,
+				exitCode,
+				options.MetricsPushgateway,
+			)
 			if qdenv.IsContainer() {
 				err := platform.ChangePermissionsRecursively(scanContext.ResultsDir())
 				if err != nil {
@@ -79,17 +333,60 @@ But you can always override qodana.yaml options with the following command-line
 			}
 			checkExitCode(exitCode, scanContext)
 			newReportUrl := cloud.GetReportUrl(scanContext.ResultsDir())
-			platform.ProcessSarif(
+			sarifSpan := qdtrace.Start("sarif processing")
+			newProblems := platform.ProcessSarif(
 				filepath.Join(scanContext.ResultsDir(), commoncontext.QodanaSarifName),
 				scanContext.AnalysisId(),
 				newReportUrl,
-				scanContext.PrintProblems(),
+				problemsOutput,
 				scanContext.GenerateCodeClimateReport(),
 				scanContext.SendBitBucketInsights(),
 			)
+			sarifSpan.End(nil)
+			if options.OwnersEnabled() {
+				err := platform.SummarizeOwners(
+					filepath.Join(scanContext.ResultsDir(), commoncontext.QodanaSarifName),
+					options.OwnersFile,
+					scanContext.ProjectDir(),
+				)
+				if err != nil {
+					msg.WarningMessage("Unable to summarize the problems by owner: %s", err)
+				}
+			}
+			if remediation != nil {
+				err := platform.EstimateDebt(filepath.Join(scanContext.ResultsDir(), commoncontext.QodanaSarifName), remediation)
+				if err != nil {
+					msg.WarningMessage("Unable to estimate the remediation time: %s", err)
+				}
+			}
+			if options.ProfileInspections {
+				platform.ReportInspectionTimings(scanContext.ResultsDir())
+			}
+			if qdenv.IsGithubActions() {
+				platform.WriteGithubResults(newProblems, newReportUrl)
+			}
+			reportDir := scanContext.ReportDir()
+			if encryption != nil {
+				encryptResults(encryption, scanContext)
+				reportDir = ""
+			}
+			platform.PublishResults(
+				options.PublishTo,
+				scanContext.ResultsDir(),
+				reportDir,
+				scanContext.AnalysisId(),
+				options.PublishRetention,
+				options.PublishUrlExpiry,
+			)
+			// finish before the report is served, serving lasts until the user stops it
+			stopCancelling()
+			platform.FinishRun(scanContext.ResultsDir(), exitCode)
+			if options.DedupResults {
+				dedupResults(scanContext)
+			}
 
-			showReport := scanContext.ShowReport()
-			if msg.IsInteractive() {
+			showReport := scanContext.ShowReport() && encryption == nil
+			if msg.IsInteractive() && encryption == nil {
 				showReport = msg.AskUserConfirm("Do you want to open the latest report")
 			}
 
@@ -98,8 +395,14 @@ But you can always override qodana.yaml options with the following command-line
 			}
 
 			if showReport {
-				commoncontext.ShowReport(scanContext.ResultsDir(), scanContext.ReportDir(), scanContext.Port())
-			} else if !qdenv.IsContainer() && msg.IsInteractive() {
+				commoncontext.ShowReport(
+					scanContext.ResultsDir(),
+					scanContext.ReportDir(),
+					scanContext.ProjectDir(),
+					scanContext.Port(),
+					options.Serve,
+				)
+			} else if !qdenv.IsContainer() && msg.IsInteractive() && encryption == nil {
 				msg.WarningMessage(
 					"To view the Qodana report later, run %s in the current directory or add %s flag to %s",
 					msg.PrimaryBold("qodana show"),
@@ -111,6 +414,7 @@ But you can always override qodana.yaml options with the following command-line
 			if exitCode == utils.QodanaFailThresholdExitCode {
 				msg.EmptyMessage()
 				msg.ErrorMessage("The number of problems exceeds the fail threshold")
+				qdtemp.Cleanup()
 				os.Exit(exitCode)
 			}
 		},
@@ -120,6 +424,14 @@ But you can always override qodana.yaml options with the following command-line
 	if err != nil {
 		return nil
 	}
+	cmd.Flags().StringVar(
+		&cliOptions.ProjectsFile,
+		"projects-file",
+		"",
+		"Scan the project directories listed in the file, one per line, one after another with the other options. The results of each project are saved to its directory in --results-dir (default ./qodana-results)",
+	)
+	cmd.MarkFlagsMutuallyExclusive("projects-file", "project-dir")
+	cmd.MarkFlagsMutuallyExclusive("projects-file", "show-report")
 
 	return cmd
 }
@@ -139,6 +451,210 @@ func checkProjectDir(projectDir string) {
 	}
 }
 
+// selectScope returns the scopes selected with --scope and the options with their linter and profile,
+// unless they are set explicitly.
+func selectScope(effective qdoptions.EffectiveOptions) (*qdscope.Scope, qdoptions.EffectiveOptions) {
+	options := effective.Options()
+	config, err := qdscope.Load(options.ProjectDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	scope, err := config.Select(options.Scopes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if options.Linter == "" && options.Ide == "" {
+		effective = effective.
+			With("linter", scope.Linter, "--scope", func(o *platformcmd.CliOptions) { o.Linter = scope.Linter }).
+			With("ide", scope.Ide, "--scope", func(o *platformcmd.CliOptions) { o.Ide = scope.Ide })
+	}
+	if options.ProfileName == "" && options.ProfilePath == "" {
+		effective = effective.
+			With("profile-name", scope.Profile.Name, "--scope", func(o *platformcmd.CliOptions) { o.ProfileName = scope.Profile.Name }).
+			With("profile-path", scope.Profile.Path, "--scope", func(o *platformcmd.CliOptions) { o.ProfilePath = scope.Profile.Path })
+	}
+	return &scope, effective
+}
+
+// scopeThresholds returns the thresholds of the scope, falling back to qodana.yaml; --fail-threshold overrides both.
+func scopeThresholds(scope *qdscope.Scope, qodanaYaml qdyaml.QodanaYaml, failThreshold string) map[string]int {
+	if failThreshold == "" && scope.HasThresholds() {
+		return qdscope.Thresholds(scope.FailThreshold, scope.SeverityThresholds)
+	}
+	return failureThresholds(qodanaYaml, failThreshold)
+}
+
+// failureThresholds returns the thresholds of qodana.yaml, --fail-threshold overrides them.
+func failureThresholds(qodanaYaml qdyaml.QodanaYaml, failThreshold string) map[string]int {
+	if failThreshold != "" {
+		threshold, err := strconv.Atoi(failThreshold)
+		if err != nil {
+			log.Fatalf("Invalid --fail-threshold %q: %s", failThreshold, err)
+		}
+		return map[string]int{"any": threshold}
+	}
+	return qdscope.Thresholds(qodanaYaml.FailThreshold, qodanaYaml.FailureConditions.SeverityThresholds)
+}
+
+// applyScope restricts the report to the scope, the exit code of the analysis is replaced by the result of the scope thresholds.
+func applyScope(resultsDir string, scope *qdscope.Scope, thresholds map[string]int) int {
+	exceeded, err := platform.ApplyScope(platform.GetSarifPath(resultsDir), scope, thresholds)
+	if err != nil {
+		log.Fatalf("Failed to apply scope %s: %s", scope.Name, err)
+	}
+	if exceeded {
+		return utils.QodanaFailThresholdExitCode
+	}
+	return utils.QodanaSuccessExitCode
+}
+
+// classifyNewCode marks the problems in the code older than the newCode cutoff, the gates check only the new code.
+func classifyNewCode(ctx context.Context, c corescan.Context, since string) {
+	_, err := platform.ClassifyNewCode(ctx, platform.GetSarifPath(c.ResultsDir()), c.ProjectDir(), since, c.LogDir())
+	if err != nil {
+		log.Fatalf("Failed to find the new code since %s: %s", since, err)
+	}
+}
+
+// applySeverityMapping remaps the severities in the report.
+func applySeverityMapping(resultsDir string, mapping *platform.SeverityMapping) {
+	if _, err := platform.ApplySeverityMapping(platform.GetSarifPath(resultsDir), mapping); err != nil {
+		log.Fatalf("Failed to remap the severities: %s", err)
+	}
+}
+
+// checkThresholds returns the exit code of the analysis checked against the thresholds again,
+// after the report is rewritten by the severity mapping or the new code classification.
+func checkThresholds(resultsDir string, thresholds map[string]int) int {
+	exceeded, err := platform.ThresholdsExceeded(platform.GetSarifPath(resultsDir), thresholds)
+	if err != nil {
+		log.Fatalf("Failed to check the thresholds: %s", err)
+	}
+	if exceeded {
+		return utils.QodanaFailThresholdExitCode
+	}
+	return utils.QodanaSuccessExitCode
+}
+
+// checkPathThresholds returns the exit code of the analysis failed if the problems in the paths exceed their thresholds.
+func checkPathThresholds(resultsDir string, pathThresholds []qdyaml.PathThresholds) int {
+	exceeded, err := platform.PathThresholdsExceeded(platform.GetSarifPath(resultsDir), pathThresholds)
+	if err != nil {
+		log.Fatalf("Failed to check the path thresholds: %s", err)
+	}
+	if exceeded {
+		return utils.QodanaFailThresholdExitCode
+	}
+	return utils.QodanaSuccessExitCode
+}
+
+// checkDuplication fails the run if the duplicated lines exceed the percentage of the lines of the project.
+func checkDuplication(resultsDir string, projectDir string, threshold float64) int {
+	exceeded, err := platform.DuplicationExceeded(platform.GetSarifPath(resultsDir), projectDir, threshold)
+	if err != nil {
+		log.Fatalf("Failed to check the duplication threshold: %s", err)
+	}
+	if exceeded {
+		return utils.QodanaFailThresholdExitCode
+	}
+	return utils.QodanaSuccessExitCode
+}
+
+// reproducibleAnalysisId returns the analysis id of --reproducible scans, derived from the analyzer and the commit.
+func reproducibleAnalysisId(ctx context.Context, commonCtx commoncontext.Context) string {
+	analyzer := commonCtx.Linter
+	if analyzer == "" {
+		analyzer = commonCtx.Ide
+	}
+	revision, err := git.CurrentRevision(ctx, commonCtx.ProjectDir, commonCtx.LogDir())
+	if err != nil {
+		msg.WarningMessage("Failed to get the revision of the project, the analysis id of --reproducible depends only on the linter: %s", err)
+	}
+	return platform.ReproducibleAnalysisId(analyzer, revision)
+}
+
+// makeReproducible rewrites the SARIF report of the scan to be the same for every --reproducible scan of the commit.
+func makeReproducible(c corescan.Context) {
+	err := platform.MakeReproducible(
+		platform.GetSarifPath(c.ResultsDir()),
+		c.AnalysisId(),
+		c.ProjectDir(),
+		core.ContainerProjectDir,
+	)
+	if err != nil {
+		log.Fatalf("Failed to make the report reproducible: %s", err)
+	}
+}
+
+// encryptResults replaces the results, and the report if it's outside them, with their encrypted archives.
+func encryptResults(encryption *qdcrypt.Encryption, c corescan.Context) {
+	dirs := []string{c.ResultsDir()}
+	if rel, err := filepath.Rel(c.ResultsDir(), c.ReportDir()); err != nil || strings.HasPrefix(rel, "..") {
+		dirs = append(dirs, c.ReportDir())
+	}
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		archive, err := platform.EncryptResults(encryption, dir)
+		if err != nil {
+			log.Fatalf("Failed to encrypt %s: %s", dir, err)
+		}
+		msg.SuccessMessage("Results are encrypted to %s", archive)
+	}
+}
+
+// githubDiffStart returns the base commit to make the scan of a GitHub pull request a diff run from,
+// empty if a run scenario is set or the base commit is not fetched.
+// unshareResults gives the results directory deduplicated by a previous run its own copies of the files,
+// so the analysis doesn't write to the files shared with the other runs.
+func unshareResults(c corescan.Context) {
+	if qdenv.IsContainer() {
+		return
+	}
+	copied, err := qdstore.Unshare(qdstore.Dir(c.QodanaSystemDir()), c.ResultsDir())
+	if err != nil {
+		log.Fatalf("Failed to unshare the results in %s: %s", c.ResultsDir(), err)
+	}
+	if copied > 0 {
+		log.Debugf("Unshared %d files of the previous results in %s", copied, c.ResultsDir())
+	}
+}
+
+// dedupResults links the large files of the results to the store shared with the other runs on this machine.
+func dedupResults(c corescan.Context) {
+	if qdenv.IsContainer() {
+		return
+	}
+	stats, err := qdstore.Dedup(qdstore.Dir(c.QodanaSystemDir()), c.ResultsDir())
+	if err != nil {
+		msg.WarningMessage("Unable to deduplicate the results: %s", err)
+		return
+	}
+	if stats.Shared > 0 {
+		msg.SuccessMessage("Deduplicated the results, %s shared with the previous runs", utils.FormatSize(stats.Shared))
+	}
+}
+
+func githubDiffStart(ctx context.Context, options platformcmd.CliOptions, projectDir string, logDir string) string {
+	if options.DiffStart != "" || options.Commit != "" || options.FullHistory || options.Script != "default" || options.FilesFrom != "" {
+		return ""
+	}
+	base := qdenv.GetGithubPullRequestBaseSha()
+	if base == "" {
+		return ""
+	}
+	if !git.RevisionExists(ctx, projectDir, base, logDir) {
+		msg.WarningMessage(
+			"The pull request base commit %s is not fetched, analysing the whole project. Set fetch-depth: 0 for actions/checkout to analyse only the changed files",
+			base,
+		)
+		return ""
+	}
+	log.Debugf("Analysing the changes since the pull request base commit %s", base)
+	return base
+}
+
 func checkExitCode(exitCode int, c corescan.Context) {
 	if exitCode == utils.QodanaEapLicenseExpiredExitCode && msg.IsInteractive() {
 		msg.EmptyMessage()
@@ -146,14 +662,19 @@ func checkExitCode(exitCode int, c corescan.Context) {
 			"Your license expired: update your license or token. If you are using EAP, make sure you are using the latest CLI version and update to the latest linter by running %s ",
 			msg.PrimaryBold("qodana init"),
 		)
-		os.Exit(exitCode)
+		exitWithOutcome(c, exitCode, exitCode)
 	} else if exitCode == utils.QodanaTimeoutExitCodePlaceholder {
-		msg.ErrorMessage("Qodana analysis reached timeout %s", c.GetAnalysisTimeout())
-		os.Exit(c.AnalysisTimeoutExitCode())
+		if timeoutErr, ok := qdtrace.TimedOut(); ok {
+			msg.ErrorMessage("Qodana %s", timeoutErr)
+		} else {
+			msg.ErrorMessage("Qodana analysis reached timeout %s", c.GetAnalysisTimeout())
+		}
+		exitWithOutcome(c, exitCode, c.AnalysisTimeoutExitCode())
 	} else if exitCode != utils.QodanaSuccessExitCode && exitCode != utils.QodanaFailThresholdExitCode {
 		msg.ErrorMessage("Qodana exited with code %d", exitCode)
 		msg.WarningMessage("Check ./logs/ in the results directory for more information")
 		if exitCode == utils.QodanaOutOfMemoryExitCode {
+			core.DiagnoseOutOfMemory(c)
 			core.CheckContainerEngineMemory()
 		} else if msg.AskUserConfirm(fmt.Sprintf("Do you want to open %s", c.ResultsDir())) {
 			err := core.OpenDir(c.ResultsDir())
@@ -161,6 +682,88 @@ func checkExitCode(exitCode int, c corescan.Context) {
 				log.Fatalf("Error while opening directory: %s", err)
 			}
 		}
-		os.Exit(exitCode)
+		exitWithOutcome(c, exitCode, exitCode)
+	}
+}
+
+// exitWithOutcome writes the run outcome before exiting, as deferred functions are not run by os.Exit.
+func exitWithOutcome(c corescan.Context, exitCode int, code int) {
+	platform.FinishRun(c.ResultsDir(), exitCode)
+	qdtemp.Cleanup()
+	os.Exit(code)
+}
+
+// scanProjects scans the projects listed in --projects-file one after another with the other options of the command,
+// each into its own directory of --results-dir, prints the summary of the scans and returns the exit code of the batch.
+func scanProjects(cmd *cobra.Command, cliOptions *platformcmd.CliOptions) int {
+	projects, err := qdbatch.ReadProjects(cliOptions.ProjectsFile)
+	if err != nil {
+		log.Fatalf("Failed to read the projects to scan: %s", err)
+	}
+	if len(projects) == 0 {
+		log.Fatalf("No projects to scan listed in %s", cliOptions.ProjectsFile)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to find the qodana executable: %s", err)
+	}
+	resultsDir := cliOptions.ResultsDir
+	if resultsDir == "" {
+		resultsDir = "qodana-results"
+	}
+	args := qdbatch.Args(cmd.Flags(), "projects-file", "results-dir", "cache-dir", "report-dir", "skip-pull")
+	// the same image is pulled once for all the projects
+	pullOnce := cliOptions.Linter != ""
+
+	var results []qdbatch.Result
+	for i, project := range projects {
+		projectResultsDir := filepath.Join(resultsDir, project.Name)
+		var scanArgs []string
+		if cliOptions.CacheDir != "" {
+			// the cache of each project is <cache-dir>/<project>/cache, the JDKs and the plugins in <cache-dir> are shared
+			scanArgs = append(scanArgs, "--cache-dir", filepath.Join(cliOptions.CacheDir, project.Name, "cache"))
+		}
+		if cliOptions.ReportDir != "" {
+			scanArgs = append(scanArgs, "--report-dir", filepath.Join(cliOptions.ReportDir, project.Name))
+		}
+		if cliOptions.SkipPull || (pullOnce && i > 0) {
+			scanArgs = append(scanArgs, "--skip-pull")
+		}
+		msg.SuccessMessage("Scanning %s (%d/%d)", msg.PrimaryBold(project.Dir), i+1, len(projects))
+		results = append(results, runProjectScan(cmd.Context(), executable, project, projectResultsDir, append(scanArgs, args...)))
+	}
+	qdbatch.PrintSummary(results)
+	return qdbatch.ExitCode(results)
+}
+
+// runProjectScan runs qodana scan of the project with the arguments and collects its result from resultsDir.
+func runProjectScan(ctx context.Context, executable string, project qdbatch.Project, resultsDir string, args []string) qdbatch.Result {
+	result := qdbatch.Result{Project: project, ResultsDir: resultsDir, Problems: -1}
+	scan := utils.CommandContext(
+		ctx,
+		executable,
+		append([]string{"scan", "--project-dir", project.Dir, "--results-dir", resultsDir}, args...)...,
+	)
+	scan.Stdin, scan.Stdout, scan.Stderr = os.Stdin, os.Stdout, os.Stderr
+	scan.Env = append(os.Environ(), "NONINTERACTIVE=1")
+	platform.ClearOutcome(resultsDir)
+	start := time.Now()
+	if err := scan.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			log.Fatalf("Failed to run qodana scan: %s", err)
+		}
+		result.ExitCode = exitErr.ExitCode()
+	}
+	result.Duration = time.Since(start)
+	if outcome, err := platform.ReadOutcome(resultsDir); err == nil {
+		result.TimedOut = outcome.TimedOut
+	}
+	if result.ExitCode == utils.QodanaSuccessExitCode || result.ExitCode == utils.QodanaFailThresholdExitCode {
+		if problems, err := platform.CountNewProblems(resultsDir); err == nil {
+			result.SetProblems(problems)
+		}
+		result.ReportUrl = cloud.GetReportUrl(resultsDir)
 	}
+	return result
 }
@@ -0,0 +1,268 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdschedule"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// scheduleOptions represents schedule command options.
+type scheduleOptions struct {
+	Dir        string
+	ProjectDir string
+	Interval   string
+	At         string
+	Notify     string
+	Backend    string
+}
+
+// newScheduleCommand returns a new instance of the schedule command.
+func newScheduleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Run scans of local projects periodically",
+		Long: `Register periodic scans of projects without CI coverage with the scheduler of the system:
+systemd user timers (or cron) on Linux, LaunchAgents on macOS and Task Scheduler on Windows.
+
+The results and the output of the last scan of a schedule are kept in its directory of --dir.`,
+	}
+	cmd.AddCommand(newScheduleAddCommand(), newScheduleRemoveCommand(), newScheduleListCommand(), newScheduleRunCommand())
+	return cmd
+}
+
+// newScheduleAddCommand returns a new instance of the schedule add command.
+func newScheduleAddCommand() *cobra.Command {
+	options := &scheduleOptions{}
+	cmd := &cobra.Command{
+		Use:   "add <name> [-- <scan options>]",
+		Short: "Schedule periodic scans of a project",
+		Long: `Schedule periodic scans of a project, the options after -- are passed to qodana scan, e.g.
+
+  qodana schedule add nightly -i ~/work/service --at 02:30 --notify 'notify-send "Qodana: $QODANA_EXIT_CODE"' -- --linter jetbrains/qodana-jvm
+
+The --notify command runs after every scan with QODANA_SCHEDULE, QODANA_PROJECT_DIR, QODANA_EXIT_CODE,
+QODANA_RESULTS_DIR and QODANA_SCAN_LOG set. An existing schedule with the same name is replaced.`,
+		Args: scheduleNameArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			hour, minute, err := qdschedule.ParseTime(options.At)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err = qdschedule.ValidateBackend(options.Backend); err != nil {
+				log.Fatal(err)
+			}
+			projectDir, err := filepath.Abs(options.ProjectDir)
+			if err != nil {
+				log.Fatal(err)
+			}
+			schedule := qdschedule.Schedule{
+				Name:       args[0],
+				ProjectDir: projectDir,
+				Interval:   options.Interval,
+				Hour:       hour,
+				Minute:     minute,
+				Args:       args[1:],
+				Notify:     options.Notify,
+				Backend:    options.Backend,
+			}
+			if err = schedule.Validate(); err != nil {
+				log.Fatal(err)
+			}
+			executable, err := os.Executable()
+			if err != nil {
+				log.Fatalf("Failed to find the qodana executable: %s", err)
+			}
+			registry := qdschedule.Registry{Dir: options.Dir}
+			if previous, ok, err := registry.Get(schedule.Name); err == nil && ok && previous.Backend != schedule.Backend {
+				_ = qdschedule.Uninstall(previous)
+			}
+			command := []string{executable, "schedule", "run", schedule.Name, "--dir", options.Dir}
+			if err = qdschedule.Install(schedule, command); err != nil {
+				log.Fatalf("Failed to register the schedule with %s: %s", schedule.Backend, err)
+			}
+			if err = registry.Put(schedule); err != nil {
+				log.Fatalf("Failed to save the schedule: %s", err)
+			}
+			msg.SuccessMessage("Scheduled %s scans of %s %s with %s", schedule.Name, projectDir, schedule.When(), schedule.Backend)
+		},
+	}
+	flags := cmd.Flags()
+	options.addDirFlag(cmd)
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVar(&options.Interval, "interval", qdschedule.Daily, "How often to scan: hourly, daily or weekly (on Mondays)")
+	flags.StringVar(&options.At, "at", "02:00", "Local time of the scans as HH:MM, only the minutes are used for hourly scans")
+	flags.StringVar(&options.Notify, "notify", "", "Shell command to run after every scan, e.g. to send a notification")
+	flags.StringVar(&options.Backend, "scheduler", qdschedule.DefaultBackend(), "Scheduler to register the scans with: cron, systemd, launchd or schtasks")
+	return cmd
+}
+
+// newScheduleRemoveCommand returns a new instance of the schedule remove command.
+func newScheduleRemoveCommand() *cobra.Command {
+	options := &scheduleOptions{}
+	cmd := &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove a schedule added with qodana schedule add",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			registry := qdschedule.Registry{Dir: options.Dir}
+			schedule, ok, err := registry.Get(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			if !ok {
+				msg.WarningMessage("No schedule named %s", args[0])
+				return
+			}
+			if err = qdschedule.Uninstall(schedule); err != nil {
+				log.Fatalf("Failed to unregister the schedule from %s: %s", schedule.Backend, err)
+			}
+			if err = registry.Remove(schedule.Name); err != nil {
+				log.Fatalf("Failed to remove the schedule: %s", err)
+			}
+			_ = os.RemoveAll(registry.ScanDir(schedule.Name))
+			msg.SuccessMessage("Removed the schedule %s", schedule.Name)
+		},
+	}
+	options.addDirFlag(cmd)
+	return cmd
+}
+
+// newScheduleListCommand returns a new instance of the schedule list command.
+func newScheduleListCommand() *cobra.Command {
+	options := &scheduleOptions{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the schedules added with qodana schedule add",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			schedules, err := qdschedule.Registry{Dir: options.Dir}.List()
+			if err != nil {
+				log.Fatal(err)
+			}
+			if len(schedules) == 0 {
+				msg.WarningMessage("No scans are scheduled, add one with %s", msg.PrimaryBold("qodana schedule add"))
+				return
+			}
+			tableData := pterm.TableData{
+				[]string{msg.PrimaryBold("Name"), msg.PrimaryBold("When"), msg.PrimaryBold("Project"), msg.PrimaryBold("Scheduler")},
+			}
+			for _, s := range schedules {
+				tableData = append(tableData, []string{s.Name, s.When(), s.ProjectDir, s.Backend})
+			}
+			if err = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render(); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	options.addDirFlag(cmd)
+	return cmd
+}
+
+// newScheduleRunCommand returns a new instance of the schedule run command.
+func newScheduleRunCommand() *cobra.Command {
+	options := &scheduleOptions{}
+	cmd := &cobra.Command{
+		Use:    "run <name>",
+		Short:  "Run the scan of a schedule, called by the scheduler",
+		Args:   cobra.ExactArgs(1),
+		Hidden: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			registry := qdschedule.Registry{Dir: options.Dir}
+			schedule, ok, err := registry.Get(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			if !ok {
+				log.Fatalf("No schedule named %s", args[0])
+			}
+			os.Exit(runSchedule(registry, schedule))
+		},
+	}
+	options.addDirFlag(cmd)
+	return cmd
+}
+
+func (o *scheduleOptions) addDirFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.Dir, "dir", defaultScheduleDir(), "Directory to keep the schedules and the results of their scans in")
+}
+
+// scheduleNameArgs accepts the schedule name followed by the scan options after --.
+func scheduleNameArgs(cmd *cobra.Command, args []string) error {
+	dash := cmd.ArgsLenAtDash()
+	if len(args) == 0 || dash == 0 {
+		return errors.New("the schedule name is required")
+	}
+	if dash > 1 || (dash == -1 && len(args) > 1) {
+		return fmt.Errorf("unexpected arguments %v, pass the scan options after --", args[1:])
+	}
+	return nil
+}
+
+// runSchedule runs the scan of the schedule, then its notification command, and returns the exit code of the scan.
+func runSchedule(registry qdschedule.Registry, schedule qdschedule.Schedule) int {
+	scanDir := registry.ScanDir(schedule.Name)
+	resultsDir := filepath.Join(scanDir, "results")
+	logFile := filepath.Join(scanDir, "scan.log")
+	if err := os.MkdirAll(scanDir, os.ModePerm); err != nil {
+		log.Fatal(err)
+	}
+	out, err := os.Create(logFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(out *os.File) {
+		_ = out.Close()
+	}(out)
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	scan := exec.Command(
+		executable,
+		append([]string{"scan", "--project-dir", schedule.ProjectDir, "--results-dir", resultsDir}, schedule.Args...)...,
+	)
+	scan.Stdout, scan.Stderr = out, out
+	exitCode := 0
+	if err = scan.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			log.Fatalf("Failed to run qodana scan: %s", err)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+	if err = qdschedule.Notify(schedule, exitCode, resultsDir, logFile); err != nil {
+		log.Errorf("The notification command of %s failed: %s", schedule.Name, err)
+	}
+	return exitCode
+}
+
+func defaultScheduleDir() string {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		userCacheDir = os.TempDir()
+	}
+	return filepath.Join(userCacheDir, "JetBrains", "Qodana", "schedule")
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdsign"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdupdate"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/platform/version"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// selfUpdateOptions represents self-update command options.
+type selfUpdateOptions struct {
+	Channel          string
+	ToRequired       bool
+	ProjectDir       string
+	ConfigName       string
+	Force            bool
+	VerifySignatures bool
+	TrustedKeys      string
+}
+
+// packageManagers are the path parts of the binaries installed with a package manager, updated with it instead.
+var packageManagers = map[string]string{
+	"/Cellar/":        "brew upgrade qodana",
+	"/linuxbrew/":     "brew upgrade qodana",
+	`\scoop\`:         "scoop update qodana",
+	`\chocolatey\`:    "choco upgrade qodana",
+	`\WinGet\`:        "winget upgrade -e --id JetBrains.QodanaCLI",
+	"/usr/bin/qodana": "the package manager of the system",
+}
+
+// newSelfUpdateCommand returns a new instance of the self-update command.
+func newSelfUpdateCommand() *cobra.Command {
+	options := &selfUpdateOptions{}
+	cmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Update the Qodana CLI to the latest release",
+		Long: `Download the binary of the latest release of the channel (or, with --to-required, of the newest release satisfying
+cliVersion of qodana.yaml) for the current OS and architecture, verify it with the checksums of the release (and their
+signature with --verify-signatures), and replace the running binary with it.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			executable, err := os.Executable()
+			if err == nil {
+				executable, err = filepath.EvalSymlinks(executable)
+			}
+			if err != nil {
+				log.Fatalf("Failed to find the Qodana CLI binary: %s", err)
+			}
+			for part, command := range packageManagers {
+				if strings.Contains(executable, part) {
+					log.Fatalf("%s is installed with a package manager, update it with %s", executable, command)
+				}
+			}
+			if options.VerifySignatures {
+				keys := options.TrustedKeys
+				if keys == "" {
+					keys = platform.TrustedKeysPath()
+				}
+				verifier, err := qdsign.LoadVerifier(keys)
+				if err != nil {
+					log.Fatal(err)
+				}
+				qdsign.Enable(verifier)
+			}
+
+			updater := qdupdate.NewUpdater()
+			var release qdupdate.Release
+			if options.ToRequired {
+				qodanaYaml := qdyaml.LoadQodanaYaml(options.ProjectDir, options.ConfigName)
+				if qodanaYaml.CliVersion == "" {
+					log.Fatalf("No cliVersion is set in %s", qdyaml.GetQodanaYamlPathWithProject(options.ProjectDir, options.ConfigName))
+				}
+				constraint, err := qdupdate.ParseConstraint(qodanaYaml.CliVersion)
+				if err != nil {
+					log.Fatalf("Invalid cliVersion: %s", err)
+				}
+				if constraint.Match(version.Version) && !options.Force {
+					msg.SuccessMessage("Qodana CLI %s satisfies cliVersion %s", version.Version, constraint)
+					return
+				}
+				if release, err = updater.Required(constraint); err != nil {
+					log.Fatalf("Failed to find the required release: %s", err)
+				}
+			} else {
+				if release, err = updater.Latest(options.Channel); err != nil {
+					log.Fatalf("Failed to find the latest release: %s", err)
+				}
+				if release.Version() == version.Version && !options.Force {
+					msg.SuccessMessage("Qodana CLI %s is the latest %s release", version.Version, options.Channel)
+					return
+				}
+			}
+			downloaded, err := updater.Download(release, executable)
+			if err != nil {
+				log.Fatalf("Failed to download Qodana CLI %s: %s", release.Version(), err)
+			}
+			if err = qdupdate.Replace(executable, downloaded); err != nil {
+				_ = os.Remove(downloaded)
+				log.Fatalf("Failed to replace %s: %s", executable, err)
+			}
+			msg.SuccessMessage("Updated Qodana CLI from %s to %s", version.Version, release.Version())
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(
+		&options.Channel,
+		"channel",
+		qdupdate.ChannelStable,
+		fmt.Sprintf("Release channel to update from: %s", strings.Join(qdupdate.Channels, " or ")),
+	)
+	flags.BoolVar(
+		&options.ToRequired,
+		"to-required",
+		false,
+		"Update to the newest release satisfying cliVersion of qodana.yaml of the project instead of the latest one of the channel",
+	)
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the project with qodana.yaml, for --to-required")
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml', for --to-required",
+	)
+	flags.BoolVar(&options.Force, "force", false, "Download the release even if it is the current version")
+	flags.BoolVar(
+		&options.VerifySignatures,
+		"verify-signatures",
+		false,
+		"Verify the minisign or cosign signature of the checksums of the release",
+	)
+	flags.StringVar(
+		&options.TrustedKeys,
+		"trusted-keys",
+		"",
+		"File with the minisign and PEM-encoded cosign public keys the signature is verified with (default trusted-keys.pub in the Qodana user config directory)",
+	)
+	cmd.MarkFlagsMutuallyExclusive("to-required", "channel")
+	_ = cmd.RegisterFlagCompletionFunc(
+		"channel", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+			return qdupdate.Channels, cobra.ShellCompDirectiveNoFileComp
+		},
+	)
+	return cmd
+}
@@ -18,6 +18,7 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/cloud"
 	"github.com/JetBrains/qodana-cli/v2024/platform"
 	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
 	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
@@ -73,11 +74,15 @@ If you are using other Qodana Cloud instance than https://qodana.cloud/, overrid
 				java = "java"
 			}
 			platform.SendReport(
+				cmd.Context(),
 				publisher,
 				tokenloader.ValidateToken(commonCtx, false),
 				publisherPath,
 				java,
 			)
+			if reportUrl := cloud.GetReportUrl(commonCtx.ResultsDir); reportUrl != "" {
+				msg.PorcelainLine("report-url", reportUrl)
+			}
 		},
 	}
 	flags := cmd.Flags()
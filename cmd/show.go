@@ -18,8 +18,10 @@ package cmd
 
 import (
 	"github.com/JetBrains/qodana-cli/v2024/core"
+	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
 	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdreport"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"os"
@@ -36,7 +38,15 @@ func newShowCommand() *cobra.Command {
 Due to JavaScript security restrictions, the generated report cannot
 be viewed via the file:// protocol (by double-clicking the index.html file).
 https://www.jetbrains.com/help/qodana/html-report.html
-This command serves the Qodana report locally and opens a browser to it.`,
+This command serves the Qodana report locally and opens a browser to it.
+To expose the report on a shared machine, restrict the access with --serve-token or --serve-basic-auth
+and serve it over HTTPS with --serve-tls-cert and --serve-tls-key.
+
+The results are also available as JSON under /api/v1/ for custom dashboards and IDE plugins:
+  GET /api/v1/problems?severity=&rule=&file=&baselineState=&q=&page=&pageSize=  problems matching the filters
+  GET /api/v1/problems/<id>                                                    a single problem
+  GET /api/v1/problems/<id>/snippet?context=                                   source code around the problem
+  GET /api/v1/severities?baselineState=                                        number of problems by severity`,
 		Run: func(cmd *cobra.Command, args []string) {
 			commonCtx := commoncontext.Compute(
 				cliOptions.Linter,
@@ -59,7 +69,9 @@ This command serves the Qodana report locally and opens a browser to it.`,
 				commoncontext.ShowReport(
 					commonCtx.ResultsDir,
 					commonCtx.ReportDir,
+					commonCtx.ProjectDir,
 					cliOptions.Port,
+					cliOptions.Serve,
 				)
 			}
 		},
@@ -82,6 +94,7 @@ This command serves the Qodana report locally and opens a browser to it.`,
 		"Override directory to save Qodana HTML report to (default <userCacheDir>/JetBrains/<linter>/results/report)",
 	)
 	flags.IntVarP(&cliOptions.Port, "port", "p", 8080, "Specify port to serve report at")
+	platformcmd.AddServeFlags(flags, &cliOptions.Serve)
 	flags.BoolVarP(&cliOptions.OpenDir, "dir-only", "d", false, "Open report directory only, don't serve it")
 	flags.StringVar(
 		&cliOptions.ConfigName,
@@ -98,6 +111,7 @@ type showOptions struct {
 	ResultsDir string
 	ReportDir  string
 	Port       int
+	Serve      qdreport.ServeOptions
 	OpenDir    bool
 	ConfigName string
 }
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/core"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+// supportBundleOptions represents support-bundle command options.
+type supportBundleOptions struct {
+	Linter     string
+	Ide        string
+	ProjectDir string
+	ConfigName string
+	ResultsDir string
+	OutputFile string
+	Yes        bool
+}
+
+// newSupportBundleCommand returns a new instance of the support-bundle command.
+func newSupportBundleCommand() *cobra.Command {
+	options := &supportBundleOptions{}
+	cmd := &cobra.Command{
+		Use:   "support-bundle",
+		Short: "Collect logs and configuration for a support ticket",
+		Long: `Assemble the logs of the last Qodana run, the effective configuration, the sanitized options and environment,
+the Qodana container inspect output and IDE logs into a single zip archive to attach to a support ticket.
+
+Tokens, passwords and other secrets are redacted. The list of the included files is shown for confirmation before the archive is written.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			commonCtx := commoncontext.Compute(
+				options.Linter,
+				options.Ide,
+				"",
+				options.ResultsDir,
+				"",
+				os.Getenv(qdenv.QodanaToken),
+				os.Getenv(qdenv.QodanaLicenseOnlyToken),
+				false,
+				options.ProjectDir,
+				options.ConfigName,
+			)
+			entries := core.CollectSupportBundle(commonCtx, options.ConfigName)
+
+			msg.SuccessMessage("The support bundle will include:")
+			for _, entry := range entries {
+				fmt.Printf("    %s (%d bytes): %s\n", msg.PrimaryBold(entry.Name), len(entry.Content), entry.Description)
+			}
+			if !options.Yes && msg.IsInteractive() && !msg.AskUserConfirm("Create the support bundle with the files above") {
+				return
+			}
+
+			outputFile := options.OutputFile
+			if outputFile == "" {
+				outputFile = fmt.Sprintf("qodana-support-bundle-%s.zip", time.Now().Format("20060102-150405"))
+			}
+			if err := core.WriteSupportBundle(outputFile, entries); err != nil {
+				log.Fatalf("Failed to write the support bundle: %s", err)
+			}
+			msg.SuccessMessage("Support bundle is saved to %s", msg.PrimaryBold(outputFile))
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&options.Linter, "linter", "l", "", "Override linter to use")
+	flags.StringVar(&options.Ide, "ide", os.Getenv(qdenv.QodanaDistEnv), "Collect the data of the native run with the given IDE")
+	flags.StringVarP(&options.ProjectDir, "project-dir", "i", ".", "Root directory of the inspected project")
+	flags.StringVar(
+		&options.ConfigName,
+		"config",
+		"",
+		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
+	)
+	flags.StringVarP(&options.ResultsDir, "results-dir", "o", "", "Override directory with Qodana inspection results")
+	flags.StringVarP(&options.OutputFile, "output", "f", "", "Path to the support bundle zip (default qodana-support-bundle-<timestamp>.zip)")
+	flags.BoolVarP(&options.Yes, "yes", "y", false, "Do not ask for confirmation")
+	cmd.MarkFlagsMutuallyExclusive("linter", "ide")
+	return cmd
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/cloud"
+	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/thirdpartyscan"
+	"github.com/JetBrains/qodana-cli/v2024/platform/version"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// telemetryOptions represents telemetry command options.
+type telemetryOptions struct {
+	Linter      string
+	Ide         string
+	NoTelemetry bool
+}
+
+// newTelemetryCommand returns a new instance of the telemetry command.
+func newTelemetryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "telemetry",
+		Short: "Inspect anonymous usage statistics",
+		Long: fmt.Sprintf(
+			`Qodana collects anonymous usage statistics to improve the product.
+Disable them with the --no-telemetry flag of qodana scan or by setting %s=%s, the setting is passed into the Qodana container as well.`,
+			qdenv.QodanaTelemetryEnv,
+			qdenv.TelemetryOff,
+		),
+	}
+	cmd.AddCommand(newTelemetryStatusCommand(), newTelemetryPreviewCommand())
+	return cmd
+}
+
+// newTelemetryStatusCommand returns a new instance of the telemetry status command.
+func newTelemetryStatusCommand() *cobra.Command {
+	options := &telemetryOptions{}
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show whether anonymous usage statistics are sent",
+		Run: func(cmd *cobra.Command, args []string) {
+			preview := options.preview()
+			if preview.Enabled {
+				msg.SuccessMessage("Anonymous usage statistics are enabled")
+			} else {
+				msg.SuccessMessage("Anonymous usage statistics are %s", preview.DisabledReason)
+			}
+		},
+	}
+	options.addFlags(cmd)
+	return cmd
+}
+
+// newTelemetryPreviewCommand returns a new instance of the telemetry preview command.
+func newTelemetryPreviewCommand() *cobra.Command {
+	options := &telemetryOptions{}
+	cmd := &cobra.Command{
+		Use:   "preview",
+		Short: "Print the anonymous usage statistics that would be sent",
+		Long: `Print as JSON exactly what would be sent by the run with the given linter:
+the statistics properties passed to the IDE-based linters and the events sent by the CLI for third-party linters.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			out, err := json.MarshalIndent(options.preview(), "", "  ")
+			if err != nil {
+				log.Fatalf("Failed to marshal telemetry preview: %s", err)
+			}
+			if _, err = fmt.Fprintln(cmd.OutOrStdout(), string(out)); err != nil {
+				log.Fatalf("Failed to write to stdout: %s", err)
+			}
+		},
+	}
+	options.addFlags(cmd)
+	return cmd
+}
+
+func (o *telemetryOptions) addFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.StringVarP(&o.Linter, "linter", "l", "", "Linter to preview the statistics for")
+	flags.StringVar(&o.Ide, "ide", os.Getenv(qdenv.QodanaDistEnv), "IDE to preview the statistics for")
+	flags.BoolVar(&o.NoTelemetry, "no-telemetry", qdenv.IsTelemetryDisabled(), "Preview with the statistics disabled")
+	cmd.MarkFlagsMutuallyExclusive("linter", "ide")
+}
+
+func (o *telemetryOptions) preview() platform.TelemetryPreview {
+	cloud.SetupLicenseToken(os.Getenv(qdenv.QodanaToken))
+	linterInfo := thirdpartyscan.LinterInfo{
+		ProductCode:   product.GuessProductCode(o.Ide, o.Linter),
+		LinterVersion: version.Version,
+	}
+	return platform.GetTelemetryPreview(linterInfo, os.Getenv(qdenv.QodanaProjectIdHash), o.NoTelemetry)
+}
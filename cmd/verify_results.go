@@ -0,0 +1,103 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdmanifest"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdsign"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+// newVerifyResultsCommand returns a new instance of the verify-results command.
+func newVerifyResultsCommand() *cobra.Command {
+	trustedKeys := ""
+	cmd := &cobra.Command{
+		Use:   "verify-results [results-dir]",
+		Short: "Check the results directory against its checksum manifest",
+		Long: `Check that the files of the results directory are the same as when qodana scan --results-manifest wrote
+` + qdmanifest.FileName + `: none of them is modified, removed or added.
+
+If the manifest is signed, its signature is verified with the public keys of --trusted-keys.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			verifyManifestSignature(dir, trustedKeys)
+			check, err := qdmanifest.Verify(dir)
+			if err != nil {
+				log.Fatalf("Failed to check %s: %s", dir, err)
+			}
+			for _, path := range check.Modified {
+				msg.ErrorMessage("Modified: %s", path)
+			}
+			for _, path := range check.Missing {
+				msg.ErrorMessage("Missing: %s", path)
+			}
+			for _, path := range check.Added {
+				msg.ErrorMessage("Added: %s", path)
+			}
+			if !check.Ok() {
+				log.Fatalf(
+					"%s doesn't match its manifest: %d files modified, %d missing, %d added",
+					dir,
+					len(check.Modified),
+					len(check.Missing),
+					len(check.Added),
+				)
+			}
+			msg.SuccessMessage("All %d files of %s match the manifest", check.Files, dir)
+		},
+	}
+	cmd.Flags().StringVar(
+		&trustedKeys,
+		"trusted-keys",
+		"",
+		"File with the public keys to verify the signature of the manifest with (default trusted-keys.pub in the Qodana user config directory)",
+	)
+	return cmd
+}
+
+// verifyManifestSignature verifies the signature of the manifest if it is signed.
+func verifyManifestSignature(dir string, trustedKeys string) {
+	manifest := filepath.Join(dir, qdmanifest.FileName)
+	if _, err := os.Stat(filepath.Join(dir, qdmanifest.SignatureName)); errors.Is(err, os.ErrNotExist) {
+		if trustedKeys != "" {
+			log.Fatalf("%s is not signed", manifest)
+		}
+		msg.WarningMessage("%s is not signed, only the checksums are checked", manifest)
+		return
+	}
+	if trustedKeys == "" {
+		trustedKeys = platform.TrustedKeysPath()
+	}
+	verifier, err := qdsign.LoadVerifier(trustedKeys)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = verifier.VerifyFile(manifest); err != nil {
+		log.Fatal(err)
+	}
+	msg.SuccessMessage("The signature of %s is valid", manifest)
+}
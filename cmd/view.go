@@ -18,13 +18,18 @@ package cmd
 
 import (
 	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
 	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 // viewOptions represents view command options.
 type viewOptions struct {
-	SarifFile string
+	SarifFile          string
+	ProjectDir         string
+	ProblemsFormat     string
+	ProblemsSeverities []string
 }
 
 // newViewCommand returns a new instance of the show command.
@@ -35,10 +40,26 @@ func newViewCommand() *cobra.Command {
 		Short: "View SARIF files in CLI",
 		Long:  `Preview all problems found in SARIF files in CLI.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			platform.ProcessSarif(options.SarifFile, "", "", true, false, false)
+			problemsOutput := &platform.ProblemsOutput{
+				Format:     options.ProblemsFormat,
+				Severities: options.ProblemsSeverities,
+				ProjectDir: options.ProjectDir,
+			}
+			if err := problemsOutput.Validate(); err != nil {
+				log.Fatal(err)
+			}
+			platform.ProcessSarif(options.SarifFile, "", "", problemsOutput, false, false)
 		},
 	}
 	flags := cmd.Flags()
 	flags.StringVarP(&options.SarifFile, "sarif-file", "f", commoncontext.QodanaSarifName, "Path to the SARIF file")
+	flags.StringVarP(
+		&options.ProjectDir,
+		"project-dir",
+		"i",
+		".",
+		"Root directory of the inspected project to read the code of the problems from if the SARIF file has no code snippets",
+	)
+	platformcmd.AddProblemsFlags(flags, &options.ProblemsFormat, &options.ProblemsSeverities)
 	return cmd
 }
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/core/startup"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdandroid"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// provisionAndroidSdk installs the SDK platforms and build tools required by the Gradle modules, missing
+// in the SDK of the environment, into the SDK in the cache, so the Gradle import of the Android project doesn't fail.
+func provisionAndroidSdk(c corescan.Context) {
+	if !c.ProvisionAndroidSdk() || (c.Prod().Code != product.QDAND && c.Prod().Code != product.QDANDC) {
+		return
+	}
+	packages := qdandroid.Detect(c.ProjectDir())
+	base := qdandroid.EnvironmentSdk()
+	missing := qdandroid.Missing(base, packages)
+	if len(missing) == 0 {
+		return
+	}
+	if err := os.MkdirAll(c.LogDir(), os.ModePerm); err != nil {
+		log.Fatal(err)
+	}
+	logPath := filepath.Join(c.LogDir(), "android-sdk.log")
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(logFile)
+
+	sdk := qdandroid.NewSdk(c.CacheDir(), base)
+	msg.PrintProcess(
+		func(_ *pterm.SpinnerPrinter) {
+			err = sdk.Install(missing, logFile)
+		},
+		fmt.Sprintf("Installing Android SDK packages %s", msg.PrimaryBold(strings.Join(missing, ", "))),
+		fmt.Sprintf("installing Android SDK packages to %s, see %s", sdk.Root, logPath),
+	)
+	if err != nil {
+		msg.WarningMessage("Failed to install Android SDK packages %s, see %s: %s", strings.Join(missing, ", "), logPath, err)
+		return
+	}
+	for _, env := range []string{qdenv.AndroidHome, qdenv.AndroidSdkRoot} {
+		if err = os.Setenv(env, sdk.Root); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if qdenv.IsContainer() {
+		startup.SetAndroidSdk(c.ConfigDir(), sdk.Root)
+	}
+	msg.SuccessMessage("Using Android SDK %s with %s", sdk.Root, strings.Join(missing, ", "))
+}
@@ -20,12 +20,18 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/platform"
 	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
 	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcheckpoint"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdcontainer"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdmetrics"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdplugin"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	"github.com/JetBrains/qodana-cli/v2024/platform/version"
 	"github.com/docker/docker/api/types/backend"
@@ -38,6 +44,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	cliconfig "github.com/docker/cli/cli/config"
 
@@ -53,6 +60,8 @@ const (
 	officialImagePrefix      = "jetbrains/qodana"
 	dockerSpecialCharsLength = 8
 	containerJvmDebugPort    = "5005"
+	// ContainerProjectDir is the directory the project is mounted to in the linter container.
+	ContainerProjectDir = "/data/project"
 )
 
 var (
@@ -77,25 +86,68 @@ func runQodanaContainer(ctx context.Context, c corescan.Context) int {
 		return 1
 	}
 	fixDarwinCaches(c.CacheDir())
+	warnIfHeapExceedsMemory(vmOptions(c), info.MemTotal)
 
 	scanStages := getScanStages()
 
-	if c.SkipPull() {
+	pullSpan := qdtrace.Start("image pull")
+	pullSpan.SetAttribute("qodana.linter", c.Linter())
+	resumedPull := c.Checkpoints().Done(qdcheckpoint.ImagePull, c.Linter()) && imageExists(ctx, docker, c.Linter())
+	if c.SkipPull() || resumedPull {
+		if resumedPull {
+			msg.SuccessMessage("Skipping the pull of %s completed by the interrupted scan", c.Linter())
+		}
 		checkImage(c.Linter())
+		pullSpan.SetAttribute("qodana.skip_pull", "true")
 	} else {
-		PullImage(docker, c.Linter())
+		pullStart := time.Now()
+		PullImage(ctx, docker, c.Linter())
+		qdmetrics.Gauge(
+			"qodana_image_pull_seconds",
+			"Time spent pulling the linter image in seconds.",
+			time.Since(pullStart).Seconds(),
+			nil,
+		)
+		c.Checkpoints().Complete(qdcheckpoint.ImagePull, c.Linter())
 	}
+	pullSpan.End(nil)
 	progress, _ := msg.StartQodanaSpinner(scanStages[0])
 
 	dockerConfig := getDockerOptions(c)
+	if qdcontainer.IsWsl2Engine(info) {
+		if err = prepareWslMounts(ctx, c, dockerConfig.HostConfig.Mounts); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if c.Reproducible() {
+		dockerConfig.Config.Image = pinImage(ctx, docker, c.Linter())
+	}
 	log.Debugf("docker command to run: %s", generateDebugDockerRunCommand(dockerConfig))
 
 	msg.UpdateText(progress, scanStages[1])
 
+	runSpan := qdtrace.Start("container run")
+	runSpan.SetAttribute("qodana.linter", c.Linter())
+	timer := &stageTimer{parent: runSpan}
+	timer.next("startup")
+	platform.ClearOutcome(c.ResultsDir())
 	runContainer(ctx, docker, dockerConfig)
-	go followLinter(docker, dockerConfig.Name, progress, scanStages)
+	go followLinter(ctx, docker, dockerConfig.Name, progress, scanStages, timer, c.Verbose())
+	stopFollowingIdeaLog := func() {}
+	if c.FollowIdeaLog() {
+		stopFollowingIdeaLog = followIdeaLog(c.LogDir(), c.Verbose())
+	}
 
 	exitCode := getContainerExitCode(ctx, docker, dockerConfig.Name)
+	stopFollowingIdeaLog()
+	timer.stop()
+	runSpan.SetAttribute("qodana.exit_code", strconv.FormatInt(exitCode, 10))
+	if outcome, err := platform.ReadOutcome(c.ResultsDir()); err == nil && outcome.TimedOut != "" {
+		runSpan.End(&qdtrace.TimeoutError{Stage: outcome.TimedOut, Timeout: stageTimeout(c, outcome.TimedOut)})
+		exitCode = utils.QodanaTimeoutExitCodePlaceholder
+	} else {
+		runSpan.End(nil)
+	}
 
 	fixDarwinCaches(c.CacheDir())
 
@@ -187,12 +239,42 @@ func encodeAuthToBase64(authConfig registry.AuthConfig) (string, error) {
 	return base64.URLEncoding.EncodeToString(buf), nil
 }
 
+// imageExists checks that the image is present locally, e.g. it was not pruned since the interrupted scan pulled it.
+func imageExists(ctx context.Context, client *client.Client, image string) bool {
+	_, _, err := client.ImageInspectWithRaw(ctx, image)
+	return err == nil
+}
+
+// pinImage returns the image referenced by its digest, so the reproducible scans run exactly the same linter.
+func pinImage(ctx context.Context, client *client.Client, image string) string {
+	if strings.Contains(image, "@") {
+		return image
+	}
+	inspect, _, err := client.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		log.Fatalf("Failed to inspect the image %s: %s", image, err)
+	}
+	repository := image
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		repository = image[:i]
+	}
+	repository = strings.TrimPrefix(repository, "docker.io/")
+	for _, digest := range inspect.RepoDigests {
+		if strings.HasPrefix(digest, repository+"@") {
+			msg.SuccessMessage("Pinned the image %s to %s", image, digest)
+			return digest
+		}
+	}
+	msg.WarningMessage("The image %s has no digest, e.g. it was built locally, it can't be pinned", image)
+	return image
+}
+
 // PullImage pulls docker image and prints the process.
-func PullImage(client *client.Client, image string) {
+func PullImage(ctx context.Context, client *client.Client, image string) {
 	checkImage(image)
 	msg.PrintProcess(
 		func(_ *pterm.SpinnerPrinter) {
-			pullImage(context.Background(), client, image)
+			pullImage(ctx, client, image)
 		},
 		fmt.Sprintf("Pulling the image %s", msg.PrimaryBold(image)),
 		"pulling the latest version of linter",
@@ -249,13 +331,15 @@ func ContainerCleanup() {
 		ctx := context.Background()
 		containers, err := docker.ContainerList(ctx, container.ListOptions{})
 		if err != nil {
-			log.Fatal("couldn't get the running containers ", err)
+			// don't exit, the working tree still needs to be restored
+			msg.ErrorMessage("Couldn't get the running containers: %s", err)
+			return
 		}
 		for _, c := range containers {
 			if c.Names[0] == fmt.Sprintf("/%s", containerName) {
 				err = docker.ContainerStop(context.Background(), c.Names[0], container.StopOptions{})
 				if err != nil {
-					log.Fatal("couldn't stop the container ", err)
+					msg.ErrorMessage("Couldn't stop the container %s: %s", containerName, err)
 				}
 			}
 		}
@@ -274,6 +358,23 @@ func getDockerOptions(c corescan.Context) *backend.ContainerCreateConfig {
 
 	updateScanContextEnv := func(key string, value string) { c = c.WithEnvExtractedFromOsEnv(key, value) }
 	qdenv.ExtractQodanaEnvironment(updateScanContextEnv)
+	if c.NoStatistics() {
+		updateScanContextEnv(qdenv.QodanaTelemetryEnv, qdenv.TelemetryOff)
+	}
+	if traceParent := qdtrace.TraceParent(); traceParent != "" {
+		// continue the trace in the container
+		updateScanContextEnv(qdenv.TraceParentEnv, traceParent)
+		for _, env := range []string{
+			qdenv.OtelExporterOtlpEndpointEnv,
+			qdenv.OtelExporterOtlpTracesEndpointEnv,
+			qdenv.OtelExporterOtlpHeadersEnv,
+			qdenv.OtelServiceNameEnv,
+		} {
+			if value := os.Getenv(env); value != "" {
+				updateScanContextEnv(env, value)
+			}
+		}
+	}
 
 	cachePath, err := filepath.Abs(c.CacheDir())
 	if err != nil {
@@ -294,28 +395,44 @@ func getDockerOptions(c corescan.Context) *backend.ContainerCreateConfig {
 	volumes := []mount.Mount{
 		{
 			Type:   mount.TypeBind,
-			Source: cachePath,
+			Source: utils.NormalizeWindowsPath(cachePath),
 			Target: "/data/cache",
 		},
 		{
 			Type:   mount.TypeBind,
-			Source: projectPath,
-			Target: "/data/project",
+			Source: utils.NormalizeWindowsPath(projectPath),
+			Target: ContainerProjectDir,
 		},
 		{
 			Type:   mount.TypeBind,
-			Source: resultsPath,
+			Source: utils.NormalizeWindowsPath(resultsPath),
 			Target: "/data/results",
 		},
 	}
+	for _, paths := range cachedPlugins(c) {
+		for _, path := range paths {
+			path, err = filepath.Abs(path)
+			if err != nil {
+				log.Fatal("couldn't get abs path for plugin", err)
+			}
+			volumes = append(
+				volumes, mount.Mount{
+					Type:   mount.TypeBind,
+					Source: utils.NormalizeWindowsPath(path),
+					Target: qdplugin.ContainerPluginsDir + "/" + filepath.Base(path),
+				},
+			)
+		}
+	}
 	for _, volume := range c.Volumes() {
-		source, target := extractDockerVolumes(volume)
+		source, target, readOnly := extractDockerVolumes(volume)
 		if source != "" && target != "" {
 			volumes = append(
 				volumes, mount.Mount{
-					Type:   mount.TypeBind,
-					Source: source,
-					Target: target,
+					Type:     mount.TypeBind,
+					Source:   source,
+					Target:   target,
+					ReadOnly: readOnly,
 				},
 			)
 		} else {
@@ -406,7 +523,11 @@ func generateDebugDockerRunCommand(cfg *backend.ContainerCreateConfig) string {
 	}
 	if cfg.HostConfig != nil {
 		for _, m := range cfg.HostConfig.Mounts {
-			cmdBuilder.WriteString(fmt.Sprintf("-v %s:%s ", m.Source, m.Target))
+			volume := fmt.Sprintf("%s:%s", m.Source, m.Target)
+			if m.ReadOnly {
+				volume += ":ro"
+			}
+			cmdBuilder.WriteString(fmt.Sprintf("-v %s ", utils.QuoteForWindows(volume)))
 		}
 		for _, capAdd := range cfg.HostConfig.CapAdd {
 			cmdBuilder.WriteString(fmt.Sprintf("--cap-add %s ", capAdd))
@@ -424,10 +545,14 @@ func generateDebugDockerRunCommand(cfg *backend.ContainerCreateConfig) string {
 }
 
 // getContainerExitCode returns the exit code of the docker container.
+// If ctx is done first, the container is stopped and the timeout or the cancellation exit code is returned.
 func getContainerExitCode(ctx context.Context, client *client.Client, id string) int64 {
 	statusCh, errCh := client.ContainerWait(ctx, id, container.WaitConditionNextExit)
 	select {
 	case err := <-errCh:
+		if ctx.Err() != nil {
+			return stopContainer(client, id, ctx.Err())
+		}
 		if err != nil {
 			log.Fatal("container hasn't finished ", err)
 		}
@@ -437,6 +562,17 @@ func getContainerExitCode(ctx context.Context, client *client.Client, id string)
 	return 0
 }
 
+// stopContainer stops the container of the run interrupted with err and returns the exit code of the run.
+func stopContainer(client *client.Client, id string, err error) int64 {
+	if e := client.ContainerStop(context.Background(), id, container.StopOptions{}); e != nil {
+		log.Warnf("Failed to stop the container %s: %s", id, e)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return utils.QodanaTimeoutExitCodePlaceholder
+	}
+	return utils.QodanaCancelledExitCode
+}
+
 // runContainer runs the container.
 func runContainer(ctx context.Context, client *client.Client, opts *backend.ContainerCreateConfig) {
 	createResp, err := client.ContainerCreate(
@@ -455,14 +591,54 @@ func runContainer(ctx context.Context, client *client.Client, opts *backend.Cont
 	}
 }
 
-// extractDockerVolumes extracts the source and target of the volume to mount.
-func extractDockerVolumes(volume string) (string, string) {
-	split := strings.Split(volume, ":")
-	if len(split) == 2 {
-		return split[0], split[1]
-	} else if //goland:noinspection GoBoolExpressions
-	runtime.GOOS == "windows" {
-		return fmt.Sprintf("%s:%s", split[0], split[1]), split[2]
+// extractDockerVolumes extracts the source, the target and the read-only mode of the volume to mount, e.g.
+// C:\src:/data/src:ro, the Windows sources are recognized on every OS and lose the extended-length prefix.
+func extractDockerVolumes(volume string) (string, string, bool) {
+	volume = utils.NormalizeWindowsPath(volume)
+	start := 0
+	if utils.HasDriveLetter(volume) {
+		start = 2
+	}
+	i := strings.Index(volume[start:], ":")
+	if i < 0 {
+		return "", "", false
+	}
+	source := volume[:start+i]
+	target, mode, _ := strings.Cut(volume[start+i+1:], ":")
+	switch mode {
+	case "", "rw":
+		return source, target, false
+	case "ro":
+		return source, target, true
+	}
+	return "", "", false
+}
+
+// prepareWslMounts rewrites the mount sources for the WSL 2 engine of Docker Desktop: a project on a Windows drive is
+// copied into the WSL filesystem with --wsl-sync, or mounted with a warning, the Windows drives are slow to mount there.
+func prepareWslMounts(ctx context.Context, c corescan.Context, mounts []mount.Mount) error {
+	for i := range mounts {
+		m := &mounts[i]
+		if m.Target == ContainerProjectDir && qdcontainer.IsWindowsDrivePath(m.Source) {
+			if c.WslSync() {
+				msg.SuccessMessage("Copying the project into the WSL filesystem")
+				synced, err := qdcontainer.SyncToWsl(ctx, m.Source)
+				if err != nil {
+					return err
+				}
+				if c.ApplyFixes() || c.Cleanup() {
+					msg.WarningMessage("The quick-fixes are applied to the copy of the project in WSL: %s", synced)
+				}
+				log.Debugf("project %s is synced to %s", m.Source, synced)
+				m.Source = synced
+				continue
+			}
+			msg.WarningMessage(
+				"The project is on a Windows drive mounted into the WSL 2 engine of Docker Desktop, the analysis may be slow.\n" +
+					"   Use --wsl-sync to copy it into the WSL filesystem before the run, or move it there",
+			)
+		}
+		m.Source = qdcontainer.WslMountSource(m.Source)
 	}
-	return "", ""
+	return nil
 }
@@ -1,8 +1,12 @@
 package core
 
 import (
+	"context"
 	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
 	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/docker/docker/api/types/mount"
+	"strings"
 	"testing"
 )
 
@@ -60,3 +64,55 @@ func TestImageChecks(t *testing.T) {
 		)
 	}
 }
+
+func TestExtractDockerVolumes(t *testing.T) {
+	longDir := `C:\work\` + strings.Repeat(`very long directory name\`, 12) + "project"
+	if len(longDir) <= 260 {
+		t.Fatalf("the test directory is %d characters long, want more than 260", len(longDir))
+	}
+	testCases := []struct {
+		volume   string
+		source   string
+		target   string
+		readOnly bool
+	}{
+		{"/home/user/cache:/data/cache", "/home/user/cache", "/data/cache", false},
+		{"/home/user/cache:/data/cache:ro", "/home/user/cache", "/data/cache", true},
+		{`C:\cache:/data/cache`, `C:\cache`, "/data/cache", false},
+		{longDir + ":/data/project:rw", longDir, "/data/project", false},
+		{`\\?\` + longDir + ":/data/project:ro", longDir, "/data/project", true},
+		{`\\server\share\project:/data/project`, `\\server\share\project`, "/data/project", false},
+		{`\\?\UNC\server\share\project:/data/project`, `\\server\share\project`, "/data/project", false},
+		{"/home/user/cache", "", "", false},
+		{"/home/user/cache:/data/cache:z", "", "", false},
+	}
+	for _, tc := range testCases {
+		source, target, readOnly := extractDockerVolumes(tc.volume)
+		if source != tc.source || target != tc.target || readOnly != tc.readOnly {
+			t.Errorf(
+				"extractDockerVolumes(%q) = %q, %q, %v, want %q, %q, %v",
+				tc.volume, source, target, readOnly, tc.source, tc.target, tc.readOnly,
+			)
+		}
+	}
+}
+
+func TestPrepareWslMounts(t *testing.T) {
+	mounts := []mount.Mount{
+		{Type: mount.TypeBind, Source: `C:\Users\user\.cache\qodana`, Target: "/data/cache"},
+		{Type: mount.TypeBind, Source: `C:\work\project`, Target: ContainerProjectDir},
+		{Type: mount.TypeBind, Source: `\\wsl$\Ubuntu\home\user\results`, Target: "/data/results"},
+	}
+	if err := prepareWslMounts(context.Background(), corescan.ContextBuilder{}.Build(), mounts); err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []string{
+		"/run/desktop/mnt/host/c/Users/user/.cache/qodana",
+		"/run/desktop/mnt/host/c/work/project",
+		`\\wsl$\Ubuntu\home\user\results`,
+	} {
+		if mounts[i].Source != expected {
+			t.Errorf("mount of %s has the source %s, want %s", mounts[i].Target, mounts[i].Source, expected)
+		}
+	}
+}
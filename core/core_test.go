@@ -17,6 +17,7 @@
 package core
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/cloud"
@@ -27,6 +28,7 @@ import (
 	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
 	"github.com/JetBrains/qodana-cli/v2024/platform/product"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
 	"github.com/JetBrains/qodana-cli/v2024/platform/tokenloader"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
@@ -667,9 +669,9 @@ func Test_Bootstrap(t *testing.T) {
 		t.Fatal(err)
 	}
 	projectDir := tmpDir
-	utils.Bootstrap("echo 'bootstrap: touch qodana.yml' > qodana.yaml", projectDir)
+	utils.Bootstrap(context.Background(), "echo 'bootstrap: touch qodana.yml' > qodana.yaml", projectDir)
 	config := qdyaml.GetQodanaYamlOrDefault(tmpDir)
-	utils.Bootstrap(config.Bootstrap, projectDir)
+	utils.Bootstrap(context.Background(), config.Bootstrap.String(), projectDir)
 	if _, err := os.Stat(filepath.Join(projectDir, "qodana.yaml")); errors.Is(err, os.ErrNotExist) {
 		t.Fatalf("No qodana.yml created by the bootstrap command in qodana.yaml")
 	}
@@ -985,6 +987,8 @@ func propertiesFixture(enableStats bool, additionalProperties []string) []string
 		fmt.Sprintf("-Didea.plugins.path=%s", filepath.Join(os.TempDir(), "entrypoint", "plugins", "233")),
 		fmt.Sprintf("-Didea.system.path=%s", filepath.Join(os.TempDir(), "entrypoint", "idea", "233")),
 		fmt.Sprintf("-Xlog:gc*:%s", filepath.Join(os.TempDir(), "entrypoint", "log", "gc.log")),
+		"-XX:+HeapDumpOnOutOfMemoryError",
+		fmt.Sprintf("-XX:HeapDumpPath=%s", filepath.Join(os.TempDir(), "entrypoint", "log")),
 		"-XX:MaxRAMPercentage=70",
 	}
 	properties = append(properties, additionalProperties...)
@@ -1003,6 +1007,7 @@ func Test_Properties(t *testing.T) {
 	t.Setenv(qdenv.QodanaDockerEnv, "true")
 	t.Setenv("DEVICEID", "FAKE")
 	t.Setenv("SALT", "FAKE")
+	setCpus(t, 8, 8)
 
 	err := os.MkdirAll(projectDir, 0o755)
 	if err != nil {
@@ -1139,3 +1144,94 @@ func Test_Properties(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// setCpus sets the CPUs of the machine and the CPU limit of the container seen by threadProperties.
+func setCpus(t *testing.T, cpus int, limit int) {
+	previousLimit, previousCpus := cpuLimit, numCpu
+	cpuLimit = func() int { return limit }
+	numCpu = func() int { return cpus }
+	t.Cleanup(
+		func() {
+			cpuLimit, numCpu = previousLimit, previousCpus
+		},
+	)
+}
+
+func TestThreadProperties(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		limit    int
+		cb       corescan.ContextBuilder
+		expected map[string]string
+	}{
+		{
+			name:     "IDE defaults without a CPU limit",
+			limit:    8,
+			expected: map[string]string{},
+		},
+		{
+			name:  "derived from the CPU limit",
+			limit: 2,
+			expected: map[string]string{
+				analysisThreadsProperty: "2",
+				indexingThreadsProperty: "1",
+			},
+		},
+		{
+			name:  "indexing threads derived from a large CPU limit are capped",
+			limit: 7,
+			expected: map[string]string{
+				analysisThreadsProperty: "7",
+				indexingThreadsProperty: "4",
+			},
+		},
+		{
+			name:  "flags override the CPU limit",
+			limit: 2,
+			cb:    corescan.ContextBuilder{AnalysisThreads: 6},
+			expected: map[string]string{
+				analysisThreadsProperty: "6",
+				indexingThreadsProperty: "1",
+			},
+		},
+		{
+			name:  "flags without a CPU limit",
+			limit: 8,
+			cb:    corescan.ContextBuilder{AnalysisThreads: 3, IndexingThreads: 2},
+			expected: map[string]string{
+				analysisThreadsProperty: "3",
+				indexingThreadsProperty: "2",
+			},
+		},
+	} {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				setCpus(t, 8, tc.limit)
+				assert.Equal(t, tc.expected, threadProperties(tc.cb.Build()))
+			},
+		)
+	}
+}
+
+func TestStageWatcher(t *testing.T) {
+	c := corescan.ContextBuilder{InspectionTimeout: 50 * time.Millisecond}.Build()
+	watcher := newStageWatcher(c, qdtrace.Start("ide run"))
+	assert.True(t, watcher.enabled())
+
+	watcher.line("Starting up IntelliJ IDEA")
+	watcher.line("The Project opening stage completed in 1 s")
+	watcher.line("The Project configuration stage completed in 2 s")
+	select {
+	case <-watcher.stop:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the analysis stage to reach the inspection timeout")
+	}
+	watcher.line("Detailed summary")
+	assert.Equal(t, &qdtrace.TimeoutError{Stage: "inspection", Timeout: 50 * time.Millisecond}, watcher.finish())
+
+	watcher = newStageWatcher(corescan.ContextBuilder{IndexingTimeout: time.Hour}.Build(), qdtrace.Start("ide run"))
+	watcher.line("Starting up IntelliJ IDEA")
+	watcher.line("The Project opening stage completed in 1 s")
+	assert.Nil(t, watcher.finish())
+	assert.False(t, newStageWatcher(corescan.ContextBuilder{}.Build(), nil).enabled())
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
 	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcheckpoint"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
 	"math"
 	"path/filepath"
@@ -97,12 +98,23 @@ type Context struct {
 	generateCodeClimateReport bool
 	sendBitBucketInsights     bool
 	skipPull                  bool
+	wslSync                   bool
 	clearCache                bool
+	skipDiskCheck             bool
 	configName                string
 	fullHistory               bool
 	applyFixes                bool
 	cleanup                   bool
 	fixesStrategy             string
+	fixesOutput               string
+	fixesRules                []string
+	fixesInclude              []string
+	checkpoints               *qdcheckpoint.Checkpoints
+	fixesPush                 bool
+	fixesBranch               string
+	fixesCommitMessage        string
+	fixesAuthor               string
+	fixesRemote               string
 	noStatistics              bool
 	cdnetSolution             string
 	cdnetProject              string
@@ -114,67 +126,104 @@ type Context struct {
 	analysisTimeoutMs         int
 	analysisTimeoutExitCode   int
 	jvmDebugPort              int
+	_vmOptions                []string
+	provisionJdk              bool
+	provisionNode             bool
+	provisionPython           bool
+	provisionAndroidSdk       bool
+	analysisThreads           int
+	indexingThreads           int
+	bootstrapTimeout          time.Duration
+	indexingTimeout           time.Duration
+	inspectionTimeout         time.Duration
+	reproducible              bool
+	verbose                   bool
+	followIdeaLog             bool
 }
 
-func (c Context) Linter() string                  { return c.linter }
-func (c Context) Ide() string                     { return c.ide }
-func (c Context) Id() string                      { return c.id }
-func (c Context) IdeDir() string                  { return c.ideDir }
-func (c Context) QodanaYaml() qdyaml.QodanaYaml   { return c.qodanaYaml }
-func (c Context) Prod() product.Product           { return c.prod }
-func (c Context) QodanaToken() string             { return c.qodanaToken }
-func (c Context) QodanaLicenseOnlyToken() string  { return c.qodanaLicenseOnlyToken }
-func (c Context) ProjectDir() string              { return c.projectDir }
-func (c Context) ResultsDir() string              { return c.resultsDir }
-func (c Context) ConfigDir() string               { return c.configDir }
-func (c Context) LogDir() string                  { return c.logDir }
-func (c Context) QodanaSystemDir() string         { return c.qodanaSystemDir }
-func (c Context) CacheDir() string                { return c.cacheDir }
-func (c Context) ReportDir() string               { return c.reportDir }
-func (c Context) CoverageDir() string             { return c.coverageDir }
-func (c Context) SourceDirectory() string         { return c.sourceDirectory }
-func (c Context) DisableSanity() bool             { return c.disableSanity }
-func (c Context) ProfileName() string             { return c.profileName }
-func (c Context) ProfilePath() string             { return c.profilePath }
-func (c Context) RunPromo() string                { return c.runPromo }
-func (c Context) StubProfile() string             { return c.stubProfile }
-func (c Context) Baseline() string                { return c.baseline }
-func (c Context) BaselineIncludeAbsent() bool     { return c.baselineIncludeAbsent }
-func (c Context) SaveReport() bool                { return c.saveReport }
-func (c Context) ShowReport() bool                { return c.showReport }
-func (c Context) Port() int                       { return c.port }
-func (c Context) Script() string                  { return c.script }
-func (c Context) FailThreshold() string           { return c.failThreshold }
-func (c Context) Commit() string                  { return c.commit }
-func (c Context) DiffStart() string               { return c.diffStart }
-func (c Context) DiffEnd() string                 { return c.diffEnd }
-func (c Context) ForceLocalChangesScript() bool   { return c.forceLocalChangesScript }
-func (c Context) AnalysisId() string              { return c.analysisId }
-func (c Context) User() string                    { return c.user }
-func (c Context) PrintProblems() bool             { return c.printProblems }
-func (c Context) GenerateCodeClimateReport() bool { return c.generateCodeClimateReport }
-func (c Context) SendBitBucketInsights() bool     { return c.sendBitBucketInsights }
-func (c Context) SkipPull() bool                  { return c.skipPull }
-func (c Context) ClearCache() bool                { return c.clearCache }
-func (c Context) ConfigName() string              { return c.configName }
-func (c Context) FullHistory() bool               { return c.fullHistory }
-func (c Context) ApplyFixes() bool                { return c.applyFixes }
-func (c Context) Cleanup() bool                   { return c.cleanup }
-func (c Context) FixesStrategy() string           { return c.fixesStrategy }
-func (c Context) NoStatistics() bool              { return c.noStatistics }
-func (c Context) CdnetSolution() string           { return c.cdnetSolution }
-func (c Context) CdnetProject() string            { return c.cdnetProject }
-func (c Context) CdnetConfiguration() string      { return c.cdnetConfiguration }
-func (c Context) CdnetPlatform() string           { return c.cdnetPlatform }
-func (c Context) CdnetNoBuild() bool              { return c.cdnetNoBuild }
-func (c Context) ClangCompileCommands() string    { return c.clangCompileCommands }
-func (c Context) ClangArgs() string               { return c.clangArgs }
-func (c Context) AnalysisTimeoutMs() int          { return c.analysisTimeoutMs }
-func (c Context) AnalysisTimeoutExitCode() int    { return c.analysisTimeoutExitCode }
-func (c Context) JvmDebugPort() int               { return c.jvmDebugPort }
-func (c Context) Env() []string                   { return arrayCopy(c._env) }
-func (c Context) Property() []string              { return arrayCopy(c._property) }
-func (c Context) Volumes() []string               { return arrayCopy(c._volumes) }
+func (c Context) Linter() string                         { return c.linter }
+func (c Context) Ide() string                            { return c.ide }
+func (c Context) Id() string                             { return c.id }
+func (c Context) IdeDir() string                         { return c.ideDir }
+func (c Context) QodanaYaml() qdyaml.QodanaYaml          { return c.qodanaYaml }
+func (c Context) Prod() product.Product                  { return c.prod }
+func (c Context) QodanaToken() string                    { return c.qodanaToken }
+func (c Context) QodanaLicenseOnlyToken() string         { return c.qodanaLicenseOnlyToken }
+func (c Context) ProjectDir() string                     { return c.projectDir }
+func (c Context) ResultsDir() string                     { return c.resultsDir }
+func (c Context) ConfigDir() string                      { return c.configDir }
+func (c Context) LogDir() string                         { return c.logDir }
+func (c Context) QodanaSystemDir() string                { return c.qodanaSystemDir }
+func (c Context) CacheDir() string                       { return c.cacheDir }
+func (c Context) ReportDir() string                      { return c.reportDir }
+func (c Context) CoverageDir() string                    { return c.coverageDir }
+func (c Context) SourceDirectory() string                { return c.sourceDirectory }
+func (c Context) DisableSanity() bool                    { return c.disableSanity }
+func (c Context) ProfileName() string                    { return c.profileName }
+func (c Context) ProfilePath() string                    { return c.profilePath }
+func (c Context) RunPromo() string                       { return c.runPromo }
+func (c Context) StubProfile() string                    { return c.stubProfile }
+func (c Context) Baseline() string                       { return c.baseline }
+func (c Context) BaselineIncludeAbsent() bool            { return c.baselineIncludeAbsent }
+func (c Context) SaveReport() bool                       { return c.saveReport }
+func (c Context) ShowReport() bool                       { return c.showReport }
+func (c Context) Port() int                              { return c.port }
+func (c Context) Script() string                         { return c.script }
+func (c Context) FailThreshold() string                  { return c.failThreshold }
+func (c Context) Commit() string                         { return c.commit }
+func (c Context) DiffStart() string                      { return c.diffStart }
+func (c Context) DiffEnd() string                        { return c.diffEnd }
+func (c Context) ForceLocalChangesScript() bool          { return c.forceLocalChangesScript }
+func (c Context) AnalysisId() string                     { return c.analysisId }
+func (c Context) User() string                           { return c.user }
+func (c Context) PrintProblems() bool                    { return c.printProblems }
+func (c Context) GenerateCodeClimateReport() bool        { return c.generateCodeClimateReport }
+func (c Context) SendBitBucketInsights() bool            { return c.sendBitBucketInsights }
+func (c Context) SkipPull() bool                         { return c.skipPull }
+func (c Context) WslSync() bool                          { return c.wslSync }
+func (c Context) ClearCache() bool                       { return c.clearCache }
+func (c Context) SkipDiskCheck() bool                    { return c.skipDiskCheck }
+func (c Context) ConfigName() string                     { return c.configName }
+func (c Context) FullHistory() bool                      { return c.fullHistory }
+func (c Context) ApplyFixes() bool                       { return c.applyFixes }
+func (c Context) Cleanup() bool                          { return c.cleanup }
+func (c Context) FixesStrategy() string                  { return c.fixesStrategy }
+func (c Context) FixesOutput() string                    { return c.fixesOutput }
+func (c Context) FixesRules() []string                   { return c.fixesRules }
+func (c Context) FixesInclude() []string                 { return c.fixesInclude }
+func (c Context) Checkpoints() *qdcheckpoint.Checkpoints { return c.checkpoints }
+func (c Context) FixesPush() bool                        { return c.fixesPush }
+func (c Context) FixesBranch() string                    { return c.fixesBranch }
+func (c Context) FixesCommitMessage() string             { return c.fixesCommitMessage }
+func (c Context) FixesAuthor() string                    { return c.fixesAuthor }
+func (c Context) FixesRemote() string                    { return c.fixesRemote }
+func (c Context) NoStatistics() bool                     { return c.noStatistics }
+func (c Context) CdnetSolution() string                  { return c.cdnetSolution }
+func (c Context) CdnetProject() string                   { return c.cdnetProject }
+func (c Context) CdnetConfiguration() string             { return c.cdnetConfiguration }
+func (c Context) CdnetPlatform() string                  { return c.cdnetPlatform }
+func (c Context) CdnetNoBuild() bool                     { return c.cdnetNoBuild }
+func (c Context) ClangCompileCommands() string           { return c.clangCompileCommands }
+func (c Context) ClangArgs() string                      { return c.clangArgs }
+func (c Context) AnalysisTimeoutMs() int                 { return c.analysisTimeoutMs }
+func (c Context) AnalysisTimeoutExitCode() int           { return c.analysisTimeoutExitCode }
+func (c Context) JvmDebugPort() int                      { return c.jvmDebugPort }
+func (c Context) Env() []string                          { return arrayCopy(c._env) }
+func (c Context) Property() []string                     { return arrayCopy(c._property) }
+func (c Context) Volumes() []string                      { return arrayCopy(c._volumes) }
+func (c Context) VmOptions() []string                    { return arrayCopy(c._vmOptions) }
+func (c Context) ProvisionJdk() bool                     { return c.provisionJdk }
+func (c Context) ProvisionNode() bool                    { return c.provisionNode }
+func (c Context) ProvisionPython() bool                  { return c.provisionPython }
+func (c Context) ProvisionAndroidSdk() bool              { return c.provisionAndroidSdk }
+func (c Context) AnalysisThreads() int                   { return c.analysisThreads }
+func (c Context) IndexingThreads() int                   { return c.indexingThreads }
+func (c Context) BootstrapTimeout() time.Duration        { return c.bootstrapTimeout }
+func (c Context) IndexingTimeout() time.Duration         { return c.indexingTimeout }
+func (c Context) InspectionTimeout() time.Duration       { return c.inspectionTimeout }
+func (c Context) Reproducible() bool                     { return c.reproducible }
+func (c Context) Verbose() bool                          { return c.verbose }
+func (c Context) FollowIdeaLog() bool                    { return c.followIdeaLog }
 
 type ContextBuilder struct {
 	Linter                    string
@@ -219,12 +268,23 @@ type ContextBuilder struct {
 	GenerateCodeClimateReport bool
 	SendBitBucketInsights     bool
 	SkipPull                  bool
+	WslSync                   bool
 	ClearCache                bool
+	SkipDiskCheck             bool
 	ConfigName                string
 	FullHistory               bool
 	ApplyFixes                bool
 	Cleanup                   bool
 	FixesStrategy             string
+	FixesOutput               string
+	FixesRules                []string
+	FixesInclude              []string
+	Checkpoints               *qdcheckpoint.Checkpoints
+	FixesPush                 bool
+	FixesBranch               string
+	FixesCommitMessage        string
+	FixesAuthor               string
+	FixesRemote               string
 	NoStatistics              bool
 	CdnetSolution             string
 	CdnetProject              string
@@ -236,6 +296,19 @@ type ContextBuilder struct {
 	AnalysisTimeoutMs         int
 	AnalysisTimeoutExitCode   int
 	JvmDebugPort              int
+	VmOptions                 []string
+	ProvisionJdk              bool
+	ProvisionNode             bool
+	ProvisionPython           bool
+	ProvisionAndroidSdk       bool
+	AnalysisThreads           int
+	IndexingThreads           int
+	BootstrapTimeout          time.Duration
+	IndexingTimeout           time.Duration
+	InspectionTimeout         time.Duration
+	Reproducible              bool
+	Verbose                   bool
+	FollowIdeaLog             bool
 }
 
 func (b ContextBuilder) Build() Context {
@@ -282,12 +355,23 @@ func (b ContextBuilder) Build() Context {
 		generateCodeClimateReport: b.GenerateCodeClimateReport,
 		sendBitBucketInsights:     b.SendBitBucketInsights,
 		skipPull:                  b.SkipPull,
+		wslSync:                   b.WslSync,
 		clearCache:                b.ClearCache,
+		skipDiskCheck:             b.SkipDiskCheck,
 		configName:                b.ConfigName,
 		fullHistory:               b.FullHistory,
 		applyFixes:                b.ApplyFixes,
 		cleanup:                   b.Cleanup,
 		fixesStrategy:             b.FixesStrategy,
+		fixesOutput:               b.FixesOutput,
+		fixesRules:                b.FixesRules,
+		fixesInclude:              b.FixesInclude,
+		checkpoints:               b.Checkpoints,
+		fixesPush:                 b.FixesPush,
+		fixesBranch:               b.FixesBranch,
+		fixesCommitMessage:        b.FixesCommitMessage,
+		fixesAuthor:               b.FixesAuthor,
+		fixesRemote:               b.FixesRemote,
 		noStatistics:              b.NoStatistics,
 		cdnetSolution:             b.CdnetSolution,
 		cdnetProject:              b.CdnetProject,
@@ -299,6 +383,19 @@ func (b ContextBuilder) Build() Context {
 		analysisTimeoutMs:         b.AnalysisTimeoutMs,
 		analysisTimeoutExitCode:   b.AnalysisTimeoutExitCode,
 		jvmDebugPort:              b.JvmDebugPort,
+		_vmOptions:                b.VmOptions,
+		provisionJdk:              b.ProvisionJdk,
+		provisionNode:             b.ProvisionNode,
+		provisionPython:           b.ProvisionPython,
+		provisionAndroidSdk:       b.ProvisionAndroidSdk,
+		analysisThreads:           b.AnalysisThreads,
+		indexingThreads:           b.IndexingThreads,
+		bootstrapTimeout:          b.BootstrapTimeout,
+		indexingTimeout:           b.IndexingTimeout,
+		inspectionTimeout:         b.InspectionTimeout,
+		reproducible:              b.Reproducible,
+		verbose:                   b.Verbose,
+		followIdeaLog:             b.FollowIdeaLog,
 	}
 }
 
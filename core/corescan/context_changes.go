@@ -85,6 +85,18 @@ func (c Context) SecondStageOfScopedScript(scopeFile string, startSarif string)
 	return c
 }
 
+// StageOfPhpVersionMatrix analyses the project for one of php.versions of qodana.yaml, with the config of the version,
+// into its own results directory, the results of all versions are merged afterward.
+func (c Context) StageOfPhpVersionMatrix(version string, configPath string) Context {
+	c.configName = configPath
+	c.qodanaYaml.Php.Version = version
+	c.qodanaYaml.Php.Versions = nil
+	c.resultsDir = filepath.Join(c.ResultsDir(), "php-"+version)
+	c.showReport = false
+	c.saveReport = false
+	return c
+}
+
 func (c Context) ForcedLocalChanges() Context {
 	c.script = "local-changes"
 	return c
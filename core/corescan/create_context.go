@@ -20,6 +20,7 @@ import (
 	"github.com/JetBrains/qodana-cli/v2024/core/startup"
 	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
 	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcheckpoint"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
 	"path/filepath"
@@ -89,22 +90,49 @@ func CreateContext(
 		GenerateCodeClimateReport: cliOptions.GenerateCodeClimateReport,
 		SendBitBucketInsights:     cliOptions.SendBitBucketInsights,
 		SkipPull:                  cliOptions.SkipPull,
+		WslSync:                   cliOptions.WslSync,
 		ClearCache:                commonCtx.IsClearCache,
+		SkipDiskCheck:             cliOptions.SkipDiskCheck,
 		ConfigName:                cliOptions.ConfigName,
 		FullHistory:               cliOptions.FullHistory,
 		ApplyFixes:                cliOptions.ApplyFixes,
 		Cleanup:                   cliOptions.Cleanup,
 		FixesStrategy:             cliOptions.FixesStrategy,
-		NoStatistics:              cliOptions.NoStatistics,
-		CdnetSolution:             cliOptions.CdnetSolution,
-		CdnetProject:              cliOptions.CdnetProject,
-		CdnetConfiguration:        cliOptions.CdnetConfiguration,
-		CdnetPlatform:             cliOptions.CdnetPlatform,
-		CdnetNoBuild:              cliOptions.CdnetNoBuild,
-		ClangCompileCommands:      cliOptions.ClangCompileCommands,
-		ClangArgs:                 cliOptions.ClangArgs,
-		AnalysisTimeoutMs:         cliOptions.AnalysisTimeoutMs,
-		AnalysisTimeoutExitCode:   cliOptions.AnalysisTimeoutExitCode,
-		JvmDebugPort:              cliOptions.JvmDebugPort,
+		FixesOutput:               cliOptions.FixesOutput,
+		FixesRules:                cliOptions.FixesRules,
+		FixesInclude:              cliOptions.FixesInclude,
+		Checkpoints: qdcheckpoint.Load(
+			filepath.Join(commonCtx.QodanaSystemDir, commonCtx.Id),
+			cliOptions.Resume,
+		),
+		FixesPush:               cliOptions.FixesPush,
+		FixesBranch:             cliOptions.FixesBranch,
+		FixesCommitMessage:      cliOptions.FixesCommitMessage,
+		FixesAuthor:             cliOptions.FixesAuthor,
+		FixesRemote:             cliOptions.FixesRemote,
+		NoStatistics:            cliOptions.NoStatistics,
+		CdnetSolution:           cliOptions.CdnetSolution,
+		CdnetProject:            cliOptions.CdnetProject,
+		CdnetConfiguration:      cliOptions.CdnetConfiguration,
+		CdnetPlatform:           cliOptions.CdnetPlatform,
+		CdnetNoBuild:            cliOptions.CdnetNoBuild,
+		ClangCompileCommands:    cliOptions.ClangCompileCommands,
+		ClangArgs:               cliOptions.ClangArgs,
+		AnalysisTimeoutMs:       cliOptions.AnalysisTimeoutMs,
+		AnalysisTimeoutExitCode: cliOptions.AnalysisTimeoutExitCode,
+		JvmDebugPort:            cliOptions.JvmDebugPort,
+		VmOptions:               cliOptions.VmOptions,
+		ProvisionJdk:            cliOptions.ProvisionJdk,
+		ProvisionNode:           cliOptions.ProvisionNode,
+		ProvisionPython:         cliOptions.ProvisionPython,
+		ProvisionAndroidSdk:     cliOptions.ProvisionAndroidSdk,
+		AnalysisThreads:         cliOptions.AnalysisThreads,
+		IndexingThreads:         cliOptions.IndexingThreads,
+		BootstrapTimeout:        cliOptions.BootstrapTimeout,
+		IndexingTimeout:         cliOptions.IndexingTimeout,
+		InspectionTimeout:       cliOptions.InspectionTimeout,
+		Reproducible:            cliOptions.Reproducible,
+		Verbose:                 cliOptions.Verbose,
+		FollowIdeaLog:           cliOptions.FollowIdeaLog,
 	}.Build()
 }
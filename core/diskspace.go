@@ -0,0 +1,195 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcontainer"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"github.com/shirou/gopsutil/v3/disk"
+	log "github.com/sirupsen/logrus"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The projections of the space a run needs, they err on the generous side: a run failing with ENOSPC in the middle
+// of the analysis costs far more than a check failing before it.
+const (
+	mib = 1024 * 1024
+	// resultsSpaceNeed is the space of the SARIF reports, the logs and the HTML report of a run.
+	resultsSpaceNeed = 512 * mib
+	// cacheSpaceBase is the space the caches of an empty project take, the indexes add cacheSpaceFactor times
+	// the size of the sources.
+	cacheSpaceBase   = 1024 * mib
+	cacheSpaceFactor = 2
+	// cacheSpaceMinGrowth is the space the existing caches need to be updated.
+	cacheSpaceMinGrowth = 256 * mib
+	// imageSpaceNeed is the space an unpacked linter image takes.
+	imageSpaceNeed = 4096 * mib
+)
+
+// spaceNeed is the projected space a run needs in a directory.
+type spaceNeed struct {
+	name string
+	dir  string
+	size int64
+	hint string
+}
+
+// checkDiskSpace checks that the disks have enough free space for the run, it's skipped with --skip-disk-check.
+func checkDiskSpace(ctx context.Context, c corescan.Context) error {
+	if c.SkipDiskCheck() {
+		return nil
+	}
+	return checkSpaceNeeds(spaceNeeds(ctx, c), disk.Usage)
+}
+
+// spaceNeeds returns the projected space the run needs in the cache and results directories, and in the directory
+// of the container engine for an image that is not pulled yet.
+func spaceNeeds(ctx context.Context, c corescan.Context) []spaceNeed {
+	projectSize := dirSize(c.ProjectDir(), c.CacheDir(), c.ResultsDir())
+	cacheSize := dirSize(c.CacheDir())
+	needs := []spaceNeed{
+		{
+			name: "cache",
+			dir:  c.CacheDir(),
+			size: cacheSpaceNeed(projectSize, cacheSize),
+			hint: "choose another cache directory with --cache-dir",
+		},
+		{
+			name: "results",
+			dir:  c.ResultsDir(),
+			size: resultsSpaceNeed,
+			hint: "choose another results directory with --results-dir",
+		},
+	}
+	if c.Ide() != "" || c.SkipPull() {
+		return needs
+	}
+	docker := qdcontainer.GetContainerClient()
+	info, err := docker.Info(ctx)
+	if err != nil {
+		return needs
+	}
+	// the images of Docker Desktop and remote engines are stored on another machine
+	if _, err = os.Stat(info.DockerRootDir); err != nil || imageExists(ctx, docker, c.Linter()) {
+		return needs
+	}
+	return append(
+		needs, spaceNeed{
+			name: "image",
+			dir:  info.DockerRootDir,
+			size: imageSpaceNeed,
+			hint: "remove the unused images with 'docker image prune'",
+		},
+	)
+}
+
+// cacheSpaceNeed returns the space the caches need to grow by for the project.
+func cacheSpaceNeed(projectSize int64, cacheSize int64) int64 {
+	need := cacheSpaceBase + cacheSpaceFactor*projectSize - cacheSize
+	if need < cacheSpaceMinGrowth {
+		return cacheSpaceMinGrowth
+	}
+	return need
+}
+
+// checkSpaceNeeds checks the needs against the free space of their disks, the needs on the same disk add up.
+func checkSpaceNeeds(needs []spaceNeed, usage func(path string) (*disk.UsageStat, error)) error {
+	type diskSpace struct {
+		free  uint64
+		need  int64
+		names []string
+		hints []string
+	}
+	var order []string
+	disks := map[string]*diskSpace{}
+	for _, need := range needs {
+		stat, err := usage(existingParent(need.dir))
+		if err != nil {
+			log.Debugf("Couldn't get the free space of %s: %s", need.dir, err)
+			continue
+		}
+		// the disks are told apart by the file system and the size, the paths of the mount points differ per OS
+		key := fmt.Sprintf("%s/%d", stat.Fstype, stat.Total)
+		d, ok := disks[key]
+		if !ok {
+			d = &diskSpace{free: stat.Free}
+			disks[key] = d
+			order = append(order, key)
+		}
+		d.need += need.size
+		d.names = append(d.names, fmt.Sprintf("%s %s (%s)", need.name, need.dir, utils.FormatSize(need.size)))
+		d.hints = append(d.hints, need.hint)
+	}
+	var problems []string
+	for _, key := range order {
+		d := disks[key]
+		if d.free < uint64(d.need) {
+			problems = append(
+				problems, fmt.Sprintf(
+					"%s available, about %s needed for %s: free up disk space, %s",
+					utils.FormatSize(int64(d.free)),
+					utils.FormatSize(d.need),
+					strings.Join(d.names, ", "),
+					strings.Join(d.hints, ", "),
+				),
+			)
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf(
+		"not enough disk space for the analysis, %s. Use --skip-disk-check to run it anyway",
+		strings.Join(problems, "; "),
+	)
+}
+
+// dirSize returns the size of the files in dir, the .git directory and the excluded directories are skipped.
+func dirSize(dir string, excluded ...string) int64 {
+	skip := map[string]bool{}
+	for _, e := range excluded {
+		if abs, err := filepath.Abs(e); err == nil {
+			skip[abs] = true
+		}
+	}
+	dir, _ = filepath.Abs(dir)
+	var size int64
+	_ = filepath.WalkDir(
+		dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != dir && (d.Name() == ".git" || skip[path]) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+				size += info.Size()
+			}
+			return nil
+		},
+	)
+	return size
+}
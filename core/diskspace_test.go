@@ -0,0 +1,116 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"github.com/shirou/gopsutil/v3/disk"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCacheSpaceNeed(t *testing.T) {
+	for _, tc := range []struct {
+		projectSize int64
+		cacheSize   int64
+		expected    int64
+	}{
+		{0, 0, cacheSpaceBase},
+		{100 * mib, 0, cacheSpaceBase + 200*mib},
+		{100 * mib, 500 * mib, cacheSpaceBase + 200*mib - 500*mib},
+		{100 * mib, 5000 * mib, cacheSpaceMinGrowth},
+	} {
+		if actual := cacheSpaceNeed(tc.projectSize, tc.cacheSize); actual != tc.expected {
+			t.Errorf("cacheSpaceNeed(%d, %d) = %d, expected %d", tc.projectSize, tc.cacheSize, actual, tc.expected)
+		}
+	}
+}
+
+func TestCheckSpaceNeeds(t *testing.T) {
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "cache")
+	results := filepath.Join(dir, "results")
+	images := filepath.Join(dir, "docker")
+	for _, d := range []string{cache, results, images} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	disks := map[string]*disk.UsageStat{
+		cache:   {Fstype: "ext4", Total: 100 * 1024 * mib, Free: 1200 * mib},
+		results: {Fstype: "ext4", Total: 100 * 1024 * mib, Free: 1200 * mib},
+		images:  {Fstype: "xfs", Total: 500 * 1024 * mib, Free: 5000 * mib},
+	}
+	usage := func(path string) (*disk.UsageStat, error) {
+		if stat, ok := disks[path]; ok {
+			return stat, nil
+		}
+		return nil, errors.New("unknown disk")
+	}
+	needs := []spaceNeed{
+		{name: "cache", dir: cache, size: 1000 * mib, hint: "use --cache-dir"},
+		{name: "image", dir: images, size: imageSpaceNeed, hint: "prune the images"},
+	}
+	if err := checkSpaceNeeds(needs, usage); err != nil {
+		t.Errorf("the needs fit the disks: %s", err)
+	}
+	// the results are on the same disk as the cache, they don't fit together
+	needs = append(needs, spaceNeed{name: "results", dir: filepath.Join(results, "new"), size: resultsSpaceNeed, hint: "use --results-dir"})
+	err := checkSpaceNeeds(needs, usage)
+	if err == nil {
+		t.Fatal("the cache and the results don't fit the disk")
+	}
+	for _, part := range []string{"1.2 GiB available", "1.5 GiB needed", "use --cache-dir, use --results-dir", "--skip-disk-check"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("%q is missing in %q", part, err)
+		}
+	}
+	if strings.Contains(err.Error(), "prune the images") {
+		t.Errorf("the image fits its disk, but it's reported in %q", err)
+	}
+	if err = checkSpaceNeeds([]spaceNeed{{name: "cache", dir: "/unknown", size: 1}}, usage); err != nil {
+		t.Errorf("the disks that can't be checked are skipped, got %s", err)
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]int{
+		"src/A.java":             100,
+		".git/objects/pack/p":    1000,
+		".qodana/cache/index":    10000,
+		"README.md":              10,
+		"src/main/resources/a.x": 1,
+	}
+	for name, size := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if actual := dirSize(dir, filepath.Join(dir, ".qodana", "cache")); actual != 111 {
+		t.Errorf("dirSize = %d, expected 111", actual)
+	}
+	if actual := dirSize(filepath.Join(dir, ".qodana")); actual != 10000 {
+		t.Errorf("dirSize = %d, expected 10000", actual)
+	}
+}
@@ -0,0 +1,296 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/cloud"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcontainer"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/tokenloader"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"github.com/pterm/pterm"
+	"github.com/shirou/gopsutil/v3/disk"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// doctorMinFreeSpace is the minimum free disk space recommended for Qodana caches and results.
+	doctorMinFreeSpace = 4 * 1024 * 1024 * 1024
+	dockerHubRegistry  = "registry-1.docker.io"
+)
+
+// DoctorCheck is a single environment check performed by `qodana doctor`.
+type DoctorCheck struct {
+	Name  string
+	Check func() error
+	// Fix is a hint shown to the user when the check fails.
+	Fix string
+	// Apply fixes the failed check with `qodana doctor --fix`, nil if it can't be fixed automatically.
+	Apply func() error
+	// Optional checks only print a warning when they fail.
+	Optional bool
+}
+
+// DoctorChecks returns the list of checks to run for the given context.
+func DoctorChecks(c commoncontext.Context) []DoctorCheck {
+	var checks []DoctorCheck
+	if c.Ide == "" {
+		checks = append(checks, containerEngineCheck())
+	} else {
+		checks = append(checks, jreCheck())
+	}
+	checks = append(
+		checks,
+		diskSpaceCheck("cache", c.CacheDir),
+		diskSpaceCheck("results", c.ResultsDir),
+	)
+	checks = append(checks, scanningChecks("cache", c.CacheDir)...)
+	checks = append(checks, scanningChecks("results", c.ResultsDir)...)
+	checks = append(
+		checks,
+		gitInstalledCheck(),
+		gitRepositoryCheck(c.ProjectDir),
+		cloudEndpointCheck(),
+	)
+	if c.Ide == "" {
+		checks = append(checks, registryCheck(c.Linter))
+	}
+	checks = append(checks, tokenCheck(c))
+	return checks
+}
+
+// RunDoctorChecks runs the given checks, prints the result of each one
+// and returns true if all non-optional checks passed. With fix, the failed checks that can be fixed are fixed and
+// checked again.
+func RunDoctorChecks(checks []DoctorCheck, fix bool) bool {
+	ok := true
+	for _, check := range checks {
+		err := check.Check()
+		if err != nil && fix && check.Apply != nil {
+			if fixErr := check.Apply(); fixErr != nil {
+				err = fmt.Errorf("%s, failed to fix: %s", err, fixErr)
+			} else if err = check.Check(); err == nil {
+				msg.SuccessMessage("%s (fixed)", check.Name)
+				continue
+			}
+		}
+		switch {
+		case err == nil:
+			msg.SuccessMessage(check.Name)
+			continue
+		case check.Optional:
+			pterm.Println(pterm.Yellow("! "), msg.Primary("%s: %s", check.Name, err))
+		default:
+			ok = false
+			msg.ErrorMessage("%s: %s", check.Name, err)
+		}
+		if check.Fix != "" {
+			fmt.Printf("    %s\n", check.Fix)
+		}
+	}
+	return ok
+}
+
+// containerEngineCheck verifies that Docker or Podman is installed and usable by the current user.
+func containerEngineCheck() DoctorCheck {
+	return DoctorCheck{
+		Name: "Container engine is available",
+		Check: func() error {
+			_, err := qdcontainer.CheckContainerEngine()
+			return err
+		},
+		Fix: "Install Docker (https://www.docker.com/get-started) or Podman, start its daemon and make sure the current user can run containers: " +
+			"https://docs.docker.com/engine/install/linux-postinstall/#manage-docker-as-a-non-root-user",
+	}
+}
+
+// jreCheck verifies that a Java runtime is available, it is used to upload the results to Qodana Cloud.
+func jreCheck() DoctorCheck {
+	return DoctorCheck{
+		Name: "Java runtime is available",
+		Check: func() error {
+			java, err := utils.GetJavaExecutablePath()
+			if err != nil {
+				return err
+			}
+			if _, err = os.Stat(java); err != nil {
+				return fmt.Errorf("java executable %s is not found", java)
+			}
+			return nil
+		},
+		Fix:      "Install JRE 17 or newer and make sure that java is available in PATH",
+		Optional: true,
+	}
+}
+
+// diskSpaceCheck verifies that there is enough free space on the disk where the given directory is located.
+func diskSpaceCheck(name string, dir string) DoctorCheck {
+	return DoctorCheck{
+		Name: fmt.Sprintf("Enough disk space for %s directory %s", name, dir),
+		Check: func() error {
+			usage, err := disk.Usage(existingParent(dir))
+			if err != nil {
+				return err
+			}
+			if usage.Free < doctorMinFreeSpace {
+				return fmt.Errorf(
+					"only %d MB available, at least %d MB is recommended",
+					usage.Free/1024/1024,
+					doctorMinFreeSpace/1024/1024,
+				)
+			}
+			return nil
+		},
+		Fix:      fmt.Sprintf("Free up disk space or choose another %s directory with the --%s-dir option", name, name),
+		Optional: true,
+	}
+}
+
+// existingParent returns the closest existing directory for the given path.
+func existingParent(path string) string {
+	path, _ = filepath.Abs(path)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// gitInstalledCheck verifies that git is available, it is required for diff runs, baselines and Qodana Cloud reports.
+func gitInstalledCheck() DoctorCheck {
+	return DoctorCheck{
+		Name: "Git is installed",
+		Check: func() error {
+			_, err := exec.LookPath("git")
+			return err
+		},
+		Fix: "Install git (https://git-scm.com/downloads) and make sure it is available in PATH",
+	}
+}
+
+// gitRepositoryCheck verifies that the project is a git repository with full history.
+func gitRepositoryCheck(projectDir string) DoctorCheck {
+	projectDir, _ = filepath.Abs(projectDir)
+	return DoctorCheck{
+		Name: fmt.Sprintf("Project %s is a git repository", projectDir),
+		Check: func() error {
+			if _, err := exec.LookPath("git"); err != nil {
+				return err
+			}
+			out, err := exec.Command("git", "-C", projectDir, "rev-parse", "--is-shallow-repository").Output()
+			if err != nil {
+				return errors.New("not a git repository or it has no commits")
+			}
+			if strings.TrimSpace(string(out)) == "true" {
+				return errors.New("the repository is a shallow clone")
+			}
+			return nil
+		},
+		Fix:      "Run Qodana on a full git clone (e.g. fetch-depth: 0 on GitHub Actions) to use diff runs and see the revision in Qodana Cloud",
+		Optional: true,
+	}
+}
+
+// cloudEndpointCheck verifies that the configured Qodana Cloud (or self-hosted Qodana) endpoint is reachable.
+func cloudEndpointCheck() DoctorCheck {
+	host := cloud.GetCloudRootEndpoint().Host
+	name := fmt.Sprintf("Qodana endpoint %s is reachable", host)
+	if ca := os.Getenv(qdenv.QodanaCaCertificateEnv); ca != "" {
+		name = fmt.Sprintf("%s (CA certificate %s)", name, ca)
+	}
+	return DoctorCheck{
+		Name: name,
+		Check: func() error {
+			_, err := cloud.GetCloudRootEndpoint().CheckHealth()
+			return err
+		},
+		Fix: fmt.Sprintf(
+			"Check the network and proxy settings, the 'cloud.endpoint' and 'cloud.caCertificate' settings in qodana.yaml or the user configuration, or the %s and %s environment variables",
+			qdenv.QodanaEndpointEnv,
+			qdenv.QodanaCaCertificateEnv,
+		),
+	}
+}
+
+// registryCheck verifies that the container registry of the linter image is reachable.
+func registryCheck(linter string) DoctorCheck {
+	host := registryHost(linter)
+	return DoctorCheck{
+		Name: fmt.Sprintf("Container registry %s is reachable", host),
+		Check: func() error {
+			client := &http.Client{Timeout: 30 * time.Second, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}}
+			resp, err := client.Get(fmt.Sprintf("https://%s/v2/", host))
+			if err != nil {
+				return err
+			}
+			_ = resp.Body.Close()
+			return nil
+		},
+		Fix:      "Check the network and proxy settings, or use --skip-pull with a pre-pulled image",
+		Optional: true,
+	}
+}
+
+// registryHost returns the registry host of the given image reference.
+func registryHost(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0]
+	}
+	return dockerHubRegistry
+}
+
+// tokenCheck verifies that the Qodana Cloud token is valid.
+func tokenCheck(c commoncontext.Context) DoctorCheck {
+	required := tokenloader.IsCloudTokenRequired(c, false)
+	return DoctorCheck{
+		Name: "Qodana Cloud token is valid",
+		Check: func() error {
+			token := tokenloader.LoadCloudToken(c, false, false, false)
+			if token == "" {
+				return fmt.Errorf("%s is not set", qdenv.QodanaToken)
+			}
+			apis, err := cloud.GetCloudRootEndpoint().CheckHealth()
+			if err != nil {
+				return err
+			}
+			_, err = apis.NewCloudApiClient(token).RequestProjectName()
+			return err
+		},
+		Fix: fmt.Sprintf(
+			"Obtain the project token at %s and provide it via %s or run %s",
+			cloud.GetCloudRootEndpoint().GetCloudUrl(),
+			qdenv.QodanaToken,
+			msg.PrimaryBold("qodana auth login"),
+		),
+		Optional: !required,
+	}
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// spotlightMarker is the file that excludes the directory from Spotlight indexing.
+const spotlightMarker = ".metadata_never_index"
+
+// scanningChecks returns the checks that the directory isn't scanned by the real-time antivirus (Windows) or indexed
+// by Spotlight (macOS): they process every file Qodana writes to the caches and results, a major hidden slowdown.
+func scanningChecks(name string, dir string) []DoctorCheck {
+	dir, _ = filepath.Abs(dir)
+	switch runtime.GOOS {
+	case "windows":
+		return []DoctorCheck{defenderCheck(name, dir)}
+	case "darwin":
+		return []DoctorCheck{spotlightCheck(name, dir)}
+	}
+	return nil
+}
+
+// defenderCheck verifies that the real-time protection of Microsoft Defender excludes the directory, the exclusion
+// is added by `qodana doctor --fix` run as administrator.
+func defenderCheck(name string, dir string) DoctorCheck {
+	return DoctorCheck{
+		Name: fmt.Sprintf("Microsoft Defender doesn't scan %s directory %s", name, dir),
+		Check: func() error {
+			out, err := powershell("$s = Get-MpComputerStatus; $p = Get-MpPreference; $s.RealTimeProtectionEnabled; $p.ExclusionPath")
+			if err != nil {
+				// no Defender, e.g. another antivirus replaced it
+				log.Debugf("Microsoft Defender status is unavailable: %s", err)
+				return nil
+			}
+			enabled, exclusions, err := parseDefenderStatus(out)
+			if err != nil || !enabled || isUnderAny(dir, exclusions) {
+				return err
+			}
+			return errors.New("the real-time protection scans every file Qodana writes there")
+		},
+		Apply: func() error {
+			_, err := powershell("Add-MpPreference -ExclusionPath '" + strings.ReplaceAll(dir, "'", "''") + "'")
+			return err
+		},
+		Fix: fmt.Sprintf(
+			"Run %s as administrator to add the exclusion, or move the directory to an excluded location with --%s-dir",
+			msg.PrimaryBold("qodana doctor --fix"),
+			name,
+		),
+		Optional: true,
+	}
+}
+
+// spotlightCheck verifies that Spotlight doesn't index the directory, `qodana doctor --fix` adds the marker file
+// excluding it.
+func spotlightCheck(name string, dir string) DoctorCheck {
+	return DoctorCheck{
+		Name: fmt.Sprintf("Spotlight doesn't index %s directory %s", name, dir),
+		Check: func() error {
+			if spotlightExcluded(dir) {
+				return nil
+			}
+			out, err := exec.Command("mdutil", "-s", existingParent(dir)).Output()
+			if err != nil || !strings.Contains(string(out), "Indexing enabled") {
+				return nil
+			}
+			return errors.New("the directory is indexed, Spotlight reads every file Qodana writes there")
+		},
+		Apply: func() error {
+			if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+				return err
+			}
+			return os.WriteFile(filepath.Join(dir, spotlightMarker), nil, 0o644)
+		},
+		Fix: fmt.Sprintf(
+			"Run %s to exclude it, or move the directory to one with the .noindex suffix with --%s-dir",
+			msg.PrimaryBold("qodana doctor --fix"),
+			name,
+		),
+		Optional: true,
+	}
+}
+
+// powershell runs the PowerShell command and returns its output.
+func powershell(command string) (string, error) {
+	out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", command).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// parseDefenderStatus parses whether the real-time protection is enabled (the first line) and the excluded paths.
+func parseDefenderStatus(out string) (bool, []string, error) {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(out, "\r\n", "\n")), "\n")
+	enabled := strings.EqualFold(strings.TrimSpace(lines[0]), "true")
+	var exclusions []string
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "N/A"):
+			if !enabled {
+				return false, nil, nil
+			}
+			return enabled, nil, errors.New("administrator rights are required to read the exclusions")
+		default:
+			exclusions = append(exclusions, line)
+		}
+	}
+	return enabled, exclusions, nil
+}
+
+// isUnderAny reports whether the Windows path is one of the directories or inside of one of them.
+func isUnderAny(path string, dirs []string) bool {
+	path = strings.ToLower(strings.TrimRight(strings.ReplaceAll(path, "/", `\`), `\`))
+	for _, dir := range dirs {
+		dir = strings.ToLower(strings.TrimRight(strings.ReplaceAll(dir, "/", `\`), `\`))
+		if dir != "" && (path == dir || strings.HasPrefix(path, dir+`\`)) {
+			return true
+		}
+	}
+	return false
+}
+
+// spotlightExcluded reports whether the directory or one of its parents has the marker file or the .noindex suffix.
+func spotlightExcluded(dir string) bool {
+	for {
+		if strings.HasSuffix(dir, ".noindex") {
+			return true
+		}
+		if _, err := os.Stat(filepath.Join(dir, spotlightMarker)); err == nil {
+			return true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
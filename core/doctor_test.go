@@ -0,0 +1,134 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistryHost(t *testing.T) {
+	for image, expected := range map[string]string{
+		"jetbrains/qodana-jvm:2024.3": dockerHubRegistry,
+		"qodana-jvm":                  dockerHubRegistry,
+		"registry.jetbrains.team/p/sa/containers/qodana-jvm:2024": "registry.jetbrains.team",
+		"localhost:5000/qodana-jvm":                               "localhost:5000",
+		"localhost/qodana-jvm":                                    "localhost",
+	} {
+		if actual := registryHost(image); actual != expected {
+			t.Errorf("registryHost(%s) = %s, expected %s", image, actual, expected)
+		}
+	}
+}
+
+func TestExistingParent(t *testing.T) {
+	dir := t.TempDir()
+	if actual := existingParent(filepath.Join(dir, "cache", "linter")); actual != dir {
+		t.Errorf("expected %s, got %s", dir, actual)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "cache"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if actual := existingParent(filepath.Join(dir, "cache")); actual != filepath.Join(dir, "cache") {
+		t.Errorf("expected %s, got %s", filepath.Join(dir, "cache"), actual)
+	}
+}
+
+func TestRunDoctorChecks(t *testing.T) {
+	failing := func() error { return os.ErrNotExist }
+	passing := func() error { return nil }
+	if !RunDoctorChecks([]DoctorCheck{{Name: "ok", Check: passing}, {Name: "optional", Check: failing, Optional: true}}, false) {
+		t.Errorf("optional check failure should not fail the doctor")
+	}
+	if RunDoctorChecks([]DoctorCheck{{Name: "ok", Check: passing}, {Name: "required", Check: failing, Fix: "fix"}}, false) {
+		t.Errorf("required check failure should fail the doctor")
+	}
+}
+
+func TestRunDoctorChecksFix(t *testing.T) {
+	fixed := false
+	check := DoctorCheck{
+		Name: "fixable",
+		Check: func() error {
+			if !fixed {
+				return os.ErrNotExist
+			}
+			return nil
+		},
+		Apply: func() error {
+			fixed = true
+			return nil
+		},
+	}
+	if RunDoctorChecks([]DoctorCheck{check}, false) || fixed {
+		t.Errorf("the check should fail and stay unfixed without --fix")
+	}
+	if !RunDoctorChecks([]DoctorCheck{check}, true) || !fixed {
+		t.Errorf("the check should be fixed with --fix")
+	}
+	failingFix := DoctorCheck{Name: "unfixable", Check: func() error { return os.ErrNotExist }, Apply: func() error { return os.ErrPermission }}
+	if RunDoctorChecks([]DoctorCheck{failingFix}, true) {
+		t.Errorf("the check should fail when the fix fails")
+	}
+}
+
+func TestParseDefenderStatus(t *testing.T) {
+	enabled, exclusions, err := parseDefenderStatus("True\r\nC:\\Users\\user\\AppData\\Local\\JetBrains\r\nD:\\builds\r\n")
+	if err != nil || !enabled || len(exclusions) != 2 || exclusions[1] != `D:\builds` {
+		t.Errorf("unexpected status %v %v %v", enabled, exclusions, err)
+	}
+	if _, _, err = parseDefenderStatus("True\nN/A: Must be an administrator to view exclusions\n"); err == nil {
+		t.Errorf("the exclusions hidden from a non-administrator should be reported")
+	}
+	if enabled, _, err = parseDefenderStatus("False\nN/A: Must be an administrator to view exclusions\n"); enabled || err != nil {
+		t.Errorf("the disabled protection needs no exclusions, got %v %v", enabled, err)
+	}
+}
+
+func TestIsUnderAny(t *testing.T) {
+	exclusions := []string{`C:\Users\user\AppData\Local\JetBrains\`, "d:/builds"}
+	for path, expected := range map[string]bool{
+		`c:\users\user\appdata\local\jetbrains\Qodana\cache`: true,
+		`C:\Users\user\AppData\Local\JetBrains`:              true,
+		`C:\Users\user\AppData\Local\JetBrainsToolbox`:       false,
+		`D:\builds\project\.qodana\results`:                  true,
+		`E:\builds`:                                          false,
+	} {
+		if actual := isUnderAny(path, exclusions); actual != expected {
+			t.Errorf("isUnderAny(%s) = %v, expected %v", path, actual, expected)
+		}
+	}
+}
+
+func TestSpotlightExcluded(t *testing.T) {
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "cache", "linter")
+	if spotlightExcluded(cache) {
+		t.Fatalf("%s is not excluded", cache)
+	}
+	if !spotlightExcluded(filepath.Join(dir, "caches.noindex", "linter")) {
+		t.Errorf("the directories with the .noindex suffix are excluded")
+	}
+	check := spotlightCheck("cache", filepath.Join(dir, "cache"))
+	if err := check.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if !spotlightExcluded(cache) {
+		t.Errorf("%s should be excluded after the fix", cache)
+	}
+}
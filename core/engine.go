@@ -0,0 +1,65 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdengine"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ApplyGameEngine prepares the analysis of a Unity or Unreal Engine project: the solution generated by the editor
+// is analyzed unless qodana.yaml sets another one, and a warning is shown if it is missing or stale.
+//
+// It returns the directories of the editor caches and build outputs to exclude from the analysis.
+func ApplyGameEngine(projectDir string, qodanaYaml *qdyaml.QodanaYaml) []string {
+	project, ok := qdengine.Detect(projectDir)
+	if !ok {
+		return nil
+	}
+	if qodanaYaml.DotNet.IsEmpty() {
+		if project.Solution != "" {
+			qodanaYaml.DotNet.Solution = project.Solution
+		} else if project.Descriptor != "" {
+			qodanaYaml.DotNet.Project = project.Descriptor
+		}
+	}
+	if project.Solution == "" {
+		msg.WarningMessage("No solution found in the %s project, generate it in the editor before the analysis", project.Engine)
+	} else if changed, stale := project.Stale(projectDir); stale {
+		msg.WarningMessage(
+			"%s is older than %s, regenerate the solution in the editor to analyze the current %s project",
+			project.Solution,
+			changed,
+			project.Engine,
+		)
+	}
+	var excluded []string
+	for _, path := range project.Excluded {
+		// the globs are excluded if the directory they start from exists
+		if _, err := os.Stat(filepath.Join(projectDir, filepath.FromSlash(strings.SplitN(path, "*", 2)[0]))); err == nil {
+			excluded = append(excluded, path)
+		}
+	}
+	if len(excluded) > 0 {
+		msg.SuccessMessage("Detected a %s project, excluding %s", project.Engine, strings.Join(excluded, ", "))
+	}
+	return excluded
+}
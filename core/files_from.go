@@ -0,0 +1,145 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const filesScopeName = "files-scope.json"
+
+// FilesFromScript writes the files listed in filesFrom ("-" for stdin) in the scope format of the scoped script
+// and returns the script analysing only them, the git history is not used.
+func FilesFromScript(filesFrom string, c commoncontext.Context) (string, error) {
+	var list io.Reader = os.Stdin
+	if filesFrom != "-" {
+		f, err := os.Open(filesFrom)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the files to analyse: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		list = f
+	}
+	projectDir, err := filepath.Abs(c.ProjectDir)
+	if err != nil {
+		return "", err
+	}
+	files, err := readFilesList(list, projectDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the files to analyse: %w", err)
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no files to analyse in %s", filesFrom)
+	}
+
+	scopeFile := filepath.Join(c.CacheDir, filesScopeName)
+	script := "scoped:" + scopeFile
+	container := c.Ide == ""
+	if container {
+		// the container sees the cache at its mount point
+		script = "scoped:" + path.Join("/data/cache", filesScopeName)
+	}
+	changes, err := wholeFileChanges(projectDir, files, container)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(c.CacheDir, 0o755); err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(scopeFile, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write scope file: %w", err)
+	}
+	if err = os.MkdirAll(c.LogDir(), 0o755); err != nil {
+		return "", err
+	}
+	if err = utils.CopyFile(scopeFile, filepath.Join(c.LogDir(), "changes.json")); err != nil {
+		return "", err
+	}
+	msg.SuccessMessage("Analysing %d files", len(files))
+	return utils.QuoteForWindows(script), nil
+}
+
+// readFilesList returns the listed files relative to projectDir, skipping the files outside it or missing.
+func readFilesList(r io.Reader, projectDir string) ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" {
+			continue
+		}
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(projectDir, name)
+		}
+		rel, err := filepath.Rel(projectDir, name)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			msg.WarningMessage("Skipping %s outside of the project directory", name)
+			continue
+		}
+		if info, err := os.Stat(name); err != nil || info.IsDir() {
+			msg.WarningMessage("Skipping %s, not a file", name)
+			continue
+		}
+		if !seen[rel] {
+			seen[rel] = true
+			files = append(files, rel)
+		}
+	}
+	return files, scanner.Err()
+}
+
+// wholeFileChanges marks the files as added entirely, with the paths seen by the container if container is set.
+func wholeFileChanges(projectDir string, files []string, container bool) (git.ChangedFiles, error) {
+	changes := git.ChangedFiles{Files: make([]*git.ChangedFile, 0, len(files))}
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(projectDir, file))
+		if err != nil {
+			return changes, err
+		}
+		lines := bytes.Count(content, []byte("\n"))
+		if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+			lines++
+		}
+		added := []*git.ChangedRegion{}
+		if lines > 0 {
+			added = append(added, &git.ChangedRegion{FirstLine: 1, Count: lines})
+		}
+		p := filepath.Join(projectDir, file)
+		if container {
+			p = path.Join("/data/project", filepath.ToSlash(file))
+		}
+		changes.Files = append(changes.Files, &git.ChangedFile{Path: p, Added: added, Deleted: []*git.ChangedRegion{}})
+	}
+	return changes, nil
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"encoding/json"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFilesFromScript(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectDir, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"src/a.go": "package a\n\nfunc A() {}\n", "b.go": "package b"} {
+		if err := os.WriteFile(filepath.Join(projectDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list := filepath.Join(t.TempDir(), "files.txt")
+	content := strings.Join([]string{"src/a.go", "", filepath.Join(projectDir, "b.go"), "src/a.go", "missing.go", "src", "../outside.go"}, "\n")
+	if err := os.WriteFile(list, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		ide   string
+		cache string
+	}{
+		{ide: "QDGO"},
+		{ide: "", cache: "/data/cache"},
+	} {
+		c := commoncontext.Context{Ide: tc.ide, ProjectDir: projectDir, CacheDir: t.TempDir(), ResultsDir: t.TempDir()}
+		script, err := FilesFromScript(list, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scopeFile := filepath.Join(c.CacheDir, filesScopeName)
+		expectedScript := "scoped:" + scopeFile
+		if tc.cache != "" {
+			expectedScript = "scoped:" + tc.cache + "/" + filesScopeName
+		}
+		if script != expectedScript {
+			t.Errorf("got script %q, want %q", script, expectedScript)
+		}
+
+		data, err := os.ReadFile(scopeFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var changes git.ChangedFiles
+		if err = json.Unmarshal(data, &changes); err != nil {
+			t.Fatal(err)
+		}
+		paths := make([]string, 0, len(changes.Files))
+		for _, f := range changes.Files {
+			paths = append(paths, f.Path)
+		}
+		expectedPaths := []string{filepath.Join(projectDir, "src", "a.go"), filepath.Join(projectDir, "b.go")}
+		if tc.ide == "" {
+			expectedPaths = []string{"/data/project/src/a.go", "/data/project/b.go"}
+		}
+		if !reflect.DeepEqual(paths, expectedPaths) {
+			t.Errorf("got paths %v, want %v", paths, expectedPaths)
+		}
+		if changes.Files[0].Added[0].Count != 3 || changes.Files[1].Added[0].Count != 1 {
+			t.Errorf("unexpected regions %+v %+v", changes.Files[0].Added[0], changes.Files[1].Added[0])
+		}
+	}
+}
+
+func TestFilesFromScriptEmpty(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "files.txt")
+	if err := os.WriteFile(list, []byte("missing.go\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := commoncontext.Context{Ide: "QDGO", ProjectDir: t.TempDir(), CacheDir: t.TempDir(), ResultsDir: t.TempDir()}
+	if _, err := FilesFromScript(list, c); err == nil {
+		t.Error("expected an error without files to analyse")
+	}
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/platform"
+	platformcmd "github.com/JetBrains/qodana-cli/v2024/platform/cmd"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	fixesPatchFile = "qodana-fixes.patch"
+	fixesPatchDir  = "fixes"
+)
+
+// fixesRequested reports whether the analysis modifies the project with quick-fixes or cleanup.
+func fixesRequested(c corescan.Context) bool {
+	if !c.FixesSupported() {
+		return false
+	}
+	switch strings.ToLower(c.FixesStrategy()) {
+	case "apply", "cleanup":
+		return true
+	}
+	return c.ApplyFixes() || c.Cleanup()
+}
+
+// fixesSelection returns the restriction of the applied quick-fixes by inspection and path.
+func fixesSelection(c corescan.Context) platform.FixesSelection {
+	return platform.FixesSelection{Rules: c.FixesRules(), Include: c.FixesInclude()}
+}
+
+// snapshotBeforeFixes records the project state when the quick-fixes are selected, exported or pushed instead of
+// being left in the project as is, returns nil otherwise.
+func snapshotBeforeFixes(ctx context.Context, c corescan.Context, scenario corescan.RunScenario) *git.WorkTreeSnapshot {
+	if !fixesRequested(c) {
+		return nil
+	}
+	var option string
+	switch {
+	case c.FixesPush():
+		option = "--fixes-push"
+	case c.FixesOutput() == platformcmd.FixesOutputPatch:
+		option = "--fixes-output=" + platformcmd.FixesOutputPatch
+	case !fixesSelection(c).IsEmpty():
+		option = "--apply-fixes-rule/--apply-fixes-include"
+	default:
+		return nil
+	}
+	if scenario == corescan.RunScenarioFullHistory || scenario == corescan.RunScenarioScoped {
+		log.Fatalf("%s is not supported with %s analysis", option, scenario)
+	}
+	if c.FixesPush() {
+		if dirty, err := git.HasTrackedChanges(ctx, c.ProjectDir(), c.LogDir()); err != nil || dirty {
+			log.Fatalf("%s requires a git repository without uncommitted changes", option)
+		}
+	}
+	snapshot, err := git.TakeSnapshot(ctx, c.ProjectDir(), c.LogDir())
+	if err != nil {
+		log.Fatalf("%s requires the project to be a git repository: %s", option, err)
+	}
+	return snapshot
+}
+
+// revertFixes reverts the quick-fixes applied since the snapshot, when the run is interrupted before they are handled.
+func revertFixes(ctx context.Context, c corescan.Context, snapshot *git.WorkTreeSnapshot) {
+	changes, err := snapshot.Changes(ctx, c.LogDir(), c.ResultsDir(), c.ReportDir(), c.CacheDir(), c.LogDir())
+	if err == nil {
+		err = snapshot.Restore(ctx, changes, c.LogDir())
+	}
+	if err != nil {
+		msg.WarningMessage("Failed to revert the applied quick-fixes: %s", err)
+	}
+}
+
+// finishFixes handles the quick-fixes applied since the snapshot according to the options.
+func finishFixes(ctx context.Context, c corescan.Context, snapshot *git.WorkTreeSnapshot) {
+	if selection := fixesSelection(c); !selection.IsEmpty() {
+		selectFixes(ctx, c, snapshot, selection)
+	}
+	switch {
+	case c.FixesPush():
+		pushFixes(ctx, c, snapshot)
+	case c.FixesOutput() == platformcmd.FixesOutputPatch:
+		exportFixes(ctx, c, snapshot)
+	}
+}
+
+// selectFixes reverts the applied quick-fixes that are not selected.
+func selectFixes(ctx context.Context, c corescan.Context, snapshot *git.WorkTreeSnapshot, selection platform.FixesSelection) {
+	changes := appliedFixes(ctx, c, snapshot)
+	selected, err := selection.Filter(changes.Patch, snapshot.Root, c.ProjectDir(), platform.GetSarifPath(c.ResultsDir()))
+	if err != nil {
+		log.Fatalf("Failed to select the quick-fixes: %s", err)
+	}
+	if err = snapshot.Restore(ctx, changes, c.LogDir()); err != nil {
+		log.Fatalf("Failed to revert the quick-fixes: %s", err)
+	}
+	if err = git.ApplyPatch(ctx, snapshot.Root, selected, c.LogDir()); err != nil {
+		log.Fatalf("Failed to apply the selected quick-fixes: %s", err)
+	}
+	log.Debugf("Kept quick-fixes in %d of %d changed files", len(git.SplitPatch(selected)), len(changes.Files()))
+}
+
+// appliedFixes returns the changes made by quick-fixes since the snapshot, ignoring Qodana's own files.
+func appliedFixes(ctx context.Context, c corescan.Context, snapshot *git.WorkTreeSnapshot) *git.WorkTreeChanges {
+	changes, err := snapshot.Changes(ctx, c.LogDir(), c.ResultsDir(), c.ReportDir(), c.CacheDir(), c.LogDir())
+	if err != nil {
+		log.Fatalf("Failed to compute the applied quick-fixes: %s", err)
+	}
+	return changes
+}
+
+// exportFixes writes the changes made by quick-fixes since the snapshot as patches to the results directory,
+// then restores the project to the snapshot state.
+func exportFixes(ctx context.Context, c corescan.Context, snapshot *git.WorkTreeSnapshot) {
+	changes := appliedFixes(ctx, c, snapshot)
+	if err := writeFixesPatches(c.ResultsDir(), changes.Patch); err != nil {
+		log.Fatalf("Failed to write the quick-fixes patch: %s", err)
+	}
+	if err := snapshot.Restore(ctx, changes, c.LogDir()); err != nil {
+		log.Fatalf("Failed to restore the project after applying quick-fixes: %s", err)
+	}
+	if changes.Patch == "" {
+		msg.SuccessMessage("No quick-fixes were applied")
+		return
+	}
+	msg.SuccessMessage("Quick-fixes are saved to %s", filepath.Join(c.ResultsDir(), fixesPatchFile))
+}
+
+// writeFixesPatches writes the combined patch and a patch per changed file under resultsDir.
+func writeFixesPatches(resultsDir string, patch string) error {
+	if err := os.WriteFile(filepath.Join(resultsDir, fixesPatchFile), []byte(patch), 0o644); err != nil {
+		return err
+	}
+	for file, filePatch := range git.SplitPatch(patch) {
+		path := filepath.Join(resultsDir, fixesPatchDir, filepath.FromSlash(file)+".patch")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(filePatch), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pushFixes commits the quick-fixes to a new branch, pushes it and opens a pull request for it.
+func pushFixes(ctx context.Context, c corescan.Context, snapshot *git.WorkTreeSnapshot) {
+	changes := appliedFixes(ctx, c, snapshot)
+	if changes.Patch == "" {
+		msg.SuccessMessage("No quick-fixes were applied, nothing to push")
+		return
+	}
+	base, err := git.Branch(ctx, snapshot.Root, c.LogDir())
+	if err != nil || base == "HEAD" {
+		base = os.Getenv(qdenv.QodanaBranch)
+	}
+	branch := c.FixesBranch()
+	if branch == "" {
+		branch = "qodana/fixes-" + time.Now().UTC().Format("20060102-150405")
+	}
+	err = git.CommitToBranch(ctx, snapshot.Root, branch, changes.Files(), c.FixesCommitMessage(), c.FixesAuthor(), c.LogDir())
+	if err != nil {
+		log.Fatalf("Failed to commit the quick-fixes: %s", err)
+	}
+	if err = git.Push(ctx, snapshot.Root, c.FixesRemote(), branch, c.LogDir()); err != nil {
+		log.Fatalf("Failed to push the quick-fixes: %s", err)
+	}
+	msg.SuccessMessage("Quick-fixes are pushed to %s/%s", c.FixesRemote(), branch)
+
+	if base == "" {
+		msg.WarningMessage("Unable to determine the target branch of the pull request, set %s to open it", qdenv.QodanaBranch)
+		return
+	}
+	remoteUrl, err := git.RemoteUrlOf(ctx, snapshot.Root, c.FixesRemote(), c.LogDir())
+	if err != nil {
+		msg.WarningMessage("Unable to open a pull request: %s", err)
+		return
+	}
+	prUrl, err := platform.OpenFixesPullRequest(
+		platform.FixesPullRequest{
+			RemoteUrl: remoteUrl,
+			Head:      branch,
+			Base:      base,
+			Title:     strings.SplitN(c.FixesCommitMessage(), "\n", 2)[0],
+			Body:      platform.FixesSummary(changes.Patch),
+		},
+	)
+	if err != nil {
+		msg.WarningMessage("Unable to open a pull request: %s", err)
+		return
+	}
+	msg.SuccessMessage("Opened %s", prUrl)
+}
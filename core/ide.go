@@ -17,10 +17,12 @@
 package core
 
 import (
+	"context"
 	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
 	"github.com/JetBrains/qodana-cli/v2024/core/startup"
 	"github.com/JetBrains/qodana-cli/v2024/platform"
 	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	"os"
 	"path/filepath"
@@ -53,17 +55,47 @@ func getIdeExitCode(resultsDir string, c int) (res int) {
 	return c
 }
 
-func runQodanaLocal(c corescan.Context) (int, error) {
+func runQodanaLocal(ctx context.Context, c corescan.Context) (int, error) {
 	writeProperties(c)
 	args := getIdeRunCommand(c)
-	ideProcess, err := utils.RunCmdWithTimeout(
+	span := qdtrace.Start("ide run")
+	span.SetAttribute("qodana.ide", c.Ide())
+	stopWatchingMemory := watchMemory()
+	stages := newStageWatcher(c, span)
+	stdout, stderr := os.Stdout, os.Stderr
+	closeStdout, closeStderr := func() {}, func() {}
+	if stages.enabled() {
+		stdout, closeStdout = stages.follow(os.Stdout)
+		stderr, closeStderr = stages.follow(os.Stderr)
+	}
+	stopFollowingIdeaLog := func() {}
+	if c.FollowIdeaLog() {
+		stopFollowingIdeaLog = followIdeaLog(c.LogDir(), c.Verbose())
+	}
+	ideProcess, err := utils.RunCmdUntil(
+		ctx,
 		"",
-		os.Stdout, os.Stderr,
+		stdout, stderr,
 		c.GetAnalysisTimeout(),
 		utils.QodanaTimeoutExitCodePlaceholder,
+		stages.stop,
 		args...,
 	)
+	closeStdout()
+	closeStderr()
+	stopFollowingIdeaLog()
+	timedOut := stages.finish()
+	stopWatchingMemory()
 	res := getIdeExitCode(c.ResultsDir(), ideProcess)
+	span.SetAttribute("qodana.exit_code", strconv.Itoa(res))
+	switch {
+	case timedOut != nil:
+		span.End(timedOut)
+	case res == utils.QodanaTimeoutExitCodePlaceholder:
+		span.End(&qdtrace.TimeoutError{Stage: "analysis", Timeout: c.GetAnalysisTimeout()})
+	default:
+		span.End(err)
+	}
 	if res > utils.QodanaSuccessExitCode && res != utils.QodanaFailThresholdExitCode {
 		postAnalysis(c)
 		return res, err
@@ -210,10 +242,28 @@ func GetIdeArgs(c corescan.Context) []string {
 		if c.JvmDebugPort() > 0 {
 			arguments = append(arguments, "--jvm-debug-port", strconv.Itoa(c.JvmDebugPort()))
 		}
+		if c.AnalysisThreads() > 0 {
+			arguments = append(arguments, "--analysis-threads", strconv.Itoa(c.AnalysisThreads()))
+		}
+		if c.IndexingThreads() > 0 {
+			arguments = append(arguments, "--indexing-threads", strconv.Itoa(c.IndexingThreads()))
+		}
+		if c.BootstrapTimeout() > 0 {
+			arguments = append(arguments, "--bootstrap-timeout", c.BootstrapTimeout().String())
+		}
+		if c.IndexingTimeout() > 0 {
+			arguments = append(arguments, "--indexing-timeout", c.IndexingTimeout().String())
+		}
+		if c.InspectionTimeout() > 0 {
+			arguments = append(arguments, "--inspection-timeout", c.InspectionTimeout().String())
+		}
 
 		for _, property := range c.Property() {
 			arguments = append(arguments, "--property="+property)
 		}
+		for _, option := range vmOptions(c) {
+			arguments = append(arguments, "--property="+option)
+		}
 	}
 	return arguments
 }
@@ -236,11 +286,14 @@ func postAnalysis(c corescan.Context) {
 
 // installPlugins runs plugin installer for every plugin id in qodana.yaml.
 func installPlugins(c corescan.Context) {
+	if len(c.QodanaYaml().Plugins) > 0 {
+		preparePlugins(c)
+	}
 	if !c.IsNative() {
 		return
 	}
 
-	plugins := c.QodanaYaml().Plugins
+	plugins := pluginsToInstall(c)
 	if len(plugins) > 0 {
 		setInstallPluginsVmoptions(c)
 	}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"os"
+	"path/filepath"
+)
+
+// ignoreConfigName is the configuration with the .qodanaignore paths merged in, relative to the project directory.
+const ignoreConfigName = ".qodana/qodana.ignore.yaml"
+
+// ApplyIgnoreFile merges .qodanaignore and the excluded paths into the exclude configuration, so they are used
+// by every linter.
+//
+// It returns the configuration to pass to the linter instead of configName and a function removing it after the run.
+func ApplyIgnoreFile(projectDir string, configName string, qodanaYaml *qdyaml.QodanaYaml, excluded []string) (string, func(), error) {
+	ignored, err := qdyaml.LoadIgnoreFile(projectDir)
+	if err != nil || len(ignored)+len(excluded) == 0 {
+		return configName, func() {}, err
+	}
+	all := append(append([]string{}, ignored...), excluded...)
+	path := filepath.Join(projectDir, ignoreConfigName)
+	if err = qdyaml.WriteIgnoredConfig(projectDir, configName, all, path); err != nil {
+		return configName, func() {}, err
+	}
+	qodanaYaml.ExcludeIgnored(all)
+	if len(ignored) > 0 {
+		msg.SuccessMessage("Excluding %d paths from %s", len(ignored), qdyaml.IgnoreFileName)
+	}
+	return filepath.ToSlash(ignoreConfigName), func() {
+		_ = os.Remove(path)
+		_ = os.Remove(filepath.Dir(path)) // only if empty
+	}, nil
+}
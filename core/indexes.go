@@ -0,0 +1,82 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdindex"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdmetrics"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	log "github.com/sirupsen/logrus"
+	"path/filepath"
+)
+
+// ideSystemDir is the IDE system directory in the cache, with the indexes of the project.
+func ideSystemDir(c corescan.Context) string {
+	return filepath.Join(c.CacheDir(), "idea", c.Prod().GetVersionBranch())
+}
+
+// verifyIndexes drops the indexes restored from the cache that the IDE can't reuse, so the project is re-indexed
+// from scratch instead of failing the analysis. It returns the indexes before the run.
+func verifyIndexes(c corescan.Context) qdindex.Snapshot {
+	systemDir := ideSystemDir(c)
+	if reason, ok := qdindex.Verify(systemDir, c.Prod().Build); !ok {
+		msg.WarningMessage("Cached indexes can't be reused, re-indexing the project: %s", reason)
+		if err := qdindex.Invalidate(systemDir); err != nil {
+			log.Fatalf("Failed to remove the cached indexes from %s: %s", systemDir, err)
+		}
+	}
+	if err := qdindex.RemoveManifest(systemDir); err != nil {
+		log.Warnf("Failed to remove the manifest of the indexes: %s", err)
+	}
+	return qdindex.Scan(systemDir)
+}
+
+// reportIndexes prints how much of the cached indexes the run reused and records the indexes left by a successful run.
+func reportIndexes(c corescan.Context, before qdindex.Snapshot, exitCode int) {
+	systemDir := ideSystemDir(c)
+	stats := qdindex.Compare(before, qdindex.Scan(systemDir))
+	qdmetrics.Gauge(
+		"qodana_cache_restored_bytes",
+		"Size of the IDE indexes in the Qodana cache before the run in bytes.",
+		float64(stats.Restored),
+		nil,
+	)
+	qdmetrics.Gauge(
+		"qodana_cache_index_reused_ratio",
+		"Share of the IDE indexes after the run reused from the Qodana cache.",
+		stats.ReusedPercent()/100,
+		nil,
+	)
+	if stats.Restored > 0 {
+		msg.SuccessMessage(
+			"Cache: %s of indexes restored, %.0f%% of %s reused",
+			utils.FormatSize(stats.Restored),
+			stats.ReusedPercent(),
+			utils.FormatSize(stats.Total),
+		)
+	} else {
+		log.Infof("Cache: no indexes restored, %s built", utils.FormatSize(stats.Total))
+	}
+	if exitCode != utils.QodanaSuccessExitCode && exitCode != utils.QodanaFailThresholdExitCode {
+		return
+	}
+	if err := qdindex.WriteManifest(systemDir, c.Prod().Build); err != nil {
+		log.Warnf("Failed to write the manifest of the indexes: %s", err)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdprofile"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"os"
+	"path/filepath"
+)
+
+// inlineProfileName is the profile generated from the inline section of qodana.yaml, relative to the project directory.
+const inlineProfileName = ".qodana/qodana.profile.xml"
+
+// ApplyInlineProfile generates the profile of the inline section of qodana.yaml on top of the selected profile.
+//
+// It returns the profile path to pass to the linter instead of profilePath and a function removing it after the run.
+func ApplyInlineProfile(
+	projectDir string,
+	profileName string,
+	profilePath string,
+	qodanaYaml *qdyaml.QodanaYaml,
+) (string, func(), error) {
+	if qodanaYaml.Profile.Inline.IsEmpty() {
+		return profilePath, func() {}, nil
+	}
+	profile, err := qdprofile.Compose(projectDir, profileName, profilePath, qodanaYaml)
+	if err != nil {
+		return profilePath, func() {}, err
+	}
+	path := filepath.Join(projectDir, inlineProfileName)
+	if err = profile.Write(path); err != nil {
+		return profilePath, func() {}, err
+	}
+	msg.SuccessMessage("Using the inline profile of qodana.yaml based on %s", profile.Name)
+	return filepath.ToSlash(inlineProfileName), func() {
+		_ = os.Remove(path)
+		_ = os.Remove(filepath.Dir(path)) // only if empty
+	}, nil
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdjdk"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+)
+
+// provisionJdk sets JAVA_HOME of the native analysis (and of the bootstrap) to the JDK required by the Gradle toolchain
+// or the Maven enforcer rules of the project, downloading it into the cache if it is not installed,
+// so the project isn't analysed against the wrong JDK. The projectJDK of qodana.yaml takes precedence.
+func provisionJdk(c corescan.Context) {
+	if !c.ProvisionJdk() || c.Prod().BaseScriptName != product.Idea || c.QodanaYaml().ProjectJdk != "" {
+		return
+	}
+	requirement, ok := qdjdk.Detect(c.ProjectDir())
+	if !ok {
+		return
+	}
+	if home := os.Getenv(qdenv.JavaHomeEnv); home != "" {
+		if version, err := qdjdk.Version(home); err == nil && version == requirement.Version {
+			return
+		}
+	}
+	home, ok := qdjdk.Find(requirement.Version, c.CacheDir())
+	if !ok {
+		var err error
+		msg.PrintProcess(
+			func(_ *pterm.SpinnerPrinter) {
+				home, err = qdjdk.NewDownloader().Download(requirement.Version, c.CacheDir())
+			},
+			fmt.Sprintf("Downloading JDK %d required by %s", requirement.Version, msg.PrimaryBold(requirement.Source)),
+			fmt.Sprintf("downloading JDK %d to %s", requirement.Version, c.CacheDir()),
+		)
+		if err != nil {
+			msg.WarningMessage("Failed to provision JDK %d required by %s: %s", requirement.Version, requirement.Source, err)
+			return
+		}
+	}
+	if err := os.Setenv(qdenv.JavaHomeEnv, home); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Setenv("PATH", filepath.Join(home, "bin")+string(os.PathListSeparator)+os.Getenv("PATH")); err != nil {
+		log.Fatal(err)
+	}
+	msg.SuccessMessage("Using JDK %d from %s, required by %s", requirement.Version, home, requirement.Source)
+}
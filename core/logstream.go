@@ -0,0 +1,134 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bufio"
+	"errors"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// ideaLogFileName is the name of the IDE log file in the log directory of the run.
+	ideaLogFileName = "idea.log"
+	// ideaLogPollInterval is how often the IDE log file is checked for new lines.
+	ideaLogPollInterval = 500 * time.Millisecond
+)
+
+// printLinterLine prints the line of the linter output. With verbose, the start of each stage is marked
+// and the line is colored by its severity.
+func printLinterLine(line string, verbose bool) {
+	if !verbose {
+		msg.PrintLinterLog(line)
+		return
+	}
+	if stage, ok := ideStageOf(line); ok {
+		msg.PrintStageMarker(stage.name)
+	}
+	msg.PrintLinterLogColored(line)
+}
+
+// followIdeaLog prints the lines appended to the IDE log of the run until the returned function is called:
+// all of them with verbose, otherwise only the warnings and the errors.
+func followIdeaLog(logDir string, verbose bool) func() {
+	return tailFile(
+		filepath.Join(logDir, ideaLogFileName), ideaLogPollInterval, func(line string) {
+			if verbose || msg.LinterLogLevel(line) <= log.WarnLevel {
+				msg.PrintIdeaLog(line)
+			}
+		},
+	)
+}
+
+// tailFile calls onLine for every line appended to the file at path, the lines written before are skipped.
+// The file may not exist yet and may be recreated or truncated, then it is followed from its start.
+// The returned function stops following the file once the lines written so far are passed to onLine.
+func tailFile(path string, interval time.Duration, onLine func(string)) func() {
+	var offset int64
+	if info, err := os.Stat(path); err == nil {
+		offset = info.Size()
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var partial strings.Builder
+		for {
+			stopped := false
+			select {
+			case <-stop:
+				stopped = true
+			case <-time.After(interval):
+			}
+			offset = readAppended(path, offset, &partial, onLine)
+			if stopped {
+				if partial.Len() > 0 {
+					onLine(partial.String())
+				}
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// readAppended passes the complete lines written to the file after offset to onLine, keeping the incomplete last
+// line in partial, and returns the new offset.
+func readAppended(path string, offset int64, partial *strings.Builder, onLine func(string)) int64 {
+	file, err := os.Open(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Debugf("Failed to follow %s: %s", path, err)
+		}
+		return offset
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	info, err := file.Stat()
+	if err != nil {
+		return offset
+	}
+	if info.Size() < offset {
+		offset = 0
+		partial.Reset()
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return offset
+	}
+	reader := bufio.NewReader(file)
+	for {
+		chunk, err := reader.ReadString('\n')
+		offset += int64(len(chunk))
+		partial.WriteString(chunk)
+		if err != nil {
+			return offset
+		}
+		onLine(strings.TrimRight(partial.String(), "\r\n"))
+		partial.Reset()
+	}
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcontainer"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdmemory"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdmetrics"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"strings"
+	"time"
+)
+
+const memorySampleInterval = 5 * time.Second
+
+// memoryPeak and memoryLimit are the peak memory used by the IDE runs of the scan and the limit it was measured against.
+var memoryPeak, memoryLimit int64
+
+// watchMemory warns when the memory used during the IDE run approaches the limit, the returned function stops watching.
+func watchMemory() func() {
+	monitor := qdmemory.Start(
+		memorySampleInterval, func(usage int64, limit int64) {
+			msg.WarningMessage(
+				"The analysis uses %s of the %s memory limit and can be killed when it runs out of memory",
+				utils.FormatSize(usage),
+				utils.FormatSize(limit),
+			)
+		},
+	)
+	return func() {
+		peak, limit := monitor.Stop()
+		memoryPeak = max(memoryPeak, peak)
+		if limit > 0 {
+			memoryLimit = limit
+		}
+		qdmetrics.Gauge("qodana_memory_peak_bytes", "Peak memory used during the analysis in bytes.", float64(memoryPeak), nil)
+	}
+}
+
+// DiagnoseOutOfMemory explains the analysis killed with utils.QodanaOutOfMemoryExitCode: the memory used,
+// the heap dumps written and the changes of the heap size or of the memory limit to try.
+func DiagnoseOutOfMemory(c corescan.Context) {
+	diagnosis := qdmemory.Diagnosis{Peak: memoryPeak, Limit: memoryLimit, Heap: heapSize(vmOptions(c))}
+	if c.Ide() == "" {
+		if info, err := qdcontainer.GetContainerClient().Info(context.Background()); err == nil {
+			diagnosis.Limit = info.MemTotal
+		}
+	}
+	var lines []string
+	if diagnosis.Peak > 0 && diagnosis.Limit > 0 {
+		lines = append(
+			lines,
+			fmt.Sprintf("Peak memory used: %s of the %s limit", utils.FormatSize(diagnosis.Peak), utils.FormatSize(diagnosis.Limit)),
+		)
+	} else if diagnosis.Limit > 0 {
+		lines = append(lines, fmt.Sprintf("Memory limit: %s", utils.FormatSize(diagnosis.Limit)))
+	}
+	if dumps := qdmemory.HeapDumps(c.LogDir(), c.ProjectDir()); len(dumps) > 0 {
+		lines = append(lines, fmt.Sprintf("Heap dumps: %s", strings.Join(dumps, ", ")))
+	} else {
+		lines = append(
+			lines,
+			fmt.Sprintf("No heap dump in %s: the process was killed by the system before the heap was exhausted", c.LogDir()),
+		)
+	}
+	for _, suggestion := range diagnosis.Suggestions() {
+		lines = append(lines, "To fix it, "+suggestion)
+	}
+	msg.ErrorMessage(
+		"The analysis was killed, most likely because it ran out of memory\n   %s",
+		strings.Join(lines, "\n   "),
+	)
+}
+
+// heapSize returns the maximum heap size set by the last -Xmx of the options in bytes, 0 if not set.
+func heapSize(options []string) int64 {
+	for i := len(options) - 1; i >= 0; i-- {
+		if size, ok := strings.CutPrefix(options[i], "-Xmx"); ok {
+			heap, _ := parseMemorySize(size)
+			return heap
+		}
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdnode"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"os"
+)
+
+// provisionNode puts the Node.js version required by .nvmrc, .node-version or the engines of package.json
+// on PATH of the JavaScript analysis (and of the bootstrap), downloading it into the cache if it is not installed,
+// so the linters run against the Node.js the project uses. In the container the Node.js version is only validated.
+func provisionNode(c corescan.Context) {
+	if !c.ProvisionNode() || c.Prod().BaseScriptName != product.WebStorm {
+		return
+	}
+	requirement, ok := qdnode.Detect(c.ProjectDir())
+	if !ok {
+		return
+	}
+	r, err := qdnode.ParseRange(requirement.Spec)
+	if err != nil {
+		msg.WarningMessage("Ignoring the Node.js version of %s: %s", requirement.Source, err)
+		return
+	}
+	installed, err := qdnode.Installed("node")
+	if err == nil && r.Match(installed) {
+		return
+	}
+	if qdenv.IsContainer() {
+		if err != nil {
+			msg.WarningMessage("Node.js %s is required by %s, but Node.js is not available: %s", r, requirement.Source, err)
+		} else {
+			msg.WarningMessage(
+				"Node.js %s is required by %s, but the container provides Node.js %s, the results may differ from the project's",
+				r,
+				requirement.Source,
+				installed,
+			)
+		}
+		return
+	}
+	home, release, ok := qdnode.Find(r, c.CacheDir())
+	if !ok {
+		msg.PrintProcess(
+			func(_ *pterm.SpinnerPrinter) {
+				home, release, err = qdnode.NewDownloader().Download(r, c.CacheDir())
+			},
+			fmt.Sprintf("Downloading Node.js %s required by %s", r, msg.PrimaryBold(requirement.Source)),
+			fmt.Sprintf("downloading Node.js %s to %s", r, c.CacheDir()),
+		)
+		if err != nil {
+			msg.WarningMessage("Failed to provision Node.js %s required by %s: %s", r, requirement.Source, err)
+			return
+		}
+	}
+	if err := os.Setenv("PATH", qdnode.BinDir(home)+string(os.PathListSeparator)+os.Getenv("PATH")); err != nil {
+		log.Fatal(err)
+	}
+	msg.SuccessMessage("Using Node.js %s from %s, required by %s", release, home, requirement.Source)
+}
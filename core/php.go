@@ -0,0 +1,91 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+)
+
+// phpVersionsProperty is the property of the merged results listing the PHP versions the problem is reported for.
+const phpVersionsProperty = "phpVersions"
+
+// isPhpVersionMatrix returns true if the project is analysed for several PHP versions, php.versions of qodana.yaml.
+func isPhpVersionMatrix(c corescan.Context) bool {
+	return c.Prod().BaseScriptName == product.PhpStorm && len(c.QodanaYaml().Php.Versions) > 0
+}
+
+// runPhpVersionMatrix analyses the project once for every PHP version of php.versions in qodana.yaml and merges
+// the results, tagging each with the versions it is reported for, to find the version-specific problems before upgrades.
+func runPhpVersionMatrix(ctx context.Context, c corescan.Context) (int, error) {
+	versions := c.QodanaYaml().Php.Versions
+	reports := make([]*sarif.Report, 0, len(versions))
+	exitCode := utils.QodanaSuccessExitCode
+	var last corescan.Context
+	for i, version := range versions {
+		configPath := filepath.Join(c.LogDir(), fmt.Sprintf("qodana-php-%s.yaml", version))
+		last = c.StageOfPhpVersionMatrix(version, configPath)
+		if err := os.MkdirAll(c.LogDir(), os.ModePerm); err != nil {
+			return 0, err
+		}
+		config := last.QodanaYaml()
+		if err := config.WriteConfig(configPath); err != nil {
+			return 0, fmt.Errorf("failed to write the config of PHP %s: %w", version, err)
+		}
+
+		msg.WarningMessage("[%d/%d] Running analysis for PHP %s", i+1, len(versions), version)
+		code, err := runQodanaLocal(ctx, last)
+		if err != nil {
+			return code, err
+		}
+		if code != utils.QodanaSuccessExitCode && code != utils.QodanaFailThresholdExitCode {
+			log.Errorf("Qodana analysis for PHP %s exited with code %d. Aborting", version, code)
+			return code, nil
+		}
+		if code == utils.QodanaFailThresholdExitCode {
+			exitCode = code
+		}
+		report, err := platform.ReadReport(platform.GetSarifPath(last.ResultsDir()))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read the report for PHP %s: %w", version, err)
+		}
+		reports = append(reports, report)
+	}
+
+	platform.TagReports(reports, versions, phpVersionsProperty)
+	finalReport, err := platform.CombineReports(reports)
+	if err != nil {
+		return 0, err
+	}
+	if err = utils.CopyDir(last.ResultsDir(), c.ResultsDir()); err != nil {
+		return 0, err
+	}
+	if err = platform.WriteReport(platform.GetSarifPath(c.ResultsDir()), finalReport); err != nil {
+		return 0, err
+	}
+	saveReport(c)
+	return exitCode, nil
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdplugin"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	log "github.com/sirupsen/logrus"
+)
+
+// preparePlugins installs the plugins of qodana.yaml with an archive or a pinned version into the cache,
+// the linter installs the others itself.
+func preparePlugins(c corescan.Context) {
+	marketplace := qdplugin.NewMarketplace("")
+	for _, plugin := range c.QodanaYaml().Plugins {
+		if _, err := qdplugin.Prepare(marketplace, c.CacheDir(), c.ProjectDir(), plugin); err != nil {
+			msg.WarningMessage("Failed to prepare the plugin %s, it is installed by the linter: %s", plugin.Id, err)
+		}
+	}
+}
+
+// cachedPlugins returns the plugins of qodana.yaml installed into the cache and the paths to load them from.
+func cachedPlugins(c corescan.Context) map[string][]string {
+	cached := map[string][]string{}
+	for _, plugin := range c.QodanaYaml().Plugins {
+		if !qdplugin.IsCached(c.CacheDir(), plugin) {
+			continue
+		}
+		paths, err := qdplugin.Installed(qdplugin.CacheDir(c.CacheDir(), plugin))
+		if err != nil {
+			log.Warnf("Failed to read the cached plugin %s: %s", plugin.Id, err)
+			continue
+		}
+		cached[plugin.Id] = paths
+	}
+	return cached
+}
+
+// pluginsToInstall returns the plugins of qodana.yaml not installed into the cache.
+func pluginsToInstall(c corescan.Context) []qdyaml.Plugin {
+	cached := cachedPlugins(c)
+	var plugins []qdyaml.Plugin
+	for _, plugin := range c.QodanaYaml().Plugins {
+		if _, ok := cached[plugin.Id]; !ok {
+			plugins = append(plugins, plugin)
+		}
+	}
+	return plugins
+}
@@ -23,6 +23,8 @@ import (
 	"github.com/JetBrains/qodana-cli/v2024/platform"
 	"github.com/JetBrains/qodana-cli/v2024/platform/product"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdplugin"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtemp"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	log "github.com/sirupsen/logrus"
@@ -40,9 +42,10 @@ func getPropertiesMap(
 	plugins []string,
 	analysisId string,
 	coverageDir string,
+	noStatistics bool,
 ) map[string]string {
 	properties := map[string]string{
-		"-Didea.headless.enable.statistics":    strconv.FormatBool(cloud.Token.IsAllowedToSendFUS()),
+		"-Didea.headless.enable.statistics":    strconv.FormatBool(cloud.Token.IsAllowedToSendFUS() && !noStatistics),
 		"-Didea.headless.statistics.device.id": deviceIdSalt[0],
 		"-Didea.headless.statistics.salt":      deviceIdSalt[1],
 		"-Dqodana.automation.guid":             utils.QuoteIfSpace(analysisId),
@@ -55,6 +58,17 @@ func getPropertiesMap(
 		properties["-Didea.required.plugins.id"] = strings.Join(plugins, ",")
 	}
 	if prefix == "Rider" {
+		if dotNet.Project == "" && dotNet.Solution == "" {
+			// the IDE opens one solution, the lists of solutions and projects are analyzed in one run by qodana-cdnet only
+			if len(dotNet.Solutions) > 0 {
+				dotNet.Solution = dotNet.Solutions[0]
+			} else if len(dotNet.Projects) > 0 {
+				dotNet.Project = dotNet.Projects[0]
+			}
+			if len(dotNet.Solutions)+len(dotNet.Projects) > 1 {
+				log.Warnf("Only %s%s is analyzed, use qodana-cdnet to analyze several solutions in one run", dotNet.Solution, dotNet.Project)
+			}
+		}
 		if dotNet.Project != "" {
 			properties["-Dqodana.net.project"] = utils.QuoteIfSpace(dotNet.Project)
 		} else if dotNet.Solution != "" {
@@ -81,7 +95,7 @@ func getPropertiesMap(
 
 // GetCommonProperties Common part for installPlugins and qodana executuion
 func GetCommonProperties(c corescan.Context) []string {
-	systemDir := filepath.Join(c.CacheDir(), "idea", c.Prod().GetVersionBranch())
+	systemDir := ideSystemDir(c)
 	pluginsDir := filepath.Join(c.CacheDir(), "plugins", c.Prod().GetVersionBranch())
 	lines := []string{
 		fmt.Sprintf("-Didea.config.path=%s", utils.QuoteIfSpace(c.ConfigDir())),
@@ -89,6 +103,9 @@ func GetCommonProperties(c corescan.Context) []string {
 		fmt.Sprintf("-Didea.plugins.path=%s", utils.QuoteIfSpace(pluginsDir)),
 		fmt.Sprintf("-Didea.log.path=%s", utils.QuoteIfSpace(c.LogDir())),
 	}
+	if repository := os.Getenv(qdenv.QodanaPluginRepositoryEnv); repository != "" {
+		lines = append(lines, fmt.Sprintf("-Didea.plugins.host=%s", utils.QuoteIfSpace(qdplugin.Repository(repository))))
+	}
 	treatAsRelease := os.Getenv(qdenv.QodanaTreatAsRelease)
 	if treatAsRelease == "true" {
 		lines = append(lines, "-Deap.require.license=release")
@@ -124,6 +141,8 @@ func GetScanProperties(c corescan.Context) []string {
 	lines = append(
 		lines,
 		fmt.Sprintf("-Xlog:gc*:%s", utils.QuoteIfSpace(filepath.Join(c.LogDir(), "gc.log"))),
+		"-XX:+HeapDumpOnOutOfMemoryError",
+		fmt.Sprintf("-XX:HeapDumpPath=%s", utils.QuoteIfSpace(c.LogDir())),
 	)
 
 	if c.JvmDebugPort() > 0 {
@@ -134,6 +153,14 @@ func GetScanProperties(c corescan.Context) []string {
 	}
 
 	customPluginPathsValue := getCustomPluginPaths(c.Prod())
+	if !qdenv.IsContainer() { // in the container, the cached plugins are mounted into the custom plugins
+		for _, paths := range cachedPlugins(c) {
+			if customPluginPathsValue != "" {
+				customPluginPathsValue += ","
+			}
+			customPluginPathsValue += strings.Join(paths, ",")
+		}
+	}
 	if customPluginPathsValue != "" {
 		lines = append(lines, fmt.Sprintf("-Dplugin.path=%s", customPluginPathsValue))
 	}
@@ -152,7 +179,16 @@ func GetScanProperties(c corescan.Context) []string {
 		plugins,
 		c.AnalysisId(),
 		c.CoverageDir(),
+		c.NoStatistics(),
 	)
+	for k, v := range threadProperties(c) {
+		props[k] = v
+	}
+	if qdtemp.Configured() { // the temporary files of the IDE go to the temp directory of the run too
+		if dir, err := qdtemp.Dir(); err == nil {
+			props["-Djava.io.tmpdir"] = dir
+		}
+	}
 	for k, v := range yamlProps { // qodana.yaml – overrides vmoptions
 		if !strings.HasPrefix(k, "-") {
 			k = fmt.Sprintf("-D%s", k)
@@ -169,6 +205,7 @@ func GetScanProperties(c corescan.Context) []string {
 	for k, v := range props {
 		lines = append(lines, fmt.Sprintf("%s=%s", k, v))
 	}
+	lines = mergeVmOptions(lines, vmOptions(c))
 
 	sort.Strings(lines)
 
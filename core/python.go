@@ -0,0 +1,85 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdpython"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+)
+
+// provisionPython activates a virtualenv with the dependencies of the Poetry, Pipenv or requirements.txt project
+// for the Python analysis (and the bootstrap), so the interpreter the analysis picks up from PATH resolves
+// the imports of the project. The virtualenv is kept in the cache until the dependency files change.
+func provisionPython(c corescan.Context) {
+	if !c.ProvisionPython() || c.Prod().BaseScriptName != product.PyCharm || os.Getenv(qdenv.VirtualEnvEnv) != "" {
+		return
+	}
+	project, ok := qdpython.Detect(c.ProjectDir())
+	if !ok {
+		return
+	}
+	python, version, err := qdpython.Interpreter()
+	if err != nil {
+		msg.WarningMessage("Skipping the Python environment setup for %s: %s", project.Manager, err)
+		return
+	}
+	if err = os.MkdirAll(c.LogDir(), os.ModePerm); err != nil {
+		log.Fatal(err)
+	}
+	logPath := filepath.Join(c.LogDir(), "python-env.log")
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(logFile)
+
+	var venv string
+	var reused bool
+	msg.PrintProcess(
+		func(_ *pterm.SpinnerPrinter) {
+			venv, reused, err = qdpython.Provision(project, c.ProjectDir(), c.CacheDir(), python, version, logFile)
+		},
+		fmt.Sprintf("Installing the dependencies of %s with Python %s", msg.PrimaryBold(string(project.Manager)), version),
+		fmt.Sprintf("setting up the Python environment, see %s", logPath),
+	)
+	if err != nil {
+		msg.WarningMessage("Failed to set up the Python environment, see %s: %s", logPath, err)
+		return
+	}
+	if err = os.Setenv(qdenv.VirtualEnvEnv, venv); err != nil {
+		log.Fatal(err)
+	}
+	if err = os.Setenv("PATH", qdpython.BinDir(venv)+string(os.PathListSeparator)+os.Getenv("PATH")); err != nil {
+		log.Fatal(err)
+	}
+	_ = os.Unsetenv("PYTHONHOME")
+	if reused {
+		msg.SuccessMessage("Using the cached Python environment %s for %s", venv, project.Manager)
+	} else {
+		msg.SuccessMessage("Using the Python environment %s with the dependencies of %s", venv, project.Manager)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
 	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdsign"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	cp "github.com/otiai10/copy"
 	"github.com/pterm/pterm"
@@ -85,6 +86,10 @@ func downloadAndInstallIDE(
 		}
 	}(downloadedIdePath)
 
+	if err = qdsign.VerifyDownload(downloadedIdePath, ideUrl); err != nil {
+		log.Fatalf("Error while verifying IDE: %v", err)
+	}
+
 	if checkSumUrl != "" {
 		checksumFilePath := filepath.Join(baseDir, strings.TrimSuffix(fileName, fileExt)+".sha256")
 		verifySha256(checksumFilePath, checkSumUrl, downloadedIdePath)
@@ -331,6 +336,10 @@ func downloadCustomPlugins(ideUrl string, installDir string, spinner *pterm.Spin
 	if err != nil {
 		return fmt.Errorf("error while downloading plugins: %v", err)
 	}
+	if err = qdsign.VerifyDownload(archivePath, pluginsUrl); err != nil {
+		_ = os.Remove(archivePath)
+		return err
+	}
 	_, err = exec.Command("tar", "-xf", archivePath, "-C", installDir).Output()
 	if err != nil {
 		return fmt.Errorf("tar: %s", err)
@@ -288,6 +288,13 @@ func writeFileIfNew(filepath string, content string) {
 	}
 }
 
+// SetAndroidSdk points the Android SDK of the default project to sdk, replacing the one set by PrepareDirectories.
+func SetAndroidSdk(confDir string, sdk string) {
+	if err := os.WriteFile(filepath.Join(confDir, "options", "project.default.xml"), []byte(androidProjectDefaultXml(sdk)), 0o755); err != nil {
+		log.Fatal(err)
+	}
+}
+
 // CreateUser will make dynamic uid as a valid user `idea`, needed for gradle cache.
 func CreateUser(fn string) {
 	if //goland:noinspection ALL
@@ -26,6 +26,7 @@ import (
 	"encoding/json"
 	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
 	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtemp"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	"io"
 	"os"
@@ -61,7 +62,7 @@ type ReleaseDownloadInfo struct {
 }
 
 func GetProductByCode(code string) (*Product, error) {
-	tempDir, err := os.MkdirTemp("", "productByCode")
+	tempDir, err := qdtemp.MkdirTemp("productByCode")
 	if err != nil {
 		msg.ErrorMessage("Cannot create temp dir", err)
 		return nil, err
@@ -0,0 +1,238 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/platform/version"
+	"github.com/docker/docker/client"
+	log "github.com/sirupsen/logrus"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const (
+	// supportBundleMaxFileSize is the maximum size of a single file in the support bundle, larger files are truncated to the tail.
+	supportBundleMaxFileSize = 10 * 1024 * 1024
+	redactedValue            = "***"
+)
+
+var (
+	secretNamePattern     = regexp.MustCompile(`(?i)(token|password|passwd|secret|api[_-]?key|license[_-]?key|credentials)`)
+	secretKeyValuePattern = regexp.MustCompile(
+		`(?i)((?:token|password|passwd|secret|api[_-]?key|license[_-]?key)["']?\s*[:=]\s*["']?)[^\s"',]+`,
+	)
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[^\s"',]+`)
+)
+
+// SupportBundleEntry is a single file included in the support bundle.
+type SupportBundleEntry struct {
+	Name        string
+	Description string
+	Content     []byte
+}
+
+// CollectSupportBundle gathers the logs, configuration and environment details useful for Qodana support.
+// All collected content is redacted.
+func CollectSupportBundle(c commoncontext.Context, configName string) []SupportBundleEntry {
+	secrets := knownSecrets(c)
+	entries := []SupportBundleEntry{
+		{
+			Name:        "environment.txt",
+			Description: "Qodana CLI version, OS, command-line arguments and QODANA_* environment variables",
+			Content:     []byte(environmentReport()),
+		},
+	}
+	qodanaYamlPath := qdyaml.GetQodanaYamlPathWithProject(c.ProjectDir, configName)
+	if content, err := os.ReadFile(qodanaYamlPath); err == nil {
+		entries = append(
+			entries, SupportBundleEntry{
+				Name:        filepath.Base(qodanaYamlPath),
+				Description: fmt.Sprintf("Qodana configuration %s", qodanaYamlPath),
+				Content:     content,
+			},
+		)
+	}
+	entries = append(entries, logEntries(c.LogDir())...)
+	if inspect, err := containerInspect(c.Id); err == nil {
+		entries = append(
+			entries, SupportBundleEntry{
+				Name:        "container-inspect.json",
+				Description: "Qodana container inspect output",
+				Content:     inspect,
+			},
+		)
+	} else {
+		log.Debugf("Qodana container inspect output is not available: %v", err)
+	}
+	for i := range entries {
+		entries[i].Content = []byte(Redact(string(entries[i].Content), secrets))
+	}
+	return entries
+}
+
+// WriteSupportBundle writes the given entries to the zip archive at path.
+func WriteSupportBundle(path string, entries []SupportBundleEntry) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	archive := zip.NewWriter(file)
+	for _, entry := range entries {
+		w, err := archive.Create(entry.Name)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(entry.Content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// Redact removes the given secret values, bearer tokens and values of secret-looking keys from text.
+func Redact(text string, secrets []string) string {
+	for _, secret := range secrets {
+		if len(secret) >= 4 {
+			text = strings.ReplaceAll(text, secret, redactedValue)
+		}
+	}
+	text = bearerPattern.ReplaceAllString(text, "${1}"+redactedValue)
+	return secretKeyValuePattern.ReplaceAllString(text, "${1}"+redactedValue)
+}
+
+// knownSecrets returns the secret values known to the CLI that must never end up in the bundle.
+func knownSecrets(c commoncontext.Context) []string {
+	secrets := []string{c.QodanaToken, c.QodanaLicenseOnlyToken}
+	for _, env := range os.Environ() {
+		name, value, found := strings.Cut(env, "=")
+		if found && secretNamePattern.MatchString(name) {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
+}
+
+func environmentReport() string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "Qodana CLI: %s\n", version.Version)
+	_, _ = fmt.Fprintf(&b, "OS: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	_, _ = fmt.Fprintf(&b, "Container: %t\n", qdenv.IsContainer())
+	_, _ = fmt.Fprintf(&b, "Arguments: %s\n", strings.Join(os.Args, " "))
+	b.WriteString("\nEnvironment:\n")
+	var env []string
+	for _, e := range os.Environ() {
+		name, value, _ := strings.Cut(e, "=")
+		if !strings.HasPrefix(name, "QODANA") {
+			continue
+		}
+		if secretNamePattern.MatchString(name) {
+			value = redactedValue
+		}
+		env = append(env, fmt.Sprintf("%s=%s", name, value))
+	}
+	sort.Strings(env)
+	for _, e := range env {
+		_, _ = fmt.Fprintln(&b, e)
+	}
+	return b.String()
+}
+
+// logEntries returns the CLI and IDE logs from the given log directory.
+func logEntries(logDir string) []SupportBundleEntry {
+	var entries []SupportBundleEntry
+	err := filepath.WalkDir(
+		logDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			content, err := readTail(path, supportBundleMaxFileSize)
+			if err != nil {
+				log.Debugf("Skipping %s: %v", path, err)
+				return nil
+			}
+			rel, err := filepath.Rel(logDir, path)
+			if err != nil {
+				return err
+			}
+			entries = append(
+				entries, SupportBundleEntry{
+					Name:        filepath.ToSlash(filepath.Join("log", rel)),
+					Description: fmt.Sprintf("Log %s", path),
+					Content:     content,
+				},
+			)
+			return nil
+		},
+	)
+	if err != nil && !os.IsNotExist(err) {
+		log.Warnf("Failed to collect logs from %s: %v", logDir, err)
+	}
+	return entries
+}
+
+// readTail reads at most limit last bytes of the file.
+func readTail(path string, limit int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - limit
+	if offset < 0 {
+		offset = 0
+	}
+	content := make([]byte, info.Size()-offset)
+	if _, err = file.ReadAt(content, offset); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// containerInspect returns the inspect output of the Qodana container created for the linter with the given id.
+func containerInspect(id string) ([]byte, error) {
+	docker, err := client.NewClientWithOpts(client.FromEnv)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = docker.Close() }()
+	name := os.Getenv(qdenv.QodanaCliContainerName)
+	if name == "" {
+		name = fmt.Sprintf("qodana-cli-%s", id)
+	}
+	inspect, err := docker.ContainerInspect(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(inspect, "", "  ")
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"archive/zip"
+	"path/filepath"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	for input, expected := range map[string]string{
+		"QODANA_TOKEN=secret-value-1":                 "QODANA_TOKEN=***",
+		`{"licenseKey": "abcdef"}`:                    `{"licenseKey": "***"}`,
+		"Authorization: Bearer eyJhbGciOi":            "Authorization: Bearer ***",
+		"uploading with my-known-secret to the cloud": "uploading with *** to the cloud",
+		"password: hunter22":                          "password: ***",
+		"nothing to hide here":                        "nothing to hide here",
+	} {
+		if actual := Redact(input, []string{"my-known-secret", ""}); actual != expected {
+			t.Errorf("Redact(%q) = %q, expected %q", input, actual, expected)
+		}
+	}
+}
+
+func TestWriteSupportBundle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	entries := []SupportBundleEntry{
+		{Name: "environment.txt", Content: []byte("env")},
+		{Name: "log/idea.log", Content: []byte("log")},
+	}
+	if err := WriteSupportBundle(path, entries); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = archive.Close() }()
+	if len(archive.File) != len(entries) {
+		t.Fatalf("expected %d files in the bundle, got %d", len(entries), len(archive.File))
+	}
+	for i, f := range archive.File {
+		if f.Name != entries[i].Name {
+			t.Errorf("expected %s, got %s", entries[i].Name, f.Name)
+		}
+	}
+}
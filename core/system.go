@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
 	"github.com/JetBrains/qodana-cli/v2024/core/startup"
@@ -27,73 +28,64 @@ import (
 	"github.com/JetBrains/qodana-cli/v2024/platform/git"
 	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
 	"github.com/JetBrains/qodana-cli/v2024/platform/nuget"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdbootstrap"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcheckpoint"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdindex"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtemp"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdupdate"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/platform/userconfig"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	cienvironment "github.com/cucumber/ci-environment/go"
 	"github.com/docker/docker/client"
 	"github.com/pterm/pterm"
 	log "github.com/sirupsen/logrus"
 	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
 	// DisableCheckUpdates flag to disable checking for updates
 	DisableCheckUpdates = false
 
-	releaseUrl = "https://api.github.com/repos/JetBrains/qodana-cli/releases/latest"
+	checkUpdatesLock sync.Mutex
 )
 
-// CheckForUpdates check GitHub https://github.com/JetBrains/qodana-cli/ for the latest version of CLI release.
+// CheckForUpdates check GitHub https://github.com/JetBrains/qodana-cli/ for the latest version of CLI release,
+// the new version is noticed at most once a day.
 func CheckForUpdates(currentVersion string) {
+	checkUpdatesLock.Lock()
+	defer checkUpdatesLock.Unlock()
 	if currentVersion == "dev" || strings.HasSuffix(
 		currentVersion,
 		"nightly",
-	) || qdenv.IsContainer() || cienvironment.DetectCIEnvironment() != nil || DisableCheckUpdates {
+	) || qdenv.IsContainer() || cienvironment.DetectCIEnvironment() != nil || DisableCheckUpdates ||
+		qdenv.IsEnabled(qdenv.QodanaDisableUpdateChecksEnv) {
 		return
 	}
-	latestVersion := getLatestVersion()
-	if latestVersion != "" && latestVersion != currentVersion {
+	channel := qdupdate.ChannelStable
+	if strings.Contains(strings.ToLower(currentVersion), qdupdate.ChannelEap) {
+		channel = qdupdate.ChannelEap
+	}
+	stateFile := filepath.Join(userconfig.Dir(), "update-check.json")
+	if latestVersion := qdupdate.NewUpdater().Notice(currentVersion, channel, stateFile, time.Now()); latestVersion != "" {
 		msg.WarningMessage(
-			"New version of %s CLI is available: %s. See https://jb.gg/qodana-cli/update\n",
+			"New version of %s CLI is available: %s. Update with %s or see https://jb.gg/qodana-cli/update (disable the notice with --disable-update-checks or %s=1)\n",
 			msg.PrimaryBold("qodana"),
 			latestVersion,
+			msg.PrimaryBold("qodana self-update"),
+			qdenv.QodanaDisableUpdateChecksEnv,
 		)
-		DisableCheckUpdates = true
 	}
-}
-
-// getLatestVersion returns the latest published version of the CLI.
-func getLatestVersion() string {
-	resp, err := http.Get(releaseUrl)
-	if err != nil {
-		return ""
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			return
-		}
-	}(resp.Body)
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return ""
-	}
-	bodyText, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return ""
-	}
-	result := make(map[string]interface{})
-	err = json.Unmarshal(bodyText, &result)
-	if err != nil {
-		return ""
-	}
-	return strings.TrimPrefix(result["tag_name"].(string), "v")
+	DisableCheckUpdates = true
 }
 
 // OpenDir opens directory in the default file manager
@@ -141,7 +133,7 @@ func RunAnalysis(ctx context.Context, c corescan.Context) int {
 	}
 
 	scenario := c.DetermineRunScenario(startHash != "")
-	if scenario != corescan.RunScenarioDefault && !git.RevisionExists(c.ProjectDir(), startHash, c.LogDir()) {
+	if scenario != corescan.RunScenarioDefault && !git.RevisionExists(ctx, c.ProjectDir(), startHash, c.LogDir()) {
 		msg.WarningMessageCI(
 			"Cannot run analysis for commit %s because it doesn't exist in the repository. Check that you retrieve the full git history before running Qodana.",
 			startHash,
@@ -152,59 +144,156 @@ func RunAnalysis(ctx context.Context, c corescan.Context) int {
 		c = c.BackoffToDefaultAnalysisBecauseOfMissingCommit()
 	}
 
+	if err = checkDiskSpace(ctx, c); err != nil {
+		log.Fatal(err)
+	}
+
+	if c.Ide() != "" {
+		provisionJdk(c)
+		provisionNode(c)
+		provisionPython(c)
+		provisionAndroidSdk(c)
+	}
 	installPlugins(c)
 	// this way of running needs to do bootstrap twice on different commits and will do it internally
 	if scenario != corescan.RunScenarioScoped && c.Ide() != "" {
-		utils.Bootstrap(c.QodanaYaml().Bootstrap, c.ProjectDir())
+		bootstrapSpan := qdtrace.Start("bootstrap")
+		err = bootstrap(ctx, c, c.QodanaYaml().Bootstrap, c.QodanaYaml().Bootstrap.String())
+		bootstrapSpan.End(err)
+		if err != nil {
+			return bootstrapExitCode(err)
+		}
+	}
+	var indexes qdindex.Snapshot
+	if c.Ide() != "" {
+		indexes = verifyIndexes(c)
 	}
+	fixesSnapshot := snapshotBeforeFixes(ctx, c, scenario)
+	keepFixes := func() {}
+	if fixesSnapshot != nil {
+		keepFixes = utils.OnInterrupt(func() { revertFixes(context.WithoutCancel(ctx), c, fixesSnapshot) })
+	}
+	var exitCode int
 	switch scenario {
 	case corescan.RunScenarioFullHistory:
-		return runWithFullHistory(ctx, c, startHash)
+		exitCode = runWithFullHistory(ctx, c, startHash)
 	case corescan.RunScenarioLocalChanges:
-		return runLocalChanges(ctx, c, startHash)
+		exitCode = runLocalChanges(ctx, c, startHash)
 	case corescan.RunScenarioScoped:
-		return runScopeScript(ctx, c, startHash)
+		exitCode = runScopeScript(ctx, c, startHash)
 	case corescan.RunScenarioDefault:
-		return runQodana(ctx, c)
+		exitCode = runQodana(ctx, c)
 	default:
 		log.Fatalf("Unknown run scenario %s", scenario)
 		panic("Unreachable")
 	}
+	keepFixes()
+	if c.Ide() != "" {
+		reportIndexes(c, indexes, exitCode)
+	}
+	if fixesSnapshot != nil && ctx.Err() != nil {
+		// the cancelled run leaves the project as it was before the fixes
+		revertFixes(context.WithoutCancel(ctx), c, fixesSnapshot)
+	} else if fixesSnapshot != nil {
+		finishFixes(ctx, c, fixesSnapshot)
+	}
+	return exitCode
+}
+
+// bootstrap runs the bootstrap, unless the resumed scan has already run it for key.
+// It returns the *qdtrace.TimeoutError if the bootstrap reached the bootstrap timeout,
+// or the context error if the run is cancelled.
+func bootstrap(ctx context.Context, c corescan.Context, b qdyaml.Bootstrap, key string) error {
+	if len(b) == 0 {
+		return nil
+	}
+	if c.Checkpoints().Done(qdcheckpoint.Bootstrap, key) {
+		msg.SuccessMessage("Skipping the bootstrap completed by the interrupted scan")
+		return nil
+	}
+	var err error
+	if config := c.QodanaYaml().BootstrapCache; !config.IsEmpty() {
+		err = cachedBootstrap(ctx, c, b, config)
+	} else {
+		err = qdbootstrap.Bootstrap(ctx, b, c.ProjectDir(), c.LogDir(), c.BootstrapTimeout())
+	}
+	if err != nil {
+		return err
+	}
+	c.Checkpoints().Complete(qdcheckpoint.Bootstrap, key)
+	return nil
+}
+
+// bootstrapExitCode returns the exit code of the run whose bootstrap failed with err.
+func bootstrapExitCode(err error) int {
+	if errors.Is(err, context.Canceled) {
+		return utils.QodanaCancelledExitCode
+	}
+	return utils.QodanaTimeoutExitCodePlaceholder
+}
+
+// cachedBootstrap runs the bootstrap unless a run with the same steps and inputs is in the bootstrap cache,
+// then the outputs of that run are restored instead.
+func cachedBootstrap(ctx context.Context, c corescan.Context, b qdyaml.Bootstrap, config qdyaml.BootstrapCache) error {
+	key, err := qdbootstrap.Key(c.ProjectDir(), b.String(), config.Inputs)
+	if err != nil {
+		msg.WarningMessage("Running the bootstrap without the cache: %s", err)
+		return qdbootstrap.Bootstrap(ctx, b, c.ProjectDir(), c.LogDir(), c.BootstrapTimeout())
+	}
+	cache := qdbootstrap.NewCache(c.CacheDir())
+	restored, err := cache.Restore(key, c.ProjectDir(), config.Outputs)
+	if err != nil {
+		log.Warnf("Failed to restore the bootstrap cache: %s", err)
+	}
+	if restored {
+		msg.SuccessMessage("Skipping the bootstrap, its inputs haven't changed since the cached run")
+		return nil
+	}
+	if err = qdbootstrap.Bootstrap(ctx, b, c.ProjectDir(), c.LogDir(), c.BootstrapTimeout()); err != nil {
+		return err
+	}
+	if err = cache.Save(key, c.ProjectDir(), config.Outputs); err != nil {
+		log.Warnf("Failed to save the bootstrap cache: %s", err)
+	}
+	return nil
 }
 
 func runLocalChanges(ctx context.Context, c corescan.Context, startHash string) int {
 	var exitCode int
 	gitReset := false
-	r, err := git.CurrentRevision(c.ProjectDir(), c.LogDir())
+	keepReset := func() {}
+	r, err := git.CurrentRevision(ctx, c.ProjectDir(), c.LogDir())
 	if err != nil {
 		log.Fatal(err)
 	}
 	if c.DiffEnd() != "" && c.DiffEnd() != r {
 		msg.WarningMessage("Cannot run local-changes because --diff-end is %s and HEAD is %s", c.DiffEnd(), r)
 	} else {
-		err := git.Reset(c.ProjectDir(), startHash, c.LogDir())
+		err := git.Reset(ctx, c.ProjectDir(), startHash, c.LogDir())
 		if err != nil {
 			msg.WarningMessage("Could not reset git repository, no --commit option will be applied: %s", err)
 		} else {
 			c = c.ForcedLocalChanges()
 			gitReset = true
+			keepReset = utils.OnInterrupt(func() { _ = git.ResetBack(context.WithoutCancel(ctx), c.ProjectDir(), c.LogDir()) })
 		}
 	}
 
 	exitCode = runQodana(ctx, c)
 
+	keepReset()
 	if gitReset {
-		_ = git.ResetBack(c.ProjectDir(), c.LogDir())
+		_ = git.ResetBack(context.WithoutCancel(ctx), c.ProjectDir(), c.LogDir())
 	}
 	return exitCode
 }
 
 func runWithFullHistory(ctx context.Context, c corescan.Context, startHash string) int {
-	remoteUrl, err := git.RemoteUrl(c.ProjectDir(), c.LogDir())
+	remoteUrl, err := git.RemoteUrl(ctx, c.ProjectDir(), c.LogDir())
 	if err != nil {
 		log.Fatal(err)
 	}
-	branch, err := git.Branch(c.ProjectDir(), c.LogDir())
+	branch, err := git.Branch(ctx, c.ProjectDir(), c.LogDir())
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -212,7 +301,14 @@ func runWithFullHistory(ctx context.Context, c corescan.Context, startHash strin
 		log.Fatal("Please check that project is located within the Git repo")
 	}
 
-	err = git.Clean(c.ProjectDir(), c.LogDir())
+	var keep []string
+	if c.ConfigName() != "" {
+		keep = append(keep, c.ConfigName())
+	}
+	if c.ProfilePath() != "" && !filepath.IsAbs(c.ProfilePath()) {
+		keep = append(keep, c.ProfilePath())
+	}
+	err = git.Clean(ctx, c.ProjectDir(), c.LogDir(), keep...)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -231,11 +327,20 @@ func runWithFullHistory(ctx context.Context, c corescan.Context, startHash strin
 		}
 	}
 
+	keepCheckout := utils.OnInterrupt(
+		func() {
+			_ = git.CheckoutAndUpdateSubmodule(context.WithoutCancel(ctx), c.ProjectDir(), branch, true, c.LogDir())
+		},
+	)
 	for _, revision := range revisions {
+		if ctx.Err() != nil {
+			exitCode = utils.QodanaCancelledExitCode
+			break
+		}
 		counter++
 
 		msg.WarningMessage("[%d/%d] Running analysis for revision %s", counter+1, allCommits, revision)
-		err = git.CheckoutAndUpdateSubmodule(c.ProjectDir(), revision, true, c.LogDir())
+		err = git.CheckoutAndUpdateSubmodule(ctx, c.ProjectDir(), revision, true, c.LogDir())
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -244,7 +349,8 @@ func runWithFullHistory(ctx context.Context, c corescan.Context, startHash strin
 		contextForAnalysis := c.WithVcsEnvForFullHistoryAnalysisIteration(remoteUrl, branch, revision)
 		exitCode = runQodana(ctx, contextForAnalysis)
 	}
-	err = git.CheckoutAndUpdateSubmodule(c.ProjectDir(), branch, true, c.LogDir())
+	keepCheckout()
+	err = git.CheckoutAndUpdateSubmodule(context.WithoutCancel(ctx), c.ProjectDir(), branch, true, c.LogDir())
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -259,22 +365,28 @@ func runScopeScript(ctx context.Context, c corescan.Context, startHash string) i
 	var err error
 	end := c.DiffEnd()
 	if end == "" {
-		end, err = git.CurrentRevision(c.ProjectDir(), c.LogDir())
+		end, err = git.CurrentRevision(ctx, c.ProjectDir(), c.LogDir())
 		if err != nil {
 			log.Fatal(err)
 		}
 	}
 
-	scopeFile, err := writeChangesFile(c, startHash, end)
+	scopeFile, err := writeChangesFile(ctx, c, startHash, end)
 	if err != nil {
 		log.Fatal("Failed to prepare diff run ", err)
 	}
 	defer func() {
 		_ = os.Remove(scopeFile)
 	}()
+	defer utils.OnInterrupt(
+		func() {
+			_ = git.CheckoutAndUpdateSubmodule(context.WithoutCancel(ctx), c.ProjectDir(), end, true, c.LogDir())
+			_ = os.Remove(scopeFile)
+		},
+	)()
 
 	runFunc := func(hash string, c corescan.Context) (bool, int) {
-		e := git.CheckoutAndUpdateSubmodule(c.ProjectDir(), hash, true, c.LogDir())
+		e := git.CheckoutAndUpdateSubmodule(ctx, c.ProjectDir(), hash, true, c.LogDir())
 		if e != nil {
 			log.Fatalf("Cannot checkout commit %s: %v", hash, e)
 		}
@@ -287,7 +399,12 @@ func runScopeScript(ctx context.Context, c corescan.Context, startHash string) i
 			log.Warnf("Could not read qodana yaml at %s: %v. Using last known config", hash, e)
 			configAtHash = c.QodanaYaml()
 		}
-		utils.Bootstrap(configAtHash.Bootstrap, c.ProjectDir())
+		bootstrapSpan := qdtrace.Start("bootstrap")
+		e = bootstrap(ctx, c, configAtHash.Bootstrap, hash+":"+configAtHash.Bootstrap.String())
+		bootstrapSpan.End(e)
+		if e != nil {
+			return true, bootstrapExitCode(e)
+		}
 
 		exitCode := runQodana(ctx, c) // TODO WHY qodana yaml is not passed further to runQodana???
 		if !(exitCode == 0 || exitCode == 255) {
@@ -321,11 +438,11 @@ func runScopeScript(ctx context.Context, c corescan.Context, startHash string) i
 }
 
 // writeChangesFile creates a temp file containing the changes between diffStart and diffEnd
-func writeChangesFile(c corescan.Context, start string, end string) (string, error) {
+func writeChangesFile(ctx context.Context, c corescan.Context, start string, end string) (string, error) {
 	if start == "" || end == "" {
 		return "", fmt.Errorf("no commits given")
 	}
-	changedFiles, err := git.ComputeChangedFiles(c.ProjectDir(), start, end, c.LogDir())
+	changedFiles, err := git.ComputeChangedFiles(ctx, c.ProjectDir(), start, end, c.LogDir())
 	if err != nil {
 		return "", err
 	}
@@ -333,7 +450,7 @@ func writeChangesFile(c corescan.Context, start string, end string) (string, err
 	if len(changedFiles.Files) == 0 {
 		return "", fmt.Errorf("nothing to compare between %s and %s", start, end)
 	}
-	file, err := os.CreateTemp("", "diff-scope.txt")
+	file, err := qdtemp.CreateTemp("diff-scope-*.txt")
 	if err != nil {
 		return "", err
 	}
@@ -368,8 +485,12 @@ func runQodana(ctx context.Context, c corescan.Context) int {
 		exitCode = runQodanaContainer(ctx, c)
 	} else if c.Ide() != "" {
 		nuget.UnsetNugetVariables() // TODO: get rid of it from 241 release
-		exitCode, err = runQodanaLocal(c)
-		if err != nil {
+		if isPhpVersionMatrix(c) {
+			exitCode, err = runPhpVersionMatrix(ctx, c)
+		} else {
+			exitCode, err = runQodanaLocal(ctx, c)
+		}
+		if err != nil && !errors.Is(err, context.Canceled) {
 			log.Fatal(err)
 		}
 	} else {
@@ -379,9 +500,48 @@ func runQodana(ctx context.Context, c corescan.Context) int {
 	return exitCode
 }
 
-// followLinter follows the linter logs and prints the progress.
-func followLinter(client *client.Client, containerName string, progress *pterm.SpinnerPrinter, scanStages []string) {
-	reader, err := client.ContainerLogs(context.Background(), containerName, containerLogsOptions)
+// stageTimer records consecutive stages of the linter run as child spans of the run span.
+type stageTimer struct {
+	mu      sync.Mutex
+	parent  *qdtrace.Span
+	current *qdtrace.Span
+}
+
+// next finishes the current stage and starts the stage with the given name.
+func (t *stageTimer) next(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current.End(nil)
+	t.current = t.parent.StartChild(name)
+}
+
+// stop finishes the current stage.
+func (t *stageTimer) stop() {
+	t.end(nil)
+}
+
+// end finishes the current stage with the error.
+func (t *stageTimer) end(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current.End(err)
+	t.current = nil
+}
+
+// followLinter follows the linter logs live, prints the progress and records the stage timings.
+func followLinter(
+	ctx context.Context,
+	client *client.Client,
+	containerName string,
+	progress *pterm.SpinnerPrinter,
+	scanStages []string,
+	timer *stageTimer,
+	verbose bool,
+) {
+	reader, err := client.ContainerLogs(ctx, containerName, containerLogsOptions)
+	if err != nil && ctx.Err() != nil {
+		return
+	}
 	if err != nil {
 		log.Fatal(err.Error())
 	}
@@ -401,22 +561,14 @@ func followLinter(client *client.Client, containerName string, progress *pterm.S
 
 		line = strings.TrimSuffix(line, "\n")
 		if err == nil || len(line) > 0 {
-			if strings.Contains(line, "Starting up") {
-				msg.UpdateText(progress, scanStages[2])
-			}
-			if strings.Contains(line, "The Project opening stage completed in") {
-				msg.UpdateText(progress, scanStages[3])
-			}
-			if strings.Contains(line, "The Project configuration stage completed in") {
-				msg.UpdateText(progress, scanStages[4])
-			}
-			if strings.Contains(line, "Detailed summary") {
-				msg.UpdateText(progress, scanStages[5])
-				if !msg.IsInteractive() {
+			if stage, ok := ideStageOf(line); ok {
+				msg.UpdateText(progress, scanStages[stage.scanStage])
+				timer.next(stage.name)
+				if stage.name == "report" && !msg.IsInteractive() {
 					msg.EmptyMessage()
 				}
 			}
-			msg.PrintLinterLog(line)
+			printLinterLine(line, verbose)
 		}
 		if err != nil {
 			if err != io.EOF {
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	log "github.com/sirupsen/logrus"
+	"runtime"
+	"strconv"
+)
+
+const (
+	// analysisThreadsProperty is the parallelism of the pool running the inspections of the IDE.
+	analysisThreadsProperty = "-Djava.util.concurrent.ForkJoinPool.common.parallelism"
+	// indexingThreadsProperty is the number of threads indexing the project.
+	indexingThreadsProperty = "-Dcaches.indexerThreadsCount"
+	// maxAutoIndexingThreads limits the indexing threads derived from the CPU limit, more threads mostly add memory pressure.
+	maxAutoIndexingThreads = 4
+)
+
+var (
+	cpuLimit = utils.CpuLimit
+	numCpu   = runtime.NumCPU
+)
+
+// threadProperties returns the properties of the analysis and indexing threads set by --analysis-threads
+// and --indexing-threads. If the CPUs are limited by the container quota, the unset ones are derived from the limit,
+// otherwise the IDE defaults are kept.
+func threadProperties(c corescan.Context) map[string]string {
+	analysis, indexing := c.AnalysisThreads(), c.IndexingThreads()
+	if limit := cpuLimit(); limit < numCpu() {
+		if analysis <= 0 {
+			analysis = limit
+		}
+		if indexing <= 0 {
+			indexing = max(1, min(limit-1, maxAutoIndexingThreads))
+		}
+		log.Debugf("CPU limit %d: %d analysis threads, %d indexing threads", limit, analysis, indexing)
+	}
+	properties := map[string]string{}
+	if analysis > 0 {
+		properties[analysisThreadsProperty] = strconv.Itoa(analysis)
+	}
+	if indexing > 0 {
+		properties[indexingThreadsProperty] = strconv.Itoa(indexing)
+	}
+	return properties
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"bufio"
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// outputDrainDelay is how long the output of a finished IDE run is forwarded, e.g. written by its children.
+const outputDrainDelay = 5 * time.Second
+
+// ideStage is a stage of the IDE run, started by the line of the IDE log containing marker.
+type ideStage struct {
+	marker string
+	name   string
+	// scanStage is the index of the stage in getScanStages.
+	scanStage int
+}
+
+var ideStages = []ideStage{
+	{marker: "Starting up", name: "indexing", scanStage: 2},
+	{marker: "The Project opening stage completed in", name: "configuration", scanStage: 3},
+	{marker: "The Project configuration stage completed in", name: "analysis", scanStage: 4},
+	{marker: "Detailed summary", name: "report", scanStage: 5},
+}
+
+// ideStageOf returns the stage of the IDE run started by the line of the IDE log.
+func ideStageOf(line string) (ideStage, bool) {
+	for _, stage := range ideStages {
+		if strings.Contains(line, stage.marker) {
+			return stage, true
+		}
+	}
+	return ideStage{}, false
+}
+
+// stageTimeout returns the timeout named as the stage of qdtrace.TimeoutError.
+func stageTimeout(c corescan.Context, stage string) time.Duration {
+	switch stage {
+	case "bootstrap":
+		return c.BootstrapTimeout()
+	case "indexing":
+		return c.IndexingTimeout()
+	case "inspection":
+		return c.InspectionTimeout()
+	default:
+		return c.GetAnalysisTimeout()
+	}
+}
+
+// stageWatcher follows the output of the native IDE run, records its stages and stops the run
+// when the indexing or the analysis stage reaches its timeout.
+type stageWatcher struct {
+	mu    sync.Mutex
+	timer *stageTimer
+	// timeouts are the timeouts of the IDE stages by their names.
+	timeouts map[string]qdtrace.TimeoutError
+	stage    string
+	deadline *time.Timer
+	timedOut *qdtrace.TimeoutError
+	finished bool
+	// verbose prints the followed output with the stage markers and the severity colors.
+	verbose bool
+	// stop is closed when a stage reaches its timeout.
+	stop chan struct{}
+}
+
+func newStageWatcher(c corescan.Context, span *qdtrace.Span) *stageWatcher {
+	timeouts := map[string]qdtrace.TimeoutError{}
+	if c.IndexingTimeout() > 0 {
+		timeouts["indexing"] = qdtrace.TimeoutError{Stage: "indexing", Timeout: c.IndexingTimeout()}
+	}
+	if c.InspectionTimeout() > 0 {
+		timeouts["analysis"] = qdtrace.TimeoutError{Stage: "inspection", Timeout: c.InspectionTimeout()}
+	}
+	return &stageWatcher{
+		timer:    &stageTimer{parent: span},
+		timeouts: timeouts,
+		verbose:  c.Verbose(),
+		stop:     make(chan struct{}),
+	}
+}
+
+// enabled reports whether a stage timeout is set or the output is verbose, only then the output of the IDE is followed.
+func (w *stageWatcher) enabled() bool {
+	return len(w.timeouts) > 0 || w.verbose
+}
+
+// line moves to the stage started by the line of the IDE log.
+func (w *stageWatcher) line(line string) {
+	stage, ok := ideStageOf(line)
+	if !ok {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.finished || w.timedOut != nil {
+		return
+	}
+	if w.deadline != nil {
+		w.deadline.Stop()
+		w.deadline = nil
+	}
+	w.stage = stage.name
+	w.timer.next(stage.name)
+	if timeout, ok := w.timeouts[stage.name]; ok {
+		w.deadline = time.AfterFunc(timeout.Timeout, func() { w.expire(stage.name, timeout) })
+	}
+}
+
+func (w *stageWatcher) expire(stage string, timeout qdtrace.TimeoutError) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.finished || w.timedOut != nil || w.stage != stage {
+		return
+	}
+	log.Debugf("The %s stage of the IDE run reached timeout %s", stage, timeout.Timeout)
+	w.timedOut = &timeout
+	close(w.stop)
+}
+
+// finish ends the current stage and returns the stage timeout reached, if any.
+func (w *stageWatcher) finish() *qdtrace.TimeoutError {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.finished = true
+	if w.deadline != nil {
+		w.deadline.Stop()
+	}
+	if w.timedOut != nil {
+		w.timer.end(w.timedOut)
+		return w.timedOut
+	}
+	w.timer.end(nil)
+	return nil
+}
+
+// follow returns the pipe to pass to the IDE instead of out: its lines are forwarded to out and watched for the stages.
+// The returned function closes the pipe once the IDE has exited.
+func (w *stageWatcher) follow(out *os.File) (*os.File, func()) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		log.Warnf("Failed to follow the stages of the IDE run: %s", err)
+		return out, func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if w.verbose {
+				printLinterLine(line, true)
+			} else {
+				_, _ = out.WriteString(line + "\n")
+			}
+			w.line(line)
+		}
+		if scanner.Err() != nil {
+			_, _ = io.Copy(out, reader)
+		}
+	}()
+	return writer, func() {
+		_ = writer.Close()
+		select {
+		case <-done:
+		case <-time.After(outputDrainDelay):
+		}
+		_ = reader.Close()
+	}
+}
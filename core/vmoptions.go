@@ -0,0 +1,134 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"strconv"
+	"strings"
+)
+
+// sizedVmOptions are the VM options followed by a memory size, e.g. -Xmx8g.
+var sizedVmOptions = []string{"-Xmx", "-Xms", "-Xss", "-Xmn"}
+
+// ValidateVmOptions checks the vmoptions of qodana.yaml and --vmoptions.
+func ValidateVmOptions(yamlOptions []string, cliOptions []string) error {
+	for _, option := range mergeVmOptions(yamlOptions, splitVmOptions(cliOptions)) {
+		if !strings.HasPrefix(option, "-") {
+			return fmt.Errorf("invalid VM option %q: VM options start with -", option)
+		}
+		for _, prefix := range sizedVmOptions {
+			if strings.HasPrefix(option, prefix) {
+				if _, err := parseMemorySize(strings.TrimPrefix(option, prefix)); err != nil {
+					return fmt.Errorf("invalid VM option %q: %w", option, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// vmOptions returns the VM options of the IDE: the vmoptions of qodana.yaml overridden by --vmoptions.
+func vmOptions(c corescan.Context) []string {
+	return mergeVmOptions(c.QodanaYaml().VmOptions, splitVmOptions(c.VmOptions()))
+}
+
+// splitVmOptions splits the values of --vmoptions, so several options can be passed in one, e.g. "-Xms1g -Xmx8g".
+func splitVmOptions(values []string) []string {
+	var options []string
+	for _, value := range values {
+		options = append(options, strings.Fields(value)...)
+	}
+	return options
+}
+
+// mergeVmOptions appends the overrides to the options, replacing the options setting the same value.
+func mergeVmOptions(options []string, overrides []string) []string {
+	merged := make([]string, 0, len(options)+len(overrides))
+	merged = append(merged, options...)
+	for _, override := range overrides {
+		key := vmOptionKey(override)
+		kept := merged[:0]
+		for _, option := range merged {
+			if vmOptionKey(option) != key {
+				kept = append(kept, option)
+			}
+		}
+		merged = append(kept, override)
+	}
+	return merged
+}
+
+// vmOptionKey returns the value set by the option: -Xmx for -Xmx8g, -XX:UseG1GC for -XX:+UseG1GC and -Dkey for -Dkey=value.
+func vmOptionKey(option string) string {
+	for _, prefix := range sizedVmOptions {
+		if strings.HasPrefix(option, prefix) {
+			return prefix
+		}
+	}
+	if name, ok := strings.CutPrefix(option, "-XX:"); ok {
+		name, _, _ = strings.Cut(strings.TrimLeft(name, "+-"), "=")
+		return "-XX:" + name
+	}
+	key, _, _ := strings.Cut(option, "=")
+	return key
+}
+
+// parseMemorySize parses a JVM memory size, e.g. 512m or 8g, to bytes.
+func parseMemorySize(size string) (int64, error) {
+	multiplier := int64(1)
+	if size != "" {
+		switch size[len(size)-1] {
+		case 'k', 'K':
+			multiplier = 1024
+		case 'm', 'M':
+			multiplier = 1024 * 1024
+		case 'g', 'G':
+			multiplier = 1024 * 1024 * 1024
+		case 't', 'T':
+			multiplier = 1024 * 1024 * 1024 * 1024
+		}
+		if multiplier > 1 {
+			size = size[:len(size)-1]
+		}
+	}
+	value, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("not a memory size, expected e.g. 512m or 8g")
+	}
+	return value * multiplier, nil
+}
+
+// warnIfHeapExceedsMemory warns if -Xmx is larger than the memory of the container engine.
+func warnIfHeapExceedsMemory(options []string, memory int64) {
+	for i := len(options) - 1; i >= 0; i-- {
+		size, ok := strings.CutPrefix(options[i], "-Xmx")
+		if !ok {
+			continue
+		}
+		if heap, err := parseMemorySize(size); err == nil && memory > 0 && heap > memory {
+			msg.WarningMessage(
+				"%s exceeds the %d MB of memory of the container engine, the analysis can be killed: lower it or increase the memory limit",
+				options[i],
+				memory/1024/1024,
+			)
+		}
+		return
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeVmOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   []string
+		overrides []string
+		expected  []string
+	}{
+		{"heap", []string{"-Xmx2g", "-Xms512m"}, []string{"-Xmx8g"}, []string{"-Xms512m", "-Xmx8g"}},
+		{"flag", []string{"-XX:+UseG1GC"}, []string{"-XX:-UseG1GC"}, []string{"-XX:-UseG1GC"}},
+		{"value", []string{"-XX:MaxRAMPercentage=50"}, []string{"-XX:MaxRAMPercentage=75"}, []string{"-XX:MaxRAMPercentage=75"}},
+		{"property", []string{"-Dfoo=bar", "-Dfoo.baz=1"}, []string{"-Dfoo=qux"}, []string{"-Dfoo.baz=1", "-Dfoo=qux"}},
+		{"new", []string{"-Xmx2g"}, []string{"-ea"}, []string{"-Xmx2g", "-ea"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := mergeVmOptions(tt.options, tt.overrides); !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("mergeVmOptions() = %v, want %v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateVmOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    []string
+		cli     []string
+		wantErr bool
+	}{
+		{"valid", []string{"-Xmx4g", "-XX:+UseG1GC"}, []string{"-Xms1g -Xmx8g"}, false},
+		{"no dash", []string{"Xmx4g"}, nil, true},
+		{"bad size", nil, []string{"-Xmx4x"}, true},
+		{"empty size", []string{"-Xmx"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateVmOptions(tt.yaml, tt.cli); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateVmOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseMemorySize(t *testing.T) {
+	for size, expected := range map[string]int64{"1024": 1024, "512m": 512 << 20, "8G": 8 << 30, "2k": 2048} {
+		if actual, err := parseMemorySize(size); err != nil || actual != expected {
+			t.Errorf("parseMemorySize(%q) = %d, %v, want %d", size, actual, err, expected)
+		}
+	}
+}
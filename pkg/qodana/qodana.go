@@ -0,0 +1,275 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qodana is the Go API of Qodana CLI for the tools embedding the scans instead of running the qodana binary.
+//
+// The functions take their configuration as arguments and return errors instead of exiting the process.
+// A scan still runs the linter of the host or of the container, as `qodana scan` does.
+package qodana
+
+import (
+	"context"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/core"
+	"github.com/JetBrains/qodana-cli/v2024/core/corescan"
+	"github.com/JetBrains/qodana-cli/v2024/core/startup"
+	"github.com/JetBrains/qodana-cli/v2024/platform"
+	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io"
+	"sync"
+)
+
+const (
+	// FormatCsv and FormatXlsx export the problems as a table, as `qodana export` does.
+	FormatCsv  = platform.ExportCsv
+	FormatXlsx = platform.ExportXlsx
+	// FormatTable, FormatCompact, FormatSarifJsonl and FormatGithubAnnotations print the problems,
+	// as `qodana scan --print-problems` does.
+	FormatTable             = platform.ProblemsFormatTable
+	FormatCompact           = platform.ProblemsFormatCompact
+	FormatSarifJsonl        = platform.ProblemsFormatSarifJsonl
+	FormatGithubAnnotations = platform.ProblemsFormatGithubAnnotations
+)
+
+// Config is the configuration of the project read from qodana.yaml.
+type Config = qdyaml.QodanaYaml
+
+// Options configure a scan, the empty fields take the defaults of `qodana scan`.
+type Options struct {
+	// ProjectDir is the root directory of the project, required.
+	ProjectDir string
+	// ConfigName is the name of the configuration file in ProjectDir, qodana.yaml or qodana.yml if empty.
+	ConfigName string
+	// Linter is the Docker image of the linter, Ide is the product code of the native IDE to run instead.
+	// If both are empty, the linter of the configuration is used.
+	Linter string
+	Ide    string
+	// ResultsDir, CacheDir and ReportDir override the directories of the results, the caches and the HTML report.
+	ResultsDir string
+	CacheDir   string
+	ReportDir  string
+	// Token is the Qodana Cloud project token, the environment is not read.
+	Token string
+	// ProfileName and ProfilePath select the inspection profile.
+	ProfileName string
+	ProfilePath string
+	// Baseline is the path to the baseline SARIF report.
+	Baseline              string
+	BaselineIncludeAbsent bool
+	// DiffStart and DiffEnd are the revisions to analyze the changes between.
+	DiffStart string
+	DiffEnd   string
+	// FailThreshold is the number of problems failing the quality gate.
+	FailThreshold string
+	// Env are the KEY=VALUE variables set for the linter, Properties are the KEY=VALUE system properties of the IDE.
+	Env        []string
+	Properties []string
+	// AnalysisId is the identifier of the run in Qodana Cloud, a random one if empty.
+	AnalysisId string
+	// ClearCache removes the caches before the scan.
+	ClearCache bool
+}
+
+// Result is the outcome of a scan.
+type Result struct {
+	// ExitCode is the exit code `qodana scan` would exit with.
+	ExitCode int
+	// ResultsDir is the directory with the results, SarifPath is the SARIF report in it.
+	ResultsDir string
+	SarifPath  string
+	// Problems is the number of the problems of the report, excluding the unchanged problems of the baseline.
+	Problems int
+	// NewProblems is the number of the problems counted by the quality gates.
+	NewProblems int
+	// ThresholdExceeded is true if the quality gate failed.
+	ThresholdExceeded bool
+}
+
+// scanLock serializes the scans: the linters share the process environment and the logger.
+var scanLock sync.Mutex
+
+// exitPanic replaces the exit of log.Fatal during a scan, so it's returned as an error.
+type exitPanic struct {
+	code int
+}
+
+// LoadConfig reads the configuration of the project, an empty one if the project has no configuration file.
+func LoadConfig(projectDir string, configName string) (Config, error) {
+	return qdyaml.ReadQodanaYaml(qdyaml.GetQodanaYamlPathWithProject(projectDir, configName))
+}
+
+// RunScan scans the project and returns the result, the scan is stopped once ctx is done.
+// The scans of the process run one at a time, concurrent calls wait for the running scan.
+// An exceeded quality gate is not an error, it's reported by Result.ThresholdExceeded.
+func RunScan(ctx context.Context, opts Options) (Result, error) {
+	if opts.ProjectDir == "" {
+		return Result{}, fmt.Errorf("the project directory is required")
+	}
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	qodanaYaml, err := LoadConfig(opts.ProjectDir, opts.ConfigName)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := platform.CheckCliVersion(qodanaYaml); err != nil {
+		return Result{}, err
+	}
+	cliOptions, err := cliOptionsOf(opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	scanLock.Lock()
+	defer scanLock.Unlock()
+	var exitCode int
+	var resultsDir string
+	err = withoutExit(
+		func() {
+			commonCtx := commoncontext.Compute(
+				cliOptions.Linter,
+				cliOptions.Ide,
+				cliOptions.CacheDir,
+				cliOptions.ResultsDir,
+				cliOptions.ReportDir,
+				opts.Token,
+				"",
+				cliOptions.ClearCache,
+				cliOptions.ProjectDir,
+				cliOptions.ConfigName,
+			)
+			preparedHost := startup.PrepareHost(commonCtx)
+			scanContext := corescan.CreateContext(*cliOptions, commonCtx, preparedHost, qodanaYaml)
+			resultsDir = scanContext.ResultsDir()
+			exitCode = core.RunAnalysis(ctx, scanContext)
+		},
+	)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	if exitCode != utils.QodanaSuccessExitCode && exitCode != utils.QodanaFailThresholdExitCode {
+		return Result{ExitCode: exitCode, ResultsDir: resultsDir}, fmt.Errorf("the scan failed with exit code %d", exitCode)
+	}
+	return resultOf(exitCode, resultsDir)
+}
+
+// ConvertSarif writes the problems of the SARIF report in the format to w and returns the number of the problems
+// written, the conversion is stopped once ctx is done.
+func ConvertSarif(ctx context.Context, sarifPath string, format string, w io.Writer) (int, error) {
+	w = &contextWriter{ctx: ctx, w: w}
+	switch format {
+	case FormatCsv, FormatXlsx:
+		var columns []string
+		for _, column := range platform.ExportColumns {
+			// the owners are read from the project, which is not known here
+			if column != "owner" {
+				columns = append(columns, column)
+			}
+		}
+		return platform.ExportProblems(sarifPath, format, columns, nil, w)
+	default:
+		return platform.WriteProblems(sarifPath, platform.ProblemsOutput{Format: format}, w)
+	}
+}
+
+// cliOptionsOf returns the options of `qodana scan` with the defaults of its flags and the values of opts.
+func cliOptionsOf(opts Options) (*platformcmd.CliOptions, error) {
+	cliOptions := &platformcmd.CliOptions{}
+	if err := platformcmd.ComputeFlags(&cobra.Command{}, cliOptions); err != nil {
+		return nil, err
+	}
+	cliOptions.ProjectDir = opts.ProjectDir
+	cliOptions.ConfigName = opts.ConfigName
+	cliOptions.Linter = opts.Linter
+	cliOptions.Ide = opts.Ide
+	cliOptions.ResultsDir = opts.ResultsDir
+	cliOptions.CacheDir = opts.CacheDir
+	cliOptions.ReportDir = opts.ReportDir
+	cliOptions.ProfileName = opts.ProfileName
+	cliOptions.ProfilePath = opts.ProfilePath
+	cliOptions.Baseline = opts.Baseline
+	cliOptions.BaselineIncludeAbsent = opts.BaselineIncludeAbsent
+	cliOptions.DiffStart = opts.DiffStart
+	cliOptions.DiffEnd = opts.DiffEnd
+	cliOptions.FailThreshold = opts.FailThreshold
+	cliOptions.Env_ = append(cliOptions.Env_, opts.Env...)
+	cliOptions.Property = append(cliOptions.Property, opts.Properties...)
+	if opts.AnalysisId != "" {
+		cliOptions.AnalysisId = opts.AnalysisId
+	}
+	cliOptions.ClearCache = opts.ClearCache
+	return cliOptions, nil
+}
+
+func resultOf(exitCode int, resultsDir string) (Result, error) {
+	result := Result{
+		ExitCode:          exitCode,
+		ResultsDir:        resultsDir,
+		SarifPath:         platform.GetSarifPath(resultsDir),
+		ThresholdExceeded: exitCode == utils.QodanaFailThresholdExitCode,
+	}
+	var err error
+	if result.NewProblems, err = platform.CountNewProblems(result.SarifPath); err != nil {
+		return result, fmt.Errorf("failed to read the SARIF report: %w", err)
+	}
+	output := platform.ProblemsOutput{Format: platform.ProblemsFormatCompact}
+	if result.Problems, err = platform.WriteProblems(result.SarifPath, output, io.Discard); err != nil {
+		return result, fmt.Errorf("failed to read the SARIF report: %w", err)
+	}
+	return result, nil
+}
+
+// withoutExit runs the function with log.Fatal panicking instead of exiting, the panic is returned as an error.
+func withoutExit(run func()) (err error) {
+	logger := log.StandardLogger()
+	exit := logger.ExitFunc
+	logger.ExitFunc = func(code int) {
+		panic(exitPanic{code: code})
+	}
+	defer func() {
+		logger.ExitFunc = exit
+		if r := recover(); r != nil {
+			e, ok := r.(exitPanic)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("the scan failed with exit code %d, see the log for details", e.code)
+		}
+	}()
+	run()
+	return nil
+}
+
+// contextWriter fails the writes once the context is done.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w *contextWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.w.Write(p)
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"context"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdaudit"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditEntry     *qdaudit.Entry
+	auditTarget    string
+	auditSystemDir string
+)
+
+// StartAudit starts the audit log entry of the command run, appended to the audit log of target (--audit-log)
+// when the run finishes with FinishRun or CancelRun. Nothing is logged for an empty target.
+func StartAudit(target string, cmd *cobra.Command) {
+	if target == "" {
+		return
+	}
+	e := qdaudit.NewEntry(cmd.CommandPath(), cmd.Flags())
+	auditEntry = &e
+	auditTarget = target
+}
+
+// AuditContext sets the project, its revision, the linter and the analysis id of the audited run.
+func AuditContext(c commoncontext.Context, analysisId string) {
+	if auditEntry == nil {
+		return
+	}
+	auditSystemDir = c.QodanaSystemDir
+	auditEntry.Project = c.ProjectDir
+	auditEntry.Linter = c.Linter
+	if auditEntry.Linter == "" {
+		auditEntry.Linter = c.Ide
+	}
+	auditEntry.AnalysisId = analysisId
+	if revision, err := git.CurrentRevision(context.Background(), c.ProjectDir, c.LogDir()); err == nil {
+		auditEntry.Revision = revision
+	}
+}
+
+// finishAudit appends the audited run with its outcome and the hashes of the reports in resultsDir.
+func finishAudit(resultsDir string, outcome Outcome) {
+	if auditEntry == nil {
+		return
+	}
+	e := *auditEntry
+	auditEntry = nil
+	e.ExitCode = outcome.ExitCode
+	e.ExitReason = outcome.Reason()
+	e.DurationMs = outcome.DurationMs
+	e.Reports = qdaudit.HashReports(resultsDir)
+	if err := qdaudit.Append(auditTarget, auditSystemDir, e); err != nil {
+		log.Warnf("Failed to write the audit log: %v", err)
+	}
+}
@@ -17,13 +17,22 @@
 package platform
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
 	"github.com/JetBrains/qodana-cli/v2024/platform/thirdpartyscan"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 // computeBaselinePrintResults runs SARIF analysis (compares with baseline and prints the result)=
-func computeBaselinePrintResults(c thirdpartyscan.Context, thresholds map[string]string) (int, error) {
+func computeBaselinePrintResults(ctx context.Context, c thirdpartyscan.Context, thresholds map[string]string) (int, error) {
 	sarifPath := GetSarifPath(c.ResultsDir())
 	args := []string{
 		utils.QuoteForWindows(c.MountInfo().JavaPath),
@@ -42,7 +51,7 @@ func computeBaselinePrintResults(c thirdpartyscan.Context, thresholds map[string
 	if c.BaselineIncludeAbsent() {
 		args = append(args, "-i")
 	}
-	_, _, ret, err := utils.LaunchAndLog(c.LogDir(), "baseline", args...)
+	_, _, ret, err := utils.LaunchAndLog(ctx, c.LogDir(), "baseline", args...)
 	if err != nil {
 		return -1, fmt.Errorf("error while running baseline-cli: %w", err)
 	}
@@ -54,3 +63,192 @@ func computeBaselinePrintResults(c thirdpartyscan.Context, thresholds map[string
 	}
 	return ret, nil
 }
+
+// DefaultBaselineName is the baseline managed by qodana baseline, relative to the project directory.
+const DefaultBaselineName = "qodana.sarif.json"
+
+// CreateBaseline copies the report to the baseline, without the problems absent from the analysed code.
+func CreateBaseline(reportPath string, baselinePath string) (int, error) {
+	report, err := readBaselineReport(reportPath)
+	if err != nil {
+		return 0, err
+	}
+	results := report.Runs[0].Results[:0]
+	for _, r := range report.Runs[0].Results {
+		if r.BaselineState != baselineStateAbsent {
+			results = append(results, r)
+		}
+	}
+	report.Runs[0].Results = results
+	return len(results), WriteReport(baselinePath, report)
+}
+
+// AbsorbBaseline adds the problems of the report missing from the baseline to it.
+func AbsorbBaseline(reportPath string, baselinePath string) (int, error) {
+	report, err := readBaselineReport(reportPath)
+	if err != nil {
+		return 0, err
+	}
+	baseline, err := readBaselineReport(baselinePath)
+	if err != nil {
+		return 0, err
+	}
+	known := make(map[string]bool, len(baseline.Runs[0].Results))
+	for _, r := range baseline.Runs[0].Results {
+		known[baselineKey(&r)] = true
+	}
+	absorbed := 0
+	for _, r := range report.Runs[0].Results {
+		key := baselineKey(&r)
+		if r.BaselineState == baselineStateAbsent || known[key] {
+			continue
+		}
+		known[key] = true
+		baseline.Runs[0].Results = append(baseline.Runs[0].Results, r)
+		absorbed++
+	}
+	if absorbed == 0 {
+		return 0, nil
+	}
+	return absorbed, WriteReport(baselinePath, baseline)
+}
+
+// TrimBaseline drops the problems in the files no longer existing in the project from the baseline.
+func TrimBaseline(baselinePath string, projectDir string) (int, error) {
+	baseline, err := readBaselineReport(baselinePath)
+	if err != nil {
+		return 0, err
+	}
+	results := baseline.Runs[0].Results
+	kept := results[:0]
+	for _, r := range results {
+		path, _, _ := problemLocation(&r)
+		path = strings.TrimPrefix(path, "file://")
+		if path != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(projectDir, filepath.FromSlash(path))
+		}
+		if path != "" {
+			if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+		}
+		kept = append(kept, r)
+	}
+	trimmed := len(results) - len(kept)
+	if trimmed == 0 {
+		return 0, nil
+	}
+	baseline.Runs[0].Results = kept
+	return trimmed, WriteReport(baselinePath, baseline)
+}
+
+func readBaselineReport(path string) (*sarif.Report, error) {
+	report, err := ReadReport(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(report.Runs) == 0 {
+		return nil, fmt.Errorf("failed to read %s: no runs found", path)
+	}
+	return report, nil
+}
+
+// baselineKey identifies the problem like the baseline state calculation does, by its fingerprint.
+func baselineKey(r *sarif.Result) string {
+	for _, name := range []string{"equalIndicator/v2", "equalIndicator/v1"} {
+		if fingerprint, ok := r.PartialFingerprints[name]; ok {
+			return fingerprint
+		}
+	}
+	path, line, column := problemLocation(r)
+	return fmt.Sprintf("%s:%s:%d:%d:%s", r.RuleId, path, line, column, problemMessage(r))
+}
+
+// BranchBaselineName returns the name of the baseline of the branch in a baselines directory, e.g. baseline.release-1.x.sarif.
+func BranchBaselineName(branch string) string {
+	return "baseline." + sanitizeCacheKey(branch, "none") + ".sarif"
+}
+
+// CurrentBranch returns the analysed branch: $QODANA_BRANCH, the branch built by the CI or the checked out branch.
+func CurrentBranch(projectDir string, logDir string) string {
+	branch := firstEnv(
+		qdenv.QodanaBranch,
+		"GITHUB_HEAD_REF",
+		"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME",
+		"CI_COMMIT_BRANCH",
+		"BITBUCKET_BRANCH",
+		"CHANGE_BRANCH",
+		"BRANCH_NAME",
+		"CIRCLE_BRANCH",
+	)
+	if branch == "" && os.Getenv("GITHUB_REF_TYPE") == "branch" {
+		branch = os.Getenv("GITHUB_REF_NAME")
+	}
+	if branch == "" {
+		branch = strings.TrimPrefix(os.Getenv("BUILD_SOURCEBRANCH"), "refs/heads/")
+	}
+	if branch == "" {
+		if b, err := git.Branch(context.Background(), projectDir, logDir); err == nil && b != "HEAD" {
+			branch = b
+		}
+	}
+	return branch
+}
+
+// BaselineBranches returns the branches whose baselines are tried in order: the analysed branch,
+// the target branch of the pull request, the default branch of the repository, main and master.
+func BaselineBranches(projectDir string, logDir string) []string {
+	candidates := []string{
+		CurrentBranch(projectDir, logDir),
+		strings.TrimPrefix(
+			firstEnv(
+				"GITHUB_BASE_REF",
+				"CI_MERGE_REQUEST_TARGET_BRANCH_NAME",
+				"SYSTEM_PULLREQUEST_TARGETBRANCH",
+				"BITBUCKET_PR_DESTINATION_BRANCH",
+				"CHANGE_TARGET",
+			),
+			"refs/heads/",
+		),
+	}
+	if b, err := git.DefaultBranch(context.Background(), projectDir, "origin", logDir); err == nil {
+		candidates = append(candidates, b)
+	}
+	candidates = append(candidates, "main", "master")
+
+	var branches []string
+	for _, b := range candidates {
+		if b != "" && !utils.Contains(branches, b) {
+			branches = append(branches, b)
+		}
+	}
+	return branches
+}
+
+// SelectBranchBaseline returns the baseline of the first branch having one in dir, a relative dir is resolved against projectDir.
+func SelectBranchBaseline(dir string, projectDir string, branches []string) (path string, branch string) {
+	for _, branch = range branches {
+		path = filepath.Join(dir, BranchBaselineName(branch))
+		resolved := path
+		if !filepath.IsAbs(resolved) {
+			resolved = filepath.Join(projectDir, resolved)
+		}
+		if _, err := os.Stat(resolved); err == nil {
+			return path, branch
+		}
+	}
+	return "", ""
+}
+
+// BaselineOfBranch returns the baseline of the analysed branch in baselinesDir, the directory of --baselines-dir,
+// falling back to the branches it is based on, empty if there is none.
+func BaselineOfBranch(baselinesDir string, projectDir string, logDir string) string {
+	branches := BaselineBranches(projectDir, logDir)
+	baseline, branch := SelectBranchBaseline(baselinesDir, projectDir, branches)
+	if baseline == "" {
+		msg.WarningMessage("No baseline of %s found in %s, all problems are new", strings.Join(branches, ", "), baselinesDir)
+		return ""
+	}
+	msg.SuccessMessage("Using the baseline of %s: %s", branch, baseline)
+	return baseline
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func baselineTestResult(fingerprint string, path string, baselineState interface{}) sarif.Result {
+	return sarif.Result{
+		RuleId:              "Rule",
+		Message:             &sarif.Message{Text: "problem"},
+		BaselineState:       baselineState,
+		PartialFingerprints: map[string]string{"equalIndicator/v1": fingerprint},
+		Locations: []sarif.Location{
+			{PhysicalLocation: &sarif.PhysicalLocation{ArtifactLocation: &sarif.ArtifactLocation{Uri: path}}},
+		},
+	}
+}
+
+func writeBaselineTestReport(t *testing.T, path string, results ...sarif.Result) {
+	report := &sarif.Report{
+		Version: "2.1.0",
+		Runs:    []sarif.Run{{Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "QDTEST"}}, Results: results}},
+	}
+	if err := WriteReport(path, report); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func baselineTestFingerprints(t *testing.T, path string) []string {
+	report, err := ReadReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fingerprints []string
+	for _, r := range report.Runs[0].Results {
+		fingerprints = append(fingerprints, baselineKey(&r))
+	}
+	return fingerprints
+}
+
+func TestBaselineLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	reportPath := filepath.Join(dir, "qodana.sarif.json")
+	baselinePath := filepath.Join(dir, "baseline", "qodana.sarif.json")
+	if err := os.MkdirAll(filepath.Dir(baselinePath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeBaselineTestReport(t, reportPath, baselineTestResult("a", "a.go", nil), baselineTestResult("gone", "a.go", baselineStateAbsent))
+	count, err := CreateBaseline(reportPath, baselinePath)
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 problem in the created baseline, got %d: %v", count, err)
+	}
+
+	writeBaselineTestReport(
+		t,
+		reportPath,
+		baselineTestResult("a", "a.go", baselineStateUnchanged),
+		baselineTestResult("b", "b.go", baselineStateNew),
+		baselineTestResult("c", "deleted.go", baselineStateNew),
+	)
+	count, err = AbsorbBaseline(reportPath, baselinePath)
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 absorbed problems, got %d: %v", count, err)
+	}
+	if got := baselineTestFingerprints(t, baselinePath); len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("unexpected baseline %v", got)
+	}
+
+	for _, name := range []string{"a.go", "b.go"} {
+		if err = os.WriteFile(filepath.Join(dir, name), []byte("package a"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count, err = TrimBaseline(baselinePath, dir)
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 trimmed problem, got %d: %v", count, err)
+	}
+	if got := baselineTestFingerprints(t, baselinePath); len(got) != 2 || got[1] != "b" {
+		t.Errorf("unexpected baseline %v", got)
+	}
+
+	if _, err = AbsorbBaseline(reportPath, filepath.Join(dir, "missing.sarif.json")); err == nil {
+		t.Error("expected an error without the baseline")
+	}
+}
+
+func TestSelectBranchBaseline(t *testing.T) {
+	dir := t.TempDir()
+	baselines := filepath.Join(dir, "baselines")
+	if err := os.MkdirAll(baselines, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, branch := range []string{"main", "release/1.x"} {
+		if err := os.WriteFile(filepath.Join(baselines, BranchBaselineName(branch)), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path, branch := SelectBranchBaseline("baselines", dir, []string{"feature", "release/1.x", "main"})
+	if branch != "release/1.x" || path != filepath.Join("baselines", BranchBaselineName("release/1.x")) {
+		t.Errorf("expected the baseline of release/1.x, got %s of %s", path, branch)
+	}
+	if path, branch = SelectBranchBaseline(baselines, "", []string{"feature", "main"}); branch != "main" {
+		t.Errorf("expected the baseline of main, got %s of %s", path, branch)
+	}
+	if path, _ = SelectBranchBaseline("baselines", dir, []string{"feature", "master"}); path != "" {
+		t.Errorf("expected no baseline, got %s", path)
+	}
+}
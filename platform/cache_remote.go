@@ -0,0 +1,280 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcache"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// DefaultCacheKey is the cache key used when --cache-key is not set.
+const DefaultCacheKey = "{linter}-{project}-{branch}"
+
+var (
+	unsafeCacheKeyChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+	cacheKeyPlaceholder = regexp.MustCompile(`\{(linter|project|branch|os|hashFiles:[^}]+)}`)
+)
+
+// RemoteCache is the cache of the run shared through a cache backend.
+type RemoteCache struct {
+	backend     qdcache.Backend
+	location    string
+	key         string
+	restoreKeys []string
+	restored    string
+}
+
+// NewRemoteCache resolves the cache key and the restore keys of the run, nil is returned if location is not set.
+// Without restoreKeys, the fallbacks are derived from the key: the cache of the same project and linter
+// for the default key, or the key without its trailing dash-separated segments for a custom key.
+func NewRemoteCache(location string, keyTemplate string, restoreKeys []string, analyzer string, projectDir string, logDir string) (*RemoteCache, error) {
+	if location == "" {
+		return nil, nil
+	}
+	backend, err := qdcache.NewBackend(location)
+	if err != nil {
+		return nil, err
+	}
+	custom := keyTemplate != ""
+	if !custom {
+		keyTemplate = DefaultCacheKey
+	}
+	c := &RemoteCache{backend: backend, location: location}
+	if c.key, err = ExpandCacheKey(keyTemplate, analyzer, projectDir, logDir); err != nil {
+		return nil, err
+	}
+	switch {
+	case len(restoreKeys) > 0:
+		for _, restoreKey := range restoreKeys {
+			expanded, err := ExpandCacheKey(restoreKey, analyzer, projectDir, logDir)
+			if err != nil {
+				return nil, err
+			}
+			c.restoreKeys = append(c.restoreKeys, expanded)
+		}
+	case custom:
+		c.restoreKeys = qdcache.FallbackKeys(c.key)
+	default:
+		prefix, _ := ExpandCacheKey("{linter}-{project}-", analyzer, projectDir, logDir)
+		c.restoreKeys = []string{prefix}
+	}
+	return c, nil
+}
+
+// ExpandCacheKey replaces the placeholders in the cache key template:
+// {linter}, {project} (the hash of the git remote or the project directory name), {branch}, {os}
+// and {hashFiles:<glob>[,<glob>...]} (the hash of the project files matching the globs, e.g. **/pom.xml).
+func ExpandCacheKey(template string, analyzer string, projectDir string, logDir string) (string, error) {
+	var expandErr error
+	key := cacheKeyPlaceholder.ReplaceAllStringFunc(
+		template, func(placeholder string) string {
+			name := placeholder[1 : len(placeholder)-1]
+			switch {
+			case name == "linter":
+				return sanitizeCacheKey(analyzer[strings.LastIndex(analyzer, "/")+1:], "qodana")
+			case name == "project":
+				return projectHash(projectDir, logDir)
+			case name == "branch":
+				branch := os.Getenv(qdenv.QodanaBranch)
+				if branch == "" {
+					branch, _ = git.Branch(context.Background(), projectDir, logDir)
+				}
+				return sanitizeCacheKey(branch, "none")
+			case name == "os":
+				return runtime.GOOS
+			default:
+				hash, err := hashFiles(projectDir, strings.Split(strings.TrimPrefix(name, "hashFiles:"), ","))
+				if err != nil {
+					expandErr = err
+				}
+				return hash
+			}
+		},
+	)
+	if expandErr != nil {
+		return "", expandErr
+	}
+	if !qdcache.ValidKey(strings.TrimSuffix(key, "-") + "x") {
+		return "", fmt.Errorf("invalid cache key %q: only letters, digits, '.', '_' and '-' are allowed", key)
+	}
+	return key, nil
+}
+
+func sanitizeCacheKey(value string, fallback string) string {
+	value = strings.Trim(unsafeCacheKeyChars.ReplaceAllString(value, "-"), "-._")
+	if len(value) > 64 {
+		value = value[:64]
+	}
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// projectHash identifies the project by its git remote, so the hash is the same on every CI agent.
+func projectHash(projectDir string, logDir string) string {
+	project, err := git.RemoteUrl(context.Background(), projectDir, logDir)
+	if err != nil || project == "" {
+		project = projectDir
+		if abs, err := filepath.Abs(projectDir); err == nil {
+			project = abs
+		}
+		project = filepath.Base(project)
+	}
+	hash := sha256.Sum256([]byte(project))
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// hashFiles returns the hash of the contents of the files in projectDir matching any of the globs, like hashFiles of GitHub Actions.
+func hashFiles(projectDir string, globs []string) (string, error) {
+	var patterns []*regexp.Regexp
+	for _, glob := range globs {
+		pattern, err := globToRegexp(strings.TrimSpace(glob))
+		if err != nil {
+			return "", fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	var files []string
+	err := filepath.WalkDir(
+		projectDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			rel, err := filepath.Rel(projectDir, path)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			for _, pattern := range patterns {
+				if pattern.MatchString(rel) {
+					files = append(files, rel)
+					break
+				}
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	hash := sha256.New()
+	for _, file := range files {
+		f, err := os.Open(filepath.Join(projectDir, file))
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(hash, f)
+		_ = f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
+
+// globToRegexp converts the glob with * (within a path segment), ** (any number of segments) and ? to a regexp.
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// Restore downloads the cache into an empty cacheDir, a warm local cache is kept as is.
+func (c *RemoteCache) Restore(cacheDir string) {
+	if c == nil {
+		return
+	}
+	if entries, err := os.ReadDir(cacheDir); err == nil && len(entries) > 0 {
+		log.Debugf("Local cache %s is not empty, skipping the download of the remote cache", cacheDir)
+		return
+	}
+	span := qdtrace.Start("cache download")
+	restored, err := qdcache.Restore(c.backend, c.key, c.restoreKeys, cacheDir)
+	span.End(err)
+	c.restored = restored
+	switch {
+	case err != nil:
+		msg.WarningMessage("Failed to download the cache from %s: %s", c.location, err)
+	case restored == c.key:
+		msg.SuccessMessage("Restored the cache %s from %s", restored, c.location)
+	case restored != "":
+		msg.SuccessMessage("Restored the cache %s from %s (no cache found for %s)", restored, c.location, c.key)
+	default:
+		log.Infof("No cache %s found on %s", c.key, c.location)
+	}
+}
+
+// Save uploads cacheDir under the cache key, unless the cache was restored by the exact key.
+func (c *RemoteCache) Save(cacheDir string) {
+	if c == nil {
+		return
+	}
+	if c.restored == c.key {
+		log.Infof("Cache %s was restored by the exact key, not uploading it", c.key)
+		return
+	}
+	span := qdtrace.Start("cache upload")
+	err := qdcache.Save(c.backend, c.key, cacheDir)
+	span.End(err)
+	if err != nil {
+		msg.WarningMessage("Failed to upload the cache to %s: %s", c.location, err)
+	} else {
+		log.Infof("Uploaded the cache %s to %s", c.key, c.location)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExpandCacheKey(t *testing.T) {
+	projectDir := t.TempDir()
+	t.Setenv(qdenv.QodanaBranch, "feature/cache")
+	for path, content := range map[string]string{"pom.xml": "root", "module/pom.xml": "module", "module/src/A.java": "class A"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(projectDir, path)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(projectDir, path), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	key, err := ExpandCacheKey("qodana-{linter}-{branch}-{os}-{hashFiles:**/pom.xml}", "jetbrains/qodana-jvm:2024.3", projectDir, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := "qodana-qodana-jvm-2024.3-feature-cache-" + runtime.GOOS + "-"
+	if !strings.HasPrefix(key, prefix) || len(key) != len(prefix)+16 {
+		t.Errorf("unexpected key %s", key)
+	}
+
+	if err = os.WriteFile(filepath.Join(projectDir, "module", "pom.xml"), []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changed, _ := ExpandCacheKey("qodana-{linter}-{branch}-{os}-{hashFiles:**/pom.xml}", "jetbrains/qodana-jvm:2024.3", projectDir, t.TempDir())
+	if changed == key {
+		t.Errorf("expected the key to change with the lockfile")
+	}
+	unchanged, _ := ExpandCacheKey("qodana-{linter}-{branch}-{os}-{hashFiles:**/pom.xml}", "jetbrains/qodana-jvm:2024.3", projectDir, t.TempDir())
+	if unchanged != changed {
+		t.Errorf("expected the key to be stable")
+	}
+
+	if _, err = ExpandCacheKey("qodana cache", "", projectDir, t.TempDir()); err == nil {
+		t.Errorf("expected an error for an invalid key")
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	for glob, paths := range map[string]map[string]bool{
+		"**/pom.xml":      {"pom.xml": true, "a/b/pom.xml": true, "a/pom.xml.bak": false},
+		"*.gradle":        {"build.gradle": true, "a/build.gradle": false},
+		"a/**/*.lock":     {"a/yarn.lock": true, "a/b/c/yarn.lock": true, "b/yarn.lock": false},
+		"go.su?":          {"go.sum": true, "go.mod": false},
+		"package[1].json": {"package[1].json": true, "package1.json": false},
+	} {
+		pattern, err := globToRegexp(glob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for path, expected := range paths {
+			if pattern.MatchString(path) != expected {
+				t.Errorf("%s matching %s: expected %v", glob, path, expected)
+			}
+		}
+	}
+}
+
+func TestNewRemoteCacheRestoreKeys(t *testing.T) {
+	projectDir := t.TempDir()
+	t.Setenv(qdenv.QodanaBranch, "main")
+	c, err := NewRemoteCache(t.TempDir(), "", nil, "qodana-jvm", projectDir, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	project := projectHash(projectDir, t.TempDir())
+	if c.key != "qodana-jvm-"+project+"-main" || len(c.restoreKeys) != 1 || c.restoreKeys[0] != "qodana-jvm-"+project+"-" {
+		t.Errorf("unexpected keys %s %v", c.key, c.restoreKeys)
+	}
+
+	c, err = NewRemoteCache(t.TempDir(), "deps-{branch}-1", nil, "qodana-jvm", projectDir, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.key != "deps-main-1" || strings.Join(c.restoreKeys, ",") != "deps-main-,deps-" {
+		t.Errorf("unexpected keys %s %v", c.key, c.restoreKeys)
+	}
+
+	c, err = NewRemoteCache(t.TempDir(), "deps-1", []string{"deps-{branch}-", "deps-"}, "qodana-jvm", projectDir, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(c.restoreKeys, ",") != "deps-main-,deps-" {
+		t.Errorf("unexpected restore keys %v", c.restoreKeys)
+	}
+
+	if c, err = NewRemoteCache("", "", nil, "qodana-jvm", projectDir, t.TempDir()); c != nil || err != nil {
+		t.Errorf("expected no remote cache without the location")
+	}
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdupdate"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/platform/version"
+	"strings"
+)
+
+// Values of cliVersionCheck of qodana.yaml.
+const (
+	CliVersionCheckWarn = "warn"
+	CliVersionCheckFail = "fail"
+)
+
+// CheckCliVersion checks the running CLI against cliVersion of qodana.yaml: the mismatch is a warning, or an error
+// with cliVersionCheck: fail. The development and nightly builds are not checked.
+func CheckCliVersion(q qdyaml.QodanaYaml) error {
+	if q.CliVersion == "" {
+		return nil
+	}
+	if q.CliVersionCheck != "" && q.CliVersionCheck != CliVersionCheckWarn && q.CliVersionCheck != CliVersionCheckFail {
+		return fmt.Errorf("invalid cliVersionCheck %q, expected %s or %s", q.CliVersionCheck, CliVersionCheckWarn, CliVersionCheckFail)
+	}
+	constraint, err := qdupdate.ParseConstraint(q.CliVersion)
+	if err != nil {
+		return fmt.Errorf("invalid cliVersion: %w", err)
+	}
+	if version.Version == "dev" || strings.HasSuffix(version.Version, "nightly") || constraint.Match(version.Version) {
+		return nil
+	}
+	mismatch := fmt.Sprintf(
+		"Qodana CLI %s doesn't satisfy cliVersion %s of qodana.yaml, update it with qodana self-update --to-required",
+		version.Version,
+		constraint,
+	)
+	if q.CliVersionCheck == CliVersionCheckFail {
+		return errors.New(mismatch)
+	}
+	msg.WarningMessage("%s", mismatch)
+	return nil
+}
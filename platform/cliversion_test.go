@@ -0,0 +1,50 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/platform/version"
+	"testing"
+)
+
+func TestCheckCliVersion(t *testing.T) {
+	current := version.Version
+	defer func() { version.Version = current }()
+	version.Version = "2024.3.1"
+
+	for _, tc := range []struct {
+		q       qdyaml.QodanaYaml
+		isError bool
+	}{
+		{qdyaml.QodanaYaml{}, false},
+		{qdyaml.QodanaYaml{CliVersion: ">=2024.2 <2025", CliVersionCheck: CliVersionCheckFail}, false},
+		{qdyaml.QodanaYaml{CliVersion: ">=2025.1"}, false},
+		{qdyaml.QodanaYaml{CliVersion: ">=2025.1", CliVersionCheck: CliVersionCheckFail}, true},
+		{qdyaml.QodanaYaml{CliVersion: ">=2025.x"}, true},
+		{qdyaml.QodanaYaml{CliVersion: ">=2024.2", CliVersionCheck: "error"}, true},
+	} {
+		if err := CheckCliVersion(tc.q); (err != nil) != tc.isError {
+			t.Errorf("cliVersion %q, cliVersionCheck %q: unexpected error %v", tc.q.CliVersion, tc.q.CliVersionCheck, err)
+		}
+	}
+
+	version.Version = "dev"
+	if err := CheckCliVersion(qdyaml.QodanaYaml{CliVersion: ">=2025.1", CliVersionCheck: CliVersionCheckFail}); err != nil {
+		t.Errorf("expected the development build not to be checked, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platformcmd
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdprofile"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+// completedRevisions is the number of the recent commits completed for the revision flags.
+const completedRevisions = 20
+
+type completionFunc = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// registerCompletions adds the completion of the values of the scan flags, the flags absent from cmd are skipped.
+func registerCompletions(cmd *cobra.Command) error {
+	completions := map[string]completionFunc{
+		"linter":       completeValues(product.AllImages),
+		"ide":          completeIde,
+		"profile-name": completeProfileName,
+		"commit":       completeRevision,
+		"diff-start":   completeRevision,
+		"diff-end":     completeRevision,
+	}
+	for name, complete := range completions {
+		if cmd.Flags().Lookup(name) == nil {
+			continue
+		}
+		if err := cmd.RegisterFlagCompletionFunc(name, complete); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func completeValues(values []string) completionFunc {
+	return func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeIde completes the product codes and their EAP versions once the code is typed.
+func completeIde(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var codes []string
+	for _, code := range product.AllNativeCodes {
+		codes = append(codes, code)
+		if strings.HasPrefix(toComplete, code) {
+			codes = append(codes, code+product.EapSuffix)
+		}
+	}
+	return codes, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeProfileName(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return qdprofile.Names(completionProjectDir(cmd)), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeRevision completes the recent commits of the project, described with their subjects.
+func completeRevision(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return git.RecentRevisions(completionProjectDir(cmd), completedRevisions), cobra.ShellCompDirectiveNoFileComp |
+		cobra.ShellCompDirectiveKeepOrder
+}
+
+// completionProjectDir returns the --project-dir typed before the completed flag, the current directory otherwise.
+func completionProjectDir(cmd *cobra.Command) string {
+	if dir, err := cmd.Flags().GetString("project-dir"); err == nil && dir != "" {
+		return dir
+	}
+	return "."
+}
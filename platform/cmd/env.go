@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platformcmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"os"
+	"strings"
+)
+
+const (
+	// EnvPrefix is the prefix of the environment variables setting the flags of the scan, see FlagEnv.
+	EnvPrefix = "QODANA_"
+	// EnvAnnotation annotates the flags set from their environment variables with the name of the variable.
+	EnvAnnotation = "qodana_env"
+
+	// mutuallyExclusiveAnnotation is the annotation of the flags of cobra.Command.MarkFlagsMutuallyExclusive.
+	mutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"
+)
+
+// envExcluded are the flags without an environment variable, QODANA_ENV is the environment the CLI runs in.
+var envExcluded = map[string]bool{"env": true}
+
+// FlagEnv returns the environment variable setting the flag, e.g. QODANA_FAIL_THRESHOLD for --fail-threshold,
+// empty for the flags without one.
+func FlagEnv(name string) string {
+	if envExcluded[name] {
+		return ""
+	}
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// bindEnv makes the command apply the environment variables of its flags before it runs, see ApplyEnv.
+func bindEnv(cmd *cobra.Command) {
+	preRun := cmd.PreRunE
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := ApplyEnv(cmd.Flags()); err != nil {
+			return err
+		}
+		if preRun != nil {
+			return preRun(cmd, args)
+		}
+		return nil
+	}
+}
+
+// ApplyEnv sets the flags not set on the command line from their environment variables, so a flag takes precedence
+// over its variable, which takes precedence over qodana.yaml and the default. The list flags take comma-separated
+// values, the repeatable ones, e.g. --property, a value per line. The variable is ignored if the flag is deprecated
+// or a flag it is mutually exclusive with is set on the command line.
+func ApplyEnv(flags *pflag.FlagSet) error {
+	changed := map[string]bool{}
+	flags.Visit(
+		func(f *pflag.Flag) {
+			changed[f.Name] = true
+		},
+	)
+	var err error
+	flags.VisitAll(
+		func(f *pflag.Flag) {
+			env := FlagEnv(f.Name)
+			value := os.Getenv(env)
+			if err != nil || env == "" || value == "" || changed[f.Name] || f.Deprecated != "" || excludedBy(f, changed) {
+				return
+			}
+			values := []string{value}
+			if f.Value.Type() == "stringArray" {
+				values = strings.Split(strings.TrimSpace(value), "\n")
+			}
+			for _, v := range values {
+				if e := flags.Set(f.Name, strings.TrimSpace(v)); e != nil {
+					err = fmt.Errorf("invalid value %q of %s: %w", value, env, e)
+					return
+				}
+			}
+			err = flags.SetAnnotation(f.Name, EnvAnnotation, []string{env})
+		},
+	)
+	return err
+}
+
+// excludedBy returns true if a flag mutually exclusive with f is set.
+func excludedBy(f *pflag.Flag, set map[string]bool) bool {
+	for _, group := range f.Annotations[mutuallyExclusiveAnnotation] {
+		for _, name := range strings.Split(group, " ") {
+			if name != f.Name && set[name] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platformcmd
+
+import (
+	"github.com/spf13/cobra"
+	"reflect"
+	"testing"
+)
+
+func TestFlagEnv(t *testing.T) {
+	if env := FlagEnv("fail-threshold"); env != "QODANA_FAIL_THRESHOLD" {
+		t.Errorf("FlagEnv() = %s", env)
+	}
+	if env := FlagEnv("env"); env != "" {
+		t.Errorf("expected no variable of --env, got %s", env)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("QODANA_FAIL_THRESHOLD", "10")
+	t.Setenv("QODANA_LINTER", "jetbrains/qodana-jvm")
+	t.Setenv("QODANA_PROFILE_NAME", "qodana.recommended")
+	t.Setenv("QODANA_PROFILE_PATH", "profile.xml")
+	t.Setenv("QODANA_PROPERTY", "a=1,2\nb=3")
+	t.Setenv("QODANA_SKIP_PULL", "true")
+
+	var failThreshold, linter, profileName, profilePath string
+	var property []string
+	var skipPull bool
+	cmd := &cobra.Command{Use: "scan"}
+	flags := cmd.Flags()
+	flags.StringVar(&failThreshold, "fail-threshold", "", "")
+	flags.StringVarP(&linter, "linter", "l", "", "")
+	flags.StringVar(&profileName, "profile-name", "", "")
+	flags.StringVar(&profilePath, "profile-path", "", "")
+	flags.StringArrayVar(&property, "property", nil, "")
+	flags.BoolVar(&skipPull, "skip-pull", false, "")
+	cmd.MarkFlagsMutuallyExclusive("profile-name", "profile-path")
+	if err := flags.Parse([]string{"-l", "jetbrains/qodana-go", "--profile-path", "other.xml"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ApplyEnv(flags); err != nil {
+		t.Fatal(err)
+	}
+	if failThreshold != "10" || !skipPull || !reflect.DeepEqual(property, []string{"a=1,2", "b=3"}) {
+		t.Errorf("the variables are not applied: %s, %v, %v", failThreshold, skipPull, property)
+	}
+	if linter != "jetbrains/qodana-go" || profilePath != "other.xml" {
+		t.Errorf("the flags are overridden by the variables: %s, %s", linter, profilePath)
+	}
+	if profileName != "" {
+		t.Errorf("expected the variable of a flag exclusive with a set one to be ignored, got %s", profileName)
+	}
+	if annotation := flags.Lookup("fail-threshold").Annotations[EnvAnnotation]; !reflect.DeepEqual(annotation, []string{"QODANA_FAIL_THRESHOLD"}) {
+		t.Errorf("unexpected annotation %v", annotation)
+	}
+	if annotation := flags.Lookup("linter").Annotations[EnvAnnotation]; annotation != nil {
+		t.Errorf("unexpected annotation of the flag set on the command line %v", annotation)
+	}
+
+	t.Setenv("QODANA_SKIP_PULL", "maybe")
+	invalid := &cobra.Command{Use: "scan"}
+	invalid.Flags().Bool("skip-pull", false, "")
+	if err := ApplyEnv(invalid.Flags()); err == nil {
+		t.Error("expected an error for an invalid value")
+	}
+}
@@ -18,19 +18,30 @@ package platformcmd
 
 import (
 	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
 	"github.com/JetBrains/qodana-cli/v2024/platform/product"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdreport"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"os"
 	"strings"
+	"time"
+)
+
+// Values of --fixes-output.
+const (
+	FixesOutputApply = "apply"
+	FixesOutputPatch = "patch"
 )
 
 type CliOptions struct {
 	ResultsDir                string
 	CacheDir                  string
 	ProjectDir                string
+	ProjectsFile              string
 	ReportDir                 string
 	CoverageDir               string
 	Linter                    string
@@ -39,10 +50,21 @@ type CliOptions struct {
 	DisableSanity             bool
 	ProfileName               string
 	ProfilePath               string
+	Scopes                    []string
+	SeverityMap               []string
+	GroupByOwner              bool
+	OwnersFile                string
+	EstimateDebt              bool
+	CodeMetrics               bool
+	FailOnMetric              []string
+	ProfileInspections        bool
+	Verbose                   bool
+	FollowIdeaLog             bool
 	RunPromo                  string
 	StubProfile               string // note: deprecated option
 	Baseline                  string
 	BaselineIncludeAbsent     bool
+	BaselinesDir              string
 	SaveReport                bool
 	ShowReport                bool
 	Port                      int
@@ -52,21 +74,35 @@ type CliOptions struct {
 	Commit                    string
 	DiffStart                 string
 	DiffEnd                   string
+	FilesFrom                 string
 	ForceLocalChangesScript   bool
 	AnalysisId                string
 	Env_                      []string
 	Volumes                   []string
 	User                      string
 	PrintProblems             bool
+	ProblemsFormat            string
+	ProblemsSeverities        []string
 	GenerateCodeClimateReport bool
 	SendBitBucketInsights     bool
 	SkipPull                  bool
+	WslSync                   bool
 	ClearCache                bool
+	SkipDiskCheck             bool
+	Resume                    bool
 	ConfigName                string
 	FullHistory               bool
 	ApplyFixes                bool
 	Cleanup                   bool
 	FixesStrategy             string // note: deprecated option
+	FixesOutput               string
+	FixesRules                []string
+	FixesInclude              []string
+	FixesPush                 bool
+	FixesBranch               string
+	FixesCommitMessage        string
+	FixesAuthor               string
+	FixesRemote               string
 	NoStatistics              bool
 	CdnetSolution             string // cdnet specific options
 	CdnetProject              string
@@ -77,7 +113,38 @@ type CliOptions struct {
 	ClangArgs                 string
 	AnalysisTimeoutMs         int
 	AnalysisTimeoutExitCode   int
+	BootstrapTimeout          time.Duration
+	IndexingTimeout           time.Duration
+	InspectionTimeout         time.Duration
+	Reproducible              bool
+	Redact                    bool
+	RedactionRules            string
+	EncryptionKeyFile         string
+	EncryptionRecipient       string
+	AuditLog                  string
+	VerifySignatures          bool
+	TrustedKeys               string
+	ResultsManifest           bool
+	ManifestSigningKey        string
+	DedupResults              bool
 	JvmDebugPort              int
+	VmOptions                 []string
+	ProvisionJdk              bool
+	ProvisionNode             bool
+	ProvisionPython           bool
+	ProvisionAndroidSdk       bool
+	AnalysisThreads           int
+	IndexingThreads           int
+	Auth                      string
+	LicenseCacheTtl           time.Duration
+	MetricsPushgateway        string
+	CacheRemote               string
+	CacheKey                  string
+	CacheRestoreKeys          []string
+	PublishTo                 string
+	PublishRetention          time.Duration
+	PublishUrlExpiry          time.Duration
+	Serve                     qdreport.ServeOptions
 }
 
 func (o CliOptions) Env() []string {
@@ -86,6 +153,27 @@ func (o CliOptions) Env() []string {
 	return env
 }
 
+// ResultsManifestEnabled returns true if the checksum manifest of the results is written with --results-manifest
+// or --manifest-signing-key.
+func (o CliOptions) ResultsManifestEnabled() bool {
+	return o.ResultsManifest || o.ManifestSigningKey != ""
+}
+
+// OwnersEnabled returns true if the problems are summarized by owner with --group-by-owner or --owners-file.
+func (o CliOptions) OwnersEnabled() bool {
+	return o.GroupByOwner || o.OwnersFile != ""
+}
+
+// CodeMetricsEnabled returns true if the code metrics are measured with --code-metrics or --fail-on-metric.
+func (o CliOptions) CodeMetricsEnabled() bool {
+	return o.CodeMetrics || len(o.FailOnMetric) > 0
+}
+
+// RedactionEnabled returns true if the results are redacted with --redact or --redaction-rules.
+func (o CliOptions) RedactionEnabled() bool {
+	return o.Redact || o.RedactionRules != ""
+}
+
 func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 	flags := cmd.Flags()
 	flags.SortFlags = false
@@ -137,9 +225,10 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 	flags.BoolVar(
 		&options.PrintProblems,
 		"print-problems",
-		false,
-		"Print all found problems by Qodana in the CLI output",
+		qdenv.IsGithubActions(),
+		"Print all found problems by Qodana in the CLI output (default true if Qodana is executed on GitHub Actions)",
 	)
+	AddProblemsFlags(flags, &options.ProblemsFormat, &options.ProblemsSeverities)
 	flags.BoolVar(
 		&options.GenerateCodeClimateReport,
 		"code-climate",
@@ -153,8 +242,57 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 		"Send the results BitBucket code Insights, no additional configuration required if ran in BitBucket Pipelines (default true if Qodana is executed on BitBucket Pipelines)",
 	)
 	flags.BoolVar(&options.ClearCache, "clear-cache", false, "Clear the local Qodana cache before running the analysis")
+	flags.BoolVar(
+		&options.SkipDiskCheck,
+		"skip-disk-check",
+		false,
+		"Skip the check that the disks have enough free space for the caches, the results and the linter image before running the analysis",
+	)
+	flags.BoolVar(
+		&options.Resume,
+		"resume",
+		false,
+		"Resume the scan interrupted e.g. by a crash of the CI agent, skipping the stages it completed (the image pull and the bootstrap) and keeping the indexes in the cache",
+	)
+	flags.StringVar(
+		&options.CacheRemote,
+		"cache-remote",
+		"",
+		"Location to share the cache in: the URL of 'qodana cache serve' (set QODANA_CACHE_TOKEN if it requires a token), s3://bucket[/prefix], gs://bucket[/prefix], az://container[/prefix] or a directory. The cache is downloaded before the analysis if the local cache is empty, and uploaded after it",
+	)
+	flags.StringVar(
+		&options.CacheKey,
+		"cache-key",
+		"",
+		"Key of the remote cache, may use {linter}, {project}, {branch}, {os} and {hashFiles:<glob>[,<glob>]} placeholders, e.g. qodana-{branch}-{hashFiles:**/pom.xml} (default {linter}-{project}-{branch})",
+	)
+	flags.StringSliceVar(
+		&options.CacheRestoreKeys,
+		"cache-restore-keys",
+		nil,
+		"Key prefixes to restore the most recent remote cache by if there is no cache for --cache-key, tried in order (default the key without its trailing '-' separated segments)",
+	)
+	flags.StringVar(
+		&options.PublishTo,
+		"publish-to",
+		"",
+		"Upload the results and the HTML report to s3://bucket[/prefix], gs://bucket[/prefix] or az://container[/prefix] using the standard credentials of the cloud (e.g. AWS_ACCESS_KEY_ID, GOOGLE_APPLICATION_CREDENTIALS, AZURE_STORAGE_CONNECTION_STRING)",
+	)
+	flags.DurationVar(
+		&options.PublishRetention,
+		"publish-retention",
+		0,
+		"Delete the runs published to --publish-to location earlier than the given time ago (e.g. 720h). Zero keeps all runs",
+	)
+	flags.DurationVar(
+		&options.PublishUrlExpiry,
+		"publish-url-expiry",
+		24*time.Hour,
+		"Expiration time of the signed URL of the published SARIF results, at most 168h",
+	)
 	flags.BoolVarP(&options.ShowReport, "show-report", "w", false, "Serve HTML report on port")
 	flags.IntVar(&options.Port, "port", 8080, "Port to serve the report on")
+	AddServeFlags(flags, &options.Serve)
 	flags.StringVar(
 		&options.ConfigName,
 		"config",
@@ -162,6 +300,18 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 		"Set a custom configuration file instead of 'qodana.yaml'. Relative paths in the configuration will be based on the project directory.",
 	)
 
+	flags.StringVar(
+		&options.Auth,
+		"auth",
+		"token",
+		"Qodana Cloud authentication method: 'token' uses QODANA_TOKEN, 'oidc' exchanges the CI (GitHub Actions, GitLab CI) OIDC ID token for a short-lived Qodana Cloud token",
+	)
+	flags.DurationVar(
+		&options.LicenseCacheTtl,
+		"license-cache-ttl",
+		0,
+		"Keep the validated Qodana Cloud license on disk for the given time (e.g. 24h) and use it when the licensing endpoint is unavailable. Zero disables the cache",
+	)
 	flags.StringVarP(
 		&options.AnalysisId,
 		"analysis-id",
@@ -176,6 +326,12 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 		"",
 		"Provide the path to an existing SARIF report to be used in the baseline state calculation",
 	)
+	flags.StringVar(
+		&options.BaselinesDir,
+		"baselines-dir",
+		"",
+		"Directory with the baselines of the branches, named baseline.<branch>.sarif. The baseline of the analysed branch is used, falling back to the target branch of the pull request, the default branch, main and master",
+	)
 	flags.BoolVar(
 		&options.BaselineIncludeAbsent,
 		"baseline-include-absent",
@@ -215,6 +371,66 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 	)
 	flags.StringVarP(&options.ProfileName, "profile-name", "n", "", "Profile name defined in the project")
 	flags.StringVarP(&options.ProfilePath, "profile-path", "p", "", "Path to the profile file")
+	flags.StringSliceVar(
+		&options.Scopes,
+		"scope",
+		nil,
+		"Analyse the scope defined in qodana.scopes.yaml, given by its name or an owner selecting all the scopes they own. Only the problems in the scope paths are reported and checked against the scope thresholds",
+	)
+	flags.StringArrayVar(
+		&options.SeverityMap,
+		"severity-map",
+		nil,
+		"Remap the severity of the problems of an inspection, INSPECTION=SEVERITY, or of a category, category:CATEGORY=SEVERITY, before the quality gates, the baseline and the report, overriding severityMapping of qodana.yaml",
+	)
+	flags.BoolVar(
+		&options.GroupByOwner,
+		"group-by-owner",
+		false,
+		"Summarize the new problems by the owners of their files in CODEOWNERS, in the output, outcome.json and the job summary",
+	)
+	flags.StringVar(
+		&options.OwnersFile,
+		"owners-file",
+		"",
+		"File in the CODEOWNERS format mapping the paths to their owners, used instead of CODEOWNERS of the project. Implies --group-by-owner",
+	)
+	flags.BoolVar(
+		&options.EstimateDebt,
+		"estimate-debt",
+		false,
+		"Estimate the time to fix the problems with the remediation efforts of qodana.yaml or the defaults, in the output, outcome.json and the job summary",
+	)
+	flags.BoolVar(
+		&options.CodeMetrics,
+		"code-metrics",
+		false,
+		"Measure the lines of code and the cyclomatic complexity of the source files and write them to metrics.json in the results directory",
+	)
+	flags.StringArrayVar(
+		&options.FailOnMetric,
+		"fail-on-metric",
+		nil,
+		"Fail the run if a code metric exceeds the maximum, METRIC=MAX with METRIC one of lines, code, complexity (of any file), total-lines, total-code, total-complexity. Implies --code-metrics",
+	)
+	flags.BoolVar(
+		&options.ProfileInspections,
+		"profile-inspections",
+		false,
+		"Record the time of each inspection, print the slowest ones and write them to inspection-profile.json in the results directory (IDE linters only)",
+	)
+	flags.BoolVar(
+		&options.Verbose,
+		"verbose",
+		false,
+		"Stream the analysis log to the console live, marking the start of each stage and coloring the warnings and the errors",
+	)
+	flags.BoolVar(
+		&options.FollowIdeaLog,
+		"follow-idea-log",
+		false,
+		"Follow the IDE log file (log/idea.log in the results directory) during the analysis and print its warnings and errors, all of its lines with --verbose",
+	)
 	flags.StringVar(
 		&options.RunPromo,
 		"run-promo",
@@ -238,6 +454,34 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 		"",
 		"Set the strategy for applying quick-fixes. Available values: 'apply', 'cleanup', 'none'",
 	)
+	flags.StringSliceVar(
+		&options.FixesRules,
+		"apply-fixes-rule",
+		nil,
+		"Keep only the quick-fixes of the problems reported by the given inspections, e.g. UnusedImport,RedundantCast",
+	)
+	flags.StringSliceVar(
+		&options.FixesInclude,
+		"apply-fixes-include",
+		nil,
+		"Keep only the quick-fixes in the project files matching the given globs, e.g. src/main/**",
+	)
+	flags.StringVar(
+		&options.FixesOutput,
+		"fixes-output",
+		FixesOutputApply,
+		"Where to put the quick-fixes applied with --apply-fixes or --cleanup: 'apply' modifies the project, 'patch' writes unified diffs to the results directory and leaves the project untouched",
+	)
+	flags.BoolVar(
+		&options.FixesPush,
+		"fixes-push",
+		false,
+		"Commit the quick-fixes applied with --apply-fixes or --cleanup to a new branch, push it and open a pull request on GitHub or a merge request on GitLab (token from QD_GITHUB_TOKEN/GITHUB_TOKEN or QD_GITLAB_TOKEN/GITLAB_TOKEN)",
+	)
+	flags.StringVar(&options.FixesBranch, "fixes-branch", "", "Branch to push the quick-fixes to with --fixes-push (default qodana/fixes-<timestamp>)")
+	flags.StringVar(&options.FixesCommitMessage, "fixes-commit-message", "Apply Qodana quick-fixes", "Message of the commit created with --fixes-push")
+	flags.StringVar(&options.FixesAuthor, "fixes-author", "Qodana <qodana-support@jetbrains.com>", "Author of the commit created with --fixes-push, as \"Name <email>\"")
+	flags.StringVar(&options.FixesRemote, "fixes-remote", "origin", "Git remote to push the quick-fixes to with --fixes-push")
 
 	flags.StringArrayVar(
 		&options.Property,
@@ -254,6 +498,90 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 		"Qodana analysis time limit in milliseconds. If reached, the analysis is terminated, process exits with code timeout-exit-code. Negative – no timeout",
 	)
 	flags.IntVar(&options.AnalysisTimeoutExitCode, "timeout-exit-code", 1, "See timeout option")
+	flags.DurationVar(
+		&options.BootstrapTimeout,
+		"bootstrap-timeout",
+		0,
+		"Time limit of the whole bootstrap (e.g. 10m). If reached, the analysis is not run, process exits with code timeout-exit-code. Zero – no timeout",
+	)
+	flags.DurationVar(
+		&options.IndexingTimeout,
+		"indexing-timeout",
+		0,
+		"Time limit of opening and indexing the project (e.g. 30m). If reached, the analysis is terminated, process exits with code timeout-exit-code. Zero – no timeout",
+	)
+	flags.DurationVar(
+		&options.InspectionTimeout,
+		"inspection-timeout",
+		0,
+		"Time limit of running the inspections (e.g. 1h). If reached, the analysis is terminated, process exits with code timeout-exit-code. Zero – no timeout",
+	)
+	flags.BoolVar(
+		&options.Reproducible,
+		"reproducible",
+		false,
+		"Produce the same SARIF report for every run on the same commit: pin the linter image to its digest, sort the results, remove the timestamps and the absolute paths, and derive the analysis id from the commit",
+	)
+	flags.BoolVar(
+		&options.Redact,
+		"redact",
+		false,
+		"Replace the project and home directories, the user and the host names in the SARIF report, the logs and the HTML report before the CLI publishes or sends them to Qodana Cloud",
+	)
+	flags.StringVar(
+		&options.RedactionRules,
+		"redaction-rules",
+		"",
+		"YAML file with the redaction rules, e.g. the environment variables whose values to replace and the regular expressions to replace. Implies --redact",
+	)
+	flags.StringVar(
+		&options.EncryptionKeyFile,
+		"encryption-key-file",
+		"",
+		"File with the base64-encoded key to encrypt the results with (or set QODANA_ENCRYPTION_KEY): the contents of the results directory are replaced with their encrypted archive before they are published. Generate a key with qodana encryption keygen",
+	)
+	flags.StringVar(
+		&options.EncryptionRecipient,
+		"encryption-recipient",
+		"",
+		"Base64-encoded public key to encrypt the results for, instead of a shared key, only the holder of the private key can decrypt them. Generate a key pair with qodana encryption keygen --recipient",
+	)
+	flags.StringVar(
+		&options.AuditLog,
+		"audit-log",
+		os.Getenv(qdenv.QodanaAuditLogEnv),
+		"Append the run with its options, user, project revision, exit reason and report hashes to an audit log: 'file' for audit.jsonl in the Qodana system directory, 'syslog', or the path of a JSONL file (or set QODANA_AUDIT_LOG)",
+	)
+	flags.BoolVar(
+		&options.VerifySignatures,
+		"verify-signatures",
+		false,
+		"Verify the minisign or cosign signatures of the policy, the plugin archives and the downloaded IDEs, plugins and jars before using them, the policy can require it with verifySignatures",
+	)
+	flags.StringVar(
+		&options.TrustedKeys,
+		"trusted-keys",
+		"",
+		"File with the minisign and PEM-encoded cosign public keys the signatures are verified with (default trusted-keys.pub in the Qodana user config directory)",
+	)
+	flags.BoolVar(
+		&options.ResultsManifest,
+		"results-manifest",
+		false,
+		"Write the SHA-256 checksums of all the files of the results directory to qodana-results.sha256 in it, check them later with qodana verify-results",
+	)
+	flags.StringVar(
+		&options.ManifestSigningKey,
+		"manifest-signing-key",
+		"",
+		"PEM-encoded ECDSA or RSA private key to sign qodana-results.sha256 with, the signature is written to qodana-results.sha256.sig, verifiable with cosign verify-blob. Implies --results-manifest",
+	)
+	flags.BoolVar(
+		&options.DedupResults,
+		"dedup-results",
+		false,
+		"Share the large files of the results, e.g. the report, with the previous runs on this machine as hard links to a content-addressed store in the Qodana system directory. Remove the files no results directory uses anymore with qodana gc",
+	)
 
 	flags.StringVar(
 		&options.DiffStart,
@@ -267,6 +595,12 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 		"",
 		"Commit to end a diff run on. Only files changed between --diff-start and --diff-end will be analysed.",
 	)
+	flags.StringVar(
+		&options.FilesFrom,
+		"files-from",
+		"",
+		"Analyse only the files listed in the file, one per line, or read the list from stdin with '-'. The git history is not used",
+	)
 	flags.BoolVar(
 		&options.ForceLocalChangesScript,
 		"force-local-changes-script",
@@ -275,12 +609,71 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 	)
 
 	flags.IntVar(&options.JvmDebugPort, "jvm-debug-port", -1, "Enable JVM remote debug under given port")
+	flags.StringArrayVar(
+		&options.VmOptions,
+		"vmoptions",
+		[]string{},
+		"Set VM options of the IDE, e.g. --vmoptions=-Xmx8g, overriding the vmoptions of qodana.yaml",
+	)
+	flags.BoolVar(
+		&options.ProvisionJdk,
+		"provision-jdk",
+		true,
+		"Set JAVA_HOME to the JDK required by the Gradle toolchain or the Maven enforcer rules of the project, downloading it into the cache if it is not installed (native mode only)",
+	)
+	flags.BoolVar(
+		&options.ProvisionNode,
+		"provision-node",
+		true,
+		"Use the Node.js version required by .nvmrc, .node-version or the engines field of package.json, downloading it into the cache if it is not installed (native mode only, inside the Qodana container the version is only validated)",
+	)
+	flags.BoolVar(
+		&options.ProvisionPython,
+		"provision-python",
+		true,
+		"Create a virtualenv in the cache with the dependencies of the Poetry, Pipenv or requirements.txt project, unless a virtualenv is already activated (native mode only)",
+	)
+	flags.BoolVar(
+		&options.ProvisionAndroidSdk,
+		"provision-android-sdk",
+		true,
+		"Install the Android SDK platforms and build tools required by the Gradle modules into the cache, accepting their licenses (native mode only)",
+	)
+	flags.IntVar(
+		&options.AnalysisThreads,
+		"analysis-threads",
+		0,
+		"Number of threads running the inspections, 0 – the CPU limit of the container (the IDE default without a limit)",
+	)
+	flags.IntVar(
+		&options.IndexingThreads,
+		"indexing-threads",
+		0,
+		"Number of threads indexing the project, 0 – derived from the CPU limit of the container, up to 4 (the IDE default without a limit)",
+	)
+
+	flags.StringVar(
+		&options.MetricsPushgateway,
+		"metrics-pushgateway",
+		"",
+		"Push the run metrics (also written to metrics.prom in the results directory) to the given Prometheus Pushgateway URL",
+	)
 
+	flags.BoolVar(
+		&options.NoStatistics,
+		"no-telemetry",
+		qdenv.IsTelemetryDisabled(),
+		fmt.Sprintf(
+			"Disable sending anonymous usage statistics, also disabled with %s=%s. Run 'qodana telemetry preview' to see what is sent",
+			qdenv.QodanaTelemetryEnv,
+			qdenv.TelemetryOff,
+		),
+	)
 	flags.BoolVar(
 		&options.NoStatistics,
 		"no-statistics",
-		false,
-		"[qodana-clang/qodana-dotner]Disable sending anonymous statistics",
+		qdenv.IsTelemetryDisabled(),
+		"Same as --no-telemetry",
 	)
 	flags.StringVar(
 		&options.ClangCompileCommands,
@@ -328,8 +721,15 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 			false,
 			"Only for container runs. Skip pulling the latest Qodana container",
 		)
+		flags.BoolVar(
+			&options.WslSync,
+			"wsl-sync",
+			false,
+			"Only for container runs with the WSL 2 engine of Docker Desktop. Copy a project from a Windows drive into the WSL filesystem with rsync before the run, mounting Windows drives is slow",
+		)
 		cmd.MarkFlagsMutuallyExclusive("linter", "ide")
 		cmd.MarkFlagsMutuallyExclusive("skip-pull", "ide")
+		cmd.MarkFlagsMutuallyExclusive("wsl-sync", "ide")
 		cmd.MarkFlagsMutuallyExclusive("volume", "ide")
 		cmd.MarkFlagsMutuallyExclusive("user", "ide")
 		cmd.MarkFlagsMutuallyExclusive("env", "ide")
@@ -337,9 +737,13 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 
 	cmd.MarkFlagsMutuallyExclusive("script", "force-local-changes-script", "full-history")
 	cmd.MarkFlagsMutuallyExclusive("commit", "script", "diff-start")
+	cmd.MarkFlagsMutuallyExclusive("files-from", "script", "commit", "diff-start", "full-history")
 	cmd.MarkFlagsMutuallyExclusive("profile-name", "profile-path")
+	cmd.MarkFlagsMutuallyExclusive("baseline", "baselines-dir")
 	cmd.MarkFlagsMutuallyExclusive("apply-fixes", "cleanup")
 
+	bindEnv(cmd)
+
 	err := cmd.Flags().MarkDeprecated("fixes-strategy", "use --apply-fixes / --cleanup instead")
 	if err != nil {
 		return err
@@ -353,5 +757,123 @@ func ComputeFlags(cmd *cobra.Command, options *CliOptions) error {
 	if err != nil {
 		return err
 	}
+	err = cmd.Flags().MarkHidden("no-statistics")
+	if err != nil {
+		return err
+	}
+	return registerCompletions(cmd)
+}
+
+// ValidateFixesOptions checks the options controlling where the quick-fixes go.
+func ValidateFixesOptions(options *CliOptions) error {
+	switch options.FixesOutput {
+	case FixesOutputApply, FixesOutputPatch:
+	default:
+		return fmt.Errorf("unknown --fixes-output %q, available values: %s, %s", options.FixesOutput, FixesOutputApply, FixesOutputPatch)
+	}
+	if options.FixesPush && options.FixesOutput == FixesOutputPatch {
+		return fmt.Errorf("--fixes-push cannot be used with --fixes-output=%s", FixesOutputPatch)
+	}
 	return nil
 }
+
+// AddServeFlags adds the flags restricting the access to the served report.
+func AddServeFlags(flags *pflag.FlagSet, options *qdreport.ServeOptions) {
+	flags.StringVar(&options.Host, "serve-host", "", "Address to serve the report on (default all interfaces)")
+	flags.StringVar(
+		&options.BasicAuth,
+		"serve-basic-auth",
+		"",
+		fmt.Sprintf("Require HTTP basic authentication with user:password to view the report (default $%s)", qdenv.QodanaServeBasicAuthEnv),
+	)
+	flags.StringVar(
+		&options.Token,
+		"serve-token",
+		"",
+		fmt.Sprintf("Require the token as a bearer token or as a basic authentication password to view the report (default $%s)", qdenv.QodanaServeTokenEnv),
+	)
+	flags.StringVar(&options.TlsCert, "serve-tls-cert", "", "PEM certificate file to serve the report over HTTPS with, requires --serve-tls-key")
+	flags.StringVar(&options.TlsKey, "serve-tls-key", "", "PEM private key file of --serve-tls-cert")
+}
+
+// AddProblemsFlags adds the flags configuring the printed problems.
+func AddProblemsFlags(flags *pflag.FlagSet, format *string, severities *[]string) {
+	defaultFormat := "text"
+	if qdenv.IsGithubActions() {
+		defaultFormat = "github-annotations"
+	}
+	flags.StringVar(
+		format,
+		"problems-format",
+		defaultFormat,
+		"Format of the printed problems: text (with the source code), table, compact (a line per problem), sarif-jsonl (a SARIF result per line) or github-annotations (GitHub Actions workflow commands, default on GitHub Actions)",
+	)
+	flags.StringSliceVar(
+		severities,
+		"problems-severity",
+		nil,
+		"Print only the problems of the given severities, e.g. critical,high (default all)",
+	)
+}
+
+// AddOutputFlags adds the flags selecting the output mode of the messages, see OutputMode.
+func AddOutputFlags(flags *pflag.FlagSet, quiet *bool, porcelain *bool) {
+	flags.BoolVarP(quiet, "quiet", "q", false, "Do not print the banners, the spinners and the success messages, only the warnings, the errors and the output requested")
+	flags.BoolVar(
+		porcelain,
+		"porcelain",
+		false,
+		"Print only the stable machine-parseable lines (key<TAB>value, e.g. new-problems, report-url, exit-code) to the standard output, the rest goes to the standard error. Implies --quiet",
+	)
+}
+
+// OutputMode returns the output mode of the messages selected by --quiet and --porcelain.
+func OutputMode(quiet bool, porcelain bool) msg.OutputMode {
+	switch {
+	case porcelain:
+		return msg.OutputPorcelain
+	case quiet:
+		return msg.OutputQuiet
+	default:
+		return msg.OutputNormal
+	}
+}
+
+// AddLanguageFlag adds the flag selecting the language of the messages.
+func AddLanguageFlag(flags *pflag.FlagSet, lang *string) {
+	flags.StringVar(
+		lang,
+		"lang",
+		os.Getenv(qdenv.QodanaLangEnv),
+		fmt.Sprintf("Language of the messages: %s (or set %s)", strings.Join(msg.Languages(), ", "), qdenv.QodanaLangEnv),
+	)
+}
+
+// AddAccessibilityFlags adds the flags making the output friendly to the terminals without Unicode and the screen readers.
+func AddAccessibilityFlags(flags *pflag.FlagSet, ascii *bool, screenReader *bool) {
+	defaultAscii := qdenv.IsEnabled(qdenv.QodanaAsciiEnv)
+	usage := fmt.Sprintf(
+		"Print only ASCII: replace the box drawing characters, the icons and the emoji (or set %s=1)",
+		qdenv.QodanaAsciiEnv,
+	)
+	flags.BoolVar(ascii, "ascii", defaultAscii, usage)
+	flags.BoolVar(ascii, "no-unicode", defaultAscii, usage)
+	flags.BoolVar(
+		screenReader,
+		"screen-reader",
+		qdenv.IsEnabled(qdenv.QodanaScreenReaderEnv),
+		fmt.Sprintf(
+			"Make the output friendly to the screen readers: plain ASCII text without colors, the spinners replaced with a line per step and the icons with words (or set %s=1)",
+			qdenv.QodanaScreenReaderEnv,
+		),
+	)
+}
+
+// ApplyAccessibility applies the output selected by AddAccessibilityFlags.
+func ApplyAccessibility(ascii bool, screenReader bool) {
+	if screenReader {
+		msg.SetScreenReader()
+	} else if ascii {
+		msg.SetAscii()
+	}
+}
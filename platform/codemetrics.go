@@ -0,0 +1,43 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcodemetrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// CollectCodeMetrics measures the source files of the project, writes metrics.json to the results directory
+// and checks the metric conditions, it returns whether any of them is violated.
+func CollectCodeMetrics(projectDir string, resultsDir string, conditions []qdcodemetrics.Condition) (bool, error) {
+	metrics, err := qdcodemetrics.Compute(projectDir)
+	if err != nil {
+		return false, fmt.Errorf("failed to measure the code of %s: %w", projectDir, err)
+	}
+	path, err := metrics.Write(resultsDir)
+	if err != nil {
+		return false, fmt.Errorf("failed to write the code metrics: %w", err)
+	}
+	log.Debugf("Code metrics are written to %s", path)
+	violations := metrics.Check(conditions)
+	for _, violation := range violations {
+		msg.ErrorMessage("Metric condition failed: %s", violation)
+	}
+	return len(violations) > 0, nil
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/JetBrains/qodana-cli/v2024/cloud"
 	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
 	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdreport"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	"github.com/pterm/pterm"
@@ -165,28 +166,32 @@ func analyzerToSelect(analyzers []string, path string) (map[string]string, []str
 	return analyzersMap, analyzersList
 }
 
-// ShowReport serves the Qodana report
-func ShowReport(resultsDir string, reportPath string, port int) {
+// ShowReport serves the Qodana report and the API over its results, see qdreport.Handler.
+func ShowReport(resultsDir string, reportPath string, projectDir string, port int, serve qdreport.ServeOptions) {
 	cloudUrl := cloud.GetReportUrl(resultsDir)
 	if cloudUrl != "" {
-		openReport(cloudUrl, reportPath, port)
+		openReport(cloudUrl, reportPath, resultsDir, projectDir, port, serve)
 	} else {
+		serve = serve.WithEnv()
+		if err := serve.Validate(); err != nil {
+			log.Fatal(err)
+		}
 		msg.WarningMessage("Press Ctrl+C to stop serving the report\n")
 		msg.PrintProcess(
 			func(_ *pterm.SpinnerPrinter) {
 				if _, err := os.Stat(reportPath); os.IsNotExist(err) {
 					log.Fatal("Qodana report not found. Get a report by running `qodana scan`")
 				}
-				openReport("", reportPath, port)
+				openReport("", reportPath, resultsDir, projectDir, port, serve)
 			},
-			fmt.Sprintf("Showing Qodana report from %s", fmt.Sprintf("http://localhost:%d/", port)),
+			fmt.Sprintf("Showing Qodana report from %s", serve.Url(port)),
 			"",
 		)
 	}
 }
 
 // openReport serves the report on the given port and opens the browser.
-func openReport(cloudUrl string, path string, port int) {
+func openReport(cloudUrl string, path string, resultsDir string, projectDir string, port int, serve qdreport.ServeOptions) {
 	if cloudUrl != "" {
 		resp, err := http.Get(cloudUrl)
 		if err == nil && resp.StatusCode == 200 {
@@ -197,18 +202,14 @@ func openReport(cloudUrl string, path string, port int) {
 		}
 		return
 	} else {
-		url := fmt.Sprintf("http://localhost:%d", port)
-		go func() {
-			resp, err := http.Get(url)
-			if err == nil && resp.StatusCode == 200 {
-				err := utils.OpenBrowser(url)
-				if err != nil {
-					return
-				}
-			}
-		}()
-		http.Handle("/", noCache(http.FileServer(http.Dir(path))))
-		err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
+		listener, err := serve.Listen(port)
+		if err == nil {
+			// open the browser once the port is listened on
+			go func() {
+				_ = utils.OpenBrowser(serve.Url(port))
+			}()
+			err = serve.Serve(listener, noCache(qdreport.Handler(http.FileServer(http.Dir(path)), resultsDir, projectDir)))
+		}
 		if err != nil {
 			msg.WarningMessage("Problem serving report, %s\n", err.Error())
 			return
@@ -24,6 +24,7 @@ import (
 	"github.com/JetBrains/qodana-cli/v2024/platform/product"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/platform/userconfig"
 	"os"
 	"path/filepath"
 )
@@ -40,6 +41,7 @@ func Compute(
 	projectDir string,
 	qodanaYamlPath string,
 ) Context {
+	configureCloudEndpoint(projectDir, qodanaYamlPath)
 	linter, ide := computeActualLinterAndIde(
 		linterFromCliOptions,
 		ideFromCliOptions,
@@ -112,6 +114,22 @@ func computeActualLinterAndIde(
 	return linter, ide
 }
 
+// configureCloudEndpoint exposes the Qodana Cloud endpoint from qodana.yaml or the user config as environment variables.
+// The endpoint set explicitly in the environment always wins.
+func configureCloudEndpoint(projectDir string, qodanaYamlPath string) {
+	cloudConfig := qdyaml.LoadQodanaYaml(projectDir, qodanaYamlPath).Cloud
+	if cloudConfig.CaCertificate != "" && !filepath.IsAbs(cloudConfig.CaCertificate) {
+		cloudConfig.CaCertificate = filepath.Join(projectDir, cloudConfig.CaCertificate)
+	}
+	if cloudConfig.IsEmpty() {
+		cloudConfig = userconfig.Load().CloudFor(projectDir)
+	}
+	if os.Getenv(qdenv.QodanaEndpointEnv) == "" {
+		qdenv.SetEnv(qdenv.QodanaCaCertificateEnv, cloudConfig.CaCertificate)
+	}
+	qdenv.SetEnv(qdenv.QodanaEndpointEnv, cloudConfig.Endpoint)
+}
+
 func computeId(linter string, ide string, projectDir string) string {
 	var analyzer string
 	if linter != "" {
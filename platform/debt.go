@@ -0,0 +1,159 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"strings"
+	"time"
+)
+
+// defaultRemediation is the effort to fix a problem by its lowercase severity.
+var defaultRemediation = map[string]time.Duration{
+	severityCritical: time.Hour,
+	severityHigh:     30 * time.Minute,
+	severityModerate: 15 * time.Minute,
+	severityLow:      5 * time.Minute,
+	severityInfo:     time.Minute,
+}
+
+// Remediation is the effort to fix the problems by their severity and inspection.
+type Remediation struct {
+	severities  map[string]time.Duration
+	inspections map[string]time.Duration
+}
+
+// Debt is the estimated time to fix the problems of the run.
+type Debt struct {
+	TotalMinutes int `json:"totalMinutes"`
+	NewMinutes   int `json:"newMinutes"`
+}
+
+// debtEstimate is the estimate of the run written to outcome.json and to the job summary.
+var debtEstimate *Debt
+
+// NewRemediation returns the efforts of qodana.yaml over the defaults, nil if the estimation is neither configured
+// nor enabled.
+func NewRemediation(config qdyaml.Remediation, enabled bool) (*Remediation, error) {
+	if !enabled && len(config.Severities) == 0 && len(config.Inspections) == 0 {
+		return nil, nil
+	}
+	remediation := &Remediation{severities: map[string]time.Duration{}, inspections: map[string]time.Duration{}}
+	for severity, effort := range defaultRemediation {
+		remediation.severities[severity] = effort
+	}
+	for severity, effort := range config.Severities {
+		if _, ok := defaultRemediation[strings.ToLower(severity)]; !ok {
+			return nil, fmt.Errorf("unknown severity %q of the remediation effort", severity)
+		}
+		duration, err := parseEffort(effort)
+		if err != nil {
+			return nil, err
+		}
+		remediation.severities[strings.ToLower(severity)] = duration
+	}
+	for inspection, effort := range config.Inspections {
+		duration, err := parseEffort(effort)
+		if err != nil {
+			return nil, err
+		}
+		remediation.inspections[inspection] = duration
+	}
+	return remediation, nil
+}
+
+func parseEffort(effort string) (time.Duration, error) {
+	duration, err := time.ParseDuration(effort)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid remediation effort %q, expected a duration like 15m or 1h30m", effort)
+	}
+	return duration, nil
+}
+
+// effort returns the effort to fix the problem.
+func (r *Remediation) effort(result *sarif.Result) time.Duration {
+	if effort, ok := r.inspections[result.RuleId]; ok {
+		return effort
+	}
+	return r.severities[strings.ToLower(getSeverity(result))]
+}
+
+// EstimateDebt estimates the time to fix the problems of the SARIF report, prints it and keeps it for outcome.json
+// and the job summary. The problems resolved since the baseline are not counted, the new ones are counted as in
+// the quality gates.
+func EstimateDebt(sarifPath string, remediation *Remediation) error {
+	debt, err := estimateDebt(sarifPath, remediation)
+	if err != nil {
+		return err
+	}
+	debtEstimate = debt
+	msg.SuccessMessage(
+		"Estimated remediation time: %s, %s for the new problems",
+		formatMinutes(debt.TotalMinutes),
+		formatMinutes(debt.NewMinutes),
+	)
+	return nil
+}
+
+func estimateDebt(sarifPath string, remediation *Remediation) (*Debt, error) {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the SARIF report: %w", err)
+	}
+	var total, newEffort time.Duration
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			r := &run.Results[i]
+			if r.BaselineState == baselineStateAbsent {
+				continue
+			}
+			effort := remediation.effort(r)
+			total += effort
+			if isNewProblem(r) {
+				newEffort += effort
+			}
+		}
+	}
+	return &Debt{TotalMinutes: int(total.Minutes()), NewMinutes: int(newEffort.Minutes())}, nil
+}
+
+// formatMinutes formats the minutes as hours and minutes, e.g. 26h 15m.
+func formatMinutes(minutes int) string {
+	switch {
+	case minutes < 60:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%dh", minutes/60)
+	default:
+		return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+	}
+}
+
+// debtMarkdown returns the estimate as a line of the job summary.
+func debtMarkdown(debt *Debt) string {
+	if debt == nil {
+		return ""
+	}
+	return fmt.Sprintf(
+		"\nEstimated remediation time: **%s**, %s for the new problems\n",
+		formatMinutes(debt.TotalMinutes),
+		formatMinutes(debt.NewMinutes),
+	)
+}
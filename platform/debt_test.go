@@ -0,0 +1,91 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"path/filepath"
+	"testing"
+)
+
+func TestEstimateDebt(t *testing.T) {
+	result := func(ruleId string, severity string, baselineState interface{}) sarif.Result {
+		return sarif.Result{
+			RuleId:        ruleId,
+			Message:       &sarif.Message{Text: "problem"},
+			BaselineState: baselineState,
+			Properties:    &sarif.PropertyBag{AdditionalProperties: map[string]interface{}{"qodanaSeverity": severity}},
+		}
+	}
+	report := &sarif.Report{
+		Version: "2.1.0",
+		Runs: []sarif.Run{
+			{
+				Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "QDTEST"}},
+				Results: []sarif.Result{
+					result("ConstantConditions", qodanaCritical, baselineStateNew),
+					result("ConstantConditions", qodanaHigh, baselineStateUnchanged),
+					result("UnusedImport", qodanaHigh, nil),
+					result("Typo", qodanaLow, nil),
+					result("Typo", qodanaLow, baselineStateAbsent),
+				},
+			},
+		},
+	}
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	if err := WriteReport(sarifPath, report); err != nil {
+		t.Fatal(err)
+	}
+	remediation, err := NewRemediation(
+		qdyaml.Remediation{Severities: map[string]string{"Critical": "2h"}, Inspections: map[string]string{"UnusedImport": "1m"}},
+		false,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	debt, err := estimateDebt(sarifPath, remediation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2h critical + 30m high + 1m UnusedImport + 5m low, the absent problem is not counted
+	if debt.TotalMinutes != 156 || debt.NewMinutes != 126 {
+		t.Errorf("got %+v, want 156 total and 126 new minutes", *debt)
+	}
+	if formatted := formatMinutes(debt.TotalMinutes); formatted != "2h 36m" {
+		t.Errorf("got %s, want 2h 36m", formatted)
+	}
+}
+
+func TestNewRemediation(t *testing.T) {
+	if remediation, err := NewRemediation(qdyaml.Remediation{}, false); remediation != nil || err != nil {
+		t.Errorf("expected no estimation without the configuration, got %v, %v", remediation, err)
+	}
+	if remediation, err := NewRemediation(qdyaml.Remediation{}, true); err != nil || remediation.severities[severityHigh] != defaultRemediation[severityHigh] {
+		t.Errorf("expected the default efforts, got %v, %v", remediation, err)
+	}
+	for _, config := range []qdyaml.Remediation{
+		{Severities: map[string]string{"blocker": "1h"}},
+		{Inspections: map[string]string{"Typo": "5 minutes"}},
+		{Inspections: map[string]string{"Typo": "-5m"}},
+	} {
+		if _, err := NewRemediation(config, false); err == nil {
+			t.Errorf("expected an error for %+v", config)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// duplicatesRuleId is the inspection reporting the duplicated fragments, the first location of its problems is
+// a fragment and the related locations are its clones.
+const duplicatesRuleId = "DuplicatedCode"
+
+// Fragment is a duplicated block of lines.
+type Fragment struct {
+	File      string `json:"file"`
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+}
+
+// CloneGroup is the fragments duplicating each other.
+type CloneGroup struct {
+	Lines     int        `json:"lines"`
+	Fragments []Fragment `json:"fragments"`
+}
+
+// Duplication is the duplicated code of the project.
+type Duplication struct {
+	Groups          []CloneGroup `json:"groups"`
+	DuplicatedLines int          `json:"duplicatedLines"`
+	TotalLines      int          `json:"totalLines"`
+	Percentage      float64      `json:"percentage"`
+}
+
+// FindDuplicates returns the clone groups of the SARIF report from the largest one, and the share of the duplicated
+// lines in the lines of the text files of projectDir, the hidden directories are skipped.
+func FindDuplicates(sarifPath string, projectDir string) (*Duplication, error) {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the SARIF report: %w", err)
+	}
+	d := &Duplication{Groups: []CloneGroup{}}
+	seen := map[string]bool{}
+	duplicated := map[string][]Fragment{}
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			r := &run.Results[i]
+			if r.RuleId != duplicatesRuleId || r.BaselineState == baselineStateAbsent {
+				continue
+			}
+			group := cloneGroup(r)
+			if len(group.Fragments) < 2 {
+				continue
+			}
+			key := cloneGroupKey(group)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			d.Groups = append(d.Groups, group)
+			for _, f := range group.Fragments {
+				duplicated[f.File] = append(duplicated[f.File], f)
+			}
+		}
+	}
+	sort.SliceStable(
+		d.Groups, func(i, j int) bool {
+			if d.Groups[i].Lines != d.Groups[j].Lines {
+				return d.Groups[i].Lines > d.Groups[j].Lines
+			}
+			return cloneGroupKey(d.Groups[i]) < cloneGroupKey(d.Groups[j])
+		},
+	)
+	for _, fragments := range duplicated {
+		d.DuplicatedLines += mergedLines(fragments)
+	}
+	if d.TotalLines, err = countProjectLines(projectDir); err != nil {
+		return nil, fmt.Errorf("failed to count the lines of %s: %w", projectDir, err)
+	}
+	if d.TotalLines > 0 {
+		d.Percentage = float64(d.DuplicatedLines) * 100 / float64(d.TotalLines)
+	}
+	return d, nil
+}
+
+// DuplicationExceeded checks if the duplicated lines exceed the percentage of the lines of the project.
+func DuplicationExceeded(sarifPath string, projectDir string, threshold float64) (bool, error) {
+	d, err := FindDuplicates(sarifPath, projectDir)
+	if err != nil {
+		return false, err
+	}
+	if d.Percentage > threshold {
+		msg.ErrorMessage("%.1f%% duplicated lines exceed the duplication threshold %g%%", d.Percentage, threshold)
+		return true, nil
+	}
+	return false, nil
+}
+
+// cloneGroup returns the fragments of the problem sorted by the file and the line.
+func cloneGroup(r *sarif.Result) CloneGroup {
+	var group CloneGroup
+	for _, locations := range [][]sarif.Location{r.Locations, r.RelatedLocations} {
+		for _, location := range locations {
+			l := location.PhysicalLocation
+			if l == nil || l.ArtifactLocation == nil || l.Region == nil || l.Region.StartLine <= 0 {
+				continue
+			}
+			f := Fragment{
+				File:      strings.TrimPrefix(l.ArtifactLocation.Uri, "file://"),
+				StartLine: int(l.Region.StartLine),
+				EndLine:   int(max(l.Region.EndLine, l.Region.StartLine)),
+			}
+			group.Fragments = append(group.Fragments, f)
+			group.Lines = max(group.Lines, f.EndLine-f.StartLine+1)
+		}
+	}
+	sort.Slice(
+		group.Fragments, func(i, j int) bool {
+			a, b := group.Fragments[i], group.Fragments[j]
+			if a.File != b.File {
+				return a.File < b.File
+			}
+			return a.StartLine < b.StartLine
+		},
+	)
+	return group
+}
+
+// cloneGroupKey is the same for the problems of every fragment of the group.
+func cloneGroupKey(group CloneGroup) string {
+	keys := make([]string, len(group.Fragments))
+	for i, f := range group.Fragments {
+		keys[i] = fmt.Sprintf("%s:%d-%d", f.File, f.StartLine, f.EndLine)
+	}
+	return strings.Join(keys, ",")
+}
+
+// mergedLines returns the number of the lines of the fragments of a file, the overlapping lines are counted once.
+func mergedLines(fragments []Fragment) int {
+	sort.Slice(
+		fragments, func(i, j int) bool {
+			return fragments[i].StartLine < fragments[j].StartLine
+		},
+	)
+	lines, covered := 0, 0
+	for _, f := range fragments {
+		start := max(f.StartLine, covered+1)
+		if f.EndLine >= start {
+			lines += f.EndLine - start + 1
+			covered = f.EndLine
+		}
+	}
+	return lines
+}
+
+// countProjectLines returns the number of the lines of the text files in dir, skipping the hidden directories
+// and the files with NUL bytes.
+func countProjectLines(dir string) (int, error) {
+	total := 0
+	err := filepath.WalkDir(
+		dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != dir && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			lines, err := countTextLines(path)
+			total += lines
+			return err
+		},
+	)
+	return total, err
+}
+
+// countTextLines returns the number of the lines of the file, 0 if it's binary.
+func countTextLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = f.Close() }()
+	lines := 0
+	last := byte('\n')
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		if bytes.IndexByte(buf[:n], 0) >= 0 {
+			return 0, nil
+		}
+		if n > 0 {
+			lines += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if last != '\n' {
+		lines++
+	}
+	return lines, nil
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	location := func(path string, startLine int64, endLine int64) sarif.Location {
+		return sarif.Location{
+			PhysicalLocation: &sarif.PhysicalLocation{
+				ArtifactLocation: &sarif.ArtifactLocation{Uri: path},
+				Region:           &sarif.Region{StartLine: startLine, EndLine: endLine},
+			},
+		}
+	}
+	duplicate := func(fragment sarif.Location, clones ...sarif.Location) sarif.Result {
+		return sarif.Result{
+			RuleId:           duplicatesRuleId,
+			Message:          &sarif.Message{Text: "Duplicated code fragment"},
+			Locations:        []sarif.Location{fragment},
+			RelatedLocations: clones,
+		}
+	}
+	report := &sarif.Report{
+		Version: "2.1.0",
+		Runs: []sarif.Run{
+			{
+				Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "QDTEST"}},
+				Results: []sarif.Result{
+					duplicate(location("src/A.java", 10, 19), location("src/B.java", 1, 10)),
+					// the same group reported for the other fragment
+					duplicate(location("src/B.java", 1, 10), location("src/A.java", 10, 19)),
+					// overlaps the first group in A.java
+					duplicate(location("src/A.java", 15, 24), location("src/C.java", 5, 14), location("src/C.java", 30, 39)),
+					{RuleId: "UnusedImport", Locations: []sarif.Location{location("src/A.java", 1, 1)}},
+				},
+			},
+		},
+	}
+	dir := t.TempDir()
+	sarifPath := filepath.Join(dir, "results", "qodana.sarif.json")
+	if err := os.MkdirAll(filepath.Dir(sarifPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteReport(sarifPath, report); err != nil {
+		t.Fatal(err)
+	}
+	projectDir := filepath.Join(dir, "project")
+	for path, content := range map[string]string{
+		"src/A.java":   strings.Repeat("a\n", 100),
+		"src/B.java":   strings.Repeat("b\n", 60),
+		"src/C.java":   strings.Repeat("c\n", 39) + "c",
+		"logo.png":     "\x89PNG\x00\n\n\n",
+		".git/HEAD":    "ref: refs/heads/main\n",
+		"docs/READ.md": "",
+	} {
+		path = filepath.Join(projectDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := FindDuplicates(sarifPath, projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedGroups := []CloneGroup{
+		{Lines: 10, Fragments: []Fragment{{"src/A.java", 10, 19}, {"src/B.java", 1, 10}}},
+		{Lines: 10, Fragments: []Fragment{{"src/A.java", 15, 24}, {"src/C.java", 5, 14}, {"src/C.java", 30, 39}}},
+	}
+	if !reflect.DeepEqual(d.Groups, expectedGroups) {
+		t.Errorf("got %+v, want %+v", d.Groups, expectedGroups)
+	}
+	// A.java 10-24, B.java 1-10, C.java 5-14 and 30-39
+	if d.DuplicatedLines != 45 || d.TotalLines != 200 || d.Percentage != 22.5 {
+		t.Errorf("got %d of %d duplicated lines (%f%%), want 45 of 200", d.DuplicatedLines, d.TotalLines, d.Percentage)
+	}
+
+	if exceeded, err := DuplicationExceeded(sarifPath, projectDir, 25); err != nil || exceeded {
+		t.Errorf("expected the duplication under 25%%, got %v, %v", exceeded, err)
+	}
+	if exceeded, err := DuplicationExceeded(sarifPath, projectDir, 20); err != nil || !exceeded {
+		t.Errorf("expected the duplication over 20%%, got %v, %v", exceeded, err)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"compress/gzip"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/platform/product"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtemp"
 	"github.com/JetBrains/qodana-cli/v2024/platform/thirdpartyscan"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	"github.com/JetBrains/qodana-cli/v2024/tooling"
@@ -119,7 +120,7 @@ func ProcessAuxiliaryTool(toolName, moniker, tempMountPath string, mountPath str
 }
 
 func getTempDir() (string, error) {
-	tmpDir, err := os.MkdirTemp("", "qodana-platform")
+	tmpDir, err := qdtemp.MkdirTemp("qodana-platform")
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"crypto/ecdh"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcache"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcrypt"
+	"os"
+	"path/filepath"
+)
+
+// EncryptedResultsName is the name of the encrypted archive replacing the contents of the results directory.
+const EncryptedResultsName = "qodana-results.tar.gz.enc"
+
+// EncryptResults replaces the contents of dir with their encrypted tar.gz archive, so the results are neither kept
+// nor published in plain text, and returns the path of the archive.
+func EncryptResults(e *qdcrypt.Encryption, dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	// the archive is written next to dir while its contents are archived
+	tmp, err := os.CreateTemp(filepath.Dir(dir), ".qodana-results-*.enc")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if err = writeEncryptedArchive(e, tmp, dir); err != nil {
+		_ = tmp.Close()
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if err = os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return "", err
+		}
+	}
+	archive := filepath.Join(dir, EncryptedResultsName)
+	return archive, os.Rename(tmp.Name(), archive)
+}
+
+func writeEncryptedArchive(e *qdcrypt.Encryption, f *os.File, dir string) error {
+	w, err := e.Encrypt(f)
+	if err != nil {
+		return err
+	}
+	if err = qdcache.Archive(w, dir); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// DecryptResults extracts the archive written by EncryptResults to dir with the shared key or the identity.
+func DecryptResults(archive string, key []byte, identity *ecdh.PrivateKey, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	r, err := qdcrypt.Decrypt(f, key, identity)
+	if err != nil {
+		return err
+	}
+	return qdcache.Extract(r, dir)
+}
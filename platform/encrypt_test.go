@@ -0,0 +1,80 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcrypt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptResults(t *testing.T) {
+	resultsDir := filepath.Join(t.TempDir(), "results")
+	files := map[string]string{
+		"qodana.sarif.json": `{"runs": []}`,
+		"log/idea.log":      "analysis finished",
+	}
+	for name, content := range files {
+		path := filepath.Join(resultsDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	encoded, err := qdcrypt.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(qdcrypt.KeyEnv, encoded)
+	encryption, err := qdcrypt.Load("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := EncryptResults(encryption, resultsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(resultsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != EncryptedResultsName {
+		t.Fatalf("expected only %s in the results directory, got %v", EncryptedResultsName, entries)
+	}
+	if siblings, _ := os.ReadDir(filepath.Dir(resultsDir)); len(siblings) != 1 {
+		t.Errorf("expected the temporary archive to be removed, got %v", siblings)
+	}
+
+	key, _ := qdcrypt.ParseKey(encoded)
+	decrypted := t.TempDir()
+	if err = DecryptResults(archive, key, nil, decrypted); err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range files {
+		content, err := os.ReadFile(filepath.Join(decrypted, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != expected {
+			t.Errorf("%s = %q, want %q", name, content, expected)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdowners"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	ExportCsv  = "csv"
+	ExportXlsx = "xlsx"
+)
+
+// ExportColumns are the columns of the exported problems, in the default order.
+var ExportColumns = []string{"rule", "severity", "file", "line", "message", "baseline", "owner"}
+
+// rowWriter writes the rows of a table.
+type rowWriter interface {
+	Write(row []string) error
+	Close() error
+}
+
+// ExportProblems writes the problems of the SARIF report as a CSV or XLSX table with the columns to w and returns
+// the number of the problems written. The report is streamed, it's never held in memory. owners can be nil
+// if the owner column is not exported.
+func ExportProblems(sarifPath string, format string, columns []string, owners *qdowners.Owners, w io.Writer) (int, error) {
+	for _, column := range columns {
+		if !containsString(ExportColumns, column) {
+			return 0, fmt.Errorf("unknown column %q, expected %s", column, strings.Join(ExportColumns, ", "))
+		}
+	}
+	if owners == nil && containsString(columns, "owner") {
+		return 0, fmt.Errorf("the owners are required to export the owner column")
+	}
+	var writer rowWriter
+	switch format {
+	case ExportCsv:
+		writer = &csvRowWriter{w: csv.NewWriter(w)}
+	case ExportXlsx:
+		numeric := make([]bool, len(columns))
+		for i, column := range columns {
+			numeric[i] = column == "line"
+		}
+		writer = newXlsxWriter(w, numeric)
+	default:
+		return 0, fmt.Errorf("unknown format %q, expected %s or %s", format, ExportCsv, ExportXlsx)
+	}
+	if err := writer.Write(columns); err != nil {
+		return 0, err
+	}
+
+	results := make(chan sarifResultItem, sarifPipelineBuffer)
+	var readErr error
+	read := make(chan struct{})
+	go func() {
+		_, readErr = streamSarifResults(sarifPath, results)
+		close(read)
+	}()
+	written := 0
+	var writeErr error
+	for item := range results {
+		// the results are drained after a write error, so the reader finishes
+		if writeErr != nil {
+			continue
+		}
+		if writeErr = writer.Write(exportRow(&item.result, columns, owners)); writeErr == nil {
+			written++
+		}
+	}
+	<-read
+	if readErr != nil {
+		return written, readErr
+	}
+	if writeErr != nil {
+		return written, writeErr
+	}
+	return written, writer.Close()
+}
+
+func exportRow(r *sarif.Result, columns []string, owners *qdowners.Owners) []string {
+	path, line, _ := problemLocation(r)
+	path = strings.TrimPrefix(path, "file://")
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		switch column {
+		case "rule":
+			row[i] = r.RuleId
+		case "severity":
+			row[i] = getSeverity(r)
+		case "file":
+			row[i] = path
+		case "line":
+			if line > 0 {
+				row[i] = strconv.Itoa(line)
+			}
+		case "message":
+			row[i] = problemMessage(r)
+		case "baseline":
+			if state, ok := r.BaselineState.(string); ok {
+				row[i] = state
+			}
+		case "owner":
+			if path == "" {
+				row[i] = qdowners.Unowned
+			} else {
+				row[i] = strings.Join(owners.Of(path), " ")
+			}
+		}
+	}
+	return row
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) Write(row []string) error {
+	return c.w.Write(row)
+}
+
+func (c *csvRowWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdowners"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeExportReport(t *testing.T) string {
+	result := func(ruleId string, path string, line int64, severity string, text string, baselineState interface{}) sarif.Result {
+		return sarif.Result{
+			RuleId:        ruleId,
+			Message:       &sarif.Message{Text: text},
+			BaselineState: baselineState,
+			Properties:    &sarif.PropertyBag{AdditionalProperties: map[string]interface{}{"qodanaSeverity": severity}},
+			Locations: []sarif.Location{
+				{
+					PhysicalLocation: &sarif.PhysicalLocation{
+						ArtifactLocation: &sarif.ArtifactLocation{Uri: path},
+						Region:           &sarif.Region{StartLine: line},
+					},
+				},
+			},
+		}
+	}
+	report := &sarif.Report{
+		Version: "2.1.0",
+		Runs: []sarif.Run{
+			{
+				Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "QDTEST"}},
+				Results: []sarif.Result{
+					result("UnusedImport", "backend/a.go", 3, qodanaHigh, "Unused import", baselineStateNew),
+					result("ConstantConditions", "README.md", 12, qodanaLow, `Condition "a, b" is <always> true`, nil),
+				},
+			},
+		},
+	}
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	if err := WriteReport(sarifPath, report); err != nil {
+		t.Fatal(err)
+	}
+	return sarifPath
+}
+
+func TestExportProblemsCsv(t *testing.T) {
+	sarifPath := writeExportReport(t)
+	ownersFile := filepath.Join(t.TempDir(), "CODEOWNERS")
+	if err := os.WriteFile(ownersFile, []byte("/backend/ @org/backend\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	owners, err := qdowners.Load(ownersFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	written, err := ExportProblems(sarifPath, ExportCsv, ExportColumns, owners, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 2 {
+		t.Errorf("got %d problems written, want 2", written)
+	}
+	expected := "rule,severity,file,line,message,baseline,owner\n" +
+		"UnusedImport,High,backend/a.go,3,Unused import,new,@org/backend\n" +
+		"ConstantConditions,Low,README.md,12,\"Condition \"\"a, b\"\" is <always> true\",,(unowned)\n"
+	if out.String() != expected {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), expected)
+	}
+}
+
+func TestExportProblemsXlsx(t *testing.T) {
+	sarifPath := writeExportReport(t)
+
+	var out bytes.Buffer
+	written, err := ExportProblems(sarifPath, ExportXlsx, []string{"line", "message"}, nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 2 {
+		t.Errorf("got %d problems written, want 2", written)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheet string
+	for _, f := range archive.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheet = string(content)
+	}
+	for _, expected := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">line</t></is></c>`,
+		`<c r="A2"><v>3</v></c>`,
+		`<c r="B3" t="inlineStr"><is><t xml:space="preserve">Condition &#34;a, b&#34; is &lt;always&gt; true</t></is></c>`,
+	} {
+		if !strings.Contains(sheet, expected) {
+			t.Errorf("%s not found in the sheet\n%s", expected, sheet)
+		}
+	}
+}
+
+func TestExportProblemsUnknownColumn(t *testing.T) {
+	if _, err := ExportProblems("qodana.sarif.json", ExportCsv, []string{"rule", "author"}, nil, io.Discard); err == nil {
+		t.Error("expected an error for the unknown column")
+	}
+}
+
+func TestXlsxColumn(t *testing.T) {
+	for i, expected := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if actual := xlsxColumn(i); actual != expected {
+			t.Errorf("xlsxColumn(%d) = %s, want %s", i, actual, expected)
+		}
+	}
+}
@@ -65,13 +65,13 @@ var (
 	}
 )
 
-// sendBitBucketReport sends annotations to BitBucket code Insights
-func sendBitBucketReport(annotations []bbapi.ReportAnnotation, toolName, cloudUrl, reportId string) error {
+// sendBitBucketReport sends annotations to BitBucket code Insights, problems is the total number of found problems
+func sendBitBucketReport(annotations []bbapi.ReportAnnotation, problems int, toolName, cloudUrl, reportId string) error {
 	client, ctx := getBitBucketClient(), getBitBucketContext()
 	repoOwner, repoName, sha := qdenv.GetBitBucketRepoOwner(), qdenv.GetBitBucketRepoName(), qdenv.GetBitBucketCommit()
 	_, resp, err := client.
 		ReportsApi.CreateOrUpdateReport(ctx, repoOwner, repoName, sha, reportId).
-		Body(buildReport(toolName, annotations, cloudUrl)).
+		Body(buildReport(toolName, problems, cloudUrl)).
 		Execute()
 	if err = checkBitBucketApiError(err, resp, http.StatusOK); err != nil {
 		return fmt.Errorf("failed to create code insights report: %w", err)
@@ -80,7 +80,9 @@ func sendBitBucketReport(annotations []bbapi.ReportAnnotation, toolName, cloudUr
 	if totalAnnotations != 0 {
 		if totalAnnotations > bitBucketAnnotationLimit {
 			totalAnnotations = bitBucketAnnotationLimit
-			log.Debugf("Warning: Only first 1000 of %d annotations will be sent", len(annotations))
+		}
+		if problems > totalAnnotations {
+			log.Debugf("Warning: Only first %d of %d annotations will be sent", totalAnnotations, problems)
 		}
 		for i := 0; i < totalAnnotations; i += 100 {
 			j := i + 100
@@ -122,9 +124,9 @@ func getBitBucketContext() context.Context {
 }
 
 // buildReport builds a report to be sent to BitBucket code Insights
-func buildReport(toolName string, annotations []bbapi.ReportAnnotation, cloudUrl string) bbapi.Report {
+func buildReport(toolName string, problems int, cloudUrl string) bbapi.Report {
 	var result string
-	if len(annotations) == 0 {
+	if problems == 0 {
 		result = bitBucketReportPassed
 	} else {
 		result = bitBucketReportFailed
@@ -136,7 +138,7 @@ func buildReport(toolName string, annotations []bbapi.ReportAnnotation, cloudUrl
 	data.SetReporter(bitBucketReporter)
 	data.SetLogoUrl(bitBucketAvatar)
 	data.SetLink(cloudUrl)
-	data.SetDetails(msg.GetProblemsFoundMessage(len(annotations)))
+	data.SetDetails(msg.GetProblemsFoundMessage(problems))
 	data.SetResult(result)
 	return *data
 }
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"io"
+	"os"
+	"strings"
+)
+
+// https://docs.github.com/en/actions/writing-workflows/choosing-what-your-workflow-does/workflow-commands-for-github-actions
+const (
+	githubOutputEnv      = "GITHUB_OUTPUT"
+	githubStepSummaryEnv = "GITHUB_STEP_SUMMARY"
+)
+
+// MaskGithubSecrets makes GitHub Actions hide the given values in the job logs.
+func MaskGithubSecrets(w io.Writer, secrets ...string) {
+	for _, secret := range secrets {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			_, _ = fmt.Fprintf(w, "::add-mask::%s\n", escapeGithubData(secret))
+		}
+	}
+}
+
+// WriteGithubResults exposes the problems count and the report URL as the step outputs and adds them to the job summary.
+func WriteGithubResults(problems int, reportUrl string) {
+	outputs := fmt.Sprintf("problems-count=%d\nreport-url=%s\n", problems, reportUrl)
+	if err := appendToEnvFile(githubOutputEnv, outputs); err != nil {
+		msg.WarningMessage("Unable to set the GitHub Actions outputs: %s", err)
+	}
+	if err := appendToEnvFile(githubStepSummaryEnv, githubSummary(problems, reportUrl)); err != nil {
+		msg.WarningMessage("Unable to write the GitHub Actions job summary: %s", err)
+	}
+}
+
+func githubSummary(problems int, reportUrl string) string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "## Qodana\n\n%s\n", msg.GetProblemsFoundMessage(problems))
+	b.WriteString(ownersMarkdown(ownersSummary))
+	b.WriteString(debtMarkdown(debtEstimate))
+	if reportUrl != "" {
+		_, _ = fmt.Fprintf(&b, "\n[View the report](%s)\n", reportUrl)
+	}
+	return b.String()
+}
+
+// appendToEnvFile appends the content to the file named by the environment variable, it does nothing if the variable is not set.
+func appendToEnvFile(env string, content string) error {
+	path := os.Getenv(env)
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.WriteString(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
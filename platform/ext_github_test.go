@@ -0,0 +1,81 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"bytes"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaskGithubSecrets(t *testing.T) {
+	var out bytes.Buffer
+	MaskGithubSecrets(&out, "token", "", "multi\nline")
+	expected := "::add-mask::token\n::add-mask::multi%0Aline\n"
+	if out.String() != expected {
+		t.Errorf("got %q, want %q", out.String(), expected)
+	}
+}
+
+func TestWriteGithubResults(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output")
+	summary := filepath.Join(dir, "summary")
+	if err := os.WriteFile(output, []byte("previous=1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(githubOutputEnv, output)
+	t.Setenv(githubStepSummaryEnv, summary)
+
+	WriteGithubResults(3, "https://qodana.cloud/report")
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "previous=1\nproblems-count=3\nreport-url=https://qodana.cloud/report\n"
+	if string(data) != expected {
+		t.Errorf("got outputs %q, want %q", string(data), expected)
+	}
+	data, err = os.ReadFile(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Found 3 new problems") || !strings.Contains(string(data), "(https://qodana.cloud/report)") {
+		t.Errorf("unexpected summary %q", string(data))
+	}
+}
+
+func TestGithubPullRequestBaseSha(t *testing.T) {
+	event := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(event, []byte(`{"pull_request":{"base":{"sha":"abc123"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_EVENT_PATH", event)
+
+	t.Setenv("GITHUB_EVENT_NAME", "pull_request")
+	if sha := qdenv.GetGithubPullRequestBaseSha(); sha != "abc123" {
+		t.Errorf("got %q, want abc123", sha)
+	}
+	t.Setenv("GITHUB_EVENT_NAME", "push")
+	if sha := qdenv.GetGithubPullRequestBaseSha(); sha != "" {
+		t.Errorf("got %q for a push event", sha)
+	}
+}
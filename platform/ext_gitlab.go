@@ -20,7 +20,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/sarif"
-	log "github.com/sirupsen/logrus"
 	"os"
 	"path/filepath"
 )
@@ -115,21 +114,49 @@ func sarifResultToCodeClimate(r *sarif.Result) CCIssue {
 	}
 }
 
-// writeGlCodeQualityReport saves GitLab CodeQuality issues to a file in JSON format
-func writeGlCodeQualityReport(issues []CCIssue, sarifPath string) error {
+// glCodeQualityWriter writes GitLab CodeQuality issues to a file in JSON format one by one
+type glCodeQualityWriter struct {
+	file  *os.File
+	count int
+}
+
+// newGlCodeQualityWriter creates the GitLab CodeQuality report file next to the SARIF report
+func newGlCodeQualityWriter(sarifPath string) (*glCodeQualityWriter, error) {
 	outputFile := filepath.Join(filepath.Dir(sarifPath), glCodeQualityReport)
 	file, err := os.Create(outputFile)
 	if err != nil {
-		log.Warnf("Failed to create GitLab CodeQuality report file: %v", err)
+		return nil, fmt.Errorf("failed to create GitLab CodeQuality report file: %w", err)
+	}
+	if _, err = file.WriteString("["); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to write GitLab CodeQuality report: %w", err)
+	}
+	return &glCodeQualityWriter{file: file}, nil
+}
+
+// Write appends the issue to the report
+func (w *glCodeQualityWriter) Write(issue CCIssue) error {
+	data, err := json.Marshal(issue)
+	if err != nil {
+		return fmt.Errorf("failed to write GitLab CodeQuality report: %w", err)
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			log.Warnf("failed to close GitLab CodeQuality report file: %s", err.Error())
-		}
-	}(file)
-	encoder := json.NewEncoder(file)
-	if err := encoder.Encode(issues); err != nil {
+	if w.count > 0 {
+		data = append([]byte(","), data...)
+	}
+	if _, err = w.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write GitLab CodeQuality report: %w", err)
+	}
+	w.count++
+	return nil
+}
+
+// Close finishes the report and closes the file
+func (w *glCodeQualityWriter) Close() error {
+	_, err := w.file.WriteString("]\n")
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
 		return fmt.Errorf("failed to write GitLab CodeQuality report: %w", err)
 	}
 	return nil
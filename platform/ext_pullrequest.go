@@ -0,0 +1,203 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// FixesPullRequest describes the pull (merge) request proposing the applied quick-fixes.
+type FixesPullRequest struct {
+	RemoteUrl string
+	Head      string
+	Base      string
+	Title     string
+	Body      string
+}
+
+// codeHost is the API of the hosting a repository lives on.
+type codeHost struct {
+	gitLab bool
+	apiUrl string
+	repo   string
+	token  string
+}
+
+// OpenFixesPullRequest opens a GitHub pull request or a GitLab merge request and returns its URL.
+func OpenFixesPullRequest(pr FixesPullRequest) (string, error) {
+	host, err := detectCodeHost(pr.RemoteUrl)
+	if err != nil {
+		return "", err
+	}
+	if host.gitLab {
+		var response struct {
+			WebUrl string `json:"web_url"`
+		}
+		err = host.post(
+			fmt.Sprintf("%s/projects/%s/merge_requests", host.apiUrl, url.PathEscape(host.repo)),
+			map[string]any{
+				"source_branch":        pr.Head,
+				"target_branch":        pr.Base,
+				"title":                pr.Title,
+				"description":          pr.Body,
+				"remove_source_branch": true,
+			},
+			&response,
+		)
+		return response.WebUrl, err
+	}
+	var response struct {
+		HtmlUrl string `json:"html_url"`
+	}
+	err = host.post(
+		fmt.Sprintf("%s/repos/%s/pulls", host.apiUrl, host.repo),
+		map[string]any{"head": pr.Head, "base": pr.Base, "title": pr.Title, "body": pr.Body},
+		&response,
+	)
+	return response.HtmlUrl, err
+}
+
+// detectCodeHost recognizes GitHub and GitLab remotes, self-hosted instances are recognized in their CI.
+func detectCodeHost(remoteUrl string) (*codeHost, error) {
+	hostname, repo, err := parseRemoteUrl(remoteUrl)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case hostname == "github.com" || hostname == hostnameOf(os.Getenv("GITHUB_SERVER_URL")):
+		apiUrl := os.Getenv("GITHUB_API_URL")
+		if apiUrl == "" {
+			apiUrl = "https://api.github.com"
+			if hostname != "github.com" {
+				apiUrl = "https://" + hostname + "/api/v3"
+			}
+		}
+		return &codeHost{apiUrl: apiUrl, repo: repo, token: firstEnv("QD_GITHUB_TOKEN", "GITHUB_TOKEN")}, nil
+	case hostname == "gitlab.com" || hostname == os.Getenv("CI_SERVER_HOST"):
+		apiUrl := os.Getenv("CI_API_V4_URL")
+		if apiUrl == "" {
+			apiUrl = "https://" + hostname + "/api/v4"
+		}
+		return &codeHost{gitLab: true, apiUrl: apiUrl, repo: repo, token: firstEnv("QD_GITLAB_TOKEN", "GITLAB_TOKEN")}, nil
+	}
+	return nil, fmt.Errorf("opening pull requests is supported only for GitHub and GitLab repositories, %s is neither", hostname)
+}
+
+// parseRemoteUrl returns the host name and the repository path of https, ssh and scp-like git remotes.
+func parseRemoteUrl(remoteUrl string) (string, string, error) {
+	var hostname, path string
+	if u, err := url.Parse(remoteUrl); err == nil && u.Host != "" {
+		hostname, path = u.Hostname(), u.Path
+	} else if at, colon := strings.Index(remoteUrl, "@"), strings.Index(remoteUrl, ":"); colon > at && at >= 0 {
+		hostname, path = remoteUrl[at+1:colon], remoteUrl[colon+1:]
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if hostname == "" || !strings.Contains(path, "/") {
+		return "", "", fmt.Errorf("unable to parse git remote URL %q", remoteUrl)
+	}
+	return hostname, path, nil
+}
+
+func hostnameOf(serverUrl string) string {
+	u, err := url.Parse(serverUrl)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// post sends the JSON request to the code host API and decodes the response into result.
+func (h *codeHost) post(endpoint string, body any, result any) error {
+	if h.token == "" {
+		if h.gitLab {
+			return fmt.Errorf("set QD_GITLAB_TOKEN or GITLAB_TOKEN to open a merge request")
+		}
+		return fmt.Errorf("set QD_GITHUB_TOKEN or GITHUB_TOKEN to open a pull request")
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.gitLab {
+		req.Header.Set("PRIVATE-TOKEN", h.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+	resp, err := (&http.Client{Timeout: httpTimeout}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("%s responded with %s: %s", endpoint, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, result)
+}
+
+// FixesSummary describes the changes of the quick-fixes patch in Markdown.
+func FixesSummary(patch string) string {
+	files := git.SplitPatch(patch)
+	var buf strings.Builder
+	_, _ = fmt.Fprintf(&buf, "Qodana applied quick-fixes to %d file(s).\n\n| File | Added | Removed |\n|---|---|---|\n", len(files))
+	names := make([]string, 0, len(files))
+	for file := range files {
+		names = append(names, file)
+	}
+	sort.Strings(names)
+	for _, file := range names {
+		added, removed := 0, 0
+		for _, line := range strings.Split(files[file], "\n") {
+			switch {
+			case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			case strings.HasPrefix(line, "+"):
+				added++
+			case strings.HasPrefix(line, "-"):
+				removed++
+			}
+		}
+		_, _ = fmt.Fprintf(&buf, "| `%s` | +%d | -%d |\n", file, added, removed)
+	}
+	return buf.String()
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FixesSelection restricts the applied quick-fixes to the given inspections and project paths.
+type FixesSelection struct {
+	// Rules are the ids of the inspections whose fixes are kept.
+	Rules []string
+	// Include are the globs of the project-relative paths whose fixes are kept.
+	Include []string
+}
+
+// lineRange is an inclusive range of lines.
+type lineRange struct {
+	from, to int
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// IsEmpty reports whether all the fixes are kept.
+func (s FixesSelection) IsEmpty() bool {
+	return len(s.Rules) == 0 && len(s.Include) == 0
+}
+
+// Filter returns the part of the patch selected by the paths and the inspections. The paths of the patch are relative
+// to root, the fixes of the inspections are matched to the hunks overlapping the problems reported in sarifPath.
+func (s FixesSelection) Filter(patch string, root string, projectDir string, sarifPath string) (string, error) {
+	var include []*regexp.Regexp
+	for _, glob := range s.Include {
+		pattern, err := globToRegexp(strings.TrimSpace(glob))
+		if err != nil {
+			return "", fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+		include = append(include, pattern)
+	}
+	var problems map[string][]lineRange
+	if len(s.Rules) > 0 {
+		var err error
+		if problems, err = problemLines(sarifPath, s.Rules); err != nil {
+			return "", err
+		}
+	}
+
+	files := git.SplitPatch(patch)
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var buf strings.Builder
+	for _, path := range paths {
+		rel, err := filepath.Rel(projectDir, filepath.Join(root, filepath.FromSlash(path)))
+		if err != nil || strings.HasPrefix(filepath.ToSlash(rel), "../") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if len(include) > 0 && !matchesAny(include, rel) {
+			continue
+		}
+		filePatch := files[path]
+		if strings.Contains(filePatch, "\nBinary files ") {
+			continue
+		}
+		if problems != nil {
+			filePatch = selectHunks(filePatch, problems[rel])
+		}
+		buf.WriteString(filePatch)
+	}
+	return buf.String(), nil
+}
+
+// problemLines returns the lines of the problems reported by the inspections per project-relative path.
+func problemLines(sarifPath string, rules []string) (map[string][]lineRange, error) {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the problems to select quick-fixes by inspection: %w", err)
+	}
+	selected := map[string]bool{}
+	for _, rule := range rules {
+		selected[strings.TrimSpace(rule)] = true
+	}
+	lines := map[string][]lineRange{}
+	for _, run := range report.Runs {
+		for _, r := range run.Results {
+			location := extractLocationProperties(&r)
+			if !selected[r.RuleId] || location == nil {
+				continue
+			}
+			end := int(r.Locations[0].PhysicalLocation.Region.EndLine)
+			if end < location.StartLine {
+				end = location.StartLine
+			}
+			lines[location.Uri] = append(lines[location.Uri], lineRange{location.StartLine, end})
+		}
+	}
+	return lines, nil
+}
+
+// selectHunks keeps the hunks of the file patch changing any of the lines, the line numbers of the kept hunks
+// are shifted to account for the dropped ones. Returns an empty string when no hunk is kept.
+func selectHunks(filePatch string, lines []lineRange) string {
+	var header strings.Builder
+	var kept strings.Builder
+	var hunk []string
+	allDelta, keptDelta, keptHunks := 0, 0, 0
+	flush := func() {
+		if len(hunk) == 0 {
+			return
+		}
+		m := hunkHeader.FindStringSubmatch(hunk[0])
+		oldStart, oldCount := hunkNumber(m[1], "1"), hunkNumber(m[2], "1")
+		newStart, newCount := hunkNumber(m[3], "1"), hunkNumber(m[4], "1")
+		shift := newStart - oldStart - allDelta
+		allDelta += newCount - oldCount
+		last := oldStart + oldCount - 1
+		if oldCount == 0 {
+			last = oldStart
+		}
+		if overlaps(lines, oldStart, last) {
+			hunk[0] = fmt.Sprintf(
+				"@@ -%d,%d +%d,%d @@%s",
+				oldStart, oldCount, oldStart+keptDelta+shift, newCount, strings.TrimPrefix(hunk[0], m[0]),
+			)
+			kept.WriteString(strings.Join(hunk, ""))
+			keptDelta += newCount - oldCount
+			keptHunks++
+		}
+		hunk = nil
+	}
+	for _, line := range strings.SplitAfter(filePatch, "\n") {
+		switch {
+		case hunkHeader.MatchString(line):
+			flush()
+			hunk = []string{line}
+		case hunk != nil:
+			hunk = append(hunk, line)
+		default:
+			header.WriteString(line)
+		}
+	}
+	flush()
+	if keptHunks == 0 {
+		return ""
+	}
+	return header.String() + kept.String()
+}
+
+func hunkNumber(value string, def string) int {
+	if value == "" {
+		value = def
+	}
+	n, _ := strconv.Atoi(value)
+	return n
+}
+
+func overlaps(lines []lineRange, from int, to int) bool {
+	for _, l := range lines {
+		if l.from <= to && from <= l.to {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(patterns []*regexp.Regexp, path string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
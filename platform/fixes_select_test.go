@@ -0,0 +1,131 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func gitIn(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func numberedLines(n int, change func(i int) string) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		sb.WriteString(change(i))
+	}
+	return sb.String()
+}
+
+func TestFixesSelectionFilter(t *testing.T) {
+	repo := t.TempDir()
+	project := filepath.Join(repo, "project")
+	original := numberedLines(30, func(i int) string { return fmt.Sprintf("line %d\n", i) })
+	for _, file := range []string{"src/A.java", "test/B.java"} {
+		path := filepath.Join(project, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitIn(t, repo, "init", "-q")
+	gitIn(t, repo, "add", "-A")
+	gitIn(t, repo, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init")
+
+	// the first fix inserts two lines after line 5, the second one replaces line 20
+	fixed := numberedLines(
+		30, func(i int) string {
+			switch i {
+			case 5:
+				return "line 5\ninserted 1\ninserted 2\n"
+			case 20:
+				return "fixed 20\n"
+			}
+			return fmt.Sprintf("line %d\n", i)
+		},
+	)
+	for _, file := range []string{"src/A.java", "test/B.java"} {
+		if err := os.WriteFile(filepath.Join(project, filepath.FromSlash(file)), []byte(fixed), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	patch := gitIn(t, repo, "diff")
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	sarifReport := `{"runs": [{"results": [
+		{"ruleId": "Fixable", "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/A.java"}, "region": {"startLine": 20}}}]},
+		{"ruleId": "Other", "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/A.java"}, "region": {"startLine": 5}}}]}
+	]}]}`
+	if err := os.WriteFile(sarifPath, []byte(sarifReport), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	apply := func(selected string) (string, string) {
+		gitIn(t, repo, "checkout", "-q", ".")
+		if selected != "" {
+			patchPath := filepath.Join(t.TempDir(), "selected.patch")
+			if err := os.WriteFile(patchPath, []byte(selected), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			gitIn(t, repo, "apply", patchPath)
+		}
+		a, _ := os.ReadFile(filepath.Join(project, "src", "A.java"))
+		b, _ := os.ReadFile(filepath.Join(project, "test", "B.java"))
+		return string(a), string(b)
+	}
+
+	selected, err := FixesSelection{Include: []string{"src/**"}}.Filter(patch, repo, project, sarifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := apply(selected); a != fixed || b != original {
+		t.Errorf("unexpected result of the path selection:\n%s\n%s", a, b)
+	}
+
+	selected, err = FixesSelection{Rules: []string{"Fixable"}}.Filter(patch, repo, project, sarifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Replace(original, "line 20\n", "fixed 20\n", 1)
+	if a, b := apply(selected); a != expected || b != original {
+		t.Errorf("unexpected result of the inspection selection:\n%s\n%s", a, b)
+	}
+	if !strings.Contains(selected, "@@ -17,7 +17,7 @@") {
+		t.Errorf("the hunk is not shifted back:\n%s", selected)
+	}
+
+	selected, err = FixesSelection{Rules: []string{"Fixable"}, Include: []string{"test/**"}}.Filter(patch, repo, project, sarifPath)
+	if err != nil || selected != "" {
+		t.Errorf("expected nothing to be selected, got %q, %v", selected, err)
+	}
+	if _, err = (FixesSelection{Rules: []string{"Fixable"}}).Filter(patch, repo, project, "missing.sarif.json"); err == nil {
+		t.Error("expected an error for a missing SARIF file")
+	}
+}
@@ -17,13 +17,15 @@
 package git
 
 import (
+	"context"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	log "github.com/sirupsen/logrus"
+	"path/filepath"
 	"strings"
 )
 
-// gitRun runs the git command in the given directory and returns an error if any.
-func gitRun(cwd string, command []string, logdir string) (string, string, error) {
+// gitRun runs the git command in the given directory and returns an error if any, git is terminated when ctx is done.
+func gitRun(ctx context.Context, cwd string, command []string, logdir string) (string, string, error) {
 	args := []string{"git"}
 	args = append(args, command...)
 	logger, err := LOGGER.GetLogger(logdir, "git")
@@ -31,7 +33,7 @@ func gitRun(cwd string, command []string, logdir string) (string, string, error)
 		log.Errorf("Failed to create git logger: %v", err)
 		return "", "", err
 	}
-	stdout, stderr, _, err := utils.RunCmdRedirectOutput(cwd, args...)
+	stdout, stderr, _, err := utils.RunCmdRedirectOutputContext(ctx, cwd, args...)
 	if logger != nil {
 		logger.Printf("Executing command: %v", args)
 		logger.Println(stdout)
@@ -51,52 +53,56 @@ func gitRun(cwd string, command []string, logdir string) (string, string, error)
 }
 
 // Reset resets the git repository to the given commit.
-func Reset(cwd string, sha string, logdir string) error {
-	_, _, err := gitRun(cwd, []string{"reset", "--soft", sha}, logdir)
+func Reset(ctx context.Context, cwd string, sha string, logdir string) error {
+	_, _, err := gitRun(ctx, cwd, []string{"reset", "--soft", sha}, logdir)
 	return err
 }
 
 // ResetBack aborts the git reset.
-func ResetBack(cwd string, logdir string) error {
-	_, _, err := gitRun(cwd, []string{"reset", "'HEAD@{1}'"}, logdir)
+func ResetBack(ctx context.Context, cwd string, logdir string) error {
+	_, _, err := gitRun(ctx, cwd, []string{"reset", "'HEAD@{1}'"}, logdir)
 	return err
 }
 
 // See QD-10767 case for why update submodule is needed
-func CheckoutAndUpdateSubmodule(cwd string, where string, force bool, logdir string) error {
-	err := checkout(cwd, where, force, logdir)
+func CheckoutAndUpdateSubmodule(ctx context.Context, cwd string, where string, force bool, logdir string) error {
+	err := checkout(ctx, cwd, where, force, logdir)
 	if err != nil {
 		return err
 	}
-	err = submoduleUpdate(cwd, force, logdir)
+	err = submoduleUpdate(ctx, cwd, force, logdir)
 	return err
 }
 
 // checkout checks out the given commit / branch.
-func checkout(cwd string, where string, force bool, logdir string) error {
+func checkout(ctx context.Context, cwd string, where string, force bool, logdir string) error {
 	var err error
 	if !force {
-		_, _, err = gitRun(cwd, []string{"checkout", where}, logdir)
+		_, _, err = gitRun(ctx, cwd, []string{"checkout", where}, logdir)
 	} else {
-		_, _, err = gitRun(cwd, []string{"checkout", "-f", where}, logdir)
+		_, _, err = gitRun(ctx, cwd, []string{"checkout", "-f", where}, logdir)
 	}
 	return err
 }
 
 // GitSubmoduleUpdate updates submodules according to current revision
-func submoduleUpdate(cwd string, force bool, logdir string) error {
+func submoduleUpdate(ctx context.Context, cwd string, force bool, logdir string) error {
 	if !force {
-		_, _, err := gitRun(cwd, []string{"submodule", "update", "--init", "--recursive"}, logdir)
+		_, _, err := gitRun(ctx, cwd, []string{"submodule", "update", "--init", "--recursive"}, logdir)
 		return err
 	} else {
-		_, _, err := gitRun(cwd, []string{"submodule", "update", "--init", "--recursive", "--force"}, logdir)
+		_, _, err := gitRun(ctx, cwd, []string{"submodule", "update", "--init", "--recursive", "--force"}, logdir)
 		return err
 	}
 }
 
-// Clean cleans the git repository.
-func Clean(cwd string, logdir string) error {
-	_, _, err := gitRun(cwd, []string{"clean", "-fdx"}, logdir)
+// Clean cleans the git repository, keeping the given paths.
+func Clean(ctx context.Context, cwd string, logdir string, keep ...string) error {
+	command := []string{"clean", "-fdx"}
+	for _, path := range keep {
+		command = append(command, "-e", utils.QuoteIfSpace(path))
+	}
+	_, _, err := gitRun(ctx, cwd, command, logdir)
 	return err
 }
 
@@ -106,8 +112,8 @@ func Revisions(cwd string) []string {
 }
 
 // Root returns absolute path of repo root
-func Root(cwd string, logdir string) (string, error) {
-	stdout, _, err := gitRun(cwd, []string{"rev-parse", "--show-toplevel"}, logdir)
+func Root(ctx context.Context, cwd string, logdir string) (string, error) {
+	stdout, _, err := gitRun(ctx, cwd, []string{"rev-parse", "--show-toplevel"}, logdir)
 	if err != nil {
 		return "", err
 	}
@@ -115,8 +121,13 @@ func Root(cwd string, logdir string) (string, error) {
 }
 
 // RemoteUrl returns the remote url of the git repository.
-func RemoteUrl(cwd string, logdir string) (string, error) {
-	stdout, _, err := gitRun(cwd, []string{"remote", "get-url", "origin"}, logdir)
+func RemoteUrl(ctx context.Context, cwd string, logdir string) (string, error) {
+	return RemoteUrlOf(ctx, cwd, "origin", logdir)
+}
+
+// RemoteUrlOf returns the url of the given remote of the git repository.
+func RemoteUrlOf(ctx context.Context, cwd string, remote string, logdir string) (string, error) {
+	stdout, _, err := gitRun(ctx, cwd, []string{"remote", "get-url", remote}, logdir)
 	if err != nil {
 		return "", err
 	}
@@ -124,16 +135,25 @@ func RemoteUrl(cwd string, logdir string) (string, error) {
 }
 
 // Branch returns the current branch of the git repository.
-func Branch(cwd string, logdir string) (string, error) {
-	stdout, _, err := gitRun(cwd, []string{"rev-parse", "--abbrev-ref", "HEAD"}, logdir)
+func Branch(ctx context.Context, cwd string, logdir string) (string, error) {
+	stdout, _, err := gitRun(ctx, cwd, []string{"rev-parse", "--abbrev-ref", "HEAD"}, logdir)
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSpace(stdout), nil
 }
 
-func CurrentRevision(cwd string, logdir string) (string, error) {
-	stdout, _, err := gitRun(cwd, []string{"rev-parse", "HEAD"}, logdir)
+// DefaultBranch returns the default branch of the remote, as last fetched.
+func DefaultBranch(ctx context.Context, cwd string, remote string, logdir string) (string, error) {
+	stdout, _, err := gitRun(ctx, cwd, []string{"symbolic-ref", "--short", "refs/remotes/" + remote + "/HEAD"}, logdir)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(strings.TrimSpace(stdout), remote+"/"), nil
+}
+
+func CurrentRevision(ctx context.Context, cwd string, logdir string) (string, error) {
+	stdout, _, err := gitRun(ctx, cwd, []string{"rev-parse", "HEAD"}, logdir)
 	if err != nil {
 		return "", err
 	}
@@ -141,10 +161,41 @@ func CurrentRevision(cwd string, logdir string) (string, error) {
 }
 
 // RevisionExists returns true when revision exists in history.
-func RevisionExists(cwd string, revision string, logdir string) bool {
-	_, stderr, err := gitRun(cwd, []string{"show", "--no-patch", revision}, logdir)
+func RevisionExists(ctx context.Context, cwd string, revision string, logdir string) bool {
+	_, stderr, err := gitRun(ctx, cwd, []string{"show", "--no-patch", revision}, logdir)
 	if strings.Contains(stderr, revision) || strings.Contains(stderr, "fatal:") || err != nil {
 		return false
 	}
 	return true
 }
+
+// HooksDir returns the directory of the git hooks of the repository, respecting core.hooksPath.
+func HooksDir(ctx context.Context, cwd string, logdir string) (string, error) {
+	stdout, _, err := gitRun(ctx, cwd, []string{"rev-parse", "--git-path", "hooks"}, logdir)
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(stdout)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(cwd, dir)
+	}
+	return dir, nil
+}
+
+// MergeBase returns the best common ancestor of the two commits.
+func MergeBase(ctx context.Context, cwd string, a string, b string, logdir string) (string, error) {
+	stdout, _, err := gitRun(ctx, cwd, []string{"merge-base", a, b}, logdir)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
+// Clone clones the repository shallowly into dir, the default branch if branch is empty.
+func Clone(ctx context.Context, url string, dir string, branch string, logdir string) error {
+	command := []string{"clone", "--depth", "1", "--single-branch", "--recurse-submodules", "--shallow-submodules"}
+	if branch != "" {
+		command = append(command, "--branch", branch)
+	}
+	return gitExec(ctx, "", nil, append(command, "--", url, dir), logdir)
+}
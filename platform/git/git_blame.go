@@ -0,0 +1,82 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlameCutoff returns the git blame argument limiting the blame to the changes since since,
+// a date (YYYY-MM-DD or RFC 3339) or a commit.
+func BlameCutoff(ctx context.Context, cwd string, since string, logdir string) (string, error) {
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if _, err := time.Parse(layout, since); err == nil {
+			return "--since=" + since, nil
+		}
+	}
+	if !RevisionExists(ctx, cwd, since, logdir) {
+		return "", fmt.Errorf("%s is neither a date (YYYY-MM-DD) nor a commit of the repository", since)
+	}
+	// the commits reachable from since are excluded
+	return "^" + since, nil
+}
+
+// NewLines returns the 1-based numbers of the lines of the file changed since the cutoff returned by BlameCutoff,
+// including the uncommitted changes. tracked is false for the files not tracked by git, all their lines are new.
+func NewLines(ctx context.Context, cwd string, path string, cutoff string, logdir string) (lines map[int]bool, tracked bool, err error) {
+	stdout, _, err := gitRun(ctx, cwd, []string{"ls-files", "--", path}, logdir)
+	if err != nil {
+		return nil, false, err
+	}
+	if strings.TrimSpace(stdout) == "" {
+		return nil, false, nil
+	}
+	// the lines older than the cutoff are blamed on the boundary commit
+	stdout, _, err = gitRun(ctx, cwd, []string{"blame", "--line-porcelain", cutoff, "--", path}, logdir)
+	if err != nil {
+		return nil, true, err
+	}
+	return parseBlameNewLines(stdout), true, nil
+}
+
+// parseBlameNewLines returns the lines of the git blame --line-porcelain output not blamed on the boundary commit.
+func parseBlameNewLines(porcelain string) map[int]bool {
+	lines := map[int]bool{}
+	line, boundary := 0, false
+	for _, s := range strings.Split(porcelain, "\n") {
+		switch {
+		case strings.HasPrefix(s, "\t"):
+			if line > 0 && !boundary {
+				lines[line] = true
+			}
+			line, boundary = 0, false
+		case s == "boundary":
+			boundary = true
+		case line == 0:
+			// the header of the line: <commit> <original line> <final line> [<lines in the group>]
+			fields := strings.Fields(s)
+			if len(fields) >= 3 && len(fields[0]) >= 40 {
+				line, _ = strconv.Atoi(fields[2])
+			}
+		}
+	}
+	return lines
+}
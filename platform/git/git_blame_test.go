@@ -0,0 +1,75 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package git
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewLines(t *testing.T) {
+	repo, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logdir := t.TempDir()
+	runGit(t, exec.Command("git", "init", "-q"), repo)
+	runGit(t, exec.Command("git", "config", "user.email", "test@example.com"), repo)
+	runGit(t, exec.Command("git", "config", "user.name", "test"), repo)
+	writeFile(t, filepath.Join(repo, "Main.java"), "class Main {\n  int a;\n}\n")
+	runGit(t, exec.Command("git", "add", "-A"), repo)
+	legacy := exec.Command("git", "commit", "-q", "-m", "legacy", "--date", "2020-01-01T00:00:00Z")
+	legacy.Env = append(legacy.Environ(), "GIT_COMMITTER_DATE=2020-01-01T00:00:00Z")
+	runGit(t, legacy, repo)
+	cutoffCommit, err := CurrentRevision(context.Background(), repo, logdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(repo, "Main.java"), "class Main {\n  int a;\n  int b;\n}\n")
+	runGit(t, exec.Command("git", "commit", "-q", "-a", "-m", "new code"), repo)
+	writeFile(t, filepath.Join(repo, "Main.java"), "class Main {\n  int a;\n  int b;\n  int c;\n}\n")
+	writeFile(t, filepath.Join(repo, "New.java"), "class New {}\n")
+
+	for _, since := range []string{cutoffCommit, "2021-01-01"} {
+		cutoff, err := BlameCutoff(context.Background(), repo, since, logdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines, tracked, err := NewLines(context.Background(), repo, "Main.java", cutoff, logdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tracked || !reflect.DeepEqual(lines, map[int]bool{3: true, 4: true}) {
+			t.Errorf("since %s: expected the committed and the uncommitted lines 3 and 4 to be new, got %v", since, lines)
+		}
+	}
+
+	cutoff, err := BlameCutoff(context.Background(), repo, cutoffCommit, logdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, tracked, err := NewLines(context.Background(), repo, "New.java", cutoff, logdir); err != nil || tracked {
+		t.Errorf("expected New.java to be untracked, got %v, %v", tracked, err)
+	}
+	if _, err = BlameCutoff(context.Background(), repo, "last-release", logdir); err == nil || !strings.Contains(err.Error(), "last-release") {
+		t.Errorf("expected an unknown cutoff to be rejected, got %v", err)
+	}
+}
@@ -18,6 +18,7 @@ package git
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	log "github.com/sirupsen/logrus"
@@ -60,12 +61,21 @@ func computeAbsPath(cwd string) (string, error) {
 	return cwdAbs, err
 }
 
-func ComputeChangedFiles(cwd string, diffStart string, diffEnd string, logdir string) (ChangedFiles, error) {
+func ComputeChangedFiles(ctx context.Context, cwd string, diffStart string, diffEnd string, logdir string) (ChangedFiles, error) {
+	return computeChangedFiles(ctx, cwd, []string{diffStart, diffEnd}, logdir)
+}
+
+// ComputeStagedFiles returns the changes staged for the next commit.
+func ComputeStagedFiles(ctx context.Context, cwd string, logdir string) (ChangedFiles, error) {
+	return computeChangedFiles(ctx, cwd, []string{"--cached"}, logdir)
+}
+
+func computeChangedFiles(ctx context.Context, cwd string, diffArgs []string, logdir string) (ChangedFiles, error) {
 	absCwd, err := computeAbsPath(cwd)
 	if err != nil {
 		return ChangedFiles{}, err
 	}
-	repoRoot, err := Root(cwd, logdir)
+	repoRoot, err := Root(ctx, cwd, logdir)
 	if err != nil {
 		return ChangedFiles{}, err
 	}
@@ -84,8 +94,9 @@ func ComputeChangedFiles(cwd string, diffStart string, diffEnd string, logdir st
 	}
 
 	_, _, err = gitRun(
+		ctx,
 		cwd,
-		[]string{"diff", diffStart, diffEnd, "--unified=0", "--no-renames", ">", utils.QuoteIfSpace(filePath)},
+		append(append([]string{"diff"}, diffArgs...), "--unified=0", "--no-renames", ">", utils.QuoteIfSpace(filePath)),
 		logdir,
 	)
 	if err != nil {
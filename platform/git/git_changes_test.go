@@ -17,6 +17,7 @@
 package git
 
 import (
+	"context"
 	"encoding/json"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -243,7 +244,7 @@ func TestChangesCalculation(t *testing.T) {
 				repo, err := filepath.EvalSymlinks(repo)
 				assert.NoError(t, err)
 				projDir := filepath.Join(repo, tc.projDir)
-				commits, err := ComputeChangedFiles(projDir, "HEAD~1", "HEAD", temp)
+				commits, err := ComputeChangedFiles(context.Background(), projDir, "HEAD~1", "HEAD", temp)
 
 				for _, file := range commits.Files {
 					relPath, _ := filepath.Rel(repo, file.Path)
@@ -0,0 +1,313 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtemp"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	log "github.com/sirupsen/logrus"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WorkTreeSnapshot records the state of the working tree before quick-fixes modify it.
+type WorkTreeSnapshot struct {
+	Root      string
+	Commit    string
+	untracked map[string]bool
+}
+
+// WorkTreeChanges are the modifications made to the working tree since the snapshot was taken.
+type WorkTreeChanges struct {
+	// Patch is the unified diff of all changes, including files created since the snapshot.
+	Patch string
+	// trackedPatch covers only the files known to git, it is used to revert the changes.
+	trackedPatch string
+	created      []string
+}
+
+// TakeSnapshot records the current working tree of the repository containing cwd without touching the index.
+func TakeSnapshot(ctx context.Context, cwd string, logdir string) (*WorkTreeSnapshot, error) {
+	root, err := Root(ctx, cwd, logdir)
+	if err != nil {
+		return nil, err
+	}
+	stdout, _, err := gitRun(ctx, root, []string{"stash", "create"}, logdir)
+	if err != nil {
+		return nil, err
+	}
+	commit := strings.TrimSpace(stdout)
+	if commit == "" {
+		if commit, err = CurrentRevision(ctx, root, logdir); err != nil {
+			return nil, err
+		}
+	}
+	untracked, err := untrackedFiles(ctx, root, logdir)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &WorkTreeSnapshot{Root: root, Commit: commit, untracked: map[string]bool{}}
+	for _, file := range untracked {
+		snapshot.untracked[file] = true
+	}
+	return snapshot, nil
+}
+
+// Changes returns the modifications made to the working tree since the snapshot.
+// Untracked files that existed before the snapshot and files created inside the excluded directories are ignored.
+func (s *WorkTreeSnapshot) Changes(ctx context.Context, logdir string, exclude ...string) (*WorkTreeChanges, error) {
+	tracked, _, err := gitRun(
+		ctx,
+		s.Root,
+		[]string{"diff", "--binary", "--no-color", "--no-ext-diff", "--no-renames", s.Commit},
+		logdir,
+	)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := untrackedFiles(ctx, s.Root, logdir)
+	if err != nil {
+		return nil, err
+	}
+	changes := &WorkTreeChanges{Patch: tracked, trackedPatch: tracked}
+	for _, file := range untracked {
+		path := filepath.Join(s.Root, file)
+		if s.untracked[file] || isInside(path, exclude) {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		changes.created = append(changes.created, file)
+		changes.Patch += newFilePatch(file, content)
+	}
+	return changes, nil
+}
+
+// Restore reverts the working tree to the state recorded by the snapshot.
+func (s *WorkTreeSnapshot) Restore(ctx context.Context, changes *WorkTreeChanges, logdir string) error {
+	if err := applyPatch(ctx, s.Root, changes.trackedPatch, true, logdir); err != nil {
+		return err
+	}
+	for _, file := range changes.created {
+		if err := os.Remove(filepath.Join(s.Root, file)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyPatch applies the patch to the working tree of the repository at root.
+func ApplyPatch(ctx context.Context, root string, patch string, logdir string) error {
+	return applyPatch(ctx, root, patch, false, logdir)
+}
+
+func applyPatch(ctx context.Context, root string, patch string, reverse bool, logdir string) error {
+	if patch == "" {
+		return nil
+	}
+	file, err := qdtemp.CreateTemp("qodana-fixes-*.patch")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+	if _, err = file.WriteString(patch); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	command := []string{"apply", "--binary"}
+	if reverse {
+		command = append(command, "-R")
+	}
+	return gitExec(ctx, root, nil, append(command, file.Name()), logdir)
+}
+
+// Files returns the changed paths relative to the repository root.
+func (c *WorkTreeChanges) Files() []string {
+	var files []string
+	for file := range SplitPatch(c.Patch) {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// HasTrackedChanges reports whether the files known to git are modified in the working tree or in the index.
+func HasTrackedChanges(ctx context.Context, cwd string, logdir string) (bool, error) {
+	stdout, _, err := gitRun(ctx, cwd, []string{"status", "--porcelain", "--untracked-files=no"}, logdir)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(stdout) != "", nil
+}
+
+// CommitToBranch commits the files to a new branch started at the current revision,
+// then checks out the original revision again. The author is given as "Name <email>".
+func CommitToBranch(ctx context.Context, root string, branch string, files []string, message string, author string, logdir string) error {
+	address, err := mail.ParseAddress(author)
+	if err != nil {
+		return fmt.Errorf("invalid author %q, expected \"Name <email>\": %w", author, err)
+	}
+	original, err := Branch(ctx, root, logdir)
+	if err != nil {
+		return err
+	}
+	if original == "HEAD" {
+		if original, err = CurrentRevision(ctx, root, logdir); err != nil {
+			return err
+		}
+	}
+	if err = gitExec(ctx, root, nil, []string{"checkout", "-b", branch}, logdir); err != nil {
+		return err
+	}
+	if err = gitExec(ctx, root, nil, append([]string{"add", "-A", "--"}, files...), logdir); err != nil {
+		return err
+	}
+	identity := []string{
+		"GIT_AUTHOR_NAME=" + address.Name,
+		"GIT_AUTHOR_EMAIL=" + address.Address,
+		"GIT_COMMITTER_NAME=" + address.Name,
+		"GIT_COMMITTER_EMAIL=" + address.Address,
+	}
+	if err = gitExec(ctx, root, identity, []string{"commit", "--no-verify", "-m", message}, logdir); err != nil {
+		return err
+	}
+	return checkout(ctx, root, original, false, logdir)
+}
+
+// Push pushes the branch to the remote.
+func Push(ctx context.Context, root string, remote string, branch string, logdir string) error {
+	return gitExec(ctx, root, nil, []string{"push", remote, "refs/heads/" + branch}, logdir)
+}
+
+// gitExec runs git without a shell, so the arguments may contain spaces and special characters.
+func gitExec(ctx context.Context, cwd string, env []string, command []string, logdir string) error {
+	logger, err := LOGGER.GetLogger(logdir, "git")
+	if err != nil {
+		log.Errorf("Failed to create git logger: %v", err)
+		return err
+	}
+	cmd := utils.CommandContext(ctx, "git", command...)
+	cmd.Dir = cwd
+	cmd.Env = append(os.Environ(), env...)
+	output, err := cmd.CombinedOutput()
+	if logger != nil {
+		logger.Printf("Executing command: %v", cmd.Args)
+		logger.Println(string(output))
+	}
+	if err != nil {
+		return fmt.Errorf("git %s: %w: %s", strings.Join(command, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// SplitPatch splits a unified diff into per-file patches keyed by the path of the file.
+func SplitPatch(patch string) map[string]string {
+	result := map[string]string{}
+	var current string
+	var buf strings.Builder
+	flush := func() {
+		if current != "" {
+			result[current] = buf.String()
+		}
+		buf.Reset()
+	}
+	for _, line := range strings.SplitAfter(patch, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+			current = patchPath(line)
+		}
+		buf.WriteString(line)
+	}
+	flush()
+	return result
+}
+
+// patchPath extracts the path of the changed file from a "diff --git a/<path> b/<path>" header.
+func patchPath(header string) string {
+	header = strings.TrimSuffix(strings.TrimPrefix(header, "diff --git "), "\n")
+	if i := strings.Index(header, " b/"); i >= 0 {
+		return header[i+len(" b/"):]
+	}
+	return strings.TrimPrefix(header, "a/")
+}
+
+// newFilePatch renders the unified diff creating the given file.
+func newFilePatch(path string, content []byte) string {
+	var buf strings.Builder
+	_, _ = fmt.Fprintf(&buf, "diff --git a/%s b/%s\nnew file mode 100644\n", path, path)
+	if bytes.IndexByte(content, 0) >= 0 {
+		_, _ = fmt.Fprintf(&buf, "Binary files /dev/null and b/%s differ\n", path)
+		return buf.String()
+	}
+	if len(content) == 0 {
+		return buf.String()
+	}
+	text := string(content)
+	noNewline := !strings.HasSuffix(text, "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	_, _ = fmt.Fprintf(&buf, "--- /dev/null\n+++ b/%s\n@@ -0,0 +1,%d @@\n", path, len(lines))
+	for _, line := range lines {
+		buf.WriteString("+" + line + "\n")
+	}
+	if noNewline {
+		buf.WriteString("\\ No newline at end of file\n")
+	}
+	return buf.String()
+}
+
+// isInside reports whether the path is located in one of the directories.
+func isInside(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = resolved
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// untrackedFiles lists the files not known to git and not ignored, relative to the repository root.
+func untrackedFiles(ctx context.Context, root string, logdir string) ([]string, error) {
+	stdout, _, err := gitRun(ctx, root, []string{"ls-files", "--others", "--exclude-standard", "-z"}, logdir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range strings.Split(stdout, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
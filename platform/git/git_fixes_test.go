@@ -0,0 +1,197 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestWorkTreeSnapshot(t *testing.T) {
+	repo, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logdir := t.TempDir()
+	runGit(t, exec.Command("git", "init", "-q"), repo)
+	runGit(t, exec.Command("git", "config", "user.email", "test@example.com"), repo)
+	runGit(t, exec.Command("git", "config", "user.name", "test"), repo)
+	writeFile(t, filepath.Join(repo, "Main.java"), "class Main {\n  int a;\n}\n")
+	writeFile(t, filepath.Join(repo, "src", "Util.java"), "class Util {}\n")
+	runGit(t, exec.Command("git", "add", "-A"), repo)
+	runGit(t, exec.Command("git", "commit", "-q", "-m", "init"), repo)
+
+	// local changes made before the analysis must survive and stay out of the patch
+	writeFile(t, filepath.Join(repo, "src", "Util.java"), "class Util { }\n")
+	writeFile(t, filepath.Join(repo, "notes.txt"), "todo\n")
+
+	snapshot, err := TakeSnapshot(context.Background(), repo, logdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, filepath.Join(repo, "Main.java"), "class Main {\n  final int a;\n}\n")
+	writeFile(t, filepath.Join(repo, "src", "Util.java"), "final class Util { }\n")
+	writeFile(t, filepath.Join(repo, "src", "New.java"), "class New {}")
+	writeFile(t, filepath.Join(repo, ".qodana", "qodana.sarif.json"), "{}")
+
+	changes, err := snapshot.Changes(context.Background(), logdir, filepath.Join(repo, ".qodana"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"+  final int a;",
+		"-class Util { }\n+final class Util { }",
+		"+++ b/src/New.java\n@@ -0,0 +1,1 @@\n+class New {}\n\\ No newline at end of file\n",
+	} {
+		if !strings.Contains(changes.Patch, expected) {
+			t.Errorf("patch does not contain %q:\n%s", expected, changes.Patch)
+		}
+	}
+	if strings.Contains(changes.Patch, "notes.txt") || strings.Contains(changes.Patch, "qodana.sarif.json") {
+		t.Errorf("patch contains unrelated files:\n%s", changes.Patch)
+	}
+
+	patches := SplitPatch(changes.Patch)
+	if len(patches) != 3 {
+		t.Fatalf("expected 3 per-file patches, got %v", patches)
+	}
+	if !strings.HasPrefix(patches["src/Util.java"], "diff --git a/src/Util.java b/src/Util.java\n") ||
+		strings.Contains(patches["src/Util.java"], "Main.java") {
+		t.Errorf("unexpected patch for src/Util.java:\n%s", patches["src/Util.java"])
+	}
+
+	if err = snapshot.Restore(context.Background(), changes, logdir); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(repo, "Main.java")); got != "class Main {\n  int a;\n}\n" {
+		t.Errorf("Main.java is not restored: %q", got)
+	}
+	if got := readFile(t, filepath.Join(repo, "src", "Util.java")); got != "class Util { }\n" {
+		t.Errorf("local changes of Util.java are lost: %q", got)
+	}
+	if _, err = os.Stat(filepath.Join(repo, "src", "New.java")); !os.IsNotExist(err) {
+		t.Errorf("created file is not removed: %v", err)
+	}
+	if got := readFile(t, filepath.Join(repo, "notes.txt")); got != "todo\n" {
+		t.Errorf("untracked file is modified: %q", got)
+	}
+}
+
+func TestCommitToBranchAndPush(t *testing.T) {
+	repo, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := t.TempDir()
+	logdir := t.TempDir()
+	runGit(t, exec.Command("git", "init", "-q", "--bare"), remote)
+	runGit(t, exec.Command("git", "init", "-q", "-b", "main"), repo)
+	runGit(t, exec.Command("git", "config", "user.email", "test@example.com"), repo)
+	runGit(t, exec.Command("git", "config", "user.name", "test"), repo)
+	runGit(t, exec.Command("git", "remote", "add", "origin", remote), repo)
+	writeFile(t, filepath.Join(repo, "Main.java"), "class Main {}\n")
+	runGit(t, exec.Command("git", "add", "-A"), repo)
+	runGit(t, exec.Command("git", "commit", "-q", "-m", "init"), repo)
+
+	if dirty, err := HasTrackedChanges(context.Background(), repo, logdir); err != nil || dirty {
+		t.Fatalf("expected a clean tree: %v %v", dirty, err)
+	}
+	writeFile(t, filepath.Join(repo, "Main.java"), "final class Main {}\n")
+	writeFile(t, filepath.Join(repo, "New File.java"), "class NewFile {}\n")
+	if dirty, _ := HasTrackedChanges(context.Background(), repo, logdir); !dirty {
+		t.Fatal("expected tracked changes")
+	}
+
+	err = CommitToBranch(context.Background(), repo, "qodana/fixes", []string{"Main.java", "New File.java"}, "Apply fixes\n\nDetails", "Qodana Bot <bot@example.com>", logdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch, _ := Branch(context.Background(), repo, logdir); branch != "main" {
+		t.Errorf("expected to return to main, got %s", branch)
+	}
+	if got := readFile(t, filepath.Join(repo, "Main.java")); got != "class Main {}\n" {
+		t.Errorf("the original branch is modified: %q", got)
+	}
+	if err = Push(context.Background(), repo, "origin", "qodana/fixes", logdir); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command("git", "-C", remote, "log", "-1", "--format=%an <%ae>|%s", "qodana/fixes").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "Qodana Bot <bot@example.com>|Apply fixes" {
+		t.Errorf("unexpected pushed commit: %s", got)
+	}
+	out, err = exec.Command("git", "-C", remote, "show", "--name-only", "--format=", "qodana/fixes").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "Main.java\nNew File.java" {
+		t.Errorf("unexpected committed files: %q", got)
+	}
+
+	if err = CommitToBranch(context.Background(), repo, "other", nil, "msg", "not an address", logdir); err == nil {
+		t.Error("expected an invalid author error")
+	}
+}
+
+func TestClone(t *testing.T) {
+	remote := t.TempDir()
+	runGit(t, exec.Command("git", "init", "-q", "-b", "main"), remote)
+	runGit(t, exec.Command("git", "config", "user.email", "test@example.com"), remote)
+	runGit(t, exec.Command("git", "config", "user.name", "test"), remote)
+	writeFile(t, filepath.Join(remote, "Main.java"), "class Main {}\n")
+	runGit(t, exec.Command("git", "add", "-A"), remote)
+	runGit(t, exec.Command("git", "commit", "-q", "-m", "init"), remote)
+	runGit(t, exec.Command("git", "checkout", "-q", "-b", "release"), remote)
+	writeFile(t, filepath.Join(remote, "Main.java"), "final class Main {}\n")
+	runGit(t, exec.Command("git", "commit", "-q", "-am", "release"), remote)
+
+	dir := filepath.Join(t.TempDir(), "clone")
+	if err := Clone(context.Background(), "file://"+filepath.ToSlash(remote), dir, "main", t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if content := readFile(t, filepath.Join(dir, "Main.java")); content != "class Main {}\n" {
+		t.Errorf("expected the main branch to be cloned, got %q", content)
+	}
+	if err := Clone(context.Background(), "file://"+filepath.ToSlash(remote), dir, "", t.TempDir()); err == nil {
+		t.Error("expected cloning into a non-empty directory to fail")
+	}
+}
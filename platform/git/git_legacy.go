@@ -58,3 +58,15 @@ func gitOutput(cwd string, args []string) []string {
 	}
 	return strings.Split(strings.TrimSpace(string(out)), "\n")
 }
+
+// RecentRevisions returns the short hashes of the last commits of HEAD with their subjects separated by a tab,
+// newest first, or nothing if cwd is not a git repository.
+func RecentRevisions(cwd string, count int) []string {
+	cmd := exec.Command("git", "--no-pager", "log", fmt.Sprintf("-%d", count), "--pretty=format:%h\t%s")
+	cmd.Dir = cwd
+	out, err := cmd.Output()
+	if err != nil || len(strings.TrimSpace(string(out))) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(string(out)), "\n")
+}
@@ -17,6 +17,7 @@
 package git
 
 import (
+	"context"
 	log "github.com/sirupsen/logrus"
 	"os"
 	"os/exec"
@@ -36,7 +37,7 @@ func TestGitFunctionalityChange(t *testing.T) {
 	projectPath := createNativeProject(t, "casamples")
 	defer deferredCleanup(projectPath)
 
-	branch, _ := Branch(projectPath, temp)
+	branch, _ := Branch(context.Background(), projectPath, temp)
 	branchLegacy := BranchLegacy(projectPath)
 	if branch != branchLegacy {
 		t.Fatalf("Old and new branch are not equal: old: %v new: %v", branchLegacy, branch)
@@ -44,7 +45,7 @@ func TestGitFunctionalityChange(t *testing.T) {
 	if branch != BRANCH {
 		t.Fatalf("New and expected branch are not equal: new: %v expected: %v", branch, BRANCH)
 	}
-	revision, _ := CurrentRevision(projectPath, temp)
+	revision, _ := CurrentRevision(context.Background(), projectPath, temp)
 	revisionLegacy := CurrentRevisionLegacy(projectPath)
 	if revision != revisionLegacy {
 		t.Fatalf("Old and new revision are not equal: old: %v new: %v", revisionLegacy, revision)
@@ -52,7 +53,7 @@ func TestGitFunctionalityChange(t *testing.T) {
 	if revision != REV {
 		t.Fatalf("New and expected revision are not equal: new: %v expected: %v", revision, REV)
 	}
-	remoteUrl, _ := RemoteUrl(projectPath, temp)
+	remoteUrl, _ := RemoteUrl(context.Background(), projectPath, temp)
 	remoteUrlLegacy := RemoteUrlLegacy(projectPath)
 	if remoteUrl != remoteUrlLegacy {
 		t.Fatalf("Old and new url are not equal: old: %v new: %v", remoteUrlLegacy, remoteUrl)
@@ -60,15 +61,15 @@ func TestGitFunctionalityChange(t *testing.T) {
 	if remoteUrl != REPO {
 		t.Fatalf("New and expected repo urls are not equal: new: %v expected: %v", remoteUrl, REPO)
 	}
-	rootPath, _ := Root(projectPath, temp)
+	rootPath, _ := Root(context.Background(), projectPath, temp)
 	if filepath.ToSlash(rootPath) != filepath.ToSlash(projectPath) {
 		t.Fatalf("Computed git root path are not equal: new: %v expected: %v", rootPath, projectPath)
 	}
-	existsCorrect := RevisionExists(projectPath, REV, temp)
+	existsCorrect := RevisionExists(context.Background(), projectPath, REV, temp)
 	if existsCorrect != true {
 		t.Fatalf("Revision %v is not found in project %v", REV, projectPath)
 	}
-	dontExists := RevisionExists(projectPath, MALFORMED, temp)
+	dontExists := RevisionExists(context.Background(), projectPath, MALFORMED, temp)
 	if dontExists {
 		t.Fatalf("Revision %v is found in project %v", MALFORMED, projectPath)
 	}
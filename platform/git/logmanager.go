@@ -17,6 +17,7 @@
 package git
 
 import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdlogs"
 	"os"
 	"path/filepath"
 	"sync"
@@ -29,6 +30,7 @@ var LOGGER = NewLoggerManager()
 // LoggerManager manages loggers for different commands
 type LoggerManager struct {
 	loggers map[string]*logrus.Logger
+	files   []*qdlogs.Writer
 	mu      sync.Mutex
 }
 
@@ -56,12 +58,13 @@ func (lm *LoggerManager) GetLogger(logdir string, command string) (*logrus.Logge
 	}
 	logFileName := filepath.Join(logdir, filepath.Base(command)+".log")
 
-	logFile, err := os.OpenFile(logFileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	logFile, err := qdlogs.OpenWriter(logFileName)
 	if err != nil {
 		return nil, err
 	}
 
 	logger.SetOutput(logFile)
+	lm.files = append(lm.files, logFile)
 
 	logger.SetFormatter(
 		&logrus.TextFormatter{
@@ -73,3 +76,12 @@ func (lm *LoggerManager) GetLogger(logdir string, command string) (*logrus.Logge
 
 	return logger, nil
 }
+
+// Sync flushes the log files to disk, so that the logs are complete when the CLI is interrupted.
+func (lm *LoggerManager) Sync() {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	for _, f := range lm.files {
+		_ = f.Sync()
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdissues"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"strings"
+	"time"
+)
+
+const (
+	// issueLabel labels the issues created for the problems.
+	issueLabel = "qodana"
+	// maxIssueSummary is the length of the longest issue summary, Jira limits it to 255 characters.
+	maxIssueSummary = 250
+)
+
+// severityRanks order the Qodana severities and the SARIF levels of the non-Qodana reports.
+var severityRanks = map[string]int{
+	severityInfo:     1,
+	severityLow:      2,
+	sarifNote:        2,
+	severityModerate: 3,
+	sarifWarning:     3,
+	severityHigh:     4,
+	sarifError:       4,
+	severityCritical: 5,
+}
+
+// ExportIssues creates the tracker issues of the new problems of the SARIF report with a severity of at least
+// minSeverity, the issues of the problems exported before, found by their fingerprints in db, are updated instead.
+// With dryRun, the issues are only printed.
+func ExportIssues(sarifPath string, tracker qdissues.Tracker, db *qdissues.Db, minSeverity string, dryRun bool) (created int, updated int, err error) {
+	minRank, ok := severityRanks[strings.ToLower(minSeverity)]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown severity %q, expected one of critical, high, moderate, low, info", minSeverity)
+	}
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read the SARIF report: %w", err)
+	}
+	if !dryRun {
+		defer func() {
+			if saveErr := db.Save(); saveErr != nil && err == nil {
+				err = fmt.Errorf("failed to save the exported issues: %w", saveErr)
+			}
+		}()
+	}
+	exported := map[string]bool{}
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			r := &run.Results[i]
+			if !isExportable(r, minRank) {
+				continue
+			}
+			fingerprint := baselineKey(r)
+			if exported[fingerprint] {
+				continue
+			}
+			exported[fingerprint] = true
+			issue := problemIssue(r, fingerprint)
+			previous := db.Get(tracker.Name(), fingerprint)
+			switch {
+			case dryRun && previous != nil:
+				msg.SuccessMessage("Would update %s: %s", previous.Id, issue.Summary)
+				updated++
+			case dryRun:
+				msg.SuccessMessage("Would create: %s", issue.Summary)
+				created++
+			case previous != nil:
+				if err = tracker.Update(previous.Id, issue); err != nil {
+					return created, updated, fmt.Errorf("failed to update %s: %w", previous.Id, err)
+				}
+				now := time.Now().UTC()
+				previous.Updated = &now
+				updated++
+			default:
+				id, url, err := tracker.Create(issue)
+				if err != nil {
+					return created, updated, fmt.Errorf("failed to create the issue of %s: %w", r.RuleId, err)
+				}
+				db.Put(tracker.Name(), fingerprint, &qdissues.Exported{Id: id, Url: url, RuleId: r.RuleId, Exported: time.Now().UTC()})
+				msg.SuccessMessage("Created %s: %s", url, issue.Summary)
+				created++
+			}
+		}
+	}
+	return created, updated, nil
+}
+
+// isExportable returns true if the problem is new, neither in the baseline nor in the old code,
+// and its severity is at least minRank.
+func isExportable(r *sarif.Result, minRank int) bool {
+	if r.BaselineState != nil && (r.BaselineState == baselineStateUnchanged || r.BaselineState == baselineStateAbsent) {
+		return false
+	}
+	return !inOldCode(r) && severityRanks[strings.ToLower(getSeverity(r))] >= minRank
+}
+
+// problemIssue describes the problem as an issue, the fingerprint identifies the problem across the reports.
+func problemIssue(r *sarif.Result, fingerprint string) qdissues.Issue {
+	message := problemMessage(r)
+	summary := []rune(fmt.Sprintf("%s: %s", r.RuleId, strings.SplitN(message, "\n", 2)[0]))
+	if len(summary) > maxIssueSummary {
+		summary = append(summary[:maxIssueSummary-1], '…')
+	}
+	location := "the project"
+	if path, line, _ := problemLocation(r); path != "" {
+		location = path
+		if line > 0 {
+			location = fmt.Sprintf("%s:%d", path, line)
+		}
+	}
+	var description strings.Builder
+	_, _ = fmt.Fprintf(&description, "Qodana found a %s problem of %s in %s:\n\n", getSeverity(r), r.RuleId, location)
+	_, _ = fmt.Fprintf(&description, "%s\n\nFingerprint: %s\n", message, fingerprint)
+	return qdissues.Issue{Summary: string(summary), Description: description.String(), Labels: []string{issueLabel}}
+}
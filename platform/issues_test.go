@@ -0,0 +1,113 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdissues"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type fakeTracker struct {
+	created []qdissues.Issue
+	updated []string
+}
+
+func (t *fakeTracker) Name() string { return "jira/QD" }
+
+func (t *fakeTracker) Create(issue qdissues.Issue) (string, string, error) {
+	t.created = append(t.created, issue)
+	return "QD-1", "https://jira.example.com/browse/QD-1", nil
+}
+
+func (t *fakeTracker) Update(id string, _ qdissues.Issue) error {
+	t.updated = append(t.updated, id)
+	return nil
+}
+
+func TestExportIssues(t *testing.T) {
+	result := func(fingerprint string, severity string, baselineState interface{}) sarif.Result {
+		return sarif.Result{
+			RuleId:              "Rule",
+			Message:             &sarif.Message{Text: "problem " + fingerprint},
+			BaselineState:       baselineState,
+			PartialFingerprints: map[string]string{"equalIndicator/v2": fingerprint},
+			Properties:          &sarif.PropertyBag{AdditionalProperties: map[string]interface{}{"qodanaSeverity": severity}},
+			Locations: []sarif.Location{
+				{
+					PhysicalLocation: &sarif.PhysicalLocation{
+						ArtifactLocation: &sarif.ArtifactLocation{Uri: "src/a.go"},
+						Region:           &sarif.Region{StartLine: 3},
+					},
+				},
+			},
+		}
+	}
+	report := &sarif.Report{
+		Version: "2.1.0",
+		Runs: []sarif.Run{
+			{
+				Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "QDTEST"}},
+				Results: []sarif.Result{
+					result("a", qodanaCritical, nil),
+					result("a", qodanaCritical, nil),
+					result("b", qodanaHigh, baselineStateUnchanged),
+					result("c", qodanaModerate, baselineStateNew),
+				},
+			},
+		},
+	}
+	dir := t.TempDir()
+	sarifPath := filepath.Join(dir, "qodana.sarif.json")
+	if err := WriteReport(sarifPath, report); err != nil {
+		t.Fatal(err)
+	}
+	db, err := qdissues.LoadDb(filepath.Join(dir, qdissues.DbFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := &fakeTracker{}
+
+	if _, _, err = ExportIssues(sarifPath, tracker, db, "severe", false); err == nil {
+		t.Error("expected an unknown severity to be rejected")
+	}
+	created, updated, err := ExportIssues(sarifPath, tracker, db, "high", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 1 || updated != 0 {
+		t.Fatalf("expected only the new critical problem to be exported once, got %d created, %d updated", created, updated)
+	}
+	issue := tracker.created[0]
+	if issue.Summary != "Rule: problem a" || !strings.Contains(issue.Description, "src/a.go:3") {
+		t.Errorf("unexpected issue %+v", issue)
+	}
+
+	db, err = qdissues.LoadDb(filepath.Join(dir, qdissues.DbFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, updated, err = ExportIssues(sarifPath, tracker, db, "moderate", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 1 || updated != 1 || tracker.updated[0] != "QD-1" {
+		t.Errorf("expected the exported problem to be updated and the moderate one created, got %d created, %d updated", created, updated)
+	}
+}
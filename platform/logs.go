@@ -0,0 +1,39 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdlogs"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	log "github.com/sirupsen/logrus"
+)
+
+// PrepareLogDir applies the log limits of the configuration and, unless the interrupted run is resumed, moves the logs
+// of the previous run to their subdirectory of log/runs, so the logs of the run start empty.
+func PrepareLogDir(logDir string, config qdyaml.Logs, resume bool) {
+	limits := qdlogs.NewLimits(config.MaxSizeMb, config.MaxFiles, config.KeepRuns)
+	qdlogs.SetLimits(limits)
+	if resume {
+		return
+	}
+	runDir, err := qdlogs.ArchiveRun(logDir, limits.KeepRuns)
+	if err != nil {
+		log.Warnf("Failed to archive the logs of the previous run: %s", err)
+	} else if runDir != "" {
+		log.Debugf("Moved the logs of the previous run to %s", runDir)
+	}
+}
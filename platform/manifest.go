@@ -0,0 +1,55 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdmanifest"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	manifestEnabled    bool
+	manifestSigningKey string
+)
+
+// EnableResultsManifest makes FinishRun and CancelRun write the checksum manifest of the results directory,
+// signed with --manifest-signing-key if set, when --results-manifest or --manifest-signing-key is set.
+func EnableResultsManifest(options platformcmd.CliOptions) {
+	manifestEnabled = options.ResultsManifestEnabled()
+	manifestSigningKey = options.ManifestSigningKey
+}
+
+// writeResultsManifest writes the checksum manifest of the results directory if it's enabled.
+func writeResultsManifest(resultsDir string) {
+	if !manifestEnabled {
+		return
+	}
+	manifestEnabled = false
+	manifest, err := qdmanifest.Write(resultsDir)
+	if err != nil {
+		log.Warnf("Failed to write %s: %v", qdmanifest.FileName, err)
+		return
+	}
+	if manifestSigningKey != "" {
+		if err = qdmanifest.Sign(resultsDir, manifestSigningKey); err != nil {
+			log.Warnf("Failed to sign %s: %v", manifest, err)
+			return
+		}
+	}
+	log.Debugf("Checksums of the results are written to %s", manifest)
+}
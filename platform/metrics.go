@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdmetrics"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	log "github.com/sirupsen/logrus"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RecordRunMetrics records the metrics of the finished run, writes them to metrics.prom in the results directory
+// and pushes them to the Prometheus Pushgateway if pushgatewayUrl is set.
+func RecordRunMetrics(resultsDir string, projectDir string, start time.Time, exitCode int, pushgatewayUrl string) {
+	qdmetrics.Gauge("qodana_run_duration_seconds", "Duration of the Qodana run in seconds.", time.Since(start).Seconds(), nil)
+	qdmetrics.Gauge("qodana_exit_code", "Exit code of the Qodana run.", float64(exitCode), nil)
+	qdmetrics.Gauge("qodana_run_timestamp_seconds", "Time the Qodana run finished, in Unix seconds.", float64(time.Now().Unix()), nil)
+	if problems, err := countNewProblemsBySeverity(filepath.Join(resultsDir, "qodana.sarif.json")); err == nil {
+		for severity, count := range problems {
+			qdmetrics.Gauge(
+				"qodana_problems",
+				"Number of new problems found by Qodana by severity.",
+				float64(count),
+				map[string]string{"severity": severity},
+			)
+		}
+	} else {
+		log.Debugf("Problems metrics are not available: %v", err)
+	}
+
+	path, err := qdmetrics.WriteFile(resultsDir)
+	if err != nil {
+		log.Warnf("Failed to write metrics: %v", err)
+	} else {
+		log.Debugf("Metrics are written to %s", path)
+	}
+	if pushgatewayUrl != "" {
+		project := projectDir
+		if abs, err := filepath.Abs(projectDir); err == nil {
+			project = filepath.Base(abs)
+		}
+		if err = qdmetrics.Push(pushgatewayUrl, "qodana", map[string]string{"project": project}); err != nil {
+			log.Warnf("Failed to push metrics: %v", err)
+		}
+	}
+}
+
+// RecordCacheHit records whether the Qodana cache was populated before the run.
+func RecordCacheHit(cacheDir string) {
+	hit := 0.0
+	if utils.CheckDirFiles(cacheDir) {
+		hit = 1
+	}
+	qdmetrics.Gauge(
+		"qodana_cache_hit",
+		"1 if the Qodana cache was populated before the run, the average over runs is the cache hit ratio.",
+		hit,
+		nil,
+	)
+}
+
+// CountNewProblems returns the number of new problems in the SARIF report of the results directory by the lowercase severity.
+func CountNewProblems(resultsDir string) (map[string]int, error) {
+	return countNewProblemsBySeverity(GetSarifPath(resultsDir))
+}
+
+// countNewProblemsBySeverity returns the number of new problems in the SARIF report by the lowercase severity.
+func countNewProblemsBySeverity(sarifPath string) (map[string]int, error) {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return nil, err
+	}
+	problems := map[string]int{
+		severityCritical: 0,
+		severityHigh:     0,
+		severityModerate: 0,
+		severityLow:      0,
+		severityInfo:     0,
+	}
+	for _, run := range report.Runs {
+		for _, r := range run.Results {
+			baselineState := baselineStateEmpty
+			if r.BaselineState != nil {
+				baselineState = r.BaselineState.(string)
+			}
+			if baselineState == baselineStateNew || baselineState == baselineStateEmpty {
+				problems[strings.ToLower(getSeverity(&r))]++
+			}
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package msg
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pterm/pterm"
+)
+
+// glyphs are the characters of the decorations of the output.
+type glyphs struct {
+	success    string
+	warning    string
+	failure    string
+	vertical   string
+	horizontal string
+	down       string
+	up         string
+	ellipsis   rune
+}
+
+var (
+	unicodeGlyphs = glyphs{
+		success: "✓ ", warning: "! ", failure: "✗ ", vertical: "│", horizontal: "─", down: "┬", up: "┴", ellipsis: '…',
+	}
+	asciiGlyphs = glyphs{
+		success: "+ ", warning: "! ", failure: "x ", vertical: "|", horizontal: "-", down: "+", up: "+", ellipsis: '~',
+	}
+	// screenReaderGlyphs name the kind of the message instead of the icons, so it is read out.
+	screenReaderGlyphs = glyphs{
+		success: "Done: ", warning: "Warning: ", failure: "Error: ", vertical: "|", horizontal: "-", down: "+", up: "+",
+		ellipsis: '~',
+	}
+
+	glyph        = unicodeGlyphs
+	screenReader bool
+)
+
+// SetAscii replaces the box drawing characters, the icons and the emoji of the output with ASCII.
+func SetAscii() {
+	if glyph == unicodeGlyphs {
+		glyph = asciiGlyphs
+	}
+	pterm.DefaultBox = *pterm.DefaultBox.
+		WithTopLeftCornerString("+").
+		WithTopRightCornerString("+").
+		WithBottomLeftCornerString("+").
+		WithBottomRightCornerString("+").
+		WithHorizontalString("-").
+		WithVerticalString("|")
+}
+
+// SetScreenReader makes the output friendly to the screen readers: the output is ASCII without colors, the messages
+// start with their kind instead of an icon, and the spinners are replaced with a line per step.
+func SetScreenReader() {
+	glyph = screenReaderGlyphs
+	screenReader = true
+	SetAscii()
+	DisableColor()
+}
+
+// IsScreenReader returns true if the output is friendly to the screen readers.
+func IsScreenReader() bool {
+	return screenReader
+}
+
+// plain removes the emoji from the text unless the output is Unicode.
+func plain(text string) string {
+	if glyph == unicodeGlyphs {
+		return text
+	}
+	text = strings.Map(
+		func(r rune) rune {
+			if unicode.Is(unicode.So, r) || unicode.Is(unicode.Variation_Selector, r) {
+				return -1
+			}
+			return r
+		}, text,
+	)
+	return strings.ReplaceAll(text, "  ", " ")
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package msg
+
+import (
+	"github.com/pterm/pterm"
+	"testing"
+)
+
+func TestAsciiOutput(t *testing.T) {
+	pterm.DisableColor()
+	defer pterm.EnableColor()
+	defer func() { glyph = unicodeGlyphs }()
+	glyph = asciiGlyphs
+
+	if actual := plain("It seems all right 👌 No new problems found"); actual != "It seems all right No new problems found" {
+		t.Errorf("expected the emoji to be removed, got %q", actual)
+	}
+	if actual := plain("新しい問題は見つかりませんでした"); actual != "新しい問題は見つかりませんでした" {
+		t.Errorf("expected the letters to be kept, got %q", actual)
+	}
+	if text, _, _ := fitWidth([]rune("int x = 1; int y = 2;"), 4, 5, 10); string(text) != "int x = 1~" {
+		t.Errorf("expected the cut to be marked with ~, got %q", string(text))
+	}
+	if actual := tableUp(12); actual != "-------+----" {
+		t.Errorf("unexpected table border %q", actual)
+	}
+}
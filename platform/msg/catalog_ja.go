@@ -0,0 +1,58 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package msg
+
+// catalogJa is the Japanese catalog of the messages.
+var catalogJa = map[string]string{
+	// summary
+	"It seems all right 👌 No new problems found according to the checks applied": "問題ありません 👌 適用されたチェックで新しい問題は見つかりませんでした",
+	"Found 1 new problem according to the checks applied":                        "適用されたチェックで新しい問題が 1 件見つかりました",
+	"Found %d new problems according to the checks applied":                      "適用されたチェックで新しい問題が %d 件見つかりました",
+	"The number of problems exceeds the fail threshold":                          "問題の数が失敗しきい値を超えています",
+	"Report is successfully uploaded to %s":                                      "レポートを %s にアップロードしました",
+	"Report is published to %s":                                                  "レポートを %s に公開しました",
+	"Qodana license plan: %s":                                                    "Qodana ライセンスプラン: %s",
+	"Finished %s":                                                                "%s が完了しました",
+	"Stage":                                                                      "ステージ",
+	"Time":                                                                       "時間",
+	"Severity mapping: %d problems are remapped":                                 "重大度マッピング: %d 件の問題の重大度を変更しました",
+	"Scope %s: %d of %d problems are in the scope":                               "スコープ %s: %[3]d 件中 %[2]d 件の問題がスコープ内です",
+	"Quick-fixes are saved to %s":                                                "クイックフィックスを %s に保存しました",
+	"No quick-fixes were applied":                                                "クイックフィックスは適用されませんでした",
+	"Results are encrypted to %s":                                                "結果を %s に暗号化しました",
+	"Summary is written to %s":                                                   "サマリーを %s に書き込みました",
+
+	// warnings
+	"To view the Qodana report later, run %s in the current directory or add %s flag to %s": "後で Qodana レポートを表示するには、現在のディレクトリで %s を実行するか、%[3]s に %[2]s フラグを追加してください",
+	"Running the tool as root is dangerous: please run it as a regular user":                "root としてツールを実行するのは危険です。一般ユーザーとして実行してください",
+	"New version of %s CLI is available: %s. See https://jb.gg/qodana-cli/update\n":         "%s CLI の新しいバージョン %s が利用可能です。https://jb.gg/qodana-cli/update を参照してください\n",
+	"Interrupting Qodana CLI, press Ctrl+C again to exit immediately...":                    "Qodana CLI を中断しています。すぐに終了するには Ctrl+C をもう一度押してください...",
+	"Unable to summarize the problems by owner: %s":                                         "所有者ごとの問題を集計できません: %s",
+	"Unable to estimate the remediation time: %s":                                           "修正時間を見積もれません: %s",
+	"Unable to change permissions in %s: %s":                                                "%s の権限を変更できません: %s",
+	"Unable to open a pull request: %s":                                                     "プルリクエストを作成できません: %s",
+	"Unable to read the inspection timings: %s":                                             "インスペクションの所要時間を読み込めません: %s",
+	"--clear-cache is ignored with --resume, the indexes of the interrupted scan are kept":  "--resume では --clear-cache は無視され、中断されたスキャンのインデックスが保持されます",
+	"Using local-changes script is deprecated, please switch to other mechanisms of incremental analysis. Further information - https://www.jetbrains.com/help/qodana/analyze-pr.html": "local-changes スクリプトは非推奨です。他の増分解析の仕組みに切り替えてください。詳細 - https://www.jetbrains.com/help/qodana/analyze-pr.html",
+
+	// errors
+	"Container engine is not running a Linux platform, other platforms are not supported by Qodana": "コンテナーエンジンが Linux プラットフォームで実行されていません。Qodana は他のプラットフォームをサポートしていません",
+	"Qodana analysis failed: %s":         "Qodana の解析に失敗しました: %s",
+	"Qodana analysis reached timeout %s": "Qodana の解析がタイムアウト %s に達しました",
+	"Qodana exited with code %d":         "Qodana が終了コード %d で終了しました",
+	"Token cannot be empty":              "トークンを空にすることはできません",
+}
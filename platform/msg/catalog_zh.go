@@ -0,0 +1,58 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package msg
+
+// catalogZh is the Simplified Chinese catalog of the messages.
+var catalogZh = map[string]string{
+	// summary
+	"It seems all right 👌 No new problems found according to the checks applied": "一切正常 👌 根据所应用的检查未发现新问题",
+	"Found 1 new problem according to the checks applied":                        "根据所应用的检查发现 1 个新问题",
+	"Found %d new problems according to the checks applied":                      "根据所应用的检查发现 %d 个新问题",
+	"The number of problems exceeds the fail threshold":                          "问题数量超过了失败阈值",
+	"Report is successfully uploaded to %s":                                      "报告已成功上传到 %s",
+	"Report is published to %s":                                                  "报告已发布到 %s",
+	"Qodana license plan: %s":                                                    "Qodana 许可证计划：%s",
+	"Finished %s":                                                                "已完成%s",
+	"Stage":                                                                      "阶段",
+	"Time":                                                                       "时间",
+	"Severity mapping: %d problems are remapped":                                 "严重性映射：已重新映射 %d 个问题",
+	"Scope %s: %d of %d problems are in the scope":                               "范围 %s：%[3]d 个问题中有 %[2]d 个在范围内",
+	"Quick-fixes are saved to %s":                                                "快速修复已保存到 %s",
+	"No quick-fixes were applied":                                                "未应用任何快速修复",
+	"Results are encrypted to %s":                                                "结果已加密到 %s",
+	"Summary is written to %s":                                                   "摘要已写入 %s",
+
+	// warnings
+	"To view the Qodana report later, run %s in the current directory or add %s flag to %s": "如需稍后查看 Qodana 报告，请在当前目录中运行 %s，或为 %[3]s 添加 %[2]s 标志",
+	"Running the tool as root is dangerous: please run it as a regular user":                "以 root 身份运行该工具很危险：请以普通用户身份运行",
+	"New version of %s CLI is available: %s. See https://jb.gg/qodana-cli/update\n":         "%s CLI 有新版本可用：%s。请参阅 https://jb.gg/qodana-cli/update\n",
+	"Interrupting Qodana CLI, press Ctrl+C again to exit immediately...":                    "正在中断 Qodana CLI，再次按 Ctrl+C 立即退出...",
+	"Unable to summarize the problems by owner: %s":                                         "无法按所有者汇总问题：%s",
+	"Unable to estimate the remediation time: %s":                                           "无法估算修复时间：%s",
+	"Unable to change permissions in %s: %s":                                                "无法更改 %s 中的权限：%s",
+	"Unable to open a pull request: %s":                                                     "无法创建拉取请求：%s",
+	"Unable to read the inspection timings: %s":                                             "无法读取检查耗时：%s",
+	"--clear-cache is ignored with --resume, the indexes of the interrupted scan are kept":  "使用 --resume 时将忽略 --clear-cache，保留中断扫描的索引",
+	"Using local-changes script is deprecated, please switch to other mechanisms of incremental analysis. Further information - https://www.jetbrains.com/help/qodana/analyze-pr.html": "local-changes 脚本已弃用，请改用其他增量分析机制。更多信息 - https://www.jetbrains.com/help/qodana/analyze-pr.html",
+
+	// errors
+	"Container engine is not running a Linux platform, other platforms are not supported by Qodana": "容器引擎未在 Linux 平台上运行，Qodana 不支持其他平台",
+	"Qodana analysis failed: %s":         "Qodana 分析失败：%s",
+	"Qodana analysis reached timeout %s": "Qodana 分析达到超时时间 %s",
+	"Qodana exited with code %d":         "Qodana 已退出，退出代码为 %d",
+	"Token cannot be empty":              "令牌不能为空",
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package msg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultLanguage is the language of the messages in the code, used when no catalog is selected.
+const DefaultLanguage = "en"
+
+// catalogs translate the formats of the messages, keyed by the English format, by the language.
+// The translations keep the verbs of the format, reordered with the explicit argument indexes if needed, e.g. %[2]s.
+var catalogs = map[string]map[string]string{
+	"ja": catalogJa,
+	"zh": catalogZh,
+}
+
+var catalog map[string]string
+
+// Languages returns the supported languages.
+func Languages() []string {
+	languages := []string{DefaultLanguage}
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages[1:])
+	return languages
+}
+
+// normalizeLanguage returns the language of the locale, e.g. ja for ja_JP.UTF-8 and zh for zh-Hans.
+func normalizeLanguage(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "_-.@"); i >= 0 {
+		locale = locale[:i]
+	}
+	return locale
+}
+
+// SetLanguage selects the catalog of the messages by the language or the locale, empty or en for English.
+func SetLanguage(locale string) error {
+	language := normalizeLanguage(locale)
+	if language == "" || language == DefaultLanguage || language == "c" || language == "posix" {
+		catalog = nil
+		return nil
+	}
+	c, ok := catalogs[language]
+	if !ok {
+		catalog = nil
+		return fmt.Errorf("unsupported language %q, available languages: %s", locale, strings.Join(Languages(), ", "))
+	}
+	catalog = c
+	return nil
+}
+
+// T returns the translation of the format of the message to the selected language, the format itself if there is none.
+func T(format string) string {
+	if translated, ok := catalog[format]; ok {
+		return translated
+	}
+	return format
+}
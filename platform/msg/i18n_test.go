@@ -0,0 +1,76 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package msg
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSetLanguage(t *testing.T) {
+	defer func() { _ = SetLanguage("") }()
+	for locale, expected := range map[string]string{
+		"ja_JP.UTF-8": "適用されたチェックで新しい問題が 3 件見つかりました",
+		"zh-CN":       "根据所应用的检查发现 3 个新问题",
+		"C":           "Found 3 new problems according to the checks applied",
+		"":            "Found 3 new problems according to the checks applied",
+	} {
+		if err := SetLanguage(locale); err != nil {
+			t.Fatalf("SetLanguage(%q): %v", locale, err)
+		}
+		if actual := GetProblemsFoundMessage(3); actual != expected {
+			t.Errorf("%q: expected %q, got %q", locale, expected, actual)
+		}
+	}
+	if err := SetLanguage("xx"); err == nil || T("Stage") != "Stage" {
+		t.Error("expected an unsupported language to fall back to English with an error")
+	}
+}
+
+// verb matches the formatting verbs with the optional explicit argument index.
+var verb = regexp.MustCompile(`%(?:\[(\d+)])?[-+# 0]*\d*(?:\.\d+)?([a-zA-Z%])`)
+
+// verbs returns the verbs of the format by the argument they format.
+func verbs(format string) []string {
+	var result []string
+	next := 1
+	for _, match := range verb.FindAllStringSubmatch(format, -1) {
+		if match[2] == "%" {
+			continue
+		}
+		if match[1] != "" {
+			_, _ = fmt.Sscan(match[1], &next)
+		}
+		result = append(result, fmt.Sprintf("%d:%s", next, match[2]))
+		next++
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestCatalogsKeepVerbs(t *testing.T) {
+	for language, c := range catalogs {
+		for format, translated := range c {
+			if strings.Join(verbs(format), ",") != strings.Join(verbs(translated), ",") {
+				t.Errorf("%s: the translation of %q formats other arguments: %q", language, format, translated)
+			}
+		}
+	}
+}
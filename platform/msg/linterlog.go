@@ -0,0 +1,80 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package msg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// linterLogLevel matches the level of a line of the IDE log, e.g. "2024-05-01 10:00:00,000 [ 1234]   WARN - #c.i.o ...".
+var linterLogLevel = regexp.MustCompile(`(?:^|[\s\[])(SEVERE|FATAL|ERROR|WARN|WARNING|INFO|DEBUG|TRACE)(?:[\s\]:]|$)`)
+
+// LinterLogLevel returns the severity of the line of the linter log, log.InfoLevel if the line has no level.
+func LinterLogLevel(line string) log.Level {
+	match := linterLogLevel.FindStringSubmatch(line)
+	if match == nil {
+		return log.InfoLevel
+	}
+	switch match[1] {
+	case "SEVERE", "FATAL", "ERROR":
+		return log.ErrorLevel
+	case "WARN", "WARNING":
+		return log.WarnLevel
+	case "DEBUG":
+		return log.DebugLevel
+	case "TRACE":
+		return log.TraceLevel
+	default:
+		return log.InfoLevel
+	}
+}
+
+// PrintLinterLogColored prints the line of the linter log colored by its severity.
+func PrintLinterLogColored(line string) {
+	switch LinterLogLevel(line) {
+	case log.ErrorLevel:
+		errorStyle.Println(line)
+	case log.WarnLevel:
+		warningStyle.Println(line)
+	default:
+		PrintLinterLog(line)
+	}
+}
+
+// PrintStageMarker prints the marker of the analysis stage started, so the stages are easy to find in the log.
+func PrintStageMarker(stage string) {
+	marker := strings.Repeat(glyph.horizontal, 2) + " " + strings.ToUpper(stage) + " "
+	width := getTerminalWidth()
+	primaryBoldStyle.Println(marker + strings.Repeat(glyph.horizontal, max(width-len([]rune(marker)), 0)))
+}
+
+// PrintIdeaLog prints the line of the IDE log file, followed during the run, colored by its severity.
+func PrintIdeaLog(line string) {
+	prefix := miscStyle.Sprint("idea.log " + glyph.vertical + " ")
+	switch LinterLogLevel(line) {
+	case log.ErrorLevel:
+		fmt.Println(prefix + errorStyle.Sprint(line))
+	case log.WarnLevel:
+		fmt.Println(prefix + warningStyle.Sprint(line))
+	default:
+		fmt.Println(prefix + miscStyle.Sprint(line))
+	}
+}
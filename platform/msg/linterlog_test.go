@@ -0,0 +1,42 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package msg
+
+import (
+	log "github.com/sirupsen/logrus"
+	"testing"
+)
+
+func TestLinterLogLevel(t *testing.T) {
+	for _, tc := range []struct {
+		line     string
+		expected log.Level
+	}{
+		{"2024-05-01 10:00:00,000 [   1234]  ERROR - #c.i.o.p.ProjectManager - failed", log.ErrorLevel},
+		{"2024-05-01 10:00:00,000 [   1234]   WARN - #c.i.o.p.ProjectManager - slow", log.WarnLevel},
+		{"2024-05-01 10:00:00,000 [   1234]   INFO - #qodana - ERROR in the message", log.InfoLevel},
+		{"2024-05-01 10:00:00,000 [   1234]  DEBUG - #qodana - details", log.DebugLevel},
+		{"[SEVERE] plugin failed to load", log.ErrorLevel},
+		{"WARNING: An illegal reflective access operation has occurred", log.WarnLevel},
+		{"The Project opening stage completed in 1234 ms", log.InfoLevel},
+		{"ERRORS: none", log.InfoLevel},
+	} {
+		if actual := LinterLogLevel(tc.line); actual != tc.expected {
+			t.Errorf("LinterLogLevel(%q) = %s, expected %s", tc.line, actual, tc.expected)
+		}
+	}
+}
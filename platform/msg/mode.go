@@ -0,0 +1,69 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package msg
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pterm/pterm"
+)
+
+// OutputMode is how much of the decorative output the CLI prints.
+type OutputMode int
+
+const (
+	// OutputNormal prints the banners, the spinners and the success messages.
+	OutputNormal OutputMode = iota
+	// OutputQuiet prints only the warnings, the errors and the output requested, e.g. the problems with --print-problems.
+	OutputQuiet
+	// OutputPorcelain is OutputQuiet printing the rest to the standard error: the standard output gets only
+	// the lines of PorcelainLine.
+	OutputPorcelain
+)
+
+var outputMode = OutputNormal
+
+// SetOutputMode sets the output mode of the messages.
+func SetOutputMode(mode OutputMode) {
+	outputMode = mode
+	if mode == OutputPorcelain {
+		pterm.SetDefaultOutput(os.Stderr)
+		DisableColor()
+	}
+}
+
+// IsQuiet returns true if the banners, the spinners and the success messages are suppressed.
+func IsQuiet() bool {
+	return outputMode != OutputNormal
+}
+
+// IsPorcelain returns true if the standard output is reserved for the machine-parseable lines.
+func IsPorcelain() bool {
+	return outputMode == OutputPorcelain
+}
+
+// PorcelainLine prints the line "key<TAB>value" to the standard output in the porcelain mode. The keys and the format
+// of their values are kept stable between the versions, new keys may be added.
+func PorcelainLine(key string, value any) {
+	if !IsPorcelain() {
+		return
+	}
+	text := strings.NewReplacer("\n", " ", "\t", " ").Replace(fmt.Sprint(value))
+	_, _ = fmt.Fprintf(os.Stdout, "%s\t%s\n", key, text)
+}
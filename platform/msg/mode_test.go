@@ -0,0 +1,51 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package msg
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+func TestPorcelainLine(t *testing.T) {
+	stdout := os.Stdout
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = writer
+	defer func() {
+		os.Stdout = stdout
+		outputMode = OutputNormal
+	}()
+
+	PorcelainLine("ignored", "not porcelain")
+	outputMode = OutputPorcelain
+	PorcelainLine("new-problems", 3)
+	PorcelainLine("status", "fail\tthreshold\n")
+	_ = writer.Close()
+	output, _ := io.ReadAll(reader)
+
+	expected := "new-problems\t3\nstatus\tfail threshold \n"
+	if string(output) != expected {
+		t.Errorf("expected %q, got %q", expected, string(output))
+	}
+	if !IsQuiet() || !IsPorcelain() {
+		t.Error("expected the porcelain output to be quiet")
+	}
+}
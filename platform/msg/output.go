@@ -74,15 +74,25 @@ var (
 	errorStyle        = pterm.NewStyle(pterm.FgRed)    // errorStyle is an error style.
 	warningStyle      = pterm.NewStyle(pterm.FgYellow) // warningStyle is a warning style.
 	miscStyle         = pterm.NewStyle(pterm.FgGray)   // miscStyle is a log style.
-	tableSep          = miscStyle.Sprint("─")
-	tableSepUp        = miscStyle.Sprint("┬")
-	tableSepMid       = miscStyle.Sprint("│")
-	tableSepDown      = miscStyle.Sprint("┴")
-	tableUp           = strings.Repeat(tableSep, noLineWidth) + tableSepUp
-	tableDown         = strings.Repeat(tableSep, noLineWidth) + tableSepDown
 	DefaultPromptText = "Do you want to continue?"
 )
 
+// the table separators are styled on use, so they are not colored once the colors are disabled
+func tableSepMid() string { return miscStyle.Sprint(glyph.vertical) }
+func tableSepLine(width int) string {
+	return miscStyle.Sprint(strings.Repeat(glyph.horizontal, max(width, 0)))
+}
+func tableUp(width int) string {
+	return miscStyle.Sprint(
+		strings.Repeat(glyph.horizontal, noLineWidth) + glyph.down + strings.Repeat(glyph.horizontal, max(width-noLineWidth-1, 0)),
+	)
+}
+func tableDown(width int) string {
+	return miscStyle.Sprint(
+		strings.Repeat(glyph.horizontal, noLineWidth) + glyph.up + strings.Repeat(glyph.horizontal, max(width-noLineWidth-1, 0)),
+	)
+}
+
 // Primary prints a message in the Primary style.
 func Primary(text string, a ...interface{}) string {
 	text = fmt.Sprintf(text, a...)
@@ -97,33 +107,39 @@ func PrimaryBold(text string, a ...interface{}) string {
 
 // EmptyMessage is a message that is used when there is no message to show.
 func EmptyMessage() {
+	if IsQuiet() {
+		return
+	}
 	pterm.Println()
 }
 
-// SuccessMessage prints a success message with the icon.
+// SuccessMessage prints a success message with the icon, unless the output is quiet.
 func SuccessMessage(message string, a ...interface{}) {
-	message = fmt.Sprintf(message, a...)
-	icon := pterm.Green("✓ ")
+	if IsQuiet() {
+		return
+	}
+	message = plain(fmt.Sprintf(T(message), a...))
+	icon := pterm.Green(glyph.success)
 	pterm.Println(icon, Primary(message))
 }
 
 // WarningMessage prints a warning message with the icon.
 func WarningMessage(message string, a ...interface{}) {
-	message = fmt.Sprintf(message, a...)
-	icon := warningStyle.Sprint("\n! ")
+	message = plain(fmt.Sprintf(T(message), a...))
+	icon := warningStyle.Sprint("\n" + glyph.warning)
 	pterm.Println(icon, Primary(message))
 }
 
 // WarningMessageCI prints a warning message to the CI environment (additional highlighting).
 func WarningMessageCI(message string, a ...interface{}) {
-	message = fmt.Sprintf(message, a...)
+	message = fmt.Sprintf(T(message), a...)
 	pterm.Println(formatMessageForCI("warning", message))
 }
 
 // ErrorMessage prints an error message with the icon.
 func ErrorMessage(message string, a ...interface{}) {
-	message = fmt.Sprintf(message, a...)
-	icon := errorStyle.Sprint("✗ ")
+	message = plain(fmt.Sprintf(T(message), a...))
+	icon := errorStyle.Sprint(glyph.failure)
 	pterm.Println(icon, errorStyle.Sprint(message))
 }
 
@@ -133,6 +149,9 @@ func PrintLinterLog(line string) {
 		strings.Contains(line, "_              _") ||
 		strings.Contains(line, "\\/__") ||
 		strings.Contains(line, "\\ \\") {
+		if IsQuiet() {
+			return
+		}
 		PrimaryStyle.Println(line)
 	} else {
 		miscStyle.Println(line)
@@ -152,8 +171,8 @@ func PrintProcess(f func(spinner *pterm.SpinnerPrinter), start string, finished
 // spin creates spinner and runs the given function. Also, spin is a spider in Dutch.
 func spin(fun func(spinner *pterm.SpinnerPrinter), message string) error {
 	spinner, _ := StartQodanaSpinner(message)
-	if spinner == nil {
-		fmt.Println(Primary(message + "..."))
+	if spinner == nil && !IsQuiet() {
+		pterm.Println(Primary(message + "..."))
 	}
 	fun(spinner)
 	if spinner != nil {
@@ -164,7 +183,7 @@ func spin(fun func(spinner *pterm.SpinnerPrinter), message string) error {
 
 // StartQodanaSpinner starts a new spinner with the given message.
 func StartQodanaSpinner(message string) (*pterm.SpinnerPrinter, error) {
-	if IsInteractive() {
+	if IsInteractive() && !IsQuiet() && !IsScreenReader() {
 		QodanaSpinner.Sequence = spinnerSequence
 		QodanaSpinner.MessageStyle = PrimaryStyle
 		return QodanaSpinner.WithStyle(pterm.NewStyle(pterm.FgGray)).WithRemoveWhenDone(true).Start(message + "...")
@@ -172,10 +191,12 @@ func StartQodanaSpinner(message string) (*pterm.SpinnerPrinter, error) {
 	return nil, nil
 }
 
-// UpdateText updates the text of the spinner.
+// UpdateText updates the text of the spinner, with the screen readers the text is printed as the next step instead.
 func UpdateText(spinner *pterm.SpinnerPrinter, message string) {
 	if spinner != nil {
 		spinner.UpdateText(message + "...")
+	} else if IsScreenReader() && !IsQuiet() {
+		pterm.Println(message + "...")
 	}
 }
 
@@ -186,23 +207,28 @@ func PrintFile(file string) {
 	if err != nil {
 		log.Fatalf("failed to read file %s: %s", file, err)
 	}
-	printLines(string(content), 1, 0, true)
+	printLines(string(content), 1)
 }
 
 // PrintProblem printProblem prints problem with source code or without it.
+// The source code starts at contextLine, the range of the problem in it is highlighted.
 func PrintProblem(
 	ruleId string,
 	level string,
 	message string,
 	path string,
-	line int,
-	column int,
+	problem ProblemRange,
 	contextLine int,
 	context string,
 ) {
 	printHeader(level, ruleId, "")
-	printPath(path, line, column)
-	printLines(context, contextLine, line, false)
+	printPath(path, problem.StartLine, problem.StartColumn)
+	if context != "" {
+		for _, line := range renderSnippet(context, contextLine, problem, getTerminalWidth()) {
+			fmt.Println(line)
+		}
+		fmt.Println(tableDown(getTerminalWidth()))
+	}
 	fmt.Print(message + "\n")
 }
 
@@ -219,10 +245,10 @@ func getTerminalWidth() int {
 func printHeader(level string, ruleId string, file string) {
 	width := getTerminalWidth()
 	fmt.Printf("%s %s\n", PrimaryBold(strings.ToUpper(level)), Primary(ruleId))
-	fmt.Println(strings.Repeat(tableSep, width))
+	fmt.Println(tableSepLine(width))
 	if file != "" {
-		fmt.Printf("%5s  %s %s\n", "", tableSepMid, PrimaryBold(file))
-		fmt.Println(strings.Repeat(tableSep, width))
+		fmt.Printf("%5s  %s %s\n", "", tableSepMid(), PrimaryBold(file))
+		fmt.Println(tableSepLine(width))
 	}
 }
 
@@ -230,43 +256,30 @@ func printHeader(level string, ruleId string, file string) {
 func printPath(path string, line int, column int) {
 	if path != "" && line > 0 && column > 0 {
 		fmt.Printf(" %s:%d:%d\n", path, line, column)
-		fmt.Printf("%s%s\n", tableUp, strings.Repeat(tableSep, getTerminalWidth()-noLineWidth-1))
+		fmt.Println(tableUp(getTerminalWidth()))
 	} else {
-		fmt.Println(strings.Repeat(tableSep, getTerminalWidth()))
+		fmt.Println(tableSepLine(getTerminalWidth()))
 	}
 }
 
-// printLines prints the lines of the problem.
-func printLines(content string, contextLine int, line int, skipHighlight bool) {
-	lines := strings.Split(content, "\n")
-	lineCount := len(lines)
-	if content[len(content)-1] == '\n' {
-		lineCount -= 1 // Remove the last empty line if content ends with a newline
-	}
-	for i := 0; i < lineCount; i++ {
-		var printLine string
-		currentLine := contextLine + i
-		if skipHighlight {
-			printLine = lines[i]
-		} else if currentLine == line {
-			printLine = errorStyle.Sprint(lines[i]) + " ←"
-		} else {
-			printLine = warningStyle.Sprint(lines[i])
-		}
-		lineNumber := miscStyle.Sprintf("%5d", currentLine)
-		fmt.Printf("%s  %s %s\n", lineNumber, tableSepMid, printLine)
+// printLines prints the lines of the file.
+func printLines(content string, contextLine int) {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	for i, line := range lines {
+		lineNumber := miscStyle.Sprintf("%5d", contextLine+i)
+		fmt.Printf("%s  %s %s\n", lineNumber, tableSepMid(), line)
 	}
-	fmt.Printf("%s%s\n", tableDown, strings.Repeat(tableSep, getTerminalWidth()-noLineWidth-1))
+	fmt.Println(tableDown(getTerminalWidth()))
 }
 
 // GetProblemsFoundMessage returns a message about the number of problems found, used in CLI and BitBucket report.
 func GetProblemsFoundMessage(newProblems int) string {
 	if newProblems == 0 {
-		return "It seems all right 👌 No new problems found according to the checks applied"
+		return T("It seems all right 👌 No new problems found according to the checks applied")
 	} else if newProblems == 1 {
-		return fmt.Sprintf("Found 1 new problem according to the checks applied")
+		return T("Found 1 new problem according to the checks applied")
 	} else {
-		return fmt.Sprintf("Found %d new problems according to the checks applied", newProblems)
+		return fmt.Sprintf(T("Found %d new problems according to the checks applied"), newProblems)
 	}
 }
 
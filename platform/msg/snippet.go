@@ -0,0 +1,137 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package msg
+
+import (
+	"github.com/pterm/pterm"
+	"strings"
+)
+
+const tabWidth = 4
+
+var (
+	// snippetGutterWidth is the width of the line numbers column of the printed code: "  123  │ "
+	snippetGutterWidth = noLineWidth + 2
+	highlightStyle     = pterm.NewStyle(pterm.FgRed, pterm.Bold, pterm.Underscore)
+)
+
+// ProblemRange is the range of the source code of a problem, the lines and the columns are 1-based and EndColumn is exclusive.
+// The range ends with its start line if EndLine is not set, and with the end of EndLine if EndColumn is not set.
+type ProblemRange struct {
+	StartLine   int
+	StartColumn int
+	EndLine     int
+	EndColumn   int
+}
+
+// columns returns the columns of the line within the range, ok is false if the line is out of it.
+// to is zero if the range continues to the end of the line.
+func (r ProblemRange) columns(line int) (from int, to int, ok bool) {
+	endLine := max(r.EndLine, r.StartLine)
+	if r.StartLine <= 0 || line < r.StartLine || line > endLine {
+		return 0, 0, false
+	}
+	from = 1
+	if line == r.StartLine {
+		from = max(r.StartColumn, 1)
+	}
+	if line == endLine && r.EndColumn > 0 {
+		to = r.EndColumn
+	}
+	return from, to, true
+}
+
+// renderSnippet renders the lines of the code starting at firstLine in the width of the terminal: the lines of the problem
+// are marked and its range is highlighted, or underlined with carets if the colors are disabled.
+func renderSnippet(content string, firstLine int, r ProblemRange, width int) []string {
+	textWidth := max(width-snippetGutterWidth, 10)
+	var rendered []string
+	for i, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		number := firstLine + i
+		from, to, marked := r.columns(number)
+		text, start, end := expandTabs(strings.TrimSuffix(line, "\r"), from, to)
+		if !marked {
+			start, end = 0, 0
+		}
+		text, start, end = fitWidth(text, start, end, textWidth)
+		if !marked {
+			rendered = append(rendered, miscStyle.Sprintf("%5d", number)+"  "+tableSepMid()+" "+miscStyle.Sprint(string(text)))
+			continue
+		}
+		gutter := errorStyle.Sprint(">") + miscStyle.Sprintf("%4d", number) + "  " + tableSepMid() + " "
+		rendered = append(
+			rendered,
+			gutter+string(text[:start])+highlightStyle.Sprint(string(text[start:end]))+string(text[end:]),
+		)
+		if !pterm.PrintColor && end > start {
+			rendered = append(
+				rendered,
+				strings.Repeat(" ", noLineWidth)+tableSepMid()+" "+strings.Repeat(" ", start)+strings.Repeat("^", end-start),
+			)
+		}
+	}
+	return rendered
+}
+
+// expandTabs replaces the tabs of the line with spaces and returns the range of the 1-based columns [from, to)
+// as the indexes of the returned runes, to of zero is the end of the line.
+func expandTabs(line string, from int, to int) ([]rune, int, int) {
+	var text []rune
+	start, end := -1, -1
+	column := 1
+	for _, c := range line {
+		if column == from {
+			start = len(text)
+		}
+		if column == to {
+			end = len(text)
+		}
+		if c == '\t' {
+			text = append(text, []rune(strings.Repeat(" ", tabWidth-len(text)%tabWidth))...)
+		} else {
+			text = append(text, c)
+		}
+		column++
+	}
+	if start < 0 {
+		start = len(text)
+	}
+	if end < 0 || to <= 0 {
+		end = len(text)
+	}
+	return text, start, max(start, end)
+}
+
+// fitWidth cuts the line to the width keeping the start of the range [start, end) visible, the cuts are marked with the ellipsis.
+func fitWidth(text []rune, start int, end int, width int) ([]rune, int, int) {
+	if len(text) <= width {
+		return text, start, end
+	}
+	offset := 0
+	if start > width/2 {
+		offset = min(start-width/3, len(text)-width+1)
+	}
+	if offset > 0 {
+		text = append([]rune{glyph.ellipsis}, text[offset+1:]...)
+		start, end = max(start-offset, 1), max(end-offset, 1)
+	}
+	if len(text) > width {
+		text = append(text[:width-1], glyph.ellipsis)
+		start, end = min(start, width-1), min(end, width-1)
+	}
+	return text, start, end
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package msg
+
+import (
+	"github.com/pterm/pterm"
+	"strings"
+	"testing"
+)
+
+func TestRenderSnippet(t *testing.T) {
+	pterm.DisableColor()
+	defer pterm.EnableColor()
+	code := "class A {\n\tint x = 1;\n}\n"
+	lines := renderSnippet(code, 10, ProblemRange{StartLine: 11, StartColumn: 6, EndColumn: 7}, 80)
+	expected := []string{
+		"   10  │ class A {",
+		">  11  │     int x = 1;",
+		"       │         ^",
+		"   12  │ }",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+
+	lines = renderSnippet(code, 10, ProblemRange{StartLine: 10, StartColumn: 7, EndLine: 11, EndColumn: 5}, 80)
+	if lines[1] != "       │       ^^^" {
+		t.Errorf("unexpected carets of the first line %q", lines[1])
+	}
+	if lines[3] != "       │ ^^^^^^^" {
+		t.Errorf("unexpected carets of the second line %q", lines[3])
+	}
+}
+
+func TestRenderLongLine(t *testing.T) {
+	pterm.DisableColor()
+	defer pterm.EnableColor()
+	code := strings.Repeat("a", 100) + "problem" + strings.Repeat("b", 100)
+	lines := renderSnippet(code, 1, ProblemRange{StartLine: 1, StartColumn: 101, EndColumn: 108}, 49)
+	if len([]rune(lines[0])) != 49 || !strings.Contains(lines[0], "…") || !strings.Contains(lines[0], "problem") {
+		t.Errorf("unexpected line %q", lines[0])
+	}
+	line, carets := []rune(lines[0]), []rune(lines[1])
+	caret := strings.IndexRune(string(carets), '^')
+	caret = len([]rune(string(carets)[:caret]))
+	if string(line[caret:caret+len("problem")]) != "problem" {
+		t.Errorf("expected the carets under the problem, got\n%s\n%s", lines[0], lines[1])
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"context"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/git"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"strings"
+)
+
+// newCodeProperty is the result property telling whether the problem is in the new code, the quality gates skip
+// the problems where it's false.
+const newCodeProperty = "qodanaNewCode"
+
+// ClassifyNewCode marks the problems of the SARIF report as in the new or in the old code, the code changed
+// since since, a date or a commit, according to git blame. It returns the number of the problems in the new code.
+// The problems without a line, e.g. the project-level ones, are left unmarked and are checked by the gates.
+// Blaming stops when ctx is done.
+func ClassifyNewCode(ctx context.Context, sarifPath string, projectDir string, since string, logDir string) (int, error) {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the SARIF report: %w", err)
+	}
+	cutoff, err := git.BlameCutoff(ctx, projectDir, since, logDir)
+	if err != nil {
+		return 0, err
+	}
+	type blame struct {
+		lines   map[int]bool
+		tracked bool
+	}
+	blames := map[string]blame{}
+	newCode, oldCode := 0, 0
+	for i := range report.Runs {
+		for j := range report.Runs[i].Results {
+			r := &report.Runs[i].Results[j]
+			path, line, _ := problemLocation(r)
+			if path == "" || line == 0 {
+				continue
+			}
+			path = strings.TrimPrefix(path, "file://")
+			b, ok := blames[path]
+			if !ok {
+				b.lines, b.tracked, err = git.NewLines(ctx, projectDir, path, cutoff, logDir)
+				if err != nil {
+					return 0, fmt.Errorf("failed to blame %s: %w", path, err)
+				}
+				blames[path] = b
+			}
+			isNew := !b.tracked || b.lines[line]
+			if r.Properties == nil {
+				r.Properties = &sarif.PropertyBag{}
+			}
+			if r.Properties.AdditionalProperties == nil {
+				r.Properties.AdditionalProperties = map[string]interface{}{}
+			}
+			r.Properties.AdditionalProperties[newCodeProperty] = isNew
+			if isNew {
+				newCode++
+			} else {
+				oldCode++
+			}
+		}
+	}
+	msg.SuccessMessage("New code since %s: %d problems in the new code, %d in the old code", since, newCode, oldCode)
+	return newCode, WriteReport(sarifPath, report)
+}
+
+// inOldCode returns true if the problem is marked by ClassifyNewCode as in the old code.
+func inOldCode(r *sarif.Result) bool {
+	if r.Properties == nil || r.Properties.AdditionalProperties == nil {
+		return false
+	}
+	isNew, ok := r.Properties.AdditionalProperties[newCodeProperty].(bool)
+	return ok && !isNew
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"context"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassifyNewCode(t *testing.T) {
+	repo := t.TempDir()
+	gitCommand := func(env []string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(), env...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s: %s", args, err, out)
+		}
+	}
+	writeSource := func(name string, content string) {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gitCommand(nil, "init", "-q")
+	gitCommand(nil, "config", "user.email", "test@example.com")
+	gitCommand(nil, "config", "user.name", "test")
+	writeSource("Legacy.java", "class Legacy {\n  int a;\n}\n")
+	gitCommand(nil, "add", "-A")
+	legacyDate := "2020-01-01T00:00:00Z"
+	gitCommand([]string{"GIT_AUTHOR_DATE=" + legacyDate, "GIT_COMMITTER_DATE=" + legacyDate}, "commit", "-q", "-m", "legacy")
+	writeSource("Legacy.java", "class Legacy {\n  int a;\n  int b;\n}\n")
+	writeSource("New.java", "class New {}\n")
+
+	result := func(path string, line int64) sarif.Result {
+		return sarif.Result{
+			RuleId:  "Rule",
+			Message: &sarif.Message{Text: "problem"},
+			Properties: &sarif.PropertyBag{
+				AdditionalProperties: map[string]interface{}{"qodanaSeverity": qodanaHigh},
+			},
+			Locations: []sarif.Location{
+				{
+					PhysicalLocation: &sarif.PhysicalLocation{
+						ArtifactLocation: &sarif.ArtifactLocation{Uri: path},
+						Region:           &sarif.Region{StartLine: line},
+					},
+				},
+			},
+		}
+	}
+	report := &sarif.Report{
+		Version: "2.1.0",
+		Runs: []sarif.Run{
+			{
+				Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "QDTEST"}},
+				Results: []sarif.Result{
+					result("Legacy.java", 2),
+					result("Legacy.java", 3),
+					result("New.java", 1),
+					{RuleId: "ProjectLevel", Message: &sarif.Message{Text: "no location"}},
+				},
+			},
+		},
+	}
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	if err := WriteReport(sarifPath, report); err != nil {
+		t.Fatal(err)
+	}
+
+	newCode, err := ClassifyNewCode(context.Background(), sarifPath, repo, "2021-01-01", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newCode != 2 {
+		t.Errorf("expected the problems of the changed line and of the untracked file to be new, got %d", newCode)
+	}
+	classified, err := ReadReport(sarifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []bool{true, false, false, false} {
+		if inOldCode(&classified.Runs[0].Results[i]) != expected {
+			t.Errorf("result %d: expected in the old code to be %v", i, expected)
+		}
+	}
+	exceeded, err := ThresholdsExceeded(sarifPath, map[string]int{"any": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exceeded {
+		t.Error("expected the problem in the old code to be skipped by the gates")
+	}
+}
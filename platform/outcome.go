@@ -0,0 +1,181 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const outcomeFileName = "outcome.json"
+
+// Outcome is the summary of the run written to outcome.json in the results directory.
+type Outcome struct {
+	ExitCode   int             `json:"exitCode"`
+	Cancelled  bool            `json:"cancelled,omitempty"`
+	TimedOut   string          `json:"timedOut,omitempty"`
+	DurationMs int64           `json:"durationMs"`
+	Stages     []qdtrace.Stage `json:"stages"`
+	Owners     []OwnerProblems `json:"owners,omitempty"`
+	Debt       *Debt           `json:"debt,omitempty"`
+}
+
+// Reason describes how the run finished.
+func (o Outcome) Reason() string {
+	switch {
+	case o.Cancelled:
+		return "cancelled"
+	case o.TimedOut != "":
+		return fmt.Sprintf("%s timeout", o.TimedOut)
+	case o.ExitCode == utils.QodanaSuccessExitCode:
+		return "passed"
+	case o.ExitCode == utils.QodanaFailThresholdExitCode:
+		return "fail threshold"
+	case o.ExitCode == utils.QodanaOutOfMemoryExitCode:
+		return "out of memory"
+	case o.ExitCode == utils.QodanaEapLicenseExpiredExitCode:
+		return "license expired"
+	default:
+		return fmt.Sprintf("failed (exit code %d)", o.ExitCode)
+	}
+}
+
+// FinishRun prints the stage timings of the run, writes them to outcome.json and exports the recorded traces.
+func FinishRun(resultsDir string, exitCode int) {
+	outcome := Outcome{
+		ExitCode:   exitCode,
+		DurationMs: qdtrace.Elapsed().Milliseconds(),
+		Stages:     qdtrace.Stages(),
+		Owners:     ownersSummary,
+		Debt:       debtEstimate,
+	}
+	if outcome.Stages == nil {
+		outcome.Stages = []qdtrace.Stage{}
+	}
+	if timeoutErr, ok := qdtrace.TimedOut(); ok {
+		outcome.TimedOut = timeoutErr.Stage
+	}
+	if !qdenv.IsContainer() && !msg.IsQuiet() {
+		printStageTimings(outcome)
+	}
+	if err := writeOutcome(resultsDir, outcome); err != nil {
+		log.Warnf("Failed to write %s: %v", outcomeFileName, err)
+	}
+	writeResultsManifest(resultsDir)
+	finishAudit(resultsDir, outcome)
+	printPorcelainOutcome(resultsDir, outcome)
+	qdtrace.Shutdown()
+}
+
+// CancelRun writes outcome.json of a run interrupted before it finished, with the stages completed so far.
+func CancelRun(resultsDir string) {
+	outcome := Outcome{
+		ExitCode:   utils.QodanaCancelledExitCode,
+		Cancelled:  true,
+		DurationMs: qdtrace.Elapsed().Milliseconds(),
+		Stages:     qdtrace.Stages(),
+	}
+	if outcome.Stages == nil {
+		outcome.Stages = []qdtrace.Stage{}
+	}
+	if err := writeOutcome(resultsDir, outcome); err != nil {
+		log.Warnf("Failed to write %s: %v", outcomeFileName, err)
+	}
+	writeResultsManifest(resultsDir)
+	finishAudit(resultsDir, outcome)
+	qdtrace.Shutdown()
+}
+
+// ClearOutcome removes outcome.json of a previous run, so the one read by ReadOutcome is written by the current run.
+func ClearOutcome(resultsDir string) {
+	if err := os.Remove(filepath.Join(resultsDir, outcomeFileName)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Failed to remove %s: %v", outcomeFileName, err)
+	}
+}
+
+// ReadOutcome reads outcome.json from the results directory, e.g. the one written by the CLI in the linter container.
+func ReadOutcome(resultsDir string) (Outcome, error) {
+	var outcome Outcome
+	data, err := os.ReadFile(filepath.Join(resultsDir, outcomeFileName))
+	if err != nil {
+		return outcome, err
+	}
+	err = json.Unmarshal(data, &outcome)
+	return outcome, err
+}
+
+func writeOutcome(resultsDir string, outcome Outcome) error {
+	data, err := json.MarshalIndent(outcome, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(resultsDir, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(resultsDir, outcomeFileName), data, 0o644)
+}
+
+func printStageTimings(outcome Outcome) {
+	if len(outcome.Stages) == 0 {
+		return
+	}
+	tableData := pterm.TableData{
+		[]string{msg.PrimaryBold(msg.T("Stage")), msg.PrimaryBold(msg.T("Time"))},
+	}
+	for _, stage := range outcome.Stages {
+		name := stage.Name
+		if stage.Parent != "" {
+			name = "  " + name
+		}
+		if stage.TimedOut {
+			name += " (timed out)"
+		} else if stage.Failed {
+			name += " (failed)"
+		}
+		tableData = append(tableData, []string{name, formatStageDuration(stage.Duration)})
+	}
+	tableData = append(
+		tableData,
+		[]string{msg.PrimaryBold("Total"), formatStageDuration(time.Duration(outcome.DurationMs) * time.Millisecond)},
+	)
+
+	msg.EmptyMessage()
+	table := pterm.DefaultTable.WithData(tableData)
+	table.HeaderRowSeparator = ""
+	table.Separator = " "
+	table.Boxed = true
+	if err := table.Render(); err != nil {
+		log.Debugf("Failed to print stage timings: %v", err)
+	}
+}
+
+// formatStageDuration formats the duration rounded to a tenth of a second, e.g. "1m2.3s".
+func formatStageDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
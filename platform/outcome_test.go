@@ -0,0 +1,125 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"encoding/json"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFinishRun(t *testing.T) {
+	resultsDir := t.TempDir()
+	qdtrace.Init("qodana scan")
+	qdtrace.Start("preparation").End(nil)
+	run := qdtrace.Start("container run")
+	run.StartChild("analysis").End(nil)
+	run.End(nil)
+	FinishRun(resultsDir, 255)
+
+	data, err := os.ReadFile(filepath.Join(resultsDir, outcomeFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outcome struct {
+		ExitCode int `json:"exitCode"`
+		Stages   []struct {
+			Name   string `json:"name"`
+			Parent string `json:"parent"`
+		} `json:"stages"`
+	}
+	if err = json.Unmarshal(data, &outcome); err != nil {
+		t.Fatal(err)
+	}
+	if outcome.ExitCode != 255 || len(outcome.Stages) != 3 {
+		t.Fatalf("unexpected outcome %s", data)
+	}
+	if outcome.Stages[2].Name != "analysis" || outcome.Stages[2].Parent != "container run" {
+		t.Errorf("unexpected stage %+v", outcome.Stages[2])
+	}
+	if len(qdtrace.Stages()) != 0 {
+		t.Errorf("expected the trace to be finished")
+	}
+}
+
+func TestFinishRunTimedOut(t *testing.T) {
+	resultsDir := t.TempDir()
+	qdtrace.Init("qodana scan")
+	run := qdtrace.Start("ide run")
+	run.StartChild("indexing").End(&qdtrace.TimeoutError{Stage: "indexing", Timeout: time.Minute})
+	run.End(nil)
+	FinishRun(resultsDir, 1)
+
+	outcome, err := ReadOutcome(resultsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome.TimedOut != "indexing" || len(outcome.Stages) != 2 || !outcome.Stages[1].TimedOut {
+		t.Fatalf("unexpected outcome %+v", outcome)
+	}
+}
+
+func TestCancelRun(t *testing.T) {
+	resultsDir := t.TempDir()
+	qdtrace.Init("qodana scan")
+	qdtrace.Start("preparation").End(nil)
+	qdtrace.Start("container run")
+	CancelRun(resultsDir)
+
+	data, err := os.ReadFile(filepath.Join(resultsDir, outcomeFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outcome Outcome
+	if err = json.Unmarshal(data, &outcome); err != nil {
+		t.Fatal(err)
+	}
+	if !outcome.Cancelled || outcome.ExitCode != utils.QodanaCancelledExitCode || len(outcome.Stages) != 1 {
+		t.Fatalf("unexpected outcome %s", data)
+	}
+}
+
+func TestOutcomeReason(t *testing.T) {
+	for expected, outcome := range map[string]Outcome{
+		"passed":               {ExitCode: utils.QodanaSuccessExitCode},
+		"fail threshold":       {ExitCode: utils.QodanaFailThresholdExitCode},
+		"cancelled":            {ExitCode: utils.QodanaCancelledExitCode, Cancelled: true},
+		"indexing timeout":     {ExitCode: 1, TimedOut: "indexing"},
+		"out of memory":        {ExitCode: utils.QodanaOutOfMemoryExitCode},
+		"failed (exit code 1)": {ExitCode: 1},
+	} {
+		if actual := outcome.Reason(); actual != expected {
+			t.Errorf("expected %s, got %s", expected, actual)
+		}
+	}
+}
+
+func TestFormatStageDuration(t *testing.T) {
+	for d, expected := range map[time.Duration]string{
+		250 * time.Millisecond:              "250ms",
+		1234 * time.Millisecond:             "1.2s",
+		time.Minute + 2345*time.Millisecond: "1m2.3s",
+	} {
+		if actual := formatStageDuration(d); actual != expected {
+			t.Errorf("expected %s, got %s", expected, actual)
+		}
+	}
+}
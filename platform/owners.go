@@ -0,0 +1,172 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdowners"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ownerSeverities are the severities of the columns of the owners summary.
+var ownerSeverities = []string{severityCritical, severityHigh, severityModerate, severityLow, severityInfo}
+
+// OwnerProblems is the number of the new problems in the files owned by the owner.
+type OwnerProblems struct {
+	Owner      string         `json:"owner"`
+	Problems   int            `json:"problems"`
+	Severities map[string]int `json:"severities"`
+}
+
+// ownersSummary is the summary of the run written to outcome.json and to the job summary.
+var ownersSummary []OwnerProblems
+
+// SummarizeOwners groups the new problems of the SARIF report by the owners of their files, read from ownersFile
+// or from CODEOWNERS of the project, prints the summary and keeps it for outcome.json and the job summary.
+func SummarizeOwners(sarifPath string, ownersFile string, projectDir string) error {
+	owners, err := LoadOwners(ownersFile, projectDir)
+	if err != nil {
+		return err
+	}
+	summary, err := groupByOwner(sarifPath, owners)
+	if err != nil {
+		return err
+	}
+	ownersSummary = summary
+	if !qdenv.IsContainer() {
+		printOwnersSummary(summary)
+	}
+	return nil
+}
+
+// LoadOwners reads the owners file, the CODEOWNERS of the project if ownersFile is empty.
+func LoadOwners(ownersFile string, projectDir string) (*qdowners.Owners, error) {
+	if ownersFile == "" {
+		if ownersFile = qdowners.Find(projectDir); ownersFile == "" {
+			return nil, fmt.Errorf("no CODEOWNERS found in %s, set the owners file with --owners-file", projectDir)
+		}
+	}
+	owners, err := qdowners.Load(ownersFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the owners: %w", err)
+	}
+	return owners, nil
+}
+
+func groupByOwner(sarifPath string, owners *qdowners.Owners) ([]OwnerProblems, error) {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the SARIF report: %w", err)
+	}
+	counts := map[string]map[string]int{}
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			r := &run.Results[i]
+			path, _, _ := problemLocation(r)
+			resultOwners := []string{qdowners.Unowned}
+			if path != "" {
+				resultOwners = owners.Of(strings.TrimPrefix(path, "file://"))
+			}
+			for _, owner := range resultOwners {
+				if counts[owner] == nil {
+					counts[owner] = map[string]int{}
+				}
+				countNewProblem(counts[owner], r)
+			}
+		}
+	}
+	summary := make([]OwnerProblems, 0, len(counts))
+	for owner, ownerCounts := range counts {
+		if ownerCounts[severityAny] == 0 {
+			continue
+		}
+		problems := OwnerProblems{Owner: owner, Problems: ownerCounts[severityAny], Severities: map[string]int{}}
+		for severity, count := range ownerCounts {
+			if severity != severityAny {
+				problems.Severities[severity] = count
+			}
+		}
+		summary = append(summary, problems)
+	}
+	sort.Slice(
+		summary, func(i, j int) bool {
+			if summary[i].Problems != summary[j].Problems {
+				return summary[i].Problems > summary[j].Problems
+			}
+			return summary[i].Owner < summary[j].Owner
+		},
+	)
+	return summary, nil
+}
+
+func printOwnersSummary(summary []OwnerProblems) {
+	if len(summary) == 0 {
+		return
+	}
+	header := []string{msg.PrimaryBold("Owner"), msg.PrimaryBold("Problems")}
+	for _, severity := range ownerSeverities {
+		header = append(header, msg.PrimaryBold(strings.ToUpper(severity[:1])+severity[1:]))
+	}
+	tableData := pterm.TableData{header}
+	for _, problems := range summary {
+		row := []string{problems.Owner, strconv.Itoa(problems.Problems)}
+		for _, severity := range ownerSeverities {
+			row = append(row, strconv.Itoa(problems.Severities[severity]))
+		}
+		tableData = append(tableData, row)
+	}
+
+	msg.EmptyMessage()
+	table := pterm.DefaultTable.WithData(tableData)
+	table.HeaderRowSeparator = ""
+	table.Separator = " "
+	table.Boxed = true
+	if err := table.Render(); err != nil {
+		log.Debugf("Failed to print the owners summary: %v", err)
+	}
+}
+
+// ownersMarkdown returns the owners summary as a Markdown table.
+func ownersMarkdown(summary []OwnerProblems) string {
+	if len(summary) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n### Problems by owner\n\n| Owner | Problems |")
+	for _, severity := range ownerSeverities {
+		_, _ = fmt.Fprintf(&b, " %s |", strings.ToUpper(severity[:1])+severity[1:])
+	}
+	b.WriteString("\n|---|---:|")
+	for range ownerSeverities {
+		b.WriteString("---:|")
+	}
+	b.WriteString("\n")
+	for _, problems := range summary {
+		_, _ = fmt.Fprintf(&b, "| %s | %d |", problems.Owner, problems.Problems)
+		for _, severity := range ownerSeverities {
+			_, _ = fmt.Fprintf(&b, " %d |", problems.Severities[severity])
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdowners"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGroupByOwner(t *testing.T) {
+	result := func(path string, severity string, baselineState interface{}) sarif.Result {
+		return sarif.Result{
+			RuleId:        "Rule",
+			Message:       &sarif.Message{Text: "problem"},
+			BaselineState: baselineState,
+			Properties:    &sarif.PropertyBag{AdditionalProperties: map[string]interface{}{"qodanaSeverity": severity}},
+			Locations: []sarif.Location{
+				{PhysicalLocation: &sarif.PhysicalLocation{ArtifactLocation: &sarif.ArtifactLocation{Uri: path}}},
+			},
+		}
+	}
+	report := &sarif.Report{
+		Version: "2.1.0",
+		Runs: []sarif.Run{
+			{
+				Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "QDTEST"}},
+				Results: []sarif.Result{
+					result("backend/a.go", qodanaCritical, nil),
+					result("backend/b.go", qodanaHigh, baselineStateUnchanged),
+					result("backend/c.go", qodanaHigh, nil),
+					result("shared/d.go", qodanaLow, nil),
+					result("README.md", qodanaInfo, nil),
+				},
+			},
+		},
+	}
+	dir := t.TempDir()
+	sarifPath := filepath.Join(dir, "qodana.sarif.json")
+	if err := WriteReport(sarifPath, report); err != nil {
+		t.Fatal(err)
+	}
+	ownersFile := filepath.Join(dir, "CODEOWNERS")
+	if err := os.WriteFile(ownersFile, []byte("/backend/ @org/backend\n/shared/ @org/backend @org/frontend\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	owners, err := qdowners.Load(ownersFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := groupByOwner(sarifPath, owners)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []OwnerProblems{
+		{Owner: "@org/backend", Problems: 3, Severities: map[string]int{"critical": 1, "high": 1, "low": 1}},
+		{Owner: "(unowned)", Problems: 1, Severities: map[string]int{"info": 1}},
+		{Owner: "@org/frontend", Problems: 1, Severities: map[string]int{"low": 1}},
+	}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("got %+v, want %+v", summary, expected)
+	}
+	markdown := ownersMarkdown(summary)
+	if !strings.Contains(markdown, "| @org/backend | 3 | 1 | 1 | 0 | 1 | 0 |\n") {
+		t.Errorf("unexpected owners summary:\n%s", markdown)
+	}
+}
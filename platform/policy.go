@@ -0,0 +1,50 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
+	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdpolicy"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"strconv"
+	"strings"
+)
+
+// EnforcePolicy returns an error explaining why the scan can't run if its settings violate the organization policy.
+func EnforcePolicy(options platformcmd.CliOptions, c commoncontext.Context, yaml qdyaml.QodanaYaml) error {
+	policy, err := qdpolicy.Load()
+	if err != nil || policy == nil {
+		return err
+	}
+	disableSanity, _ := strconv.ParseBool(yaml.DisableSanityInspections)
+	violations := policy.Check(
+		qdpolicy.Settings{
+			Linter:        c.Linter,
+			Ide:           c.Ide,
+			DisableSanity: options.DisableSanity || disableSanity,
+			Baseline:      options.Baseline,
+			BaselinesDir:  options.BaselinesDir,
+			CloudToken:    c.QodanaToken,
+		},
+	)
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the scan violates the policy %s:\n- %s", policy, strings.Join(violations, "\n- "))
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/cloud"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"os"
+)
+
+// printPorcelainOutcome prints the lines describing the finished run in the porcelain mode, see msg.PorcelainLine.
+func printPorcelainOutcome(resultsDir string, outcome Outcome) {
+	if !msg.IsPorcelain() {
+		return
+	}
+	msg.PorcelainLine("results-dir", resultsDir)
+	sarifPath := GetSarifPath(resultsDir)
+	if _, err := os.Stat(sarifPath); err == nil {
+		msg.PorcelainLine("sarif", sarifPath)
+		if newProblems, err := CountNewProblems(sarifPath); err == nil {
+			msg.PorcelainLine("new-problems", newProblems)
+		}
+	}
+	if reportUrl := cloud.GetReportUrl(resultsDir); reportUrl != "" {
+		msg.PorcelainLine("report-url", reportUrl)
+	}
+	msg.PorcelainLine("status", outcome.Reason())
+	msg.PorcelainLine("duration-ms", outcome.DurationMs)
+	msg.PorcelainLine("exit-code", outcome.ExitCode)
+}
+
+// CountNewProblems returns the number of the problems of the report counted by the quality gates.
+func CountNewProblems(sarifPath string) (int, error) {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			if isNewProblem(&run.Results[i]) {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,307 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"github.com/pterm/pterm"
+	"io"
+	"strings"
+)
+
+// snippetContextLines is the number of the lines around the problem printed if the code is read from the project.
+const snippetContextLines = 2
+
+// Formats of the problems printed with --print-problems.
+const (
+	ProblemsFormatText              = "text"
+	ProblemsFormatTable             = "table"
+	ProblemsFormatCompact           = "compact"
+	ProblemsFormatSarifJsonl        = "sarif-jsonl"
+	ProblemsFormatGithubAnnotations = "github-annotations"
+)
+
+// ProblemsFormats are the supported formats of the printed problems.
+var ProblemsFormats = []string{
+	ProblemsFormatText,
+	ProblemsFormatTable,
+	ProblemsFormatCompact,
+	ProblemsFormatSarifJsonl,
+	ProblemsFormatGithubAnnotations,
+}
+
+var problemsFormatAliases = map[string]string{
+	"json":          ProblemsFormatSarifJsonl,
+	"jsonl":         ProblemsFormatSarifJsonl,
+	"ghannotations": ProblemsFormatGithubAnnotations,
+	"github":        ProblemsFormatGithubAnnotations,
+}
+
+// ProblemsOutput configures how the problems are printed.
+type ProblemsOutput struct {
+	// Format is one of ProblemsFormats, text if empty.
+	Format string
+	// Severities are the severities of the problems to print, all problems are printed if empty.
+	Severities []string
+	// ProjectDir is the directory to read the code of the problems from if the report has no code snippets.
+	ProjectDir string
+}
+
+// Validate checks the format of the output.
+func (o ProblemsOutput) Validate() error {
+	_, err := problemsFormat(o.Format)
+	return err
+}
+
+// accepts checks if the problem of the severity should be printed.
+func (o ProblemsOutput) accepts(severity string) bool {
+	if len(o.Severities) == 0 {
+		return true
+	}
+	for _, s := range o.Severities {
+		if strings.EqualFold(strings.TrimSpace(s), severity) {
+			return true
+		}
+	}
+	return false
+}
+
+func problemsFormat(format string) (string, error) {
+	format = strings.ToLower(format)
+	if format == "" {
+		return ProblemsFormatText, nil
+	}
+	if alias, ok := problemsFormatAliases[format]; ok {
+		return alias, nil
+	}
+	for _, f := range ProblemsFormats {
+		if f == format {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown problems format %q, use one of %s", format, strings.Join(ProblemsFormats, ", "))
+}
+
+// problemsPrinter prints the problems one by one as they are read from the report.
+type problemsPrinter interface {
+	print(r *sarif.Result) error
+	// close prints the problems buffered by the printer.
+	close() error
+}
+
+func newProblemsPrinter(output ProblemsOutput, w io.Writer) (problemsPrinter, error) {
+	format, err := problemsFormat(output.Format)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case ProblemsFormatTable:
+		return &tableProblemsPrinter{w: w}, nil
+	case ProblemsFormatCompact:
+		return &compactProblemsPrinter{w: w}, nil
+	case ProblemsFormatSarifJsonl:
+		return &jsonlProblemsPrinter{enc: json.NewEncoder(w)}, nil
+	case ProblemsFormatGithubAnnotations:
+		return &githubProblemsPrinter{w: w}, nil
+	default:
+		return &textProblemsPrinter{projectDir: output.ProjectDir}, nil
+	}
+}
+
+// WriteProblems writes the problems of the SARIF report in the format of the output to w and returns the number
+// of the problems written. As with --print-problems, the unchanged problems of the baseline and the problems
+// without a location are skipped. The text format is printed to the terminal, it can't be written to w.
+func WriteProblems(sarifPath string, output ProblemsOutput, w io.Writer) (int, error) {
+	if format, err := problemsFormat(output.Format); err != nil {
+		return 0, err
+	} else if format == ProblemsFormatText {
+		return 0, fmt.Errorf("the %s format can't be written, use one of the other formats", ProblemsFormatText)
+	}
+	printer, err := newProblemsPrinter(output, w)
+	if err != nil {
+		return 0, err
+	}
+	results := make(chan sarifResultItem, sarifPipelineBuffer)
+	var readErr error
+	read := make(chan struct{})
+	go func() {
+		_, readErr = streamSarifResults(sarifPath, results)
+		close(read)
+	}()
+	written := 0
+	var writeErr error
+	for item := range results {
+		r := &item.result
+		// the results are drained after a write error, so the reader finishes
+		if writeErr != nil || len(r.Locations) == 0 || r.BaselineState == baselineStateUnchanged || !output.accepts(getSeverity(r)) {
+			continue
+		}
+		if writeErr = printer.print(r); writeErr == nil {
+			written++
+		}
+	}
+	<-read
+	if readErr != nil {
+		return written, readErr
+	}
+	if writeErr != nil {
+		return written, writeErr
+	}
+	return written, printer.close()
+}
+
+// problemLocation returns the location of the first physical location of the result, path is empty if there is none.
+func problemLocation(r *sarif.Result) (path string, line int, column int) {
+	if len(r.Locations) == 0 || r.Locations[0].PhysicalLocation == nil {
+		return "", 0, 0
+	}
+	location := r.Locations[0].PhysicalLocation
+	if location.ArtifactLocation != nil {
+		path = location.ArtifactLocation.Uri
+	}
+	if location.Region != nil {
+		line, column = int(location.Region.StartLine), int(location.Region.StartColumn)
+	}
+	return path, line, column
+}
+
+func problemMessage(r *sarif.Result) string {
+	if r.Message == nil {
+		return ""
+	}
+	return r.Message.Text
+}
+
+// textProblemsPrinter prints the problems with their source code.
+type textProblemsPrinter struct {
+	projectDir string
+}
+
+func (p *textProblemsPrinter) print(r *sarif.Result) error {
+	printSarifProblem(r, r.RuleId, problemMessage(r), p.projectDir)
+	return nil
+}
+
+func (p *textProblemsPrinter) close() error { return nil }
+
+// tableProblemsPrinter prints the problems as a table once all of them are read.
+type tableProblemsPrinter struct {
+	w    io.Writer
+	rows pterm.TableData
+}
+
+func (p *tableProblemsPrinter) print(r *sarif.Result) error {
+	path, line, _ := problemLocation(r)
+	if line > 0 {
+		path = fmt.Sprintf("%s:%d", path, line)
+	}
+	p.rows = append(p.rows, []string{getSeverity(r), r.RuleId, path, strings.ReplaceAll(problemMessage(r), "\n", " ")})
+	return nil
+}
+
+func (p *tableProblemsPrinter) close() error {
+	if len(p.rows) == 0 {
+		return nil
+	}
+	header := []string{msg.PrimaryBold("Severity"), msg.PrimaryBold("Rule"), msg.PrimaryBold("Location"), msg.PrimaryBold("Message")}
+	table, err := pterm.DefaultTable.WithHasHeader().WithData(append(pterm.TableData{header}, p.rows...)).Srender()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(p.w, table)
+	return err
+}
+
+// compactProblemsPrinter prints a problem per line in the path:line:column: severity [rule] message format of compilers.
+type compactProblemsPrinter struct {
+	w io.Writer
+}
+
+func (p *compactProblemsPrinter) print(r *sarif.Result) error {
+	path, line, column := problemLocation(r)
+	location := path
+	if line > 0 {
+		location = fmt.Sprintf("%s:%d:%d", path, line, max(column, 1))
+	}
+	if location != "" {
+		location += ": "
+	}
+	_, err := fmt.Fprintf(
+		p.w,
+		"%s%s [%s] %s\n",
+		location,
+		strings.ToLower(getSeverity(r)),
+		r.RuleId,
+		strings.ReplaceAll(problemMessage(r), "\n", " "),
+	)
+	return err
+}
+
+func (p *compactProblemsPrinter) close() error { return nil }
+
+// jsonlProblemsPrinter prints the SARIF results as JSON Lines.
+type jsonlProblemsPrinter struct {
+	enc *json.Encoder
+}
+
+func (p *jsonlProblemsPrinter) print(r *sarif.Result) error {
+	return p.enc.Encode(r)
+}
+
+func (p *jsonlProblemsPrinter) close() error { return nil }
+
+// githubProblemsPrinter prints the problems as GitHub Actions workflow commands, shown as annotations of the files.
+type githubProblemsPrinter struct {
+	w io.Writer
+}
+
+func (p *githubProblemsPrinter) print(r *sarif.Result) error {
+	command := "notice"
+	switch strings.ToLower(getSeverity(r)) {
+	case "critical", "high", "error":
+		command = "error"
+	case "moderate", "warning":
+		command = "warning"
+	}
+	var properties []string
+	path, line, column := problemLocation(r)
+	if path != "" {
+		properties = append(properties, "file="+escapeGithubProperty(path))
+		if line > 0 {
+			properties = append(properties, fmt.Sprintf("line=%d", line))
+		}
+		if column > 0 {
+			properties = append(properties, fmt.Sprintf("col=%d", column))
+		}
+	}
+	properties = append(properties, "title="+escapeGithubProperty(fmt.Sprintf("%s (%s)", r.RuleId, getSeverity(r))))
+	_, err := fmt.Fprintf(p.w, "::%s %s::%s\n", command, strings.Join(properties, ","), escapeGithubData(problemMessage(r)))
+	return err
+}
+
+func (p *githubProblemsPrinter) close() error { return nil }
+
+func escapeGithubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGithubProperty(s string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(escapeGithubData(s))
+}
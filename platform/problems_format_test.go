@@ -0,0 +1,181 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testProblems() []sarif.Result {
+	return []sarif.Result{
+		{
+			RuleId:     "ConstantValue",
+			Message:    &sarif.Message{Text: "Condition is always true,\nreally"},
+			Properties: &sarif.PropertyBag{AdditionalProperties: map[string]interface{}{"qodanaSeverity": "High"}},
+			Locations: []sarif.Location{
+				{
+					PhysicalLocation: &sarif.PhysicalLocation{
+						ArtifactLocation: &sarif.ArtifactLocation{Uri: "src/A, B.java"},
+						Region:           &sarif.Region{StartLine: 11, StartColumn: 5},
+					},
+				},
+			},
+		},
+		{
+			RuleId:     "UnusedProperty",
+			Message:    &sarif.Message{Text: "100% unused"},
+			Properties: &sarif.PropertyBag{AdditionalProperties: map[string]interface{}{"qodanaSeverity": "Moderate"}},
+		},
+	}
+}
+
+func printProblems(t *testing.T, format string) string {
+	var out bytes.Buffer
+	printer, err := newProblemsPrinter(ProblemsOutput{Format: format}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range testProblems() {
+		r := r
+		if err = printer.print(&r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = printer.close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestCompactProblemsFormat(t *testing.T) {
+	expected := "src/A, B.java:11:5: high [ConstantValue] Condition is always true, really\n" +
+		"moderate [UnusedProperty] 100% unused\n"
+	if actual := printProblems(t, ProblemsFormatCompact); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestGithubAnnotationsProblemsFormat(t *testing.T) {
+	expected := "::error file=src/A%2C B.java,line=11,col=5,title=ConstantValue (High)::Condition is always true,%0Areally\n" +
+		"::warning title=UnusedProperty (Moderate)::100%25 unused\n"
+	if actual := printProblems(t, "ghannotations"); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestSarifJsonlProblemsFormat(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(printProblems(t, ProblemsFormatSarifJsonl)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a line per problem, got %q", lines)
+	}
+	r := sarif.Result{}
+	if err := json.Unmarshal([]byte(lines[0]), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.RuleId != "ConstantValue" || getSeverity(&r) != "High" {
+		t.Errorf("unexpected result %+v", r)
+	}
+}
+
+func TestTableProblemsFormat(t *testing.T) {
+	table := printProblems(t, ProblemsFormatTable)
+	if !strings.Contains(table, "src/A, B.java:11") || !strings.Contains(table, "UnusedProperty") {
+		t.Errorf("unexpected table %s", table)
+	}
+}
+
+func TestWriteProblems(t *testing.T) {
+	unchanged := testProblems()[0]
+	unchanged.BaselineState = baselineStateUnchanged
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	report := &sarif.Report{
+		Version: "2.1.0",
+		Runs:    []sarif.Run{{Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "QDTEST"}}, Results: append(testProblems(), unchanged)}},
+	}
+	if err := WriteReport(sarifPath, report); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	written, err := WriteProblems(sarifPath, ProblemsOutput{Format: ProblemsFormatCompact}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the problem without a location and the unchanged one are skipped
+	if written != 1 || strings.Count(out.String(), "ConstantValue") != 1 {
+		t.Errorf("expected the problem with a location only, got %d: %s", written, out.String())
+	}
+	if _, err = WriteProblems(sarifPath, ProblemsOutput{Format: ProblemsFormatText}, &out); err == nil {
+		t.Error("expected the text format to be rejected")
+	}
+}
+
+func TestProblemsOutput(t *testing.T) {
+	if err := (ProblemsOutput{Format: "xml"}).Validate(); err == nil {
+		t.Errorf("expected an unknown format to be rejected")
+	}
+	if err := (ProblemsOutput{}).Validate(); err != nil {
+		t.Errorf("expected the default format, got %v", err)
+	}
+	output := ProblemsOutput{Severities: []string{"critical", " high"}}
+	if !output.accepts("High") || output.accepts("Moderate") || !(ProblemsOutput{}).accepts("Info") {
+		t.Errorf("unexpected severity filter")
+	}
+}
+
+func TestProblemRange(t *testing.T) {
+	context := "class A {\n    int x = 1;\n}\n"
+	for name, c := range map[string]struct {
+		region   sarif.Region
+		expected msg.ProblemRange
+	}{
+		"end":        {sarif.Region{StartLine: 2, StartColumn: 5, EndLine: 2, EndColumn: 8}, msg.ProblemRange{StartLine: 2, StartColumn: 5, EndLine: 2, EndColumn: 8}},
+		"length":     {sarif.Region{StartLine: 2, StartColumn: 9, CharLength: 1}, msg.ProblemRange{StartLine: 2, StartColumn: 9, EndLine: 2, EndColumn: 10}},
+		"multiline":  {sarif.Region{StartLine: 1, StartColumn: 7, CharLength: 8}, msg.ProblemRange{StartLine: 1, StartColumn: 7, EndLine: 2, EndColumn: 5}},
+		"overflow":   {sarif.Region{StartLine: 3, StartColumn: 1, CharLength: 10}, msg.ProblemRange{StartLine: 3, StartColumn: 1, EndLine: 4}},
+		"no length":  {sarif.Region{StartLine: 2, StartColumn: 5}, msg.ProblemRange{StartLine: 2, StartColumn: 5}},
+		"no context": {sarif.Region{StartLine: 20, StartColumn: 1, CharLength: 3}, msg.ProblemRange{StartLine: 20, StartColumn: 1, EndLine: 20}},
+	} {
+		c := c
+		if actual := problemRange(&c.region, context, 1); actual != c.expected {
+			t.Errorf("%s: expected %+v, got %+v", name, c.expected, actual)
+		}
+	}
+}
+
+func TestReadSourceLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "A.java")
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree\nfour\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if line, code := readSourceLines(path, 0, 2); line != 1 || code != "one\ntwo\n" {
+		t.Errorf("unexpected code %d %q", line, code)
+	}
+	if line, code := readSourceLines(path, 3, 10); line != 3 || code != "three\nfour\n" {
+		t.Errorf("unexpected code %d %q", line, code)
+	}
+	if _, code := readSourceLines(path+".missing", 1, 2); code != "" {
+		t.Errorf("expected no code, got %q", code)
+	}
+}
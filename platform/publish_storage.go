@@ -0,0 +1,221 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdstorage"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
+	log "github.com/sirupsen/logrus"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	publishRunTimeLayout = "20060102T150405Z"
+	maxSignedUrlExpiry   = 7 * 24 * time.Hour
+)
+
+// publishedRun matches the objects of a published run: <run>/results/... and <run>/report/...
+var publishedRun = regexp.MustCompile(`^(\d{8}T\d{6}Z-[^/]+)/(?:results|report)/`)
+
+// PublishedRun is the location of the run published to an object storage.
+type PublishedRun struct {
+	Prefix    string
+	ReportUrl string
+	SarifUrl  string
+}
+
+// PublishToStorage uploads the results directory and the HTML report to location (s3://, gs:// or az://)
+// under <prefix>/<time>-<analysisId>/, and deletes the runs published there earlier than retention ago (zero keeps them all).
+// The SARIF URL is signed for urlExpiry if the credentials can sign URLs.
+func PublishToStorage(location string, resultsDir string, reportDir string, analysisId string, retention time.Duration, urlExpiry time.Duration) (*PublishedRun, error) {
+	bucket, prefix, err := qdstorage.Open(location)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	run := qdstorage.Join(prefix, now.Format(publishRunTimeLayout)+"-"+sanitizeCacheKey(analysisId, "run"))
+
+	files := map[string]string{}
+	if reportDir != "" {
+		if err = collectPublishedFiles(reportDir, run+"/report", "", files); err != nil {
+			return nil, err
+		}
+	}
+	if err = collectPublishedFiles(resultsDir, run+"/results", reportDir, files); err != nil {
+		return nil, err
+	}
+	if err = uploadFiles(bucket, files); err != nil {
+		return nil, err
+	}
+
+	published := &PublishedRun{Prefix: run}
+	if reportDir != "" {
+		published.ReportUrl = bucket.Url(run + "/report/index.html")
+	}
+	sarif := run + "/results/qodana.sarif.json"
+	if _, ok := files[sarif]; ok {
+		published.SarifUrl = bucket.Url(sarif)
+		if urlExpiry > maxSignedUrlExpiry {
+			urlExpiry = maxSignedUrlExpiry
+		}
+		signed, err := bucket.SignedUrl(sarif, urlExpiry)
+		switch {
+		case err == nil:
+			published.SarifUrl = signed
+		case errors.Is(err, qdstorage.ErrSigningUnsupported):
+			log.Debugf("Not signing the URL of %s: %s", sarif, err)
+		default:
+			log.Warnf("Failed to sign the URL of %s: %s", sarif, err)
+		}
+	}
+
+	if retention > 0 {
+		if err = deleteExpiredRuns(bucket, prefix, now.Add(-retention)); err != nil {
+			log.Warnf("Failed to delete the expired runs from %s: %s", location, err)
+		}
+	}
+	return published, nil
+}
+
+// PublishResults publishes the run to the object storage if location is set, failures are reported as warnings.
+func PublishResults(location string, resultsDir string, reportDir string, analysisId string, retention time.Duration, urlExpiry time.Duration) {
+	if location == "" {
+		return
+	}
+	span := qdtrace.Start("publish")
+	published, err := PublishToStorage(location, resultsDir, reportDir, analysisId, retention, urlExpiry)
+	span.End(err)
+	if err != nil {
+		msg.WarningMessage("Failed to publish the results to %s: %s", location, err)
+		return
+	}
+	if published.ReportUrl != "" {
+		msg.SuccessMessage("Report is published to %s", published.ReportUrl)
+	}
+	if published.SarifUrl != "" {
+		msg.SuccessMessage("SARIF results are published to %s", published.SarifUrl)
+	}
+}
+
+// collectPublishedFiles maps the files in dir to the object names under prefix, skipping the excluded directory.
+func collectPublishedFiles(dir string, prefix string, exclude string, files map[string]string) error {
+	if exclude != "" {
+		if abs, err := filepath.Abs(exclude); err == nil {
+			exclude = abs
+		}
+	}
+	return filepath.WalkDir(
+		dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if abs, err := filepath.Abs(path); err == nil && exclude != "" && abs == exclude {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files[prefix+"/"+filepath.ToSlash(rel)] = path
+			return nil
+		},
+	)
+}
+
+func uploadFiles(bucket qdstorage.Bucket, files map[string]string) error {
+	names := make(chan string)
+	errs := make(chan error, len(files))
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU()*2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				if err := uploadFile(bucket, name, files[name]); err != nil {
+					errs <- fmt.Errorf("failed to upload %s: %w", files[name], err)
+				}
+			}
+		}()
+	}
+	for name := range files {
+		names <- name
+	}
+	close(names)
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+func uploadFile(bucket qdstorage.Bucket, name string, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return bucket.Put(name, f, info.Size(), contentType)
+}
+
+// deleteExpiredRuns deletes the objects of the runs published under prefix before the deadline, other objects are kept.
+func deleteExpiredRuns(bucket qdstorage.Bucket, prefix string, deadline time.Time) error {
+	listPrefix := ""
+	if prefix != "" {
+		listPrefix = prefix + "/"
+	}
+	objects, err := bucket.List(listPrefix)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		match := publishedRun.FindStringSubmatch(strings.TrimPrefix(object.Name, listPrefix))
+		if match == nil {
+			continue
+		}
+		started, err := time.Parse(publishRunTimeLayout, match[1][:len(publishRunTimeLayout)])
+		if err != nil || !started.Before(deadline) {
+			continue
+		}
+		if err = bucket.Delete(object.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdstorage"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryBucket struct {
+	mu      sync.Mutex
+	objects map[string]string
+}
+
+func (b *memoryBucket) Get(name string) (io.ReadCloser, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.objects[name]
+	return io.NopCloser(strings.NewReader(data)), ok, nil
+}
+
+func (b *memoryBucket) Put(name string, r io.Reader, _ int64, _ string) error {
+	data, err := io.ReadAll(r)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[name] = string(data)
+	return err
+}
+
+func (b *memoryBucket) List(prefix string) ([]qdstorage.Object, error) {
+	var objects []qdstorage.Object
+	for name := range b.objects {
+		if strings.HasPrefix(name, prefix) {
+			objects = append(objects, qdstorage.Object{Name: name})
+		}
+	}
+	return objects, nil
+}
+
+func (b *memoryBucket) Delete(name string) error {
+	delete(b.objects, name)
+	return nil
+}
+
+func (b *memoryBucket) Url(name string) string { return "https://storage/" + name }
+
+func (b *memoryBucket) SignedUrl(string, time.Duration) (string, error) {
+	return "", qdstorage.ErrSigningUnsupported
+}
+
+func (b *memoryBucket) names() []string {
+	var names []string
+	for name := range b.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestUploadPublishedFiles(t *testing.T) {
+	resultsDir := t.TempDir()
+	reportDir := filepath.Join(resultsDir, "report")
+	for path, content := range map[string]string{
+		"qodana.sarif.json":   "{}",
+		"log/idea.log":        "log",
+		"report/index.html":   "<html/>",
+		"report/js/report.js": "js",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(resultsDir, path)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(resultsDir, path), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{}
+	if err := collectPublishedFiles(reportDir, "run/report", "", files); err != nil {
+		t.Fatal(err)
+	}
+	if err := collectPublishedFiles(resultsDir, "run/results", reportDir, files); err != nil {
+		t.Fatal(err)
+	}
+	bucket := &memoryBucket{objects: map[string]string{}}
+	if err := uploadFiles(bucket, files); err != nil {
+		t.Fatal(err)
+	}
+	expected := "run/report/index.html,run/report/js/report.js,run/results/log/idea.log,run/results/qodana.sarif.json"
+	if actual := strings.Join(bucket.names(), ","); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+	if bucket.objects["run/report/index.html"] != "<html/>" {
+		t.Errorf("unexpected contents %s", bucket.objects["run/report/index.html"])
+	}
+}
+
+func TestDeleteExpiredRuns(t *testing.T) {
+	bucket := &memoryBucket{
+		objects: map[string]string{
+			"qodana/20240101T000000Z-old/results/qodana.sarif.json": "",
+			"qodana/20240101T000000Z-old/report/index.html":         "",
+			"qodana/20240301T000000Z-new/results/qodana.sarif.json": "",
+			"qodana/20230101T000000Z-notes.txt":                     "",
+			"qodana/unrelated/report/index.html":                    "",
+			"other/20230101T000000Z-old/results/qodana.sarif.json":  "",
+		},
+	}
+	if err := deleteExpiredRuns(bucket, "qodana", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	expected := "other/20230101T000000Z-old/results/qodana.sarif.json,qodana/20230101T000000Z-notes.txt," +
+		"qodana/20240301T000000Z-new/results/qodana.sarif.json,qodana/unrelated/report/index.html"
+	if actual := strings.Join(bucket.names(), ","); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
@@ -23,10 +23,14 @@
 package platform
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
+	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/cloud"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdsign"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	cp "github.com/otiai10/copy"
 	log "github.com/sirupsen/logrus"
@@ -47,7 +51,7 @@ type Publisher struct {
 }
 
 // SendReport sends report to Qodana Cloud.
-func SendReport(publisher Publisher, token string, publisherPath string, javaPath string) {
+func SendReport(ctx context.Context, publisher Publisher, token string, publisherPath string, javaPath string) {
 	if _, err := os.Stat(publisherPath); os.IsNotExist(err) {
 		err := os.MkdirAll(filepath.Dir(publisherPath), os.ModePerm)
 		if err != nil {
@@ -80,9 +84,17 @@ func SendReport(publisher Publisher, token string, publisherPath string, javaPat
 		token,
 		cloud.GetCloudApiEndpoints().CloudApiUrl,
 	)
-	if _, _, res, err := utils.LaunchAndLog(publisher.LogDir, "publisher", publisherCommand...); res > 0 || err != nil {
+	span := qdtrace.Start("upload")
+	span.SetAttribute("qodana.analysis_id", publisher.AnalysisId)
+	if _, _, res, err := utils.LaunchAndLog(ctx, publisher.LogDir, "publisher", publisherCommand...); res > 0 || err != nil {
+		if err == nil {
+			err = fmt.Errorf("publisher exited with code %d", res)
+		}
+		span.End(err)
+		qdtrace.Shutdown()
 		os.Exit(res)
 	}
+	span.End(nil)
 }
 
 // getPublisherArgs returns args for the publisher.
@@ -127,6 +139,10 @@ func fetchPublisher(path string) {
 		log.Fatal(err)
 	}
 	verifyMd5Hash(jarVersion, path)
+	if err = qdsign.VerifyDownload(path, getPublisherUrl(jarVersion)); err != nil {
+		_ = os.Remove(path)
+		log.Fatal(err)
+	}
 }
 
 func verifyMd5Hash(version string, path string) {
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdandroid detects the Android SDK packages an Android project compiles against and installs the missing ones
+// into an SDK in the Qodana cache, overlaying the SDK of the environment, so the Gradle import doesn't fail on them.
+package qdandroid
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// compileSdk = 34, compileSdkVersion 34, compileSdkVersion "android-34"
+	compileSdkPattern = regexp.MustCompile(`\bcompileSdk(?:Version)?\s*(?:=\s*|\(\s*)?["']?(?:android-)?(\d+)\b`)
+	// buildToolsVersion = "34.0.0", buildToolsVersion "34.0.0"
+	buildToolsPattern = regexp.MustCompile(`\bbuildToolsVersion\s*(?:=\s*|\(\s*)?["'](\d+\.\d+\.\d+)["']`)
+)
+
+// maxDepth is the depth of the Gradle modules in the project searched for the SDK packages.
+const maxDepth = 3
+
+// Detect returns the SDK packages, like platforms;android-34 and build-tools;34.0.0, required by the Gradle modules
+// of the project.
+func Detect(projectDir string) []string {
+	packages := map[string]bool{}
+	_ = filepath.WalkDir(
+		projectDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				rel, _ := filepath.Rel(projectDir, path)
+				name := d.Name()
+				if path != projectDir && (strings.HasPrefix(name, ".") || name == "build" || name == "node_modules" ||
+					strings.Count(filepath.ToSlash(rel), "/") >= maxDepth) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.Name() != "build.gradle" && d.Name() != "build.gradle.kts" {
+				return nil
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			for _, m := range compileSdkPattern.FindAllStringSubmatch(string(content), -1) {
+				packages["platforms;android-"+m[1]] = true
+			}
+			for _, m := range buildToolsPattern.FindAllStringSubmatch(string(content), -1) {
+				packages["build-tools;"+m[1]] = true
+			}
+			return nil
+		},
+	)
+	result := make([]string, 0, len(packages))
+	for p := range packages {
+		result = append(result, p)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,261 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdandroid
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRepositoryUrl is the Android repository the command-line tools are downloaded from.
+	DefaultRepositoryUrl = "https://dl.google.com/android/repository"
+	// cmdlineToolsBuild is the build of the downloaded command-line tools, sdkmanager updates the packages anyway.
+	cmdlineToolsBuild = "11076708"
+	cacheDirName      = "android-sdk"
+	// maxPackageDepth is the depth of the package directories in the SDK, system-images/android-34/google_apis/x86_64.
+	maxPackageDepth = 4
+)
+
+// EnvironmentSdk returns the SDK of the environment, $ANDROID_HOME or $ANDROID_SDK_ROOT.
+func EnvironmentSdk() string {
+	if sdk := os.Getenv(qdenv.AndroidHome); sdk != "" {
+		return sdk
+	}
+	return os.Getenv(qdenv.AndroidSdkRoot)
+}
+
+// Missing returns the packages not installed in the SDK, all of them if there is no SDK.
+func Missing(sdk string, packages []string) []string {
+	if sdk == "" {
+		return packages
+	}
+	var missing []string
+	for _, p := range packages {
+		if !isInstalled(sdk, p) {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// isInstalled returns true if the package is in the SDK: platforms;android-34 has platforms/android-34/package.xml.
+func isInstalled(sdk string, p string) bool {
+	_, err := os.Stat(filepath.Join(sdk, filepath.Join(strings.Split(p, ";")...), "package.xml"))
+	return err == nil
+}
+
+// Sdk is the SDK in the Qodana cache, overlaying the SDK of the environment.
+type Sdk struct {
+	Root          string
+	Base          string
+	RepositoryUrl string
+	Os            string
+	Client        *http.Client
+}
+
+// NewSdk returns the SDK in cacheDir overlaying base, the command-line tools are downloaded
+// from $QODANA_ANDROID_REPOSITORY_URL if it is set, otherwise from the Android repository.
+func NewSdk(cacheDir string, base string) *Sdk {
+	u := os.Getenv(qdenv.QodanaAndroidRepositoryUrlEnv)
+	if u == "" {
+		u = DefaultRepositoryUrl
+	}
+	return &Sdk{
+		Root:          filepath.Join(cacheDir, cacheDirName),
+		Base:          base,
+		RepositoryUrl: strings.TrimSuffix(u, "/"),
+		Os:            runtime.GOOS,
+		Client:        &http.Client{Timeout: 30 * time.Minute, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}},
+	}
+}
+
+// Install installs the packages into the SDK accepting their licenses: the packages of the base SDK are linked,
+// and sdkmanager installs the ones still missing. The output of sdkmanager is written to log.
+func (s *Sdk) Install(packages []string, log io.Writer) error {
+	if err := os.MkdirAll(s.Root, os.ModePerm); err != nil {
+		return err
+	}
+	if s.Base != "" && filepath.Clean(s.Base) != filepath.Clean(s.Root) {
+		if err := overlay(s.Base, s.Root); err != nil {
+			return fmt.Errorf("failed to link the packages of %s: %w", s.Base, err)
+		}
+	}
+	missing := Missing(s.Root, packages)
+	if len(missing) == 0 {
+		return nil
+	}
+	sdkmanager, err := s.sdkmanager()
+	if err != nil {
+		return err
+	}
+	if err = s.run(log, sdkmanager, "--licenses"); err != nil {
+		return err
+	}
+	if err = s.run(log, sdkmanager, missing...); err != nil {
+		return err
+	}
+	if missing = Missing(s.Root, missing); len(missing) > 0 {
+		return fmt.Errorf("sdkmanager didn't install %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (s *Sdk) run(log io.Writer, sdkmanager string, args ...string) error {
+	args = append([]string{"--sdk_root=" + s.Root}, args...)
+	_, _ = fmt.Fprintf(log, "$ %s %s\n", sdkmanager, strings.Join(args, " "))
+	cmd := exec.Command(sdkmanager, args...)
+	cmd.Stdin = strings.NewReader(strings.Repeat("y\n", 100)) // accepts the licenses
+	cmd.Stdout = log
+	cmd.Stderr = log
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sdkmanager %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// sdkmanager returns sdkmanager of the command-line tools in the SDK, downloading them if there are none.
+func (s *Sdk) sdkmanager() (string, error) {
+	name := "sdkmanager"
+	if s.Os == "windows" {
+		name += ".bat"
+	}
+	matches, _ := filepath.Glob(filepath.Join(s.Root, "cmdline-tools", "*", "bin", name))
+	for _, candidate := range append([]string{filepath.Join(s.Root, "cmdline-tools", "latest", "bin", name)}, matches...) {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	osName, ok := map[string]string{"linux": "linux", "darwin": "mac", "windows": "win"}[s.Os]
+	if !ok {
+		return "", fmt.Errorf("no Android command-line tools for %s", s.Os)
+	}
+	u := fmt.Sprintf("%s/commandlinetools-%s-%s_latest.zip", s.RepositoryUrl, osName, cmdlineToolsBuild)
+	response, err := s.Client.Get(u)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach %s: %w", s.RepositoryUrl, err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", u, response.Status)
+	}
+	tmp := filepath.Join(s.Root, "cmdline-tools", "latest.download")
+	_ = os.RemoveAll(tmp)
+	defer func() { _ = os.RemoveAll(tmp) }()
+	if err = os.MkdirAll(tmp, os.ModePerm); err != nil {
+		return "", err
+	}
+	if err = utils.ExtractZip(response.Body, tmp); err != nil {
+		return "", fmt.Errorf("failed to extract the Android command-line tools: %w", err)
+	}
+	// the archive contains the cmdline-tools directory, installed as the latest version
+	latest := filepath.Join(s.Root, "cmdline-tools", "latest")
+	_ = os.RemoveAll(latest)
+	if err = os.Rename(filepath.Join(tmp, "cmdline-tools"), latest); err != nil {
+		return "", fmt.Errorf("no Android command-line tools in the archive downloaded from %s: %w", u, err)
+	}
+	return filepath.Join(latest, "bin", name), nil
+}
+
+// overlay links the packages of the base SDK missing in root and copies the accepted licenses,
+// the links to the packages removed from the base SDK since are deleted.
+func overlay(base string, root string) error {
+	err := filepath.WalkDir(
+		root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.Type()&fs.ModeSymlink != 0 {
+				if _, statErr := os.Stat(path); statErr != nil {
+					_ = os.Remove(path)
+				}
+				return nil
+			}
+			if rel, _ := filepath.Rel(root, path); d.IsDir() && strings.Count(filepath.ToSlash(rel), "/")+1 >= maxPackageDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(
+		base, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() || path == base {
+				return nil
+			}
+			rel, err := filepath.Rel(base, path)
+			if err != nil {
+				return err
+			}
+			target := filepath.Join(root, rel)
+			if rel == "licenses" {
+				if err = copyLicenses(path, target); err != nil {
+					return err
+				}
+				return filepath.SkipDir
+			}
+			if _, err = os.Stat(filepath.Join(path, "package.xml")); err != nil {
+				if strings.Count(filepath.ToSlash(rel), "/")+1 >= maxPackageDepth {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if _, err = os.Lstat(target); err == nil {
+				return filepath.SkipDir
+			}
+			if err = os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+				return err
+			}
+			if err = os.Symlink(path, target); err != nil {
+				return err
+			}
+			return filepath.SkipDir
+		},
+	)
+}
+
+func copyLicenses(src string, dst string) error {
+	if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		target := filepath.Join(dst, entry.Name())
+		if _, err = os.Stat(target); entry.IsDir() || err == nil {
+			continue
+		}
+		if err = utils.CopyFile(filepath.Join(src, entry.Name()), target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdandroid
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, content string, mode os.FileMode) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, "build.gradle.kts"), `plugins { id("com.android.application") apply false }`, 0o644)
+	writeFile(
+		t,
+		filepath.Join(project, "app", "build.gradle.kts"),
+		"android {\n  compileSdk = 34\n  buildToolsVersion = \"34.0.0\"\n}",
+		0o644,
+	)
+	writeFile(t, filepath.Join(project, "features", "login", "build.gradle"), "android {\n  compileSdkVersion 'android-33'\n}", 0o644)
+	writeFile(t, filepath.Join(project, "app", "build", "tmp", "build.gradle"), "android { compileSdk 21 }", 0o644)
+
+	expected := []string{"build-tools;34.0.0", "platforms;android-33", "platforms;android-34"}
+	if actual := Detect(project); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Detect() = %v, want %v", actual, expected)
+	}
+}
+
+func TestInstall(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake sdkmanager is a shell script")
+	}
+	base := t.TempDir()
+	writeFile(t, filepath.Join(base, "platforms", "android-33", "package.xml"), "", 0o644)
+	writeFile(t, filepath.Join(base, "licenses", "android-sdk-license"), "24333f8a63b6825ea9c5514f83c2829b004d1fee", 0o644)
+	// sdkmanager installs the packages given as arguments, except for the options
+	writeFile(
+		t,
+		filepath.Join(base, "cmdline-tools", "latest", "bin", "sdkmanager"),
+		`#!/bin/sh
+root=${1#--sdk_root=}
+shift
+for p in "$@"; do
+  case "$p" in --*) continue ;; esac
+  dir="$root/$(echo "$p" | tr ';' '/')"
+  mkdir -p "$dir" && touch "$dir/package.xml"
+done
+`,
+		0o755,
+	)
+	writeFile(t, filepath.Join(base, "cmdline-tools", "latest", "package.xml"), "", 0o644)
+
+	sdk := NewSdk(t.TempDir(), base)
+	packages := []string{"platforms;android-33", "platforms;android-34", "build-tools;34.0.0"}
+	var log bytes.Buffer
+	if err := sdk.Install(Missing(base, packages), &log); err != nil {
+		t.Fatalf("%s\n%s", err, log.String())
+	}
+	if missing := Missing(sdk.Root, packages); len(missing) != 0 {
+		t.Errorf("expected all packages in %s, missing %v", sdk.Root, missing)
+	}
+	if target, err := os.Readlink(filepath.Join(sdk.Root, "platforms", "android-33")); err != nil || target != filepath.Join(base, "platforms", "android-33") {
+		t.Errorf("expected the package of the base SDK to be linked, got %s %v", target, err)
+	}
+	if _, err := os.Stat(filepath.Join(sdk.Root, "licenses", "android-sdk-license")); err != nil {
+		t.Errorf("expected the licenses of the base SDK to be copied: %v", err)
+	}
+
+	// the links to the packages removed from the base SDK are dropped
+	if err := os.RemoveAll(filepath.Join(base, "platforms", "android-33")); err != nil {
+		t.Fatal(err)
+	}
+	if err := overlay(base, sdk.Root); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(filepath.Join(sdk.Root, "platforms", "android-33")); !os.IsNotExist(err) {
+		t.Errorf("expected the dangling link to be removed, got %v", err)
+	}
+}
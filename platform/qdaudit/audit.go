@@ -0,0 +1,196 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdaudit appends the runs of the CLI to an append-only audit log, a JSONL file or syslog.
+package qdaudit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/pflag"
+	"io"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// FileName is the name of the audit log in the Qodana system directory.
+	FileName = "audit.jsonl"
+	// FileTarget writes the audit log to FileName in the Qodana system directory.
+	FileTarget = "file"
+	// SyslogTarget writes the audit log to the system logger.
+	SyslogTarget = "syslog"
+
+	masked = "***"
+)
+
+// sensitiveFlags matches the names of the flags whose values are not logged.
+var sensitiveFlags = regexp.MustCompile(`(?i)token|password|secret|auth`)
+
+// Entry is a run of the CLI in the audit log.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	// Options are the flags of the command with a value, with the values of the sensitive ones masked.
+	Options    map[string]string `json:"options"`
+	User       string            `json:"user"`
+	Host       string            `json:"host"`
+	Project    string            `json:"project,omitempty"`
+	Revision   string            `json:"revision,omitempty"`
+	Linter     string            `json:"linter,omitempty"`
+	AnalysisId string            `json:"analysisId,omitempty"`
+	ExitCode   int               `json:"exitCode"`
+	ExitReason string            `json:"exitReason"`
+	DurationMs int64             `json:"durationMs"`
+	// Reports are the SHA-256 hashes of the files in the results directory by their names.
+	Reports map[string]string `json:"reports,omitempty"`
+}
+
+// NewEntry returns the entry of the command run now by the current user.
+func NewEntry(command string, flags *pflag.FlagSet) Entry {
+	e := Entry{Time: time.Now().UTC(), Command: command, Options: Options(flags)}
+	if current, err := user.Current(); err == nil {
+		e.User = current.Username
+	}
+	e.Host, _ = os.Hostname()
+	return e
+}
+
+// Options returns the values of the flags set explicitly or having a non-empty default, with the secrets masked:
+// the values of the token, password and authentication flags, of the environment variables passed with --env
+// and the credentials in URLs.
+func Options(flags *pflag.FlagSet) map[string]string {
+	options := map[string]string{}
+	if flags == nil {
+		return options
+	}
+	flags.VisitAll(
+		func(f *pflag.Flag) {
+			value := f.Value.String()
+			if !f.Changed && (value == "" || value == "[]" || value == "false" || value == "0") {
+				return
+			}
+			options[f.Name] = Mask(f.Name, value)
+		},
+	)
+	return options
+}
+
+// Mask returns the value of the flag name with the secrets masked, see Options.
+func Mask(name string, value string) string {
+	switch {
+	case value == "":
+		return value
+	case sensitiveFlags.MatchString(name):
+		return masked
+	case name == "env":
+		return maskEnv(value)
+	default:
+		return maskUrl(value)
+	}
+}
+
+// maskEnv masks the values of the [NAME=value,...] list of --env.
+func maskEnv(value string) string {
+	variables := strings.Split(strings.Trim(value, "[]"), ",")
+	for i, variable := range variables {
+		if name, _, found := strings.Cut(variable, "="); found {
+			variables[i] = name + "=" + masked
+		}
+	}
+	return "[" + strings.Join(variables, ",") + "]"
+}
+
+// maskUrl masks the password of a URL value, e.g. of --cache-remote.
+func maskUrl(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	return u.Redacted()
+}
+
+// HashReports returns the SHA-256 hashes of the files in dir, not in its subdirectories, by their names.
+func HashReports(dir string) map[string]string {
+	hashes := map[string]string{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return hashes
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if hash, err := hashFile(filepath.Join(dir, entry.Name())); err == nil {
+			hashes[entry.Name()] = hash
+		}
+	}
+	return hashes
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Append appends the entry to the audit log of target: FileTarget, SyslogTarget or the path of a JSONL file.
+func Append(target string, systemDir string, e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	switch target {
+	case SyslogTarget:
+		return writeSyslog(string(line))
+	case FileTarget:
+		if systemDir == "" {
+			return fmt.Errorf("the Qodana system directory is unknown")
+		}
+		target = filepath.Join(systemDir, FileName)
+	}
+	return appendLine(target, line)
+}
+
+// appendLine writes the line with a single write, so the concurrent runs don't interleave their entries.
+func appendLine(path string, line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdaudit
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/spf13/pflag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOptions(t *testing.T) {
+	flags := pflag.NewFlagSet("scan", pflag.ContinueOnError)
+	flags.String("linter", "", "")
+	flags.String("results-dir", "/tmp/results", "")
+	flags.String("cache-remote", "", "")
+	flags.String("auth", "", "")
+	flags.StringArrayP("env", "e", nil, "")
+	flags.Bool("skip-pull", false, "")
+	flags.Int("port", 0, "")
+	err := flags.Parse(
+		[]string{
+			"--linter", "jetbrains/qodana-jvm",
+			"--cache-remote", "s3://user:pass@bucket/qodana",
+			"--auth", "secret-token",
+			"-e", "A=1", "--env", "B=2",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"linter":       "jetbrains/qodana-jvm",
+		"results-dir":  "/tmp/results",
+		"cache-remote": "s3://user:xxxxx@bucket/qodana",
+		"auth":         "***",
+		"env":          "[A=***,B=***]",
+	}
+	if actual := Options(flags); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Options() = %v, want %v", actual, expected)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	systemDir := t.TempDir()
+	for i, exitCode := range []int{0, 255} {
+		e := NewEntry("qodana scan", nil)
+		e.ExitCode = exitCode
+		if err := Append(FileTarget, systemDir, e); err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+	}
+
+	f, err := os.Open(filepath.Join(systemDir, FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	var exitCodes []int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err = json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid entry %q: %v", scanner.Text(), err)
+		}
+		if e.Command != "qodana scan" || e.User == "" {
+			t.Errorf("unexpected entry %q", scanner.Text())
+		}
+		exitCodes = append(exitCodes, e.ExitCode)
+	}
+	if !reflect.DeepEqual(exitCodes, []int{0, 255}) {
+		t.Errorf("exit codes = %v, want [0 255]", exitCodes)
+	}
+}
+
+func TestHashReports(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "qodana.sarif.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "log"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"qodana.sarif.json": "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+	}
+	if actual := HashReports(dir); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("HashReports() = %v, want %v", actual, expected)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdbadge renders the status badges of the Qodana results as SVG and publishes them to a gist
+// or an object storage, so that READMEs show the status of the scans.
+package qdbadge
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+const (
+	// FileName is the name of the badge written to the results directory.
+	FileName = "qodana-badge.svg"
+
+	TypeProblems = "problems"
+	TypeGate     = "gate"
+	TypeGrade    = "grade"
+
+	colorGreen       = "#4c1"
+	colorYellowGreen = "#97ca00"
+	colorYellow      = "#dfb317"
+	colorOrange      = "#fe7d37"
+	colorRed         = "#e05d44"
+	colorGray        = "#9f9f9f"
+	labelColor       = "#555"
+	label            = "qodana"
+)
+
+// Types are the kinds of badges.
+var Types = []string{TypeProblems, TypeGate, TypeGrade}
+
+// severities are the lowercase severities from the most severe one with the grade and the color of the results
+// whose most severe problem is of that severity.
+var severities = []struct {
+	name  string
+	grade string
+	color string
+}{
+	{"critical", "F", colorRed},
+	{"high", "D", colorRed},
+	{"moderate", "C", colorOrange},
+	{"low", "B", colorYellowGreen},
+}
+
+// Badge is a badge with the label on the left and the colored message on the right.
+type Badge struct {
+	Label   string
+	Message string
+	Color   string
+}
+
+// New returns the badge of the type for the number of new problems by the lowercase severity and the quality gate
+// status, passed, failed or empty if unknown.
+func New(kind string, problems map[string]int, gate string) (Badge, error) {
+	switch kind {
+	case TypeProblems:
+		total := 0
+		for _, count := range problems {
+			total += count
+		}
+		message := fmt.Sprintf("%d problems", total)
+		switch total {
+		case 0:
+			message = "no problems"
+		case 1:
+			message = "1 problem"
+		}
+		return Badge{Label: label, Message: message, Color: worstSeverity(problems, colorGreen, true)}, nil
+	case TypeGate:
+		switch gate {
+		case "passed":
+			return Badge{Label: label, Message: "passed", Color: colorGreen}, nil
+		case "":
+			return Badge{Label: label, Message: "unknown", Color: colorGray}, nil
+		default:
+			return Badge{Label: label, Message: gate, Color: colorRed}, nil
+		}
+	case TypeGrade:
+		return Badge{Label: label, Message: worstSeverity(problems, "A", false), Color: worstSeverity(problems, colorGreen, true)}, nil
+	default:
+		return Badge{}, fmt.Errorf("unknown badge type %q, expected %s", kind, strings.Join(Types, ", "))
+	}
+}
+
+// worstSeverity returns the color or the grade of the most severe problem, def if there are only info problems or none.
+func worstSeverity(problems map[string]int, def string, color bool) string {
+	for _, severity := range severities {
+		if problems[severity.name] > 0 {
+			if color {
+				return severity.color
+			}
+			return severity.grade
+		}
+	}
+	return def
+}
+
+// SVG renders the badge in the flat style of shields.io.
+func (b Badge) SVG() []byte {
+	labelWidth := textWidth(b.Label) + 10
+	messageWidth := textWidth(b.Message) + 10
+	width := labelWidth + messageWidth
+	title := html.EscapeString(b.Label + ": " + b.Message)
+	text := func(x int, value string) string {
+		value = html.EscapeString(value)
+		return fmt.Sprintf(
+			`<text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%d" y="14">%s</text>`,
+			x, value, x, value,
+		)
+	}
+	return []byte(
+		fmt.Sprintf(
+			`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s"><title>%s</title>`+
+				`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+
+				`<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`+
+				`<g clip-path="url(#r)"><rect width="%d" height="20" fill="%s"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`+
+				`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">%s%s</g></svg>`+"\n",
+			width, title, title,
+			width,
+			labelWidth, labelColor, labelWidth, messageWidth, html.EscapeString(b.Color), width,
+			text(labelWidth/2, b.Label), text(labelWidth+messageWidth/2, b.Message),
+		),
+	)
+}
+
+// textWidth estimates the width of the text in Verdana 11px.
+func textWidth(text string) int {
+	width := 0.0
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune("iljtfrI.,:;!|' ", r):
+			width += 4
+		case strings.ContainsRune("mwMW", r):
+			width += 11
+		case r >= 'A' && r <= 'Z':
+			width += 8
+		default:
+			width += 7
+		}
+	}
+	return int(width + 0.5)
+}
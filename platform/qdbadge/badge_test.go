@@ -0,0 +1,106 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdbadge
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		kind     string
+		problems map[string]int
+		gate     string
+		expected Badge
+	}{
+		{TypeProblems, map[string]int{"high": 0, "info": 0}, "", Badge{"qodana", "no problems", colorGreen}},
+		{TypeProblems, map[string]int{"moderate": 1}, "", Badge{"qodana", "1 problem", colorOrange}},
+		{TypeProblems, map[string]int{"critical": 2, "low": 3}, "", Badge{"qodana", "5 problems", colorRed}},
+		{TypeGate, nil, "passed", Badge{"qodana", "passed", colorGreen}},
+		{TypeGate, nil, "failed", Badge{"qodana", "failed", colorRed}},
+		{TypeGate, nil, "", Badge{"qodana", "unknown", colorGray}},
+		{TypeGrade, map[string]int{"info": 4}, "", Badge{"qodana", "A", colorGreen}},
+		{TypeGrade, map[string]int{"low": 1, "info": 4}, "", Badge{"qodana", "B", colorYellowGreen}},
+		{TypeGrade, map[string]int{"high": 1, "low": 1}, "", Badge{"qodana", "D", colorRed}},
+	} {
+		badge, err := New(tc.kind, tc.problems, tc.gate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if badge != tc.expected {
+			t.Errorf("%s badge of %v, %q: expected %+v, got %+v", tc.kind, tc.problems, tc.gate, tc.expected, badge)
+		}
+	}
+	if _, err := New("coverage", nil, ""); err == nil {
+		t.Error("expected an error for the unknown badge type")
+	}
+}
+
+func TestSVG(t *testing.T) {
+	svg := string(Badge{Label: "qodana", Message: "<b>", Color: colorRed}.SVG())
+	for _, expected := range []string{`aria-label="qodana: &lt;b&gt;"`, `fill="#e05d44"`, `<text x="26" y="14">qodana</text>`} {
+		if !strings.Contains(svg, expected) {
+			t.Errorf("%s not found in\n%s", expected, svg)
+		}
+	}
+}
+
+func TestPushGist(t *testing.T) {
+	var content string
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPatch || r.URL.Path != "/gists/abc" || r.Header.Get("Authorization") != "Bearer token" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				var body struct {
+					Files map[string]struct {
+						Content string `json:"content"`
+					} `json:"files"`
+				}
+				data, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(data, &body); err != nil {
+					t.Error(err)
+				}
+				content = body.Files["status.svg"].Content
+				_, _ = w.Write([]byte(`{"owner": {"login": "octocat"}}`))
+			},
+		),
+	)
+	defer server.Close()
+	t.Setenv("QD_GITHUB_TOKEN", "token")
+	t.Setenv("GITHUB_API_URL", server.URL)
+
+	url, err := Push([]byte("<svg/>"), "gist://abc/status.svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "<svg/>" {
+		t.Errorf("unexpected gist content %q", content)
+	}
+	if url != "https://gist.githubusercontent.com/octocat/abc/raw/status.svg" {
+		t.Errorf("unexpected badge URL %s", url)
+	}
+	if _, err = Push([]byte("<svg/>"), "https://example.com/badge.svg"); err == nil {
+		t.Error("expected an error for the unsupported destination")
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdbadge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdstorage"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	gistScheme  = "gist://"
+	pushTimeout = 30 * time.Second
+)
+
+// Push publishes the badge to the destination and returns the URL to embed it with:
+// gist://<id>[/<file>] updates the file of the GitHub gist (with the token from QD_GITHUB_TOKEN or GITHUB_TOKEN),
+// s3://, gs:// and az:// locations store it as the object, or as qodana-badge.svg under the location if it's not an .svg.
+func Push(svg []byte, destination string) (string, error) {
+	switch {
+	case strings.HasPrefix(destination, gistScheme):
+		id, file, _ := strings.Cut(strings.TrimPrefix(destination, gistScheme), "/")
+		if file == "" {
+			file = FileName
+		}
+		return pushGist(svg, id, file)
+	case qdstorage.IsLocation(destination):
+		bucket, name, err := qdstorage.Open(destination)
+		if err != nil {
+			return "", err
+		}
+		if !strings.HasSuffix(name, ".svg") {
+			name = qdstorage.Join(name, FileName)
+		}
+		if err = bucket.Put(name, bytes.NewReader(svg), int64(len(svg)), "image/svg+xml"); err != nil {
+			return "", err
+		}
+		return bucket.Url(name), nil
+	default:
+		return "", fmt.Errorf("unsupported badge destination %s, use gist://<id>, s3://, gs:// or az://", destination)
+	}
+}
+
+// pushGist updates the file of the gist and returns its raw URL without the revision, so it always shows the latest badge.
+func pushGist(svg []byte, id string, file string) (string, error) {
+	token := os.Getenv("QD_GITHUB_TOKEN")
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	if token == "" {
+		return "", fmt.Errorf("set QD_GITHUB_TOKEN or GITHUB_TOKEN to update the gist")
+	}
+	apiUrl := os.Getenv("GITHUB_API_URL")
+	if apiUrl == "" {
+		apiUrl = "https://api.github.com"
+	}
+	payload, err := json.Marshal(map[string]any{"files": map[string]any{file: map[string]string{"content": string(svg)}}})
+	if err != nil {
+		return "", err
+	}
+	endpoint := strings.TrimSuffix(apiUrl, "/") + "/gists/" + id
+	req, err := http.NewRequest(http.MethodPatch, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := (&http.Client{Timeout: pushTimeout}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s responded with %s: %s", endpoint, resp.Status, strings.TrimSpace(string(data)))
+	}
+	var gist struct {
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+		Files map[string]struct {
+			RawUrl string `json:"raw_url"`
+		} `json:"files"`
+	}
+	if err = json.Unmarshal(data, &gist); err != nil {
+		return "", err
+	}
+	if gist.Owner.Login == "" {
+		return gist.Files[file].RawUrl, nil
+	}
+	return fmt.Sprintf("https://gist.githubusercontent.com/%s/%s/raw/%s", gist.Owner.Login, id, file), nil
+}
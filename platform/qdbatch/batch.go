@@ -0,0 +1,215 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdbatch scans several projects listed in a file in one invocation and summarizes their results.
+package qdbatch
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Severities are the severities of the problems counted by the batch, from the highest.
+var Severities = []string{"critical", "high", "moderate", "low", "info"}
+
+// Project is a project of the batch.
+type Project struct {
+	// Name is unique within the batch and names the results directory of the project.
+	Name string
+	Dir  string
+	// Url is the repository the project is cloned from, empty for local projects.
+	Url string
+}
+
+// uniqueName returns the name made safe for a directory name, with a suffix if it was already taken.
+func uniqueName(names map[string]int, name string) string {
+	name = unsafeNameChars.ReplaceAllString(name, "_")
+	names[name]++
+	if names[name] > 1 {
+		name = fmt.Sprintf("%s-%d", name, names[name])
+	}
+	return name
+}
+
+// ReadProjects reads the project directories listed one per line in path, relative ones are resolved against
+// the directory of the file. Empty lines and lines starting with # are skipped.
+func ReadProjects(path string) ([]Project, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	base, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var projects []Project
+	names := map[string]int{}
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		dir := filepath.FromSlash(line)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(base, dir)
+		}
+		dir = filepath.Clean(dir)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("%s listed in %s is not a directory", line, path)
+		}
+		projects = append(projects, Project{Name: uniqueName(names, filepath.Base(dir)), Dir: dir})
+	}
+	return projects, scanner.Err()
+}
+
+// Args returns the flags set in the command line, except for the skipped ones, as the arguments of the scans.
+func Args(flags *pflag.FlagSet, skip ...string) []string {
+	var args []string
+	flags.Visit(
+		func(flag *pflag.Flag) {
+			if slices.Contains(skip, flag.Name) {
+				return
+			}
+			if values, ok := flag.Value.(pflag.SliceValue); ok {
+				for _, value := range values.GetSlice() {
+					args = append(args, fmt.Sprintf("--%s=%s", flag.Name, value))
+				}
+				return
+			}
+			args = append(args, fmt.Sprintf("--%s=%s", flag.Name, flag.Value.String()))
+		},
+	)
+	return args
+}
+
+// Result is the result of the scan of a project.
+type Result struct {
+	Project    Project
+	ResultsDir string
+	ExitCode   int
+	// Error is why the project couldn't be scanned, e.g. a failed clone.
+	Error string
+	// TimedOut is the timeout reached by the scan, if any.
+	TimedOut string
+	// Problems is the number of new problems found, -1 if the scan produced no report.
+	Problems int
+	// BySeverity is the number of new problems by the lowercase severity.
+	BySeverity map[string]int
+	Duration   time.Duration
+	// ReportUrl is the report uploaded to Qodana Cloud, if any.
+	ReportUrl string
+}
+
+// SetProblems sets the new problems found by the lowercase severity.
+func (r *Result) SetProblems(bySeverity map[string]int) {
+	r.BySeverity = bySeverity
+	r.Problems = 0
+	for _, count := range bySeverity {
+		r.Problems += count
+	}
+}
+
+// Status describes how the scan finished.
+func (r Result) Status() string {
+	switch {
+	case r.Error != "":
+		return r.Error
+	case r.TimedOut != "":
+		return fmt.Sprintf("%s timeout", r.TimedOut)
+	case r.ExitCode == utils.QodanaSuccessExitCode:
+		return "passed"
+	case r.ExitCode == utils.QodanaFailThresholdExitCode:
+		return "fail threshold"
+	default:
+		return fmt.Sprintf("failed (exit code %d)", r.ExitCode)
+	}
+}
+
+// ExitCode returns the exit code of the first failed scan, otherwise QodanaFailThresholdExitCode
+// if a scan exceeded the fail threshold.
+func ExitCode(results []Result) int {
+	exitCode := utils.QodanaSuccessExitCode
+	for _, r := range results {
+		switch r.ExitCode {
+		case utils.QodanaSuccessExitCode:
+		case utils.QodanaFailThresholdExitCode:
+			exitCode = utils.QodanaFailThresholdExitCode
+		default:
+			return r.ExitCode
+		}
+	}
+	return exitCode
+}
+
+// Summary returns the table summarizing the scans of the batch.
+func Summary(results []Result) pterm.TableData {
+	tableData := pterm.TableData{
+		[]string{
+			msg.PrimaryBold("Project"),
+			msg.PrimaryBold("Result"),
+			msg.PrimaryBold("Problems"),
+			msg.PrimaryBold("Time"),
+			msg.PrimaryBold("Results"),
+		},
+	}
+	for _, r := range results {
+		problems := "-"
+		if r.Problems >= 0 {
+			problems = strconv.Itoa(r.Problems)
+		}
+		tableData = append(
+			tableData,
+			[]string{r.Project.Name, r.Status(), problems, r.Duration.Round(time.Second).String(), r.ResultsDir},
+		)
+	}
+	return tableData
+}
+
+// PrintSummary prints the table summarizing the scans of the batch.
+func PrintSummary(results []Result) {
+	msg.EmptyMessage()
+	table := pterm.DefaultTable.WithData(Summary(results))
+	table.HeaderRowSeparator = ""
+	table.Separator = " "
+	table.Boxed = true
+	if err := table.Render(); err != nil {
+		log.Debugf("Failed to print the summary of the scans: %v", err)
+	}
+}
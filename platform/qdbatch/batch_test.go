@@ -0,0 +1,116 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdbatch
+
+import (
+	"github.com/spf13/pflag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadProjects(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"services/api", "libs/api", "web app"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list := filepath.Join(root, "projects.txt")
+	content := "# org audit\nservices/api\n\n  libs/api  \n" + filepath.Join(root, "web app") + "\nservices/api\n"
+	if err := os.WriteFile(list, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	projects, err := ReadProjects(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Project{
+		{Name: "api", Dir: filepath.Join(root, "services", "api")},
+		{Name: "api-2", Dir: filepath.Join(root, "libs", "api")},
+		{Name: "web_app", Dir: filepath.Join(root, "web app")},
+	}
+	if !reflect.DeepEqual(projects, expected) {
+		t.Errorf("ReadProjects() = %v, want %v", projects, expected)
+	}
+
+	if err = os.WriteFile(list, []byte("missing\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ReadProjects(list); err == nil {
+		t.Error("expected an error for a missing project directory")
+	}
+}
+
+func TestArgs(t *testing.T) {
+	flags := pflag.NewFlagSet("scan", pflag.ContinueOnError)
+	flags.StringP("linter", "l", "", "")
+	flags.String("results-dir", "", "")
+	flags.StringArrayP("env", "e", nil, "")
+	flags.Bool("apply-fixes", false, "")
+	flags.Int("port", 8080, "")
+	err := flags.Parse([]string{"-l", "jetbrains/qodana-jvm", "--results-dir", "out", "-e", "A=1", "-e", "B=2", "--apply-fixes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"--apply-fixes=true", "--env=A=1", "--env=B=2", "--linter=jetbrains/qodana-jvm"}
+	if actual := Args(flags, "results-dir"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Args() = %v, want %v", actual, expected)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		exitCodes []int
+		expected  int
+	}{
+		{name: "all passed", exitCodes: []int{0, 0}, expected: 0},
+		{name: "fail threshold", exitCodes: []int{0, 255, 0}, expected: 255},
+		{name: "first failure", exitCodes: []int{255, 1, 137}, expected: 1},
+	} {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				var results []Result
+				for _, exitCode := range tt.exitCodes {
+					results = append(results, Result{ExitCode: exitCode})
+				}
+				if actual := ExitCode(results); actual != tt.expected {
+					t.Errorf("ExitCode() = %d, want %d", actual, tt.expected)
+				}
+			},
+		)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	for _, tt := range []struct {
+		result   Result
+		expected string
+	}{
+		{result: Result{}, expected: "passed"},
+		{result: Result{ExitCode: 255}, expected: "fail threshold"},
+		{result: Result{ExitCode: 3}, expected: "failed (exit code 3)"},
+		{result: Result{ExitCode: 1, TimedOut: "inspection"}, expected: "inspection timeout"},
+	} {
+		if actual := tt.result.Status(); actual != tt.expected {
+			t.Errorf("Status() of %+v = %q, want %q", tt.result, actual, tt.expected)
+		}
+	}
+}
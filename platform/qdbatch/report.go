@@ -0,0 +1,132 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdbatch
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	csvReportName  = "summary.csv"
+	jsonReportName = "summary.json"
+)
+
+// record is the row of the consolidated report of a scan.
+type record struct {
+	Name       string         `json:"name"`
+	Url        string         `json:"url,omitempty"`
+	Status     string         `json:"status"`
+	ExitCode   int            `json:"exitCode"`
+	Problems   *int           `json:"problems"`
+	BySeverity map[string]int `json:"problemsBySeverity,omitempty"`
+	DurationMs int64          `json:"durationMs"`
+	ReportUrl  string         `json:"reportUrl,omitempty"`
+	ResultsDir string         `json:"resultsDir"`
+}
+
+func newRecord(r Result) record {
+	rec := record{
+		Name:       r.Project.Name,
+		Url:        r.Project.Url,
+		Status:     r.Status(),
+		ExitCode:   r.ExitCode,
+		BySeverity: r.BySeverity,
+		DurationMs: r.Duration.Milliseconds(),
+		ReportUrl:  r.ReportUrl,
+		ResultsDir: r.ResultsDir,
+	}
+	if r.Problems >= 0 {
+		problems := r.Problems
+		rec.Problems = &problems
+	}
+	return rec
+}
+
+// WriteCsv writes the problem counts of the scans as CSV, one row per project.
+func WriteCsv(w io.Writer, results []Result) error {
+	writer := csv.NewWriter(w)
+	header := []string{"name", "url", "status", "exit_code", "problems"}
+	header = append(header, Severities...)
+	header = append(header, "duration_ms", "report_url", "results_dir")
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, r := range results {
+		rec := newRecord(r)
+		problems := ""
+		if rec.Problems != nil {
+			problems = strconv.Itoa(*rec.Problems)
+		}
+		row := []string{rec.Name, rec.Url, rec.Status, strconv.Itoa(rec.ExitCode), problems}
+		for _, severity := range Severities {
+			count := ""
+			if rec.Problems != nil {
+				count = strconv.Itoa(rec.BySeverity[severity])
+			}
+			row = append(row, count)
+		}
+		row = append(row, strconv.FormatInt(rec.DurationMs, 10), rec.ReportUrl, rec.ResultsDir)
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJson writes the problem counts of the scans as a JSON array, one object per project.
+func WriteJson(w io.Writer, results []Result) error {
+	records := make([]record, 0, len(results))
+	for _, r := range results {
+		records = append(records, newRecord(r))
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+// WriteReports writes summary.csv and summary.json of the scans to dir and returns their paths.
+func WriteReports(dir string, results []Result) ([]string, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, report := range []struct {
+		name  string
+		write func(io.Writer, []Result) error
+	}{{csvReportName, WriteCsv}, {jsonReportName, WriteJson}} {
+		path := filepath.Join(dir, report.name)
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		err = report.write(file, results)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
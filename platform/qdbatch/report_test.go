@@ -0,0 +1,92 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdbatch
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func batchResults() []Result {
+	scanned := Result{
+		Project:    Project{Name: "service", Url: "https://github.com/org/service.git"},
+		ResultsDir: "out/results/service",
+		ExitCode:   255,
+		Duration:   1500 * time.Millisecond,
+		ReportUrl:  "https://qodana.cloud/report",
+	}
+	scanned.SetProblems(map[string]int{"critical": 1, "high": 2, "moderate": 0, "low": 0, "info": 4})
+	return []Result{
+		scanned,
+		{Project: Project{Name: "web", Url: "git@github.com:org/web.git"}, ResultsDir: "out/results/web", ExitCode: 1, Error: "clone failed", Problems: -1},
+	}
+}
+
+func TestWriteCsv(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteCsv(&out, batchResults()); err != nil {
+		t.Fatal(err)
+	}
+	expected := `name,url,status,exit_code,problems,critical,high,moderate,low,info,duration_ms,report_url,results_dir
+service,https://github.com/org/service.git,fail threshold,255,7,1,2,0,0,4,1500,https://qodana.cloud/report,out/results/service
+web,git@github.com:org/web.git,clone failed,1,,,,,,,0,,out/results/web
+`
+	if out.String() != expected {
+		t.Errorf("WriteCsv() =\n%s\nwant\n%s", out.String(), expected)
+	}
+}
+
+func TestWriteJson(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteJson(&out, batchResults()[1:]); err != nil {
+		t.Fatal(err)
+	}
+	expected := `[
+  {
+    "name": "web",
+    "url": "git@github.com:org/web.git",
+    "status": "clone failed",
+    "exitCode": 1,
+    "problems": null,
+    "durationMs": 0,
+    "resultsDir": "out/results/web"
+  }
+]
+`
+	if out.String() != expected {
+		t.Errorf("WriteJson() =\n%s\nwant\n%s", out.String(), expected)
+	}
+}
+
+func TestWriteReports(t *testing.T) {
+	dir := t.TempDir()
+	paths, err := WriteReports(dir, batchResults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 || paths[0] != filepath.Join(dir, "summary.csv") || paths[1] != filepath.Join(dir, "summary.json") {
+		t.Errorf("unexpected reports %v", paths)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdbatch
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strings"
+)
+
+// Repos is the list of the repositories scanned by qodana batch.
+type Repos struct {
+	// Args are the qodana scan options of all the repositories.
+	Args  []string `yaml:"args,omitempty"`
+	Repos []Repo   `yaml:"repos"`
+}
+
+// Repo is a repository scanned by qodana batch.
+type Repo struct {
+	Url string `yaml:"url"`
+	// Name of the directories of the repository, derived from the URL if not set.
+	Name string `yaml:"name,omitempty"`
+	// Branch to scan, the default branch of the repository if not set.
+	Branch string `yaml:"branch,omitempty"`
+	// Args are the qodana scan options of the repository, added after the common ones.
+	Args []string `yaml:"args,omitempty"`
+}
+
+// LoadRepos reads the repositories to scan from the YAML file at path.
+func LoadRepos(path string) (Repos, error) {
+	var repos Repos
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return repos, err
+	}
+	if err = yaml.Unmarshal(data, &repos); err != nil {
+		return repos, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	names := map[string]int{}
+	for i, repo := range repos.Repos {
+		if repo.Url == "" {
+			return repos, fmt.Errorf("repository %d in %s has no url", i+1, path)
+		}
+		name := repo.Name
+		if name == "" {
+			name = repoName(repo.Url)
+		}
+		repos.Repos[i].Name = uniqueName(names, name)
+	}
+	return repos, nil
+}
+
+// repoName returns the last part of the repository URL without .git, e.g. service of git@github.com:org/service.git.
+func repoName(url string) string {
+	url = strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	if i := strings.LastIndexAny(url, "/:"); i >= 0 {
+		url = url[i+1:]
+	}
+	if url == "" {
+		return "repository"
+	}
+	return url
+}
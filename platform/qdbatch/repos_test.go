@@ -0,0 +1,76 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdbatch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadRepos(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repos.yaml")
+	content := `args: [--fail-threshold, "100"]
+repos:
+  - url: https://github.com/org/service.git
+    branch: main
+  - url: git@github.com:other/service.git
+    args: [--linter, jetbrains/qodana-js]
+  - url: https://gitlab.example.com/group/web/
+    name: frontend
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repos, err := LoadRepos(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Repos{
+		Args: []string{"--fail-threshold", "100"},
+		Repos: []Repo{
+			{Url: "https://github.com/org/service.git", Name: "service", Branch: "main"},
+			{Url: "git@github.com:other/service.git", Name: "service-2", Args: []string{"--linter", "jetbrains/qodana-js"}},
+			{Url: "https://gitlab.example.com/group/web/", Name: "frontend"},
+		},
+	}
+	if !reflect.DeepEqual(repos, expected) {
+		t.Errorf("LoadRepos() = %+v, want %+v", repos, expected)
+	}
+
+	if err = os.WriteFile(path, []byte("repos:\n  - branch: main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadRepos(path); err == nil {
+		t.Error("expected an error for a repository without url")
+	}
+}
+
+func TestRepoName(t *testing.T) {
+	for url, expected := range map[string]string{
+		"https://github.com/org/service.git": "service",
+		"git@github.com:org/web.git":         "web",
+		"https://example.com/group/app/":     "app",
+		"../local":                           "local",
+		"/":                                  "repository",
+	} {
+		if actual := repoName(url); actual != expected {
+			t.Errorf("repoName(%q) = %q, want %q", url, actual, expected)
+		}
+	}
+}
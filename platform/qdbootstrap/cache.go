@@ -0,0 +1,213 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdbootstrap keeps the results of the bootstrap command in the Qodana cache, addressed by the hash
+// of the command and of its input files, so the bootstrap is skipped when nothing it depends on has changed.
+package qdbootstrap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcache"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	cacheDirName = "bootstrap"
+	markerName   = "complete"
+	// keepEntries is the number of the most recent bootstrap results kept in the cache.
+	keepEntries = 3
+)
+
+// Key returns the content address of the bootstrap: the hash of the command and of the files matching
+// the inputs, glob patterns (see filepath.Match) relative to projectDir.
+func Key(projectDir string, command string, inputs []string) (string, error) {
+	files := map[string]bool{}
+	for _, pattern := range inputs {
+		matches, err := filepath.Glob(filepath.Join(projectDir, filepath.FromSlash(pattern)))
+		if err != nil {
+			return "", fmt.Errorf("invalid bootstrap input %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			files[match] = true
+		}
+	}
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\x00", command)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		if info.IsDir() {
+			continue
+		}
+		rel, err := filepath.Rel(projectDir, path)
+		if err != nil {
+			return "", err
+		}
+		_, _ = fmt.Fprintf(h, "%s\x00%d\x00", filepath.ToSlash(rel), info.Size())
+		if err = hashFile(h, path); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Cache keeps the outputs of the bootstrap runs in the Qodana cache directory by key.
+type Cache struct {
+	dir string
+}
+
+// NewCache returns the bootstrap cache of the Qodana cache directory.
+func NewCache(cacheDir string) *Cache {
+	return &Cache{dir: filepath.Join(cacheDir, cacheDirName)}
+}
+
+// Restore replaces the outputs, directories relative to projectDir, with the ones kept by the bootstrap run
+// for key. It returns false if there was no such run.
+func (c *Cache) Restore(key string, projectDir string, outputs []string) (bool, error) {
+	entry := filepath.Join(c.dir, key)
+	if _, err := os.Stat(filepath.Join(entry, markerName)); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, output := range outputs {
+		if !filepath.IsLocal(output) {
+			return false, fmt.Errorf("bootstrap output %s is outside the project", output)
+		}
+		archive, err := os.Open(filepath.Join(entry, archiveName(output)))
+		if os.IsNotExist(err) {
+			continue // the bootstrap didn't create it
+		}
+		if err != nil {
+			return false, err
+		}
+		target := filepath.Join(projectDir, output)
+		if err = os.RemoveAll(target); err == nil {
+			err = qdcache.Extract(archive, target)
+		}
+		_ = archive.Close()
+		if err != nil {
+			return false, fmt.Errorf("failed to restore %s: %w", output, err)
+		}
+	}
+	now := time.Now()
+	_ = os.Chtimes(entry, now, now)
+	return true, nil
+}
+
+// Save keeps the outputs, directories relative to projectDir, of the bootstrap run for key,
+// dropping all but the most recent runs.
+func (c *Cache) Save(key string, projectDir string, outputs []string) error {
+	entry := filepath.Join(c.dir, key)
+	tmp := entry + ".tmp"
+	_ = os.RemoveAll(tmp)
+	if err := os.MkdirAll(tmp, os.ModePerm); err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+	for _, output := range outputs {
+		if !filepath.IsLocal(output) {
+			return fmt.Errorf("bootstrap output %s is outside the project", output)
+		}
+		source := filepath.Join(projectDir, output)
+		if _, err := os.Stat(source); os.IsNotExist(err) {
+			continue
+		}
+		if err := archiveDir(source, filepath.Join(tmp, archiveName(output))); err != nil {
+			return fmt.Errorf("failed to cache %s: %w", output, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tmp, markerName), []byte(time.Now().UTC().Format(time.RFC3339)), 0o644); err != nil {
+		return err
+	}
+	_ = os.RemoveAll(entry)
+	if err := os.Rename(tmp, entry); err != nil {
+		return err
+	}
+	c.prune()
+	return nil
+}
+
+func archiveDir(dir string, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = qdcache.Archive(f, dir); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// prune removes all but the keepEntries most recently used bootstrap results.
+func (c *Cache) prune() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	type result struct {
+		path     string
+		modified time.Time
+	}
+	var results []result
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !e.IsDir() || filepath.Ext(e.Name()) == ".tmp" {
+			continue
+		}
+		results = append(results, result{filepath.Join(c.dir, e.Name()), info.ModTime()})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].modified.After(results[j].modified) })
+	for i := keepEntries; i < len(results); i++ {
+		_ = os.RemoveAll(results[i].path)
+	}
+}
+
+// archiveName returns the name of the archive of the output in a cache entry.
+func archiveName(output string) string {
+	sum := sha256.Sum256([]byte(filepath.ToSlash(filepath.Clean(output))))
+	return hex.EncodeToString(sum[:8]) + ".tar.gz"
+}
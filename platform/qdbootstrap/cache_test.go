@@ -0,0 +1,120 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdbootstrap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, "package-lock.json"), "v1")
+	writeFile(t, filepath.Join(project, "src", "index.js"), "code")
+
+	key := func(command string, inputs ...string) string {
+		k, err := Key(project, command, inputs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	base := key("npm ci", "package-lock.json")
+	if key("npm ci", "package-lock.json") != base {
+		t.Error("expected the same key for the same inputs")
+	}
+	if key("npm ci", "package-lock.json", "*.lock") != base {
+		t.Error("expected unmatched patterns not to change the key")
+	}
+	if key("npm install", "package-lock.json") == base {
+		t.Error("expected the command to change the key")
+	}
+	writeFile(t, filepath.Join(project, "src", "index.js"), "changed")
+	if key("npm ci", "package-lock.json") != base {
+		t.Error("expected files outside the inputs not to change the key")
+	}
+	writeFile(t, filepath.Join(project, "package-lock.json"), "v2")
+	if key("npm ci", "package-lock.json") == base {
+		t.Error("expected a changed input to change the key")
+	}
+}
+
+func TestSaveRestore(t *testing.T) {
+	cache := NewCache(t.TempDir())
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, "node_modules", "lib", "index.js"), "lib")
+
+	if restored, err := cache.Restore("key", project, []string{"node_modules"}); err != nil || restored {
+		t.Fatalf("expected nothing to restore before saving, got %v %v", restored, err)
+	}
+	if err := cache.Save("key", project, []string{"node_modules", "missing"}); err != nil {
+		t.Fatal(err)
+	}
+
+	target := t.TempDir()
+	writeFile(t, filepath.Join(target, "node_modules", "stale.js"), "stale")
+	restored, err := cache.Restore("key", target, []string{"node_modules", "missing"})
+	if err != nil || !restored {
+		t.Fatalf("expected the outputs to be restored, got %v %v", restored, err)
+	}
+	if content, err := os.ReadFile(filepath.Join(target, "node_modules", "lib", "index.js")); err != nil || string(content) != "lib" {
+		t.Errorf("unexpected restored output %q %v", content, err)
+	}
+	if _, err = os.Stat(filepath.Join(target, "node_modules", "stale.js")); !os.IsNotExist(err) {
+		t.Error("expected the stale output to be replaced")
+	}
+
+	if err = cache.Save("escape", project, []string{"../outside"}); err == nil {
+		t.Error("expected outputs outside the project to be rejected")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	cache := NewCache(t.TempDir())
+	project := t.TempDir()
+	keys := []string{"a", "b", "c", "d", "e"}
+	for i, key := range keys {
+		if err := cache.Save(key, project, nil); err != nil {
+			t.Fatal(err)
+		}
+		used := time.Now().Add(time.Duration(i-len(keys)) * time.Hour)
+		if err := os.Chtimes(filepath.Join(cache.dir, key), used, used); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache.prune()
+	for i, key := range keys {
+		restored, err := cache.Restore(key, project, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := i >= len(keys)-keepEntries; restored != expected {
+			t.Errorf("expected %s to be kept: %v, got %v", key, expected, restored)
+		}
+	}
+}
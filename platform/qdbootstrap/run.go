@@ -0,0 +1,204 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdbootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"time"
+)
+
+// logDirName is the directory of the step logs in the log directory.
+const logDirName = "bootstrap"
+
+// waitDelay is how long a timed out step is waited for after it is killed, e.g. for its children holding the output.
+const waitDelay = 10 * time.Second
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// StepError reports the bootstrap step that failed.
+type StepError struct {
+	Step     string
+	ExitCode int
+	TimedOut bool
+	// BootstrapTimeout is the timeout of the whole bootstrap if it was reached during the step.
+	BootstrapTimeout time.Duration
+	Log              string
+	Err              error
+}
+
+func (e *StepError) Error() string {
+	switch {
+	case e.BootstrapTimeout > 0:
+		return fmt.Sprintf("bootstrap reached timeout %s in step %q, see %s", e.BootstrapTimeout, e.Step, e.Log)
+	case e.TimedOut:
+		return fmt.Sprintf("bootstrap step %q timed out, see %s", e.Step, e.Log)
+	case e.Err != nil:
+		return fmt.Sprintf("bootstrap step %q failed: %v, see %s", e.Step, e.Err, e.Log)
+	default:
+		return fmt.Sprintf("bootstrap step %q exited with code %d, see %s", e.Step, e.ExitCode, e.Log)
+	}
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// Bootstrap runs the bootstrap of qodana.yaml and exits if it fails, like utils.Bootstrap does for a single command.
+// If the whole bootstrap doesn't finish within timeout (0 – no limit), a *qdtrace.TimeoutError is returned instead,
+// and if ctx is cancelled, the error of ctx is returned.
+func Bootstrap(ctx context.Context, bootstrap qdyaml.Bootstrap, projectDir string, logDir string, timeout time.Duration) error {
+	if command, ok := bootstrap.Command(); ok && timeout <= 0 {
+		utils.Bootstrap(ctx, command, projectDir)
+		return ctx.Err()
+	}
+	err := RunWithTimeout(ctx, bootstrap, projectDir, logDir, timeout)
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	var stepErr *StepError
+	if errors.As(err, &stepErr) && stepErr.BootstrapTimeout > 0 {
+		msg.ErrorMessage("%s", stepErr)
+		return &qdtrace.TimeoutError{Stage: "bootstrap", Timeout: stepErr.BootstrapTimeout}
+	}
+	if errors.As(err, &stepErr) {
+		msg.ErrorMessage("%s", stepErr)
+		exitCode := stepErr.ExitCode
+		if exitCode <= 0 {
+			exitCode = 1
+		}
+		os.Exit(exitCode)
+	}
+	if err != nil {
+		log.Fatalf("Failed to run the bootstrap: %s", err)
+	}
+	return nil
+}
+
+// Run runs the steps of the bootstrap one after another in projectDir, writing the output of each step
+// to its own file in logDir/bootstrap as well, and stops at the first step that fails with a *StepError.
+// The running step is stopped when ctx is done.
+func Run(ctx context.Context, bootstrap qdyaml.Bootstrap, projectDir string, logDir string) error {
+	return RunWithTimeout(ctx, bootstrap, projectDir, logDir, 0)
+}
+
+// RunWithTimeout is Run stopping the bootstrap when all its steps together take longer than timeout (0 – no limit),
+// each step runs at most for the time left.
+func RunWithTimeout(ctx context.Context, bootstrap qdyaml.Bootstrap, projectDir string, logDir string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	dir := filepath.Join(logDir, logDirName)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	for i, step := range bootstrap {
+		name := step.Name
+		if name == "" {
+			name = fmt.Sprintf("step %d", i+1)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		logPath := filepath.Join(dir, fmt.Sprintf("%02d-%s.log", i+1, unsafeFileNameChars.ReplaceAllString(name, "_")))
+		msg.SuccessMessage("Running the bootstrap step %s", msg.PrimaryBold(name))
+		start := time.Now()
+		limited := false
+		if timeout > 0 {
+			left := time.Until(deadline)
+			if left <= 0 {
+				return &StepError{Step: name, TimedOut: true, BootstrapTimeout: timeout, Log: dir}
+			}
+			if step.Timeout <= 0 || left < step.Timeout {
+				step.Timeout, limited = left, true
+			}
+		}
+		if err := runStep(ctx, step, projectDir, logPath); err != nil {
+			err.Step = name
+			if err.TimedOut && limited {
+				err.BootstrapTimeout = timeout
+			}
+			return err
+		}
+		log.Debugf("Bootstrap step %s finished in %s", name, time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+func runStep(ctx context.Context, step qdyaml.BootstrapStep, projectDir string, logPath string) *StepError {
+	stepErr := &StepError{Log: logPath}
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		stepErr.Err = err
+		return stepErr
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(logFile)
+
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, step.Timeout)
+		defer cancel()
+	}
+	var cmd *exec.Cmd
+	if //goland:noinspection GoBoolExpressions
+	runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/c", step.Run)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", step.Run)
+	}
+	cmd.Dir = filepath.Join(projectDir, filepath.FromSlash(step.WorkingDir))
+	cmd.Env = os.Environ()
+	for key, value := range step.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	cmd.Stdout = io.MultiWriter(os.Stdout, logFile)
+	cmd.Stderr = io.MultiWriter(os.Stderr, logFile)
+	cmd.WaitDelay = waitDelay
+	killProcessGroup(cmd)
+
+	err = cmd.Run()
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		stepErr.TimedOut = true
+		return stepErr
+	case context.Canceled:
+		stepErr.Err = ctx.Err()
+		return stepErr
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		stepErr.ExitCode = exitErr.ExitCode()
+		return stepErr
+	}
+	if err != nil {
+		stepErr.Err = err
+		return stepErr
+	}
+	return nil
+}
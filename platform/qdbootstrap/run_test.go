@@ -0,0 +1,140 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdbootstrap
+
+import (
+	"context"
+	"errors"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the steps are sh commands")
+	}
+	project := t.TempDir()
+	logDir := t.TempDir()
+	writeFile(t, filepath.Join(project, "packages", "api", "marker"), "")
+
+	err := Run(
+		context.Background(),
+		qdyaml.Bootstrap{
+			{Name: "env", Run: "echo $GREETING > greeting.txt", Env: map[string]string{"GREETING": "hello"}},
+			{Run: "ls > listing.txt", WorkingDir: "packages/api"},
+		},
+		project,
+		logDir,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(project, "greeting.txt")); strings.TrimSpace(string(content)) != "hello" {
+		t.Errorf("expected the env of the step to be set, got %q", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(project, "packages", "api", "listing.txt")); !strings.Contains(string(content), "marker") {
+		t.Errorf("expected the step to run in its working dir, got %q", content)
+	}
+
+	err = Run(
+		context.Background(),
+		qdyaml.Bootstrap{
+			{Name: "ok", Run: "echo fine"},
+			{Name: "broken step", Run: "echo failing && exit 3"},
+			{Name: "skipped", Run: "touch skipped"},
+		},
+		project,
+		logDir,
+	)
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) || stepErr.Step != "broken step" || stepErr.ExitCode != 3 {
+		t.Fatalf("expected the broken step to fail with 3, got %v", err)
+	}
+	if content, _ := os.ReadFile(stepErr.Log); strings.TrimSpace(string(content)) != "failing" {
+		t.Errorf("expected the output of the step in %s, got %q", stepErr.Log, content)
+	}
+	if filepath.Base(stepErr.Log) != "02-broken_step.log" {
+		t.Errorf("unexpected log file %s", stepErr.Log)
+	}
+	if _, err = os.Stat(filepath.Join(project, "skipped")); !os.IsNotExist(err) {
+		t.Error("expected the steps after the failed one to be skipped")
+	}
+
+	err = Run(context.Background(), qdyaml.Bootstrap{{Name: "slow", Run: "sleep 10", Timeout: 100 * time.Millisecond}}, project, logDir)
+	if !errors.As(err, &stepErr) || !stepErr.TimedOut {
+		t.Fatalf("expected the slow step to time out, got %v", err)
+	}
+}
+
+func TestRunWithTimeout(t *testing.T) {
+	project := t.TempDir()
+	logDir := t.TempDir()
+	bootstrap := qdyaml.Bootstrap{
+		{Name: "slow", Run: "sleep 10", Timeout: time.Minute},
+		{Name: "skipped", Run: "touch skipped"},
+	}
+	start := time.Now()
+	err := RunWithTimeout(context.Background(), bootstrap, project, logDir, 200*time.Millisecond)
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) || stepErr.BootstrapTimeout != 200*time.Millisecond || stepErr.Step != "slow" {
+		t.Fatalf("expected the bootstrap to time out in the slow step, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the slow step to be stopped at the bootstrap timeout, it ran for %s", elapsed)
+	}
+
+	err = RunWithTimeout(
+		context.Background(),
+		qdyaml.Bootstrap{{Name: "slow", Run: "sleep 10", Timeout: 100 * time.Millisecond}},
+		project,
+		logDir,
+		time.Minute,
+	)
+	if !errors.As(err, &stepErr) || !stepErr.TimedOut || stepErr.BootstrapTimeout != 0 {
+		t.Errorf("expected the step timeout to be reached before the bootstrap one, got %v", err)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the steps are sh commands")
+	}
+	project := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	err := Run(
+		ctx,
+		qdyaml.Bootstrap{{Name: "slow", Run: "sleep 10"}, {Name: "skipped", Run: "touch skipped"}},
+		project,
+		t.TempDir(),
+	)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the bootstrap to be cancelled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the slow step to be stopped, it ran for %s", elapsed)
+	}
+	if _, err = os.Stat(filepath.Join(project, "skipped")); !os.IsNotExist(err) {
+		t.Error("expected the steps after the cancelled one to be skipped")
+	}
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcache
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdstorage"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtemp"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is a cache archive stored in a backend.
+type Entry struct {
+	Key      string    `json:"key"`
+	Modified time.Time `json:"modified"`
+}
+
+// Backend stores the cache archives by key.
+type Backend interface {
+	// Get opens the archive stored under key, ok is false if there is no such archive.
+	Get(key string) (r io.ReadCloser, ok bool, err error)
+	// Put stores the archive of size bytes read from r under key, replacing the existing one.
+	Put(key string, r io.Reader, size int64) error
+	// List returns the archives with keys starting with prefix.
+	List(prefix string) ([]Entry, error)
+}
+
+// NewBackend returns the backend for location:
+// http(s)://host[:port] for qodana cache serve, s3://bucket[/prefix], gs://bucket[/prefix], az://container[/prefix],
+// or file:///path and plain paths for a local (or mounted) directory.
+func NewBackend(location string) (Backend, error) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 { // single letter schemes are Windows drives
+		return &dirBackend{dir: location}, nil
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &httpBackend{base: u}, nil
+	case "file":
+		return &dirBackend{dir: u.Path}, nil
+	case "s3", "gs", "az":
+		bucket, prefix, err := qdstorage.Open(location)
+		if err != nil {
+			return nil, err
+		}
+		return &objectBackend{bucket: bucket, prefix: prefix}, nil
+	default:
+		return nil, fmt.Errorf("unsupported cache location %s, use http(s)://, s3://, gs://, az:// or a directory", location)
+	}
+}
+
+// Restore extracts into dir the archive stored under key or, if there is none, the most recent archive
+// with a key starting with one of restoreKeys, tried in order, like actions/cache does.
+// It returns the key of the restored archive, empty if nothing was restored.
+func Restore(backend Backend, key string, restoreKeys []string, dir string) (string, error) {
+	if !ValidKey(key) {
+		return "", fmt.Errorf("invalid cache key %q", key)
+	}
+	r, ok, err := backend.Get(key)
+	if err != nil {
+		return "", err
+	}
+	matched := key
+	for _, prefix := range restoreKeys {
+		if ok {
+			break
+		}
+		entries, err := backend.List(prefix)
+		if err != nil {
+			return "", err
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Modified.After(entries[j].Modified) })
+		matched = entries[0].Key
+		if r, ok, err = backend.Get(matched); err != nil {
+			return "", err
+		}
+	}
+	if !ok {
+		return "", nil
+	}
+	defer func(r io.ReadCloser) {
+		_ = r.Close()
+	}(r)
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	if err = Extract(r, dir); err != nil {
+		return "", fmt.Errorf("failed to extract the cache %s: %w", matched, err)
+	}
+	return matched, nil
+}
+
+// Save archives dir and stores it under key. The archive is written to a temporary file first,
+// as object storages need to know the size of the upload.
+func Save(backend Backend, key string, dir string) error {
+	if !ValidKey(key) {
+		return fmt.Errorf("invalid cache key %q", key)
+	}
+	tmp, err := qdtemp.CreateTemp("qodana-cache-*" + archiveExtension)
+	if err != nil {
+		return err
+	}
+	defer func(name string) {
+		_ = os.Remove(name)
+	}(tmp.Name())
+	defer func(tmp *os.File) {
+		_ = tmp.Close()
+	}(tmp)
+	if err = Archive(tmp, dir); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", dir, err)
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return backend.Put(key, tmp, size)
+}
+
+// FallbackKeys returns the restore keys derived from key by dropping its dash-separated segments from the end,
+// e.g. qodana-jvm-main-1a2b gives qodana-jvm-main-, qodana-jvm- and qodana-.
+func FallbackKeys(key string) []string {
+	var keys []string
+	for i := strings.LastIndex(key, "-"); i > 0; i = strings.LastIndex(key[:i], "-") {
+		keys = append(keys, key[:i+1])
+	}
+	return keys
+}
+
+// objectBackend keeps the archives in an object storage bucket.
+type objectBackend struct {
+	bucket qdstorage.Bucket
+	prefix string
+}
+
+func (b *objectBackend) Get(key string) (io.ReadCloser, bool, error) {
+	return b.bucket.Get(qdstorage.Join(b.prefix, key+archiveExtension))
+}
+
+func (b *objectBackend) Put(key string, r io.Reader, size int64) error {
+	return b.bucket.Put(qdstorage.Join(b.prefix, key+archiveExtension), r, size, "application/gzip")
+}
+
+func (b *objectBackend) List(prefix string) ([]Entry, error) {
+	objects, err := b.bucket.List(qdstorage.Join(b.prefix, prefix))
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, object := range objects {
+		name := object.Name
+		if b.prefix != "" {
+			name = strings.TrimPrefix(name, b.prefix+"/")
+		}
+		if strings.HasSuffix(name, archiveExtension) && !strings.Contains(name, "/") {
+			entries = append(entries, Entry{Key: strings.TrimSuffix(name, archiveExtension), Modified: object.Modified})
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcache
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dirBackend keeps the archives in a local (or mounted network) directory.
+type dirBackend struct {
+	dir string
+}
+
+func (b *dirBackend) Get(key string) (io.ReadCloser, bool, error) {
+	f, err := os.Open(filepath.Join(b.dir, key+archiveExtension))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return f, true, nil
+}
+
+func (b *dirBackend) Put(key string, r io.Reader, _ int64) error {
+	return storeFile(b.dir, key+archiveExtension, r)
+}
+
+func (b *dirBackend) List(prefix string) ([]Entry, error) {
+	files, err := os.ReadDir(b.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, archiveExtension) || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, Entry{Key: strings.TrimSuffix(name, archiveExtension), Modified: info.ModTime()})
+	}
+	return entries, nil
+}
+
+// storeFile writes r to a temporary file in dir first, so concurrent readers never see a partial archive.
+func storeFile(dir string, name string, r io.Reader) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer func(name string) {
+		_ = os.Remove(name)
+	}(tmp.Name())
+	if _, err = io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to receive the archive: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
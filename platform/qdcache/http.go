@@ -0,0 +1,109 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"time"
+)
+
+const (
+	cachePath       = "/cache/"
+	transferTimeout = 30 * time.Minute
+)
+
+// httpBackend talks to the server started with qodana cache serve.
+type httpBackend struct {
+	base *url.URL
+}
+
+func (b *httpBackend) Get(key string) (io.ReadCloser, bool, error) {
+	resp, err := b.do(http.MethodGet, path.Join(cachePath, key+archiveExtension), nil, nil, -1)
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		_ = resp.Body.Close()
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, false, fmt.Errorf("%s responded with %s", b.base.Redacted(), resp.Status)
+	}
+	return resp.Body, true, nil
+}
+
+func (b *httpBackend) Put(key string, r io.Reader, size int64) error {
+	resp, err := b.do(http.MethodPut, path.Join(cachePath, key+archiveExtension), nil, r, size)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with %s", b.base.Redacted(), resp.Status)
+	}
+	return nil
+}
+
+func (b *httpBackend) List(prefix string) ([]Entry, error) {
+	resp, err := b.do(http.MethodGet, cachePath, url.Values{"prefix": {prefix}}, nil, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s responded with %s", b.base.Redacted(), resp.Status)
+	}
+	var entries []Entry
+	if err = json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to list the caches: %w", err)
+	}
+	return entries, nil
+}
+
+func (b *httpBackend) do(method string, p string, query url.Values, body io.Reader, size int64) (*http.Response, error) {
+	u := *b.base
+	u.Path = path.Join(u.Path, p)
+	if p == cachePath {
+		u.Path += "/"
+	}
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	if token := os.Getenv(qdenv.QodanaCacheTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return newClient().Do(req)
+}
+
+func newClient() *http.Client {
+	return &http.Client{Timeout: transferTimeout, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}}
+}
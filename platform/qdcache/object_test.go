@@ -0,0 +1,150 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// objectStore is an in-memory storage serving the subset of the S3 and GCS APIs used by the backends.
+type objectStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	auth    []string
+}
+
+func newObjectStore() *objectStore {
+	return &objectStore{objects: map[string][]byte{}}
+}
+
+func (s *objectStore) list(prefix string) []string {
+	var names []string
+	for name := range s.objects {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (s *objectStore) s3(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+	name := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	switch {
+	case r.Method == http.MethodPut:
+		s.objects[name], _ = io.ReadAll(r.Body)
+	case r.URL.Query().Get("list-type") == "2":
+		_, _ = fmt.Fprint(w, "<ListBucketResult>")
+		for _, name := range s.list(r.URL.Query().Get("prefix")) {
+			_, _ = fmt.Fprintf(w, "<Contents><Key>%s</Key><LastModified>2024-01-01T00:00:00.000Z</LastModified></Contents>", name)
+		}
+		_, _ = fmt.Fprint(w, "<IsTruncated>false</IsTruncated></ListBucketResult>")
+	default:
+		data, ok := s.objects[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, "<Error><Code>NoSuchKey</Code></Error>")
+			return
+		}
+		_, _ = w.Write(data)
+	}
+}
+
+func (s *objectStore) gcs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/bucket/o":
+		s.objects[r.URL.Query().Get("name")], _ = io.ReadAll(r.Body)
+		_, _ = fmt.Fprint(w, "{}")
+	case r.URL.Path == "/storage/v1/b/bucket/o":
+		var items []map[string]string
+		for _, name := range s.list(r.URL.Query().Get("prefix")) {
+			items = append(items, map[string]string{"name": name, "updated": "2024-01-01T00:00:00Z"})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": items})
+	default:
+		name, _ := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/storage/v1/b/bucket/o/"))
+		data, ok := s.objects[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"error": {"message": "No such object"}}`)
+			return
+		}
+		_, _ = w.Write(data)
+	}
+}
+
+func testObjectBackend(t *testing.T, backend Backend, store *objectStore) {
+	if err := Save(backend, "qodana-main-aaa", writeCacheDir(t)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.objects["qodana/qodana-main-aaa.tar.gz"]; !ok {
+		t.Fatalf("expected the archive to be stored under the prefix, got %v", store.list(""))
+	}
+	restored, err := Restore(backend, "qodana-feature-bbb", []string{"qodana-feature-", "qodana-"}, t.TempDir())
+	if err != nil || restored != "qodana-main-aaa" {
+		t.Errorf("expected the cache to be restored by the prefix, got %q %v", restored, err)
+	}
+	restored, err = Restore(backend, "other-ccc", []string{"other-"}, t.TempDir())
+	if err != nil || restored != "" {
+		t.Errorf("expected no cache, got %q %v", restored, err)
+	}
+}
+
+func TestS3Backend(t *testing.T) {
+	store := newObjectStore()
+	svr := httptest.NewServer(http.HandlerFunc(store.s3))
+	defer svr.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("AWS_ENDPOINT_URL_S3", svr.URL)
+	backend, err := NewBackend("s3://bucket/qodana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testObjectBackend(t, backend, store)
+	for _, auth := range store.auth {
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+			t.Errorf("unexpected authorization %s", auth)
+		}
+	}
+}
+
+func TestGcsBackend(t *testing.T) {
+	store := newObjectStore()
+	svr := httptest.NewServer(http.HandlerFunc(store.gcs))
+	defer svr.Close()
+	t.Setenv("STORAGE_EMULATOR_HOST", svr.URL)
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+	backend, err := NewBackend("gs://bucket/qodana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testObjectBackend(t, backend, store)
+}
@@ -0,0 +1,323 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcache
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Format is the compression of the archives.
+type Format string
+
+const (
+	Gzip Format = "gzip"
+	Zstd Format = "zstd"
+
+	// ManifestName is the last entry of the packed archives with the SHA-256 checksums of the files,
+	// in the format of sha256sum.
+	ManifestName = ".qodana-archive.sha256"
+	// maxManifestSize limits the manifest read into memory.
+	maxManifestSize = 64 << 20
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// FormatOf returns the format of the archive by its file name: .tar.gz and .tgz are gzip, .tar.zst and .tzst are zstd.
+func FormatOf(path string) (Format, bool) {
+	name := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return Gzip, true
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return Zstd, true
+	}
+	return "", false
+}
+
+// ParseFormat returns the format by its name, as in the --format flags.
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case Gzip, "gz":
+		return Gzip, nil
+	case Zstd, "zst":
+		return Zstd, nil
+	}
+	return "", fmt.Errorf("unsupported archive format %q, expected %s or %s", name, Gzip, Zstd)
+}
+
+// Progress is the number of the files and their bytes packed or unpacked so far.
+type Progress struct {
+	Files int
+	Bytes int64
+}
+
+// PackOptions configure Pack.
+type PackOptions struct {
+	// Format is the compression, gzip if not set.
+	Format Format
+	// Progress is called after every packed file.
+	Progress func(Progress)
+}
+
+// UnpackOptions configure Unpack.
+type UnpackOptions struct {
+	// Progress is called after every unpacked file.
+	Progress func(Progress)
+}
+
+// Pack streams the contents of dir to w as a compressed tar archive, only regular files, directories and symlinks are
+// kept. The checksums of the files are written as the last entry, so Unpack detects the damaged and truncated archives.
+func Pack(w io.Writer, dir string, options PackOptions) error {
+	cw, err := compressor(w, options.Format)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(cw)
+	var manifest bytes.Buffer
+	progress := Progress{}
+	err = filepath.Walk(
+		dir, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil || rel == "." {
+				return err
+			}
+			mode := info.Mode()
+			if !mode.IsRegular() && !mode.IsDir() && mode&os.ModeSymlink == 0 {
+				return nil // sockets (e.g. .port of a running IDE), pipes and devices
+			}
+			link := ""
+			if mode&os.ModeSymlink != 0 {
+				if link, err = os.Readlink(p); err != nil {
+					return err
+				}
+			}
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(rel)
+			if err = tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if !mode.IsRegular() {
+				return nil
+			}
+			sum, err := packFile(tw, p)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(&manifest, "%s  %s\n", sum, header.Name)
+			progress.Files++
+			progress.Bytes += header.Size
+			if options.Progress != nil {
+				options.Progress(progress)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(
+		&tar.Header{Typeflag: tar.TypeReg, Name: ManifestName, Mode: 0o644, Size: int64(manifest.Len())},
+	)
+	if err != nil {
+		return err
+	}
+	if _, err = tw.Write(manifest.Bytes()); err != nil {
+		return err
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+func packFile(w io.Writer, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+	h := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(w, h), f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func compressor(w io.Writer, format Format) (io.WriteCloser, error) {
+	switch format {
+	case Gzip, "":
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported archive format %q", format)
+}
+
+// decompressor detects the format of the archive by its magic bytes.
+func decompressor(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic):
+		d, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	case err != nil && !errors.Is(err, io.EOF):
+		return nil, err
+	}
+	return nil, fmt.Errorf("not a tar.gz or tar.zst archive")
+}
+
+// Unpack extracts the tar.gz or tar.zst archive from r into dir, entries pointing outside dir are rejected.
+// If the archive has the checksums written by Pack, the extracted files are verified against them, it returns whether
+// the archive was verified.
+func Unpack(r io.Reader, dir string, options UnpackOptions) (bool, error) {
+	dr, err := decompressor(r)
+	if err != nil {
+		return false, err
+	}
+	defer func(dr io.ReadCloser) {
+		_ = dr.Close()
+	}(dr)
+	tr := tar.NewReader(dr)
+	sums := map[string]string{}
+	progress := Progress{}
+	verified := false
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, fmt.Errorf("the archive is damaged: %w", err)
+		}
+		if verified {
+			return false, fmt.Errorf("the archive is damaged: unexpected entry %s after the checksums", header.Name)
+		}
+		if header.Name == ManifestName {
+			if err = verifyManifest(tr, sums); err != nil {
+				return false, err
+			}
+			verified = true
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !isWithin(dir, target) {
+			return false, fmt.Errorf("archive entry %s points outside %s", header.Name, dir)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, os.FileMode(header.Mode)|0o700); err != nil {
+				return false, err
+			}
+		case tar.TypeReg:
+			h := sha256.New()
+			if err = extractFile(io.TeeReader(tr, h), target, os.FileMode(header.Mode)); err != nil {
+				return false, err
+			}
+			sums[strings.TrimPrefix(header.Name, "./")] = hex.EncodeToString(h.Sum(nil))
+			progress.Files++
+			progress.Bytes += header.Size
+			if options.Progress != nil {
+				options.Progress(progress)
+			}
+		case tar.TypeSymlink:
+			if filepath.IsAbs(header.Linkname) || !isWithin(dir, filepath.Join(filepath.Dir(target), header.Linkname)) {
+				return false, fmt.Errorf("archive entry %s links outside %s", header.Name, dir)
+			}
+			if err = os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+				return false, err
+			}
+			_ = os.Remove(target)
+			if err = os.Symlink(header.Linkname, target); err != nil {
+				return false, err
+			}
+		default:
+			log.Debugf("Skipping unsupported archive entry %s", header.Name)
+		}
+	}
+	if !verified {
+		log.Debugf("The archive has no checksums, %d files extracted to %s without verification", progress.Files, dir)
+	}
+	return verified, nil
+}
+
+// verifyManifest checks that the extracted files are exactly the ones listed in the manifest with the same checksums.
+func verifyManifest(r io.Reader, sums map[string]string) error {
+	data, err := io.ReadAll(io.LimitReader(r, maxManifestSize))
+	if err != nil {
+		return fmt.Errorf("the archive is damaged: %w", err)
+	}
+	listed := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		sum, name, found := strings.Cut(line, "  ")
+		if !found {
+			return fmt.Errorf("the archive is damaged: invalid checksum line %q", line)
+		}
+		actual, extracted := sums[name]
+		switch {
+		case !extracted:
+			return fmt.Errorf("the archive is damaged: %s is missing", name)
+		case actual != sum:
+			return fmt.Errorf("the archive is damaged: %s doesn't match its checksum", name)
+		}
+		listed[name] = true
+	}
+	var unlisted []string
+	for name := range sums {
+		if !listed[name] {
+			unlisted = append(unlisted, name)
+		}
+	}
+	if len(unlisted) > 0 {
+		sort.Strings(unlisted)
+		return fmt.Errorf("the archive is damaged: %s has no checksum", strings.Join(unlisted, ", "))
+	}
+	return nil
+}
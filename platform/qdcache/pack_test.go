@@ -0,0 +1,140 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcache
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTarGz writes a tar.gz archive of the files as is, e.g. with a manifest not matching them.
+func writeTarGz(t *testing.T, files map[string]string, names ...string) *bytes.Buffer {
+	var archive bytes.Buffer
+	gw := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gw)
+	for _, name := range names {
+		header := &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644, Size: int64(len(files[name]))}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &archive
+}
+
+func TestPackUnpackZstd(t *testing.T) {
+	var archive bytes.Buffer
+	var packed Progress
+	err := Pack(&archive, writeCacheDir(t), PackOptions{Format: Zstd, Progress: func(p Progress) { packed = p }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(archive.Bytes(), zstdMagic) {
+		t.Fatalf("expected a zstd archive")
+	}
+
+	target := t.TempDir()
+	var unpacked Progress
+	verified, err := Unpack(&archive, target, UnpackOptions{Progress: func(p Progress) { unpacked = p }})
+	if err != nil || !verified {
+		t.Fatalf("expected the archive to be verified, got %v %v", verified, err)
+	}
+	if packed != (Progress{Files: 1, Bytes: 3}) || unpacked != packed {
+		t.Errorf("unexpected progress: packed %+v, unpacked %+v", packed, unpacked)
+	}
+	data, err := os.ReadFile(filepath.Join(target, "m2", "link.jar"))
+	if err != nil || string(data) != "jar" {
+		t.Errorf("unexpected contents %q: %v", data, err)
+	}
+	if _, err = os.Stat(filepath.Join(target, ManifestName)); !os.IsNotExist(err) {
+		t.Errorf("expected the checksums not to be extracted")
+	}
+}
+
+func TestUnpackDetectsDamage(t *testing.T) {
+	wrongSum := strings.Repeat("0", 64)
+	var truncated bytes.Buffer
+	if err := Pack(&truncated, writeCacheDir(t), PackOptions{Format: Zstd}); err != nil {
+		t.Fatal(err)
+	}
+	truncated.Truncate(truncated.Len() - 8)
+
+	for name, archive := range map[string]*bytes.Buffer{
+		"checksum mismatch": writeTarGz(
+			t,
+			map[string]string{"lib.jar": "jar", ManifestName: wrongSum + "  lib.jar\n"},
+			"lib.jar", ManifestName,
+		),
+		"missing file": writeTarGz(
+			t,
+			map[string]string{ManifestName: wrongSum + "  lib.jar\n"},
+			ManifestName,
+		),
+		"unlisted file": writeTarGz(
+			t,
+			map[string]string{"lib.jar": "jar", ManifestName: ""},
+			"lib.jar", ManifestName,
+		),
+		"truncated": &truncated,
+	} {
+		if _, err := Unpack(archive, t.TempDir(), UnpackOptions{}); err == nil || !strings.Contains(err.Error(), "damaged") {
+			t.Errorf("%s: expected the archive to be reported as damaged, got %v", name, err)
+		}
+	}
+}
+
+func TestUnpackWithoutChecksums(t *testing.T) {
+	target := t.TempDir()
+	archive := writeTarGz(t, map[string]string{"lib.jar": "jar"}, "lib.jar")
+	verified, err := Unpack(archive, target, UnpackOptions{})
+	if err != nil || verified {
+		t.Fatalf("expected the archive to be extracted without verification, got %v %v", verified, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(target, "lib.jar")); err != nil || string(data) != "jar" {
+		t.Errorf("unexpected contents %q: %v", data, err)
+	}
+	if _, err = Unpack(bytes.NewBufferString("not an archive"), t.TempDir(), UnpackOptions{}); err == nil {
+		t.Errorf("expected an error for an unsupported archive")
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for path, expected := range map[string]Format{
+		"cache.tar.gz":       Gzip,
+		"cache.TGZ":          Gzip,
+		"/tmp/cache.tar.zst": Zstd,
+		"cache.tzst":         Zstd,
+		"cache.zip":          "",
+	} {
+		if actual, _ := FormatOf(path); actual != expected {
+			t.Errorf("%s: expected %q, got %q", path, expected, actual)
+		}
+	}
+}
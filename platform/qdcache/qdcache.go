@@ -0,0 +1,69 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdcache shares the Qodana cache directory between CI agents as tar.gz archives
+// stored in a cache backend: qodana cache serve, a local directory, S3 or GCS, and exports it as tar.zst archives.
+package qdcache
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const archiveExtension = ".tar.gz"
+
+// keyPattern limits the cache keys to safe file names.
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,511}$`)
+
+// ValidKey checks if key can be used as a cache key.
+func ValidKey(key string) bool {
+	return keyPattern.MatchString(key) && !strings.Contains(key, "..")
+}
+
+// Archive writes the contents of dir to w as a tar.gz archive with the checksums of the files, see Pack.
+func Archive(w io.Writer, dir string) error {
+	return Pack(w, dir, PackOptions{Format: Gzip})
+}
+
+// Extract unpacks the tar.gz or tar.zst archive from r into dir and verifies the checksums if the archive has them,
+// see Unpack.
+func Extract(r io.Reader, dir string) error {
+	_, err := Unpack(r, dir, UnpackOptions{})
+	return err
+}
+
+func extractFile(r io.Reader, target string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode|0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func isWithin(dir string, target string) bool {
+	rel, err := filepath.Rel(dir, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcache
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCacheDir(t *testing.T) string {
+	source := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(source, "m2", "repository"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "m2", "repository", "lib.jar"), []byte("jar"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("repository/lib.jar", filepath.Join(source, "m2", "link.jar")); err != nil {
+		t.Fatal(err)
+	}
+	return source
+}
+
+func TestSaveRestoreServer(t *testing.T) {
+	svr := httptest.NewServer(Handler(t.TempDir(), "secret"))
+	defer svr.Close()
+	t.Setenv(qdenv.QodanaCacheTokenEnv, "secret")
+	backend, err := NewBackend(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := t.TempDir()
+	if restored, err := Restore(backend, "qodana-jvm-1234", nil, target); err != nil || restored != "" {
+		t.Fatalf("expected no cache before saving, got %q %v", restored, err)
+	}
+	if err = Save(backend, "qodana-jvm-1234", writeCacheDir(t)); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := Restore(backend, "qodana-jvm-5678", []string{"qodana-jvm-"}, target)
+	if err != nil || restored != "qodana-jvm-1234" {
+		t.Fatalf("expected the cache to be restored by the prefix, got %q %v", restored, err)
+	}
+	data, err := os.ReadFile(filepath.Join(target, "m2", "link.jar"))
+	if err != nil || string(data) != "jar" {
+		t.Errorf("unexpected contents %q: %v", data, err)
+	}
+
+	t.Setenv(qdenv.QodanaCacheTokenEnv, "wrong")
+	if _, err = Restore(backend, "qodana-jvm-1234", nil, t.TempDir()); err == nil {
+		t.Errorf("expected the restore to fail with a wrong token")
+	}
+}
+
+func TestRestoreKeys(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := NewBackend(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := writeCacheDir(t)
+	for i, key := range []string{"qodana-main-aaa", "qodana-main-bbb", "qodana-feature-ccc"} {
+		if err = Save(backend, key, source); err != nil {
+			t.Fatal(err)
+		}
+		modified := time.Now().Add(time.Duration(i-10) * time.Minute)
+		if err = os.Chtimes(filepath.Join(dir, key+archiveExtension), modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tc := range []struct {
+		key         string
+		restoreKeys []string
+		expected    string
+	}{
+		{"qodana-main-aaa", []string{"qodana-"}, "qodana-main-aaa"},
+		{"qodana-main-zzz", []string{"qodana-main-", "qodana-"}, "qodana-main-bbb"},
+		{"qodana-release-zzz", []string{"qodana-release-", "qodana-"}, "qodana-feature-ccc"},
+		{"other-zzz", []string{"other-"}, ""},
+	} {
+		restored, err := Restore(backend, tc.key, tc.restoreKeys, t.TempDir())
+		if err != nil || restored != tc.expected {
+			t.Errorf("%s %v: expected %q, got %q %v", tc.key, tc.restoreKeys, tc.expected, restored, err)
+		}
+	}
+}
+
+func TestFallbackKeys(t *testing.T) {
+	keys := FallbackKeys("qodana-jvm-main-1a2b")
+	expected := []string{"qodana-jvm-main-", "qodana-jvm-", "qodana-"}
+	if len(keys) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, keys)
+	}
+	for i := range keys {
+		if keys[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, keys)
+		}
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	for location, expected := range map[string]string{
+		"https://cache.example.com": "*qdcache.httpBackend",
+		"s3://bucket/qodana":        "*qdcache.objectBackend",
+		"gs://bucket":               "*qdcache.objectBackend",
+		"file:///mnt/cache":         "*qdcache.dirBackend",
+		"/mnt/cache":                "*qdcache.dirBackend",
+		`C:\cache`:                  "*qdcache.dirBackend",
+	} {
+		backend, err := NewBackend(location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := fmt.Sprintf("%T", backend); actual != expected {
+			t.Errorf("%s: expected %s, got %s", location, expected, actual)
+		}
+	}
+	if _, err := NewBackend("ftp://cache"); err == nil {
+		t.Errorf("expected an error for an unsupported scheme")
+	}
+}
+
+func TestHandlerRejectsInvalidKeys(t *testing.T) {
+	handler := Handler(t.TempDir(), "")
+	for _, path := range []string{"/cache/../secret.tar.gz", "/cache/.hidden.tar.gz", "/cache/key.zip", "/other/key.tar.gz"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString("data")))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for %s, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestExtractRejectsPathTraversal(t *testing.T) {
+	source := t.TempDir()
+	if err := os.Symlink("../../outside", filepath.Join(source, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	var archive bytes.Buffer
+	if err := Archive(&archive, source); err != nil {
+		t.Fatal(err)
+	}
+	if err := Extract(&archive, t.TempDir()); err == nil {
+		t.Errorf("expected a symlink pointing outside the directory to be rejected")
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcache
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Handler serves the cache archives stored in root: GET and HEAD of /cache/<key>.tar.gz download the archive,
+// PUT replaces it, GET of /cache/?prefix=<prefix> lists the archives as JSON.
+// If token is set, requests must carry it as a bearer token.
+func Handler(root string, token string) http.Handler {
+	return &server{root: root, token: token, backend: &dirBackend{dir: root}}
+}
+
+type server struct {
+	root    string
+	token   string
+	backend *dirBackend
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(auth), []byte(s.token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	if r.URL.Path == cachePath && r.Method == http.MethodGet {
+		s.listArchives(w, r.URL.Query().Get("prefix"))
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, cachePath)
+	key := strings.TrimSuffix(name, archiveExtension)
+	if !strings.HasPrefix(r.URL.Path, cachePath) || !strings.HasSuffix(name, archiveExtension) || !ValidKey(key) {
+		http.NotFound(w, r)
+		return
+	}
+	archive := filepath.Join(s.root, key+archiveExtension)
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		s.serveArchive(w, r, archive)
+	case http.MethodPut:
+		if err := storeFile(s.root, filepath.Base(archive), r.Body); err != nil {
+			log.Errorf("Failed to store cache %s: %v", key, err)
+			http.Error(w, "failed to store the cache", http.StatusInternalServerError)
+			return
+		}
+		log.Infof("Stored cache %s", key)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *server) serveArchive(w http.ResponseWriter, r *http.Request, archive string) {
+	f, err := os.Open(archive)
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	http.ServeContent(w, r, filepath.Base(archive), info.ModTime(), f)
+}
+
+func (s *server) listArchives(w http.ResponseWriter, prefix string) {
+	entries, err := s.backend.List(prefix)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []Entry{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdcheckpoint records the completed stages of a scan, so that a scan interrupted by a crash
+// can be resumed with qodana scan --resume.
+package qdcheckpoint
+
+import (
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const fileName = "checkpoints.json"
+
+// Stages that can be skipped when resuming, the indexes are kept in the cache directory.
+const (
+	ImagePull = "image pull"
+	Bootstrap = "bootstrap"
+)
+
+// Stage is a completed stage, Key identifies what the stage was completed for, e.g. the pulled image.
+type Stage struct {
+	Key         string    `json:"key"`
+	CompletedAt time.Time `json:"completedAt"`
+}
+
+// Checkpoints are the completed stages of the current scan, stored in a directory of the system directory.
+// A nil *Checkpoints records nothing.
+type Checkpoints struct {
+	path   string
+	mu     sync.Mutex
+	stages map[string]Stage
+}
+
+// Load returns the checkpoints kept in dir by the interrupted scan if resume is set,
+// otherwise the previous checkpoints are discarded and the scan starts from scratch.
+func Load(dir string, resume bool) *Checkpoints {
+	c := &Checkpoints{path: filepath.Join(dir, fileName), stages: map[string]Stage{}}
+	if !resume {
+		c.Clear()
+		return c
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("Failed to read the scan checkpoints %s: %v", c.path, err)
+		}
+		return c
+	}
+	if err = json.Unmarshal(data, &c.stages); err != nil {
+		log.Warnf("Ignoring the corrupted scan checkpoints %s: %v", c.path, err)
+		c.stages = map[string]Stage{}
+	}
+	return c
+}
+
+// Done reports whether the stage was completed for key.
+func (c *Checkpoints) Done(stage string, key string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.stages[stage]
+	return ok && s.Key == key
+}
+
+// Completed returns the names of the completed stages, sorted.
+func (c *Checkpoints) Completed() []string {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stages := make([]string, 0, len(c.stages))
+	for stage := range c.stages {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+	return stages
+}
+
+// Complete records the stage as completed for key.
+func (c *Checkpoints) Complete(stage string, key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stages[stage] = Stage{Key: key, CompletedAt: time.Now().UTC()}
+	data, err := json.MarshalIndent(c.stages, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(c.path), os.ModePerm)
+	}
+	if err == nil {
+		err = os.WriteFile(c.path, data, 0o644)
+	}
+	if err != nil {
+		log.Warnf("Failed to write the scan checkpoints %s: %v", c.path, err)
+	}
+}
+
+// Clear discards the checkpoints, once the scan has finished.
+func (c *Checkpoints) Clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stages = map[string]Stage{}
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Failed to remove the scan checkpoints %s: %v", c.path, err)
+	}
+}
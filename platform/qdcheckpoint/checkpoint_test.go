@@ -0,0 +1,56 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcheckpoint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckpoints(t *testing.T) {
+	dir := t.TempDir()
+	c := Load(dir, true)
+	if len(c.Completed()) != 0 {
+		t.Fatalf("expected no checkpoints, got %v", c.Completed())
+	}
+	c.Complete(ImagePull, "jetbrains/qodana-jvm:2024.3")
+	c.Complete(Bootstrap, "make deps")
+
+	resumed := Load(dir, true)
+	if !reflect.DeepEqual(resumed.Completed(), []string{Bootstrap, ImagePull}) {
+		t.Fatalf("unexpected checkpoints %v", resumed.Completed())
+	}
+	if !resumed.Done(ImagePull, "jetbrains/qodana-jvm:2024.3") || resumed.Done(ImagePull, "jetbrains/qodana-js:2024.3") {
+		t.Fatal("expected the image pull to be done only for the pulled image")
+	}
+
+	restarted := Load(dir, false)
+	if restarted.Done(Bootstrap, "make deps") {
+		t.Fatal("expected a scan without --resume to discard the checkpoints")
+	}
+	if _, err := os.Stat(filepath.Join(dir, fileName)); !os.IsNotExist(err) {
+		t.Fatalf("expected the checkpoints file to be removed, got %v", err)
+	}
+
+	var none *Checkpoints
+	none.Complete(Bootstrap, "make deps")
+	if none.Done(Bootstrap, "make deps") {
+		t.Fatal("expected nil checkpoints to record nothing")
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdci generates the CI pipeline files running Qodana.
+package qdci
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Providers of the CI pipelines.
+const (
+	GitHub    = "github"
+	GitLab    = "gitlab"
+	Azure     = "azure"
+	Bitbucket = "bitbucket"
+	Jenkins   = "jenkins"
+	CircleCI  = "circleci"
+)
+
+var pipelines = map[string]struct {
+	path     string
+	template *template.Template
+}{
+	GitHub:    {".github/workflows/qodana_code_quality.yml", template.Must(template.New(GitHub).Parse(gitHubTemplate))},
+	GitLab:    {".gitlab-ci.yml", template.Must(template.New(GitLab).Parse(gitLabTemplate))},
+	Azure:     {"azure-pipelines.yml", template.Must(template.New(Azure).Parse(azureTemplate))},
+	Bitbucket: {"bitbucket-pipelines.yml", template.Must(template.New(Bitbucket).Parse(bitbucketTemplate))},
+	Jenkins:   {"Jenkinsfile", template.Must(template.New(Jenkins).Parse(jenkinsTemplate))},
+	CircleCI:  {".circleci/config.yml", template.Must(template.New(CircleCI).Parse(circleCiTemplate))},
+}
+
+// Options tune the generated pipeline.
+type Options struct {
+	// Linter is the image of the linter, e.g. jetbrains/qodana-jvm:2024.3.
+	Linter string
+	// Version is the version of the Qodana CI integrations (actions, tasks and orbs).
+	Version string
+	// Branch is the default branch of the repository, analysed on every push.
+	Branch string
+	// PrMode analyses only the changes of pull requests.
+	PrMode bool
+	// Cache keeps the Qodana cache between the runs.
+	Cache bool
+	// Token passes QODANA_TOKEN from the secrets of the CI, required by the linters with a paid license.
+	Token bool
+}
+
+// Providers returns the supported CI providers.
+func Providers() []string {
+	providers := make([]string, 0, len(pipelines))
+	for provider := range pipelines {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// Path returns the path of the pipeline file of the provider relative to the repository root.
+func Path(provider string) (string, error) {
+	pipeline, ok := pipelines[provider]
+	if !ok {
+		return "", unknownProvider(provider)
+	}
+	return pipeline.path, nil
+}
+
+// Generate returns the pipeline file of the provider running Qodana.
+func Generate(provider string, options Options) (string, error) {
+	pipeline, ok := pipelines[provider]
+	if !ok {
+		return "", unknownProvider(provider)
+	}
+	if options.Linter == "" {
+		return "", fmt.Errorf("the linter image is required")
+	}
+	var sb strings.Builder
+	if err := pipeline.template.Execute(&sb, options); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func unknownProvider(provider string) error {
+	return fmt.Errorf("unknown CI provider %q, available values: %s", provider, strings.Join(Providers(), ", "))
+}
+
+// MajorVersion returns the year of Version, the major version of the Azure Pipelines task.
+func (o Options) MajorVersion() string {
+	major, _, _ := strings.Cut(o.Version, ".")
+	return major
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdci
+
+import (
+	"gopkg.in/yaml.v3"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	for _, provider := range Providers() {
+		for _, options := range []Options{
+			{Linter: "jetbrains/qodana-jvm:2024.3", Version: "2024.3", Branch: "main", PrMode: true, Cache: true, Token: true},
+			{Linter: "jetbrains/qodana-jvm-community:2024.3", Version: "2024.3", Branch: "develop"},
+		} {
+			pipeline, err := Generate(provider, options)
+			if err != nil {
+				t.Fatalf("%s: %v", provider, err)
+			}
+			if !strings.Contains(pipeline, options.Linter) {
+				t.Errorf("%s: expected the linter in the pipeline:\n%s", provider, pipeline)
+			}
+			if strings.Contains(pipeline, "QODANA_TOKEN") != options.Token {
+				t.Errorf("%s: expected QODANA_TOKEN only if required:\n%s", provider, pipeline)
+			}
+			if strings.Contains(pipeline, "cache") != options.Cache && provider != GitHub {
+				t.Errorf("%s: expected the cache only if enabled:\n%s", provider, pipeline)
+			}
+			path, _ := Path(provider)
+			if strings.HasSuffix(path, ".yml") {
+				var document map[string]any
+				if err = yaml.Unmarshal([]byte(pipeline), &document); err != nil {
+					t.Errorf("%s: invalid YAML: %v\n%s", provider, err, pipeline)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateGitHub(t *testing.T) {
+	pipeline, err := Generate(
+		GitHub,
+		Options{Linter: "jetbrains/qodana-go:2024.3", Version: "2024.3", Branch: "main", PrMode: true, Cache: true, Token: true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"uses: JetBrains/qodana-action@v2024.3",
+		"args: --linter,jetbrains/qodana-go:2024.3",
+		"pr-mode: true",
+		"QODANA_TOKEN: ${{ secrets.QODANA_TOKEN }}",
+		"ref: ${{ github.event.pull_request.head.sha }}",
+	} {
+		if !strings.Contains(pipeline, expected) {
+			t.Errorf("expected %q in:\n%s", expected, pipeline)
+		}
+	}
+}
+
+func TestGenerateUnknownProvider(t *testing.T) {
+	if _, err := Generate("travis", Options{Linter: "jetbrains/qodana-go:2024.3"}); err == nil {
+		t.Fatal("expected an error for an unknown provider")
+	}
+	if _, err := Path("travis"); err == nil {
+		t.Fatal("expected an error for an unknown provider")
+	}
+}
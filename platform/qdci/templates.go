@@ -0,0 +1,220 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdci
+
+const gitHubTemplate = `name: Qodana
+on:
+  workflow_dispatch:
+  pull_request:
+  push:
+    branches:
+      - {{.Branch}}
+
+jobs:
+  qodana:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: write
+      pull-requests: write
+      checks: write
+    steps:
+      - uses: actions/checkout@v4
+        with:
+          ref: ${{"{{"}} github.event.pull_request.head.sha }}
+          fetch-depth: 0
+      - name: 'Qodana Scan'
+        uses: JetBrains/qodana-action@v{{.Version}}
+        with:
+          args: --linter,{{.Linter}}
+          pr-mode: {{.PrMode}}
+          use-caches: {{.Cache}}
+{{- if .Token}}
+        env:
+          QODANA_TOKEN: ${{"{{"}} secrets.QODANA_TOKEN }}
+{{- end}}
+`
+
+const gitLabTemplate = `{{if .Token}}# Set QODANA_TOKEN as a masked CI/CD variable of the project.
+{{end -}}
+qodana:
+  image:
+    name: {{.Linter}}
+    entrypoint: [""]
+{{- if .Cache}}
+  cache:
+    - key: qodana-{{.Version}}-$CI_DEFAULT_BRANCH-$CI_COMMIT_REF_SLUG
+      fallback_keys:
+        - qodana-{{.Version}}-$CI_DEFAULT_BRANCH-
+        - qodana-{{.Version}}-
+      paths:
+        - .qodana/cache
+{{- end}}
+{{- if .PrMode}}
+  variables:
+    GIT_DEPTH: 0
+{{- end}}
+  script:
+    - qodana --results-dir=$CI_PROJECT_DIR/.qodana/results{{if .Cache}} --cache-dir=$CI_PROJECT_DIR/.qodana/cache{{end}}{{if .PrMode}} ${CI_MERGE_REQUEST_DIFF_BASE_SHA:+--diff-start=$CI_MERGE_REQUEST_DIFF_BASE_SHA}{{end}}
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+    - if: $CI_COMMIT_BRANCH == "{{.Branch}}"
+  artifacts:
+    paths:
+      - .qodana/results/
+    expose_as: 'Qodana report'
+`
+
+const azureTemplate = `{{if .Token}}# Set QODANA_TOKEN as a secret variable of the pipeline.
+{{end -}}
+trigger:
+  - {{.Branch}}
+{{- if .PrMode}}
+pr:
+  - {{.Branch}}
+{{- end}}
+
+pool:
+  vmImage: ubuntu-latest
+
+steps:
+  - checkout: self
+    fetchDepth: 0
+{{- if .Cache}}
+  - task: Cache@2
+    displayName: Cache Qodana
+    inputs:
+      key: '"qodana-{{.Version}}" | "$(Build.SourceBranchName)"'
+      restoreKeys: |
+        "qodana-{{.Version}}" | "$(Build.SourceBranchName)"
+        "qodana-{{.Version}}"
+      path: $(Agent.TempDirectory)/qodana/cache
+{{- end}}
+  - task: QodanaScan@{{.MajorVersion}}
+    inputs:
+      args: --linter,{{.Linter}}{{if .Cache}},--cache-dir,$(Agent.TempDirectory)/qodana/cache{{end}}
+      prMode: {{.PrMode}}
+{{- if .Token}}
+    env:
+      QODANA_TOKEN: $(QODANA_TOKEN)
+{{- end}}
+`
+
+const bitbucketTemplate = `{{if .Token}}# Set QODANA_TOKEN as a secured repository variable.
+{{end -}}
+clone:
+  depth: full
+
+definitions:
+{{- if .Cache}}
+  caches:
+    qodana: .qodana/cache
+{{- end}}
+  steps:
+    - step: &qodana
+        name: Qodana
+        image: {{.Linter}}
+{{- if .Cache}}
+        caches:
+          - qodana
+{{- end}}
+        script:
+{{- if .PrMode}}
+          - if [ -n "$BITBUCKET_PR_DESTINATION_BRANCH" ]; then git fetch origin "$BITBUCKET_PR_DESTINATION_BRANCH"; fi
+{{- end}}
+          - qodana --results-dir=$BITBUCKET_CLONE_DIR/.qodana/results{{if .Cache}} --cache-dir=$BITBUCKET_CLONE_DIR/.qodana/cache{{end}}{{if .PrMode}} ${BITBUCKET_PR_DESTINATION_BRANCH:+--diff-start=$(git merge-base HEAD origin/$BITBUCKET_PR_DESTINATION_BRANCH)}{{end}}
+        artifacts:
+          - .qodana/results/**
+
+pipelines:
+  branches:
+    {{.Branch}}:
+      - step: *qodana
+{{- if .PrMode}}
+  pull-requests:
+    '**':
+      - step: *qodana
+{{- end}}
+`
+
+const jenkinsTemplate = `pipeline {
+{{- if .Token}}
+    environment {
+        QODANA_TOKEN = credentials('qodana-token')
+    }
+{{- end}}
+    agent {
+        docker {
+            args '''
+              -v "${WORKSPACE}":/data/project
+{{- if .Cache}}
+              -v qodana-cache:/data/cache
+{{- end}}
+              --entrypoint=""
+              '''
+            image '{{.Linter}}'
+        }
+    }
+    stages {
+        stage('Qodana') {
+{{- if not .PrMode}}
+            when {
+                branch '{{.Branch}}'
+            }
+{{- end}}
+            steps {
+                sh '''qodana{{if .PrMode}} ${CHANGE_TARGET:+--diff-start=$(git merge-base HEAD origin/$CHANGE_TARGET)}{{end}}'''
+            }
+        }
+    }
+}
+`
+
+const circleCiTemplate = `{{if .Token}}# Set QODANA_TOKEN in the qodana context of the organization.
+{{end -}}
+{{if .PrMode}}# CircleCI doesn't provide the base of pull requests, the whole project is analysed.
+{{end -}}
+version: 2.1
+orbs:
+  qodana: jetbrains/qodana@{{.Version}}
+
+jobs:
+  code-quality:
+    machine:
+      image: 'ubuntu-2204:current'
+    steps:
+      - checkout
+{{- if .Cache}}
+      - restore_cache:
+          keys:
+            - qodana-{{.Version}}-{{"{{"}} .Branch }}-
+            - qodana-{{.Version}}-
+{{- end}}
+      - qodana/scan:
+          args: --linter {{.Linter}}{{if .Cache}} --cache-dir /tmp/qodana-cache{{end}}
+{{- if .Cache}}
+      - save_cache:
+          key: qodana-{{.Version}}-{{"{{"}} .Branch }}-{{"{{"}} .Revision }}
+          paths:
+            - /tmp/qodana-cache
+{{- end}}
+
+workflows:
+  main:
+    jobs:
+      - code-quality:
+          context: qodana
+`
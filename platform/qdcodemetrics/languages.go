@@ -0,0 +1,95 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcodemetrics
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// language is how the lines of a language are classified and which tokens branch its control flow.
+type language struct {
+	name         string
+	lineComments []string
+	blockComment [2]string
+	// keywords are the branching words, matched as whole words
+	keywords []string
+	// operators are the branching operators, matched anywhere in the code
+	operators []string
+}
+
+var (
+	cLikeKeywords  = []string{"if", "for", "while", "case", "catch"}
+	cLikeOperators = []string{"&&", "||"}
+)
+
+func cLike(name string, keywords ...string) *language {
+	return &language{
+		name:         name,
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		keywords:     append(append([]string{}, cLikeKeywords...), keywords...),
+		operators:    cLikeOperators,
+	}
+}
+
+// languages are the languages measured by the extensions of their files.
+var languages = map[string]*language{
+	".go":     cLike("Go", "select"),
+	".java":   cLike("Java"),
+	".kt":     cLike("Kotlin", "when"),
+	".kts":    cLike("Kotlin", "when"),
+	".scala":  cLike("Scala", "match"),
+	".groovy": cLike("Groovy"),
+	".js":     cLike("JavaScript"),
+	".jsx":    cLike("JavaScript"),
+	".mjs":    cLike("JavaScript"),
+	".ts":     cLike("TypeScript"),
+	".tsx":    cLike("TypeScript"),
+	".c":      cLike("C"),
+	".h":      cLike("C"),
+	".cc":     cLike("C++"),
+	".cpp":    cLike("C++"),
+	".hpp":    cLike("C++"),
+	".cs":     cLike("C#"),
+	".swift":  cLike("Swift", "guard"),
+	".dart":   cLike("Dart"),
+	".rs":     cLike("Rust", "match", "loop"),
+	".php": {
+		name:         "PHP",
+		lineComments: []string{"//", "#"},
+		blockComment: [2]string{"/*", "*/"},
+		keywords:     append([]string{"elseif", "foreach"}, cLikeKeywords...),
+		operators:    cLikeOperators,
+	},
+	".py": {
+		name:         "Python",
+		lineComments: []string{"#"},
+		keywords:     []string{"if", "elif", "for", "while", "except", "and", "or"},
+	},
+	".rb": {
+		name:         "Ruby",
+		lineComments: []string{"#"},
+		keywords:     []string{"if", "elsif", "unless", "while", "until", "for", "when", "rescue"},
+		operators:    cLikeOperators,
+	},
+}
+
+// languageOf returns the language of the file, nil if it's not measured.
+func languageOf(path string) *language {
+	return languages[strings.ToLower(filepath.Ext(path))]
+}
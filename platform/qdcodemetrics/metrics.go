@@ -0,0 +1,260 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdcodemetrics measures the lines of code and the cyclomatic complexity of the source files of a project,
+// independent of the linter, and checks them against the metric conditions of the run.
+package qdcodemetrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// FileName is the name of the metrics written to the results directory.
+const FileName = "metrics.json"
+
+// skippedDirs are the directories of the dependencies and the build outputs, the hidden directories are skipped too.
+var skippedDirs = map[string]bool{"node_modules": true, "vendor": true, "build": true, "target": true, "out": true, "dist": true}
+
+// FileMetrics are the metrics of a source file. The complexity is the number of its branch points, e.g. if and &&,
+// an approximation of the cyclomatic complexity computed without parsing the code.
+type FileMetrics struct {
+	File       string `json:"file"`
+	Language   string `json:"language"`
+	Lines      int    `json:"lines"`
+	Code       int    `json:"code"`
+	Comments   int    `json:"comments"`
+	Blank      int    `json:"blank"`
+	Complexity int    `json:"complexity"`
+}
+
+// Metrics are the metrics of the source files of the project and their totals.
+type Metrics struct {
+	Files []FileMetrics `json:"files"`
+	Total FileMetrics   `json:"total"`
+}
+
+// Compute measures the source files of projectDir, the files are sorted by their path.
+func Compute(projectDir string) (*Metrics, error) {
+	m := &Metrics{Files: []FileMetrics{}}
+	err := filepath.WalkDir(
+		projectDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != projectDir && (strings.HasPrefix(d.Name(), ".") || skippedDirs[d.Name()]) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			lang := languageOf(path)
+			if lang == nil || !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(projectDir, path)
+			if err != nil {
+				return err
+			}
+			f, err := measureFile(path, lang)
+			if err != nil {
+				return err
+			}
+			f.File = filepath.ToSlash(rel)
+			m.Files = append(m.Files, f)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].File < m.Files[j].File })
+	for _, f := range m.Files {
+		m.Total.Lines += f.Lines
+		m.Total.Code += f.Code
+		m.Total.Comments += f.Comments
+		m.Total.Blank += f.Blank
+		m.Total.Complexity += f.Complexity
+	}
+	return m, nil
+}
+
+// Write writes the metrics to metrics.json in dir.
+func (m *Metrics) Write(dir string) (string, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, FileName)
+	return path, os.WriteFile(path, data, 0o644)
+}
+
+func measureFile(path string, lang *language) (FileMetrics, error) {
+	m := FileMetrics{Language: lang.name}
+	f, err := os.Open(path)
+	if err != nil {
+		return m, err
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	inBlock := false
+	for scanner.Scan() {
+		m.Lines++
+		code, comment := splitLine(strings.TrimSpace(scanner.Text()), lang, &inBlock)
+		switch {
+		case code != "":
+			m.Code++
+			m.Complexity += branches(code, lang)
+		case comment:
+			m.Comments++
+		default:
+			m.Blank++
+		}
+	}
+	return m, scanner.Err()
+}
+
+// splitLine returns the code of the line without its comments, and whether the line has a comment.
+// inBlock tracks the block comments spanning lines.
+func splitLine(line string, lang *language, inBlock *bool) (string, bool) {
+	var code strings.Builder
+	comment := false
+	for line != "" {
+		if *inBlock {
+			comment = true
+			end := strings.Index(line, lang.blockComment[1])
+			if end < 0 {
+				return strings.TrimSpace(code.String()), comment
+			}
+			line = line[end+len(lang.blockComment[1]):]
+			*inBlock = false
+			continue
+		}
+		next, isBlock := len(line), false
+		for _, prefix := range lang.lineComments {
+			if i := strings.Index(line, prefix); i >= 0 && i < next {
+				next = i
+			}
+		}
+		if lang.blockComment[0] != "" {
+			if i := strings.Index(line, lang.blockComment[0]); i >= 0 && i < next {
+				next, isBlock = i, true
+			}
+		}
+		code.WriteString(line[:next])
+		if next == len(line) {
+			break
+		}
+		comment = true
+		if !isBlock {
+			break
+		}
+		line = line[next+len(lang.blockComment[0]):]
+		*inBlock = true
+	}
+	return strings.TrimSpace(code.String()), comment
+}
+
+// branches counts the branching keywords and operators of the code.
+func branches(code string, lang *language) int {
+	count := 0
+	for _, operator := range lang.operators {
+		count += strings.Count(code, operator)
+	}
+	words := strings.FieldsFunc(
+		code, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		},
+	)
+	for _, word := range words {
+		for _, keyword := range lang.keywords {
+			if word == keyword {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// Condition fails the run if the metric exceeds the maximum: lines, code or complexity of any file,
+// or total-lines, total-code or total-complexity of the project.
+type Condition struct {
+	Metric string
+	Max    int
+}
+
+// conditionMetrics are the metrics of the conditions.
+var conditionMetrics = []string{"lines", "code", "complexity", "total-lines", "total-code", "total-complexity"}
+
+// ParseConditions parses the METRIC=MAX conditions.
+func ParseConditions(entries []string) ([]Condition, error) {
+	conditions := make([]Condition, 0, len(entries))
+	for _, entry := range entries {
+		metric, value, found := strings.Cut(entry, "=")
+		metric = strings.ToLower(strings.TrimSpace(metric))
+		maxValue, err := strconv.Atoi(strings.TrimSpace(value))
+		if !found || err != nil || maxValue < 0 || !slices.Contains(conditionMetrics, metric) {
+			return nil, fmt.Errorf(
+				"invalid metric condition %q, expected METRIC=MAX with METRIC one of %s",
+				entry,
+				strings.Join(conditionMetrics, ", "),
+			)
+		}
+		conditions = append(conditions, Condition{Metric: metric, Max: maxValue})
+	}
+	return conditions, nil
+}
+
+// Check returns the descriptions of the violated conditions.
+func (m *Metrics) Check(conditions []Condition) []string {
+	var violations []string
+	for _, c := range conditions {
+		if total, ok := strings.CutPrefix(c.Metric, "total-"); ok {
+			if value := metricValue(m.Total, total); value > c.Max {
+				violations = append(violations, fmt.Sprintf("%s %d exceeds %d", c.Metric, value, c.Max))
+			}
+			continue
+		}
+		for _, f := range m.Files {
+			if value := metricValue(f, c.Metric); value > c.Max {
+				violations = append(violations, fmt.Sprintf("%s of %s %d exceeds %d", c.Metric, f.File, value, c.Max))
+			}
+		}
+	}
+	return violations
+}
+
+func metricValue(f FileMetrics, metric string) int {
+	switch metric {
+	case "lines":
+		return f.Lines
+	case "code":
+		return f.Code
+	default:
+		return f.Complexity
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcodemetrics
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompute(t *testing.T) {
+	dir := t.TempDir()
+	for path, content := range map[string]string{
+		"main.go": `package main
+
+// main prints the arguments
+func main() {
+	/* a block
+	   comment */
+	for _, arg := range os.Args { // the arguments
+		if arg != "" && arg != "-" {
+			fmt.Println(arg) /* inline */
+		}
+	}
+}
+`,
+		"scripts/run.py":      "# run\nif a and b:\n    pass\n\nelif c:\n    pass",
+		"README.md":           "# not measured\n",
+		"node_modules/x.js":   "if (a) {}\n",
+		".idea/workspace.go":  "package idea\n",
+		"src/verify_if_me.ts": "const verify_if = notify;\n",
+	} {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := Compute(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []FileMetrics{
+		{File: "main.go", Language: "Go", Lines: 12, Code: 8, Comments: 3, Blank: 1, Complexity: 3},
+		{File: "scripts/run.py", Language: "Python", Lines: 6, Code: 4, Comments: 1, Blank: 1, Complexity: 3},
+		{File: "src/verify_if_me.ts", Language: "TypeScript", Lines: 1, Code: 1},
+	}
+	if !reflect.DeepEqual(m.Files, expected) {
+		t.Errorf("got %+v, want %+v", m.Files, expected)
+	}
+	if m.Total.Lines != 19 || m.Total.Code != 13 || m.Total.Complexity != 6 {
+		t.Errorf("unexpected totals %+v", m.Total)
+	}
+
+	path, err := m.Write(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	m := &Metrics{
+		Files: []FileMetrics{
+			{File: "a.go", Lines: 100, Code: 80, Complexity: 12},
+			{File: "b.go", Lines: 300, Code: 250, Complexity: 4},
+		},
+		Total: FileMetrics{Lines: 400, Code: 330, Complexity: 16},
+	}
+	conditions, err := ParseConditions([]string{"complexity=10", "Total-Code = 300", "lines=500"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"complexity of a.go 12 exceeds 10", "total-code 330 exceeds 300"}
+	if violations := m.Check(conditions); !reflect.DeepEqual(violations, expected) {
+		t.Errorf("got %v, want %v", violations, expected)
+	}
+	for _, entry := range []string{"complexity", "methods=3", "lines=-1", "code=many"} {
+		if _, err := ParseConditions([]string{entry}); err == nil {
+			t.Errorf("expected an error for %s", entry)
+		}
+	}
+}
@@ -19,6 +19,7 @@ package qdcontainer
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
 	"github.com/docker/docker/client"
@@ -34,13 +35,35 @@ func checkRequiredToolInstalled(tool string) bool {
 	return err == nil
 }
 
-func PrepareContainerEnvSettings() {
-	var tool string
+// containerEngineTool returns the container engine CLI to use, or an empty string if none is installed.
+func containerEngineTool() string {
 	if os.Getenv(qdenv.QodanaCliUsePodman) == "" && checkRequiredToolInstalled("docker") {
-		tool = "docker"
+		return "docker"
 	} else if checkRequiredToolInstalled("podman") {
-		tool = "podman"
-	} else {
+		return "podman"
+	}
+	return ""
+}
+
+// CheckContainerEngine checks that the container engine is installed and can be used by the current user.
+func CheckContainerEngine() (string, error) {
+	tool := containerEngineTool()
+	if tool == "" {
+		return "", errors.New("docker (or podman) is not installed on the system or can't be found in PATH")
+	}
+	output, err := exec.Command(tool, "ps").CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(output), "permission denied") {
+			return tool, fmt.Errorf("%s can't be run by the current user", tool)
+		}
+		return tool, fmt.Errorf("'%s ps' failed, perhaps the %s daemon is not running: %w", tool, tool, err)
+	}
+	return tool, nil
+}
+
+func PrepareContainerEnvSettings() {
+	tool := containerEngineTool()
+	if tool == "" {
 		msg.ErrorMessage(
 			"Docker (or podman) is not installed on the system or can't be found in PATH, refer to https://www.docker.com/get-started for installing it",
 		)
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcontainer
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"github.com/docker/docker/api/types/system"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+const (
+	// desktopHostMountDir is where the WSL 2 engine of Docker Desktop sees the Windows drives.
+	desktopHostMountDir = "/run/desktop/mnt/host"
+	// wslSyncDir is the directory in the WSL filesystem, relative to the home directory, with the synced projects.
+	wslSyncDir = ".cache/JetBrains/Qodana/wsl"
+	// wslSyncScript copies the project ($1) to $HOME/<wslSyncDir>/<name> ($2) and prints the distribution and the copy,
+	// the Windows paths are translated with wslpath.
+	wslSyncScript = `set -e; src="$1"; case "$src" in [A-Za-z]:*) src="$(wslpath -u "$src")" ;; esac; ` +
+		`target="$HOME/` + wslSyncDir + `/$2"; mkdir -p "$target"; rsync -a --delete "$src/" "$target/"; ` +
+		`echo "$WSL_DISTRO_NAME"; echo "$target"`
+)
+
+// wslDrivePath matches the Windows drives mounted in a WSL distribution, e.g. /mnt/c/work.
+var wslDrivePath = regexp.MustCompile(`^/mnt/[a-zA-Z](/|$)`)
+
+// IsWsl2Engine reports whether the container engine is Docker Desktop running in the WSL 2 VM.
+func IsWsl2Engine(info system.Info) bool {
+	return strings.Contains(info.OperatingSystem, "Docker Desktop") &&
+		strings.Contains(strings.ToLower(info.KernelVersion), "wsl2")
+}
+
+// IsWindowsDrivePath reports whether the path is on a Windows drive, C:\work on Windows or /mnt/c/work inside WSL:
+// bind mounts of these paths into the WSL 2 engine go through the 9P file server and are slow.
+func IsWindowsDrivePath(path string) bool {
+	return utils.HasDriveLetter(path) || wslDrivePath.MatchString(filepath.ToSlash(path))
+}
+
+// WslMountSource returns the source of the bind mount of path as the WSL 2 engine sees it: the Windows drives are
+// under /run/desktop/mnt/host, the other paths, e.g. \\wsl$\Ubuntu\home\user\project, are returned as is.
+func WslMountSource(path string) string {
+	path = utils.NormalizeWindowsPath(path)
+	if !utils.HasDriveLetter(path) {
+		return path
+	}
+	rest := strings.TrimSuffix(strings.ReplaceAll(path[2:], `\`, "/"), "/")
+	if rest != "" && !strings.HasPrefix(rest, "/") {
+		rest = "/" + rest
+	}
+	return desktopHostMountDir + "/" + strings.ToLower(path[:1]) + rest
+}
+
+// SyncToWsl copies the project with rsync into the WSL filesystem and returns the path of the copy to mount, the copy
+// is kept between the runs so only the changes are copied next time. On Windows the copy is made in the default
+// WSL distribution and its \\wsl$ path is returned.
+func SyncToWsl(ctx context.Context, projectDir string) (string, error) {
+	args := []string{"sh", "-c", wslSyncScript, "sh", projectDir, wslSyncName(projectDir)}
+	if //goland:noinspection GoBoolExpressions
+	runtime.GOOS == "windows" {
+		args = append([]string{"wsl.exe", "-e"}, args...)
+	}
+	output, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to copy %s into WSL: %w\n%s", projectDir, err, output)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) < 2 {
+		return "", fmt.Errorf("failed to copy %s into WSL: unexpected output %q", projectDir, output)
+	}
+	distribution, target := strings.TrimSpace(lines[len(lines)-2]), strings.TrimSpace(lines[len(lines)-1])
+	if //goland:noinspection GoBoolExpressions
+	runtime.GOOS == "windows" {
+		return wslUncPath(distribution, target), nil
+	}
+	return target, nil
+}
+
+// wslSyncName returns the name of the copy of the project, stable for the project directory.
+func wslSyncName(projectDir string) string {
+	trimmed := strings.TrimRight(projectDir, `/\`)
+	base := strings.NewReplacer(":", "_", " ", "_").Replace(trimmed[strings.LastIndexAny(trimmed, `/\`)+1:])
+	return fmt.Sprintf("%s-%x", base, sha256.Sum256([]byte(projectDir)))[:len(base)+13]
+}
+
+// wslUncPath returns the Windows path of the file in the WSL distribution, e.g. \\wsl$\Ubuntu\home\user.
+func wslUncPath(distribution string, path string) string {
+	return `\\wsl$\` + distribution + strings.ReplaceAll(path, "/", `\`)
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcontainer
+
+import (
+	"github.com/docker/docker/api/types/system"
+	"testing"
+)
+
+func TestIsWsl2Engine(t *testing.T) {
+	for _, tc := range []struct {
+		info     system.Info
+		expected bool
+	}{
+		{system.Info{OperatingSystem: "Docker Desktop", KernelVersion: "5.15.133.1-microsoft-standard-WSL2"}, true},
+		{system.Info{OperatingSystem: "Docker Desktop", KernelVersion: "6.6.16-linuxkit"}, false},
+		{system.Info{OperatingSystem: "Ubuntu 22.04.4 LTS", KernelVersion: "5.15.0-105-generic"}, false},
+	} {
+		if actual := IsWsl2Engine(tc.info); actual != tc.expected {
+			t.Errorf("IsWsl2Engine(%s, %s) = %v, want %v", tc.info.OperatingSystem, tc.info.KernelVersion, actual, tc.expected)
+		}
+	}
+}
+
+func TestIsWindowsDrivePath(t *testing.T) {
+	for path, expected := range map[string]bool{
+		`C:\work\project`:               true,
+		`\\?\D:\work\project`:           true,
+		"/mnt/c/work/project":           true,
+		"/mnt/d":                        true,
+		"/mnt/data/project":             false,
+		"/home/user/project":            false,
+		`\\wsl$\Ubuntu\home\user\cache`: false,
+	} {
+		if actual := IsWindowsDrivePath(path); actual != expected {
+			t.Errorf("IsWindowsDrivePath(%q) = %v, want %v", path, actual, expected)
+		}
+	}
+}
+
+func TestWslMountSource(t *testing.T) {
+	for path, expected := range map[string]string{
+		`C:\work\project`:                 "/run/desktop/mnt/host/c/work/project",
+		`D:\`:                             "/run/desktop/mnt/host/d",
+		`\\?\E:\work\project\`:            "/run/desktop/mnt/host/e/work/project",
+		`\\wsl$\Ubuntu\home\user\project`: `\\wsl$\Ubuntu\home\user\project`,
+		"/home/user/project":              "/home/user/project",
+	} {
+		if actual := WslMountSource(path); actual != expected {
+			t.Errorf("WslMountSource(%q) = %q, want %q", path, actual, expected)
+		}
+	}
+}
+
+func TestWslSyncName(t *testing.T) {
+	name := wslSyncName(`C:\work\my project\`)
+	if len(name) != len("my_project")+13 || name[:len("my_project-")] != "my_project-" {
+		t.Errorf("unexpected name of the copy %s", name)
+	}
+	if name == wslSyncName(`D:\work\my project`) {
+		t.Error("the copies of different projects must not share the name")
+	}
+	if name != wslSyncName(`C:\work\my project\`) {
+		t.Error("the copy of the project must keep the name between the runs")
+	}
+	if path := wslUncPath("Ubuntu", "/home/user/"+name); path != `\\wsl$\Ubuntu\home\user\`+name {
+		t.Errorf("unexpected path of the copy %s", path)
+	}
+}
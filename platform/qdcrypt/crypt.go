@@ -0,0 +1,333 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdcrypt encrypts the results archives with AES-256-GCM, either with a shared key
+// or for the X25519 public key of a recipient, who decrypts them with the private key (the identity).
+package qdcrypt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// KeyEnv is the environment variable with the shared key, used if no key file or recipient is set.
+	KeyEnv = "QODANA_ENCRYPTION_KEY"
+	// KeySize is the size of the shared keys and of the X25519 keys.
+	KeySize = 32
+
+	magic         = "qodana-encrypted/v1\n"
+	modeKey       = byte(1)
+	modeRecipient = byte(2)
+	saltSize      = 16
+	chunkSize     = 64 * 1024
+	fileKeyInfo   = "qodana results"
+)
+
+// Encryption encrypts the archives with a shared key or for a recipient.
+type Encryption struct {
+	key       []byte
+	recipient *ecdh.PublicKey
+}
+
+// Load returns the encryption with the shared key read from keyFile or the KeyEnv variable, or for the recipient,
+// nil if none of them is set.
+func Load(keyFile string, recipient string) (*Encryption, error) {
+	if keyFile != "" && recipient != "" {
+		return nil, fmt.Errorf("either an encryption key or a recipient is expected, not both")
+	}
+	if recipient != "" {
+		publicKey, err := ParseRecipient(recipient)
+		if err != nil {
+			return nil, err
+		}
+		return &Encryption{recipient: publicKey}, nil
+	}
+	encoded := os.Getenv(KeyEnv)
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the encryption key: %w", err)
+		}
+		encoded = string(data)
+	}
+	if encoded == "" {
+		return nil, nil
+	}
+	key, err := ParseKey(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return &Encryption{key: key}, nil
+}
+
+// ParseKey decodes the base64-encoded shared key.
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != KeySize {
+		return nil, fmt.Errorf("the encryption key must be %d base64-encoded bytes", KeySize)
+	}
+	return key, nil
+}
+
+// ParseRecipient decodes the base64-encoded X25519 public key of the recipient.
+func ParseRecipient(encoded string) (*ecdh.PublicKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	publicKey, err := ecdh.X25519().NewPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	return publicKey, nil
+}
+
+// ParseIdentity decodes the base64-encoded X25519 private key of the recipient.
+func ParseIdentity(encoded string) (*ecdh.PrivateKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity: %w", err)
+	}
+	privateKey, err := ecdh.X25519().NewPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity: %w", err)
+	}
+	return privateKey, nil
+}
+
+// GenerateKey returns a new base64-encoded shared key.
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// GenerateIdentity returns a new base64-encoded identity and the recipient of the archives it decrypts.
+func GenerateIdentity() (identity string, recipient string, err error) {
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(privateKey.Bytes()),
+		base64.StdEncoding.EncodeToString(privateKey.PublicKey().Bytes()),
+		nil
+}
+
+// Encrypt returns the writer encrypting the data written to it to w, it must be closed to write the last chunk.
+func (e *Encryption) Encrypt(w io.Writer) (io.WriteCloser, error) {
+	header := bytes.NewBufferString(magic)
+	var fileKey []byte
+	if e.recipient != nil {
+		ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		shared, err := ephemeral.ECDH(e.recipient)
+		if err != nil {
+			return nil, err
+		}
+		header.WriteByte(modeRecipient)
+		header.Write(ephemeral.PublicKey().Bytes())
+		fileKey = deriveKey(shared, ephemeral.PublicKey().Bytes(), e.recipient.Bytes())
+	} else {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		header.WriteByte(modeKey)
+		header.Write(salt)
+		fileKey = deriveKey(e.key, salt)
+	}
+	aead, err := newAead(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(header.Bytes()); err != nil {
+		return nil, err
+	}
+	return &writer{w: w, aead: aead, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+// Decrypt returns the reader of the data encrypted to r with the shared key or for the identity.
+func Decrypt(r io.Reader, key []byte, identity *ecdh.PrivateKey) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(br, header); err != nil || string(header[:len(magic)]) != magic {
+		return nil, fmt.Errorf("not a Qodana encrypted archive")
+	}
+	var fileKey []byte
+	switch header[len(magic)] {
+	case modeKey:
+		if key == nil {
+			return nil, fmt.Errorf("the archive is encrypted with a shared key, the key is required to decrypt it")
+		}
+		salt := make([]byte, saltSize)
+		if _, err := io.ReadFull(br, salt); err != nil {
+			return nil, err
+		}
+		fileKey = deriveKey(key, salt)
+	case modeRecipient:
+		if identity == nil {
+			return nil, fmt.Errorf("the archive is encrypted for a recipient, its identity is required to decrypt it")
+		}
+		ephemeralBytes := make([]byte, KeySize)
+		if _, err := io.ReadFull(br, ephemeralBytes); err != nil {
+			return nil, err
+		}
+		ephemeral, err := ecdh.X25519().NewPublicKey(ephemeralBytes)
+		if err != nil {
+			return nil, err
+		}
+		shared, err := identity.ECDH(ephemeral)
+		if err != nil {
+			return nil, err
+		}
+		fileKey = deriveKey(shared, ephemeralBytes, identity.PublicKey().Bytes())
+	default:
+		return nil, fmt.Errorf("unsupported encryption mode %d", header[len(magic)])
+	}
+	aead, err := newAead(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	return &reader{r: br, aead: aead}, nil
+}
+
+func deriveKey(secret []byte, context ...[]byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(fileKeyInfo))
+	for _, c := range context {
+		mac.Write(c)
+	}
+	return mac.Sum(nil)
+}
+
+func newAead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonce of the chunk is its counter followed by 1 for the last chunk, so the truncated or reordered archives
+// fail to decrypt.
+func nonce(counter uint64, last bool) []byte {
+	n := make([]byte, 12)
+	binary.BigEndian.PutUint64(n[3:11], counter)
+	if last {
+		n[11] = 1
+	}
+	return n
+}
+
+type writer struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	buf     []byte
+	counter uint64
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// a full chunk is written once more data follows, the last one is written by Close
+		if len(w.buf) == chunkSize {
+			if err := w.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buf[len(w.buf):chunkSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (w *writer) flush(last bool) error {
+	sealed := w.aead.Seal(nil, nonce(w.counter, last), w.buf, nil)
+	w.counter++
+	w.buf = w.buf[:0]
+	_, err := w.w.Write(sealed)
+	return err
+}
+
+func (w *writer) Close() error {
+	return w.flush(true)
+}
+
+type reader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	counter uint64
+	plain   []byte
+	done    bool
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *reader) next() error {
+	sealed := make([]byte, chunkSize+r.aead.Overhead())
+	n, err := io.ReadFull(r.r, sealed)
+	last := false
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, peekErr := r.r.Peek(1); peekErr == io.EOF {
+			last = true
+		}
+	}
+	plain, err := r.aead.Open(nil, nonce(r.counter, last), sealed[:n], nil)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt the archive, the key is wrong or the archive is damaged")
+	}
+	r.counter++
+	r.plain = plain
+	r.done = last
+	return nil
+}
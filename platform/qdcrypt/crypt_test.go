@@ -0,0 +1,144 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdcrypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func encrypt(t *testing.T, e *Encryption, data []byte) []byte {
+	var encrypted bytes.Buffer
+	w, err := e.Encrypt(&encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// odd writes to cross the chunk boundaries
+	for len(data) > 0 {
+		n := min(len(data), 10000)
+		if _, err = w.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return encrypted.Bytes()
+}
+
+func TestSharedKey(t *testing.T) {
+	encoded, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err = os.WriteFile(keyFile, []byte(encoded+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	e, err := Load(keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := ParseKey(encoded)
+	for _, size := range []int{0, 1, chunkSize, 3*chunkSize + 17} {
+		data := make([]byte, size)
+		_, _ = rand.Read(data)
+		encrypted := encrypt(t, e, data)
+		r, err := Decrypt(bytes.NewReader(encrypted), key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("size %d: %s", size, err)
+		}
+		if !bytes.Equal(decrypted, data) {
+			t.Errorf("size %d: decrypted data differs", size)
+		}
+		if size > chunkSize {
+			// dropping the last chunk must be detected
+			truncated := encrypted[:len(encrypted)-(size%chunkSize)-16]
+			r, err = Decrypt(bytes.NewReader(truncated), key, nil)
+			if err == nil {
+				_, err = io.ReadAll(r)
+			}
+			if err == nil {
+				t.Errorf("size %d: expected an error for a truncated archive", size)
+			}
+		}
+	}
+
+	other, _ := GenerateKey()
+	otherKey, _ := ParseKey(other)
+	r, err := Decrypt(bytes.NewReader(encrypt(t, e, []byte("results"))), otherKey, nil)
+	if err == nil {
+		_, err = io.ReadAll(r)
+	}
+	if err == nil {
+		t.Error("expected an error for a wrong key")
+	}
+}
+
+func TestRecipient(t *testing.T) {
+	identityEncoded, recipient, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := Load("", recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := ParseIdentity(identityEncoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("qodana.sarif.json "), 10000)
+	encrypted := encrypt(t, e, data)
+	if _, err = Decrypt(bytes.NewReader(encrypted), nil, nil); err == nil {
+		t.Error("expected an error without the identity")
+	}
+	r, err := Decrypt(bytes.NewReader(encrypted), nil, identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, data) {
+		t.Error("decrypted data differs")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv(KeyEnv, "")
+	if e, err := Load("", ""); e != nil || err != nil {
+		t.Errorf("Load() = %v, %v, want no encryption", e, err)
+	}
+	t.Setenv(KeyEnv, "short")
+	if _, err := Load("", ""); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+	if _, err := Load("key", "recipient"); err == nil {
+		t.Error("expected an error for both a key and a recipient")
+	}
+}
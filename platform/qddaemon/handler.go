@@ -0,0 +1,131 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qddaemon
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const logPollInterval = 200 * time.Millisecond
+
+// Handler serves the control API of the manager:
+// POST /api/v1/scans starts a scan, GET /api/v1/scans lists the scans, GET /api/v1/scans/{id} returns the status,
+// POST /api/v1/scans/{id}/cancel cancels the scan and GET /api/v1/scans/{id}/logs returns the output,
+// streamed until the scan finishes with ?follow=true.
+func Handler(m *Manager) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(
+		"POST /api/v1/scans", func(w http.ResponseWriter, r *http.Request) {
+			var request ScanRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				http.Error(w, "invalid scan request: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			scan, err := m.Start(request)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJson(w, http.StatusAccepted, scan)
+		},
+	)
+	mux.HandleFunc(
+		"GET /api/v1/scans", func(w http.ResponseWriter, r *http.Request) {
+			writeJson(w, http.StatusOK, m.List())
+		},
+	)
+	mux.HandleFunc(
+		"GET /api/v1/scans/{id}", func(w http.ResponseWriter, r *http.Request) {
+			scan, err := m.Status(r.PathValue("id"))
+			writeScan(w, scan, err)
+		},
+	)
+	mux.HandleFunc(
+		"POST /api/v1/scans/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+			scan, err := m.Cancel(r.PathValue("id"))
+			writeScan(w, scan, err)
+		},
+	)
+	mux.HandleFunc(
+		"GET /api/v1/scans/{id}/logs", func(w http.ResponseWriter, r *http.Request) {
+			logFile, done, err := m.LogFile(r.PathValue("id"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			streamLog(w, r, logFile, done, r.URL.Query().Get("follow") == "true")
+		},
+	)
+	return mux
+}
+
+func writeScan(w http.ResponseWriter, scan Scan, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJson(w, http.StatusOK, scan)
+}
+
+func writeJson(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func streamLog(w http.ResponseWriter, r *http.Request, logFile string, done <-chan struct{}, follow bool) {
+	f, err := os.Open(logFile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	flusher, _ := w.(http.Flusher)
+	for {
+		// check before copying so that the output written before the scan finished is not lost
+		finished := isClosed(done)
+		if _, err = io.Copy(w, f); err != nil || !follow || finished {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-done:
+		case <-time.After(logPollInterval):
+		}
+	}
+}
+
+func isClosed(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qddaemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHelperProcess stands for qodana scan in the tests.
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv("QODANA_DAEMON_HELPER")
+	if mode == "" {
+		return
+	}
+	fmt.Println(strings.Join(os.Args[3:], " "))
+	switch mode {
+	case "fail":
+		os.Exit(255)
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+	os.Exit(0)
+}
+
+func helperCommand(mode string) CommandFunc {
+	return func(args []string) *exec.Cmd {
+		cmd := exec.Command(os.Args[0], append([]string{"-test.run=TestHelperProcess", "--"}, args...)...)
+		cmd.Env = append(os.Environ(), "QODANA_DAEMON_HELPER="+mode)
+		return cmd
+	}
+}
+
+func startScan(t *testing.T, url string, projectDir string, args ...string) Scan {
+	body, _ := json.Marshal(ScanRequest{ProjectDir: projectDir, Args: args})
+	resp, err := http.Post(url+"/api/v1/scans", "application/json", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+	var scan Scan
+	if err = json.NewDecoder(resp.Body).Decode(&scan); err != nil {
+		t.Fatal(err)
+	}
+	return scan
+}
+
+func getText(t *testing.T, url string) string {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func getScan(t *testing.T, url string, id string) Scan {
+	var scan Scan
+	if err := json.Unmarshal([]byte(getText(t, url+"/api/v1/scans/"+id)), &scan); err != nil {
+		t.Fatal(err)
+	}
+	return scan
+}
+
+func TestDaemonScans(t *testing.T) {
+	projectDir := t.TempDir()
+	m := NewManager(t.TempDir(), 1, helperCommand("ok"))
+	server := httptest.NewServer(Handler(m))
+	defer server.Close()
+
+	first := startScan(t, server.URL, projectDir, "--linter", "qodana-jvm")
+	logs := getText(t, server.URL+"/api/v1/scans/"+first.Id+"/logs?follow=true")
+	if !strings.Contains(logs, "--project-dir "+projectDir) || !strings.Contains(logs, "--cache-dir") ||
+		strings.Contains(logs, "--skip-pull") {
+		t.Fatalf("unexpected first scan arguments: %s", logs)
+	}
+	status := getScan(t, server.URL, first.Id)
+	if status.State != Succeeded || status.ExitCode == nil || *status.ExitCode != 0 || status.FinishedAt == nil {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+
+	second := startScan(t, server.URL, projectDir, "--linter", "qodana-jvm")
+	logs = getText(t, server.URL+"/api/v1/scans/"+second.Id+"/logs?follow=true")
+	if !strings.Contains(logs, "--skip-pull") {
+		t.Fatalf("expected the second scan to skip the pull: %s", logs)
+	}
+
+	var scans []Scan
+	if err := json.Unmarshal([]byte(getText(t, server.URL+"/api/v1/scans")), &scans); err != nil {
+		t.Fatal(err)
+	}
+	if len(scans) != 2 || scans[0].Id != first.Id || scans[1].Id != second.Id {
+		t.Fatalf("unexpected scans: %+v", scans)
+	}
+
+	resp, err := http.Get(server.URL + "/api/v1/scans/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+	resp, err = http.Post(server.URL+"/api/v1/scans", "application/json", strings.NewReader(`{"projectDir":"relative"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestDaemonFailedScan(t *testing.T) {
+	m := NewManager(t.TempDir(), 1, helperCommand("fail"))
+	server := httptest.NewServer(Handler(m))
+	defer server.Close()
+
+	scan := startScan(t, server.URL, t.TempDir())
+	getText(t, server.URL+"/api/v1/scans/"+scan.Id+"/logs?follow=true")
+	status := getScan(t, server.URL, scan.Id)
+	if status.State != Failed || status.ExitCode == nil || *status.ExitCode != 255 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
+
+func TestDaemonCancel(t *testing.T) {
+	m := NewManager(t.TempDir(), 1, helperCommand("hang"))
+	server := httptest.NewServer(Handler(m))
+	defer server.Close()
+
+	running := startScan(t, server.URL, t.TempDir())
+	queued := startScan(t, server.URL, t.TempDir())
+	for getScan(t, server.URL, running.Id).State != Running {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, id := range []string{queued.Id, running.Id} {
+		resp, err := http.Post(server.URL+"/api/v1/scans/"+id+"/cancel", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+	getText(t, server.URL+"/api/v1/scans/"+running.Id+"/logs?follow=true")
+	for _, id := range []string{queued.Id, running.Id} {
+		if status := getScan(t, server.URL, id); status.State != Cancelled || status.FinishedAt == nil {
+			t.Fatalf("unexpected status: %+v", status)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qddaemon keeps the state of the scans run by qodana daemon.
+package qddaemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State is the state of a scan.
+type State string
+
+const (
+	Queued    State = "queued"
+	Running   State = "running"
+	Succeeded State = "succeeded"
+	Failed    State = "failed"
+	Cancelled State = "cancelled"
+)
+
+// Done reports whether the scan with the state has finished.
+func (s State) Done() bool {
+	return s == Succeeded || s == Failed || s == Cancelled
+}
+
+// ScanRequest is the body of a request starting a scan.
+type ScanRequest struct {
+	// ProjectDir is the absolute path of the project to analyze.
+	ProjectDir string `json:"projectDir"`
+	// Args are the additional qodana scan arguments.
+	Args []string `json:"args,omitempty"`
+}
+
+// Scan is the status of a scan.
+type Scan struct {
+	Id         string     `json:"id"`
+	ProjectDir string     `json:"projectDir"`
+	Args       []string   `json:"args"`
+	State      State      `json:"state"`
+	ExitCode   *int       `json:"exitCode,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	ResultsDir string     `json:"resultsDir"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// CommandFunc returns the command running qodana with args.
+type CommandFunc func(args []string) *exec.Cmd
+
+// ErrNotFound is returned for unknown scan ids.
+var ErrNotFound = errors.New("scan not found")
+
+// Manager runs the requested scans, at most parallel at a time, keeping a cache directory per project
+// so that subsequent scans of a project start warm, and skipping the image pull after the first scan of a project.
+type Manager struct {
+	dir     string
+	command CommandFunc
+	queue   chan *scan
+
+	mu     sync.Mutex
+	scans  map[string]*scan
+	warmed map[string]bool
+}
+
+type scan struct {
+	Scan
+	logFile string
+	cmd     *exec.Cmd
+	done    chan struct{}
+}
+
+// NewManager returns a manager keeping its data in dir and running up to parallel scans with command.
+func NewManager(dir string, parallel int, command CommandFunc) *Manager {
+	m := &Manager{
+		dir:     dir,
+		command: command,
+		queue:   make(chan *scan, 1024),
+		scans:   map[string]*scan{},
+		warmed:  map[string]bool{},
+	}
+	for i := 0; i < max(parallel, 1); i++ {
+		go m.work()
+	}
+	return m
+}
+
+// Start queues a new scan.
+func (m *Manager) Start(request ScanRequest) (Scan, error) {
+	if request.ProjectDir == "" || !filepath.IsAbs(request.ProjectDir) {
+		return Scan{}, errors.New("projectDir must be an absolute path")
+	}
+	if info, err := os.Stat(request.ProjectDir); err != nil || !info.IsDir() {
+		return Scan{}, fmt.Errorf("project directory %s does not exist", request.ProjectDir)
+	}
+	id := uuid.New().String()
+	scanDir := filepath.Join(m.dir, "scans", id)
+	if err := os.MkdirAll(scanDir, os.ModePerm); err != nil {
+		return Scan{}, err
+	}
+	resultsDir := filepath.Join(scanDir, "results")
+	if value, ok := flagValue(request.Args, "results-dir", "o"); ok {
+		resultsDir = value
+	}
+	s := &scan{
+		Scan: Scan{
+			Id:         id,
+			ProjectDir: filepath.Clean(request.ProjectDir),
+			Args:       append([]string{}, request.Args...),
+			State:      Queued,
+			ResultsDir: resultsDir,
+			CreatedAt:  time.Now(),
+		},
+		logFile: filepath.Join(scanDir, "scan.log"),
+		done:    make(chan struct{}),
+	}
+	if err := os.WriteFile(s.logFile, nil, 0o644); err != nil {
+		return Scan{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case m.queue <- s:
+	default:
+		return Scan{}, errors.New("too many queued scans")
+	}
+	m.scans[id] = s
+	return s.Scan, nil
+}
+
+// Status returns the status of the scan.
+func (m *Manager) Status(id string) (Scan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.scans[id]
+	if !ok {
+		return Scan{}, ErrNotFound
+	}
+	return s.Scan, nil
+}
+
+// List returns the statuses of all scans, oldest first.
+func (m *Manager) List() []Scan {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	scans := make([]Scan, 0, len(m.scans))
+	for _, s := range m.scans {
+		scans = append(scans, s.Scan)
+	}
+	sortScans(scans)
+	return scans
+}
+
+// Cancel drops a queued scan or interrupts a running one.
+func (m *Manager) Cancel(id string) (Scan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.scans[id]
+	if !ok {
+		return Scan{}, ErrNotFound
+	}
+	switch s.State {
+	case Queued:
+		m.finish(s, Cancelled, nil, "")
+	case Running:
+		s.State = Cancelled
+		interrupt(s.cmd)
+	}
+	return s.Scan, nil
+}
+
+// LogFile returns the path of the scan output and a channel closed when the scan finishes.
+func (m *Manager) LogFile(id string) (string, <-chan struct{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.scans[id]
+	if !ok {
+		return "", nil, ErrNotFound
+	}
+	return s.logFile, s.done, nil
+}
+
+func (m *Manager) work() {
+	for s := range m.queue {
+		m.run(s)
+	}
+}
+
+func (m *Manager) run(s *scan) {
+	m.mu.Lock()
+	if s.State != Queued {
+		m.mu.Unlock()
+		return
+	}
+	out, err := os.OpenFile(s.logFile, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		m.finish(s, Failed, nil, err.Error())
+		m.mu.Unlock()
+		return
+	}
+	defer func(out *os.File) {
+		_ = out.Close()
+	}(out)
+	s.cmd = m.command(m.scanArgs(s))
+	s.cmd.Stdout, s.cmd.Stderr = out, out
+	if err = s.cmd.Start(); err != nil {
+		m.finish(s, Failed, nil, err.Error())
+		m.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	s.State, s.StartedAt = Running, &now
+	m.mu.Unlock()
+	log.Infof("Started scan %s of %s", s.Id, s.ProjectDir)
+
+	err = s.cmd.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	exitCode := s.cmd.ProcessState.ExitCode()
+	state := Succeeded
+	message := ""
+	var exitErr *exec.ExitError
+	switch {
+	case s.State == Cancelled:
+		state = Cancelled
+	case errors.As(err, &exitErr):
+		state = Failed
+	case err != nil:
+		state, message = Failed, err.Error()
+	}
+	if state != Cancelled {
+		m.warmed[s.ProjectDir] = true
+	}
+	m.finish(s, state, &exitCode, message)
+	log.Infof("Scan %s finished: %s", s.Id, state)
+}
+
+// finish must be called with the lock held.
+func (m *Manager) finish(s *scan, state State, exitCode *int, message string) {
+	now := time.Now()
+	s.State, s.ExitCode, s.Error, s.FinishedAt = state, exitCode, message, &now
+	close(s.done)
+}
+
+// scanArgs must be called with the lock held.
+func (m *Manager) scanArgs(s *scan) []string {
+	args := []string{"scan", "--project-dir", s.ProjectDir}
+	if _, ok := flagValue(s.Args, "results-dir", "o"); !ok {
+		args = append(args, "--results-dir", s.ResultsDir)
+	}
+	if _, ok := flagValue(s.Args, "cache-dir", ""); !ok {
+		args = append(args, "--cache-dir", m.cacheDir(s.ProjectDir))
+	}
+	if m.warmed[s.ProjectDir] && !hasFlag(s.Args, "ide", "") && !hasFlag(s.Args, "skip-pull", "") {
+		args = append(args, "--skip-pull")
+	}
+	return append(args, s.Args...)
+}
+
+func (m *Manager) cacheDir(projectDir string) string {
+	sum := sha256.Sum256([]byte(projectDir))
+	return filepath.Join(m.dir, "cache", hex.EncodeToString(sum[:])[:16])
+}
+
+func interrupt(cmd *exec.Cmd) {
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	// qodana scan stops the analysis and the container on interrupt, Windows processes can only be killed
+	if runtime.GOOS == "windows" || cmd.Process.Signal(os.Interrupt) != nil {
+		_ = cmd.Process.Kill()
+	}
+}
+
+func hasFlag(args []string, long string, short string) bool {
+	for _, arg := range args {
+		if arg == "--"+long || strings.HasPrefix(arg, "--"+long+"=") || (short != "" && arg == "-"+short) {
+			return true
+		}
+	}
+	return false
+}
+
+func flagValue(args []string, long string, short string) (string, bool) {
+	for i, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "--"+long+"="); ok {
+			return value, true
+		}
+		if (arg == "--"+long || (short != "" && arg == "-"+short)) && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+func sortScans(scans []Scan) {
+	sort.Slice(
+		scans, func(i, j int) bool {
+			if !scans[i].CreatedAt.Equal(scans[j].CreatedAt) {
+				return scans[i].CreatedAt.Before(scans[j].CreatedAt)
+			}
+			return scans[i].Id < scans[j].Id
+		},
+	)
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdengine detects Unity and Unreal Engine projects: the solution generated by the editor,
+// whether it is older than the files it is generated from, and the directories of the editor caches and build outputs.
+package qdengine
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Engine is the game engine of a project.
+type Engine string
+
+const (
+	Unity  Engine = "Unity"
+	Unreal Engine = "Unreal Engine"
+)
+
+// Project is a game engine project.
+type Project struct {
+	Engine Engine
+	// Solution is the solution generated by the editor relative to the project directory, empty if there is none.
+	Solution string
+	// Descriptor is the file describing the project relative to the project directory, the .uproject of Unreal projects.
+	Descriptor string
+	// Excluded are the directories of the editor caches and build outputs relative to the project directory.
+	Excluded []string
+}
+
+var (
+	unityExcluded  = []string{"Library", "Temp", "Logs", "obj", "Build", "Builds", "UserSettings", "MemoryCaptures"}
+	unrealExcluded = []string{"Binaries", "Intermediate", "Saved", "DerivedDataCache", "Plugins/**/Binaries", "Plugins/**/Intermediate"}
+)
+
+// Detect returns the Unity project, with ProjectSettings/ProjectVersion.txt and Assets,
+// or the Unreal Engine project, with a .uproject file, in the project directory.
+func Detect(projectDir string) (Project, bool) {
+	_, versionErr := os.Stat(filepath.Join(projectDir, "ProjectSettings", "ProjectVersion.txt"))
+	assets, assetsErr := os.Stat(filepath.Join(projectDir, "Assets"))
+	if versionErr == nil && assetsErr == nil && assets.IsDir() {
+		return Project{Engine: Unity, Solution: solution(projectDir, ""), Excluded: unityExcluded}, true
+	}
+	descriptors, _ := filepath.Glob(filepath.Join(projectDir, "*.uproject"))
+	if len(descriptors) > 0 {
+		descriptor := filepath.Base(descriptors[0])
+		return Project{
+			Engine:     Unreal,
+			Solution:   solution(projectDir, strings.TrimSuffix(descriptor, ".uproject")),
+			Descriptor: descriptor,
+			Excluded:   unrealExcluded,
+		}, true
+	}
+	return Project{}, false
+}
+
+// solution returns the solution in the project directory, the one named after the project if there are several.
+func solution(projectDir string, name string) string {
+	solutions, _ := filepath.Glob(filepath.Join(projectDir, "*.sln"))
+	if len(solutions) == 0 {
+		return ""
+	}
+	if name == "" {
+		name = filepath.Base(projectDir)
+	}
+	sort.Strings(solutions)
+	for _, s := range solutions {
+		if strings.EqualFold(strings.TrimSuffix(filepath.Base(s), ".sln"), name) {
+			return filepath.Base(s)
+		}
+	}
+	return filepath.Base(solutions[0])
+}
+
+// Stale returns the file the solution is generated from changed after the solution was generated, if there is one:
+// the package manifest and assembly definitions of Unity, or the descriptors and module rules of Unreal Engine.
+func (p Project) Stale(projectDir string) (string, bool) {
+	if p.Solution == "" {
+		return "", false
+	}
+	info, err := os.Stat(filepath.Join(projectDir, p.Solution))
+	if err != nil {
+		return "", false
+	}
+	generated := info.ModTime()
+	var newest string
+	var newestTime time.Time
+	check := func(path string, modified time.Time) {
+		if modified.After(generated) && modified.After(newestTime) {
+			newest, newestTime = path, modified
+		}
+	}
+	var roots, suffixes []string
+	switch p.Engine {
+	case Unity:
+		for _, name := range []string{"Packages/manifest.json", "ProjectSettings/ProjectVersion.txt"} {
+			if info, err := os.Stat(filepath.Join(projectDir, name)); err == nil {
+				check(name, info.ModTime())
+			}
+		}
+		roots, suffixes = []string{"Assets", "Packages"}, []string{".asmdef", ".asmref"}
+	case Unreal:
+		if info, err := os.Stat(filepath.Join(projectDir, p.Descriptor)); err == nil {
+			check(p.Descriptor, info.ModTime())
+		}
+		roots, suffixes = []string{"Source", "Plugins"}, []string{".Build.cs", ".Target.cs", ".uplugin"}
+	}
+	for _, root := range roots {
+		_ = filepath.WalkDir(
+			filepath.Join(projectDir, root), func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return nil
+				}
+				if d.IsDir() {
+					if strings.HasPrefix(d.Name(), ".") || d.Name() == "Intermediate" || d.Name() == "Binaries" {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				for _, suffix := range suffixes {
+					if strings.HasSuffix(d.Name(), suffix) {
+						if info, err := d.Info(); err == nil {
+							rel, _ := filepath.Rel(projectDir, path)
+							check(filepath.ToSlash(rel), info.ModTime())
+						}
+					}
+				}
+				return nil
+			},
+		)
+	}
+	return newest, newest != ""
+}
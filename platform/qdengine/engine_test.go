@@ -0,0 +1,87 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdengine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path string, modified time.Time) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte{}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnity(t *testing.T) {
+	project := filepath.Join(t.TempDir(), "Game")
+	generated := time.Now().Add(-time.Hour)
+	writeFile(t, filepath.Join(project, "ProjectSettings", "ProjectVersion.txt"), generated.Add(-time.Hour))
+	writeFile(t, filepath.Join(project, "Assets", "Scripts", "Game.asmdef"), generated.Add(-time.Hour))
+	writeFile(t, filepath.Join(project, "Other.sln"), generated)
+	writeFile(t, filepath.Join(project, "Game.sln"), generated)
+
+	p, ok := Detect(project)
+	if !ok || p.Engine != Unity || p.Solution != "Game.sln" {
+		t.Fatalf("expected the Unity project with Game.sln, got %v %v", p, ok)
+	}
+	if changed, stale := p.Stale(project); stale {
+		t.Errorf("expected the solution to be up to date, %s changed", changed)
+	}
+	writeFile(t, filepath.Join(project, "Assets", "Scripts", "Editor", "Editor.asmdef"), time.Now())
+	if changed, stale := p.Stale(project); !stale || changed != "Assets/Scripts/Editor/Editor.asmdef" {
+		t.Errorf("expected the solution to be stale after the assembly definition was added, got %s %v", changed, stale)
+	}
+}
+
+func TestUnreal(t *testing.T) {
+	project := t.TempDir()
+	generated := time.Now().Add(-time.Hour)
+	writeFile(t, filepath.Join(project, "Shooter.uproject"), generated.Add(-time.Hour))
+	writeFile(t, filepath.Join(project, "Source", "Shooter", "Shooter.Build.cs"), generated.Add(-time.Hour))
+	writeFile(t, filepath.Join(project, "Intermediate", "Build", "Shooter.Build.cs"), time.Now())
+
+	p, ok := Detect(project)
+	if !ok || p.Engine != Unreal || p.Descriptor != "Shooter.uproject" || p.Solution != "" {
+		t.Fatalf("expected the Unreal project without a solution, got %v %v", p, ok)
+	}
+	writeFile(t, filepath.Join(project, "Shooter.sln"), generated)
+	p, _ = Detect(project)
+	if changed, stale := p.Stale(project); p.Solution != "Shooter.sln" || stale {
+		t.Errorf("expected the up to date Shooter.sln, got %s stale because of %s", p.Solution, changed)
+	}
+	writeFile(t, filepath.Join(project, "Source", "Shooter", "Shooter.Build.cs"), time.Now())
+	if changed, stale := p.Stale(project); !stale || changed != "Source/Shooter/Shooter.Build.cs" {
+		t.Errorf("expected the solution to be stale after the module rules changed, got %s %v", changed, stale)
+	}
+}
+
+func TestNotAGameProject(t *testing.T) {
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, "App.sln"), time.Now())
+	if p, ok := Detect(project); ok {
+		t.Errorf("expected no game engine project, got %v", p)
+	}
+}
@@ -17,6 +17,7 @@
 package qdenv
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/platform/version"
 	cienvironment "github.com/cucumber/ci-environment/go"
@@ -58,6 +59,37 @@ const (
 	QodanaCloudRequestCooldownEnv = "QODANA_CLOUD_REQUEST_COOLDOWN"
 	QodanaCloudRequestTimeoutEnv  = "QODANA_CLOUD_REQUEST_TIMEOUT"
 	QodanaCloudRequestRetriesEnv  = "QODANA_CLOUD_REQUEST_RETRIES"
+	QodanaOidcTokenEnv            = "QODANA_ID_TOKEN"
+	QodanaCaCertificateEnv        = "QODANA_CA_CERTIFICATE"
+	QodanaTelemetryEnv            = "QODANA_TELEMETRY"
+	TelemetryOff                  = "off"
+	QodanaCacheTokenEnv           = "QODANA_CACHE_TOKEN"
+	QodanaTmpEnv                  = "QODANA_TMP"
+	QodanaServeTokenEnv           = "QODANA_SERVE_TOKEN"
+	QodanaServeBasicAuthEnv       = "QODANA_SERVE_BASIC_AUTH"
+	QodanaAuditLogEnv             = "QODANA_AUDIT_LOG"
+	QodanaLangEnv                 = "QODANA_LANG"
+	QodanaAsciiEnv                = "QODANA_ASCII"
+	QodanaScreenReaderEnv         = "QODANA_SCREEN_READER"
+	QodanaDisableUpdateChecksEnv  = "QODANA_DISABLE_UPDATE_CHECKS"
+
+	QodanaPluginRepositoryEnv        = "QODANA_PLUGIN_REPOSITORY"
+	QodanaPluginRepositoryTokenEnv   = "QODANA_PLUGIN_REPOSITORY_TOKEN"
+	QodanaPluginRepositoryHeadersEnv = "QODANA_PLUGIN_REPOSITORY_HEADERS"
+
+	QodanaJdkDownloadUrlEnv = "QODANA_JDK_DOWNLOAD_URL"
+	JavaHomeEnv             = "JAVA_HOME"
+
+	QodanaNodeDownloadUrlEnv      = "QODANA_NODE_DOWNLOAD_URL"
+	QodanaAndroidRepositoryUrlEnv = "QODANA_ANDROID_REPOSITORY_URL"
+	AndroidHome                   = "ANDROID_HOME"
+	VirtualEnvEnv                 = "VIRTUAL_ENV"
+
+	OtelExporterOtlpEndpointEnv       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	OtelExporterOtlpTracesEndpointEnv = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	OtelExporterOtlpHeadersEnv        = "OTEL_EXPORTER_OTLP_HEADERS"
+	OtelServiceNameEnv                = "OTEL_SERVICE_NAME"
+	TraceParentEnv                    = "TRACEPARENT"
 )
 
 func SetEnv(key string, value string) {
@@ -71,6 +103,24 @@ func SetEnv(key string, value string) {
 	}
 }
 
+// IsEnabled returns true if the environment variable is set to a true value, e.g. 1, true or yes.
+func IsEnabled(key string) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(key))) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// IsTelemetryDisabled returns true if usage statistics are disabled with QODANA_TELEMETRY=off.
+func IsTelemetryDisabled() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(QodanaTelemetryEnv))) {
+	case TelemetryOff, "false", "0", "no", "disabled":
+		return true
+	}
+	return false
+}
+
 func IsContainer() bool {
 	return os.Getenv(QodanaDockerEnv) != ""
 }
@@ -92,6 +142,11 @@ func ExtractQodanaEnvironment(setEnvironmentFunc func(string, string)) {
 	if revision := os.Getenv(QodanaRevision); revision != "" {
 		setEnvironmentFunc(QodanaRevision, revision)
 	}
+	for _, env := range []string{QodanaPluginRepositoryEnv, QodanaPluginRepositoryTokenEnv, QodanaPluginRepositoryHeadersEnv} {
+		if value := os.Getenv(env); value != "" {
+			setEnvironmentFunc(env, value)
+		}
+	}
 	ci := cienvironment.DetectCIEnvironment()
 	qEnv := "cli"
 	if ci != nil {
@@ -214,6 +269,37 @@ func IsGitLab() bool {
 	return os.Getenv("GITLAB_CI") == "true"
 }
 
+// IsGithubActions returns true if the current environment is GitHub Actions.
+func IsGithubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// GetGithubPullRequestBaseSha returns the base commit of the pull request triggering the GitHub Actions workflow, if any.
+func GetGithubPullRequestBaseSha() string {
+	switch os.Getenv("GITHUB_EVENT_NAME") {
+	case "pull_request", "pull_request_target":
+	default:
+		return ""
+	}
+	data, err := os.ReadFile(os.Getenv("GITHUB_EVENT_PATH"))
+	if err != nil {
+		log.Debugf("Unable to read the GitHub event: %s", err)
+		return ""
+	}
+	var event struct {
+		PullRequest struct {
+			Base struct {
+				Sha string `json:"sha"`
+			} `json:"base"`
+		} `json:"pull_request"`
+	}
+	if err = json.Unmarshal(data, &event); err != nil {
+		log.Debugf("Unable to parse the GitHub event: %s", err)
+		return ""
+	}
+	return event.PullRequest.Base.Sha
+}
+
 // IsBitBucket returns true if the current environment is BitBucket Pipelines.
 func IsBitBucket() bool {
 	return os.Getenv("BITBUCKET_PIPELINE_UUID") != ""
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdhelp
+
+import (
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	topic, ok := Find("exit-codes")
+	if !ok {
+		t.Fatal("expected the exit-codes topic")
+	}
+	text := Render(topic)
+	if !strings.HasPrefix(text, "Exit codes\n") || !strings.Contains(text, "\n    255   a quality gate failed") {
+		t.Errorf("unexpected text\n%s", text)
+	}
+	if _, ok = Find("unknown"); ok {
+		t.Error("expected no unknown topic")
+	}
+	for _, topic := range Topics() {
+		if topic.Title == "" || topic.Summary == "" || len(topic.Sections) == 0 {
+			t.Errorf("incomplete topic %s", topic.Name)
+		}
+	}
+}
+
+func TestWriteMan(t *testing.T) {
+	root := &cobra.Command{Use: "qodana", Short: "Run Qodana CLI"}
+	root.PersistentFlags().String("log-level", "error", "Set log-level for output")
+	scan := &cobra.Command{Use: "scan", Short: "Scan project with Qodana", Run: func(*cobra.Command, []string) {}}
+	scan.Flags().StringP("linter", "l", "", "Linter to use")
+	scan.Flags().Bool("hidden", false, "Hidden flag")
+	_ = scan.Flags().MarkHidden("hidden")
+	root.AddCommand(scan, &cobra.Command{Use: "internal", Hidden: true, Run: func(*cobra.Command, []string) {}})
+
+	dir := t.TempDir()
+	if err := WriteMan(root, dir, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(filepath.Join(dir, "qodana-scan.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`.TH "QODANA-SCAN" "1" "May 2024"`,
+		`qodana\-scan \- Scan project with Qodana`,
+		`\fB\-l, \-\-linter string\fP`,
+		`\fB\-\-log\-level string\fP` + "\nSet log\\-level for output (default error)",
+		"qodana(1)",
+	} {
+		if !strings.Contains(string(page), expected) {
+			t.Errorf("expected %q in\n%s", expected, page)
+		}
+	}
+	if strings.Contains(string(page), "hidden") {
+		t.Errorf("expected no hidden flag in\n%s", page)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "qodana-internal.1")); err == nil {
+		t.Error("expected no page of the hidden command")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "qodana-exit-codes.7")); err != nil {
+		t.Error("expected the page of the exit-codes topic")
+	}
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdhelp
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Render returns the topic as the text printed by qodana help <topic>.
+func Render(topic Topic) string {
+	var b strings.Builder
+	b.WriteString(topic.Title + "\n")
+	for _, section := range topic.Sections {
+		b.WriteString("\n")
+		if section.Heading != "" {
+			b.WriteString(section.Heading + "\n\n")
+		}
+		for i, paragraph := range paragraphs(section.Body) {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString("  " + strings.ReplaceAll(paragraph.text, "\n", "\n  ") + "\n")
+		}
+	}
+	return b.String()
+}
+
+type paragraph struct {
+	text     string
+	verbatim bool
+}
+
+// paragraphs splits the body at the empty lines and between the indented and the plain lines.
+func paragraphs(body string) []paragraph {
+	var result []paragraph
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var current *paragraph
+		for _, line := range strings.Split(block, "\n") {
+			verbatim := strings.HasPrefix(line, "  ")
+			if current == nil || current.verbatim != verbatim {
+				result = append(result, paragraph{verbatim: verbatim})
+				current = &result[len(result)-1]
+				current.text = line
+				continue
+			}
+			current.text += "\n" + line
+		}
+	}
+	return result
+}
+
+// WriteMan writes the man pages of the commands of root to dir, qodana-scan.1 for qodana scan, and of the topics,
+// qodana-exit-codes.7 for the exit-codes topic.
+func WriteMan(root *cobra.Command, dir string, date time.Time) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	var write func(cmd *cobra.Command) error
+	write = func(cmd *cobra.Command) error {
+		if !cmd.IsAvailableCommand() && cmd != root || cmd.IsAdditionalHelpTopicCommand() {
+			return nil
+		}
+		name := strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+		if err := os.WriteFile(filepath.Join(dir, name+".1"), CommandMan(cmd, date), 0o644); err != nil {
+			return err
+		}
+		for _, child := range cmd.Commands() {
+			if err := write(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := write(root); err != nil {
+		return err
+	}
+	for _, topic := range topics {
+		page := TopicMan(root.Name(), topic, date)
+		if err := os.WriteFile(filepath.Join(dir, root.Name()+"-"+topic.Name+".7"), page, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CommandMan returns the man page of the command in the roff format.
+func CommandMan(cmd *cobra.Command, date time.Time) []byte {
+	var b bytes.Buffer
+	name := strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+	header(&b, name, 1, date)
+	b.WriteString(".SH NAME\n")
+	fmt.Fprintf(&b, "%s \\- %s\n", escape(name), escape(cmd.Short))
+	b.WriteString(".SH SYNOPSIS\n")
+	fmt.Fprintf(&b, ".B %s\n", escape(cmd.UseLine()))
+	description := cmd.Long
+	if description == "" {
+		description = cmd.Short
+	}
+	b.WriteString(".SH DESCRIPTION\n")
+	writeParagraphs(&b, description)
+	writeFlags(&b, "OPTIONS", cmd.NonInheritedFlags())
+	writeFlags(&b, "OPTIONS INHERITED FROM PARENT COMMANDS", cmd.InheritedFlags())
+	if cmd.Example != "" {
+		b.WriteString(".SH EXAMPLE\n.nf\n" + escape(cmd.Example) + "\n.fi\n")
+	}
+	var related []string
+	if cmd.HasParent() {
+		related = append(related, strings.ReplaceAll(cmd.Parent().CommandPath(), " ", "-")+"(1)")
+	}
+	for _, child := range cmd.Commands() {
+		if child.IsAvailableCommand() {
+			related = append(related, strings.ReplaceAll(child.CommandPath(), " ", "-")+"(1)")
+		}
+	}
+	if !cmd.HasParent() {
+		for _, topic := range topics {
+			related = append(related, cmd.Name()+"-"+topic.Name+"(7)")
+		}
+	}
+	if len(related) > 0 {
+		b.WriteString(".SH SEE ALSO\n" + escape(strings.Join(related, ", ")) + "\n")
+	}
+	return b.Bytes()
+}
+
+// TopicMan returns the man page of the topic of the program in the roff format.
+func TopicMan(program string, topic Topic, date time.Time) []byte {
+	var b bytes.Buffer
+	name := program + "-" + topic.Name
+	header(&b, name, 7, date)
+	b.WriteString(".SH NAME\n")
+	fmt.Fprintf(&b, "%s \\- %s\n", escape(name), escape(topic.Summary))
+	for i, section := range topic.Sections {
+		heading := strings.ToUpper(section.Heading)
+		if i == 0 && heading == "" {
+			heading = "DESCRIPTION"
+		}
+		if heading != "" {
+			fmt.Fprintf(&b, ".SH %s\n", escape(heading))
+		}
+		writeParagraphs(&b, section.Body)
+	}
+	return b.Bytes()
+}
+
+func header(b *bytes.Buffer, name string, section int, date time.Time) {
+	fmt.Fprintf(b, ".TH \"%s\" \"%d\" \"%s\" \"Qodana CLI\" \"Qodana Manual\"\n", strings.ToUpper(name), section, date.Format("Jan 2006"))
+	b.WriteString(".nh\n.ad l\n")
+}
+
+func writeParagraphs(b *bytes.Buffer, body string) {
+	for _, paragraph := range paragraphs(body) {
+		if paragraph.verbatim {
+			b.WriteString(".PP\n.nf\n" + escape(paragraph.text) + "\n.fi\n")
+		} else {
+			b.WriteString(".PP\n" + escape(paragraph.text) + "\n")
+		}
+	}
+}
+
+func writeFlags(b *bytes.Buffer, heading string, flags *pflag.FlagSet) {
+	if !flags.HasAvailableFlags() {
+		return
+	}
+	b.WriteString(".SH " + heading + "\n")
+	flags.VisitAll(
+		func(flag *pflag.Flag) {
+			if flag.Hidden || flag.Deprecated != "" {
+				return
+			}
+			name := "--" + flag.Name
+			if flag.Shorthand != "" && flag.ShorthandDeprecated == "" {
+				name = "-" + flag.Shorthand + ", " + name
+			}
+			varname, usage := pflag.UnquoteUsage(flag)
+			if varname != "" {
+				name += " " + varname
+			}
+			if flag.DefValue != "" && flag.DefValue != "false" && flag.DefValue != "[]" {
+				usage += fmt.Sprintf(" (default %s)", flag.DefValue)
+			}
+			fmt.Fprintf(b, ".TP\n\\fB%s\\fP\n%s\n", escape(name), escape(usage))
+		},
+	)
+}
+
+// escape escapes the backslashes, the hyphens and the leading dots and quotes of the lines for roff.
+func escape(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, "-", `\-`)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
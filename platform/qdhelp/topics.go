@@ -0,0 +1,202 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdhelp holds the long-form help topics of the CLI, printed by qodana help <topic>, and renders
+// them and the commands as man pages.
+package qdhelp
+
+import "sort"
+
+// Topic is a long-form help topic, e.g. the exit codes of the CLI.
+type Topic struct {
+	Name     string
+	Title    string
+	Summary  string
+	Sections []Section
+}
+
+// Section is a part of a topic, its body is plain text with the paragraphs separated by empty lines
+// and the lines starting with two spaces kept as is, e.g. the examples.
+type Section struct {
+	Heading string
+	Body    string
+}
+
+var topics = []Topic{
+	{
+		Name:    "configuration",
+		Title:   "Configuring the analysis",
+		Summary: "qodana.yaml, the profiles and the precedence of the options",
+		Sections: []Section{
+			{
+				Body: `The analysis is configured with qodana.yaml in the root of the project, the command line options
+and the environment variables. The options of the command line take precedence over the environment
+variables, which take precedence over qodana.yaml, which takes precedence over the defaults of the linter.
+Use --config to read another file than qodana.yaml, the relative paths in it are resolved against the
+project directory. qodana options dump prints the effective options and where their values come from.`,
+			},
+			{
+				Heading: "qodana.yaml",
+				Body: `The most used keys are:
+
+  version: "1.0"
+  linter: jetbrains/qodana-jvm:latest   # or ide: QDJVM for the runs without a container
+  profile:
+    name: qodana.recommended            # or path: .qodana/profile.xml
+  exclude:
+    - name: All
+      paths:
+        - build
+  include:
+    - name: SomeInspection
+  failThreshold: 0
+  bootstrap: ./gradlew assemble
+
+Run qodana init to create the file for the project and qodana profile show to print the resulting
+inspection profile.`,
+			},
+			{
+				Heading: "CLI version",
+				Body: `cliVersion pins the versions of the CLI the project is analysed with, so the teammates and CI
+don't drift apart:
+
+  cliVersion: ">=2024.2 <2025"
+  cliVersionCheck: fail                 # warn by default
+
+qodana self-update --to-required installs the newest release satisfying it.`,
+			},
+			{
+				Heading: "Profiles",
+				Body: `The inspection profile is selected with --profile-name or --profile-path, the profile section
+of qodana.yaml, or the profile of the project settings in .idea/inspectionProfiles. The bundled profiles
+are qodana.starter, qodana.recommended and qodana.sanity.`,
+			},
+			{
+				Heading: "Environment",
+				Body: `Every option of qodana scan can be set with the QODANA_<OPTION> environment variable, the name of
+the option in upper case with the dashes replaced by underscores, e.g. in a container or a CI pipeline:
+
+  QODANA_FAIL_THRESHOLD=10 QODANA_BASELINE=qodana.sarif.json qodana scan
+
+The list options take comma-separated values and the repeatable ones, e.g. --property, a value per line.
+The variable of an option is ignored if the option, or an option it conflicts with, is set on the command
+line. --env has no variable, QODANA_ENV is reserved.
+
+QODANA_TOKEN is the project token of Qodana Cloud, the results are uploaded when it is set.
+QODANA_LANG selects the language of the messages, QODANA_ASCII and QODANA_SCREEN_READER the plain output.
+QODANA_TMP is the directory for the temporary files, the same as --temp-dir.`,
+			},
+		},
+	},
+	{
+		Name:    "baselines",
+		Title:   "Baselines",
+		Summary: "reporting only the problems new since a snapshot of the project",
+		Sections: []Section{
+			{
+				Body: `A baseline is a SARIF report of the known problems of the project. When a run is given a baseline,
+the problems present in it are reported as unchanged, the others as new, and only the new problems count
+towards the quality gates.`,
+			},
+			{
+				Heading: "Using a baseline",
+				Body: `Pass the report with --baseline, e.g.
+
+  qodana scan --baseline qodana.sarif.json
+
+add --baseline-include-absent to report the problems of the baseline absent from the run too. With
+--baselines-dir, the directory holds a baseline per branch named baseline.<branch>.sarif: the baseline
+of the analysed branch is used, falling back to the target branch of the pull request, the default
+branch, main and master.`,
+			},
+			{
+				Heading: "Maintaining a baseline",
+				Body: `qodana baseline create writes the baseline from the latest report, qodana baseline absorb adds
+the new problems of the latest report to it, and qodana baseline trim removes the problems of the deleted
+files.`,
+			},
+		},
+	},
+	{
+		Name:    "exit-codes",
+		Title:   "Exit codes",
+		Summary: "what the exit code of qodana scan means",
+		Sections: []Section{
+			{
+				Body: `  0     the analysis is completed and no quality gate failed
+  1     the analysis failed, or reached a time limit (see --timeout-exit-code)
+  7     the license of the EAP linter has expired
+  130   the run is cancelled with SIGINT or SIGTERM
+  137   the linter is killed, often because it ran out of memory
+  255   a quality gate failed: --fail-threshold, failThreshold or failureConditions of qodana.yaml,
+        or --fail-on-metric`,
+			},
+			{
+				Heading: "Time limits",
+				Body: `A run that reaches --timeout, --bootstrap-timeout, --indexing-timeout or --inspection-timeout
+exits with --timeout-exit-code, 1 by default.`,
+			},
+		},
+	},
+	{
+		Name:    "ci",
+		Title:   "Running Qodana in CI",
+		Summary: "the pipelines of the CI providers and the pull request analysis",
+		Sections: []Section{
+			{
+				Body: `qodana ci generate <provider> writes the pipeline running Qodana for GitHub Actions, GitLab CI,
+Azure Pipelines, Bitbucket Pipelines, Jenkins or CircleCI, with the pull requests analysed in the diff mode
+and the caches kept between the runs.`,
+			},
+			{
+				Heading: "Pull requests",
+				Body: `Analyse only the files changed by a pull request with --diff-start (and --diff-end), or --commit to
+reset the working tree to the commit and analyse the changes since it:
+
+  qodana scan --diff-start origin/main
+
+Combine it with a baseline (see qodana help baselines) to fail only on the new problems.`,
+			},
+			{
+				Heading: "Output",
+				Body: `--porcelain prints the key<TAB>value lines of the outcome, e.g. new-problems and exit-code, for
+the scripts of the pipeline, and --problems-format github-annotations annotates the changed lines on
+GitHub Actions, the default there.`,
+			},
+		},
+	},
+}
+
+// Topics returns the help topics sorted by name.
+func Topics() []Topic {
+	sorted := append([]Topic{}, topics...)
+	sort.Slice(
+		sorted, func(i, j int) bool {
+			return sorted[i].Name < sorted[j].Name
+		},
+	)
+	return sorted
+}
+
+// Find returns the help topic with the name.
+func Find(name string) (Topic, bool) {
+	for _, topic := range topics {
+		if topic.Name == name {
+			return topic, true
+		}
+	}
+	return Topic{}, false
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdhook installs the git hooks running Qodana on the changes being committed or pushed.
+package qdhook
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	PreCommit = "pre-commit"
+	PrePush   = "pre-push"
+
+	// marker identifies the hooks installed by Qodana, other hooks are never overwritten or removed without --force.
+	marker = "# Installed by qodana hook install"
+)
+
+// ValidateType checks the hook type.
+func ValidateType(hookType string) error {
+	if hookType != PreCommit && hookType != PrePush {
+		return fmt.Errorf("unknown hook type %q, available values: %s, %s", hookType, PreCommit, PrePush)
+	}
+	return nil
+}
+
+// Script returns the shell script of the hook running `qodana hook run` with the executable.
+// The scan arguments are passed to `qodana scan` as is.
+func Script(executable string, hookType string, severity string, scanArgs []string) string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n" + marker + "\n")
+	sb.WriteString(shellQuote(executable) + " hook run --type " + hookType + " --severity " + shellQuote(severity))
+	if hookType == PrePush {
+		sb.WriteString(` --remote "$1"`)
+	}
+	if len(scanArgs) > 0 {
+		sb.WriteString(" --")
+		for _, arg := range scanArgs {
+			sb.WriteString(" " + shellQuote(arg))
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// Install writes the hook script to hooksDir, an existing hook not installed by Qodana is replaced only with force.
+func Install(hooksDir string, hookType string, script string, force bool) (string, error) {
+	path := filepath.Join(hooksDir, hookType)
+	if installed, err := installedByQodana(path); err == nil && !installed && !force {
+		return "", fmt.Errorf("%s already exists, use --force to replace it", path)
+	}
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		return "", err
+	}
+	return path, os.Chmod(path, 0o755)
+}
+
+// Uninstall removes the hook installed by Qodana, returns the removed path or "" if there was nothing to remove.
+func Uninstall(hooksDir string, hookType string) (string, error) {
+	path := filepath.Join(hooksDir, hookType)
+	installed, err := installedByQodana(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !installed {
+		return "", fmt.Errorf("%s was not installed by Qodana, remove it manually", path)
+	}
+	return path, os.Remove(path)
+}
+
+func installedByQodana(path string) (bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(content), marker), nil
+}
+
+// shellQuote quotes the argument for sh when it contains anything but safe characters.
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=,@+") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
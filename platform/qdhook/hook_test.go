@@ -0,0 +1,76 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdhook
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	script := Script("/opt/qodana cli/qodana", PrePush, "High", []string{"--ide", "QDJVM", "--property", "a=it's"})
+	expected := "#!/bin/sh\n" + marker + "\n" +
+		`'/opt/qodana cli/qodana' hook run --type pre-push --severity High --remote "$1" -- --ide QDJVM --property 'a=it'\''s'` + "\n"
+	if script != expected {
+		t.Errorf("unexpected script:\n%s", script)
+	}
+	if strings.Contains(Script("qodana", PreCommit, "Critical", nil), "--remote") {
+		t.Error("pre-commit hook must not pass the remote")
+	}
+	if ValidateType("post-commit") == nil {
+		t.Error("expected an error for an unsupported hook type")
+	}
+}
+
+func TestInstallAndUninstall(t *testing.T) {
+	hooksDir := filepath.Join(t.TempDir(), "hooks")
+	script := Script("qodana", PreCommit, "High", nil)
+
+	path, err := Install(hooksDir, PreCommit, script, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm()&0o100 == 0 {
+		t.Fatalf("the hook is not executable: %v %v", info, err)
+	}
+	if _, err = Install(hooksDir, PreCommit, script, false); err != nil {
+		t.Errorf("reinstalling the Qodana hook failed: %s", err)
+	}
+	if path, err = Uninstall(hooksDir, PreCommit); err != nil || path == "" {
+		t.Fatalf("uninstall failed: %q %v", path, err)
+	}
+	if path, err = Uninstall(hooksDir, PreCommit); err != nil || path != "" {
+		t.Errorf("expected nothing to uninstall: %q %v", path, err)
+	}
+
+	custom := filepath.Join(hooksDir, PrePush)
+	if err = os.WriteFile(custom, []byte("#!/bin/sh\nmake lint\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Install(hooksDir, PrePush, script, false); err == nil {
+		t.Error("expected an error replacing a custom hook")
+	}
+	if _, err = Uninstall(hooksDir, PrePush); err == nil {
+		t.Error("expected an error removing a custom hook")
+	}
+	if _, err = Install(hooksDir, PrePush, script, true); err != nil {
+		t.Errorf("forced install failed: %s", err)
+	}
+}
@@ -0,0 +1,183 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdindex verifies the IDE indexes restored from the Qodana cache before the analysis
+// and measures how much of them the analysis reused.
+package qdindex
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// manifestName is the manifest of the indexes written to the IDE system directory after a successful run.
+	manifestName = ".qodana-index.json"
+	// corruptionMarker is created in the index directory by the IDE when it finds the indexes corrupted.
+	corruptionMarker = "corruption.marker"
+)
+
+// dirs are the directories of the IDE system directory rebuilt by a full re-index.
+var dirs = []string{"index", "caches"}
+
+// Manifest describes the indexes left by a successful run: the IDE build and the size of every index shard.
+type Manifest struct {
+	Build  string           `json:"build"`
+	Shards map[string]int64 `json:"shards"`
+}
+
+type file struct {
+	size    int64
+	modTime time.Time
+}
+
+// Snapshot is the state of the index shards of the IDE system directory, by the path relative to it.
+type Snapshot map[string]file
+
+// Scan returns the state of the index shards in systemDir.
+func Scan(systemDir string) Snapshot {
+	snapshot := Snapshot{}
+	for _, dir := range dirs {
+		_ = filepath.WalkDir(
+			filepath.Join(systemDir, dir), func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return nil
+				}
+				info, err := d.Info()
+				if err != nil || !info.Mode().IsRegular() {
+					return nil
+				}
+				rel, err := filepath.Rel(systemDir, path)
+				if err != nil {
+					return nil
+				}
+				snapshot[filepath.ToSlash(rel)] = file{size: info.Size(), modTime: info.ModTime()}
+				return nil
+			},
+		)
+	}
+	return snapshot
+}
+
+// Size returns the total size of the shards in bytes.
+func (s Snapshot) Size() int64 {
+	var size int64
+	for _, f := range s {
+		size += f.size
+	}
+	return size
+}
+
+// Verify checks that the indexes in systemDir can be reused by the IDE build: they were built by the same build,
+// the IDE didn't mark them corrupted, and every shard has the size it had after the run that built them.
+// The reason is returned if they can't, indexes without a manifest are not verified.
+func Verify(systemDir string, build string) (string, bool) {
+	if _, err := os.Stat(filepath.Join(systemDir, "index", corruptionMarker)); err == nil {
+		return "the IDE marked the indexes as corrupted", false
+	}
+	data, err := os.ReadFile(filepath.Join(systemDir, manifestName))
+	if err != nil {
+		return "", true
+	}
+	var manifest Manifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Sprintf("the manifest of the indexes is unreadable: %s", err), false
+	}
+	if manifest.Build != build {
+		return fmt.Sprintf("the indexes were built by IDE %s, not %s", manifest.Build, build), false
+	}
+	snapshot := Scan(systemDir)
+	shards := make([]string, 0, len(manifest.Shards))
+	for shard := range manifest.Shards {
+		shards = append(shards, shard)
+	}
+	sort.Strings(shards)
+	for _, shard := range shards {
+		f, ok := snapshot[shard]
+		if !ok {
+			return fmt.Sprintf("the index shard %s is missing", shard), false
+		}
+		if f.size != manifest.Shards[shard] {
+			return fmt.Sprintf("the index shard %s has %d bytes instead of %d", shard, f.size, manifest.Shards[shard]), false
+		}
+	}
+	return "", true
+}
+
+// Invalidate removes the indexes from systemDir, so the IDE re-indexes the project from scratch.
+func Invalidate(systemDir string) error {
+	for _, dir := range append([]string{manifestName}, dirs...) {
+		if err := os.RemoveAll(filepath.Join(systemDir, dir)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveManifest removes the manifest before the run, the indexes left by an interrupted run are not verified.
+func RemoveManifest(systemDir string) error {
+	if err := os.Remove(filepath.Join(systemDir, manifestName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// WriteManifest records the indexes in systemDir built by the IDE build after a successful run.
+func WriteManifest(systemDir string, build string) error {
+	manifest := Manifest{Build: build, Shards: map[string]int64{}}
+	for shard, f := range Scan(systemDir) {
+		manifest.Shards[shard] = f.size
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(systemDir, manifestName), data, 0o644)
+}
+
+// Statistics compares the indexes before and after the run.
+type Statistics struct {
+	// Restored is the size of the indexes before the run in bytes.
+	Restored int64
+	// Reused is the size of the shards left unchanged by the run in bytes.
+	Reused int64
+	// Total is the size of the indexes after the run in bytes.
+	Total int64
+}
+
+// Compare returns the statistics of the run that changed the indexes from before to after.
+func Compare(before Snapshot, after Snapshot) Statistics {
+	stats := Statistics{Restored: before.Size(), Total: after.Size()}
+	for shard, f := range after {
+		if b, ok := before[shard]; ok && b.size == f.size && b.modTime.Equal(f.modTime) {
+			stats.Reused += f.size
+		}
+	}
+	return stats
+}
+
+// ReusedPercent returns the percentage of the indexes after the run reused from before it.
+func (s Statistics) ReusedPercent() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Reused) * 100 / float64(s.Total)
+}
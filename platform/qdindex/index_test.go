@@ -0,0 +1,120 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdindex
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeShard(t *testing.T, path string, size int) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		build  string
+		change func(t *testing.T, systemDir string)
+		reason string
+	}{
+		{name: "valid", build: "242.100", change: func(*testing.T, string) {}},
+		{name: "other build", build: "242.200", change: func(*testing.T, string) {}, reason: "built by IDE 242.100, not 242.200"},
+		{
+			name:  "truncated shard",
+			build: "242.100",
+			change: func(t *testing.T, systemDir string) {
+				writeShard(t, filepath.Join(systemDir, "index", "stubs", "stubs.dat"), 10)
+			},
+			reason: "index/stubs/stubs.dat has 10 bytes instead of 100",
+		},
+		{
+			name:  "missing shard",
+			build: "242.100",
+			change: func(t *testing.T, systemDir string) {
+				_ = os.Remove(filepath.Join(systemDir, "caches", "names.dat"))
+			},
+			reason: "caches/names.dat is missing",
+		},
+		{
+			name:  "corruption marker",
+			build: "242.100",
+			change: func(t *testing.T, systemDir string) {
+				writeShard(t, filepath.Join(systemDir, "index", corruptionMarker), 0)
+			},
+			reason: "marked the indexes as corrupted",
+		},
+	} {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				systemDir := t.TempDir()
+				writeShard(t, filepath.Join(systemDir, "index", "stubs", "stubs.dat"), 100)
+				writeShard(t, filepath.Join(systemDir, "caches", "names.dat"), 50)
+				if err := WriteManifest(systemDir, "242.100"); err != nil {
+					t.Fatal(err)
+				}
+				tt.change(t, systemDir)
+				reason, ok := Verify(systemDir, tt.build)
+				if ok != (tt.reason == "") || !strings.Contains(reason, tt.reason) {
+					t.Fatalf("expected %q, got %q %v", tt.reason, reason, ok)
+				}
+				if ok {
+					return
+				}
+				if err := Invalidate(systemDir); err != nil {
+					t.Fatal(err)
+				}
+				if len(Scan(systemDir)) != 0 {
+					t.Errorf("expected the indexes to be removed")
+				}
+			},
+		)
+	}
+}
+
+func TestVerifyWithoutManifest(t *testing.T) {
+	systemDir := t.TempDir()
+	writeShard(t, filepath.Join(systemDir, "index", "stubs", "stubs.dat"), 100)
+	if reason, ok := Verify(systemDir, "242.100"); !ok {
+		t.Errorf("expected the indexes without a manifest to be kept, got %s", reason)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	systemDir := t.TempDir()
+	writeShard(t, filepath.Join(systemDir, "index", "stubs", "stubs.dat"), 300)
+	writeShard(t, filepath.Join(systemDir, "index", "trigrams", "trigrams.dat"), 100)
+	before := Scan(systemDir)
+
+	modified := time.Now().Add(time.Hour)
+	writeShard(t, filepath.Join(systemDir, "index", "trigrams", "trigrams.dat"), 200)
+	_ = os.Chtimes(filepath.Join(systemDir, "index", "trigrams", "trigrams.dat"), modified, modified)
+	writeShard(t, filepath.Join(systemDir, "caches", "names.dat"), 100)
+
+	stats := Compare(before, Scan(systemDir))
+	if stats.Restored != 400 || stats.Reused != 300 || stats.Total != 600 || stats.ReusedPercent() != 50 {
+		t.Errorf("unexpected statistics %+v", stats)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdissues
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DbFileName is the name of the database of the exported issues in the Qodana user directory.
+const DbFileName = "issues.json"
+
+// Exported is the issue a problem is exported to.
+type Exported struct {
+	Id       string     `json:"id"`
+	Url      string     `json:"url,omitempty"`
+	RuleId   string     `json:"ruleId"`
+	Exported time.Time  `json:"exported"`
+	Updated  *time.Time `json:"updated,omitempty"`
+}
+
+// Db remembers the issues of the exported problems by the tracker name and the problem fingerprint.
+type Db struct {
+	path   string
+	Issues map[string]map[string]*Exported `json:"issues"`
+}
+
+// LoadDb reads the database, an empty one if the file does not exist.
+func LoadDb(path string) (*Db, error) {
+	db := &Db{path: path, Issues: map[string]map[string]*Exported{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, db); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if db.Issues == nil {
+		db.Issues = map[string]map[string]*Exported{}
+	}
+	return db, nil
+}
+
+// Get returns the issue the problem with the fingerprint is exported to in the tracker, nil if it's not exported.
+func (db *Db) Get(tracker string, fingerprint string) *Exported {
+	return db.Issues[tracker][fingerprint]
+}
+
+// Put remembers the issue of the problem with the fingerprint.
+func (db *Db) Put(tracker string, fingerprint string, issue *Exported) {
+	if db.Issues[tracker] == nil {
+		db.Issues[tracker] = map[string]*Exported{}
+	}
+	db.Issues[tracker][fingerprint] = issue
+}
+
+// Save writes the database.
+func (db *Db) Save() error {
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(db.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(db.path, data, 0o600)
+}
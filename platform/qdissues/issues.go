@@ -0,0 +1,137 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdissues creates and updates the issues of the problems in YouTrack and Jira,
+// remembering the issue of each problem fingerprint so that the problems are exported once.
+package qdissues
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	YouTrack = "youtrack"
+	Jira     = "jira"
+
+	httpTimeout = 30 * time.Second
+)
+
+// Issue is the issue of a problem.
+type Issue struct {
+	Summary     string
+	Description string
+	Labels      []string
+}
+
+// Tracker creates and updates the issues of a tracker project.
+type Tracker interface {
+	// Name is the tracker and the project the issues are created in, e.g. jira/QD.
+	Name() string
+	// Create creates the issue and returns its readable id, e.g. QD-42, and its URL.
+	Create(issue Issue) (id string, url string, err error)
+	// Update updates the summary and the description of the issue.
+	Update(id string, issue Issue) error
+}
+
+// New returns the tracker of the kind for the project, the URL and the credentials default to the environment:
+// YOUTRACK_URL and YOUTRACK_TOKEN, or JIRA_URL, JIRA_TOKEN and JIRA_USER for Jira Cloud API tokens.
+func New(kind string, baseUrl string, project string) (Tracker, error) {
+	if project == "" {
+		return nil, fmt.Errorf("the project to create the issues in is required")
+	}
+	switch kind {
+	case YouTrack:
+		if baseUrl == "" {
+			baseUrl = os.Getenv("YOUTRACK_URL")
+		}
+		t := &youTrack{client: client{baseUrl: strings.TrimSuffix(baseUrl, "/"), token: os.Getenv("YOUTRACK_TOKEN")}, project: project}
+		return t, t.validate("YOUTRACK_URL", "YOUTRACK_TOKEN")
+	case Jira:
+		if baseUrl == "" {
+			baseUrl = os.Getenv("JIRA_URL")
+		}
+		t := &jira{
+			client:  client{baseUrl: strings.TrimSuffix(baseUrl, "/"), token: os.Getenv("JIRA_TOKEN"), user: os.Getenv("JIRA_USER")},
+			project: project,
+		}
+		return t, t.validate("JIRA_URL", "JIRA_TOKEN")
+	default:
+		return nil, fmt.Errorf("unknown tracker %q, expected %s or %s", kind, YouTrack, Jira)
+	}
+}
+
+// client calls the REST API of the tracker, with the basic authentication if user is set and the bearer token otherwise.
+type client struct {
+	baseUrl string
+	token   string
+	user    string
+}
+
+func (c *client) validate(urlEnv string, tokenEnv string) error {
+	if c.baseUrl == "" {
+		return fmt.Errorf("set the URL of the tracker with --url or %s", urlEnv)
+	}
+	if c.token == "" {
+		return fmt.Errorf("set the token of the tracker with %s", tokenEnv)
+	}
+	return nil
+}
+
+// call sends the JSON request and decodes the JSON response into result, if not nil.
+func (c *client) call(method string, path string, body any, result any) error {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.baseUrl+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := (&http.Client{Timeout: httpTimeout}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s responded with %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdissues
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestYouTrack(t *testing.T) {
+	var created, updated map[string]any
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer perm:token" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/api/admin/projects":
+					_, _ = w.Write([]byte(`[{"id":"0-1","shortName":"QDX"},{"id":"0-2","shortName":"QD"}]`))
+				case r.Method == http.MethodPost && r.URL.Path == "/api/issues":
+					_ = json.NewDecoder(r.Body).Decode(&created)
+					_, _ = w.Write([]byte(`{"idReadable":"QD-42"}`))
+				case r.Method == http.MethodPost && r.URL.Path == "/api/issues/QD-42":
+					_ = json.NewDecoder(r.Body).Decode(&updated)
+					_, _ = w.Write([]byte(`{"idReadable":"QD-42"}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			},
+		),
+	)
+	defer server.Close()
+	t.Setenv("YOUTRACK_URL", server.URL+"/")
+	t.Setenv("YOUTRACK_TOKEN", "perm:token")
+
+	tracker, err := New(YouTrack, "", "QD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, url, err := tracker.Create(Issue{Summary: "summary", Description: "description", Labels: []string{"qodana"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "QD-42" || url != server.URL+"/issue/QD-42" {
+		t.Errorf("unexpected issue %s %s", id, url)
+	}
+	if created["project"].(map[string]any)["id"] != "0-2" || created["summary"] != "summary" {
+		t.Errorf("unexpected request %v", created)
+	}
+	if err = tracker.Update(id, Issue{Summary: "new summary"}); err != nil {
+		t.Fatal(err)
+	}
+	if updated["summary"] != "new summary" {
+		t.Errorf("unexpected request %v", updated)
+	}
+}
+
+func TestJira(t *testing.T) {
+	var created map[string]map[string]any
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if user, token, ok := r.BasicAuth(); !ok || user != "qodana@example.com" || token != "api-token" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == "/rest/api/2/issue":
+					_ = json.NewDecoder(r.Body).Decode(&created)
+					w.WriteHeader(http.StatusCreated)
+					_, _ = w.Write([]byte(`{"id":"10000","key":"QD-7"}`))
+				case r.Method == http.MethodPut && r.URL.Path == "/rest/api/2/issue/QD-7":
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			},
+		),
+	)
+	defer server.Close()
+	t.Setenv("JIRA_TOKEN", "api-token")
+	t.Setenv("JIRA_USER", "qodana@example.com")
+
+	if _, err := New(Jira, "", "QD"); err == nil {
+		t.Error("expected the missing URL to be reported")
+	}
+	tracker, err := New(Jira, server.URL, "QD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, url, err := tracker.Create(Issue{Summary: "summary", Description: "description"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "QD-7" || url != server.URL+"/browse/QD-7" {
+		t.Errorf("unexpected issue %s %s", id, url)
+	}
+	if created["fields"]["project"].(map[string]any)["key"] != "QD" {
+		t.Errorf("unexpected request %v", created)
+	}
+	if err = tracker.Update(id, Issue{Summary: "new summary"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = tracker.Update("QD-8", Issue{}); err == nil {
+		t.Error("expected the update of an unknown issue to fail")
+	}
+}
+
+func TestDb(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qodana", DbFileName)
+	db, err := LoadDb(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Get("jira/QD", "fingerprint") != nil {
+		t.Error("expected an empty database")
+	}
+	db.Put("jira/QD", "fingerprint", &Exported{Id: "QD-7", RuleId: "Rule"})
+	if err = db.Save(); err != nil {
+		t.Fatal(err)
+	}
+	db, err = LoadDb(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue := db.Get("jira/QD", "fingerprint"); issue == nil || issue.Id != "QD-7" {
+		t.Errorf("expected QD-7 to be remembered, got %+v", issue)
+	}
+	if db.Get("youtrack/QD", "fingerprint") != nil {
+		t.Error("expected the issues to be remembered per tracker")
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdissues
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// jiraIssueType is the type of the issues created in Jira.
+const jiraIssueType = "Bug"
+
+// jira creates the issues with the Jira REST API v2, the project is given by its key, e.g. QD.
+type jira struct {
+	client
+	project string
+}
+
+func (t *jira) Name() string {
+	return Jira + "/" + t.project
+}
+
+func (t *jira) Create(issue Issue) (string, string, error) {
+	var created struct {
+		Key string `json:"key"`
+	}
+	fields := map[string]any{
+		"project":     map[string]string{"key": t.project},
+		"issuetype":   map[string]string{"name": jiraIssueType},
+		"summary":     issue.Summary,
+		"description": issue.Description,
+	}
+	if len(issue.Labels) > 0 {
+		fields["labels"] = issue.Labels
+	}
+	if err := t.call(http.MethodPost, "/rest/api/2/issue", map[string]any{"fields": fields}, &created); err != nil {
+		return "", "", err
+	}
+	return created.Key, t.baseUrl + "/browse/" + created.Key, nil
+}
+
+func (t *jira) Update(id string, issue Issue) error {
+	fields := map[string]any{"summary": issue.Summary, "description": issue.Description}
+	return t.call(http.MethodPut, "/rest/api/2/issue/"+url.PathEscape(id), map[string]any{"fields": fields}, nil)
+}
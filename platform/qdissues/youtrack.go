@@ -0,0 +1,78 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdissues
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// youTrack creates the issues with the YouTrack REST API, the project is given by its short name, e.g. QD.
+type youTrack struct {
+	client
+	project   string
+	projectId string
+}
+
+func (t *youTrack) Name() string {
+	return YouTrack + "/" + t.project
+}
+
+func (t *youTrack) Create(issue Issue) (string, string, error) {
+	if t.projectId == "" {
+		var projects []struct {
+			Id        string `json:"id"`
+			ShortName string `json:"shortName"`
+		}
+		if err := t.call(http.MethodGet, "/api/admin/projects?fields=id,shortName&query="+url.QueryEscape(t.project), nil, &projects); err != nil {
+			return "", "", err
+		}
+		for _, p := range projects {
+			if p.ShortName == t.project || p.Id == t.project {
+				t.projectId = p.Id
+			}
+		}
+		if t.projectId == "" {
+			return "", "", fmt.Errorf("YouTrack project %s is not found", t.project)
+		}
+	}
+	var created struct {
+		IdReadable string `json:"idReadable"`
+	}
+	body := map[string]any{
+		"project":     map[string]string{"id": t.projectId},
+		"summary":     issue.Summary,
+		"description": issue.Description,
+	}
+	if len(issue.Labels) > 0 {
+		tags := make([]map[string]string, 0, len(issue.Labels))
+		for _, label := range issue.Labels {
+			tags = append(tags, map[string]string{"name": label})
+		}
+		body["tags"] = tags
+	}
+	if err := t.call(http.MethodPost, "/api/issues?fields=idReadable", body, &created); err != nil {
+		return "", "", err
+	}
+	return created.IdReadable, t.baseUrl + "/issue/" + created.IdReadable, nil
+}
+
+func (t *youTrack) Update(id string, issue Issue) error {
+	body := map[string]any{"summary": issue.Summary, "description": issue.Description}
+	return t.call(http.MethodPost, "/api/issues/"+url.PathEscape(id)+"?fields=idReadable", body, nil)
+}
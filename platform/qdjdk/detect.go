@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdjdk detects the JDK a Gradle or Maven project requires and provisions it for the native analysis:
+// an installed JDK of the version is selected, otherwise the Temurin JDK is downloaded into the Qodana cache.
+package qdjdk
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Requirement is the JDK version required by the project and the build file requiring it.
+type Requirement struct {
+	Version int
+	Source  string
+}
+
+var (
+	// java { toolchain { languageVersion = JavaLanguageVersion.of(17) } }
+	gradleToolchainPattern = regexp.MustCompile(`JavaLanguageVersion\.of\(\s*"?(\d+)"?\s*\)`)
+	// kotlin { jvmToolchain(17) }
+	kotlinToolchainPattern = regexp.MustCompile(`jvmToolchain\(\s*(\d+)\s*\)`)
+	// <requireJavaVersion><version>[17,)</version></requireJavaVersion> of maven-enforcer-plugin
+	enforcerPattern        = regexp.MustCompile(`(?s)<requireJavaVersion>(.*?)</requireJavaVersion>`)
+	enforcerVersionPattern = regexp.MustCompile(`<version>\s*[\[(]?\s*(?:1\.)?(\d+)`)
+	// <maven.compiler.release>17</maven.compiler.release>
+	mavenReleasePattern = regexp.MustCompile(`<maven\.compiler\.release>\s*(?:1\.)?(\d+)\s*<`)
+)
+
+// buildFiles are the build files declaring the JDK of the project.
+var buildFiles = []string{"build.gradle.kts", "build.gradle", "pom.xml"}
+
+// Detect returns the highest JDK version required by the build files of the project and of its direct subprojects.
+func Detect(projectDir string) (Requirement, bool) {
+	dirs := []string{projectDir}
+	if entries, err := os.ReadDir(projectDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") && entry.Name() != "node_modules" {
+				dirs = append(dirs, filepath.Join(projectDir, entry.Name()))
+			}
+		}
+	}
+	var found Requirement
+	for _, dir := range dirs {
+		for _, name := range buildFiles {
+			path := filepath.Join(dir, name)
+			content, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			if version := requiredVersion(name, string(content)); version > found.Version {
+				rel, _ := filepath.Rel(projectDir, path)
+				found = Requirement{Version: version, Source: filepath.ToSlash(rel)}
+			}
+		}
+	}
+	return found, found.Version > 0
+}
+
+// requiredVersion returns the JDK version required by the build file, 0 if there is none.
+func requiredVersion(name string, content string) int {
+	if name == "pom.xml" {
+		for _, rule := range enforcerPattern.FindAllStringSubmatch(content, -1) {
+			if m := enforcerVersionPattern.FindStringSubmatch(rule[1]); m != nil {
+				return atoi(m[1])
+			}
+		}
+		if m := mavenReleasePattern.FindStringSubmatch(content); m != nil {
+			return atoi(m[1])
+		}
+		return 0
+	}
+	if m := gradleToolchainPattern.FindStringSubmatch(content); m != nil {
+		return atoi(m[1])
+	}
+	if m := kotlinToolchainPattern.FindStringSubmatch(content); m != nil {
+		return atoi(m[1])
+	}
+	return 0
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdjdk
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcache"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// DefaultDownloadUrl is the Adoptium API the Temurin JDKs are downloaded from.
+const DefaultDownloadUrl = "https://api.adoptium.net"
+
+// Downloader downloads the Temurin JDKs from the Adoptium API, or a mirror of it.
+type Downloader struct {
+	Url    string
+	Os     string
+	Arch   string
+	Client *http.Client
+}
+
+// NewDownloader returns the downloader of the JDKs for the current platform,
+// from $QODANA_JDK_DOWNLOAD_URL if it is set, otherwise from the Adoptium API.
+func NewDownloader() *Downloader {
+	u := os.Getenv(qdenv.QodanaJdkDownloadUrlEnv)
+	if u == "" {
+		u = DefaultDownloadUrl
+	}
+	return &Downloader{
+		Url:    strings.TrimSuffix(u, "/"),
+		Os:     runtime.GOOS,
+		Arch:   runtime.GOARCH,
+		Client: &http.Client{Timeout: 30 * time.Minute, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}},
+	}
+}
+
+// Download downloads the JDK of the version into cacheDir and returns its home.
+func (d *Downloader) Download(version int, cacheDir string) (string, error) {
+	osName, ok := map[string]string{"linux": "linux", "darwin": "mac", "windows": "windows"}[d.Os]
+	if !ok {
+		return "", fmt.Errorf("no JDK downloads for %s", d.Os)
+	}
+	arch, ok := map[string]string{"amd64": "x64", "arm64": "aarch64"}[d.Arch]
+	if !ok {
+		return "", fmt.Errorf("no JDK downloads for %s", d.Arch)
+	}
+	u := fmt.Sprintf("%s/v3/binary/latest/%d/ga/%s/%s/jdk/hotspot/normal/eclipse", d.Url, version, osName, arch)
+	response, err := d.Client.Get(u)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach %s: %w", d.Url, err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", u, response.Status)
+	}
+
+	dir := filepath.Join(cacheDir, cacheDirName, fmt.Sprintf("temurin-%d", version))
+	tmp := dir + ".download"
+	_ = os.RemoveAll(tmp)
+	defer func() { _ = os.RemoveAll(tmp) }()
+	if err = os.MkdirAll(tmp, os.ModePerm); err != nil {
+		return "", err
+	}
+	if osName == "windows" {
+		err = utils.ExtractZip(response.Body, tmp)
+	} else {
+		err = qdcache.Extract(response.Body, tmp)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to extract JDK %d: %w", version, err)
+	}
+	_ = os.RemoveAll(dir)
+	if err = os.Rename(tmp, dir); err != nil {
+		return "", err
+	}
+	home, ok := jdkHome(dir)
+	if !ok {
+		return "", fmt.Errorf("no JDK in the archive downloaded from %s", u)
+	}
+	return home, nil
+}
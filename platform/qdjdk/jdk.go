@@ -0,0 +1,125 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdjdk
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// cacheDirName is the directory of the downloaded JDKs in the Qodana cache directory.
+const cacheDirName = "jdks"
+
+var releaseVersionPattern = regexp.MustCompile(`(?m)^JAVA_VERSION="(?:1\.)?(\d+)`)
+
+// Version returns the feature version of the JDK in home, read from its release file.
+func Version(home string) (int, error) {
+	content, err := os.ReadFile(filepath.Join(home, "release"))
+	if err != nil {
+		return 0, err
+	}
+	m := releaseVersionPattern.FindSubmatch(content)
+	if m == nil {
+		return 0, fmt.Errorf("no JAVA_VERSION in the release file of %s", home)
+	}
+	return atoi(string(m[1])), nil
+}
+
+// Find returns the home of an installed JDK of the version: JAVA_HOME, JAVA_HOME_<version>_X64 (set by CI images),
+// the JDKs of the usual install locations, SDKMAN!, IntelliJ IDEA and Gradle, and the JDKs downloaded to cacheDir.
+func Find(version int, cacheDir string) (string, bool) {
+	for _, home := range candidates(version, cacheDir) {
+		if v, err := Version(home); err == nil && v == version {
+			return home, true
+		}
+	}
+	return "", false
+}
+
+func candidates(version int, cacheDir string) []string {
+	homes := []string{os.Getenv(qdenv.JavaHomeEnv)}
+	for _, arch := range []string{"X64", "ARM64", "AARCH64"} {
+		homes = append(homes, os.Getenv(fmt.Sprintf("JAVA_HOME_%d_%s", version, arch)))
+	}
+	roots := []string{filepath.Join(cacheDir, cacheDirName)}
+	if userHome, err := os.UserHomeDir(); err == nil {
+		roots = append(
+			roots,
+			filepath.Join(userHome, ".jdks"),
+			filepath.Join(userHome, ".gradle", "jdks"),
+			filepath.Join(userHome, ".sdkman", "candidates", "java"),
+			filepath.Join(userHome, "Library", "Java", "JavaVirtualMachines"),
+		)
+	}
+	roots = append(
+		roots,
+		"/usr/lib/jvm",
+		"/usr/java",
+		"/opt/java",
+		"/Library/Java/JavaVirtualMachines",
+		filepath.Join(os.Getenv("ProgramFiles"), "Java"),
+		filepath.Join(os.Getenv("ProgramFiles"), "Eclipse Adoptium"),
+	)
+	for _, root := range roots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(names))) // the latest update first
+		for _, name := range names {
+			if home, ok := jdkHome(filepath.Join(root, name)); ok {
+				homes = append(homes, home)
+			}
+		}
+	}
+	var existing []string
+	for _, home := range homes {
+		if strings.TrimSpace(home) != "" {
+			existing = append(existing, home)
+		}
+	}
+	return existing
+}
+
+// jdkHome returns the JDK home in dir: dir itself, or Contents/Home of a macOS bundle,
+// or the single directory of an extracted archive.
+func jdkHome(dir string) (string, bool) {
+	for _, home := range []string{dir, filepath.Join(dir, "Contents", "Home")} {
+		if _, err := os.Stat(filepath.Join(home, "release")); err == nil {
+			return home, true
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return "", false
+	}
+	for _, home := range []string{filepath.Join(dir, entries[0].Name()), filepath.Join(dir, entries[0].Name(), "Contents", "Home")} {
+		if _, err := os.Stat(filepath.Join(home, "release")); err == nil {
+			return home, true
+		}
+	}
+	return "", false
+}
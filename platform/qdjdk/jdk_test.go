@@ -0,0 +1,134 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdjdk
+
+import (
+	"bytes"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcache"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected Requirement
+	}{
+		{
+			"gradle toolchain",
+			map[string]string{"build.gradle": "java {\n  toolchain {\n    languageVersion = JavaLanguageVersion.of(17)\n  }\n}"},
+			Requirement{17, "build.gradle"},
+		},
+		{
+			"kotlin toolchain in a subproject",
+			map[string]string{"build.gradle.kts": "plugins {}", "app/build.gradle.kts": "kotlin { jvmToolchain(21) }"},
+			Requirement{21, "app/build.gradle.kts"},
+		},
+		{
+			"maven enforcer",
+			map[string]string{
+				"pom.xml": `<rules><requireJavaVersion><message>JDK 11</message><version>[11,)</version></requireJavaVersion></rules>
+<properties><maven.compiler.release>8</maven.compiler.release></properties>`,
+			},
+			Requirement{11, "pom.xml"},
+		},
+		{
+			"maven compiler release",
+			map[string]string{"pom.xml": "<properties><maven.compiler.release>1.8</maven.compiler.release></properties>"},
+			Requirement{8, "pom.xml"},
+		},
+		{"no requirement", map[string]string{"build.gradle": "plugins { id 'java' }"}, Requirement{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, filepath.Join(project, name), content)
+			}
+			actual, ok := Detect(project)
+			if actual != tt.expected || ok != (tt.expected.Version > 0) {
+				t.Errorf("Detect() = %v %v, want %v", actual, ok, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	t.Setenv("JAVA_HOME", "")
+	cacheDir := t.TempDir()
+	writeFile(t, filepath.Join(cacheDir, cacheDirName, "temurin-11", "jdk-11.0.21+9", "release"), `JAVA_VERSION="11.0.21"`)
+	writeFile(t, filepath.Join(cacheDir, cacheDirName, "temurin-8", "release"), `JAVA_VERSION="1.8.0_392"`)
+
+	if home, ok := Find(11, cacheDir); !ok || filepath.Base(home) != "jdk-11.0.21+9" {
+		t.Errorf("expected the cached JDK 11, got %s %v", home, ok)
+	}
+	if home, ok := Find(8, cacheDir); !ok || filepath.Base(home) != "temurin-8" {
+		t.Errorf("expected the cached JDK 8, got %s %v", home, ok)
+	}
+	if home, ok := Find(1234, cacheDir); ok {
+		t.Errorf("expected no JDK 1234, got %s", home)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	jdk := t.TempDir()
+	writeFile(t, filepath.Join(jdk, "jdk-17.0.9+9", "release"), `JAVA_VERSION="17.0.9"`)
+	var archive bytes.Buffer
+	if err := qdcache.Archive(&archive, jdk); err != nil {
+		t.Fatal(err)
+	}
+	var requested string
+	svr := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				requested = r.URL.Path
+				_, _ = w.Write(archive.Bytes())
+			},
+		),
+	)
+	defer svr.Close()
+
+	d := &Downloader{Url: svr.URL, Os: "linux", Arch: "amd64", Client: svr.Client()}
+	cacheDir := t.TempDir()
+	home, err := d.Download(17, cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requested != "/v3/binary/latest/17/ga/linux/x64/jdk/hotspot/normal/eclipse" {
+		t.Errorf("unexpected request %s", requested)
+	}
+	if version, err := Version(home); err != nil || version != 17 {
+		t.Errorf("expected JDK 17 in %s, got %d %v", home, version, err)
+	}
+	t.Setenv("JAVA_HOME", "")
+	if found, ok := Find(17, cacheDir); !ok || found != home {
+		t.Errorf("expected the downloaded JDK to be found, got %s %v", found, ok)
+	}
+}
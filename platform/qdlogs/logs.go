@@ -0,0 +1,266 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdlogs keeps the log directory of the results bounded: the logs written by the CLI are rotated by size,
+// and the logs of the previous runs are moved to per-run subdirectories, of which only the latest are kept.
+package qdlogs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// RunsDir is the directory in the log directory with the logs of the previous runs, one subdirectory per run.
+	RunsDir = "runs"
+	// runDirLayout is the layout of the names of the run subdirectories, the time of the latest log of the run.
+	runDirLayout = "20060102-150405"
+
+	// DefaultMaxSizeMb, DefaultMaxFiles and DefaultKeepRuns are the limits used when the configuration does not set them.
+	DefaultMaxSizeMb = 10
+	DefaultMaxFiles  = 3
+	DefaultKeepRuns  = 5
+)
+
+// Limits bound the size of the log directory.
+type Limits struct {
+	// MaxSize is the size in bytes a log file is rotated at.
+	MaxSize int64
+	// MaxFiles is the number of the rotated files kept of each log, besides the current one.
+	MaxFiles int
+	// KeepRuns is the number of the previous runs whose logs are kept.
+	KeepRuns int
+}
+
+var (
+	limitsMu sync.Mutex
+	limits   = NewLimits(0, 0, 0)
+)
+
+// NewLimits returns the limits, the zero values are replaced with the defaults.
+func NewLimits(maxSizeMb int, maxFiles int, keepRuns int) Limits {
+	if maxSizeMb <= 0 {
+		maxSizeMb = DefaultMaxSizeMb
+	}
+	if maxFiles <= 0 {
+		maxFiles = DefaultMaxFiles
+	}
+	if keepRuns <= 0 {
+		keepRuns = DefaultKeepRuns
+	}
+	return Limits{MaxSize: int64(maxSizeMb) * 1024 * 1024, MaxFiles: maxFiles, KeepRuns: keepRuns}
+}
+
+// SetLimits sets the limits of the logs written by Append and the writers of OpenWriter.
+func SetLimits(l Limits) {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+	limits = l
+}
+
+func currentLimits() Limits {
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+	return limits
+}
+
+// Append appends text to the log file at path, rotating it first if it would exceed the maximum size.
+func Append(path string, text string) error {
+	l := currentLimits()
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 && info.Size()+int64(len(text)) > l.MaxSize {
+		if err = rotate(path, l.MaxFiles); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.WriteString(text); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rotate renames the log file at path to path.1, shifting the older rotated files and removing the ones beyond maxFiles.
+func rotate(path string, maxFiles int) error {
+	_ = os.Remove(fmt.Sprintf("%s.%d", path, maxFiles))
+	for i := maxFiles - 1; i >= 1; i-- {
+		older := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(older); err == nil {
+			if err = os.Rename(older, fmt.Sprintf("%s.%d", path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(path, path+".1")
+}
+
+// Writer appends to a log file, rotating it when it reaches the maximum size.
+type Writer struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	size int64
+}
+
+// OpenWriter opens the log file at path for appending.
+func OpenWriter(path string) (*Writer, error) {
+	w := &Writer{path: path}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.file, w.size = f, info.Size()
+	return nil
+}
+
+// Write appends p to the log file, rotating it first if it would exceed the maximum size.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	l := currentLimits()
+	if w.size > 0 && w.size+int64(len(p)) > l.MaxSize {
+		_ = w.file.Close()
+		if err := rotate(w.path, l.MaxFiles); err != nil {
+			return 0, err
+		}
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Sync flushes the log file to disk.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Sync()
+}
+
+// Close closes the log file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// ArchiveRun moves the logs left in logDir by the previous run to a subdirectory of RunsDir named after the time
+// of its latest log, and removes the oldest run subdirectories beyond keepRuns. It returns the subdirectory created,
+// empty if logDir has no logs.
+func ArchiveRun(logDir string, keepRuns int) (string, error) {
+	entries, err := os.ReadDir(logDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	var names []string
+	var latest time.Time
+	for _, entry := range entries {
+		if entry.Name() == RunsDir {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		names = append(names, entry.Name())
+	}
+	runDir := ""
+	if len(names) > 0 {
+		runDir, err = newRunDir(filepath.Join(logDir, RunsDir), latest)
+		if err != nil {
+			return "", err
+		}
+		for _, name := range names {
+			if err = os.Rename(filepath.Join(logDir, name), filepath.Join(runDir, name)); err != nil {
+				return runDir, err
+			}
+		}
+	}
+	return runDir, pruneRuns(filepath.Join(logDir, RunsDir), keepRuns)
+}
+
+// newRunDir creates the subdirectory of runsDir for the run whose latest log was written at t.
+func newRunDir(runsDir string, t time.Time) (string, error) {
+	if err := os.MkdirAll(runsDir, 0o755); err != nil {
+		return "", err
+	}
+	name := t.Format(runDirLayout)
+	dir := filepath.Join(runsDir, name)
+	for i := 2; ; i++ {
+		err := os.Mkdir(dir, 0o755)
+		if err == nil {
+			return dir, nil
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		dir = filepath.Join(runsDir, fmt.Sprintf("%s-%d", name, i))
+	}
+}
+
+// pruneRuns removes the oldest run subdirectories of runsDir beyond keepRuns.
+func pruneRuns(runsDir string, keepRuns int) error {
+	entries, err := os.ReadDir(runsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var runs []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			runs = append(runs, entry.Name())
+		}
+	}
+	// the names start with the time of the run, so they sort chronologically
+	sort.Strings(runs)
+	for len(runs) > keepRuns {
+		if err = os.RemoveAll(filepath.Join(runsDir, runs[0])); err != nil {
+			return err
+		}
+		runs = runs[1:]
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdlogs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readFile(t *testing.T, path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestAppendRotates(t *testing.T) {
+	SetLimits(Limits{MaxSize: 10, MaxFiles: 2, KeepRuns: DefaultKeepRuns})
+	defer SetLimits(NewLimits(0, 0, 0))
+	path := filepath.Join(t.TempDir(), "converter-out.log")
+	for _, text := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if err := Append(path, text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, expected := range map[string]string{"": "fourth\n", ".1": "third\n", ".2": "second\n"} {
+		if actual := readFile(t, path+name); actual != expected {
+			t.Errorf("expected %q in %s, got %q", expected, path+name, actual)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only %d rotated files", 2)
+	}
+}
+
+func TestWriterRotates(t *testing.T) {
+	SetLimits(Limits{MaxSize: 10, MaxFiles: 1, KeepRuns: DefaultKeepRuns})
+	defer SetLimits(NewLimits(0, 0, 0))
+	path := filepath.Join(t.TempDir(), "git.log")
+	w, err := OpenWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range []string{"12345", "6789", "abcdef", "g"} {
+		if _, err = w.Write([]byte(text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = w.Close()
+	if actual := readFile(t, path); actual != "abcdefg" {
+		t.Errorf("expected the current log to be %q, got %q", "abcdefg", actual)
+	}
+	if actual := readFile(t, path+".1"); actual != "123456789" {
+		t.Errorf("expected the rotated log to be %q, got %q", "123456789", actual)
+	}
+}
+
+func TestArchiveRun(t *testing.T) {
+	logDir := t.TempDir()
+	runsDir := filepath.Join(logDir, RunsDir)
+	for i, name := range []string{"20240101-100000", "20240102-100000", "20240103-100000"} {
+		if err := os.MkdirAll(filepath.Join(runsDir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.WriteFile(filepath.Join(runsDir, name, "idea.log"), []byte(strings.Repeat("x", i)), 0o644)
+	}
+	_ = os.MkdirAll(filepath.Join(logDir, "plugins"), 0o755)
+	_ = os.WriteFile(filepath.Join(logDir, "idea.log"), []byte("previous run"), 0o644)
+	_ = os.WriteFile(filepath.Join(logDir, "plugins", "plugin.log"), []byte("plugin"), 0o644)
+	modified := time.Date(2024, 1, 4, 12, 30, 0, 0, time.Local)
+	for _, name := range []string{"idea.log", "plugins"} {
+		_ = os.Chtimes(filepath.Join(logDir, name), modified, modified)
+	}
+
+	runDir, err := ArchiveRun(logDir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runDir != filepath.Join(runsDir, "20240104-123000") {
+		t.Errorf("unexpected run directory %s", runDir)
+	}
+	if actual := readFile(t, filepath.Join(runDir, "idea.log")); actual != "previous run" {
+		t.Errorf("expected the log of the previous run to be moved, got %q", actual)
+	}
+	if actual := readFile(t, filepath.Join(runDir, "plugins", "plugin.log")); actual != "plugin" {
+		t.Errorf("expected the subdirectories of the previous run to be moved, got %q", actual)
+	}
+	entries, _ := os.ReadDir(logDir)
+	if len(entries) != 1 || entries[0].Name() != RunsDir {
+		t.Errorf("expected only %s in the log directory, got %v", RunsDir, entries)
+	}
+	runs, _ := os.ReadDir(runsDir)
+	var names []string
+	for _, run := range runs {
+		names = append(names, run.Name())
+	}
+	if strings.Join(names, ",") != "20240103-100000,20240104-123000" {
+		t.Errorf("expected the latest 2 runs to be kept, got %v", names)
+	}
+
+	runDir, err = ArchiveRun(logDir, 2)
+	if err != nil || runDir != "" {
+		t.Errorf("expected nothing to archive, got %q, %v", runDir, err)
+	}
+}
@@ -0,0 +1,384 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdlsp publishes the problems of the Qodana report as Language Server Protocol diagnostics.
+package qdlsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdreport"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#diagnosticSeverity
+const (
+	severityError       = 1
+	severityWarning     = 2
+	severityInformation = 3
+	severityHint        = 4
+
+	messageTypeWarning = 2
+	messageTypeInfo    = 3
+
+	errorMethodNotFound = -32601
+	errorParse          = -32700
+)
+
+// Server serves the diagnostics over a JSON-RPC connection, e.g. the standard input and output of `qodana lsp`.
+type Server struct {
+	// ProjectDir is the directory the paths of the report are relative to.
+	ProjectDir string
+	// SarifPath is the report to publish, it is re-read when it changes.
+	SarifPath string
+	// Rescan analyses the saved file and returns the path of the report with its problems, nil disables rescans.
+	Rescan func(file string) (string, error)
+	// PollInterval is how often the report is checked for changes.
+	PollInterval time.Duration
+
+	mu         sync.Mutex
+	out        io.Writer
+	published  map[string]bool
+	modTime    time.Time
+	rescanning map[string]bool
+}
+
+type message struct {
+	JsonRpc string           `json:"jsonrpc"`
+	Id      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type diagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+	Uri         string       `json:"uri"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type textDocumentParams struct {
+	TextDocument struct {
+		Uri string `json:"uri"`
+	} `json:"textDocument"`
+}
+
+// Serve handles the messages from in until the client sends exit or closes the connection.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.out = out
+	s.published = map[string]bool{}
+	s.rescanning = map[string]bool{}
+	if s.PollInterval == 0 {
+		s.PollInterval = 2 * time.Second
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+
+	reader := bufio.NewReader(in)
+	for {
+		data, err := readMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var m message
+		if err = json.Unmarshal(data, &m); err != nil {
+			s.send(message{Error: &responseError{Code: errorParse, Message: err.Error()}})
+			continue
+		}
+		switch m.Method {
+		case "initialize":
+			s.reply(m, map[string]any{
+				"capabilities": map[string]any{
+					"textDocumentSync": map[string]any{"openClose": true, "change": 0, "save": map[string]any{"includeText": false}},
+				},
+				"serverInfo": map[string]any{"name": "qodana"},
+			})
+		case "initialized":
+			s.reload(true)
+			go s.poll(stop)
+		case "textDocument/didSave":
+			var params textDocumentParams
+			if err = json.Unmarshal(m.Params, &params); err == nil && s.Rescan != nil {
+				go s.rescan(params.TextDocument.Uri)
+			}
+		case "shutdown":
+			s.reply(m, nil)
+		case "exit":
+			return nil
+		default:
+			if m.Id != nil {
+				s.send(message{Id: m.Id, Error: &responseError{Code: errorMethodNotFound, Message: "method not found: " + m.Method}})
+			}
+		}
+	}
+}
+
+// poll republishes the diagnostics when the report changes.
+func (s *Server) poll(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.reload(false)
+		}
+	}
+}
+
+// reload publishes the diagnostics of the report if it changed since the last time or force is set.
+func (s *Server) reload(force bool) {
+	info, err := os.Stat(s.SarifPath)
+	if err != nil {
+		if force {
+			s.log(messageTypeWarning, fmt.Sprintf("No Qodana report at %s yet, run qodana scan to get the problems", s.SarifPath))
+		}
+		return
+	}
+	s.mu.Lock()
+	changed := !info.ModTime().Equal(s.modTime)
+	s.modTime = info.ModTime()
+	s.mu.Unlock()
+	if !changed && !force {
+		return
+	}
+	problems, err := qdreport.LoadProblems(s.SarifPath)
+	if err != nil {
+		s.log(messageTypeWarning, fmt.Sprintf("Failed to read the Qodana report: %s", err))
+		return
+	}
+	diagnostics := s.diagnostics(problems)
+	s.mu.Lock()
+	stale := s.published
+	s.published = map[string]bool{}
+	s.mu.Unlock()
+	for uri, d := range diagnostics {
+		s.publish(uri, d)
+		delete(stale, uri)
+	}
+	for uri := range stale {
+		s.publish(uri, nil)
+	}
+	s.log(messageTypeInfo, fmt.Sprintf("Published %d Qodana problems in %d files", len(problems), len(diagnostics)))
+}
+
+// rescan analyses the saved file and replaces its diagnostics with the new ones.
+func (s *Server) rescan(uri string) {
+	file, err := uriToPath(uri)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	if s.rescanning[file] {
+		s.mu.Unlock()
+		return
+	}
+	s.rescanning[file] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.rescanning, file)
+		s.mu.Unlock()
+	}()
+
+	s.log(messageTypeInfo, fmt.Sprintf("Analysing %s with Qodana", file))
+	report, err := s.Rescan(file)
+	if err != nil {
+		s.log(messageTypeWarning, fmt.Sprintf("Qodana analysis of %s failed: %s", file, err))
+		return
+	}
+	problems, err := qdreport.LoadProblems(report)
+	if err != nil {
+		s.log(messageTypeWarning, fmt.Sprintf("Failed to read the Qodana report: %s", err))
+		return
+	}
+	s.publish(uri, s.diagnostics(problems)[s.fileUri(file)])
+}
+
+// diagnostics groups the problems present in the current code by the document URI.
+func (s *Server) diagnostics(problems []qdreport.Problem) map[string][]diagnostic {
+	result := map[string][]diagnostic{}
+	for _, p := range problems {
+		if p.File == "" || p.BaselineState == "absent" {
+			continue
+		}
+		uri := s.fileUri(filepath.Join(s.ProjectDir, filepath.FromSlash(p.File)))
+		result[uri] = append(result[uri], toDiagnostic(p))
+	}
+	return result
+}
+
+func toDiagnostic(p qdreport.Problem) diagnostic {
+	start := position{Line: max(p.Line-1, 0), Character: max(p.Column-1, 0)}
+	end := position{Line: start.Line + 1}
+	if p.EndLine > 0 {
+		end = position{Line: p.EndLine - 1, Character: max(p.EndColumn-1, 0)}
+	}
+	return diagnostic{
+		Range:    lspRange{Start: start, End: end},
+		Severity: toSeverity(p.Severity),
+		Code:     p.RuleId,
+		Source:   "qodana",
+		Message:  p.Message,
+	}
+}
+
+// toSeverity maps Qodana and SARIF severities to the diagnostic ones.
+func toSeverity(severity string) int {
+	switch strings.ToLower(severity) {
+	case "critical", "high", "error":
+		return severityError
+	case "moderate", "warning":
+		return severityWarning
+	case "low", "note":
+		return severityInformation
+	default:
+		return severityHint
+	}
+}
+
+func (s *Server) publish(uri string, diagnostics []diagnostic) {
+	if diagnostics == nil {
+		diagnostics = []diagnostic{}
+	}
+	s.mu.Lock()
+	if len(diagnostics) > 0 {
+		s.published[uri] = true
+	} else {
+		delete(s.published, uri)
+	}
+	s.mu.Unlock()
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{Uri: uri, Diagnostics: diagnostics})
+}
+
+func (s *Server) log(messageType int, text string) {
+	s.notify("window/logMessage", map[string]any{"type": messageType, "message": text})
+}
+
+func (s *Server) notify(method string, params any) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	s.send(message{Method: method, Params: data})
+}
+
+func (s *Server) reply(request message, result any) {
+	if result == nil {
+		null := json.RawMessage("null")
+		result = null
+	}
+	s.send(message{Id: request.Id, Result: result})
+}
+
+func (s *Server) send(m message) {
+	m.JsonRpc = "2.0"
+	data, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+// readMessage reads a message framed with the Content-Length header.
+func readMessage(reader *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, "Content-Length:"); ok {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+	data := make([]byte, length)
+	_, err := io.ReadFull(reader, data)
+	return data, err
+}
+
+// fileUri returns the file:// URI of the path.
+func (s *Server) fileUri(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// uriToPath returns the local path of the file:// URI.
+func uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported document URI %q", uri)
+	}
+	path := u.Path
+	//goland:noinspection GoBoolExpressions
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.FromSlash(path), nil
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdlsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testSarif = `{"runs": [{"results": [
+	{"ruleId": "UnusedImport", "level": "warning", "message": {"text": "Unused import"},
+	 "properties": {"qodanaSeverity": "High"},
+	 "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/A.java"}, "region": {"startLine": 3, "startColumn": 1, "charLength": 10}}}]},
+	{"ruleId": "Resolved", "level": "note", "message": {"text": "Gone"}, "baselineState": "absent",
+	 "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/A.java"}, "region": {"startLine": 1}}}]},
+	{"ruleId": "TodoComment", "level": "note", "message": {"text": "TODO"},
+	 "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/B.java"}, "region": {"startLine": 5, "startColumn": 4, "endLine": 6, "endColumn": 2}}}]}
+]}]}`
+
+type client struct {
+	t        *testing.T
+	in       io.Writer
+	messages chan map[string]any
+}
+
+// newClient reads the messages of the server in the background, so the server never blocks on writing.
+func newClient(t *testing.T, in io.Writer, out io.Reader) *client {
+	c := &client{t: t, in: in, messages: make(chan map[string]any, 100)}
+	go func() {
+		reader := bufio.NewReader(out)
+		for {
+			data, err := readMessage(reader)
+			if err != nil {
+				close(c.messages)
+				return
+			}
+			var m map[string]any
+			if err = json.Unmarshal(data, &m); err == nil {
+				c.messages <- m
+			}
+		}
+	}()
+	return c
+}
+
+func (c *client) send(id int, method string, params any) {
+	m := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+	if id > 0 {
+		m["id"] = id
+	}
+	data, _ := json.Marshal(m)
+	if _, err := fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(data), data); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// next returns the next message with the method, or the next response if method is empty.
+func (c *client) next(method string) map[string]any {
+	for {
+		var m map[string]any
+		select {
+		case m = <-c.messages:
+		case <-time.After(10 * time.Second):
+			c.t.Fatalf("no %q message", method)
+		}
+		if m["method"] == method || (method == "" && m["method"] == nil) {
+			return m
+		}
+	}
+}
+
+func diagnosticsOf(m map[string]any) (string, []any) {
+	params := m["params"].(map[string]any)
+	return params["uri"].(string), params["diagnostics"].([]any)
+}
+
+func TestServer(t *testing.T) {
+	projectDir := t.TempDir()
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	if err := os.WriteFile(sarifPath, []byte(testSarif), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rescanned := make(chan string, 1)
+	server := &Server{
+		ProjectDir:   projectDir,
+		SarifPath:    sarifPath,
+		PollInterval: 10 * time.Millisecond,
+		Rescan: func(file string) (string, error) {
+			rescanned <- file
+			report := filepath.Join(t.TempDir(), "qodana.sarif.json")
+			return report, os.WriteFile(report, []byte(`{"runs": [{"results": []}]}`), 0o644)
+		},
+	}
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(inReader, outWriter) }()
+	c := newClient(t, inWriter, outReader)
+
+	c.send(1, "initialize", map[string]any{})
+	response := c.next("")
+	if response["result"].(map[string]any)["capabilities"] == nil {
+		t.Fatalf("unexpected initialize response %v", response)
+	}
+	c.send(0, "initialized", map[string]any{})
+
+	uriA, uriB := server.fileUri(filepath.Join(projectDir, "src", "A.java")), server.fileUri(filepath.Join(projectDir, "src", "B.java"))
+	published := map[string][]any{}
+	for len(published) < 2 {
+		uri, diagnostics := diagnosticsOf(c.next("textDocument/publishDiagnostics"))
+		published[uri] = diagnostics
+	}
+	if len(published[uriA]) != 1 || len(published[uriB]) != 1 {
+		t.Fatalf("unexpected diagnostics %v", published)
+	}
+	a := published[uriA][0].(map[string]any)
+	expectedRange := map[string]any{
+		"start": map[string]any{"line": 2.0, "character": 0.0},
+		"end":   map[string]any{"line": 2.0, "character": 10.0},
+	}
+	if a["severity"] != 1.0 || a["code"] != "UnusedImport" || fmt.Sprint(a["range"]) != fmt.Sprint(expectedRange) {
+		t.Errorf("unexpected diagnostic %v", a)
+	}
+	if b := published[uriB][0].(map[string]any); b["severity"] != 3.0 {
+		t.Errorf("unexpected diagnostic %v", b)
+	}
+
+	c.send(0, "textDocument/didSave", map[string]any{"textDocument": map[string]any{"uri": uriA}})
+	if file := <-rescanned; file != filepath.Join(projectDir, "src", "A.java") {
+		t.Errorf("unexpected rescanned file %s", file)
+	}
+	if uri, diagnostics := diagnosticsOf(c.next("textDocument/publishDiagnostics")); uri != uriA || len(diagnostics) != 0 {
+		t.Errorf("expected the rescan to clear %s, got %s %v", uriA, uri, diagnostics)
+	}
+
+	// B.java is fixed, its diagnostics must be cleared after the report changes
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(sarifPath, []byte(`{"runs": [{"results": []}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(sarifPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if uri, diagnostics := diagnosticsOf(c.next("textDocument/publishDiagnostics")); uri != uriB || len(diagnostics) != 0 {
+		t.Errorf("expected %s to be cleared, got %s %v", uriB, uri, diagnostics)
+	}
+
+	c.send(2, "textDocument/hover", map[string]any{})
+	if response = c.next(""); response["error"] == nil {
+		t.Errorf("expected method not found, got %v", response)
+	}
+	c.send(3, "shutdown", nil)
+	if response = c.next(""); response["id"] != 3.0 {
+		t.Errorf("unexpected shutdown response %v", response)
+	}
+	c.send(0, "exit", nil)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
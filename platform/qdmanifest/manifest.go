@@ -0,0 +1,175 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdmanifest writes and checks the SHA-256 manifest of the files of a results directory, in the format
+// of sha256sum, so the archived results can be checked with sha256sum -c too.
+package qdmanifest
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdsign"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// FileName is the name of the manifest in the results directory.
+	FileName = "qodana-results.sha256"
+	// SignatureName is the name of the detached signature of the manifest.
+	SignatureName = FileName + qdsign.CosignExt
+)
+
+// Check is the result of checking the results directory against its manifest.
+type Check struct {
+	Files    int      `json:"files"`
+	Modified []string `json:"modified"`
+	Missing  []string `json:"missing"`
+	Added    []string `json:"added"`
+}
+
+// Ok returns true if the results directory is the same as when its manifest was written.
+func (c Check) Ok() bool {
+	return len(c.Modified) == 0 && len(c.Missing) == 0 && len(c.Added) == 0
+}
+
+// Write writes the manifest of the files in dir, recursively, and returns its path.
+func Write(dir string) (string, error) {
+	hashes, err := hashDir(dir)
+	if err != nil {
+		return "", err
+	}
+	paths := make([]string, 0, len(hashes))
+	for path := range hashes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var b strings.Builder
+	for _, path := range paths {
+		_, _ = fmt.Fprintf(&b, "%s  %s\n", hashes[path], path)
+	}
+	manifest := filepath.Join(dir, FileName)
+	return manifest, os.WriteFile(manifest, []byte(b.String()), 0o644)
+}
+
+// Sign writes the detached signature of the manifest in dir with the key.
+func Sign(dir string, keyFile string) error {
+	key, err := qdsign.LoadSigningKey(keyFile)
+	if err != nil {
+		return err
+	}
+	signature, err := qdsign.SignFile(filepath.Join(dir, FileName), key)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, SignatureName), signature, 0o644)
+}
+
+// Verify checks the files in dir against its manifest.
+func Verify(dir string) (Check, error) {
+	var c Check
+	expected, err := read(filepath.Join(dir, FileName))
+	if err != nil {
+		return c, err
+	}
+	actual, err := hashDir(dir)
+	if err != nil {
+		return c, err
+	}
+	c.Files = len(expected)
+	for path, hash := range expected {
+		switch actualHash, ok := actual[path]; {
+		case !ok:
+			c.Missing = append(c.Missing, path)
+		case actualHash != hash:
+			c.Modified = append(c.Modified, path)
+		}
+	}
+	for path := range actual {
+		if _, ok := expected[path]; !ok {
+			c.Added = append(c.Added, path)
+		}
+	}
+	sort.Strings(c.Modified)
+	sort.Strings(c.Missing)
+	sort.Strings(c.Added)
+	return c, nil
+}
+
+func read(manifest string) (map[string]string, error) {
+	f, err := os.Open(manifest)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	hashes := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		hash, path, found := strings.Cut(scanner.Text(), "  ")
+		if !found || len(hash) != sha256.Size*2 {
+			return nil, fmt.Errorf("malformed line %q in %s", scanner.Text(), manifest)
+		}
+		hashes[path] = hash
+	}
+	return hashes, scanner.Err()
+}
+
+// hashDir returns the SHA-256 hashes of the files in dir, except the manifest and its signature, by their
+// slash-separated paths relative to dir.
+func hashDir(dir string) (map[string]string, error) {
+	hashes := map[string]string{}
+	err := filepath.WalkDir(
+		dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if rel == FileName || rel == SignatureName {
+				return nil
+			}
+			hash, err := hashFile(path)
+			if err != nil {
+				return err
+			}
+			hashes[rel] = hash
+			return nil
+		},
+	)
+	return hashes, err
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdmanifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteVerify(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"qodana.sarif.json":  "{}",
+		"log/idea.log":       "started",
+		"report/index.html":  "<html></html>",
+		"report/results.zip": "zip",
+	}
+	for path, content := range files {
+		full := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifest, err := Write(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != len(files) || lines[1] != "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a  qodana.sarif.json" {
+		t.Errorf("unexpected manifest %s", data)
+	}
+	check, err := Verify(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !check.Ok() || check.Files != len(files) {
+		t.Errorf("expected the unchanged results to match, got %+v", check)
+	}
+
+	_ = os.WriteFile(filepath.Join(dir, "qodana.sarif.json"), []byte(`{"runs":[]}`), 0o644)
+	_ = os.Remove(filepath.Join(dir, "log", "idea.log"))
+	_ = os.WriteFile(filepath.Join(dir, "extra.txt"), []byte("extra"), 0o644)
+	check, err = Verify(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Check{
+		Files:    len(files),
+		Modified: []string{"qodana.sarif.json"},
+		Missing:  []string{"log/idea.log"},
+		Added:    []string{"extra.txt"},
+	}
+	if !reflect.DeepEqual(check, expected) {
+		t.Errorf("Verify() = %+v, want %+v", check, expected)
+	}
+}
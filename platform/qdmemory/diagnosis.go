@@ -0,0 +1,81 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdmemory
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	mib = 1024 * 1024
+	gib = 1024 * mib
+	// heapRatio is the largest share of the memory limit the heap can take, the IDE also uses memory outside of it.
+	heapRatio = 0.75
+)
+
+// Diagnosis describes a run killed because it ran out of memory.
+type Diagnosis struct {
+	// Peak is the peak memory used by the run in bytes, 0 if unknown.
+	Peak int64
+	// Limit is the memory limit in bytes, 0 if unknown.
+	Limit int64
+	// Heap is the maximum heap size set by -Xmx in bytes, 0 if not set.
+	Heap int64
+}
+
+// HeapDumps returns the heap dumps written to the directories on OutOfMemoryError.
+func HeapDumps(dirs ...string) []string {
+	var dumps []string
+	for _, dir := range dirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.hprof"))
+		dumps = append(dumps, matches...)
+	}
+	sort.Strings(dumps)
+	return dumps
+}
+
+// Suggestions returns the changes of the heap size and of the memory limit letting the analysis fit in memory.
+func (d Diagnosis) Suggestions() []string {
+	var suggestions []string
+	if d.Heap > 0 && d.Limit > 0 && float64(d.Heap) > heapRatio*float64(d.Limit) {
+		suggestions = append(
+			suggestions,
+			fmt.Sprintf(
+				"lower the heap size to -Xmx%dm, %.0f%% of the memory limit, as the IDE also uses memory outside of the heap",
+				int64(heapRatio*float64(d.Limit))/mib,
+				heapRatio*100,
+			),
+		)
+	}
+	if d.Heap == 0 {
+		suggestions = append(
+			suggestions,
+			"set the heap size with --vmoptions=-Xmx<size> or vmoptions of qodana.yaml, by default it is 70% of the memory limit",
+		)
+	}
+	if d.Limit > 0 {
+		needed := max(d.Limit*3/2, int64(float64(d.Heap)/heapRatio))
+		needed = (needed + gib - 1) / gib * gib
+		suggestions = append(
+			suggestions,
+			fmt.Sprintf("increase the memory limit of the container or of the machine to at least %d GB", needed/gib),
+		)
+	}
+	return suggestions
+}
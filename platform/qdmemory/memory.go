@@ -0,0 +1,132 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdmemory watches the memory used during the analysis against the memory limit of the container
+// or of the machine, and explains the runs killed because they ran out of memory.
+package qdmemory
+
+import (
+	"github.com/shirou/gopsutil/v3/mem"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	cgroupRoot = "/sys/fs/cgroup"
+	// WarningRatio is the share of the memory limit used at which the monitor warns.
+	WarningRatio = 0.9
+	// cgroupV1Unlimited is the limit of cgroup v1 without a limit, rounded down to the page size.
+	cgroupV1Unlimited = math.MaxInt64 / 4096 * 4096
+)
+
+// Sample returns the memory used and the memory limit in bytes: of the cgroup of the container,
+// or of the machine if there is no cgroup limit.
+func Sample() (int64, int64, bool) {
+	if usage, limit, ok := cgroupSample(cgroupRoot); ok {
+		return usage, limit, true
+	}
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		return 0, 0, false
+	}
+	return int64(vm.Used), int64(vm.Total), true
+}
+
+// cgroupSample reads memory.current and memory.max of cgroup v2 or memory.usage_in_bytes and memory.limit_in_bytes
+// of cgroup v1 in root.
+func cgroupSample(root string) (int64, int64, bool) {
+	for _, files := range [][2]string{
+		{"memory.current", "memory.max"},
+		{"memory/memory.usage_in_bytes", "memory/memory.limit_in_bytes"},
+	} {
+		limit, ok := readBytes(filepath.Join(root, files[1]))
+		if !ok || limit >= cgroupV1Unlimited {
+			continue
+		}
+		if usage, ok := readBytes(filepath.Join(root, files[0])); ok {
+			return usage, limit, true
+		}
+	}
+	return 0, 0, false
+}
+
+func readBytes(path string) (int64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || value <= 0 {
+		return 0, false // "max" of cgroup v2 without a limit
+	}
+	return value, true
+}
+
+// Monitor samples the memory during the analysis.
+type Monitor struct {
+	mu    sync.Mutex
+	peak  int64
+	limit int64
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// Start samples the memory every interval until Stop, warn is called once when the memory used
+// reaches WarningRatio of the limit.
+func Start(interval time.Duration, warn func(usage int64, limit int64)) *Monitor {
+	return start(Sample, interval, warn)
+}
+
+func start(sample func() (int64, int64, bool), interval time.Duration, warn func(usage int64, limit int64)) *Monitor {
+	m := &Monitor{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		warned := false
+		for {
+			if usage, limit, ok := sample(); ok {
+				m.mu.Lock()
+				m.peak, m.limit = max(m.peak, usage), limit
+				m.mu.Unlock()
+				if !warned && float64(usage) >= WarningRatio*float64(limit) {
+					warned = true
+					warn(usage, limit)
+				}
+			}
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return m
+}
+
+// Stop stops sampling and returns the peak memory used and the memory limit in bytes.
+func (m *Monitor) Stop() (int64, int64) {
+	close(m.stop)
+	<-m.done
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.peak, m.limit
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdmemory
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCgroupSample(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		files map[string]string
+		usage int64
+		limit int64
+		ok    bool
+	}{
+		{name: "no cgroup"},
+		{name: "cgroup v2 without a limit", files: map[string]string{"memory.current": "1024\n", "memory.max": "max\n"}},
+		{
+			name:  "cgroup v2",
+			files: map[string]string{"memory.current": "1024\n", "memory.max": "4096\n"},
+			usage: 1024,
+			limit: 4096,
+			ok:    true,
+		},
+		{
+			name: "cgroup v1 without a limit",
+			files: map[string]string{
+				"memory/memory.usage_in_bytes": "1024\n",
+				"memory/memory.limit_in_bytes": strconv.FormatInt(cgroupV1Unlimited, 10),
+			},
+		},
+		{
+			name:  "cgroup v1",
+			files: map[string]string{"memory/memory.usage_in_bytes": "2048\n", "memory/memory.limit_in_bytes": "8192\n"},
+			usage: 2048,
+			limit: 8192,
+			ok:    true,
+		},
+	} {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				root := t.TempDir()
+				for name, content := range tt.files {
+					path := filepath.Join(root, filepath.FromSlash(name))
+					if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				usage, limit, ok := cgroupSample(root)
+				if usage != tt.usage || limit != tt.limit || ok != tt.ok {
+					t.Errorf("cgroupSample() = %d, %d, %v, want %d, %d, %v", usage, limit, ok, tt.usage, tt.limit, tt.ok)
+				}
+			},
+		)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	var mu sync.Mutex
+	samples := []int64{50, 95, 80, 99}
+	calls := 0
+	sample := func() (int64, int64, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return samples[min(calls, len(samples))-1], 100, true
+	}
+	warnings := make(chan int64, 4)
+	m := start(sample, time.Millisecond, func(usage int64, limit int64) { warnings <- usage })
+	// the last sample is recorded before the one after it is taken
+	for {
+		mu.Lock()
+		sampled := calls > len(samples)
+		mu.Unlock()
+		if sampled {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	peak, limit := m.Stop()
+	if peak != 99 || limit != 100 {
+		t.Errorf("expected the peak 99 of 100, got %d of %d", peak, limit)
+	}
+	close(warnings)
+	var warned []int64
+	for usage := range warnings {
+		warned = append(warned, usage)
+	}
+	if !reflect.DeepEqual(warned, []int64{95}) {
+		t.Errorf("expected one warning at 95, got %v", warned)
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		diagnosis Diagnosis
+		expected  []string
+	}{
+		{
+			name:      "heap close to the limit",
+			diagnosis: Diagnosis{Limit: 8 * gib, Heap: 8 * gib},
+			expected: []string{
+				"lower the heap size to -Xmx6144m, 75% of the memory limit, as the IDE also uses memory outside of the heap",
+				"increase the memory limit of the container or of the machine to at least 12 GB",
+			},
+		},
+		{
+			name:      "default heap",
+			diagnosis: Diagnosis{Limit: 3 * gib},
+			expected: []string{
+				"set the heap size with --vmoptions=-Xmx<size> or vmoptions of qodana.yaml, by default it is 70% of the memory limit",
+				"increase the memory limit of the container or of the machine to at least 5 GB",
+			},
+		},
+		{
+			name:      "heap within the limit",
+			diagnosis: Diagnosis{Limit: 16 * gib, Heap: 12 * gib},
+			expected:  []string{"increase the memory limit of the container or of the machine to at least 24 GB"},
+		},
+		{
+			name:      "unknown limit",
+			diagnosis: Diagnosis{Heap: 4 * gib},
+		},
+	} {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if actual := tt.diagnosis.Suggestions(); !reflect.DeepEqual(actual, tt.expected) {
+					t.Errorf("Suggestions() = %q, want %q", actual, tt.expected)
+				}
+			},
+		)
+	}
+}
+
+func TestHeapDumps(t *testing.T) {
+	logDir := t.TempDir()
+	for _, name := range []string{"java_pid42.hprof", "idea.log"} {
+		if err := os.WriteFile(filepath.Join(logDir, name), []byte{}, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{filepath.Join(logDir, "java_pid42.hprof")}
+	if actual := HeapDumps(logDir, t.TempDir()); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("HeapDumps() = %v, want %v", actual, expected)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdmetrics collects the metrics of a Qodana run and exposes them in the Prometheus text format.
+package qdmetrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the metrics file written to the results directory.
+const FileName = "metrics.prom"
+
+const pushTimeout = 30 * time.Second
+
+type sample struct {
+	labels map[string]string
+	value  float64
+}
+
+type gauge struct {
+	help    string
+	samples []sample
+}
+
+var (
+	mu     sync.Mutex
+	gauges = map[string]*gauge{}
+)
+
+// Gauge records the value of the gauge with the given labels, replacing the previous value of the same series.
+func Gauge(name string, help string, value float64, labels map[string]string) {
+	mu.Lock()
+	defer mu.Unlock()
+	g, ok := gauges[name]
+	if !ok {
+		g = &gauge{help: help}
+		gauges[name] = g
+	}
+	key := labelsString(labels)
+	for i, s := range g.samples {
+		if labelsString(s.labels) == key {
+			g.samples[i].value = value
+			return
+		}
+	}
+	g.samples = append(g.samples, sample{labels: labels, value: value})
+}
+
+// Write writes all recorded metrics in the Prometheus text format.
+func Write(w io.Writer) error {
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(gauges))
+	for name := range gauges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g := gauges[name]
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, g.help, name); err != nil {
+			return err
+		}
+		samples := append([]sample(nil), g.samples...)
+		sort.Slice(samples, func(i, j int) bool { return labelsString(samples[i].labels) < labelsString(samples[j].labels) })
+		for _, s := range samples {
+			value := strconv.FormatFloat(s.value, 'g', -1, 64)
+			if _, err := fmt.Fprintf(w, "%s%s %s\n", name, labelsString(s.labels), value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteFile writes all recorded metrics to metrics.prom in the given directory.
+func WriteFile(dir string) (string, error) {
+	var b bytes.Buffer
+	if err := Write(&b); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, FileName)
+	return path, os.WriteFile(path, b.Bytes(), 0o644)
+}
+
+// Push replaces the metrics of the given job and grouping labels in the Prometheus Pushgateway.
+func Push(pushgatewayUrl string, job string, grouping map[string]string) error {
+	var b bytes.Buffer
+	if err := Write(&b); err != nil {
+		return err
+	}
+	target := strings.TrimSuffix(pushgatewayUrl, "/") + "/metrics/job/" + url.PathEscape(job)
+	keys := sortedKeys(grouping)
+	for _, k := range keys {
+		target += "/" + url.PathEscape(k) + "/" + url.PathEscape(grouping[k])
+	}
+	req, err := http.NewRequest(http.MethodPut, target, &b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	client := &http.Client{Timeout: pushTimeout, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pushgateway %s responded with %s", pushgatewayUrl, resp.Status)
+	}
+	return nil
+}
+
+// Reset removes all recorded metrics.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	gauges = map[string]*gauge{}
+}
+
+func labelsString(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels))
+	for _, k := range sortedKeys(labels) {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, strconv.Quote(labels[k])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdmetrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteAndPush(t *testing.T) {
+	Reset()
+	defer Reset()
+	Gauge("qodana_problems", "Number of problems.", 1, map[string]string{"severity": "high"})
+	Gauge("qodana_problems", "Number of problems.", 3, map[string]string{"severity": "critical"})
+	Gauge("qodana_problems", "Number of problems.", 2, map[string]string{"severity": "high"})
+	Gauge("qodana_run_duration_seconds", "Duration.", 12.5, nil)
+
+	expected := `# HELP qodana_problems Number of problems.
+# TYPE qodana_problems gauge
+qodana_problems{severity="critical"} 3
+qodana_problems{severity="high"} 2
+# HELP qodana_run_duration_seconds Duration.
+# TYPE qodana_run_duration_seconds gauge
+qodana_run_duration_seconds 12.5
+`
+	var b strings.Builder
+	if err := Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+
+	var path, body string
+	svr := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPut {
+					t.Errorf("expected PUT, got %s", r.Method)
+				}
+				path = r.URL.Path
+				content, _ := io.ReadAll(r.Body)
+				body = string(content)
+			},
+		),
+	)
+	defer svr.Close()
+	if err := Push(svr.URL+"/", "qodana", map[string]string{"project": "my-project"}); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/metrics/job/qodana/project/my-project" {
+		t.Errorf("unexpected push path %s", path)
+	}
+	if body != expected {
+		t.Errorf("unexpected push body %s", body)
+	}
+}
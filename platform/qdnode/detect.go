@@ -0,0 +1,71 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdnode detects the Node.js version a JavaScript project requires and provisions it for the native analysis:
+// an installed Node.js matching the version is selected, otherwise it is downloaded from nodejs.org into the Qodana cache.
+package qdnode
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Requirement is the Node.js version spec required by the project and the file requiring it.
+type Requirement struct {
+	Spec   string
+	Source string
+}
+
+// Detect returns the Node.js version required by the project, read from .nvmrc, .node-version
+// or the engines field of package.json, in this order.
+func Detect(projectDir string) (Requirement, bool) {
+	for _, name := range []string{".nvmrc", ".node-version"} {
+		content, err := os.ReadFile(filepath.Join(projectDir, name))
+		if err != nil {
+			continue
+		}
+		if spec := versionFileSpec(string(content)); spec != "" {
+			return Requirement{Spec: spec, Source: name}, true
+		}
+	}
+	content, err := os.ReadFile(filepath.Join(projectDir, "package.json"))
+	if err != nil {
+		return Requirement{}, false
+	}
+	var packageJson struct {
+		Engines map[string]string `json:"engines"`
+	}
+	if json.Unmarshal(content, &packageJson) != nil {
+		return Requirement{}, false
+	}
+	if spec := strings.TrimSpace(packageJson.Engines["node"]); spec != "" {
+		return Requirement{Spec: spec, Source: "package.json"}, true
+	}
+	return Requirement{}, false
+}
+
+// versionFileSpec returns the first line of .nvmrc or .node-version, without comments.
+func versionFileSpec(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdnode
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcache"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultDownloadUrl is the distribution site the Node.js versions are downloaded from.
+const DefaultDownloadUrl = "https://nodejs.org/dist"
+
+// Downloader downloads the Node.js versions from nodejs.org, or a mirror of it.
+type Downloader struct {
+	Url    string
+	Os     string
+	Arch   string
+	Client *http.Client
+}
+
+// NewDownloader returns the downloader of Node.js for the current platform,
+// from $QODANA_NODE_DOWNLOAD_URL if it is set, otherwise from nodejs.org.
+func NewDownloader() *Downloader {
+	u := os.Getenv(qdenv.QodanaNodeDownloadUrlEnv)
+	if u == "" {
+		u = DefaultDownloadUrl
+	}
+	return &Downloader{
+		Url:    strings.TrimSuffix(u, "/"),
+		Os:     runtime.GOOS,
+		Arch:   runtime.GOARCH,
+		Client: &http.Client{Timeout: 30 * time.Minute, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}},
+	}
+}
+
+// indexEntry is a release listed in index.json of the distribution site, lts is false or the codename.
+type indexEntry struct {
+	Version string          `json:"version"`
+	Files   []string        `json:"files"`
+	Lts     json.RawMessage `json:"lts"`
+}
+
+// Download downloads the newest Node.js matching the range into cacheDir and returns its home and release.
+func (d *Downloader) Download(r Range, cacheDir string) (string, Release, error) {
+	osName, ok := map[string]string{"linux": "linux", "darwin": "darwin", "windows": "win"}[d.Os]
+	if !ok {
+		return "", Release{}, fmt.Errorf("no Node.js downloads for %s", d.Os)
+	}
+	arch, ok := map[string]string{"amd64": "x64", "arm64": "arm64"}[d.Arch]
+	if !ok {
+		return "", Release{}, fmt.Errorf("no Node.js downloads for %s", d.Arch)
+	}
+	file := map[string]string{"linux": "linux-%s", "darwin": "osx-%s-tar", "win": "win-%s-zip"}[osName]
+	release, err := d.find(r, fmt.Sprintf(file, arch))
+	if err != nil {
+		return "", Release{}, err
+	}
+
+	ext := "tar.gz"
+	if osName == "win" {
+		ext = "zip"
+	}
+	u := fmt.Sprintf("%s/%s/node-%s-%s-%s.%s", d.Url, release.Version, release.Version, osName, arch, ext)
+	response, err := d.Client.Get(u)
+	if err != nil {
+		return "", Release{}, fmt.Errorf("failed to reach %s: %w", d.Url, err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return "", Release{}, fmt.Errorf("GET %s: %s", u, response.Status)
+	}
+
+	dir := filepath.Join(cacheDir, cacheDirName, release.Version)
+	tmp := dir + ".download"
+	_ = os.RemoveAll(tmp)
+	defer func() { _ = os.RemoveAll(tmp) }()
+	if err = os.MkdirAll(tmp, os.ModePerm); err != nil {
+		return "", Release{}, err
+	}
+	if ext == "zip" {
+		err = utils.ExtractZip(response.Body, tmp)
+	} else {
+		err = qdcache.Extract(response.Body, tmp)
+	}
+	if err != nil {
+		return "", Release{}, fmt.Errorf("failed to extract Node.js %s: %w", release.Version, err)
+	}
+	// the archives contain the single node-<version>-<os>-<arch> directory
+	home := filepath.Join(tmp, fmt.Sprintf("node-%s-%s-%s", release.Version, osName, arch))
+	if _, err = os.Stat(home); err != nil {
+		return "", Release{}, fmt.Errorf("no Node.js in the archive downloaded from %s", u)
+	}
+	_ = os.RemoveAll(dir)
+	if err = os.Rename(home, dir); err != nil {
+		return "", Release{}, err
+	}
+	return dir, release, nil
+}
+
+// find returns the newest release matching the range with the file for the platform.
+func (d *Downloader) find(r Range, file string) (Release, error) {
+	u := d.Url + "/index.json"
+	response, err := d.Client.Get(u)
+	if err != nil {
+		return Release{}, fmt.Errorf("failed to reach %s: %w", d.Url, err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return Release{}, fmt.Errorf("GET %s: %s", u, response.Status)
+	}
+	var entries []indexEntry
+	if err = json.NewDecoder(response.Body).Decode(&entries); err != nil {
+		return Release{}, fmt.Errorf("failed to parse %s: %w", u, err)
+	}
+	var found Release
+	var foundVersion [3]int
+	for _, entry := range entries {
+		release := Release{Version: entry.Version}
+		_ = json.Unmarshal(entry.Lts, &release.Lts) // false for non-LTS releases
+		version, err := parseVersion(entry.Version)
+		if err != nil || !slices.Contains(entry.Files, file) || !r.Match(release) {
+			continue
+		}
+		if found.Version == "" || compare(version, foundVersion) > 0 {
+			found, foundVersion = release, version
+		}
+	}
+	if found.Version == "" {
+		return Release{}, fmt.Errorf("no Node.js release matches %s for %s", r, file)
+	}
+	return found, nil
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdnode
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cacheDirName is the directory of the downloaded Node.js versions in the Qodana cache directory.
+const cacheDirName = "nodes"
+
+// Installed returns the release of the node executable, "node" for the one on PATH.
+func Installed(node string) (Release, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, node, "-p", `process.version + " " + (process.release.lts || "")`).Output()
+	if err != nil {
+		return Release{}, fmt.Errorf("failed to run %s: %w", node, err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return Release{}, fmt.Errorf("%s printed no version", node)
+	}
+	release := Release{Version: fields[0]}
+	if len(fields) > 1 {
+		release.Lts = fields[1]
+	}
+	return release, nil
+}
+
+// BinDir returns the directory of the node executable in the Node.js home.
+func BinDir(home string) string {
+	if runtime.GOOS == "windows" {
+		return home
+	}
+	return filepath.Join(home, "bin")
+}
+
+// Find returns the home of an installed Node.js matching the range, the newest one if there are several:
+// the versions downloaded to cacheDir and the versions installed by nvm.
+func Find(r Range, cacheDir string) (string, Release, bool) {
+	for _, home := range candidates(cacheDir) {
+		node := filepath.Join(BinDir(home), "node")
+		if runtime.GOOS == "windows" {
+			node += ".exe"
+		}
+		if _, err := os.Stat(node); err != nil {
+			continue
+		}
+		// the name of the directory is the version, but the LTS codename is known only to node itself
+		if _, err := parseVersion(filepath.Base(home)); err == nil && !r.IsLts() && !r.Match(Release{Version: filepath.Base(home)}) {
+			continue
+		}
+		if release, err := Installed(node); err == nil && r.Match(release) {
+			return home, release, true
+		}
+	}
+	return "", Release{}, false
+}
+
+func candidates(cacheDir string) []string {
+	roots := []string{filepath.Join(cacheDir, cacheDirName)}
+	if nvmDir := os.Getenv("NVM_DIR"); nvmDir != "" {
+		roots = append(roots, filepath.Join(nvmDir, "versions", "node"))
+	} else if userHome, err := os.UserHomeDir(); err == nil {
+		roots = append(roots, filepath.Join(userHome, ".nvm", "versions", "node"))
+	}
+	if nvmHome := os.Getenv("NVM_HOME"); nvmHome != "" { // nvm-windows
+		roots = append(roots, nvmHome)
+	}
+	var homes []string
+	for _, root := range roots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+		var versions []string
+		for _, entry := range entries {
+			if entry.IsDir() {
+				versions = append(versions, entry.Name())
+			}
+		}
+		sort.Slice(
+			versions, func(i, j int) bool {
+				a, _ := parseVersion(versions[i])
+				b, _ := parseVersion(versions[j])
+				return compare(a, b) > 0
+			},
+		)
+		for _, version := range versions {
+			homes = append(homes, filepath.Join(root, version))
+		}
+	}
+	return homes
+}
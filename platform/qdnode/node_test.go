@@ -0,0 +1,195 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdnode
+
+import (
+	"bytes"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcache"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, content string, mode os.FileMode) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected Requirement
+	}{
+		{"nvmrc", map[string]string{".nvmrc": "# pinned\nv18.17.0\n", "package.json": `{"engines":{"node":">=16"}}`}, Requirement{"v18.17.0", ".nvmrc"}},
+		{"node-version", map[string]string{".node-version": "20.10\n"}, Requirement{"20.10", ".node-version"}},
+		{"engines", map[string]string{"package.json": `{"engines":{"node":">=18 <21"}}`}, Requirement{">=18 <21", "package.json"}},
+		{"no requirement", map[string]string{"package.json": `{"name":"app"}`}, Requirement{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, filepath.Join(project, name), content, 0o644)
+			}
+			actual, ok := Detect(project)
+			if actual != tt.expected || ok != (tt.expected.Spec != "") {
+				t.Errorf("Detect() = %v %v, want %v", actual, ok, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		spec     string
+		version  string
+		lts      string
+		expected bool
+	}{
+		{"18", "v18.17.0", "", true},
+		{"18", "v19.0.0", "", false},
+		{"v18.17.0", "v18.17.0", "", true},
+		{"18.17.0", "v18.17.1", "", false},
+		{"18.x", "v18.0.0", "", true},
+		{">=18 <21", "v20.10.0", "", true},
+		{">=18 <21", "v21.0.0", "", false},
+		{">= 18.17", "v18.16.9", "", false},
+		{">16", "v16.20.0", "", false},
+		{">16", "v17.0.0", "", true},
+		{"<=20.9", "v20.9.5", "", true},
+		{"^18.17.0", "v18.20.0", "", true},
+		{"^18.17.0", "v19.0.0", "", false},
+		{"^0.12.1", "v0.12.9", "", true},
+		{"^0.12.1", "v0.13.0", "", false},
+		{"~20.10.0", "v20.10.9", "", true},
+		{"~20.10.0", "v20.11.0", "", false},
+		{"16 || 18", "v18.0.0", "", true},
+		{"16 || 18", "v17.0.0", "", false},
+		{"18.0.0 - 20", "v20.11.0", "", true},
+		{"18.0.0 - 20", "v21.0.0", "", false},
+		{"node", "v21.1.0", "", true},
+		{"lts/*", "v21.1.0", "", false},
+		{"lts/*", "v20.9.0", "Iron", true},
+		{"lts/hydrogen", "v18.17.0", "Hydrogen", true},
+		{"lts/hydrogen", "v20.9.0", "Iron", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec+" "+tt.version, func(t *testing.T) {
+			r, err := ParseRange(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := r.Match(Release{tt.version, tt.lts}); actual != tt.expected {
+				t.Errorf("%q.Match(%s) = %v, want %v", tt.spec, tt.version, actual, tt.expected)
+			}
+		})
+	}
+	if _, err := ParseRange(">=eighteen"); err == nil {
+		t.Error("expected an invalid version to be rejected")
+	}
+}
+
+// fakeNode writes a node executable printing the release, as node -p does.
+func fakeNode(t *testing.T, home string, release string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake node executable is a shell script")
+	}
+	writeFile(t, filepath.Join(home, "bin", "node"), "#!/bin/sh\necho '"+release+"'\n", 0o755)
+}
+
+func TestFind(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("NVM_DIR", t.TempDir())
+	t.Setenv("NVM_HOME", "")
+	fakeNode(t, filepath.Join(cacheDir, cacheDirName, "v18.17.0"), "v18.17.0 Hydrogen")
+	fakeNode(t, filepath.Join(cacheDir, cacheDirName, "v18.19.1"), "v18.19.1 Hydrogen")
+	fakeNode(t, filepath.Join(os.Getenv("NVM_DIR"), "versions", "node", "v21.1.0"), "v21.1.0 ")
+
+	for spec, expected := range map[string]string{"18": "v18.19.1", "lts/hydrogen": "v18.19.1", ">=20": "v21.1.0", "18.17": "v18.17.0"} {
+		r, _ := ParseRange(spec)
+		home, release, ok := Find(r, cacheDir)
+		if !ok || release.Version != expected || filepath.Base(home) != expected {
+			t.Errorf("Find(%s) = %s %v %v, want %s", spec, home, release, ok, expected)
+		}
+	}
+	r, _ := ParseRange("16")
+	if home, _, ok := Find(r, cacheDir); ok {
+		t.Errorf("expected no Node.js 16, got %s", home)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	node := t.TempDir()
+	writeFile(t, filepath.Join(node, "node-v20.10.0-linux-x64", "bin", "node"), "", 0o755)
+	var archive bytes.Buffer
+	if err := qdcache.Archive(&archive, node); err != nil {
+		t.Fatal(err)
+	}
+	index := `[
+  {"version": "v21.1.0", "files": ["linux-x64"], "lts": false},
+  {"version": "v20.10.0", "files": ["linux-x64", "win-x64-zip"], "lts": "Iron"},
+  {"version": "v20.9.0", "files": ["linux-x64"], "lts": "Iron"},
+  {"version": "v18.19.0", "files": ["osx-arm64-tar"], "lts": "Hydrogen"}
+]`
+	var requested []string
+	svr := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				requested = append(requested, r.URL.Path)
+				if r.URL.Path == "/index.json" {
+					_, _ = w.Write([]byte(index))
+					return
+				}
+				_, _ = w.Write(archive.Bytes())
+			},
+		),
+	)
+	defer svr.Close()
+
+	d := &Downloader{Url: svr.URL, Os: "linux", Arch: "amd64", Client: svr.Client()}
+	cacheDir := t.TempDir()
+	r, _ := ParseRange("lts/*")
+	home, release, err := d.Download(r, cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release != (Release{"v20.10.0", "Iron"}) {
+		t.Errorf("expected Node.js v20.10.0 (Iron), got %s", release)
+	}
+	if len(requested) != 2 || requested[1] != "/v20.10.0/node-v20.10.0-linux-x64.tar.gz" {
+		t.Errorf("unexpected requests %v", requested)
+	}
+	if home != filepath.Join(cacheDir, cacheDirName, "v20.10.0") {
+		t.Errorf("unexpected home %s", home)
+	}
+	if _, err = os.Stat(filepath.Join(BinDir(home), "node")); err != nil {
+		t.Error(err)
+	}
+
+	r, _ = ParseRange("18")
+	if _, _, err = d.Download(r, cacheDir); err == nil {
+		t.Error("expected no Node.js 18 for linux-x64")
+	}
+}
@@ -0,0 +1,266 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdnode
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Release is a Node.js version, with the codename of its LTS line if it is an LTS release.
+type Release struct {
+	Version string
+	Lts     string
+}
+
+func (r Release) String() string {
+	if r.Lts == "" {
+		return r.Version
+	}
+	return fmt.Sprintf("%s (%s)", r.Version, r.Lts)
+}
+
+// Range is a parsed Node.js version spec: a version, an npm semver range like ">=18 <21" or "^20.10",
+// or an nvm alias like "lts/*", "lts/iron" or "node".
+type Range struct {
+	spec string
+	lts  string // "*" for any LTS release, otherwise the lowercase codename; empty for semver ranges
+	any  bool
+	sets [][]comparator // alternatives of ||, each of them ANDed comparators
+}
+
+type comparator struct {
+	op      string
+	version [3]int
+}
+
+var (
+	hyphenPattern  = regexp.MustCompile(`^(\S+)\s+-\s+(\S+)$`)
+	operatorSpaces = regexp.MustCompile(`(>=|<=|>|<|=|\^|~)\s+`)
+	partialPattern = regexp.MustCompile(`^(\^|~>?|>=|<=|>|<|=)?v?(.*)$`)
+)
+
+// ParseRange parses the Node.js version spec.
+func ParseRange(spec string) (Range, error) {
+	spec = strings.TrimSpace(spec)
+	r := Range{spec: spec}
+	switch alias := strings.ToLower(spec); {
+	case alias == "node" || alias == "latest" || alias == "current" || alias == "stable" || alias == "*" || alias == "":
+		r.any = true
+		return r, nil
+	case alias == "lts" || alias == "lts/*" || strings.HasPrefix(alias, "lts/-"):
+		r.lts = "*"
+		return r, nil
+	case strings.HasPrefix(alias, "lts/"):
+		r.lts = strings.TrimPrefix(alias, "lts/")
+		return r, nil
+	}
+	for _, alternative := range strings.Split(spec, "||") {
+		set, err := parseComparatorSet(strings.TrimSpace(alternative))
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid Node.js version %q: %w", spec, err)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+func (r Range) String() string {
+	return r.spec
+}
+
+// IsLts returns true if the range is an LTS alias, which can be matched only by a release with a known LTS codename.
+func (r Range) IsLts() bool {
+	return r.lts != ""
+}
+
+// Match returns true if the release satisfies the range.
+func (r Range) Match(release Release) bool {
+	if r.any {
+		return true
+	}
+	if r.lts != "" {
+		return release.Lts != "" && (r.lts == "*" || strings.EqualFold(r.lts, release.Lts))
+	}
+	version, err := parseVersion(release.Version)
+	if err != nil {
+		return false
+	}
+	for _, set := range r.sets {
+		if matchAll(set, version) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAll(set []comparator, version [3]int) bool {
+	for _, c := range set {
+		d := compare(version, c.version)
+		ok := false
+		switch c.op {
+		case "=":
+			ok = d == 0
+		case ">":
+			ok = d > 0
+		case ">=":
+			ok = d >= 0
+		case "<":
+			ok = d < 0
+		case "<=":
+			ok = d <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func compare(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseComparatorSet converts the space-separated comparators, or a hyphen range, to plain comparators.
+func parseComparatorSet(set string) ([]comparator, error) {
+	if m := hyphenPattern.FindStringSubmatch(set); m != nil {
+		from, err := parsePartial(m[1])
+		if err != nil {
+			return nil, err
+		}
+		to, err := parsePartial(m[2])
+		if err != nil {
+			return nil, err
+		}
+		return append(expand(">=", from), expand("<=", to)...), nil
+	}
+	var result []comparator
+	for _, token := range strings.Fields(operatorSpaces.ReplaceAllString(set, "$1")) {
+		m := partialPattern.FindStringSubmatch(token)
+		p, err := parsePartial(m[2])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, expand(m[1], p)...)
+	}
+	return result, nil
+}
+
+// expand converts the operator applied to a partial version, like ^1.2 or <=18, to plain comparators.
+func expand(op string, p []int) []comparator {
+	lower := pad(p)
+	switch op {
+	case "", "=":
+		if len(p) == 3 {
+			return []comparator{{"=", lower}}
+		}
+		return between(p, lower, len(p)-1)
+	case ">=":
+		return []comparator{{">=", lower}}
+	case ">":
+		if len(p) == 3 {
+			return []comparator{{">", lower}}
+		}
+		if len(p) == 0 {
+			return []comparator{{"<", [3]int{}}} // nothing is greater than any version
+		}
+		return []comparator{{">=", bump(p, len(p)-1)}}
+	case "<":
+		return []comparator{{"<", lower}}
+	case "<=":
+		if len(p) == 3 {
+			return []comparator{{"<=", lower}}
+		}
+		if len(p) == 0 {
+			return nil
+		}
+		return []comparator{{"<", bump(p, len(p)-1)}}
+	case "~", "~>":
+		return between(p, lower, min(len(p)-1, 1))
+	case "^":
+		// the left-most non-zero part is bumped, ^0.2.3 is <0.3.0 and ^0.0.3 is <0.0.4
+		i := 0
+		for i < len(p)-1 && p[i] == 0 {
+			i++
+		}
+		return between(p, lower, i)
+	}
+	return nil
+}
+
+// between returns >=lower <p with the part at the index bumped, any version if p is empty.
+func between(p []int, lower [3]int, index int) []comparator {
+	if len(p) == 0 {
+		return nil
+	}
+	return []comparator{{">=", lower}, {"<", bump(p, index)}}
+}
+
+func bump(p []int, index int) [3]int {
+	var v [3]int
+	copy(v[:], p[:index+1])
+	v[index]++
+	return v
+}
+
+func pad(p []int) [3]int {
+	var v [3]int
+	copy(v[:], p)
+	return v
+}
+
+// parsePartial parses a possibly partial version like 18, 18.x or 18.17.0, ignoring the prerelease and build metadata.
+func parsePartial(s string) ([]int, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "="), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	var parts []int
+	for _, part := range strings.Split(s, ".") {
+		if part == "" || part == "x" || part == "X" || part == "*" {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || len(parts) == 3 {
+			return nil, fmt.Errorf("%q is not a version", s)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
+
+// parseVersion parses a full version like v18.17.0.
+func parseVersion(s string) ([3]int, error) {
+	p, err := parsePartial(strings.TrimSpace(s))
+	if err != nil {
+		return [3]int{}, err
+	}
+	if len(p) != 3 {
+		return [3]int{}, fmt.Errorf("%q is not a full version", s)
+	}
+	return pad(p), nil
+}
@@ -0,0 +1,242 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdoptions resolves the options of a scan once, from the flags, the environment and qodana.yaml,
+// into EffectiveOptions recording where every value comes from.
+package qdoptions
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdaudit"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/spf13/pflag"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Source is where the effective value of an option comes from, in the order of precedence, the highest last.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceYaml    Source = "qodana.yaml"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+	// SourceDerived is a value computed by the CLI from the other options, e.g. the analysis id of --reproducible.
+	SourceDerived Source = "derived"
+)
+
+// Formats of Write.
+const (
+	FormatText = "text"
+	FormatJson = "json"
+)
+
+// envDefaults are the environment variables the defaults of the flags are read from, by the flag name,
+// besides the variables of platformcmd.ApplyEnv.
+var envDefaults = map[string]string{
+	"ide":           qdenv.QodanaDistEnv,
+	"audit-log":     qdenv.QodanaAuditLogEnv,
+	"no-telemetry":  qdenv.QodanaTelemetryEnv,
+	"no-statistics": qdenv.QodanaTelemetryEnv,
+}
+
+// Value is the effective value of an option and its provenance.
+type Value struct {
+	// Name is the name of the flag of the option.
+	Name string `json:"name"`
+	// Value is the value as it is printed, with the secrets masked.
+	Value  string `json:"value"`
+	Source Source `json:"source"`
+	// Origin is the environment variable, the qodana.yaml key or the reason of the derived value.
+	Origin string `json:"origin,omitempty"`
+}
+
+// EffectiveOptions are the resolved options of a scan. They are never changed in place, With returns a changed copy,
+// so the precedence of the flags, the environment and qodana.yaml is decided once by Resolve.
+type EffectiveOptions struct {
+	options platformcmd.CliOptions
+	values  []Value
+}
+
+// Resolve returns the effective options of the flags parsed to options: a flag set explicitly overrides
+// its environment variable, which overrides the qodana.yaml key of the option, which overrides the default.
+func Resolve(flags *pflag.FlagSet, options platformcmd.CliOptions, qodanaYaml qdyaml.QodanaYaml) EffectiveOptions {
+	resolved := EffectiveOptions{options: clone(options)}
+	flags.VisitAll(
+		func(f *pflag.Flag) {
+			if f.Hidden {
+				return
+			}
+			value := Value{Name: f.Name, Value: qdaudit.Mask(f.Name, f.Value.String()), Source: SourceDefault}
+			env, hasEnv := envDefaults[f.Name]
+			key, yamlValue := yamlValueOf(f.Name, qodanaYaml)
+			switch {
+			case len(f.Annotations[platformcmd.EnvAnnotation]) > 0:
+				value.Source, value.Origin = SourceEnv, f.Annotations[platformcmd.EnvAnnotation][0]
+			case f.Changed:
+				value.Source = SourceFlag
+			case hasEnv && os.Getenv(env) != "":
+				value.Source, value.Origin = SourceEnv, env
+			case yamlValue != "":
+				value.Value, value.Source, value.Origin = qdaudit.Mask(f.Name, yamlValue), SourceYaml, key
+			}
+			resolved.values = append(resolved.values, value)
+		},
+	)
+	return resolved
+}
+
+// yamlValueOf returns the qodana.yaml key of the option of the flag and its value, empty if it is not set.
+func yamlValueOf(flag string, qodanaYaml qdyaml.QodanaYaml) (string, string) {
+	switch flag {
+	case "linter":
+		return "linter", qodanaYaml.Linter
+	case "ide":
+		return "ide", qodanaYaml.Ide
+	case "profile-name":
+		return "profile.name", qodanaYaml.Profile.Name
+	case "profile-path":
+		return "profile.path", qodanaYaml.Profile.Path
+	case "fail-threshold":
+		if qodanaYaml.FailThreshold == nil {
+			return "failThreshold", ""
+		}
+		return "failThreshold", strconv.Itoa(*qodanaYaml.FailThreshold)
+	case "script":
+		return "script.name", qodanaYaml.Script.Name
+	case "disable-sanity":
+		return "disableSanityInspections", qodanaYaml.DisableSanityInspections
+	case "run-promo":
+		return "runPromoInspections", qodanaYaml.RunPromoInspections
+	case "baseline-include-absent":
+		return "includeAbsent", qodanaYaml.IncludeAbsent
+	case "fixes-strategy":
+		return "fixesStrategy", qodanaYaml.FixesStrategy
+	case "vmoptions":
+		if len(qodanaYaml.VmOptions) == 0 {
+			return "vmoptions", ""
+		}
+		return "vmoptions", "[" + strings.Join(qodanaYaml.VmOptions, ",") + "]"
+	case "solution":
+		return "dotnet.solution", qodanaYaml.DotNet.Solution
+	case "project":
+		return "dotnet.project", qodanaYaml.DotNet.Project
+	case "configuration":
+		return "dotnet.configuration", qodanaYaml.DotNet.Configuration
+	case "platform":
+		return "dotnet.platform", qodanaYaml.DotNet.Platform
+	}
+	return "", ""
+}
+
+// Options returns a copy of the options, changing it doesn't change the effective options.
+func (o EffectiveOptions) Options() platformcmd.CliOptions {
+	return clone(o.options)
+}
+
+// With returns a copy of the effective options with the option of the flag name changed by set to value,
+// recorded as derived for the reason.
+func (o EffectiveOptions) With(
+	name string,
+	value string,
+	reason string,
+	set func(options *platformcmd.CliOptions),
+) EffectiveOptions {
+	changed := EffectiveOptions{options: clone(o.options), values: slices.Clone(o.values)}
+	set(&changed.options)
+	derived := Value{Name: name, Value: qdaudit.Mask(name, value), Source: SourceDerived, Origin: reason}
+	if i := slices.IndexFunc(changed.values, func(v Value) bool { return v.Name == name }); i >= 0 {
+		changed.values[i] = derived
+	} else {
+		changed.values = append(changed.values, derived)
+	}
+	return changed
+}
+
+// Value returns the effective value of the option of the flag name.
+func (o EffectiveOptions) Value(name string) (Value, bool) {
+	i := slices.IndexFunc(o.values, func(v Value) bool { return v.Name == name })
+	if i < 0 {
+		return Value{}, false
+	}
+	return o.values[i], true
+}
+
+// Values returns the effective values of the options in the order of the flags,
+// only the ones set or having a non-empty default unless all is true.
+func (o EffectiveOptions) Values(all bool) []Value {
+	values := make([]Value, 0, len(o.values))
+	for _, v := range o.values {
+		if all || v.Source != SourceDefault || !isEmpty(v.Value) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Write writes the values of Values(all) as a table or as JSON.
+func (o EffectiveOptions) Write(w io.Writer, format string, all bool) error {
+	values := o.Values(all)
+	switch format {
+	case FormatJson:
+		data, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case FormatText:
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(table, "OPTION\tVALUE\tSOURCE")
+		for _, v := range values {
+			source := string(v.Source)
+			if v.Origin != "" {
+				source += " (" + v.Origin + ")"
+			}
+			_, _ = fmt.Fprintf(table, "--%s\t%s\t%s\n", v.Name, v.Value, source)
+		}
+		return table.Flush()
+	}
+	return fmt.Errorf("unknown format %q, expected %s or %s", format, FormatText, FormatJson)
+}
+
+func isEmpty(value string) bool {
+	return value == "" || value == "[]" || value == "false" || value == "0" || value == "0s"
+}
+
+// clone returns a copy of the options not sharing the slices with them.
+func clone(o platformcmd.CliOptions) platformcmd.CliOptions {
+	o.Scopes = slices.Clone(o.Scopes)
+	o.SeverityMap = slices.Clone(o.SeverityMap)
+	o.FailOnMetric = slices.Clone(o.FailOnMetric)
+	o.Property = slices.Clone(o.Property)
+	o.ProblemsSeverities = slices.Clone(o.ProblemsSeverities)
+	o.Env_ = slices.Clone(o.Env_)
+	o.Volumes = slices.Clone(o.Volumes)
+	o.FixesRules = slices.Clone(o.FixesRules)
+	o.FixesInclude = slices.Clone(o.FixesInclude)
+	o.VmOptions = slices.Clone(o.VmOptions)
+	o.CacheRestoreKeys = slices.Clone(o.CacheRestoreKeys)
+	return o
+}
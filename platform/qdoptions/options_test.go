@@ -0,0 +1,147 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdoptions
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/spf13/pflag"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseFlags(t *testing.T, options *platformcmd.CliOptions, args ...string) *pflag.FlagSet {
+	flags := pflag.NewFlagSet("scan", pflag.ContinueOnError)
+	flags.SortFlags = false
+	flags.StringVarP(&options.Linter, "linter", "l", "", "")
+	flags.StringVar(&options.Ide, "ide", os.Getenv(qdenv.QodanaDistEnv), "")
+	flags.StringVar(&options.ProfileName, "profile-name", "", "")
+	flags.StringVar(&options.FailThreshold, "fail-threshold", "", "")
+	flags.StringVar(&options.Auth, "auth", "", "")
+	flags.StringArrayVarP(&options.Property, "property", "p", nil, "")
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return flags
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv(qdenv.QodanaDistEnv, "QDJVM")
+	options := platformcmd.CliOptions{}
+	flags := parseFlags(t, &options, "--profile-name", "qodana.recommended", "--auth", "secret")
+	threshold := 10
+	qodanaYaml := qdyaml.QodanaYaml{
+		Linter:        "jetbrains/qodana-jvm",
+		Ide:           "QDPY",
+		Profile:       qdyaml.Profile{Name: "qodana.starter"},
+		FailThreshold: &threshold,
+	}
+
+	effective := Resolve(flags, options, qodanaYaml)
+
+	expected := []Value{
+		{Name: "linter", Value: "jetbrains/qodana-jvm", Source: SourceYaml, Origin: "linter"},
+		{Name: "ide", Value: "QDJVM", Source: SourceEnv, Origin: qdenv.QodanaDistEnv},
+		{Name: "profile-name", Value: "qodana.recommended", Source: SourceFlag},
+		{Name: "fail-threshold", Value: "10", Source: SourceYaml, Origin: "failThreshold"},
+		{Name: "auth", Value: "***", Source: SourceFlag},
+	}
+	if actual := effective.Values(false); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Values() = %v, want %v", actual, expected)
+	}
+	if len(effective.Values(true)) != 6 {
+		t.Errorf("Values(true) = %v, want all the flags", effective.Values(true))
+	}
+	if effective.Options().Auth != "secret" {
+		t.Errorf("Options().Auth = %q, the masked value is only for printing", effective.Options().Auth)
+	}
+}
+
+func TestWithDoesNotChangeTheOptions(t *testing.T) {
+	options := platformcmd.CliOptions{}
+	flags := parseFlags(t, &options, "-p", "a=1")
+	effective := Resolve(flags, options, qdyaml.QodanaYaml{})
+
+	changed := effective.With(
+		"property", "[a=1,b=2]", "--profile-inspections", func(o *platformcmd.CliOptions) {
+			o.Property = append(o.Property, "b=2")
+		},
+	)
+	copied := changed.Options()
+	copied.Property[0] = "c=3"
+
+	if !reflect.DeepEqual(effective.Options().Property, []string{"a=1"}) {
+		t.Errorf("the original options are changed: %v", effective.Options().Property)
+	}
+	if !reflect.DeepEqual(changed.Options().Property, []string{"a=1", "b=2"}) {
+		t.Errorf("unexpected changed options: %v", changed.Options().Property)
+	}
+	original, _ := effective.Value("property")
+	derived, _ := changed.Value("property")
+	if original.Source != SourceFlag || derived.Source != SourceDerived || derived.Origin != "--profile-inspections" {
+		t.Errorf("unexpected provenance %v, %v", original, derived)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	options := platformcmd.CliOptions{}
+	flags := parseFlags(t, &options, "-l", "jetbrains/qodana-go")
+	effective := Resolve(flags, options, qdyaml.QodanaYaml{Profile: qdyaml.Profile{Name: "qodana.starter"}})
+
+	var text bytes.Buffer
+	if err := effective.Write(&text, FormatText, false); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(text.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "--linter") || !strings.HasSuffix(lines[2], "qodana.yaml (profile.name)") {
+		t.Errorf("unexpected table:\n%s", text.String())
+	}
+
+	var out bytes.Buffer
+	if err := effective.Write(&out, FormatJson, false); err != nil {
+		t.Fatal(err)
+	}
+	var values []Value
+	if err := json.Unmarshal(out.Bytes(), &values); err != nil || len(values) != 2 {
+		t.Errorf("unexpected JSON %s: %v", out.String(), err)
+	}
+
+	if err := effective.Write(&out, "yaml", false); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestResolveApplyEnv(t *testing.T) {
+	t.Setenv(platformcmd.FlagEnv("fail-threshold"), "5")
+	options := platformcmd.CliOptions{}
+	flags := parseFlags(t, &options)
+	if err := platformcmd.ApplyEnv(flags); err != nil {
+		t.Fatal(err)
+	}
+	threshold := 10
+	effective := Resolve(flags, options, qdyaml.QodanaYaml{FailThreshold: &threshold})
+
+	expected := Value{Name: "fail-threshold", Value: "5", Source: SourceEnv, Origin: "QODANA_FAIL_THRESHOLD"}
+	if actual, _ := effective.Value("fail-threshold"); actual != expected {
+		t.Errorf("Value() = %v, want %v", actual, expected)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdowners reads CODEOWNERS, or a custom owners file in the same format, mapping the paths
+// of the project to the teams or people owning them.
+package qdowners
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdscope"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Unowned is the owner of the paths not matched by any rule or matched by a rule without owners.
+const Unowned = "(unowned)"
+
+// FileNames are the locations of CODEOWNERS looked up in the project directory, in the order GitHub looks them up.
+var FileNames = []string{
+	filepath.Join(".github", "CODEOWNERS"),
+	"CODEOWNERS",
+	filepath.Join("docs", "CODEOWNERS"),
+	filepath.Join(".gitlab", "CODEOWNERS"),
+}
+
+// Owners maps the paths to their owners, the last matching rule wins.
+type Owners struct {
+	rules []rule
+}
+
+type rule struct {
+	glob   qdscope.Glob
+	owners []string
+}
+
+// Find returns the path of CODEOWNERS in the project directory, empty if there is none.
+func Find(projectDir string) string {
+	for _, name := range FileNames {
+		path := filepath.Join(projectDir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load reads the owners file.
+func Load(path string) (*Owners, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	owners := &Owners{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// comments and the GitLab section headers, e.g. [Documentation] @docs
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		owners.rules = append(owners.rules, rule{glob: qdscope.CompileGlob(fields[0]), owners: fields[1:]})
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return owners, nil
+}
+
+// Of returns the owners of the path relative to the project directory, Unowned if it has none.
+func (o *Owners) Of(path string) []string {
+	for i := len(o.rules) - 1; i >= 0; i-- {
+		if o.rules[i].glob.Matches(path) {
+			if len(o.rules[i].owners) == 0 {
+				break
+			}
+			return o.rules[i].owners
+		}
+	}
+	return []string{Unowned}
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdowners
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOwners(t *testing.T) {
+	projectDir := t.TempDir()
+	if Find(projectDir) != "" {
+		t.Error("expected no CODEOWNERS in an empty project")
+	}
+	codeowners := filepath.Join(projectDir, ".github", "CODEOWNERS")
+	if err := os.MkdirAll(filepath.Dir(codeowners), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := `# default owners
+*       @org/platform
+
+[Backend]
+/backend/        @org/backend @alice # the backend team
+*.sql            @org/dba
+/backend/vendor/
+`
+	if err := os.WriteFile(codeowners, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if Find(projectDir) != codeowners {
+		t.Fatalf("expected %s to be found", codeowners)
+	}
+	owners, err := Load(codeowners)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		path string
+		want []string
+	}{
+		{"README.md", []string{"@org/platform"}},
+		{"backend/main.go", []string{"@org/backend", "@alice"}},
+		{"backend/db/schema.sql", []string{"@org/dba"}},
+		{"backend/vendor/lib.go", []string{Unowned}},
+		{"frontend/backend/app.ts", []string{"@org/platform"}},
+	} {
+		if got := owners.Of(tc.path); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.path, tc.want, got)
+		}
+	}
+}
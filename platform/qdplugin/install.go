@@ -0,0 +1,149 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdplugin
+
+import (
+	"archive/zip"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdsign"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ContainerPluginsDir is the directory of the plugins loaded by the linters in the container.
+const ContainerPluginsDir = "/opt/idea/custom-plugins"
+
+// archiveVersion is the cache version of the plugins installed from an archive of qodana.yaml.
+const archiveVersion = "archive"
+
+// CacheDir returns the directory of the plugin installed into the cache of the linter.
+func CacheDir(cacheDir string, plugin qdyaml.Plugin) string {
+	version := plugin.Version
+	if plugin.Archive != "" {
+		version = archiveVersion
+	}
+	return filepath.Join(cacheDir, "plugin-archives", plugin.Id, version)
+}
+
+// IsCached checks if the plugin is installed into the cache.
+func IsCached(cacheDir string, plugin qdyaml.Plugin) bool {
+	installed, err := Installed(CacheDir(cacheDir, plugin))
+	return err == nil && len(installed) > 0
+}
+
+// Prepare installs the plugin into the cache from its archive, or from the repository if its version is pinned.
+//
+// It returns the directory of the installed plugin, empty for a plugin installed by the linter itself.
+func Prepare(m *Marketplace, cacheDir string, projectDir string, plugin qdyaml.Plugin) (string, error) {
+	dir := CacheDir(cacheDir, plugin)
+	switch {
+	case plugin.Archive != "":
+		archive := plugin.Archive
+		if !filepath.IsAbs(archive) {
+			archive = filepath.Join(projectDir, archive)
+		}
+		if err := qdsign.VerifyFile(archive); err != nil {
+			return "", err
+		}
+		if err := Install(archive, dir); err != nil {
+			return "", err
+		}
+	case plugin.Version == "":
+		return "", nil
+	case !IsCached(cacheDir, plugin):
+		archive := filepath.Join(cacheDir, "plugin-archives", plugin.Id, plugin.Id+"-"+plugin.Version+".zip")
+		if err := m.Download(Release{Id: plugin.Id, Version: plugin.Version}, archive); err != nil {
+			return "", err
+		}
+		defer func() { _ = os.Remove(archive) }()
+		if err := Install(archive, dir); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// Install installs the plugin archive into dir: a plugin .jar is copied, a plugin .zip is extracted.
+func Install(archive string, dir string) error {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("not a plugin archive %s: %w", archive, err)
+	}
+	defer func() { _ = reader.Close() }()
+	if err = os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, f := range reader.File {
+		if f.Name == "META-INF/plugin.xml" {
+			name := strings.TrimSuffix(filepath.Base(archive), filepath.Ext(archive)) + ".jar"
+			return utils.CopyFile(archive, filepath.Join(dir, name))
+		}
+	}
+	for _, f := range reader.File {
+		if err = extract(f, dir); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", archive, err)
+		}
+	}
+	return nil
+}
+
+func extract(f *zip.File, dir string) error {
+	path := filepath.Join(dir, filepath.FromSlash(f.Name))
+	if !strings.HasPrefix(path, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return fmt.Errorf("illegal path %s", f.Name)
+	}
+	if f.FileInfo().IsDir() {
+		return os.MkdirAll(path, 0o755)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	in, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode()|0o600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Installed returns the entries of the plugins directory of the plugin installed into dir.
+func Installed(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var installed []string
+	for _, e := range entries {
+		installed = append(installed, filepath.Join(dir, e.Name()))
+	}
+	return installed, nil
+}
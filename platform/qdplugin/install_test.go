@@ -0,0 +1,103 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdplugin
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testZip(t *testing.T, files ...string) []byte {
+	b := &bytes.Buffer{}
+	w := zip.NewWriter(b)
+	for _, name := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = f.Write([]byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func testPluginZip(t *testing.T) []byte {
+	return testZip(t, "detekt/lib/detekt.jar", "detekt/lib/detekt-core.jar")
+}
+
+func TestInstall(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "detekt.zip")
+	if err := os.WriteFile(archive, testPluginZip(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	jar := filepath.Join(dir, "plugin-1.0.jar")
+	if err := os.WriteFile(jar, testZip(t, "META-INF/plugin.xml", "a/A.class"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	evil := filepath.Join(dir, "evil.zip")
+	if err := os.WriteFile(evil, testZip(t, "../evil.txt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Install(archive, filepath.Join(dir, "zip")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "zip", "detekt", "lib", "detekt-core.jar")); err != nil {
+		t.Error(err)
+	}
+	if err := Install(jar, filepath.Join(dir, "jar")); err != nil {
+		t.Fatal(err)
+	}
+	if installed, err := Installed(filepath.Join(dir, "jar")); err != nil || len(installed) != 1 || filepath.Base(installed[0]) != "plugin-1.0.jar" {
+		t.Errorf("expected the jar to be copied, got %v: %v", installed, err)
+	}
+	if err := Install(evil, filepath.Join(dir, "evil")); err == nil {
+		t.Error("expected an error for a path outside of the plugin directory")
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	m := newTestMarketplace(t)
+	cacheDir := t.TempDir()
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "detekt.zip"), testPluginZip(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := Prepare(m, cacheDir, projectDir, qdyaml.Plugin{Id: "detekt", Version: "2.4.0"})
+	if err != nil || dir != CacheDir(cacheDir, qdyaml.Plugin{Id: "detekt", Version: "2.4.0"}) {
+		t.Fatalf("expected the plugin to be downloaded, got %s: %v", dir, err)
+	}
+	if !IsCached(cacheDir, qdyaml.Plugin{Id: "detekt", Version: "2.4.0"}) {
+		t.Error("expected the plugin to be cached")
+	}
+	if dir, err = Prepare(m, cacheDir, projectDir, qdyaml.Plugin{Id: "detekt", Archive: "detekt.zip"}); err != nil || filepath.Base(dir) != archiveVersion {
+		t.Errorf("expected the plugin to be installed from the archive, got %s: %v", dir, err)
+	}
+	if dir, err = Prepare(m, cacheDir, projectDir, qdyaml.Plugin{Id: "detekt"}); err != nil || dir != "" {
+		t.Errorf("expected the plugin without version to be left to the linter, got %s: %v", dir, err)
+	}
+}
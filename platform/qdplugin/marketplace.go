@@ -0,0 +1,167 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdplugin resolves the plugins of qodana.yaml against JetBrains Marketplace and keeps
+// their archives in the Qodana cache, so they are installed into the linter without downloading them again.
+package qdplugin
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdsign"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultRepository is the JetBrains Marketplace.
+const DefaultRepository = "https://plugins.jetbrains.com"
+
+// Release is a version of a plugin.
+type Release struct {
+	Id      string
+	Name    string
+	Version string
+}
+
+// Marketplace is a plugin repository with the JetBrains Marketplace API.
+type Marketplace struct {
+	Url     string
+	Headers http.Header
+	Client  *http.Client
+}
+
+// NewMarketplace returns the client of the plugin repository: repository if set, then $QODANA_PLUGIN_REPOSITORY,
+// then JetBrains Marketplace. The requests are authorized with $QODANA_PLUGIN_REPOSITORY_TOKEN as a bearer token
+// and $QODANA_PLUGIN_REPOSITORY_HEADERS as comma-separated key=value headers.
+func NewMarketplace(repository string) *Marketplace {
+	m := &Marketplace{
+		Url:     Repository(repository),
+		Headers: http.Header{},
+		Client:  &http.Client{Timeout: 5 * time.Minute, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}},
+	}
+	if token := os.Getenv(qdenv.QodanaPluginRepositoryTokenEnv); token != "" {
+		m.Headers.Set("Authorization", "Bearer "+token)
+	}
+	for _, header := range strings.Split(os.Getenv(qdenv.QodanaPluginRepositoryHeadersEnv), ",") {
+		if key, value, ok := strings.Cut(header, "="); ok {
+			m.Headers.Set(strings.TrimSpace(key), strings.TrimSpace(value))
+		}
+	}
+	return m
+}
+
+// Repository returns the plugin repository: repository if set, then $QODANA_PLUGIN_REPOSITORY, then JetBrains Marketplace.
+func Repository(repository string) string {
+	if repository == "" {
+		repository = os.Getenv(qdenv.QodanaPluginRepositoryEnv)
+	}
+	if repository == "" {
+		return DefaultRepository
+	}
+	return strings.TrimSuffix(repository, "/")
+}
+
+type pluginRepository struct {
+	Categories []struct {
+		Plugins []struct {
+			Id      string `xml:"id"`
+			Name    string `xml:"name"`
+			Version string `xml:"version"`
+		} `xml:"idea-plugin"`
+	} `xml:"category"`
+}
+
+// Resolve returns the latest release of the plugin, or the release with the version if it is set.
+func (m *Marketplace) Resolve(id string, version string) (Release, error) {
+	response, err := m.get("/plugins/list", url.Values{"pluginId": {id}})
+	if err != nil {
+		return Release{}, err
+	}
+	defer func() { _ = response.Body.Close() }()
+	repository := &pluginRepository{}
+	if err = xml.NewDecoder(response.Body).Decode(repository); err != nil && err != io.EOF {
+		return Release{}, fmt.Errorf("unexpected response of %s: %w", m.Url, err)
+	}
+	for _, category := range repository.Categories {
+		for _, p := range category.Plugins {
+			if p.Id != id {
+				continue
+			}
+			release := Release{Id: p.Id, Name: p.Name, Version: p.Version}
+			if version != "" {
+				release.Version = version
+			}
+			return release, nil
+		}
+	}
+	return Release{}, fmt.Errorf("plugin %s not found in %s", id, m.Url)
+}
+
+// Download saves the archive of the release to path.
+func (m *Marketplace) Download(release Release, path string) error {
+	response, err := m.get("/plugin/download", url.Values{"pluginId": {release.Id}, "version": {release.Version}})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = response.Body.Close() }()
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".download"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, response.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to download %s %s: %w", release.Id, release.Version, err)
+	}
+	// the signature is next to the archive the repository redirects to
+	if err = qdsign.VerifyDownload(tmp, response.Request.URL.String()); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (m *Marketplace) get(path string, query url.Values) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(m.Url, "/")+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range m.Headers {
+		request.Header[key] = values
+	}
+	response, err := m.Client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach %s: %w", m.Url, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		_ = response.Body.Close()
+		return nil, fmt.Errorf("%s %s: %s", request.Method, request.URL.Redacted(), response.Status)
+	}
+	return response, nil
+}
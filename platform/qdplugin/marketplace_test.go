@@ -0,0 +1,125 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdplugin
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const pluginList = `<plugin-repository>
+  <category name="Code tools">
+    <idea-plugin>
+      <name>Detekt</name>
+      <id>detekt</id>
+      <version>2.4.0</version>
+    </idea-plugin>
+  </category>
+</plugin-repository>`
+
+func newTestMarketplace(t *testing.T) *Marketplace {
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/plugins/list" && r.URL.Query().Get("pluginId") == "detekt":
+					_, _ = w.Write([]byte(pluginList))
+				case r.URL.Path == "/plugins/list":
+					_, _ = w.Write([]byte("<plugin-repository/>"))
+				case r.URL.Path == "/plugin/download" && r.URL.Query().Get("version") == "2.4.0":
+					_, _ = w.Write(testPluginZip(t))
+				default:
+					http.NotFound(w, r)
+				}
+			},
+		),
+	)
+	t.Cleanup(server.Close)
+	return &Marketplace{Url: server.URL, Client: server.Client()}
+}
+
+func TestResolve(t *testing.T) {
+	m := newTestMarketplace(t)
+
+	release, err := m.Resolve("detekt", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Name != "Detekt" || release.Version != "2.4.0" {
+		t.Errorf("unexpected release %+v", release)
+	}
+	if release, err = m.Resolve("detekt", "2.3.0"); err != nil || release.Version != "2.3.0" {
+		t.Errorf("expected the pinned version, got %+v: %v", release, err)
+	}
+	if _, err = m.Resolve("missing", ""); err == nil {
+		t.Error("expected an error for a missing plugin")
+	}
+}
+
+func TestDownload(t *testing.T) {
+	m := newTestMarketplace(t)
+	path := filepath.Join(t.TempDir(), "plugins", "detekt.zip")
+
+	if err := m.Download(Release{Id: "detekt", Version: "2.4.0"}, path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+	if err := m.Download(Release{Id: "detekt", Version: "0.1"}, path+"2"); err == nil {
+		t.Error("expected an error for a missing version")
+	}
+	if _, err := os.Stat(path + "2.download"); err == nil {
+		t.Error("expected no partial download")
+	}
+}
+
+func TestPrivateRepository(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Mirror") != "ci" {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+				_, _ = w.Write([]byte(pluginList))
+			},
+		),
+	)
+	defer server.Close()
+	t.Setenv(qdenv.QodanaPluginRepositoryEnv, server.URL+"/")
+	t.Setenv(qdenv.QodanaPluginRepositoryTokenEnv, "secret")
+
+	m := NewMarketplace("")
+	if m.Url != server.URL {
+		t.Errorf("expected the repository of the environment, got %s", m.Url)
+	}
+	if _, err := m.Resolve("detekt", ""); err == nil {
+		t.Error("expected the request without X-Mirror to be rejected")
+	}
+	t.Setenv(qdenv.QodanaPluginRepositoryHeadersEnv, "X-Mirror=ci, X-Other = 1")
+	if _, err := NewMarketplace("").Resolve("detekt", ""); err != nil {
+		t.Error(err)
+	}
+	if m = NewMarketplace("https://plugins.example.com"); m.Url != "https://plugins.example.com" {
+		t.Errorf("expected the repository of the argument, got %s", m.Url)
+	}
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdpolicy enforces the organization policy on the settings of the scans, e.g. to require a baseline
+// or to allow only the pinned versions of the linters.
+package qdpolicy
+
+import (
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/userconfig"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// PolicyEnv is the environment variable with the path to the policy file, overriding the one in the user config dir.
+const PolicyEnv = "QODANA_POLICY"
+
+// Policy is the organization policy, stored in <userConfigDir>/JetBrains/Qodana/policy.yaml or the file of PolicyEnv.
+type Policy struct {
+	// Name is the name of the policy shown with the violations, e.g. the organization.
+	Name string `yaml:"name,omitempty"`
+	// ForbidDisableSanity forbids disabling the sanity inspections with --disable-sanity or qodana.yaml.
+	ForbidDisableSanity bool `yaml:"forbidDisableSanity,omitempty"`
+	// RequireBaseline requires a baseline set with --baseline or --baselines-dir.
+	RequireBaseline bool `yaml:"requireBaseline,omitempty"`
+	// RequireUpload requires a Qodana Cloud token, so the results are uploaded to Qodana Cloud.
+	RequireUpload bool `yaml:"requireUpload,omitempty"`
+	// PinLinterVersions requires the linter images to be set with a version tag other than latest or a digest.
+	PinLinterVersions bool `yaml:"pinLinterVersions,omitempty"`
+	// VerifySignatures requires the signatures of the downloaded tools and the plugin archives to be verified,
+	// as with --verify-signatures.
+	VerifySignatures bool `yaml:"verifySignatures,omitempty"`
+	// Linters are the patterns of the allowed linter images and IDE codes, e.g. jetbrains/qodana-*:2024.3,
+	// any linter is allowed if empty.
+	Linters []string `yaml:"linters,omitempty"`
+
+	path string
+}
+
+// Settings are the settings of a scan checked against the policy.
+type Settings struct {
+	Linter        string
+	Ide           string
+	DisableSanity bool
+	Baseline      string
+	BaselinesDir  string
+	CloudToken    string
+}
+
+// Path returns the path to the policy file.
+func Path() string {
+	if p := os.Getenv(PolicyEnv); p != "" {
+		return p
+	}
+	return filepath.Join(userconfig.Dir(), "policy.yaml")
+}
+
+// Load reads the policy, nil if there is no policy file.
+// Unlike the user config, an invalid policy is an error, so that a broken policy file doesn't disable the policy.
+func Load() (*Policy, error) {
+	p := Path()
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) && os.Getenv(PolicyEnv) == "" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the policy %s: %w", p, err)
+	}
+	policy := &Policy{path: p}
+	if err = yaml.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse the policy %s: %w", p, err)
+	}
+	for _, pattern := range policy.Linters {
+		if _, err = path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid linter pattern %q in the policy %s: %w", pattern, p, err)
+		}
+	}
+	return policy, nil
+}
+
+// String returns the name of the policy and the file it's read from.
+func (p *Policy) String() string {
+	if p.Name == "" {
+		return p.path
+	}
+	return fmt.Sprintf("%s (%s)", p.Name, p.path)
+}
+
+// Check returns the reasons the settings violate the policy, none if they comply with it.
+func (p *Policy) Check(s Settings) []string {
+	var violations []string
+	if p.ForbidDisableSanity && s.DisableSanity {
+		violations = append(
+			violations,
+			"the sanity inspections must not be disabled, remove --disable-sanity and disableSanityInspections from qodana.yaml",
+		)
+	}
+	if p.RequireBaseline && s.Baseline == "" && s.BaselinesDir == "" {
+		violations = append(violations, "a baseline is required, set it with --baseline or --baselines-dir")
+	}
+	if p.RequireUpload && s.CloudToken == "" {
+		violations = append(
+			violations,
+			"the results must be uploaded to Qodana Cloud, set the project token with QODANA_TOKEN or qodana auth login",
+		)
+	}
+	if s.Linter != "" && p.PinLinterVersions && !isPinned(s.Linter) {
+		violations = append(
+			violations,
+			fmt.Sprintf("the linter %s must be pinned to a version, e.g. %s:2024.3", s.Linter, imageName(s.Linter)),
+		)
+	}
+	analyzer := s.Linter
+	if analyzer == "" {
+		analyzer = s.Ide
+	}
+	if analyzer != "" && len(p.Linters) > 0 && !p.allows(analyzer) {
+		violations = append(
+			violations,
+			fmt.Sprintf("the linter %s is not allowed, the allowed ones are %s", analyzer, strings.Join(p.Linters, ", ")),
+		)
+	}
+	return violations
+}
+
+func (p *Policy) allows(analyzer string) bool {
+	for _, pattern := range p.Linters {
+		if matched, _ := path.Match(pattern, analyzer); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isPinned returns true if the image has a digest or a tag other than latest.
+func isPinned(image string) bool {
+	if strings.Contains(image, "@") {
+		return true
+	}
+	tag := ""
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		tag = image[i+1:]
+	}
+	return tag != "" && tag != "latest"
+}
+
+// imageName returns the image without its tag.
+func imageName(image string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i]
+	}
+	return image
+}
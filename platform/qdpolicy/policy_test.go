@@ -0,0 +1,122 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdpolicy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	policy := &Policy{
+		ForbidDisableSanity: true,
+		RequireBaseline:     true,
+		RequireUpload:       true,
+		PinLinterVersions:   true,
+		Linters:             []string{"jetbrains/qodana-*:2024.3", "QDNET"},
+	}
+	for _, tc := range []struct {
+		name       string
+		settings   Settings
+		violations int
+	}{
+		{
+			name: "compliant",
+			settings: Settings{
+				Linter:     "jetbrains/qodana-jvm:2024.3",
+				Baseline:   "qodana.sarif.json",
+				CloudToken: "token",
+			},
+		},
+		{
+			name:       "compliant ide",
+			settings:   Settings{Ide: "QDNET", BaselinesDir: "baselines", CloudToken: "token"},
+			violations: 0,
+		},
+		{
+			name:       "unpinned",
+			settings:   Settings{Linter: "jetbrains/qodana-jvm", Baseline: "qodana.sarif.json", CloudToken: "token"},
+			violations: 2,
+		},
+		{
+			name:       "latest",
+			settings:   Settings{Linter: "jetbrains/qodana-jvm:latest", Baseline: "qodana.sarif.json", CloudToken: "token"},
+			violations: 2,
+		},
+		{
+			name:       "everything",
+			settings:   Settings{Linter: "registry:5000/qodana-jvm:2024.2", DisableSanity: true},
+			violations: 4,
+		},
+	} {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				if violations := policy.Check(tc.settings); len(violations) != tc.violations {
+					t.Errorf("expected %d violations, got %v", tc.violations, violations)
+				}
+			},
+		)
+	}
+}
+
+func TestIsPinned(t *testing.T) {
+	for image, expected := range map[string]bool{
+		"jetbrains/qodana-jvm":                     false,
+		"jetbrains/qodana-jvm:latest":              false,
+		"jetbrains/qodana-jvm:2024.3":              true,
+		"registry:5000/qodana-jvm":                 false,
+		"registry:5000/qodana-jvm:2024.3":          true,
+		"jetbrains/qodana-jvm@sha256:0123456789ab": true,
+	} {
+		if actual := isPinned(image); actual != expected {
+			t.Errorf("isPinned(%s) = %v, want %v", image, actual, expected)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	policyPath := filepath.Join(dir, "policy.yaml")
+	t.Setenv(PolicyEnv, policyPath)
+	if _, err := Load(); err == nil {
+		t.Error("expected an error for the missing policy set with " + PolicyEnv)
+	}
+
+	content := "name: ACME\nrequireBaseline: true\nlinters:\n  - jetbrains/qodana-jvm:2024.3\n"
+	if err := os.WriteFile(policyPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !policy.RequireBaseline || !reflect.DeepEqual(policy.Linters, []string{"jetbrains/qodana-jvm:2024.3"}) {
+		t.Errorf("unexpected policy %+v", policy)
+	}
+	if policy.String() != "ACME ("+policyPath+")" {
+		t.Errorf("unexpected policy name %s", policy)
+	}
+
+	if err = os.WriteFile(policyPath, []byte("linters: [\"[\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Load(); err == nil {
+		t.Error("expected an error for an invalid linter pattern")
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdprofile
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InlineProfileName is the name of the generated profile without a base profile.
+const InlineProfileName = "qodana.inline"
+
+// severities are the IntelliJ highlighting levels accepted in the inline profile.
+var severities = []string{"ERROR", "WARNING", "WEAK WARNING", "INFORMATION", "INFO", "TYPO", "SERVER PROBLEM"}
+
+// Compose builds the profile of the inline section of qodana.yaml on top of the profile selected
+// as in Resolve; without a selected profile, the project default profile is used if the project has one.
+func Compose(projectDir string, name string, path string, q *qdyaml.QodanaYaml) (*Profile, error) {
+	var profile *Profile
+	var err error
+	if name == "" && path == "" && q.Profile.Name == "" && q.Profile.Path == "" {
+		if profile, err = ProjectDefault(projectDir); err != nil {
+			profile = &Profile{Name: InlineProfileName}
+		}
+	} else if profile, err = Resolve(projectDir, name, path, q); err != nil {
+		return nil, fmt.Errorf("failed to resolve the base of the inline profile: %w", err)
+	}
+	if err = profile.Override(q.Profile.Inline); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// Override enables, disables and sets the severity of the inspections of the inline profile.
+func (p *Profile) Override(inline *qdyaml.InlineProfile) error {
+	if inline.IsEmpty() {
+		return nil
+	}
+	for _, id := range inline.Enable {
+		p.override(id, func(i *Inspection) { i.Enabled = true })
+	}
+	for _, id := range inline.Disable {
+		p.override(id, func(i *Inspection) { i.Enabled = false })
+	}
+	ids := make([]string, 0, len(inline.Severity))
+	for id := range inline.Severity {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		level := strings.ToUpper(strings.TrimSpace(inline.Severity[id]))
+		if !isSeverity(level) {
+			return fmt.Errorf(
+				"invalid severity %q of %s in the inline profile, expected one of %s",
+				inline.Severity[id],
+				id,
+				strings.Join(severities, ", "),
+			)
+		}
+		p.override(id, func(i *Inspection) { i.Enabled, i.Severity = true, level })
+	}
+	return nil
+}
+
+func (p *Profile) override(id string, apply func(i *Inspection)) {
+	inspection, ok := p.Inspection(id)
+	if !ok {
+		inspection = Inspection{Id: id, Severity: DefaultSeverity}
+	}
+	apply(&inspection)
+	p.set(inspection)
+}
+
+func isSeverity(level string) bool {
+	for _, s := range severities {
+		if level == s {
+			return true
+		}
+	}
+	return false
+}
+
+type xmlComponent struct {
+	XMLName xml.Name   `xml:"component"`
+	Name    string     `xml:"name,attr"`
+	Profile xmlProfile `xml:"profile"`
+}
+
+// Write saves the profile as an IntelliJ inspection profile, the scopes on qodana.yaml paths are not saved.
+func (p *Profile) Write(path string) error {
+	component := xmlComponent{Name: "InspectionProjectProfileManager"}
+	component.Profile.Version = "1.0"
+	component.Profile.Options = []xmlOption{{Name: "myName", Value: p.Name}}
+	for _, i := range p.Inspections {
+		tool := xmlTool{Class: i.Id, Level: i.Severity, EnabledByDefault: strconv.FormatBool(i.Enabled)}
+		enabled := i.Enabled
+		for _, s := range i.Scopes {
+			if len(s.Paths) > 0 {
+				continue
+			}
+			tool.Scopes = append(tool.Scopes, xmlScope{Name: s.Name, Enabled: strconv.FormatBool(s.Enabled), Level: s.Severity})
+			enabled = enabled || s.Enabled
+		}
+		tool.Enabled = strconv.FormatBool(enabled)
+		component.Profile.Tools = append(component.Profile.Tools, tool)
+	}
+	data, err := xml.MarshalIndent(component, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdprofile
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompose(t *testing.T) {
+	dir := writeProfiles(t, map[string]string{"Project_Default.xml": projectDefaultXml})
+	q := &qdyaml.QodanaYaml{
+		Profile: qdyaml.Profile{
+			Inline: &qdyaml.InlineProfile{
+				Enable:   []string{"UnusedDeclaration"},
+				Disable:  []string{"HardcodedPasswords"},
+				Severity: map[string]string{"SpellCheckingInspection": "typo"},
+			},
+		},
+	}
+	profile, err := Compose(dir, "", "", q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "qodana.profile.xml")
+	if err = profile.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	written, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written.Name != "Project Default" || len(Diff(profile, written)) != 0 {
+		t.Fatalf("expected the written profile to be the composed one, got %+v", written)
+	}
+	for id, expected := range map[string]string{
+		"ConstantConditions":      "ERROR",
+		"UnusedDeclaration":       "WARNING",
+		"HardcodedPasswords":      "disabled",
+		"SpellCheckingInspection": "TYPO",
+	} {
+		if i, ok := written.Inspection(id); !ok || i.String() != expected {
+			t.Errorf("expected %s to be %s, got %+v", id, expected, i)
+		}
+	}
+	if i, _ := written.Inspection("ConstantConditions"); len(i.Scopes) != 1 || i.Scopes[0].String() != "Tests: disabled" {
+		t.Errorf("expected the scope of the base profile to be kept, got %+v", i.Scopes)
+	}
+
+	profile, err = Compose(t.TempDir(), "", "", q)
+	if err != nil || profile.Name != InlineProfileName || len(profile.Inspections) != 3 {
+		t.Errorf("expected a profile without a base, got %+v: %v", profile, err)
+	}
+
+	q.Profile.Inline.Severity["SpellCheckingInspection"] = "critical"
+	if _, err = Compose(dir, "", "", q); err == nil {
+		t.Error("expected an error for an invalid severity")
+	}
+}
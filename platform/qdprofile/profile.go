@@ -0,0 +1,358 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdprofile reads the IntelliJ inspection profiles (.idea/inspectionProfiles/*.xml) to show
+// which inspections are enabled and with which severity.
+package qdprofile
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ProfilesDir is the directory of the project inspection profiles.
+const ProfilesDir = ".idea/inspectionProfiles"
+
+// DefaultSeverity is the severity of an inspection without a level in the profile.
+const DefaultSeverity = "WARNING"
+
+// bundledProfiles are the profiles shipped with the linters, they are not available to the CLI.
+var bundledProfiles = []string{"qodana.starter", "qodana.recommended", "qodana.sanity", "empty"}
+
+// Profile is an inspection profile with its inspections sorted by id.
+type Profile struct {
+	Name string
+	// Path is the file the profile is read from, empty for a profile built by the CLI.
+	Path        string
+	Inspections []Inspection
+}
+
+// Inspection is the setting of an inspection in a profile.
+type Inspection struct {
+	Id       string
+	Enabled  bool
+	Severity string
+	// Scopes override the setting for some files.
+	Scopes []Scope
+}
+
+// Scope overrides the setting of an inspection for the files of a named scope or the paths of qodana.yaml.
+type Scope struct {
+	Name     string
+	Paths    []string
+	Enabled  bool
+	Severity string
+}
+
+// String describes the setting of the inspection, e.g. "WARNING" or "disabled".
+func (i Inspection) String() string {
+	if !i.Enabled {
+		return "disabled"
+	}
+	return i.Severity
+}
+
+// String describes the scope, e.g. "Tests: disabled".
+func (s Scope) String() string {
+	name := s.Name
+	if len(s.Paths) > 0 {
+		name += " " + strings.Join(s.Paths, ", ")
+	}
+	if !s.Enabled {
+		return name + ": disabled"
+	}
+	return name + ": " + s.Severity
+}
+
+type xmlDocument struct {
+	XMLName xml.Name
+	// Profile is set when the profile is wrapped into <component name="InspectionProjectProfileManager">.
+	Profile *xmlProfile `xml:"profile"`
+	// Settings is the content of profiles_settings.xml.
+	Settings *xmlProfile `xml:"settings"`
+	xmlProfile
+}
+
+type xmlProfile struct {
+	Version string      `xml:"version,attr,omitempty"`
+	Options []xmlOption `xml:"option"`
+	Tools   []xmlTool   `xml:"inspection_tool"`
+}
+
+type xmlOption struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type xmlTool struct {
+	Class            string     `xml:"class,attr"`
+	Enabled          string     `xml:"enabled,attr"`
+	Level            string     `xml:"level,attr"`
+	EnabledByDefault string     `xml:"enabled_by_default,attr,omitempty"`
+	Scopes           []xmlScope `xml:"scope"`
+}
+
+type xmlScope struct {
+	Name    string `xml:"name,attr"`
+	Enabled string `xml:"enabled,attr"`
+	Level   string `xml:"level,attr"`
+}
+
+// Load reads the profile from an IntelliJ inspection profile file.
+func Load(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc := &xmlDocument{}
+	if err = xml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("not a valid inspection profile %s: %w", path, err)
+	}
+	p := &doc.xmlProfile
+	if doc.XMLName.Local != "profile" {
+		if doc.Profile == nil {
+			return nil, fmt.Errorf("not a valid inspection profile %s: no <profile> element", path)
+		}
+		p = doc.Profile
+	}
+
+	profile := &Profile{Path: path}
+	for _, o := range p.Options {
+		if o.Name == "myName" {
+			profile.Name = o.Value
+		}
+	}
+	if profile.Name == "" {
+		profile.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	for _, t := range p.Tools {
+		inspection := Inspection{Id: t.Class, Enabled: t.Enabled == "true", Severity: severity(t.Level)}
+		if t.EnabledByDefault != "" && len(t.Scopes) > 0 {
+			inspection.Enabled = t.EnabledByDefault == "true"
+		}
+		for _, s := range t.Scopes {
+			inspection.Scopes = append(
+				inspection.Scopes,
+				Scope{Name: s.Name, Enabled: s.Enabled == "true", Severity: severity(s.Level)},
+			)
+		}
+		profile.set(inspection)
+	}
+	return profile, nil
+}
+
+func severity(level string) string {
+	if level == "" {
+		return DefaultSeverity
+	}
+	return level
+}
+
+// Resolve finds the profile used by the analysis of the project: the profile of the command line,
+// then the one of qodana.yaml, then the project default profile.
+func Resolve(projectDir string, name string, path string, q *qdyaml.QodanaYaml) (*Profile, error) {
+	if name == "" && path == "" {
+		name, path = q.Profile.Name, q.Profile.Path
+	}
+	switch {
+	case path != "":
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(projectDir, path)
+		}
+		if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+			return nil, fmt.Errorf("%s is a YAML profile, it is resolved by the linter", path)
+		}
+		return Load(path)
+	case name != "":
+		return FindByName(projectDir, name)
+	}
+	return ProjectDefault(projectDir)
+}
+
+// FindByName finds the project profile with the name.
+func FindByName(projectDir string, name string) (*Profile, error) {
+	for _, bundled := range bundledProfiles {
+		if name == bundled {
+			return nil, fmt.Errorf("%s is bundled with the linter, its inspections are listed in the report of qodana scan", name)
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(projectDir, ProfilesDir, "*.xml"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if filepath.Base(file) == "profiles_settings.xml" {
+			continue
+		}
+		profile, err := Load(file)
+		if err != nil {
+			return nil, err
+		}
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+	return nil, fmt.Errorf("profile %s not found in %s", name, filepath.Join(projectDir, ProfilesDir))
+}
+
+// Names returns the names of the bundled profiles and of the project profiles, the unreadable ones are skipped.
+func Names(projectDir string) []string {
+	names := append([]string{}, bundledProfiles...)
+	files, _ := filepath.Glob(filepath.Join(projectDir, ProfilesDir, "*.xml"))
+	for _, file := range files {
+		if filepath.Base(file) == "profiles_settings.xml" {
+			continue
+		}
+		if profile, err := Load(file); err == nil && profile.Name != "" {
+			names = append(names, profile.Name)
+		}
+	}
+	return names
+}
+
+// ProjectDefault returns the profile selected in the project settings, Project Default if none is selected.
+func ProjectDefault(projectDir string) (*Profile, error) {
+	settings, err := os.ReadFile(filepath.Join(projectDir, ProfilesDir, "profiles_settings.xml"))
+	if err == nil {
+		doc := &xmlDocument{}
+		if err = xml.Unmarshal(settings, doc); err == nil && doc.Settings != nil {
+			for _, o := range doc.Settings.Options {
+				if o.Name == "PROJECT_PROFILE" && o.Value != "" {
+					return FindByName(projectDir, o.Value)
+				}
+			}
+		}
+	}
+	profile, err := Load(filepath.Join(projectDir, ProfilesDir, "Project_Default.xml"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("the project has no inspection profile in %s, set one with --profile-name or --profile-path", filepath.Join(projectDir, ProfilesDir))
+	}
+	return profile, err
+}
+
+// Apply applies the include and exclude sections of qodana.yaml to the profile.
+func (p *Profile) Apply(q *qdyaml.QodanaYaml) {
+	for _, include := range q.Includes {
+		inspection, ok := p.Inspection(include.Name)
+		if !ok {
+			inspection = Inspection{Id: include.Name, Severity: DefaultSeverity}
+		}
+		if len(include.Paths) == 0 {
+			inspection.Enabled = true
+		} else if !inspection.Enabled {
+			inspection.Scopes = append(
+				inspection.Scopes,
+				Scope{Name: "qodana.yaml include", Paths: include.Paths, Enabled: true, Severity: inspection.Severity},
+			)
+		}
+		p.set(inspection)
+	}
+	for _, exclude := range q.Excludes {
+		if exclude.Name == "All" {
+			continue
+		}
+		inspection, ok := p.Inspection(exclude.Name)
+		if !ok {
+			inspection = Inspection{Id: exclude.Name, Severity: DefaultSeverity}
+		}
+		if len(exclude.Paths) == 0 {
+			inspection.Enabled = false
+			inspection.Scopes = nil
+		} else {
+			inspection.Scopes = append(inspection.Scopes, Scope{Name: "qodana.yaml exclude", Paths: exclude.Paths})
+		}
+		p.set(inspection)
+	}
+}
+
+// Inspection returns the setting of the inspection in the profile.
+func (p *Profile) Inspection(id string) (Inspection, bool) {
+	i := sort.Search(len(p.Inspections), func(i int) bool { return p.Inspections[i].Id >= id })
+	if i < len(p.Inspections) && p.Inspections[i].Id == id {
+		return p.Inspections[i], true
+	}
+	return Inspection{}, false
+}
+
+func (p *Profile) set(inspection Inspection) {
+	i := sort.Search(len(p.Inspections), func(i int) bool { return p.Inspections[i].Id >= inspection.Id })
+	if i < len(p.Inspections) && p.Inspections[i].Id == inspection.Id {
+		p.Inspections[i] = inspection
+		return
+	}
+	p.Inspections = append(p.Inspections, Inspection{})
+	copy(p.Inspections[i+1:], p.Inspections[i:])
+	p.Inspections[i] = inspection
+}
+
+// Enabled returns the number of enabled inspections.
+func (p *Profile) Enabled() int {
+	count := 0
+	for _, i := range p.Inspections {
+		if i.Enabled {
+			count++
+		}
+	}
+	return count
+}
+
+// Change is an inspection set differently in two profiles, Before or After is nil if the inspection is absent.
+type Change struct {
+	Id     string
+	Before *Inspection
+	After  *Inspection
+}
+
+// Diff returns the inspections set differently in the profiles, sorted by id.
+func Diff(before *Profile, after *Profile) []Change {
+	var changes []Change
+	i, j := 0, 0
+	for i < len(before.Inspections) || j < len(after.Inspections) {
+		switch {
+		case j == len(after.Inspections) || (i < len(before.Inspections) && before.Inspections[i].Id < after.Inspections[j].Id):
+			changes = append(changes, Change{Id: before.Inspections[i].Id, Before: &before.Inspections[i]})
+			i++
+		case i == len(before.Inspections) || after.Inspections[j].Id < before.Inspections[i].Id:
+			changes = append(changes, Change{Id: after.Inspections[j].Id, After: &after.Inspections[j]})
+			j++
+		default:
+			if !equal(before.Inspections[i], after.Inspections[j]) {
+				changes = append(changes, Change{Id: before.Inspections[i].Id, Before: &before.Inspections[i], After: &after.Inspections[j]})
+			}
+			i++
+			j++
+		}
+	}
+	return changes
+}
+
+func equal(a Inspection, b Inspection) bool {
+	if a.Enabled != b.Enabled || (a.Enabled && a.Severity != b.Severity) || len(a.Scopes) != len(b.Scopes) {
+		return false
+	}
+	for k := range a.Scopes {
+		if a.Scopes[k].String() != b.Scopes[k].String() {
+			return false
+		}
+	}
+	return true
+}
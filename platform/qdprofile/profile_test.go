@@ -0,0 +1,151 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdprofile
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const projectDefaultXml = `<component name="InspectionProjectProfileManager">
+  <profile version="1.0">
+    <option name="myName" value="Project Default" />
+    <inspection_tool class="UnusedDeclaration" enabled="false" level="WARNING" enabled_by_default="false" />
+    <inspection_tool class="ConstantConditions" enabled="true" level="ERROR" enabled_by_default="true">
+      <scope name="Tests" level="WARNING" enabled="false" />
+    </inspection_tool>
+  </profile>
+</component>`
+
+const strictXml = `<profile version="1.0">
+  <option name="myName" value="Strict" />
+  <inspection_tool class="ConstantConditions" enabled="true" level="ERROR" enabled_by_default="true" />
+  <inspection_tool class="UnusedDeclaration" enabled="true" level="WARNING" enabled_by_default="true" />
+  <inspection_tool class="SpellCheckingInspection" enabled="true" level="TYPO" enabled_by_default="true" />
+</profile>`
+
+func writeProfiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, ProfilesDir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, ProfilesDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestResolve(t *testing.T) {
+	dir := writeProfiles(t, map[string]string{"Project_Default.xml": projectDefaultXml, "strict.xml": strictXml})
+
+	profile, err := Resolve(dir, "", "", &qdyaml.QodanaYaml{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Name != "Project Default" || len(profile.Inspections) != 2 || profile.Enabled() != 1 {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+	if i, _ := profile.Inspection("ConstantConditions"); i.String() != "ERROR" || len(i.Scopes) != 1 || i.Scopes[0].String() != "Tests: disabled" {
+		t.Errorf("unexpected inspection %+v", i)
+	}
+
+	profile, err = Resolve(dir, "", "", &qdyaml.QodanaYaml{Profile: qdyaml.Profile{Name: "Strict"}})
+	if err != nil || profile.Name != "Strict" {
+		t.Fatalf("expected the profile of qodana.yaml, got %+v: %v", profile, err)
+	}
+	profile, err = Resolve(dir, "", filepath.Join(ProfilesDir, "Project_Default.xml"), &qdyaml.QodanaYaml{Profile: qdyaml.Profile{Name: "Strict"}})
+	if err != nil || profile.Name != "Project Default" {
+		t.Fatalf("expected the profile of the command line, got %+v: %v", profile, err)
+	}
+
+	if _, err = Resolve(dir, "qodana.recommended", "", &qdyaml.QodanaYaml{}); err == nil {
+		t.Error("expected an error for a bundled profile")
+	}
+	if _, err = Resolve(t.TempDir(), "", "", &qdyaml.QodanaYaml{}); err == nil {
+		t.Error("expected an error without a profile")
+	}
+}
+
+func TestNames(t *testing.T) {
+	dir := writeProfiles(t, map[string]string{"Project_Default.xml": projectDefaultXml, "strict.xml": strictXml, "broken.xml": "<profile"})
+
+	names := Names(dir)
+	expected := append(append([]string{}, bundledProfiles...), "Project Default", "Strict")
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+	if names = Names(t.TempDir()); len(names) != len(bundledProfiles) {
+		t.Errorf("expected only the bundled profiles, got %v", names)
+	}
+}
+
+func TestApply(t *testing.T) {
+	dir := writeProfiles(t, map[string]string{"Project_Default.xml": projectDefaultXml})
+	profile, err := ProjectDefault(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile.Apply(
+		&qdyaml.QodanaYaml{
+			Includes: []qdyaml.Clude{{Name: "UnusedDeclaration"}, {Name: "HardcodedPasswords"}},
+			Excludes: []qdyaml.Clude{{Name: "ConstantConditions"}, {Name: "All", Paths: []string{"build"}}},
+		},
+	)
+	for id, expected := range map[string]string{
+		"UnusedDeclaration":  "WARNING",
+		"HardcodedPasswords": "WARNING",
+		"ConstantConditions": "disabled",
+	} {
+		if i, ok := profile.Inspection(id); !ok || i.String() != expected {
+			t.Errorf("expected %s to be %s, got %+v", id, expected, i)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := writeProfiles(t, map[string]string{"Project_Default.xml": projectDefaultXml, "strict.xml": strictXml})
+	before, err := Load(filepath.Join(dir, ProfilesDir, "Project_Default.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := Load(filepath.Join(dir, ProfilesDir, "strict.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := Diff(before, after)
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+	if changes[0].Id != "ConstantConditions" || changes[0].Before == nil || changes[0].After == nil {
+		t.Errorf("expected the scope of ConstantConditions to change, got %+v", changes[0])
+	}
+	if changes[1].Id != "SpellCheckingInspection" || changes[1].Before != nil {
+		t.Errorf("expected SpellCheckingInspection to be added, got %+v", changes[1])
+	}
+	if changes[2].Id != "UnusedDeclaration" || changes[2].Before.Enabled || !changes[2].After.Enabled {
+		t.Errorf("expected UnusedDeclaration to be enabled, got %+v", changes[2])
+	}
+	if len(Diff(after, after)) != 0 {
+		t.Error("expected no changes between the same profiles")
+	}
+}
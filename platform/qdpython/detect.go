@@ -0,0 +1,70 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdpython sets up the Python environment of a Poetry, Pipenv or requirements.txt project for the analysis:
+// a virtualenv with the dependencies of the project is created in the Qodana cache, addressed by the hash
+// of the dependency files, so the interpreter of the analysis resolves the imports of the project.
+package qdpython
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Manager is the tool declaring the dependencies of a Python project.
+type Manager string
+
+const (
+	Poetry       Manager = "poetry"
+	Pipenv       Manager = "pipenv"
+	Requirements Manager = "requirements.txt"
+)
+
+// Project is the dependency manager of a Python project and its files, relative to the project directory.
+type Project struct {
+	Manager Manager
+	Files   []string
+}
+
+// Detect returns the dependency manager of the project: Poetry if pyproject.toml has a [tool.poetry] table,
+// Pipenv if there is a Pipfile, otherwise pip with requirements.txt.
+func Detect(projectDir string) (Project, bool) {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(projectDir, name))
+		return err == nil
+	}
+	if content, err := os.ReadFile(filepath.Join(projectDir, "pyproject.toml")); err == nil && strings.Contains(string(content), "[tool.poetry]") {
+		return Project{Manager: Poetry, Files: existing(exists, "pyproject.toml", "poetry.lock")}, true
+	}
+	if exists("Pipfile") {
+		return Project{Manager: Pipenv, Files: existing(exists, "Pipfile", "Pipfile.lock")}, true
+	}
+	if exists("requirements.txt") {
+		return Project{Manager: Requirements, Files: []string{"requirements.txt"}}, true
+	}
+	return Project{}, false
+}
+
+func existing(exists func(string) bool, names ...string) []string {
+	var files []string
+	for _, name := range names {
+		if exists(name) {
+			files = append(files, name)
+		}
+	}
+	return files
+}
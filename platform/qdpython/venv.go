@@ -0,0 +1,182 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdpython
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdbootstrap"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	cacheDirName = "python"
+	markerName   = ".qodana-complete"
+	// keepEnvs is the number of the most recently used virtualenvs kept in the cache.
+	keepEnvs = 3
+)
+
+// Interpreter returns the Python interpreter on PATH and its version.
+func Interpreter() (string, string, error) {
+	for _, name := range []string{"python3", "python"} {
+		python, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		out, err := exec.Command(python, "-c", "import sys; print('%d.%d.%d' % sys.version_info[:3])").Output()
+		if err != nil {
+			continue
+		}
+		return python, strings.TrimSpace(string(out)), nil
+	}
+	return "", "", fmt.Errorf("no Python interpreter on PATH")
+}
+
+// BinDir returns the directory of the executables of the virtualenv.
+func BinDir(venv string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(venv, "Scripts")
+	}
+	return filepath.Join(venv, "bin")
+}
+
+func executable(dir string, name string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(dir, name+".exe")
+	}
+	return filepath.Join(dir, name)
+}
+
+// Provision returns the virtualenv with the dependencies of the project installed by the python interpreter
+// of the version, creating it in cacheDir unless it was created for the same interpreter and dependency files.
+// It returns true if the virtualenv was reused. The output of pip, Poetry or Pipenv is written to log.
+func Provision(p Project, projectDir string, cacheDir string, python string, version string, log io.Writer) (string, bool, error) {
+	key, err := qdbootstrap.Key(projectDir, fmt.Sprintf("%s\x00%s", p.Manager, version), p.Files)
+	if err != nil {
+		return "", false, err
+	}
+	root := filepath.Join(cacheDir, cacheDirName)
+	venv := filepath.Join(root, "venv-"+key[:16])
+	if _, err = os.Stat(filepath.Join(venv, markerName)); err == nil {
+		now := time.Now()
+		_ = os.Chtimes(venv, now, now)
+		return venv, true, nil
+	}
+
+	// virtualenvs are not relocatable, the scripts refer to the interpreter by the absolute path
+	_ = os.RemoveAll(venv)
+	if err = run(log, projectDir, nil, python, "-m", "venv", venv); err != nil {
+		_ = os.RemoveAll(venv)
+		return "", false, err
+	}
+	if err = install(p, projectDir, root, venv, log); err != nil {
+		_ = os.RemoveAll(venv)
+		return "", false, err
+	}
+	if err = os.WriteFile(filepath.Join(venv, markerName), []byte(time.Now().UTC().Format(time.RFC3339)), 0o644); err != nil {
+		return "", false, err
+	}
+	prune(root)
+	return venv, false, nil
+}
+
+// install installs the dependencies of the project into the virtualenv, as the activated one.
+func install(p Project, projectDir string, root string, venv string, log io.Writer) error {
+	python := executable(BinDir(venv), "python")
+	env := []string{
+		qdenv.VirtualEnvEnv + "=" + venv,
+		"PATH=" + BinDir(venv) + string(os.PathListSeparator) + os.Getenv("PATH"),
+		"PIP_CACHE_DIR=" + filepath.Join(root, "pip"),
+		"PIP_DISABLE_PIP_VERSION_CHECK=1",
+	}
+	switch p.Manager {
+	case Poetry:
+		env = append(env, "POETRY_VIRTUALENVS_CREATE=false", "POETRY_CACHE_DIR="+filepath.Join(root, "poetry"))
+		poetry, err := tool(string(Poetry), python, projectDir, env, log)
+		if err != nil {
+			return err
+		}
+		return run(log, projectDir, env, poetry, "install", "--no-root", "--no-interaction")
+	case Pipenv:
+		env = append(env, "PIPENV_CACHE_DIR="+filepath.Join(root, "pipenv"), "PIPENV_YES=1", "PIPENV_NOSPIN=1")
+		pipenv, err := tool(string(Pipenv), python, projectDir, env, log)
+		if err != nil {
+			return err
+		}
+		if _, err = os.Stat(filepath.Join(projectDir, "Pipfile.lock")); err == nil {
+			return run(log, projectDir, env, pipenv, "sync", "--dev")
+		}
+		return run(log, projectDir, env, pipenv, "install", "--dev", "--skip-lock")
+	default:
+		return run(log, projectDir, env, python, "-m", "pip", "install", "-r", "requirements.txt")
+	}
+}
+
+// tool returns the installed dependency manager, installing it into the virtualenv if there is none.
+func tool(name string, python string, projectDir string, env []string, log io.Writer) (string, error) {
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	if err := run(log, projectDir, env, python, "-m", "pip", "install", name); err != nil {
+		return "", err
+	}
+	return executable(filepath.Dir(python), name), nil
+}
+
+func run(log io.Writer, dir string, env []string, name string, args ...string) error {
+	_, _ = fmt.Fprintf(log, "$ %s %s\n", name, strings.Join(args, " "))
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w", filepath.Base(name), strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// prune removes all but the keepEnvs most recently used virtualenvs.
+func prune(root string) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	type venv struct {
+		path     string
+		modified time.Time
+	}
+	var venvs []venv
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !e.IsDir() || !strings.HasPrefix(e.Name(), "venv-") {
+			continue
+		}
+		venvs = append(venvs, venv{filepath.Join(root, e.Name()), info.ModTime()})
+	}
+	sort.Slice(venvs, func(i, j int) bool { return venvs[i].modified.After(venvs[j].modified) })
+	for i := keepEnvs; i < len(venvs); i++ {
+		_ = os.RemoveAll(venvs[i].path)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdpython
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected Project
+	}{
+		{
+			"poetry",
+			map[string]string{"pyproject.toml": "[tool.poetry]\nname = \"app\"", "poetry.lock": "", "requirements.txt": ""},
+			Project{Poetry, []string{"pyproject.toml", "poetry.lock"}},
+		},
+		{"pipenv", map[string]string{"Pipfile": "[packages]", "pyproject.toml": "[tool.black]"}, Project{Pipenv, []string{"Pipfile"}}},
+		{"requirements", map[string]string{"requirements.txt": "requests==2.31.0"}, Project{Requirements, []string{"requirements.txt"}}},
+		{"no dependencies", map[string]string{"main.py": ""}, Project{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, filepath.Join(project, name), content)
+			}
+			actual, ok := Detect(project)
+			if !reflect.DeepEqual(actual, tt.expected) || ok != (tt.expected.Manager != "") {
+				t.Errorf("Detect() = %v %v, want %v", actual, ok, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProvision(t *testing.T) {
+	python, version, err := Interpreter()
+	if err != nil {
+		t.Skip(err)
+	}
+	project := t.TempDir()
+	cacheDir := t.TempDir()
+	writeFile(t, filepath.Join(project, "requirements.txt"), "# no dependencies\n")
+	p, _ := Detect(project)
+
+	var log bytes.Buffer
+	venv, reused, err := Provision(p, project, cacheDir, python, version, &log)
+	if err != nil {
+		t.Fatalf("%s\n%s", err, log.String())
+	}
+	if reused {
+		t.Error("expected a new virtualenv")
+	}
+	if _, err = os.Stat(executable(BinDir(venv), "python")); err != nil {
+		t.Error(err)
+	}
+	if again, reused, err := Provision(p, project, cacheDir, python, version, &log); err != nil || !reused || again != venv {
+		t.Errorf("expected the virtualenv to be reused, got %s %v %v", again, reused, err)
+	}
+
+	writeFile(t, filepath.Join(project, "requirements.txt"), "# still no dependencies\n")
+	if changed, reused, err := Provision(p, project, cacheDir, python, version, &log); err != nil || reused || changed == venv {
+		t.Errorf("expected a new virtualenv for the changed requirements, got %s %v %v", changed, reused, err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < keepEnvs+2; i++ {
+		venv := filepath.Join(root, "venv-"+string(rune('a'+i)))
+		writeFile(t, filepath.Join(venv, markerName), "")
+		modified := time.Now().Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(venv, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prune(root)
+	entries, _ := os.ReadDir(root)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"venv-c", "venv-d", "venv-e"}) {
+		t.Errorf("expected the most recent virtualenvs to be kept, got %v", names)
+	}
+}
@@ -0,0 +1,223 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdredact rewrites host-specific details, e.g. absolute paths and user names, in the results of a run.
+package qdredact
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	ProjectDirPlaceholder = "$PROJECT_DIR"
+	HomeDirPlaceholder    = "~"
+	UserPlaceholder       = "<user>"
+	HostPlaceholder       = "<host>"
+	// minValueLength is the length of the shortest value redacted, shorter ones would match unrelated text.
+	minValueLength = 3
+)
+
+// textExtensions are the extensions of the files redacted by RedactDir, the other files are kept as is.
+var textExtensions = map[string]bool{
+	".json": true, ".sarif": true, ".log": true, ".txt": true, ".html": true, ".htm": true, ".js": true,
+	".xml": true, ".yaml": true, ".yml": true, ".csv": true, ".md": true,
+}
+
+// Rules configure what is redacted, read from the redaction rules file.
+type Rules struct {
+	// Paths replaces the project and the home directories, true if not set.
+	Paths *bool `yaml:"paths,omitempty"`
+	// Users replaces the name of the current user and the host name, true if not set.
+	Users *bool `yaml:"users,omitempty"`
+	// Env are the names of the environment variables whose values are replaced with <$NAME>.
+	Env []string `yaml:"env,omitempty"`
+	// Replace are the regular expressions replaced with the given text, e.g. internal host names.
+	Replace []Replacement `yaml:"replace,omitempty"`
+}
+
+// Replacement replaces the matches of Pattern with With, which can refer to the groups of the pattern as ${1}.
+type Replacement struct {
+	Pattern string `yaml:"pattern"`
+	With    string `yaml:"with"`
+}
+
+// LoadRules reads the redaction rules from the YAML file at path, the default rules are returned for an empty path.
+func LoadRules(path string) (Rules, error) {
+	var rules Rules
+	if path == "" {
+		return rules, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rules, err
+	}
+	if err = yaml.Unmarshal(data, &rules); err != nil {
+		return rules, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Redactor replaces the values of the rules in text.
+type Redactor struct {
+	literals []literal
+	patterns []pattern
+}
+
+type literal struct {
+	value       string
+	replacement string
+}
+
+type pattern struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// New returns the redactor of the rules for the project.
+func New(rules Rules, projectDir string) (*Redactor, error) {
+	r := &Redactor{}
+	if rules.Paths == nil || *rules.Paths {
+		r.addPath(projectDir, ProjectDirPlaceholder)
+		if home, err := os.UserHomeDir(); err == nil {
+			r.addPath(home, HomeDirPlaceholder)
+		}
+	}
+	if rules.Users == nil || *rules.Users {
+		if current, err := user.Current(); err == nil {
+			r.addWord(current.Username, UserPlaceholder)
+			if _, name, found := strings.Cut(current.Username, `\`); found {
+				r.addWord(name, UserPlaceholder)
+			}
+		}
+		if host, err := os.Hostname(); err == nil {
+			r.addWord(host, HostPlaceholder)
+		}
+	}
+	for _, name := range rules.Env {
+		r.addLiteral(os.Getenv(name), "<$"+name+">")
+	}
+	for _, replacement := range rules.Replace {
+		re, err := regexp.Compile(replacement.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", replacement.Pattern, err)
+		}
+		r.patterns = append(r.patterns, pattern{re: re, replacement: replacement.With})
+	}
+	// the longer values first, e.g. the project directory inside the home directory
+	sort.SliceStable(
+		r.literals, func(i, j int) bool {
+			return len(r.literals[i].value) > len(r.literals[j].value)
+		},
+	)
+	return r, nil
+}
+
+func (r *Redactor) addLiteral(value string, replacement string) {
+	if len(value) >= minValueLength {
+		r.literals = append(r.literals, literal{value: value, replacement: replacement})
+	}
+}
+
+// addPath adds the directory in its native, slash-separated and JSON-escaped forms.
+func (r *Redactor) addPath(dir string, replacement string) {
+	if dir == "" {
+		return
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	dir = strings.TrimRight(dir, `/\`)
+	if len(dir) < minValueLength {
+		return
+	}
+	forms := []string{dir, filepath.ToSlash(dir), strings.ReplaceAll(dir, `\`, `\\`)}
+	seen := map[string]bool{}
+	for _, form := range forms {
+		if !seen[form] {
+			seen[form] = true
+			r.addLiteral(form, replacement)
+		}
+	}
+}
+
+// addWord adds the value matched after a path separator, a quote, =, @ or :, e.g. in /home/user or user.name=user,
+// as a short user or host name is likely a part of unrelated text too.
+func (r *Redactor) addWord(value string, replacement string) {
+	if len(value) >= minValueLength {
+		re := regexp.MustCompile(`(^|[/\\=@:"'])` + regexp.QuoteMeta(value) + `\b`)
+		r.patterns = append(r.patterns, pattern{re: re, replacement: "${1}" + replacement})
+	}
+}
+
+// Redact returns the text with the values of the rules replaced.
+func (r *Redactor) Redact(text string) string {
+	return string(r.RedactBytes([]byte(text)))
+}
+
+// RedactBytes returns the content with the values of the rules replaced.
+func (r *Redactor) RedactBytes(content []byte) []byte {
+	for _, l := range r.literals {
+		content = bytes.ReplaceAll(content, []byte(l.value), []byte(l.replacement))
+	}
+	for _, p := range r.patterns {
+		content = p.re.ReplaceAll(content, []byte(p.replacement))
+	}
+	return content
+}
+
+// RedactFile rewrites the file with the values of the rules replaced, it returns whether the file is changed.
+func (r *Redactor) RedactFile(path string) (bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	redacted := r.RedactBytes(content)
+	if bytes.Equal(content, redacted) {
+		return false, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(path, redacted, info.Mode().Perm())
+}
+
+// RedactDir redacts the text files in dir recursively and returns the number of the changed ones.
+func (r *Redactor) RedactDir(dir string) (int, error) {
+	changed := 0
+	err := filepath.WalkDir(
+		dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !textExtensions[strings.ToLower(filepath.Ext(path))] {
+				return err
+			}
+			fileChanged, err := r.RedactFile(path)
+			if fileChanged {
+				changed++
+			}
+			return err
+		},
+	)
+	return changed, err
+}
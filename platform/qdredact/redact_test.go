@@ -0,0 +1,113 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdredact
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the paths of the test are POSIX ones")
+	}
+	t.Setenv("HOME", "/home/alice")
+	t.Setenv("CI_SERVER_HOST", "gitlab.corp.example")
+	rulesPath := filepath.Join(t.TempDir(), "redaction.yaml")
+	rules := `users: false
+env: [CI_SERVER_HOST]
+replace:
+  - pattern: 'ticket-(\d+)'
+    with: 'ticket-N'
+`
+	if err := os.WriteFile(rulesPath, []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadRules(rulesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := New(loaded, "/home/alice/work/service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.addWord("alice", UserPlaceholder)
+	for _, tt := range []struct {
+		text     string
+		expected string
+	}{
+		{text: "file:///home/alice/work/service/src/A.java", expected: "file://$PROJECT_DIR/src/A.java"},
+		{text: "Cache: /home/alice/.cache/qodana", expected: "Cache: ~/.cache/qodana"},
+		{text: "user.name=alice, alice in wonderland", expected: "user.name=<user>, alice in wonderland"},
+		{text: "cloned from https://gitlab.corp.example/org/repo", expected: "cloned from https://<$CI_SERVER_HOST>/org/repo"},
+		{text: "see ticket-1234", expected: "see ticket-N"},
+	} {
+		if actual := r.Redact(tt.text); actual != tt.expected {
+			t.Errorf("Redact(%q) = %q, want %q", tt.text, actual, tt.expected)
+		}
+	}
+}
+
+func TestRedactDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the paths of the test are POSIX ones")
+	}
+	t.Setenv("HOME", "/home/alice")
+	dir := t.TempDir()
+	files := map[string]string{
+		"qodana.sarif.json":    `{"uri": "/home/alice/project/A.java"}`,
+		"log/idea.log":         "opened /home/alice/project",
+		"report/index.html":    "<html></html>",
+		"report/cache/data.db": "/home/alice/project",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	disabled := false
+	r, err := New(Rules{Users: &disabled}, "/home/alice/project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := r.RedactDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 2 {
+		t.Errorf("RedactDir() changed %d files, want 2", changed)
+	}
+	for name, expected := range map[string]string{
+		"qodana.sarif.json":    `{"uri": "$PROJECT_DIR/A.java"}`,
+		"log/idea.log":         "opened $PROJECT_DIR",
+		"report/cache/data.db": "/home/alice/project",
+	} {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != expected {
+			t.Errorf("%s = %q, want %q", name, content, expected)
+		}
+	}
+}
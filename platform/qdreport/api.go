@@ -0,0 +1,408 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdreport serves the Qodana HTML report together with a REST API over the SARIF results of the run.
+package qdreport
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	apiPath          = "/api/v1/"
+	sarifName        = "qodana.sarif.json"
+	defaultPageSize  = 100
+	maxPageSize      = 1000
+	defaultContext   = 3
+	maxContext       = 50
+	unknownSeverity  = "note"
+	severityProperty = "qodanaSeverity"
+)
+
+// Problem is a SARIF result of the run as returned by the API.
+type Problem struct {
+	Id            int    `json:"id"`
+	RuleId        string `json:"ruleId"`
+	Severity      string `json:"severity"`
+	Message       string `json:"message"`
+	File          string `json:"file,omitempty"`
+	Line          int    `json:"line,omitempty"`
+	Column        int    `json:"column,omitempty"`
+	EndLine       int    `json:"endLine,omitempty"`
+	EndColumn     int    `json:"endColumn,omitempty"`
+	BaselineState string `json:"baselineState,omitempty"`
+	Fingerprint   string `json:"fingerprint,omitempty"`
+
+	snippet *sarif.Region
+}
+
+// ProblemsPage is a page of the problems matching the filters.
+type ProblemsPage struct {
+	Total    int       `json:"total"`
+	Page     int       `json:"page"`
+	PageSize int       `json:"pageSize"`
+	Problems []Problem `json:"problems"`
+}
+
+// Summary is the number of problems by severity.
+type Summary struct {
+	Total      int            `json:"total"`
+	Severities map[string]int `json:"severities"`
+}
+
+// Snippet is the source code around a problem.
+type Snippet struct {
+	File      string   `json:"file"`
+	StartLine int      `json:"startLine"`
+	Lines     []string `json:"lines"`
+}
+
+// Handler serves the report with static and the API over the SARIF report in resultsDir:
+//
+//	GET /api/v1/problems?severity=&rule=&file=&baselineState=&q=&page=&pageSize= lists the problems
+//	GET /api/v1/problems/{id} returns the problem
+//	GET /api/v1/problems/{id}/snippet?context= returns the source lines around the problem
+//	GET /api/v1/severities?baselineState= returns the number of problems by severity
+//
+// Snippets are read from projectDir, or taken from the SARIF report if the file is not there.
+func Handler(static http.Handler, resultsDir string, projectDir string) http.Handler {
+	api := &api{sarifPath: filepath.Join(resultsDir, sarifName), projectDir: projectDir}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+apiPath+"problems", api.listProblems)
+	mux.HandleFunc("GET "+apiPath+"problems/{id}", api.getProblem)
+	mux.HandleFunc("GET "+apiPath+"problems/{id}/snippet", api.getSnippet)
+	mux.HandleFunc("GET "+apiPath+"severities", api.getSeverities)
+	mux.HandleFunc(apiPath, func(w http.ResponseWriter, r *http.Request) { writeError(w, http.StatusNotFound, "not found") })
+	mux.Handle("/", static)
+	return mux
+}
+
+type api struct {
+	sarifPath  string
+	projectDir string
+
+	mu       sync.Mutex
+	modified time.Time
+	problems []Problem
+}
+
+// load returns the problems of the SARIF report, the report is read again when it changes.
+func (a *api) load() ([]Problem, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	info, err := os.Stat(a.sarifPath)
+	if err != nil {
+		return nil, err
+	}
+	if a.problems != nil && info.ModTime().Equal(a.modified) {
+		return a.problems, nil
+	}
+	problems, err := LoadProblems(a.sarifPath)
+	if err != nil {
+		return nil, err
+	}
+	a.problems = problems
+	a.modified = info.ModTime()
+	return problems, nil
+}
+
+// LoadProblems reads the problems of the SARIF report, the problems are identified by their position in the report.
+func LoadProblems(sarifPath string) ([]Problem, error) {
+	data, err := os.ReadFile(sarifPath)
+	if err != nil {
+		return nil, err
+	}
+	report := &sarif.Report{}
+	if err = json.Unmarshal(data, report); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", sarifPath, err)
+	}
+	problems := make([]Problem, 0)
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			problems = append(problems, toProblem(len(problems), &run.Results[i]))
+		}
+	}
+	return problems, nil
+}
+
+func toProblem(id int, r *sarif.Result) Problem {
+	p := Problem{Id: id, RuleId: r.RuleId, Severity: severity(r)}
+	if r.Message != nil {
+		p.Message = r.Message.Text
+	}
+	if state, ok := r.BaselineState.(string); ok {
+		p.BaselineState = state
+	}
+	if len(r.Locations) > 0 && r.Locations[0].PhysicalLocation != nil {
+		location := r.Locations[0].PhysicalLocation
+		if location.ArtifactLocation != nil {
+			p.File = location.ArtifactLocation.Uri
+		}
+		if location.Region != nil {
+			p.Line = int(location.Region.StartLine)
+			p.Column = int(location.Region.StartColumn)
+			if location.Region.EndLine > 0 {
+				p.EndLine, p.EndColumn = int(location.Region.EndLine), int(location.Region.EndColumn)
+			} else if location.Region.CharLength > 0 && p.Column > 0 {
+				p.EndLine, p.EndColumn = p.Line, p.Column+int(location.Region.CharLength)
+			}
+		}
+		p.snippet = location.ContextRegion
+	}
+	p.Fingerprint = r.PartialFingerprints["equalIndicator/v2"]
+	if p.Fingerprint == "" {
+		p.Fingerprint = r.PartialFingerprints["equalIndicator/v1"]
+	}
+	return p
+}
+
+func severity(r *sarif.Result) string {
+	if r.Properties != nil {
+		if s, ok := r.Properties.AdditionalProperties[severityProperty].(string); ok {
+			return s
+		}
+	}
+	if level, ok := r.Level.(string); ok {
+		return level
+	}
+	return unknownSeverity
+}
+
+// filter returns the problems matching the query parameters of the request.
+func filter(problems []Problem, query url.Values) []Problem {
+	severities := splitValues(query["severity"])
+	rules := splitValues(query["rule"])
+	states := splitValues(query["baselineState"])
+	file := query.Get("file")
+	text := strings.ToLower(query.Get("q"))
+	matched := make([]Problem, 0)
+	for _, p := range problems {
+		if (len(severities) > 0 && !severities[strings.ToLower(p.Severity)]) ||
+			(len(rules) > 0 && !rules[strings.ToLower(p.RuleId)]) ||
+			(len(states) > 0 && !states[strings.ToLower(p.BaselineState)]) ||
+			(file != "" && !strings.HasPrefix(p.File, file)) ||
+			(text != "" && !strings.Contains(strings.ToLower(p.Message), text)) {
+			continue
+		}
+		matched = append(matched, p)
+	}
+	return matched
+}
+
+// splitValues returns the lowercase set of the values given as repeated or comma-separated parameters.
+func splitValues(values []string) map[string]bool {
+	set := map[string]bool{}
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				set[strings.ToLower(v)] = true
+			}
+		}
+	}
+	return set
+}
+
+func (a *api) listProblems(w http.ResponseWriter, r *http.Request) {
+	problems, ok := a.loadOrFail(w)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	page, err := intParam(query, "page", 1, 1, 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	pageSize, err := intParam(query, "pageSize", defaultPageSize, 1, maxPageSize)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	matched := filter(problems, query)
+	from := min((page-1)*pageSize, len(matched))
+	to := min(from+pageSize, len(matched))
+	writeJson(w, ProblemsPage{Total: len(matched), Page: page, PageSize: pageSize, Problems: matched[from:to]})
+}
+
+func (a *api) getProblem(w http.ResponseWriter, r *http.Request) {
+	if p, ok := a.problem(w, r); ok {
+		writeJson(w, p)
+	}
+}
+
+func (a *api) getSnippet(w http.ResponseWriter, r *http.Request) {
+	p, ok := a.problem(w, r)
+	if !ok {
+		return
+	}
+	context, err := intParam(r.URL.Query(), "context", defaultContext, 0, maxContext)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if p.File == "" || p.Line == 0 {
+		writeError(w, http.StatusNotFound, "the problem has no source location")
+		return
+	}
+	snippet, err := a.readSnippet(p, context)
+	if err != nil {
+		log.Debugf("Failed to read the snippet of problem %d: %v", p.Id, err)
+		writeError(w, http.StatusNotFound, "source code is not available")
+		return
+	}
+	writeJson(w, snippet)
+}
+
+func (a *api) getSeverities(w http.ResponseWriter, r *http.Request) {
+	problems, ok := a.loadOrFail(w)
+	if !ok {
+		return
+	}
+	summary := Summary{Severities: map[string]int{}}
+	for _, p := range filter(problems, url.Values{"baselineState": r.URL.Query()["baselineState"]}) {
+		summary.Total++
+		summary.Severities[p.Severity]++
+	}
+	writeJson(w, summary)
+}
+
+func (a *api) problem(w http.ResponseWriter, r *http.Request) (Problem, bool) {
+	problems, ok := a.loadOrFail(w)
+	if !ok {
+		return Problem{}, false
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 0 || id >= len(problems) {
+		writeError(w, http.StatusNotFound, "problem not found")
+		return Problem{}, false
+	}
+	return problems[id], true
+}
+
+func (a *api) loadOrFail(w http.ResponseWriter) ([]Problem, bool) {
+	problems, err := a.load()
+	if errors.Is(err, os.ErrNotExist) {
+		writeError(w, http.StatusNotFound, "no results found, get them by running `qodana scan`")
+		return nil, false
+	}
+	if err != nil {
+		log.Errorf("Failed to read the results: %v", err)
+		writeError(w, http.StatusInternalServerError, "failed to read the results")
+		return nil, false
+	}
+	return problems, true
+}
+
+// readSnippet reads the lines around the problem from the project, or takes the context region from the SARIF report.
+func (a *api) readSnippet(p Problem, context int) (*Snippet, error) {
+	start := max(p.Line-context, 1)
+	if path, ok := a.sourcePath(p.File); ok {
+		if snippet, err := readLines(path, start, p.Line+context); err == nil {
+			snippet.File = p.File
+			return snippet, nil
+		}
+	}
+	if p.snippet == nil || p.snippet.Snippet == nil || p.snippet.StartLine == 0 {
+		return nil, errors.New("no snippet in the SARIF report")
+	}
+	lines := strings.Split(strings.TrimSuffix(p.snippet.Snippet.Text, "\n"), "\n")
+	first := int(p.snippet.StartLine)
+	from := min(max(start-first, 0), len(lines))
+	to := min(max(p.Line+context-first+1, from), len(lines))
+	return &Snippet{File: p.File, StartLine: first + from, Lines: lines[from:to]}, nil
+}
+
+// sourcePath resolves the file of the problem in the project directory, files outside of it are never read.
+func (a *api) sourcePath(file string) (string, bool) {
+	if a.projectDir == "" || strings.Contains(file, "://") {
+		return "", false
+	}
+	root, err := filepath.Abs(a.projectDir)
+	if err != nil {
+		return "", false
+	}
+	path := filepath.Join(root, filepath.FromSlash(file))
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return path, true
+}
+
+func readLines(path string, from int, to int) (*Snippet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	snippet := &Snippet{StartLine: from, Lines: make([]string, 0)}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; line <= to && scanner.Scan(); line++ {
+		if line >= from {
+			snippet.Lines = append(snippet.Lines, scanner.Text())
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(snippet.Lines) == 0 {
+		return nil, fmt.Errorf("%s has less than %d lines", path, from)
+	}
+	return snippet, nil
+}
+
+// intParam parses the integer query parameter, max of zero means no upper limit.
+func intParam(query url.Values, name string, def int, minValue int, maxValue int) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < minValue || (maxValue > 0 && n > maxValue) {
+		if maxValue > 0 {
+			return 0, fmt.Errorf("%s must be an integer from %d to %d", name, minValue, maxValue)
+		}
+		return 0, fmt.Errorf("%s must be an integer not less than %d", name, minValue)
+	}
+	return n, nil
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debugf("Failed to write the response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSarif = `{
+  "version": "2.1.0",
+  "runs": [{
+    "tool": {"driver": {"name": "QDJVM"}},
+    "results": [
+      {
+        "ruleId": "UnusedImport", "level": "warning", "message": {"text": "Unused import"}, "baselineState": "new",
+        "properties": {"qodanaSeverity": "Moderate"},
+        "locations": [{"physicalLocation": {"artifactLocation": {"uri": "src/A.java"}, "region": {"startLine": 3, "startColumn": 1}}}]
+      },
+      {
+        "ruleId": "ConstantValue", "level": "error", "message": {"text": "Condition is always true"}, "baselineState": "unchanged",
+        "properties": {"qodanaSeverity": "High"},
+        "locations": [{"physicalLocation": {
+          "artifactLocation": {"uri": "src/Missing.java"}, "region": {"startLine": 11},
+          "contextRegion": {"startLine": 9, "snippet": {"text": "nine\nten\neleven\ntwelve\nthirteen\n"}}
+        }}]
+      },
+      {
+        "ruleId": "Escape", "level": "error", "message": {"text": "Outside"}, "baselineState": "new",
+        "properties": {"qodanaSeverity": "High"},
+        "locations": [{"physicalLocation": {"artifactLocation": {"uri": "../secret.txt"}, "region": {"startLine": 1}}}]
+      }
+    ]
+  }]
+}`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	resultsDir := t.TempDir()
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(resultsDir, sarifName), []byte(testSarif), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(projectDir, "src"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	source := "package a;\n\nimport java.util.List;\n\nclass A {}\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "src", "A.java"), []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filepath.Dir(projectDir), "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	static := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("report")) })
+	svr := httptest.NewServer(Handler(static, resultsDir, projectDir))
+	t.Cleanup(svr.Close)
+	return svr
+}
+
+func get(t *testing.T, svr *httptest.Server, path string, status int, v interface{}) {
+	resp, err := http.Get(svr.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != status {
+		t.Fatalf("%s: expected status %d, got %d", path, status, resp.StatusCode)
+	}
+	if v != nil {
+		if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProblems(t *testing.T) {
+	svr := newTestServer(t)
+
+	page := ProblemsPage{}
+	get(t, svr, "/api/v1/problems", http.StatusOK, &page)
+	if page.Total != 3 || len(page.Problems) != 3 || page.PageSize != defaultPageSize {
+		t.Fatalf("unexpected page %+v", page)
+	}
+	first := page.Problems[0]
+	if first.RuleId != "UnusedImport" || first.Severity != "Moderate" || first.File != "src/A.java" || first.Line != 3 {
+		t.Errorf("unexpected problem %+v", first)
+	}
+
+	get(t, svr, "/api/v1/problems?severity=high&baselineState=new", http.StatusOK, &page)
+	if page.Total != 1 || page.Problems[0].RuleId != "Escape" {
+		t.Errorf("unexpected filtered page %+v", page)
+	}
+	get(t, svr, "/api/v1/problems?q=ALWAYS&file=src/", http.StatusOK, &page)
+	if page.Total != 1 || page.Problems[0].Id != 1 {
+		t.Errorf("unexpected searched page %+v", page)
+	}
+	get(t, svr, "/api/v1/problems?page=2&pageSize=2", http.StatusOK, &page)
+	if page.Total != 3 || len(page.Problems) != 1 || page.Problems[0].Id != 2 {
+		t.Errorf("unexpected second page %+v", page)
+	}
+	get(t, svr, "/api/v1/problems?page=5", http.StatusOK, &page)
+	if page.Total != 3 || len(page.Problems) != 0 {
+		t.Errorf("unexpected empty page %+v", page)
+	}
+	get(t, svr, "/api/v1/problems?pageSize=0", http.StatusBadRequest, nil)
+
+	problem := Problem{}
+	get(t, svr, "/api/v1/problems/1", http.StatusOK, &problem)
+	if problem.RuleId != "ConstantValue" {
+		t.Errorf("unexpected problem %+v", problem)
+	}
+	get(t, svr, "/api/v1/problems/3", http.StatusNotFound, nil)
+	get(t, svr, "/api/v1/unknown", http.StatusNotFound, nil)
+	get(t, svr, "/index.html", http.StatusOK, nil)
+}
+
+func TestSeverities(t *testing.T) {
+	svr := newTestServer(t)
+	summary := Summary{}
+	get(t, svr, "/api/v1/severities", http.StatusOK, &summary)
+	if summary.Total != 3 || summary.Severities["High"] != 2 || summary.Severities["Moderate"] != 1 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	get(t, svr, "/api/v1/severities?baselineState=new", http.StatusOK, &summary)
+	if summary.Total != 2 {
+		t.Errorf("unexpected summary of new problems %+v", summary)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	svr := newTestServer(t)
+	snippet := Snippet{}
+	get(t, svr, "/api/v1/problems/0/snippet?context=1", http.StatusOK, &snippet)
+	if snippet.StartLine != 2 || len(snippet.Lines) != 3 || snippet.Lines[1] != "import java.util.List;" {
+		t.Errorf("unexpected snippet %+v", snippet)
+	}
+
+	snippet = Snippet{}
+	get(t, svr, "/api/v1/problems/1/snippet?context=1", http.StatusOK, &snippet)
+	if snippet.StartLine != 10 || len(snippet.Lines) != 3 || snippet.Lines[0] != "ten" || snippet.Lines[2] != "twelve" {
+		t.Errorf("unexpected snippet from the SARIF report %+v", snippet)
+	}
+
+	get(t, svr, "/api/v1/problems/2/snippet", http.StatusNotFound, nil)
+	get(t, svr, "/api/v1/problems/0/snippet?context=100", http.StatusBadRequest, nil)
+}
+
+func TestNoResults(t *testing.T) {
+	static := http.NotFoundHandler()
+	svr := httptest.NewServer(Handler(static, t.TempDir(), ""))
+	defer svr.Close()
+	get(t, svr, "/api/v1/problems", http.StatusNotFound, nil)
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"encoding/json"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
+	"os"
+	"sort"
+	"time"
+)
+
+// Benchmark compares the scans of the same project revision by two linters, e.g. a release and an EAP version.
+type Benchmark struct {
+	Revision   string        `json:"revision,omitempty"`
+	Base       BenchmarkRun  `json:"base"`
+	Head       BenchmarkRun  `json:"head"`
+	Stages     []StageTiming `json:"stages"`
+	Comparison *Comparison   `json:"comparison"`
+}
+
+// BenchmarkRun is a scan of the benchmark.
+type BenchmarkRun struct {
+	Linter     string `json:"linter"`
+	ResultsDir string `json:"resultsDir"`
+	ExitCode   int    `json:"exitCode"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// StageTiming is the duration of a stage in both scans, 0 if the stage is missing from a scan.
+type StageTiming struct {
+	Name   string `json:"name"`
+	Parent string `json:"parent,omitempty"`
+	BaseMs int64  `json:"baseMs"`
+	HeadMs int64  `json:"headMs"`
+}
+
+// Delta returns how much longer the stage took in the head scan.
+func (s StageTiming) Delta() time.Duration {
+	return time.Duration(s.HeadMs-s.BaseMs) * time.Millisecond
+}
+
+// CompareStages matches the stages of the scans by their names and parents, in the order of the base scan
+// followed by the stages only the head scan has. The repeated stages of a scan are summed up.
+func CompareStages(base []qdtrace.Stage, head []qdtrace.Stage) []StageTiming {
+	timings := make([]StageTiming, 0)
+	index := map[[2]string]int{}
+	timing := func(s qdtrace.Stage) *StageTiming {
+		key := [2]string{s.Parent, s.Name}
+		i, ok := index[key]
+		if !ok {
+			i = len(timings)
+			index[key] = i
+			timings = append(timings, StageTiming{Name: s.Name, Parent: s.Parent})
+		}
+		return &timings[i]
+	}
+	for _, s := range base {
+		timing(s).BaseMs += s.Duration.Milliseconds()
+	}
+	for _, s := range head {
+		timing(s).HeadMs += s.Duration.Milliseconds()
+	}
+	return timings
+}
+
+// WriteBenchmark writes the benchmark as JSON to path.
+func WriteBenchmark(b *Benchmark, path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Stability is the problems found in only some of the repeated runs of the same analysis.
+type Stability struct {
+	Runs     []string          `json:"runs"`
+	Problems int               `json:"problems"`
+	Unstable []UnstableProblem `json:"unstable"`
+	Rules    []UnstableRule    `json:"rules"`
+}
+
+// UnstableProblem is a problem reported a different number of times by the runs.
+type UnstableProblem struct {
+	Problem Problem `json:"problem"`
+	// Counts is the number of times the problem is reported by each run.
+	Counts []int `json:"counts"`
+}
+
+// UnstableRule is a rule with unstable problems.
+type UnstableRule struct {
+	RuleId   string `json:"ruleId"`
+	Unstable int    `json:"unstable"`
+}
+
+// CompareRepeatedResults compares the SARIF reports of the results directories (or SARIF files) of the repeated runs.
+func CompareRepeatedResults(runs []string) (*Stability, error) {
+	problems := make([][]Problem, 0, len(runs))
+	for _, run := range runs {
+		p, err := LoadProblems(SarifPath(run))
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, p)
+	}
+	s := CompareRuns(problems)
+	s.Runs = runs
+	return s, nil
+}
+
+// CompareRuns matches the problems of the runs like Compare does and finds the ones not reported the same number
+// of times by every run, in the order they are first reported.
+func CompareRuns(runs [][]Problem) *Stability {
+	s := &Stability{Unstable: []UnstableProblem{}, Rules: []UnstableRule{}}
+	var keys []string
+	found := map[string]*UnstableProblem{}
+	for i, problems := range runs {
+		for _, p := range problems {
+			u, ok := found[p.key()]
+			if !ok {
+				u = &UnstableProblem{Problem: p, Counts: make([]int, len(runs))}
+				found[p.key()] = u
+				keys = append(keys, p.key())
+			}
+			u.Counts[i]++
+		}
+	}
+	s.Problems = len(keys)
+	rules := map[string]int{}
+	for _, key := range keys {
+		u := found[key]
+		for _, count := range u.Counts {
+			if count != u.Counts[0] {
+				s.Unstable = append(s.Unstable, *u)
+				if _, ok := rules[u.Problem.RuleId]; !ok {
+					s.Rules = append(s.Rules, UnstableRule{RuleId: u.Problem.RuleId})
+				}
+				rules[u.Problem.RuleId]++
+				break
+			}
+		}
+	}
+	for i := range s.Rules {
+		s.Rules[i].Unstable = rules[s.Rules[i].RuleId]
+	}
+	sort.SliceStable(
+		s.Rules, func(i, j int) bool {
+			return s.Rules[i].Unstable > s.Rules[j].Unstable
+		},
+	)
+	return s
+}
+
+// WriteStability writes the stability of the runs as JSON to path.
+func WriteStability(s *Stability, path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompareStages(t *testing.T) {
+	base := []qdtrace.Stage{
+		{Name: "bootstrap", Duration: 2 * time.Second},
+		{Name: "analysis", Duration: 60 * time.Second},
+		{Name: "indexing", Parent: "analysis", Duration: 20 * time.Second},
+		{Name: "indexing", Parent: "analysis", Duration: 5 * time.Second},
+	}
+	head := []qdtrace.Stage{
+		{Name: "analysis", Duration: 50 * time.Second},
+		{Name: "indexing", Parent: "analysis", Duration: 15 * time.Second},
+		{Name: "inspection", Parent: "analysis", Duration: 30 * time.Second},
+	}
+	expected := []StageTiming{
+		{Name: "bootstrap", BaseMs: 2000},
+		{Name: "analysis", BaseMs: 60000, HeadMs: 50000},
+		{Name: "indexing", Parent: "analysis", BaseMs: 25000, HeadMs: 15000},
+		{Name: "inspection", Parent: "analysis", HeadMs: 30000},
+	}
+	actual := CompareStages(base, head)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("CompareStages() = %+v, want %+v", actual, expected)
+	}
+	if delta := actual[1].Delta(); delta != -10*time.Second {
+		t.Errorf("Delta() = %s, want -10s", delta)
+	}
+}
+
+func TestCompareRuns(t *testing.T) {
+	stable := Problem{RuleId: "UnusedImport", File: "A.java", Message: "Unused import", Fingerprint: "1"}
+	flaky := Problem{RuleId: "DataFlowIssue", File: "B.java", Message: "May produce NPE", Fingerprint: "2"}
+	duplicated := Problem{RuleId: "DataFlowIssue", File: "C.java", Message: "Condition is always true"}
+	runs := [][]Problem{
+		{stable, flaky, duplicated, duplicated},
+		{stable, duplicated},
+		{stable, flaky, duplicated, duplicated},
+	}
+	s := CompareRuns(runs)
+	if s.Problems != 3 {
+		t.Errorf("Problems = %d, want 3", s.Problems)
+	}
+	expected := []UnstableProblem{
+		{Problem: flaky, Counts: []int{1, 0, 1}},
+		{Problem: duplicated, Counts: []int{2, 1, 2}},
+	}
+	if !reflect.DeepEqual(s.Unstable, expected) {
+		t.Errorf("Unstable = %+v, want %+v", s.Unstable, expected)
+	}
+	if rules := []UnstableRule{{RuleId: "DataFlowIssue", Unstable: 2}}; !reflect.DeepEqual(s.Rules, rules) {
+		t.Errorf("Rules = %+v, want %+v", s.Rules, rules)
+	}
+	if s = CompareRuns([][]Problem{{stable}, {stable}}); len(s.Unstable) != 0 || len(s.Rules) != 0 {
+		t.Errorf("expected no unstable problems, got %+v", s)
+	}
+}
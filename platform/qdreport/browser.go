@@ -0,0 +1,347 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"atomicgo.dev/keyboard/keys"
+	"fmt"
+	"github.com/pterm/pterm"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// browserAction is what the terminal loop needs to do after a key press.
+type browserAction int
+
+const (
+	actionNone browserAction = iota
+	actionQuit
+	actionEdit
+)
+
+// browserInput is the filter being typed in the browser.
+type browserInput struct {
+	label string
+	value string
+	apply func(value string)
+}
+
+// browser is the state of the terminal UI listing the problems.
+type browser struct {
+	problems   []Problem
+	visible    []int
+	severities []string
+	cursor     int
+	offset     int
+
+	severity string
+	rule     string
+	path     string
+	query    string
+
+	input      *browserInput
+	suppressed map[int]bool
+	suppress   func(p Problem) error
+	status     string
+}
+
+func newBrowser(problems []Problem, suppress func(p Problem) error) *browser {
+	b := &browser{problems: problems, suppressed: map[int]bool{}, suppress: suppress}
+	present := map[string]bool{}
+	for _, p := range problems {
+		present[p.Severity] = true
+	}
+	for severity := range present {
+		b.severities = append(b.severities, severity)
+	}
+	sortSeverities(b.severities)
+	b.applyFilters()
+	return b
+}
+
+func (b *browser) applyFilters() {
+	b.visible = b.visible[:0]
+	for i, p := range b.problems {
+		if (b.severity != "" && p.Severity != b.severity) ||
+			(b.rule != "" && !strings.Contains(strings.ToLower(p.RuleId), strings.ToLower(b.rule))) ||
+			(b.path != "" && !strings.HasPrefix(p.File, b.path)) ||
+			(b.query != "" && !strings.Contains(strings.ToLower(p.Message), strings.ToLower(b.query))) {
+			continue
+		}
+		b.visible = append(b.visible, i)
+	}
+	b.cursor, b.offset = 0, 0
+}
+
+// selected returns the problem under the cursor, ok is false if no problems match the filters.
+func (b *browser) selected() (Problem, bool) {
+	if len(b.visible) == 0 {
+		return Problem{}, false
+	}
+	return b.problems[b.visible[b.cursor]], true
+}
+
+func (b *browser) move(delta int) {
+	b.cursor = max(min(b.cursor+delta, len(b.visible)-1), 0)
+}
+
+func (b *browser) handle(key keys.Key) browserAction {
+	if key.Code == keys.CtrlC {
+		return actionQuit
+	}
+	if b.input != nil {
+		b.handleInput(key)
+		return actionNone
+	}
+	b.status = ""
+	switch key.Code {
+	case keys.Up:
+		b.move(-1)
+	case keys.Down:
+		b.move(1)
+	case keys.PgUp:
+		b.move(-10)
+	case keys.PgDown:
+		b.move(10)
+	case keys.Home:
+		b.move(-len(b.visible))
+	case keys.End:
+		b.move(len(b.visible))
+	case keys.Enter:
+		return b.edit()
+	case keys.RuneKey:
+		switch string(key.Runes) {
+		case "k":
+			b.move(-1)
+		case "j":
+			b.move(1)
+		case "q":
+			return actionQuit
+		case "e":
+			return b.edit()
+		case "s":
+			b.nextSeverity()
+		case "r":
+			b.startInput("Rule", b.rule, func(value string) { b.rule = value })
+		case "p":
+			b.startInput("Path", b.path, func(value string) { b.path = value })
+		case "/":
+			b.startInput("Search", b.query, func(value string) { b.query = value })
+		case "c":
+			b.severity, b.rule, b.path, b.query = "", "", "", ""
+			b.applyFilters()
+		case "x":
+			b.suppressSelected()
+		}
+	}
+	return actionNone
+}
+
+func (b *browser) handleInput(key keys.Key) {
+	switch key.Code {
+	case keys.Enter:
+		b.input.apply(strings.TrimSpace(b.input.value))
+		b.input = nil
+		b.applyFilters()
+	case keys.Escape:
+		b.input = nil
+	case keys.Backspace, keys.CtrlH:
+		if runes := []rune(b.input.value); len(runes) > 0 {
+			b.input.value = string(runes[:len(runes)-1])
+		}
+	case keys.RuneKey, keys.Space:
+		b.input.value += string(key.Runes)
+	}
+}
+
+func (b *browser) startInput(label string, value string, apply func(value string)) {
+	b.input = &browserInput{label: label, value: value, apply: apply}
+}
+
+func (b *browser) nextSeverity() {
+	next := ""
+	for i, severity := range b.severities {
+		if b.severity == "" {
+			next = severity
+			break
+		}
+		if severity == b.severity && i+1 < len(b.severities) {
+			next = b.severities[i+1]
+			break
+		}
+	}
+	b.severity = next
+	b.applyFilters()
+}
+
+func (b *browser) edit() browserAction {
+	p, ok := b.selected()
+	if !ok || p.File == "" {
+		b.status = "The problem has no source location"
+		return actionNone
+	}
+	return actionEdit
+}
+
+func (b *browser) suppressSelected() {
+	p, ok := b.selected()
+	if !ok {
+		return
+	}
+	if b.suppressed[p.Id] {
+		b.status = fmt.Sprintf("%s is already suppressed in %s", p.RuleId, p.File)
+		return
+	}
+	if err := b.suppress(p); err != nil {
+		b.status = fmt.Sprintf("Failed to suppress %s: %s", p.RuleId, err)
+		return
+	}
+	for _, other := range b.problems {
+		if other.RuleId == p.RuleId && (other.File == p.File || p.File == "") {
+			b.suppressed[other.Id] = true
+		}
+	}
+	if p.File == "" {
+		b.status = fmt.Sprintf("%s is excluded from the analysis", p.RuleId)
+	} else {
+		b.status = fmt.Sprintf("%s is excluded from the analysis of %s", p.RuleId, p.File)
+	}
+}
+
+// render returns the lines of the screen of the given size.
+func (b *browser) render(width int, height int) []string {
+	listHeight := max(height-4, 1)
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	} else if b.cursor >= b.offset+listHeight {
+		b.offset = b.cursor - listHeight + 1
+	}
+
+	header := fmt.Sprintf("Qodana problems: %d of %d", len(b.visible), len(b.problems))
+	for _, filter := range [][2]string{{"severity", b.severity}, {"rule", b.rule}, {"path", b.path}, {"search", b.query}} {
+		if filter[1] != "" {
+			header += fmt.Sprintf("  [%s: %s]", filter[0], filter[1])
+		}
+	}
+	lines := []string{pterm.Bold.Sprint(truncate(header, width))}
+	for row := 0; row < listHeight; row++ {
+		i := b.offset + row
+		if i >= len(b.visible) {
+			lines = append(lines, "")
+			continue
+		}
+		lines = append(lines, b.renderProblem(b.problems[b.visible[i]], i == b.cursor, width))
+	}
+
+	details := ""
+	if p, ok := b.selected(); ok {
+		details = p.Message
+		if p.File != "" {
+			details = location(p) + ": " + details
+		}
+	}
+	lines = append(lines, "", truncate(details, width))
+	switch {
+	case b.input != nil:
+		lines = append(lines, truncate(fmt.Sprintf("%s: %s_  (Enter to apply, Esc to cancel)", b.input.label, b.input.value), width))
+	case b.status != "":
+		lines = append(lines, truncate(b.status, width))
+	default:
+		lines = append(lines, pterm.Gray(truncate("↑/↓ move  Enter open in $EDITOR  s severity  r rule  p path  / search  c clear  x suppress  q quit", width)))
+	}
+	return lines
+}
+
+func (b *browser) renderProblem(p Problem, selected bool, width int) string {
+	prefix := "  "
+	if selected {
+		prefix = "> "
+	}
+	severity := fmt.Sprintf("%-9s", p.Severity)
+	text := fmt.Sprintf("%s  %s  %s", p.RuleId, location(p), p.Message)
+	if b.suppressed[p.Id] {
+		text = "[suppressed] " + text
+	}
+	text = truncate(text, width-len(prefix)-len(severity)-1)
+	if selected {
+		return prefix + severityColor(p.Severity).Sprint(severity) + " " + pterm.Bold.Sprint(text)
+	}
+	if b.suppressed[p.Id] {
+		return prefix + pterm.Gray(severity+" "+text)
+	}
+	return prefix + severityColor(p.Severity).Sprint(severity) + " " + text
+}
+
+func severityColor(severity string) pterm.Color {
+	switch severity {
+	case "Critical", "High", "error":
+		return pterm.FgRed
+	case "Moderate", "warning":
+		return pterm.FgYellow
+	case "Low", "note":
+		return pterm.FgBlue
+	default:
+		return pterm.FgGray
+	}
+}
+
+func location(p Problem) string {
+	if p.Line == 0 {
+		return p.File
+	}
+	return p.File + ":" + strconv.Itoa(p.Line)
+}
+
+// truncate shortens the single-line text to width runes.
+func truncate(text string, width int) string {
+	text = strings.NewReplacer("\r\n", " ", "\n", " ", "\t", " ").Replace(text)
+	runes := []rune(text)
+	if width <= 0 {
+		return ""
+	}
+	if len(runes) <= width {
+		return text
+	}
+	return string(runes[:max(width-1, 0)]) + "…"
+}
+
+// editorCommand returns the command opening the file at the line in the editor ($VISUAL or $EDITOR command line).
+func editorCommand(editor string, file string, line int, column int) []string {
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+		if runtime.GOOS == "windows" {
+			args = []string{"notepad"}
+		}
+	}
+	line, column = max(line, 1), max(column, 1)
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(args[0])), ".exe")
+	switch name {
+	case "code", "code-insiders", "codium", "cursor":
+		return append(args, "-g", fmt.Sprintf("%s:%d:%d", file, line, column))
+	case "subl", "zed":
+		return append(args, fmt.Sprintf("%s:%d:%d", file, line, column))
+	case "idea", "idea64", "goland", "pycharm", "webstorm", "phpstorm", "rider", "clion", "rubymine", "fleet":
+		return append(args, "--line", strconv.Itoa(line), "--column", strconv.Itoa(column), file)
+	case "notepad":
+		return append(args, file)
+	default:
+		return append(args, fmt.Sprintf("+%d", line), file)
+	}
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"atomicgo.dev/keyboard/keys"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testBrowser(suppress func(p Problem) error) *browser {
+	return newBrowser(
+		[]Problem{
+			{Id: 0, RuleId: "UnusedImport", Severity: "Moderate", File: "src/A.java", Line: 3, Message: "Unused import"},
+			{Id: 1, RuleId: "ConstantValue", Severity: "High", File: "src/B.java", Line: 11, Message: "Condition is always true"},
+			{Id: 2, RuleId: "UnusedImport", Severity: "Moderate", File: "test/C.java", Line: 1, Message: "Unused import"},
+			{Id: 3, RuleId: "UnusedImport", Severity: "Moderate", File: "src/A.java", Line: 4, Message: "Unused import"},
+		},
+		suppress,
+	)
+}
+
+func press(b *browser, input ...interface{}) browserAction {
+	action := actionNone
+	for _, i := range input {
+		switch v := i.(type) {
+		case string:
+			for _, r := range v {
+				action = b.handle(keys.Key{Code: keys.RuneKey, Runes: []rune{r}})
+			}
+		case keys.KeyCode:
+			action = b.handle(keys.Key{Code: v})
+		}
+	}
+	return action
+}
+
+func TestBrowserFilters(t *testing.T) {
+	b := testBrowser(nil)
+	if strings.Join(b.severities, ",") != "High,Moderate" {
+		t.Errorf("unexpected severities %v", b.severities)
+	}
+	press(b, "s")
+	if b.severity != "High" || len(b.visible) != 1 {
+		t.Errorf("expected the High problems, got %s %v", b.severity, b.visible)
+	}
+	press(b, "s", "s")
+	if b.severity != "" || len(b.visible) != 4 {
+		t.Errorf("expected all problems, got %s %v", b.severity, b.visible)
+	}
+	press(b, "p", "src/", keys.Enter, "r", "unused", keys.Enter)
+	if len(b.visible) != 2 || b.visible[1] != 3 {
+		t.Errorf("unexpected filtered problems %v", b.visible)
+	}
+	press(b, "/", "nothing", keys.Escape)
+	if b.query != "" || len(b.visible) != 2 {
+		t.Errorf("expected the search to be canceled, got %q", b.query)
+	}
+	press(b, "/", "x", keys.Backspace, "import", keys.Enter)
+	if b.query != "import" || len(b.visible) != 2 {
+		t.Errorf("unexpected search %q %v", b.query, b.visible)
+	}
+	press(b, "c")
+	if len(b.visible) != 4 {
+		t.Errorf("expected the filters to be cleared, got %v", b.visible)
+	}
+}
+
+func TestBrowserNavigation(t *testing.T) {
+	b := testBrowser(nil)
+	press(b, keys.Down, "j", "j", "j", "j")
+	if p, _ := b.selected(); p.Id != 3 {
+		t.Errorf("expected the last problem, got %d", p.Id)
+	}
+	press(b, keys.Up, "k")
+	if p, _ := b.selected(); p.Id != 1 {
+		t.Errorf("expected the second problem, got %d", p.Id)
+	}
+	if press(b, keys.Enter) != actionEdit || press(b, "q") != actionQuit || press(b, keys.CtrlC) != actionQuit {
+		t.Errorf("unexpected actions")
+	}
+	press(b, "/", "missing", keys.Enter)
+	if press(b, keys.Enter) != actionNone || b.status == "" {
+		t.Errorf("expected nothing to open without problems")
+	}
+
+	b = testBrowser(nil)
+	lines := b.render(60, 8)
+	if len(lines) != 8 || !strings.Contains(lines[0], "4 of 4") || !strings.Contains(lines[1], "> ") {
+		t.Errorf("unexpected screen %q", lines)
+	}
+	press(b, keys.End)
+	b.render(60, 7)
+	if b.offset != 1 {
+		t.Errorf("expected the list to scroll, got offset %d", b.offset)
+	}
+}
+
+func TestBrowserSuppress(t *testing.T) {
+	var suppressed []Problem
+	b := testBrowser(
+		func(p Problem) error {
+			suppressed = append(suppressed, p)
+			return nil
+		},
+	)
+	press(b, "x", "x")
+	if len(suppressed) != 1 || !b.suppressed[0] || !b.suppressed[3] || b.suppressed[2] {
+		t.Errorf("unexpected suppressed problems %v", b.suppressed)
+	}
+	b = testBrowser(func(p Problem) error { return errors.New("read-only") })
+	press(b, "x")
+	if len(b.suppressed) != 0 || !strings.Contains(b.status, "read-only") {
+		t.Errorf("expected the failure to be shown, got %q", b.status)
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	for editor, expected := range map[string]string{
+		"vim":                 "vim +12 A.java",
+		"code --wait":         "code --wait -g A.java:12:5",
+		"/usr/local/bin/idea": "/usr/local/bin/idea --line 12 --column 5 A.java",
+		"subl":                "subl A.java:12:5",
+		"C:\\Tools\\nano.exe": "C:\\Tools\\nano.exe +12 A.java",
+	} {
+		if actual := strings.Join(editorCommand(editor, "A.java", 12, 5), " "); actual != expected {
+			t.Errorf("%s: expected %s, got %s", editor, expected, actual)
+		}
+	}
+	if actual := editorCommand("", "A.java", 0, 0); actual[len(actual)-2] != "+1" {
+		t.Errorf("unexpected default command %v", actual)
+	}
+}
@@ -0,0 +1,266 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// severityOrder is the order of the Qodana severities from the most severe one.
+var severityOrder = []string{"Critical", "High", "Moderate", "Low", "Info"}
+
+// Comparison is the difference between the problems of the base and the head results.
+type Comparison struct {
+	Base            string          `json:"base"`
+	Head            string          `json:"head"`
+	Added           []Problem       `json:"added"`
+	Resolved        []Problem       `json:"resolved"`
+	SeverityChanges []SeverityShift `json:"severityChanges"`
+	Rules           []Delta         `json:"rules"`
+	Severities      []Delta         `json:"severities"`
+}
+
+// Delta is the change of the number of problems of a rule or a severity.
+type Delta struct {
+	Name  string `json:"name"`
+	Base  int    `json:"base"`
+	Head  int    `json:"head"`
+	Delta int    `json:"delta"`
+}
+
+// SeverityShift is a problem found in both results with a different severity.
+type SeverityShift struct {
+	Problem Problem `json:"problem"`
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+}
+
+// SarifPath returns the SARIF report of the results directory, or path itself if it's a file.
+func SarifPath(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return filepath.Join(path, sarifName)
+	}
+	return path
+}
+
+// CompareResults compares the SARIF reports of the base and the head results directories (or SARIF files).
+func CompareResults(base string, head string) (*Comparison, error) {
+	baseProblems, err := LoadProblems(SarifPath(base))
+	if err != nil {
+		return nil, err
+	}
+	headProblems, err := LoadProblems(SarifPath(head))
+	if err != nil {
+		return nil, err
+	}
+	c := Compare(baseProblems, headProblems)
+	c.Base, c.Head = base, head
+	return c, nil
+}
+
+// Compare matches the problems by their fingerprints, or by the rule, the file and the message if there are no fingerprints.
+func Compare(base []Problem, head []Problem) *Comparison {
+	c := &Comparison{Added: []Problem{}, Resolved: []Problem{}, SeverityChanges: []SeverityShift{}}
+	unmatched := map[string][]Problem{}
+	for _, p := range base {
+		unmatched[p.key()] = append(unmatched[p.key()], p)
+	}
+	for _, p := range head {
+		candidates := unmatched[p.key()]
+		if len(candidates) == 0 {
+			c.Added = append(c.Added, p)
+			continue
+		}
+		matched := candidates[0]
+		unmatched[p.key()] = candidates[1:]
+		if matched.Severity != p.Severity {
+			c.SeverityChanges = append(c.SeverityChanges, SeverityShift{Problem: p, From: matched.Severity, To: p.Severity})
+		}
+	}
+	for _, p := range base {
+		if candidates := unmatched[p.key()]; len(candidates) > 0 && candidates[0].Id == p.Id {
+			c.Resolved = append(c.Resolved, p)
+			unmatched[p.key()] = candidates[1:]
+		}
+	}
+	c.Rules = deltas(base, head, func(p Problem) string { return p.RuleId })
+	sort.SliceStable(
+		c.Rules, func(i, j int) bool {
+			return abs(c.Rules[i].Delta) > abs(c.Rules[j].Delta)
+		},
+	)
+	c.Severities = deltas(base, head, func(p Problem) string { return p.Severity })
+	sort.SliceStable(
+		c.Severities, func(i, j int) bool {
+			return severityRank(c.Severities[i].Name) < severityRank(c.Severities[j].Name)
+		},
+	)
+	return c
+}
+
+func (p Problem) key() string {
+	if p.Fingerprint != "" {
+		return p.Fingerprint
+	}
+	return p.RuleId + "\x00" + p.File + "\x00" + p.Message
+}
+
+// deltas counts the problems by name in base and head, the names with unchanged counts are skipped. The deltas are sorted by name.
+func deltas(base []Problem, head []Problem, name func(Problem) string) []Delta {
+	counts := map[string]*Delta{}
+	count := func(p Problem) *Delta {
+		d, ok := counts[name(p)]
+		if !ok {
+			d = &Delta{Name: name(p)}
+			counts[name(p)] = d
+		}
+		return d
+	}
+	for _, p := range base {
+		count(p).Base++
+	}
+	for _, p := range head {
+		count(p).Head++
+	}
+	result := make([]Delta, 0)
+	for _, d := range counts {
+		d.Delta = d.Head - d.Base
+		if d.Delta != 0 {
+			result = append(result, *d)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// sortSeverities sorts the severities from the most severe one, unknown severities go last by name.
+func sortSeverities(severities []string) {
+	sort.Slice(
+		severities, func(i, j int) bool {
+			ri, rj := severityRank(severities[i]), severityRank(severities[j])
+			if ri != rj {
+				return ri < rj
+			}
+			return severities[i] < severities[j]
+		},
+	)
+}
+
+// SeveritiesAtLeast returns the severities as severe as the given one or more.
+func SeveritiesAtLeast(severity string) ([]string, error) {
+	for i, s := range severityOrder {
+		if strings.EqualFold(s, severity) {
+			return severityOrder[:i+1], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown severity %q, available values: %s", severity, strings.Join(severityOrder, ", "))
+}
+
+func severityRank(severity string) int {
+	for i, s := range severityOrder {
+		if s == severity {
+			return i
+		}
+	}
+	return len(severityOrder)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// CompareHandler serves the comparison page at / and the comparison as JSON at /comparison.json.
+func CompareHandler(c *Comparison) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(
+		"GET /comparison.json", func(w http.ResponseWriter, r *http.Request) {
+			writeJson(w, c)
+		},
+	)
+	mux.HandleFunc(
+		"GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if err := comparePage.Execute(w, c); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+		},
+	)
+	return mux
+}
+
+// WriteComparison writes the comparison as JSON to path.
+func WriteComparison(c *Comparison, path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+var comparePage = template.Must(
+	template.New("compare").Parse(
+		`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Qodana results comparison</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; margin: 2em; color: #19191c; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border-bottom: 1px solid #e0e0e0; padding: 4px 12px; text-align: left; }
+.added { color: #c62828; } .resolved { color: #2e7d32; } .path { color: #6b6b6b; }
+</style>
+</head>
+<body>
+<h1>Qodana results comparison</h1>
+<p><span class="path">{{.Base}}</span> &rarr; <span class="path">{{.Head}}</span></p>
+<p><b class="added">{{len .Added}} added</b>, <b class="resolved">{{len .Resolved}} resolved</b>, {{len .SeverityChanges}} changed severity</p>
+<h2>Severities</h2>
+<table><tr><th>Severity</th><th>Base</th><th>Head</th><th>Delta</th></tr>
+{{range .Severities}}<tr><td>{{.Name}}</td><td>{{.Base}}</td><td>{{.Head}}</td><td class="{{if gt .Delta 0}}added{{else}}resolved{{end}}">{{printf "%+d" .Delta}}</td></tr>
+{{else}}<tr><td colspan="4">No changes</td></tr>{{end}}</table>
+<h2>Rules</h2>
+<table><tr><th>Rule</th><th>Base</th><th>Head</th><th>Delta</th></tr>
+{{range .Rules}}<tr><td>{{.Name}}</td><td>{{.Base}}</td><td>{{.Head}}</td><td class="{{if gt .Delta 0}}added{{else}}resolved{{end}}">{{printf "%+d" .Delta}}</td></tr>
+{{else}}<tr><td colspan="4">No changes</td></tr>{{end}}</table>
+<h2 class="added">Added problems</h2>
+<table><tr><th>Severity</th><th>Rule</th><th>Location</th><th>Message</th></tr>
+{{range .Added}}<tr><td>{{.Severity}}</td><td>{{.RuleId}}</td><td class="path">{{.File}}:{{.Line}}</td><td>{{.Message}}</td></tr>
+{{else}}<tr><td colspan="4">None</td></tr>{{end}}</table>
+<h2 class="resolved">Resolved problems</h2>
+<table><tr><th>Severity</th><th>Rule</th><th>Location</th><th>Message</th></tr>
+{{range .Resolved}}<tr><td>{{.Severity}}</td><td>{{.RuleId}}</td><td class="path">{{.File}}:{{.Line}}</td><td>{{.Message}}</td></tr>
+{{else}}<tr><td colspan="4">None</td></tr>{{end}}</table>
+<h2>Severity changes</h2>
+<table><tr><th>From</th><th>To</th><th>Rule</th><th>Location</th><th>Message</th></tr>
+{{range .SeverityChanges}}<tr><td>{{.From}}</td><td>{{.To}}</td><td>{{.Problem.RuleId}}</td><td class="path">{{.Problem.File}}:{{.Problem.Line}}</td><td>{{.Problem.Message}}</td></tr>
+{{else}}<tr><td colspan="5">None</td></tr>{{end}}</table>
+</body>
+</html>
+`,
+	),
+)
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	base := []Problem{
+		{Id: 0, RuleId: "UnusedImport", Severity: "Moderate", Fingerprint: "a"},
+		{Id: 1, RuleId: "ConstantValue", Severity: "High", Fingerprint: "b"},
+		{Id: 2, RuleId: "UnusedImport", Severity: "Moderate", Fingerprint: "c"},
+		{Id: 3, RuleId: "Typo", Severity: "Low", File: "A.java", Message: "Typo"},
+	}
+	head := []Problem{
+		{Id: 0, RuleId: "UnusedImport", Severity: "High", Fingerprint: "a"},
+		{Id: 1, RuleId: "ConstantValue", Severity: "High", Fingerprint: "b"},
+		{Id: 2, RuleId: "ConstantValue", Severity: "High", Fingerprint: "d"},
+		{Id: 3, RuleId: "ConstantValue", Severity: "High", Fingerprint: "e"},
+		{Id: 4, RuleId: "Typo", Severity: "Low", File: "A.java", Message: "Typo"},
+	}
+	c := Compare(base, head)
+	if len(c.Added) != 2 || c.Added[0].Fingerprint != "d" || c.Added[1].Fingerprint != "e" {
+		t.Errorf("unexpected added problems %+v", c.Added)
+	}
+	if len(c.Resolved) != 1 || c.Resolved[0].Fingerprint != "c" {
+		t.Errorf("unexpected resolved problems %+v", c.Resolved)
+	}
+	if len(c.SeverityChanges) != 1 || c.SeverityChanges[0].From != "Moderate" || c.SeverityChanges[0].To != "High" {
+		t.Errorf("unexpected severity changes %+v", c.SeverityChanges)
+	}
+	expectedRules := []Delta{{"ConstantValue", 1, 3, 2}, {"UnusedImport", 2, 1, -1}}
+	if !equalDeltas(c.Rules, expectedRules) {
+		t.Errorf("expected %v, got %v", expectedRules, c.Rules)
+	}
+	expectedSeverities := []Delta{{"High", 1, 4, 3}, {"Moderate", 2, 0, -2}}
+	if !equalDeltas(c.Severities, expectedSeverities) {
+		t.Errorf("expected %v, got %v", expectedSeverities, c.Severities)
+	}
+}
+
+func equalDeltas(actual []Delta, expected []Delta) bool {
+	if len(actual) != len(expected) {
+		return false
+	}
+	for i := range actual {
+		if actual[i] != expected[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCompareResults(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(baseDir, sarifName), []byte(testSarif), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	headSarif := filepath.Join(t.TempDir(), "head.sarif.json")
+	if err := os.WriteFile(headSarif, []byte(`{"version": "2.1.0", "runs": [{"tool": {"driver": {"name": "QDJVM"}}, "results": []}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := CompareResults(baseDir, headSarif)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Resolved) != 3 || len(c.Added) != 0 {
+		t.Fatalf("unexpected comparison %+v", c)
+	}
+
+	svr := httptest.NewServer(CompareHandler(c))
+	defer svr.Close()
+	resp, err := http.Get(svr.URL + "/comparison.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := &Comparison{}
+	err = json.NewDecoder(resp.Body).Decode(served)
+	_ = resp.Body.Close()
+	if err != nil || len(served.Resolved) != 3 || served.Head != headSarif {
+		t.Errorf("unexpected served comparison %+v: %v", served, err)
+	}
+	resp, err = http.Get(svr.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if !strings.Contains(string(page), "3 resolved") || !strings.Contains(string(page), "Condition is always true") {
+		t.Errorf("unexpected page %s", page)
+	}
+
+	if _, err = CompareResults(t.TempDir(), headSarif); err == nil {
+		t.Errorf("expected an error for the directory without results")
+	}
+}
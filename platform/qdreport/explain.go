@@ -0,0 +1,145 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"html"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// inspectopediaUrl is the documentation of the JetBrains inspections, used for the rules without a help URI.
+const inspectopediaUrl = "https://www.jetbrains.com/help/inspectopedia/%s.html"
+
+var (
+	htmlBreaks   = regexp.MustCompile(`(?i)<br\s*/?>|</?p>|</li>|</?pre>|</?h\d>|</?ul>|</?ol>`)
+	htmlItems    = regexp.MustCompile(`(?i)<li>`)
+	htmlTags     = regexp.MustCompile(`<[^>]+>`)
+	extraNewLine = regexp.MustCompile(`\n{3,}`)
+)
+
+// RuleHelp is the documentation of a rule from the rules metadata of a SARIF report.
+type RuleHelp struct {
+	Id          string
+	Name        string
+	Severity    string
+	Categories  []string
+	Summary     string
+	Description string
+	HelpUri     string
+	// Problems are the problems of the rule in the report.
+	Problems []Problem
+}
+
+// ExplainRule returns the documentation of the rule from the SARIF report, nil with the ids of the rules
+// of the report similar to ruleId if the report doesn't have it.
+func ExplainRule(sarifPath string, ruleId string) (*RuleHelp, []string, error) {
+	data, err := os.ReadFile(sarifPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	report := &sarif.Report{}
+	if err = json.Unmarshal(data, report); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", sarifPath, err)
+	}
+	var similar []string
+	for _, run := range report.Runs {
+		components := run.Tool.Extensions
+		if run.Tool.Driver != nil {
+			components = append([]sarif.ToolComponent{*run.Tool.Driver}, components...)
+		}
+		for _, component := range components {
+			for i := range component.Rules {
+				rule := &component.Rules[i]
+				if rule.Id == ruleId {
+					help := toRuleHelp(rule, components)
+					help.Problems = ruleProblems(run.Results, ruleId)
+					if len(help.Problems) > 0 {
+						help.Severity = help.Problems[0].Severity
+					}
+					return help, nil, nil
+				}
+				if strings.Contains(strings.ToLower(rule.Id), strings.ToLower(ruleId)) {
+					similar = append(similar, rule.Id)
+				}
+			}
+		}
+	}
+	sort.Strings(similar)
+	return nil, similar, nil
+}
+
+func toRuleHelp(rule *sarif.ReportingDescriptor, components []sarif.ToolComponent) *RuleHelp {
+	help := &RuleHelp{Id: rule.Id, Name: rule.Name, HelpUri: rule.HelpUri}
+	if help.HelpUri == "" {
+		help.HelpUri = fmt.Sprintf(inspectopediaUrl, rule.Id)
+	}
+	if rule.DefaultConfiguration != nil {
+		if level, ok := rule.DefaultConfiguration.Level.(string); ok {
+			help.Severity = level
+		}
+	}
+	help.Summary = messageText(rule.ShortDescription)
+	for _, description := range []*sarif.MultiformatMessageString{rule.Help, rule.FullDescription} {
+		if text := messageText(description); text != "" && text != help.Summary {
+			help.Description = text
+			break
+		}
+	}
+	for _, relationship := range rule.Relationships {
+		if relationship.Target == nil {
+			continue
+		}
+		for _, component := range components {
+			for _, taxon := range component.Taxa {
+				if taxon.Id == relationship.Target.Id && taxon.Name != "" {
+					help.Categories = append(help.Categories, taxon.Name)
+				}
+			}
+		}
+	}
+	return help
+}
+
+func ruleProblems(results []sarif.Result, ruleId string) []Problem {
+	problems := make([]Problem, 0)
+	for i := range results {
+		if results[i].RuleId == ruleId {
+			problems = append(problems, toProblem(i, &results[i]))
+		}
+	}
+	return problems
+}
+
+// messageText returns the Markdown of the message, or its text with the HTML tags removed.
+func messageText(message *sarif.MultiformatMessageString) string {
+	if message == nil {
+		return ""
+	}
+	if message.Markdown != "" {
+		return strings.TrimSpace(message.Markdown)
+	}
+	text := htmlBreaks.ReplaceAllString(message.Text, "\n")
+	text = htmlItems.ReplaceAllString(text, "\n- ")
+	text = html.UnescapeString(htmlTags.ReplaceAllString(text, ""))
+	return strings.TrimSpace(extraNewLine.ReplaceAllString(text, "\n\n"))
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const explainSarif = `{
+  "runs": [{
+    "tool": {
+      "driver": {
+        "name": "QDJVM",
+        "taxa": [{"id": "Java/Probable bugs", "name": "Probable bugs"}],
+        "rules": [{
+          "id": "ConstantValue",
+          "shortDescription": {"text": "Constant values"},
+          "fullDescription": {"text": "Reports expressions that always have the same value.<p>Example:</p><pre><code>if (x &gt; 0 &amp;&amp; x &lt; 0) {}</code></pre>"},
+          "defaultConfiguration": {"level": "warning"},
+          "relationships": [{"target": {"id": "Java/Probable bugs"}}]
+        }]
+      },
+      "extensions": [{
+        "name": "org.jetbrains.kotlin",
+        "rules": [{"id": "ConstantConditionIf", "shortDescription": {"text": "Condition of if is constant"}}]
+      }]
+    },
+    "results": [
+      {"ruleId": "ConstantValue", "message": {"text": "Condition is always false"}, "properties": {"qodanaSeverity": "High"},
+       "locations": [{"physicalLocation": {"artifactLocation": {"uri": "A.java"}, "region": {"startLine": 3}}}]},
+      {"ruleId": "UnusedImport", "message": {"text": "Unused import"}}
+    ]
+  }]
+}`
+
+func TestExplainRule(t *testing.T) {
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	if err := os.WriteFile(sarifPath, []byte(explainSarif), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	help, _, err := ExplainRule(sarifPath, "ConstantValue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedDescription := "Reports expressions that always have the same value.\nExample:\n\nif (x > 0 && x < 0) {}"
+	if help.Summary != "Constant values" || help.Description != expectedDescription {
+		t.Errorf("unexpected description %q, %q", help.Summary, help.Description)
+	}
+	if help.Severity != "High" || !reflect.DeepEqual(help.Categories, []string{"Probable bugs"}) {
+		t.Errorf("unexpected severity %s or categories %v", help.Severity, help.Categories)
+	}
+	if help.HelpUri != "https://www.jetbrains.com/help/inspectopedia/ConstantValue.html" {
+		t.Errorf("unexpected help URI %s", help.HelpUri)
+	}
+	if len(help.Problems) != 1 || help.Problems[0].File != "A.java" || help.Problems[0].Line != 3 {
+		t.Errorf("unexpected problems %+v", help.Problems)
+	}
+
+	help, similar, err := ExplainRule(sarifPath, "constant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if help != nil || !reflect.DeepEqual(similar, []string{"ConstantConditionIf", "ConstantValue"}) {
+		t.Errorf("expected the similar rules, got %+v, %v", help, similar)
+	}
+}
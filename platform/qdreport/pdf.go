@@ -0,0 +1,154 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// the page of the PDF documents is A4 in points
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 50.0
+)
+
+// rgb is a color with the components from 0 to 1.
+type rgb [3]float64
+
+var (
+	pdfBlack = rgb{0.1, 0.1, 0.1}
+	pdfGray  = rgb{0.45, 0.45, 0.45}
+	pdfWhite = rgb{1, 1, 1}
+)
+
+// pdfDocument is a minimal PDF writer, the pages are drawn with text of the standard Helvetica fonts and filled
+// rectangles from the top of the page to the bottom.
+type pdfDocument struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func newPdfDocument() *pdfDocument {
+	d := &pdfDocument{}
+	d.newPage()
+	return d
+}
+
+func (d *pdfDocument) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pdfPageHeight - pdfMargin
+}
+
+// ensure starts a new page if the height does not fit on the current one.
+func (d *pdfDocument) ensure(height float64) {
+	if d.y-height < pdfMargin {
+		d.newPage()
+	}
+}
+
+func (d *pdfDocument) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// text draws the text with its baseline at y.
+func (d *pdfDocument) text(x float64, y float64, size float64, bold bool, color rgb, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	_, _ = fmt.Fprintf(
+		d.page(),
+		"BT %.3f %.3f %.3f rg /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+		color[0], color[1], color[2], font, size, x, y, pdfString(text),
+	)
+}
+
+// rect fills the rectangle with its bottom left corner at x, y.
+func (d *pdfDocument) rect(x float64, y float64, width float64, height float64, color rgb) {
+	_, _ = fmt.Fprintf(
+		d.page(),
+		"%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n",
+		color[0], color[1], color[2], x, y, width, height,
+	)
+}
+
+// pdfString returns the text as a PDF string literal in WinAnsiEncoding, the characters out of Latin-1 are replaced with ?.
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r >= ' ' && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// truncatePdf shortens the text to fit the width, the width of Helvetica characters is about a half of the font size.
+func truncatePdf(text string, width float64, size float64) string {
+	maxChars := int(width / (size * 0.5))
+	runes := []rune(text)
+	if len(runes) <= maxChars || maxChars < 4 {
+		return text
+	}
+	return string(runes[:maxChars-3]) + "..."
+}
+
+// write writes the document with the cross-reference table of the objects.
+func (d *pdfDocument) write(w io.Writer) error {
+	out := &bytes.Buffer{}
+	var offsets []int
+	object := func(content string) {
+		offsets = append(offsets, out.Len())
+		_, _ = fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", len(offsets), content)
+	}
+	out.WriteString("%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range d.pages {
+		object(
+			fmt.Sprintf(
+				"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, 6+2*i,
+			),
+		)
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+	xref := out.Len()
+	_, _ = fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		_, _ = fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	_, _ = fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	_, err := w.Write(out.Bytes())
+	return err
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ServeOptions restrict the access to the served report.
+type ServeOptions struct {
+	// Host is the address to listen on, all interfaces if empty.
+	Host string
+	// BasicAuth is user:password required with HTTP basic authentication, QODANA_SERVE_BASIC_AUTH if empty.
+	BasicAuth string
+	// Token is required as a bearer token or as the basic authentication password of any user, QODANA_SERVE_TOKEN if empty.
+	Token string
+	// TlsCert and TlsKey are the PEM files of the certificate and the key to serve HTTPS with.
+	TlsCert string
+	TlsKey  string
+}
+
+// WithEnv returns the options with the credentials not set falling back to the environment variables.
+func (o ServeOptions) WithEnv() ServeOptions {
+	if o.BasicAuth == "" {
+		o.BasicAuth = os.Getenv(qdenv.QodanaServeBasicAuthEnv)
+	}
+	if o.Token == "" {
+		o.Token = os.Getenv(qdenv.QodanaServeTokenEnv)
+	}
+	return o
+}
+
+// Validate checks that the options are consistent.
+func (o ServeOptions) Validate() error {
+	if (o.TlsCert == "") != (o.TlsKey == "") {
+		return errors.New("both the TLS certificate and the TLS key must be set to serve HTTPS")
+	}
+	if o.BasicAuth != "" && !strings.Contains(o.BasicAuth, ":") {
+		return errors.New("basic authentication credentials must be set as user:password")
+	}
+	return nil
+}
+
+// Url returns the URL the report is served at.
+func (o ServeOptions) Url(port int) string {
+	scheme := "http"
+	if o.TlsCert != "" {
+		scheme = "https"
+	}
+	host := o.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s://%s/", scheme, net.JoinHostPort(host, strconv.Itoa(port)))
+}
+
+// Protect requires the credentials of the options for the requests to h, h is returned as is if no credentials are set.
+func (o ServeOptions) Protect(h http.Handler) http.Handler {
+	if o.BasicAuth == "" && o.Token == "" {
+		return h
+	}
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if !o.authorized(r) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="Qodana report", charset="UTF-8"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			h.ServeHTTP(w, r)
+		},
+	)
+}
+
+func (o ServeOptions) authorized(r *http.Request) bool {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return o.Token != "" && secretEqual(token, o.Token)
+	}
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	if o.Token != "" && secretEqual(password, o.Token) {
+		return true
+	}
+	return o.BasicAuth != "" && secretEqual(user+":"+password, o.BasicAuth)
+}
+
+func secretEqual(actual string, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(actual), []byte(expected)) == 1
+}
+
+// Listen opens the listener on the port.
+func (o ServeOptions) Listen(port int) (net.Listener, error) {
+	return net.Listen("tcp", net.JoinHostPort(o.Host, strconv.Itoa(port)))
+}
+
+// Serve serves h on the listener until it fails, over HTTPS if the certificate is set.
+func (o ServeOptions) Serve(l net.Listener, h http.Handler) error {
+	server := &http.Server{Handler: o.Protect(h)}
+	if o.TlsCert != "" {
+		return server.ServeTLS(l, o.TlsCert, o.TlsKey)
+	}
+	return server.Serve(l)
+}
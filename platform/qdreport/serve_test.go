@@ -0,0 +1,149 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProtect(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	options := ServeOptions{BasicAuth: "qodana:password", Token: "token"}
+	for name, c := range map[string]struct {
+		authorize func(r *http.Request)
+		status    int
+	}{
+		"none":           {func(r *http.Request) {}, http.StatusUnauthorized},
+		"basic":          {func(r *http.Request) { r.SetBasicAuth("qodana", "password") }, http.StatusOK},
+		"wrong password": {func(r *http.Request) { r.SetBasicAuth("qodana", "wrong") }, http.StatusUnauthorized},
+		"token password": {func(r *http.Request) { r.SetBasicAuth("anyone", "token") }, http.StatusOK},
+		"bearer":         {func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, http.StatusOK},
+		"wrong bearer":   {func(r *http.Request) { r.Header.Set("Authorization", "Bearer password") }, http.StatusUnauthorized},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/index.html", nil)
+		c.authorize(r)
+		w := httptest.NewRecorder()
+		options.Protect(ok).ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Errorf("%s: expected %d, got %d", name, c.status, w.Code)
+		}
+		if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: expected the authentication challenge", name)
+		}
+	}
+	if h := (ServeOptions{}).Protect(ok); h == nil {
+		t.Errorf("expected the handler to be served without credentials")
+	}
+}
+
+func TestServeOptions(t *testing.T) {
+	t.Setenv("QODANA_SERVE_TOKEN", "from-env")
+	if options := (ServeOptions{}).WithEnv(); options.Token != "from-env" {
+		t.Errorf("expected the token from the environment, got %q", options.Token)
+	}
+	if options := (ServeOptions{Token: "flag"}).WithEnv(); options.Token != "flag" {
+		t.Errorf("expected the token from the flag, got %q", options.Token)
+	}
+	if err := (ServeOptions{TlsCert: "cert.pem"}).Validate(); err == nil {
+		t.Errorf("expected the key to be required")
+	}
+	if err := (ServeOptions{BasicAuth: "qodana"}).Validate(); err == nil {
+		t.Errorf("expected the password to be required")
+	}
+	if url := (ServeOptions{}).Url(8080); url != "http://localhost:8080/" {
+		t.Errorf("unexpected URL %s", url)
+	}
+	if url := (ServeOptions{Host: "::1", TlsCert: "cert.pem"}).Url(8443); url != "https://[::1]:8443/" {
+		t.Errorf("unexpected URL %s", url)
+	}
+}
+
+func TestServeTls(t *testing.T) {
+	certFile, keyFile, pool := writeCertificate(t)
+	options := ServeOptions{Host: "127.0.0.1", Token: "token", TlsCert: certFile, TlsKey: keyFile}
+	listener, err := options.Listen(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = options.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	}()
+	defer func() { _ = listener.Close() }()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	request, _ := http.NewRequest(http.MethodGet, options.Url(listener.Addr().(*net.TCPAddr).Port), nil)
+	request.Header.Set("Authorization", "Bearer token")
+	resp, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the report to be served, got %d", resp.StatusCode)
+	}
+}
+
+func writeCertificate(t *testing.T) (string, string, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "qodana"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
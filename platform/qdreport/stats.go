@@ -0,0 +1,226 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Stats are the hotspots of the problems, to prioritize the refactorings.
+type Stats struct {
+	Problems    int          `json:"problems"`
+	Files       []FileStats  `json:"files"`
+	Rules       []Count      `json:"rules"`
+	Regressions []Regression `json:"regressions"`
+}
+
+// FileStats is the number of problems in a file, and per 1000 lines if the file is found in the project.
+type FileStats struct {
+	File     string  `json:"file"`
+	Problems int     `json:"problems"`
+	Lines    int     `json:"lines,omitempty"`
+	Density  float64 `json:"density,omitempty"`
+}
+
+// Regression is the number of problems of a rule added and resolved since the baseline.
+type Regression struct {
+	RuleId   string `json:"ruleId"`
+	New      int    `json:"new"`
+	Resolved int    `json:"resolved"`
+	Delta    int    `json:"delta"`
+}
+
+// TreemapNode is a directory or a file of the treemap, the value is the number of problems in it.
+type TreemapNode struct {
+	Name     string         `json:"name"`
+	Value    int            `json:"value"`
+	Children []*TreemapNode `json:"children,omitempty"`
+}
+
+// ComputeStats returns the top files by the problem density, the top rules and the rules with the largest regressions
+// since the baseline. The lines of the files are counted in projectDir, the files not found there go after the others
+// sorted by the number of problems.
+func ComputeStats(problems []Problem, projectDir string, top int) *Stats {
+	s := &Stats{}
+	files := map[string]int{}
+	rules := map[string]int{}
+	regressions := map[string]*Regression{}
+	for _, p := range problems {
+		if p.BaselineState == baselineNew || p.BaselineState == baselineAbsent {
+			r, ok := regressions[p.RuleId]
+			if !ok {
+				r = &Regression{RuleId: p.RuleId}
+				regressions[p.RuleId] = r
+			}
+			if p.BaselineState == baselineNew {
+				r.New++
+				r.Delta++
+			} else {
+				r.Resolved++
+				r.Delta--
+			}
+		}
+		if p.BaselineState == baselineAbsent {
+			continue
+		}
+		s.Problems++
+		rules[p.RuleId]++
+		if file := problemFile(p); file != "" {
+			files[file]++
+		}
+	}
+
+	for file, count := range files {
+		stats := FileStats{File: file, Problems: count}
+		if lines, err := countLines(filepath.Join(projectDir, filepath.FromSlash(file))); err == nil && lines > 0 {
+			stats.Lines = lines
+			stats.Density = float64(count) * 1000 / float64(lines)
+		}
+		s.Files = append(s.Files, stats)
+	}
+	sort.Slice(
+		s.Files, func(i, j int) bool {
+			a, b := s.Files[i], s.Files[j]
+			if a.Density != b.Density {
+				return a.Density > b.Density
+			}
+			if a.Problems != b.Problems {
+				return a.Problems > b.Problems
+			}
+			return a.File < b.File
+		},
+	)
+	s.Files = s.Files[:min(top, len(s.Files))]
+
+	s.Rules = sortedCounts(rules)
+	s.Rules = s.Rules[:min(top, len(s.Rules))]
+
+	s.Regressions = make([]Regression, 0)
+	for _, r := range regressions {
+		if r.Delta > 0 {
+			s.Regressions = append(s.Regressions, *r)
+		}
+	}
+	sort.Slice(
+		s.Regressions, func(i, j int) bool {
+			if s.Regressions[i].Delta != s.Regressions[j].Delta {
+				return s.Regressions[i].Delta > s.Regressions[j].Delta
+			}
+			return s.Regressions[i].RuleId < s.Regressions[j].RuleId
+		},
+	)
+	s.Regressions = s.Regressions[:min(top, len(s.Regressions))]
+	return s
+}
+
+// Treemap returns the tree of the directories and the files with the number of their problems, e.g. for the treemap
+// charts of d3 or ECharts. The problems without a file are counted in the root only.
+func Treemap(problems []Problem) *TreemapNode {
+	root := &TreemapNode{Name: "."}
+	children := map[*TreemapNode]map[string]*TreemapNode{}
+	for _, p := range problems {
+		if p.BaselineState == baselineAbsent {
+			continue
+		}
+		root.Value++
+		file := problemFile(p)
+		if file == "" {
+			continue
+		}
+		node := root
+		for _, name := range strings.Split(file, "/") {
+			if children[node] == nil {
+				children[node] = map[string]*TreemapNode{}
+			}
+			child, ok := children[node][name]
+			if !ok {
+				child = &TreemapNode{Name: name}
+				children[node][name] = child
+				node.Children = append(node.Children, child)
+			}
+			child.Value++
+			node = child
+		}
+	}
+	sortTreemap(root)
+	return root
+}
+
+// sortTreemap orders the children from the largest one.
+func sortTreemap(node *TreemapNode) {
+	sort.SliceStable(
+		node.Children, func(i, j int) bool {
+			if node.Children[i].Value != node.Children[j].Value {
+				return node.Children[i].Value > node.Children[j].Value
+			}
+			return node.Children[i].Name < node.Children[j].Name
+		},
+	)
+	for _, child := range node.Children {
+		sortTreemap(child)
+	}
+}
+
+// WriteTreemap writes the treemap as JSON to path.
+func WriteTreemap(node *TreemapNode, path string) error {
+	data, err := json.MarshalIndent(node, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// problemFile returns the project-relative path of the file of the problem.
+func problemFile(p Problem) string {
+	return strings.TrimPrefix(strings.TrimPrefix(p.File, "file://"), "./")
+}
+
+func countLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = f.Close() }()
+	lines := 0
+	last := byte('\n')
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			lines += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	// the last line without the line break
+	if last != '\n' {
+		lines++
+	}
+	return lines, nil
+}
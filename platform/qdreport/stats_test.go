@@ -0,0 +1,94 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComputeStats(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectDir, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "src", "A.java"), []byte(strings.Repeat("a\n", 1000)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "src", "B.java"), []byte("b\nb\nb\nb"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	problems := []Problem{
+		{RuleId: "UnusedImport", File: "src/A.java", BaselineState: "new"},
+		{RuleId: "UnusedImport", File: "src/A.java", BaselineState: "new"},
+		{RuleId: "UnusedImport", File: "src/A.java", BaselineState: "unchanged"},
+		{RuleId: "Typo", File: "file://src/B.java", BaselineState: "new"},
+		{RuleId: "Typo", File: "src/B.java", BaselineState: "absent"},
+		{RuleId: "Typo", File: "src/B.java", BaselineState: "absent"},
+		{RuleId: "ConstantValue", File: "README.md"},
+		{RuleId: "ConstantValue", File: "README.md"},
+	}
+	s := ComputeStats(problems, projectDir, 2)
+	if s.Problems != 6 {
+		t.Errorf("expected 6 problems, got %d", s.Problems)
+	}
+	expectedFiles := []FileStats{
+		{File: "src/B.java", Problems: 1, Lines: 4, Density: 250},
+		{File: "src/A.java", Problems: 3, Lines: 1000, Density: 3},
+	}
+	if !reflect.DeepEqual(s.Files, expectedFiles) {
+		t.Errorf("expected %+v, got %+v", expectedFiles, s.Files)
+	}
+	expectedRules := []Count{{"UnusedImport", 3}, {"ConstantValue", 2}}
+	if !reflect.DeepEqual(s.Rules, expectedRules) {
+		t.Errorf("expected %v, got %v", expectedRules, s.Rules)
+	}
+	expectedRegressions := []Regression{{RuleId: "UnusedImport", New: 2, Delta: 2}}
+	if !reflect.DeepEqual(s.Regressions, expectedRegressions) {
+		t.Errorf("expected %+v, got %+v", expectedRegressions, s.Regressions)
+	}
+}
+
+func TestTreemap(t *testing.T) {
+	problems := []Problem{
+		{File: "src/main/A.java"},
+		{File: "src/main/A.java"},
+		{File: "src/test/B.java"},
+		{File: "README.md", BaselineState: "absent"},
+		{},
+	}
+	expected := &TreemapNode{
+		Name:  ".",
+		Value: 4,
+		Children: []*TreemapNode{
+			{
+				Name:  "src",
+				Value: 3,
+				Children: []*TreemapNode{
+					{Name: "main", Value: 2, Children: []*TreemapNode{{Name: "A.java", Value: 2}}},
+					{Name: "test", Value: 1, Children: []*TreemapNode{{Name: "B.java", Value: 1}}},
+				},
+			},
+		},
+	}
+	if actual := Treemap(problems); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected treemap %+v", actual)
+	}
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+const (
+	// GatePassed and GateFailed are the quality gate statuses of the summary, any other status is shown as is.
+	GatePassed = "passed"
+	GateFailed = "failed"
+
+	baselineAbsent = "absent"
+	baselineNew    = "new"
+)
+
+// ExecutiveSummary is the summary of the results of a run for the people who don't read the problems one by one.
+type ExecutiveSummary struct {
+	Project    string
+	Generated  time.Time
+	Gate       string
+	Total      int
+	New        int
+	Resolved   int
+	Severities []Count
+	TopRules   []Count
+	Trend      []Count
+}
+
+// severityColors are the colors of the severity bars, the bars of the other severities and of the rules are pdfAccent.
+var severityColors = map[string]rgb{
+	"Critical": {0.80, 0.15, 0.15},
+	"High":     {0.93, 0.45, 0.13},
+	"Moderate": {0.95, 0.74, 0.15},
+	"Low":      {0.25, 0.55, 0.85},
+	"Info":     {0.60, 0.60, 0.60},
+}
+
+var (
+	pdfAccent    = rgb{0.42, 0.25, 0.85}
+	pdfGateColor = map[string]rgb{GatePassed: {0.18, 0.62, 0.30}, "": {0.55, 0.55, 0.55}}
+	pdfGateFail  = rgb{0.80, 0.15, 0.15}
+)
+
+// Count is the number of problems of a severity, a rule or a run.
+type Count struct {
+	Name  string
+	Count int
+}
+
+// Run is the problems of a run of the trend.
+type Run struct {
+	Name     string
+	Problems []Problem
+}
+
+// Summarize returns the summary of the problems with the topRules rules with the most problems, the trend is
+// the number of problems of the earlier runs followed by the current one. The problems resolved since the baseline
+// are counted only as resolved.
+func Summarize(project string, gate string, problems []Problem, history []Run, topRules int) *ExecutiveSummary {
+	s := &ExecutiveSummary{Project: project, Generated: time.Now(), Gate: gate}
+	severities := map[string]int{}
+	rules := map[string]int{}
+	for _, p := range problems {
+		switch p.BaselineState {
+		case baselineAbsent:
+			s.Resolved++
+			continue
+		case baselineNew, "":
+			s.New++
+		}
+		s.Total++
+		severities[p.Severity]++
+		rules[p.RuleId]++
+	}
+	for _, severity := range severityOrder {
+		s.Severities = append(s.Severities, Count{Name: severity, Count: severities[severity]})
+		delete(severities, severity)
+	}
+	s.Severities = append(s.Severities, sortedCounts(severities)...)
+	s.TopRules = sortedCounts(rules)
+	if len(s.TopRules) > topRules {
+		s.TopRules = s.TopRules[:topRules]
+	}
+	for _, run := range history {
+		s.Trend = append(s.Trend, Count{Name: run.Name, Count: countPresent(run.Problems)})
+	}
+	if len(s.Trend) > 0 {
+		s.Trend = append(s.Trend, Count{Name: "current", Count: s.Total})
+	}
+	return s
+}
+
+// sortedCounts returns the counts by name from the largest one, the equal ones sorted by name.
+func sortedCounts(counts map[string]int) []Count {
+	sorted := make([]Count, 0, len(counts))
+	for name, count := range counts {
+		sorted = append(sorted, Count{Name: name, Count: count})
+	}
+	sort.Slice(
+		sorted, func(i, j int) bool {
+			if sorted[i].Count != sorted[j].Count {
+				return sorted[i].Count > sorted[j].Count
+			}
+			return sorted[i].Name < sorted[j].Name
+		},
+	)
+	return sorted
+}
+
+func countPresent(problems []Problem) int {
+	count := 0
+	for _, p := range problems {
+		if p.BaselineState != baselineAbsent {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteSummaryPdf renders the summary as a PDF document: the quality gate status, the problems by severity,
+// the top rules and the trend.
+func WriteSummaryPdf(s *ExecutiveSummary, w io.Writer) error {
+	d := newPdfDocument()
+	d.text(pdfMargin, d.y-22, 22, true, pdfBlack, "Qodana summary")
+	d.y -= 40
+	d.text(pdfMargin, d.y, 10, false, pdfGray, fmt.Sprintf("%s, generated %s", s.Project, s.Generated.Format("2006-01-02 15:04")))
+	d.y -= 42
+
+	gate, gateColor := s.Gate, pdfGateFail
+	if color, ok := pdfGateColor[gate]; ok {
+		gateColor = color
+	}
+	if gate == "" {
+		gate = "unknown"
+	}
+	d.rect(pdfMargin, d.y-9, pdfPageWidth-2*pdfMargin, 30, gateColor)
+	d.text(pdfMargin+12, d.y, 14, true, pdfWhite, "Quality gate: "+gate)
+	d.y -= 36
+	d.text(
+		pdfMargin, d.y, 12, false, pdfBlack,
+		fmt.Sprintf("Problems: %d    New: %d    Resolved: %d", s.Total, s.New, s.Resolved),
+	)
+	d.y -= 18
+
+	drawBars(d, "Problems by severity", s.Severities, severityColors)
+	drawBars(d, "Top rules", s.TopRules, nil)
+	drawTrend(d, s.Trend)
+	return d.write(w)
+}
+
+// drawBars draws the counts as horizontal bars with their names on the left.
+func drawBars(d *pdfDocument, title string, counts []Count, colors map[string]rgb) {
+	const rowHeight, labelWidth, barWidth = 18.0, 180.0, 260.0
+	d.ensure(40 + rowHeight)
+	d.y -= 30
+	d.text(pdfMargin, d.y, 14, true, pdfBlack, title)
+	d.y -= 8
+	if len(counts) == 0 {
+		d.y -= rowHeight
+		d.text(pdfMargin, d.y, 10, false, pdfGray, "No problems")
+		return
+	}
+	maxCount := 1
+	for _, c := range counts {
+		maxCount = max(maxCount, c.Count)
+	}
+	for _, c := range counts {
+		d.ensure(rowHeight)
+		d.y -= rowHeight
+		d.text(pdfMargin, d.y, 10, false, pdfBlack, truncatePdf(c.Name, labelWidth-10, 10))
+		color, ok := colors[c.Name]
+		if !ok {
+			color = pdfAccent
+		}
+		width := barWidth * float64(c.Count) / float64(maxCount)
+		if width > 0 {
+			d.rect(pdfMargin+labelWidth, d.y-2, width, 12, color)
+		}
+		d.text(pdfMargin+labelWidth+width+6, d.y, 10, false, pdfBlack, fmt.Sprint(c.Count))
+	}
+}
+
+// drawTrend draws the number of problems of the runs as vertical bars with the names of the runs below.
+func drawTrend(d *pdfDocument, trend []Count) {
+	const chartHeight, maxColumnWidth = 110.0, 60.0
+	d.ensure(70 + chartHeight)
+	d.y -= 30
+	d.text(pdfMargin, d.y, 14, true, pdfBlack, "Trend")
+	if len(trend) == 0 {
+		d.y -= 26
+		d.text(pdfMargin, d.y, 10, false, pdfGray, "No earlier runs")
+		return
+	}
+	columnWidth := min(maxColumnWidth, (pdfPageWidth-2*pdfMargin)/float64(len(trend)))
+	maxCount := 1
+	for _, c := range trend {
+		maxCount = max(maxCount, c.Count)
+	}
+	bottom := d.y - 24 - chartHeight
+	for i, c := range trend {
+		x := pdfMargin + float64(i)*columnWidth
+		height := chartHeight * float64(c.Count) / float64(maxCount)
+		if height > 0 {
+			d.rect(x+columnWidth*0.2, bottom, columnWidth*0.6, height, pdfAccent)
+		}
+		d.text(x+columnWidth*0.2, bottom+height+4, 9, false, pdfBlack, fmt.Sprint(c.Count))
+		d.text(x+columnWidth*0.1, bottom-12, 8, false, pdfGray, truncatePdf(c.Name, columnWidth*0.9, 8))
+	}
+	d.y = bottom - 12
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	problems := []Problem{
+		{RuleId: "UnusedImport", Severity: "Moderate", BaselineState: "new"},
+		{RuleId: "UnusedImport", Severity: "Moderate", BaselineState: "unchanged"},
+		{RuleId: "ConstantValue", Severity: "High"},
+		{RuleId: "Typo", Severity: "note"},
+		{RuleId: "ConstantValue", Severity: "High", BaselineState: "absent"},
+	}
+	history := []Run{
+		{Name: "week 1", Problems: []Problem{{RuleId: "Typo"}, {RuleId: "Typo"}, {RuleId: "Typo"}, {RuleId: "Typo", BaselineState: "absent"}}},
+	}
+	s := Summarize("project", GatePassed, problems, history, 2)
+	if s.Total != 4 || s.New != 3 || s.Resolved != 1 {
+		t.Errorf("unexpected totals %d, %d new, %d resolved", s.Total, s.New, s.Resolved)
+	}
+	expectedSeverities := []Count{{"Critical", 0}, {"High", 1}, {"Moderate", 2}, {"Low", 0}, {"Info", 0}, {"note", 1}}
+	if !reflect.DeepEqual(s.Severities, expectedSeverities) {
+		t.Errorf("expected %v, got %v", expectedSeverities, s.Severities)
+	}
+	expectedRules := []Count{{"UnusedImport", 2}, {"ConstantValue", 1}}
+	if !reflect.DeepEqual(s.TopRules, expectedRules) {
+		t.Errorf("expected %v, got %v", expectedRules, s.TopRules)
+	}
+	expectedTrend := []Count{{"week 1", 3}, {"current", 4}}
+	if !reflect.DeepEqual(s.Trend, expectedTrend) {
+		t.Errorf("expected %v, got %v", expectedTrend, s.Trend)
+	}
+}
+
+func TestWriteSummaryPdf(t *testing.T) {
+	rules := make([]Problem, 0)
+	for i := 0; i < 60; i++ {
+		rules = append(rules, Problem{RuleId: "Rule" + strconv.Itoa(i), Severity: "Low"})
+	}
+	s := Summarize("project (main)", GateFailed, rules, []Run{{Name: "before", Problems: rules[:10]}}, 60)
+	var out bytes.Buffer
+	if err := WriteSummaryPdf(s, &out); err != nil {
+		t.Fatal(err)
+	}
+	pdf := out.String()
+	if !strings.HasPrefix(pdf, "%PDF-1.4\n") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatalf("not a PDF document:\n%s", pdf)
+	}
+	for _, expected := range []string{"(Quality gate: failed)", `(project \(main\), generated `, "(Rule59)", "(before)"} {
+		if !strings.Contains(pdf, expected) {
+			t.Errorf("%s not found in the document", expected)
+		}
+	}
+	if pages := strings.Count(pdf, "/Type /Page "); pages < 2 {
+		t.Errorf("expected the rules to continue on the next page, got %d pages", pages)
+	}
+
+	// every object of the cross-reference table starts at its offset
+	xref := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(pdf, -1)
+	for i, offset := range xref {
+		position, _ := strconv.Atoi(offset[1])
+		if !strings.HasPrefix(pdf[position:], strconv.Itoa(i+1)+" 0 obj") {
+			t.Errorf("object %d is not at offset %d", i+1, position)
+		}
+	}
+	startXref := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(pdf)
+	if position, _ := strconv.Atoi(startXref[1]); !strings.HasPrefix(pdf[position:], "xref\n") {
+		t.Errorf("startxref %d does not point to the cross-reference table", position)
+	}
+}
+
+func TestPdfString(t *testing.T) {
+	if actual := pdfString(`a (b) \ café → ✓`); actual != "a \\(b\\) \\\\ caf\xe9 ? ?" {
+		t.Errorf("unexpected PDF string %q", actual)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdreport
+
+import (
+	"atomicgo.dev/keyboard"
+	"atomicgo.dev/keyboard/keys"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/pterm/pterm"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	enterAlternateScreen = "\033[?1049h"
+	leaveAlternateScreen = "\033[?1049l"
+	clearScreen          = "\033[H\033[2J"
+)
+
+// Browse shows the problems of the SARIF report in the terminal UI. The problems are opened in $VISUAL or $EDITOR
+// from projectDir, and suppressed by excluding their rule for their file in the qodana.yaml file of projectDir.
+func Browse(sarifPath string, projectDir string, configName string) error {
+	problems, err := LoadProblems(sarifPath)
+	if err != nil {
+		return err
+	}
+	b := newBrowser(
+		problems, func(p Problem) error {
+			return qdyaml.AddExclude(projectDir, configName, p.RuleId, p.File)
+		},
+	)
+	fmt.Print(enterAlternateScreen)
+	defer fmt.Print(leaveAlternateScreen)
+	for {
+		action := actionNone
+		draw(b)
+		err = keyboard.Listen(
+			func(key keys.Key) (bool, error) {
+				action = b.handle(key)
+				if action != actionNone {
+					return true, nil
+				}
+				draw(b)
+				return false, nil
+			},
+		)
+		if err != nil {
+			return err
+		}
+		switch action {
+		case actionQuit:
+			return nil
+		case actionEdit:
+			p, _ := b.selected()
+			if err = openInEditor(projectDir, p); err != nil {
+				b.status = fmt.Sprintf("Failed to open %s: %s", p.File, err)
+			}
+		}
+	}
+}
+
+func draw(b *browser) {
+	lines := b.render(pterm.GetTerminalWidth(), pterm.GetTerminalHeight())
+	fmt.Print(clearScreen + strings.Join(lines, "\r\n"))
+}
+
+func openInEditor(projectDir string, p Problem) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := editorCommand(editor, filepath.Join(projectDir, filepath.FromSlash(p.File)), p.Line, p.Column)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,313 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdschedule
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Schedulers the scans can be registered with.
+const (
+	Cron     = "cron"
+	Systemd  = "systemd"
+	Launchd  = "launchd"
+	Schtasks = "schtasks"
+)
+
+// marker tags the crontab lines of the schedules.
+const marker = "# qodana-schedule:"
+
+// run runs a scheduler command with stdin, replaced in tests.
+var run = func(stdin string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// DefaultBackend returns the scheduler of the system:
+// systemd timers on Linux with systemd and cron otherwise, LaunchAgents on macOS and Task Scheduler on Windows.
+func DefaultBackend() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return Launchd
+	case "windows":
+		return Schtasks
+	}
+	if _, err := exec.LookPath("systemctl"); err == nil {
+		if info, err := os.Stat("/run/systemd/system"); err == nil && info.IsDir() {
+			return Systemd
+		}
+	}
+	return Cron
+}
+
+// ValidateBackend checks the scheduler name.
+func ValidateBackend(backend string) error {
+	switch backend {
+	case Cron, Systemd, Launchd, Schtasks:
+		return nil
+	}
+	return fmt.Errorf("unknown scheduler %q, available values: %s, %s, %s, %s", backend, Cron, Systemd, Launchd, Schtasks)
+}
+
+// Install registers the schedule with its backend to run command, replacing the previous registration.
+func Install(s Schedule, command []string) error {
+	switch s.Backend {
+	case Cron:
+		current, _ := run("", "crontab", "-l") // fails if there is no crontab yet
+		_, err := run(cronTable(current, s, command), "crontab", "-")
+		return err
+	case Systemd:
+		dir, err := systemdUnitDir()
+		if err != nil {
+			return err
+		}
+		service, timer := systemdUnits(s, command)
+		if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+		if err = os.WriteFile(filepath.Join(dir, unitName(s)+".service"), []byte(service), 0o644); err != nil {
+			return err
+		}
+		if err = os.WriteFile(filepath.Join(dir, unitName(s)+".timer"), []byte(timer), 0o644); err != nil {
+			return err
+		}
+		if _, err = run("", "systemctl", "--user", "daemon-reload"); err != nil {
+			return err
+		}
+		_, err = run("", "systemctl", "--user", "enable", "--now", unitName(s)+".timer")
+		return err
+	case Launchd:
+		path, err := launchAgentPath(s)
+		if err != nil {
+			return err
+		}
+		if _, err = os.Stat(path); err == nil {
+			_, _ = run("", "launchctl", "unload", path)
+		}
+		if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return err
+		}
+		if err = os.WriteFile(path, []byte(launchdPlist(s, command)), 0o644); err != nil {
+			return err
+		}
+		_, err = run("", "launchctl", "load", "-w", path)
+		return err
+	case Schtasks:
+		_, err := run("", "schtasks", schtasksCreateArgs(s, command)...)
+		return err
+	}
+	return ValidateBackend(s.Backend)
+}
+
+// Uninstall removes the registration of the schedule from its backend.
+func Uninstall(s Schedule) error {
+	switch s.Backend {
+	case Cron:
+		current, err := run("", "crontab", "-l")
+		if err != nil {
+			return nil
+		}
+		_, err = run(cronTable(current, s, nil), "crontab", "-")
+		return err
+	case Systemd:
+		dir, err := systemdUnitDir()
+		if err != nil {
+			return err
+		}
+		_, _ = run("", "systemctl", "--user", "disable", "--now", unitName(s)+".timer")
+		for _, ext := range []string{".service", ".timer"} {
+			if err = os.Remove(filepath.Join(dir, unitName(s)+ext)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		_, err = run("", "systemctl", "--user", "daemon-reload")
+		return err
+	case Launchd:
+		path, err := launchAgentPath(s)
+		if err != nil {
+			return err
+		}
+		_, _ = run("", "launchctl", "unload", "-w", path)
+		if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	case Schtasks:
+		_, err := run("", "schtasks", "/Delete", "/F", "/TN", taskName(s))
+		return err
+	}
+	return ValidateBackend(s.Backend)
+}
+
+// cronTable returns the crontab with the line of the schedule replaced, or removed if command is nil.
+func cronTable(current string, s Schedule, command []string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(current, "\n") {
+		if line == "" || strings.HasSuffix(line, marker+s.Name) {
+			continue
+		}
+		sb.WriteString(line + "\n")
+	}
+	if command != nil {
+		quoted := make([]string, 0, len(command))
+		for _, arg := range command {
+			// % starts a new line in crontab commands
+			quoted = append(quoted, strings.ReplaceAll(shellQuote(arg), "%", `\%`))
+		}
+		sb.WriteString(fmt.Sprintf("%s %s %s%s\n", cronExpression(s), strings.Join(quoted, " "), marker, s.Name))
+	}
+	return sb.String()
+}
+
+func cronExpression(s Schedule) string {
+	switch s.Interval {
+	case Hourly:
+		return fmt.Sprintf("%d * * * *", s.Minute)
+	case Weekly:
+		return fmt.Sprintf("%d %d * * 1", s.Minute, s.Hour)
+	default:
+		return fmt.Sprintf("%d %d * * *", s.Minute, s.Hour)
+	}
+}
+
+func unitName(s Schedule) string {
+	return "qodana-" + s.Name
+}
+
+func systemdUnitDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "systemd", "user"), nil
+}
+
+// systemdUnits returns the service running command and the timer starting it.
+func systemdUnits(s Schedule, command []string) (string, string) {
+	quoted := make([]string, 0, len(command))
+	for _, arg := range command {
+		quoted = append(quoted, systemdQuote(arg))
+	}
+	service := fmt.Sprintf(
+		"[Unit]\nDescription=Qodana scan %s\n\n[Service]\nType=oneshot\nExecStart=%s\n",
+		s.Name,
+		strings.Join(quoted, " "),
+	)
+	var calendar string
+	switch s.Interval {
+	case Hourly:
+		calendar = fmt.Sprintf("*-*-* *:%02d:00", s.Minute)
+	case Weekly:
+		calendar = fmt.Sprintf("Mon *-*-* %02d:%02d:00", s.Hour, s.Minute)
+	default:
+		calendar = fmt.Sprintf("*-*-* %02d:%02d:00", s.Hour, s.Minute)
+	}
+	// Persistent runs the scans missed while the machine was off
+	timer := fmt.Sprintf(
+		"[Unit]\nDescription=Qodana scan %s %s\n\n[Timer]\nOnCalendar=%s\nPersistent=true\n\n[Install]\nWantedBy=timers.target\n",
+		s.Name,
+		s.When(),
+		calendar,
+	)
+	return service, timer
+}
+
+func systemdQuote(arg string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "$", "$$")
+	return `"` + r.Replace(arg) + `"`
+}
+
+func launchAgentPath(s Schedule) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", launchdLabel(s)+".plist"), nil
+}
+
+func launchdLabel(s Schedule) string {
+	return "com.jetbrains.qodana." + s.Name
+}
+
+// launchdPlist returns the LaunchAgent running command.
+func launchdPlist(s Schedule, command []string) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	sb.WriteString("  <key>Label</key>\n  <string>" + xmlEscape(launchdLabel(s)) + "</string>\n")
+	sb.WriteString("  <key>ProgramArguments</key>\n  <array>\n")
+	for _, arg := range command {
+		sb.WriteString("    <string>" + xmlEscape(arg) + "</string>\n")
+	}
+	sb.WriteString("  </array>\n  <key>StartCalendarInterval</key>\n  <dict>\n")
+	sb.WriteString(fmt.Sprintf("    <key>Minute</key>\n    <integer>%d</integer>\n", s.Minute))
+	if s.Interval != Hourly {
+		sb.WriteString(fmt.Sprintf("    <key>Hour</key>\n    <integer>%d</integer>\n", s.Hour))
+	}
+	if s.Interval == Weekly {
+		sb.WriteString("    <key>Weekday</key>\n    <integer>1</integer>\n")
+	}
+	sb.WriteString("  </dict>\n</dict>\n</plist>\n")
+	return sb.String()
+}
+
+func xmlEscape(value string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(value))
+	return buf.String()
+}
+
+func taskName(s Schedule) string {
+	return `Qodana\` + s.Name
+}
+
+// schtasksCreateArgs returns the schtasks arguments creating the task running command.
+func schtasksCreateArgs(s Schedule, command []string) []string {
+	quoted := make([]string, 0, len(command))
+	for _, arg := range command {
+		if strings.ContainsAny(arg, " \t") {
+			arg = `"` + arg + `"`
+		}
+		quoted = append(quoted, arg)
+	}
+	args := []string{"/Create", "/F", "/TN", taskName(s), "/TR", strings.Join(quoted, " ")}
+	switch s.Interval {
+	case Hourly:
+		args = append(args, "/SC", "HOURLY", "/ST", fmt.Sprintf("00:%02d", s.Minute))
+	case Weekly:
+		args = append(args, "/SC", "WEEKLY", "/D", "MON", "/ST", fmt.Sprintf("%02d:%02d", s.Hour, s.Minute))
+	default:
+		args = append(args, "/SC", "DAILY", "/ST", fmt.Sprintf("%02d:%02d", s.Hour, s.Minute))
+	}
+	return args
+}
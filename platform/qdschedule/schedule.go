@@ -0,0 +1,208 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdschedule registers periodic local scans with the scheduler of the system:
+// cron or systemd timers on Linux, LaunchAgents on macOS and Task Scheduler on Windows.
+package qdschedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Intervals of the scheduled scans.
+const (
+	Hourly = "hourly"
+	Daily  = "daily"
+	Weekly = "weekly" // on Mondays
+)
+
+const registryFileName = "schedules.json"
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Schedule is a periodic scan of a project.
+type Schedule struct {
+	Name       string `json:"name"`
+	ProjectDir string `json:"projectDir"`
+	Interval   string `json:"interval"`
+	// Hour and Minute are the local time of the scan, Hour is ignored for hourly scans.
+	Hour   int `json:"hour"`
+	Minute int `json:"minute"`
+	// Args are the additional qodana scan arguments.
+	Args []string `json:"args,omitempty"`
+	// Notify is the shell command run after the scan.
+	Notify string `json:"notify,omitempty"`
+	// Backend is the scheduler the scan is registered with.
+	Backend string `json:"backend"`
+}
+
+// Validate checks the name and the time of the schedule.
+func (s Schedule) Validate() error {
+	if !namePattern.MatchString(s.Name) {
+		return fmt.Errorf("invalid schedule name %q, use letters, digits, '-' and '_'", s.Name)
+	}
+	if s.Interval != Hourly && s.Interval != Daily && s.Interval != Weekly {
+		return fmt.Errorf("unknown interval %q, available values: %s, %s, %s", s.Interval, Hourly, Daily, Weekly)
+	}
+	if s.Hour < 0 || s.Hour > 23 || s.Minute < 0 || s.Minute > 59 {
+		return fmt.Errorf("invalid time %02d:%02d", s.Hour, s.Minute)
+	}
+	return nil
+}
+
+// When describes the time of the scans, e.g. "daily at 02:30".
+func (s Schedule) When() string {
+	switch s.Interval {
+	case Hourly:
+		return fmt.Sprintf("hourly at :%02d", s.Minute)
+	case Weekly:
+		return fmt.Sprintf("weekly on Monday at %02d:%02d", s.Hour, s.Minute)
+	default:
+		return fmt.Sprintf("daily at %02d:%02d", s.Hour, s.Minute)
+	}
+}
+
+// ParseTime parses HH:MM.
+func ParseTime(value string) (int, int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hour, &minute); err != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("invalid time %q, use HH:MM", value)
+	}
+	return hour, minute, nil
+}
+
+// Registry keeps the schedules added with qodana schedule add in dir, along with the results and logs of their scans.
+type Registry struct {
+	Dir string
+}
+
+// List returns the schedules sorted by name.
+func (r Registry) List() ([]Schedule, error) {
+	data, err := os.ReadFile(filepath.Join(r.Dir, registryFileName))
+	if os.IsNotExist(err) {
+		return []Schedule{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var schedules []Schedule
+	if err = json.Unmarshal(data, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", registryFileName, err)
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Name < schedules[j].Name })
+	return schedules, nil
+}
+
+// Get returns the schedule with the name.
+func (r Registry) Get(name string) (Schedule, bool, error) {
+	schedules, err := r.List()
+	if err != nil {
+		return Schedule{}, false, err
+	}
+	for _, s := range schedules {
+		if s.Name == name {
+			return s, true, nil
+		}
+	}
+	return Schedule{}, false, nil
+}
+
+// Put adds the schedule, replacing the one with the same name.
+func (r Registry) Put(schedule Schedule) error {
+	schedules, err := r.List()
+	if err != nil {
+		return err
+	}
+	schedules = append(without(schedules, schedule.Name), schedule)
+	return r.write(schedules)
+}
+
+// Remove removes the schedule with the name.
+func (r Registry) Remove(name string) error {
+	schedules, err := r.List()
+	if err != nil {
+		return err
+	}
+	return r.write(without(schedules, name))
+}
+
+// ScanDir returns the directory with the results and the log of the last scan of the schedule.
+func (r Registry) ScanDir(name string) string {
+	return filepath.Join(r.Dir, name)
+}
+
+func (r Registry) write(schedules []Schedule) error {
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Name < schedules[j].Name })
+	data, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(r.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(r.Dir, registryFileName), data, 0o644)
+}
+
+func without(schedules []Schedule, name string) []Schedule {
+	result := make([]Schedule, 0, len(schedules))
+	for _, s := range schedules {
+		if s.Name != name {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=,@+") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// Notify runs the notification command of the schedule after a scan, with the outcome of the scan
+// in QODANA_SCHEDULE, QODANA_PROJECT_DIR, QODANA_EXIT_CODE, QODANA_RESULTS_DIR and QODANA_SCAN_LOG.
+func Notify(s Schedule, exitCode int, resultsDir string, logFile string) error {
+	if s.Notify == "" {
+		return nil
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/c", s.Notify)
+	} else {
+		cmd = exec.Command("sh", "-c", s.Notify)
+	}
+	cmd.Dir = s.ProjectDir
+	cmd.Env = append(
+		os.Environ(),
+		"QODANA_SCHEDULE="+s.Name,
+		"QODANA_PROJECT_DIR="+s.ProjectDir,
+		"QODANA_EXIT_CODE="+strconv.Itoa(exitCode),
+		"QODANA_RESULTS_DIR="+resultsDir,
+		"QODANA_SCAN_LOG="+logFile,
+	)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdschedule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTime(t *testing.T) {
+	hour, minute, err := ParseTime("02:30")
+	if err != nil || hour != 2 || minute != 30 {
+		t.Fatalf("unexpected %d:%d, %v", hour, minute, err)
+	}
+	for _, value := range []string{"24:00", "12:60", "noon", ""} {
+		if _, _, err = ParseTime(value); err == nil {
+			t.Errorf("expected %q to be invalid", value)
+		}
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	valid := Schedule{Name: "nightly-jvm", Interval: Daily, Hour: 2}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []Schedule{
+		{Name: "night ly", Interval: Daily},
+		{Name: "nightly", Interval: "monthly"},
+		{Name: "nightly", Interval: Daily, Hour: 25},
+	} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", s)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := Registry{Dir: t.TempDir()}
+	if schedules, err := r.List(); err != nil || len(schedules) != 0 {
+		t.Fatalf("expected no schedules, got %v, %v", schedules, err)
+	}
+	for _, s := range []Schedule{
+		{Name: "web", ProjectDir: "/work/web", Interval: Hourly},
+		{Name: "api", ProjectDir: "/work/api", Interval: Daily},
+		{Name: "web", ProjectDir: "/work/web", Interval: Weekly},
+	} {
+		if err := r.Put(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schedules, err := r.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schedules) != 2 || schedules[0].Name != "api" || schedules[1].Interval != Weekly {
+		t.Fatalf("unexpected schedules %+v", schedules)
+	}
+	if err = r.Remove("api"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := r.Get("api"); ok {
+		t.Fatal("expected api to be removed")
+	}
+}
+
+func TestCronTable(t *testing.T) {
+	s := Schedule{Name: "web", Interval: Weekly, Hour: 3, Minute: 15}
+	command := []string{"/opt/qodana", "schedule", "run", "web", "--dir", "/home/dev/my cache"}
+	current := "0 * * * * backup\n1 2 * * * /old/qodana schedule run web # qodana-schedule:web\n"
+
+	table := cronTable(current, s, command)
+	expected := "0 * * * * backup\n15 3 * * 1 /opt/qodana schedule run web --dir '/home/dev/my cache' # qodana-schedule:web\n"
+	if table != expected {
+		t.Fatalf("unexpected crontab:\n%s", table)
+	}
+	if removed := cronTable(table, s, nil); removed != "0 * * * * backup\n" {
+		t.Fatalf("unexpected crontab after removal:\n%s", removed)
+	}
+}
+
+func TestSchedulerEntries(t *testing.T) {
+	s := Schedule{Name: "api", Interval: Daily, Hour: 2, Minute: 5}
+	command := []string{"/opt/qodana", "schedule", "run", "api"}
+
+	service, timer := systemdUnits(s, command)
+	if !strings.Contains(service, `ExecStart="/opt/qodana" "schedule" "run" "api"`) {
+		t.Errorf("unexpected service:\n%s", service)
+	}
+	if !strings.Contains(timer, "OnCalendar=*-*-* 02:05:00\n") || !strings.Contains(timer, "Persistent=true") {
+		t.Errorf("unexpected timer:\n%s", timer)
+	}
+
+	plist := launchdPlist(Schedule{Name: "api", Interval: Hourly, Minute: 5}, command)
+	if !strings.Contains(plist, "<string>com.jetbrains.qodana.api</string>") ||
+		!strings.Contains(plist, "<key>Minute</key>\n    <integer>5</integer>") || strings.Contains(plist, "<key>Hour</key>") {
+		t.Errorf("unexpected plist:\n%s", plist)
+	}
+
+	args := schtasksCreateArgs(
+		Schedule{Name: "api", Interval: Weekly, Hour: 2, Minute: 5},
+		[]string{`C:\Program Files\qodana.exe`, "schedule", "run", "api"},
+	)
+	expected := []string{
+		"/Create", "/F", "/TN", `Qodana\api`, "/TR", `"C:\Program Files\qodana.exe" schedule run api`,
+		"/SC", "WEEKLY", "/D", "MON", "/ST", "02:05",
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("unexpected schtasks arguments %q", args)
+	}
+}
+
+func TestInstallCron(t *testing.T) {
+	crontab := "0 * * * * backup\n"
+	defer func(original func(string, string, ...string) (string, error)) { run = original }(run)
+	run = func(stdin string, name string, args ...string) (string, error) {
+		if name != "crontab" {
+			t.Fatalf("unexpected command %s", name)
+		}
+		if args[0] == "-" {
+			crontab = stdin
+		}
+		return crontab, nil
+	}
+	s := Schedule{Name: "web", Interval: Hourly, Minute: 30, Backend: Cron}
+	if err := Install(s, []string{"qodana", "schedule", "run", "web"}); err != nil {
+		t.Fatal(err)
+	}
+	if crontab != "0 * * * * backup\n30 * * * * qodana schedule run web # qodana-schedule:web\n" {
+		t.Fatalf("unexpected crontab:\n%s", crontab)
+	}
+	if err := Uninstall(s); err != nil {
+		t.Fatal(err)
+	}
+	if crontab != "0 * * * * backup\n" {
+		t.Fatalf("unexpected crontab after uninstall:\n%s", crontab)
+	}
+}
@@ -0,0 +1,317 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdscope reads qodana.scopes.yaml, the presets mapping the directories of a monorepo
+// to the linter, profile and failure gates of the team owning them.
+package qdscope
+
+import (
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileNames are the names of the scopes file looked up in the project directory.
+var FileNames = []string{"qodana.scopes.yaml", "qodana.scopes.yml"}
+
+// Config is the content of the scopes file.
+type Config struct {
+	Version string  `yaml:"version,omitempty"`
+	Scopes  []Scope `yaml:"scopes"`
+}
+
+// Scope is a set of paths of the project analysed with its own settings.
+type Scope struct {
+	Name string `yaml:"name"`
+	// Paths are CODEOWNERS-like globs relative to the project directory.
+	Paths []string `yaml:"paths"`
+	// Owners are the teams or people owning the paths, e.g. @org/backend.
+	Owners []string `yaml:"owners,omitempty"`
+	Linter string   `yaml:"linter,omitempty"`
+	Ide    string   `yaml:"ide,omitempty"`
+	// Profile overrides the profile of qodana.yaml.
+	Profile qdyaml.Profile `yaml:"profile,omitempty"`
+	// FailThreshold and SeverityThresholds override the failure conditions of qodana.yaml for the problems in the scope.
+	FailThreshold      *int                       `yaml:"failThreshold,omitempty"`
+	SeverityThresholds *qdyaml.SeverityThresholds `yaml:"severityThresholds,omitempty"`
+
+	globs []Glob
+}
+
+// Load reads the scopes file from the project directory.
+func Load(projectDir string) (*Config, error) {
+	for _, name := range FileNames {
+		path := filepath.Join(projectDir, name)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		config := &Config{}
+		if err = yaml.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("not a valid %s: %w", name, err)
+		}
+		if err = config.validate(); err != nil {
+			return nil, fmt.Errorf("not a valid %s: %w", name, err)
+		}
+		return config, nil
+	}
+	return nil, fmt.Errorf("%s not found in %s", FileNames[0], projectDir)
+}
+
+func (c *Config) validate() error {
+	names := map[string]bool{}
+	for i, s := range c.Scopes {
+		if s.Name == "" {
+			return fmt.Errorf("scope #%d has no name", i+1)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("scope %q is defined twice", s.Name)
+		}
+		names[s.Name] = true
+		if len(s.Paths) == 0 {
+			return fmt.Errorf("scope %q has no paths", s.Name)
+		}
+		if s.Linter != "" && s.Ide != "" {
+			return fmt.Errorf("scope %q sets both linter and ide", s.Name)
+		}
+	}
+	return nil
+}
+
+// Select merges the scopes matching the selectors: a scope name, or an owner selecting all the scopes it owns.
+//
+// The merged scope covers the paths of all the selected scopes and uses the strictest of their thresholds,
+// the selected scopes must not set different linters or profiles.
+func (c *Config) Select(selectors []string) (Scope, error) {
+	var selected []Scope
+	for _, selector := range selectors {
+		found := false
+		for _, s := range c.Scopes {
+			if s.Name == selector || contains(s.Owners, selector) {
+				found = true
+				if !containsScope(selected, s.Name) {
+					selected = append(selected, s)
+				}
+			}
+		}
+		if !found {
+			return Scope{}, fmt.Errorf("no scope named or owned by %q, available scopes: %s", selector, strings.Join(c.names(), ", "))
+		}
+	}
+	if len(selected) == 0 {
+		return Scope{}, errors.New("no scope selected")
+	}
+
+	merged := Scope{}
+	names := make([]string, 0, len(selected))
+	for _, s := range selected {
+		names = append(names, s.Name)
+		merged.Paths = append(merged.Paths, s.Paths...)
+		merged.Owners = append(merged.Owners, s.Owners...)
+		if err := mergeSetting(&merged.Linter, s.Linter, "linter"); err != nil {
+			return Scope{}, err
+		}
+		if err := mergeSetting(&merged.Ide, s.Ide, "ide"); err != nil {
+			return Scope{}, err
+		}
+		if err := mergeSetting(&merged.Profile.Name, s.Profile.Name, "profile name"); err != nil {
+			return Scope{}, err
+		}
+		if err := mergeSetting(&merged.Profile.Path, s.Profile.Path, "profile path"); err != nil {
+			return Scope{}, err
+		}
+		merged.FailThreshold = stricter(merged.FailThreshold, s.FailThreshold)
+		if s.SeverityThresholds != nil {
+			merged.SeverityThresholds = stricterThresholds(merged.SeverityThresholds, s.SeverityThresholds)
+		}
+	}
+	merged.Name = strings.Join(names, "+")
+	if merged.Linter != "" && merged.Ide != "" {
+		return Scope{}, fmt.Errorf("scopes %s set both linter and ide", merged.Name)
+	}
+	return merged, nil
+}
+
+// HasThresholds returns true if the scope sets its own failure conditions.
+func (s Scope) HasThresholds() bool {
+	return s.FailThreshold != nil || s.SeverityThresholds != nil
+}
+
+// Contains returns true if the path relative to the project directory is in the scope.
+func (s *Scope) Contains(path string) bool {
+	if s.globs == nil {
+		for _, p := range s.Paths {
+			s.globs = append(s.globs, CompileGlob(p))
+		}
+	}
+	for _, glob := range s.globs {
+		if glob.Matches(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Glob is a CODEOWNERS-like glob of the paths relative to the project directory.
+type Glob struct {
+	re *regexp.Regexp
+}
+
+// CompileGlob compiles the CODEOWNERS-like glob, see compilePattern.
+func CompileGlob(pattern string) Glob {
+	return Glob{re: compilePattern(pattern)}
+}
+
+// Matches returns true if the glob matches the path relative to the project directory.
+func (g Glob) Matches(path string) bool {
+	path = strings.TrimPrefix(filepath.ToSlash(path), "./")
+	// like in CODEOWNERS, a pattern matching a directory matches all the files in it
+	for p := path; p != ""; p = parentDir(p) {
+		if g.re.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Thresholds returns the failure thresholds by severity, "any" limits the total number of problems.
+func Thresholds(failThreshold *int, severities *qdyaml.SeverityThresholds) map[string]int {
+	thresholds := map[string]int{}
+	if failThreshold != nil {
+		thresholds["any"] = *failThreshold
+	}
+	if severities != nil {
+		for name, value := range map[string]*int{
+			"any":      severities.Any,
+			"critical": severities.Critical,
+			"high":     severities.High,
+			"moderate": severities.Moderate,
+			"low":      severities.Low,
+			"info":     severities.Info,
+		} {
+			if value != nil {
+				thresholds[name] = *value
+			}
+		}
+	}
+	return thresholds
+}
+
+// compilePattern converts a CODEOWNERS-like glob to a regular expression:
+// a pattern without a slash matches at any depth, a leading slash anchors it to the project directory,
+// "*" matches within a path segment and "**" across segments.
+func compilePattern(pattern string) *regexp.Regexp {
+	pattern = strings.TrimSuffix(filepath.ToSlash(strings.TrimSpace(pattern)), "/")
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	pattern = strings.TrimPrefix(strings.TrimPrefix(pattern, "/"), "./")
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+func parentDir(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
+
+func mergeSetting(merged *string, value string, name string) error {
+	if value == "" {
+		return nil
+	}
+	if *merged != "" && *merged != value {
+		return fmt.Errorf("the selected scopes set different %ss: %s and %s", name, *merged, value)
+	}
+	*merged = value
+	return nil
+}
+
+func stricter(a *int, b *int) *int {
+	if a == nil || (b != nil && *b < *a) {
+		return b
+	}
+	return a
+}
+
+func stricterThresholds(a *qdyaml.SeverityThresholds, b *qdyaml.SeverityThresholds) *qdyaml.SeverityThresholds {
+	if a == nil {
+		return b
+	}
+	return &qdyaml.SeverityThresholds{
+		Any:      stricter(a.Any, b.Any),
+		Critical: stricter(a.Critical, b.Critical),
+		High:     stricter(a.High, b.High),
+		Moderate: stricter(a.Moderate, b.Moderate),
+		Low:      stricter(a.Low, b.Low),
+		Info:     stricter(a.Info, b.Info),
+	}
+}
+
+func (c *Config) names() []string {
+	names := make([]string, 0, len(c.Scopes))
+	for _, s := range c.Scopes {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsScope(scopes []Scope, name string) bool {
+	for _, s := range scopes {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
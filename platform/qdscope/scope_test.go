@@ -0,0 +1,126 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdscope
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const scopesYaml = `version: "1.0"
+scopes:
+  - name: backend
+    paths: [services/, /libs/core]
+    owners: ["@org/backend"]
+    linter: jetbrains/qodana-jvm:2024.3
+    failThreshold: 10
+  - name: web
+    paths: ["apps/web/**/*.ts", "*.css"]
+    owners: ["@org/frontend"]
+    linter: jetbrains/qodana-js:2024.3
+  - name: shared
+    paths: [libs/shared]
+    owners: ["@org/backend", "@org/frontend"]
+    severityThresholds:
+      critical: 0
+      any: 5
+`
+
+func loadTestConfig(t *testing.T, content string) (*Config, error) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, FileNames[0]), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(dir)
+}
+
+func TestContains(t *testing.T) {
+	config, err := loadTestConfig(t, scopesYaml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		scope string
+		path  string
+		want  bool
+	}{
+		{"backend", "services/api/Main.java", true},
+		{"backend", "./services/Main.java", true},
+		{"backend", "libs/core/Util.java", true},
+		{"backend", "other/libs/core/Util.java", false},
+		{"backend", "servicesx/Main.java", false},
+		{"web", "apps/web/src/deep/index.ts", true},
+		{"web", "apps/web/index.ts", true},
+		{"web", "apps/web/index.js", false},
+		{"web", "any/where/style.css", true},
+	} {
+		scope, err := config.Select([]string{tc.scope})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := scope.Contains(tc.path); got != tc.want {
+			t.Errorf("scope %s contains %s: got %v, want %v", tc.scope, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	config, err := loadTestConfig(t, scopesYaml)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scope, err := config.Select([]string{"@org/backend"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scope.Name != "backend+shared" || scope.Linter != "jetbrains/qodana-jvm:2024.3" {
+		t.Errorf("unexpected scope %+v", scope)
+	}
+	expected := map[string]int{"any": 5, "critical": 0}
+	if got := Thresholds(scope.FailThreshold, scope.SeverityThresholds); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got thresholds %v, want %v", got, expected)
+	}
+	if !scope.Contains("libs/shared/a.kt") || scope.Contains("apps/web/a.ts") {
+		t.Errorf("unexpected paths of the merged scope %v", scope.Paths)
+	}
+
+	if _, err = config.Select([]string{"backend", "web"}); err == nil || !strings.Contains(err.Error(), "different linters") {
+		t.Errorf("expected the conflicting linters error, got %v", err)
+	}
+	if _, err = config.Select([]string{"mobile"}); err == nil || !strings.Contains(err.Error(), "backend, web, shared") {
+		t.Errorf("expected the unknown scope error, got %v", err)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for content, expected := range map[string]string{
+		"scopes:\n  - name: a\n":    "has no paths",
+		"scopes:\n  - paths: [a]\n": "has no name",
+		"scopes:\n  - {name: a, paths: [a]}\n  - {name: a, paths: [b]}\n": "defined twice",
+	} {
+		if _, err := loadTestConfig(t, content); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q error for %q, got %v", expected, content, err)
+		}
+	}
+	if _, err := Load(t.TempDir()); err == nil {
+		t.Error("expected an error without the scopes file")
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdsign
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignFile(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tc := range map[string]struct {
+		private any
+		public  any
+	}{
+		"ecdsa": {ecKey, &ecKey.PublicKey},
+		"rsa":   {rsaKey, &rsaKey.PublicKey},
+	} {
+		t.Run(
+			name, func(t *testing.T) {
+				dir := t.TempDir()
+				privateDer, err := x509.MarshalPKCS8PrivateKey(tc.private)
+				if err != nil {
+					t.Fatal(err)
+				}
+				keyPath := filepath.Join(dir, "signing.key")
+				privatePem := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDer})
+				if err = os.WriteFile(keyPath, privatePem, 0o600); err != nil {
+					t.Fatal(err)
+				}
+				path := filepath.Join(dir, "manifest")
+				if err = os.WriteFile(path, []byte("the manifest"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+
+				key, err := LoadSigningKey(keyPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				signature, err := SignFile(path, key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				publicDer, err := x509.MarshalPKIXPublicKey(tc.public)
+				if err != nil {
+					t.Fatal(err)
+				}
+				verifier, err := ParseKeys(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer}))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err = verifier.Verify(path, signature); err != nil {
+					t.Errorf("expected a valid signature, got %v", err)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,311 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdsign verifies the detached signatures of the configuration files and the downloaded tools,
+// made with minisign or with cosign sign-blob and a key pair.
+package qdsign
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/blake2b"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	// MinisignExt is the extension of the minisign signatures.
+	MinisignExt = ".minisig"
+	// CosignExt is the extension of the cosign signatures.
+	CosignExt = ".sig"
+
+	untrustedComment = "untrusted comment:"
+	trustedComment   = "trusted comment: "
+	keyIdSize        = 8
+)
+
+// Verifier verifies the signatures with the trusted keys.
+type Verifier struct {
+	minisignKeys []minisignKey
+	cosignKeys   []crypto.PublicKey
+}
+
+type minisignKey struct {
+	id  []byte
+	key ed25519.PublicKey
+}
+
+// LoadVerifier reads the trusted keys from the file: minisign public keys, one per line, and PEM-encoded
+// public keys of cosign.
+func LoadVerifier(path string) (*Verifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the trusted keys: %w", err)
+	}
+	v, err := ParseKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the trusted keys %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// ParseKeys returns the verifier with the keys of data, see LoadVerifier.
+func ParseKeys(data []byte) (*Verifier, error) {
+	v := &Verifier{}
+	var text []byte
+	for {
+		start := bytes.Index(data, []byte("-----BEGIN "))
+		if start < 0 {
+			text = append(text, data...)
+			break
+		}
+		text = append(text, data[:start]...)
+		block, rest := pem.Decode(data[start:])
+		if block == nil {
+			return nil, errors.New("malformed PEM block")
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case *ecdsa.PublicKey, *rsa.PublicKey:
+			v.cosignKeys = append(v.cosignKeys, key)
+		default:
+			return nil, fmt.Errorf("unsupported key type %T", key)
+		}
+		data = rest
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, untrustedComment) {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(line)
+		if err != nil || len(decoded) != 2+keyIdSize+ed25519.PublicKeySize || string(decoded[:2]) != "Ed" {
+			return nil, fmt.Errorf("invalid minisign public key %q", line)
+		}
+		v.minisignKeys = append(
+			v.minisignKeys,
+			minisignKey{id: decoded[2 : 2+keyIdSize], key: decoded[2+keyIdSize:]},
+		)
+	}
+	if len(v.minisignKeys) == 0 && len(v.cosignKeys) == 0 {
+		return nil, errors.New("no keys found")
+	}
+	return v, nil
+}
+
+// VerifyFile verifies the file with its signature next to it, path.minisig or path.sig.
+func (v *Verifier) VerifyFile(path string) error {
+	for _, ext := range []string{MinisignExt, CosignExt} {
+		signature, err := os.ReadFile(path + ext)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return v.Verify(path, signature)
+	}
+	return fmt.Errorf("%s is not signed, no %s or %s found next to it", path, MinisignExt, CosignExt)
+}
+
+// VerifyDownload verifies the file downloaded from rawUrl with the signature downloaded from rawUrl.minisig
+// or rawUrl.sig.
+func (v *Verifier) VerifyDownload(path string, rawUrl string) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return err
+	}
+	u.RawQuery = ""
+	for _, ext := range []string{MinisignExt, CosignExt} {
+		signatureUrl := u.String() + ext
+		signature, err := download(signatureUrl)
+		if err != nil {
+			return err
+		}
+		if signature != nil {
+			return v.Verify(path, signature)
+		}
+	}
+	return fmt.Errorf("%s is not signed, no %s or %s found next to it", u, MinisignExt, CosignExt)
+}
+
+// download returns the content of the URL, nil if it is not found.
+func download(url string) ([]byte, error) {
+	response, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the signature %s: %w", url, err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download the signature %s: %s", url, response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
+
+// Verify verifies the file with the minisign or the cosign signature.
+func (v *Verifier) Verify(path string, signature []byte) error {
+	var err error
+	if bytes.HasPrefix(signature, []byte(untrustedComment)) {
+		err = v.verifyMinisign(path, signature)
+	} else {
+		err = v.verifyCosign(path, signature)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid signature of %s: %w", path, err)
+	}
+	return nil
+}
+
+func (v *Verifier) verifyMinisign(path string, signature []byte) error {
+	lines := strings.Split(strings.ReplaceAll(string(signature), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], trustedComment) {
+		return errors.New("malformed minisign signature")
+	}
+	sig, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(sig) != 2+keyIdSize+ed25519.SignatureSize {
+		return errors.New("malformed minisign signature")
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return errors.New("malformed minisign signature")
+	}
+	algorithm, keyId, fileSig := string(sig[:2]), sig[2:2+keyIdSize], sig[2+keyIdSize:]
+	var key *minisignKey
+	for i := range v.minisignKeys {
+		if bytes.Equal(v.minisignKeys[i].id, keyId) {
+			key = &v.minisignKeys[i]
+		}
+	}
+	if key == nil {
+		return fmt.Errorf("signed with the untrusted key %X", reverse(keyId))
+	}
+	var message []byte
+	switch algorithm {
+	case "ED":
+		h, _ := blake2b.New512(nil)
+		if message, err = digest(path, h); err != nil {
+			return err
+		}
+	case "Ed":
+		if message, err = os.ReadFile(path); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported minisign algorithm %q", algorithm)
+	}
+	if !ed25519.Verify(key.key, message, fileSig) {
+		return errors.New("the file doesn't match the signature")
+	}
+	comment := strings.TrimPrefix(lines[2], trustedComment)
+	if !ed25519.Verify(key.key, append(fileSig, comment...), globalSig) {
+		return errors.New("the trusted comment doesn't match the signature")
+	}
+	return nil
+}
+
+func (v *Verifier) verifyCosign(path string, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return errors.New("malformed cosign signature, a base64-encoded signature is expected")
+	}
+	sum, err := digest(path, sha256.New())
+	if err != nil {
+		return err
+	}
+	for _, key := range v.cosignKeys {
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, sum, sig) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, sum, sig) == nil {
+				return nil
+			}
+		}
+	}
+	return errors.New("the file isn't signed with any of the trusted keys")
+}
+
+func digest(path string, h hash.Hash) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	if _, err = io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// reverse returns the key id in the order minisign prints it.
+func reverse(id []byte) []byte {
+	r := make([]byte, len(id))
+	for i := range id {
+		r[len(id)-1-i] = id[i]
+	}
+	return r
+}
+
+var enabled *Verifier
+
+// Enable makes VerifyFile and VerifyDownload verify the signatures with v, nil disables the verification.
+func Enable(v *Verifier) {
+	enabled = v
+}
+
+// IsEnabled returns true if the signatures are verified.
+func IsEnabled() bool {
+	return enabled != nil
+}
+
+// VerifyFile verifies the file with its signature next to it if the verification is enabled.
+func VerifyFile(path string) error {
+	if enabled == nil {
+		return nil
+	}
+	return enabled.VerifyFile(path)
+}
+
+// VerifyDownload verifies the file downloaded from rawUrl with its signature if the verification is enabled.
+func VerifyDownload(path string, rawUrl string) error {
+	if enabled == nil {
+		return nil
+	}
+	return enabled.VerifyDownload(path, rawUrl)
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdsign
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"golang.org/x/crypto/blake2b"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// minisign signs the content like minisign -S does and returns the public key and the signature.
+func minisign(t *testing.T, content []byte) (string, []byte) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyId := make([]byte, keyIdSize)
+	_, _ = rand.Read(keyId)
+	key := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyId...), public...))
+
+	sum := blake2b.Sum512(content)
+	fileSig := ed25519.Sign(private, sum[:])
+	comment := "timestamp:1700000000\tfile:tool.zip\thashed"
+	globalSig := ed25519.Sign(private, append(append([]byte{}, fileSig...), comment...))
+	signature := strings.Join(
+		[]string{
+			"untrusted comment: signature from minisign secret key",
+			base64.StdEncoding.EncodeToString(append(append([]byte("ED"), keyId...), fileSig...)),
+			trustedComment + comment,
+			base64.StdEncoding.EncodeToString(globalSig),
+			"",
+		}, "\n",
+	)
+	return "untrusted comment: minisign public key\n" + key + "\n", []byte(signature)
+}
+
+// cosign signs the content like cosign sign-blob --key does and returns the public key and the signature.
+func cosign(t *testing.T, content []byte) (string, []byte) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	sig, err := ecdsa.SignASN1(rand.Reader, private, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	return key, []byte(base64.StdEncoding.EncodeToString(sig))
+}
+
+func TestVerifyFile(t *testing.T) {
+	content := []byte("the tool")
+	for name, sign := range map[string]func(*testing.T, []byte) (string, []byte){"minisign": minisign, "cosign": cosign} {
+		t.Run(
+			name, func(t *testing.T) {
+				dir := t.TempDir()
+				path := filepath.Join(dir, "tool.zip")
+				if err := os.WriteFile(path, content, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				key, signature := sign(t, content)
+				_, otherSignature := sign(t, content)
+				untrustedKey, _ := sign(t, content)
+				verifier, err := ParseKeys([]byte("# trusted keys\n" + untrustedKey + key))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				ext := MinisignExt
+				if name == "cosign" {
+					ext = CosignExt
+				}
+				if err = verifier.VerifyFile(path); err == nil {
+					t.Error("expected an error for an unsigned file")
+				}
+				if err = os.WriteFile(path+ext, signature, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if err = verifier.VerifyFile(path); err != nil {
+					t.Errorf("expected a valid signature, got %v", err)
+				}
+				if err = os.WriteFile(path+ext, otherSignature, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if err = verifier.VerifyFile(path); err == nil {
+					t.Error("expected an error for a signature of an untrusted key")
+				}
+				if err = os.WriteFile(path+ext, signature, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if err = os.WriteFile(path, []byte("the tampered tool"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if err = verifier.VerifyFile(path); err == nil {
+					t.Error("expected an error for a tampered file")
+				}
+			},
+		)
+	}
+}
+
+func TestVerifyDownload(t *testing.T) {
+	content := []byte("the tool")
+	key, signature := minisign(t, content)
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/tool.zip"+MinisignExt {
+					_, _ = w.Write(signature)
+					return
+				}
+				http.NotFound(w, r)
+			},
+		),
+	)
+	defer server.Close()
+	verifier, err := ParseKeys([]byte(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "tool.zip")
+	if err = os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = verifier.VerifyDownload(path, server.URL+"/tool.zip?build=1"); err != nil {
+		t.Errorf("expected a valid signature, got %v", err)
+	}
+	if err = verifier.VerifyDownload(path, server.URL+"/other.zip"); err == nil {
+		t.Error("expected an error for a download without a signature")
+	}
+}
@@ -0,0 +1,295 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdstorage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const azureVersion = "2021-08-06"
+
+// azureContainer is an Azure Blob Storage container. It is configured with AZURE_STORAGE_CONNECTION_STRING or
+// AZURE_STORAGE_ACCOUNT with AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN, AZURE_STORAGE_BLOB_ENDPOINT overrides the endpoint.
+type azureContainer struct {
+	container string
+	account   string
+	key       []byte
+	sasToken  string
+	endpoint  *url.URL
+	now       func() time.Time
+}
+
+func newAzureContainer(container string) (*azureContainer, error) {
+	settings := map[string]string{
+		"AccountName":           os.Getenv("AZURE_STORAGE_ACCOUNT"),
+		"AccountKey":            os.Getenv("AZURE_STORAGE_KEY"),
+		"SharedAccessSignature": os.Getenv("AZURE_STORAGE_SAS_TOKEN"),
+		"BlobEndpoint":          os.Getenv("AZURE_STORAGE_BLOB_ENDPOINT"),
+	}
+	for _, part := range strings.Split(os.Getenv("AZURE_STORAGE_CONNECTION_STRING"), ";") {
+		if name, value, ok := strings.Cut(part, "="); ok && value != "" {
+			settings[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	c := &azureContainer{
+		container: container,
+		account:   settings["AccountName"],
+		sasToken:  strings.TrimPrefix(settings["SharedAccessSignature"], "?"),
+		now:       time.Now,
+	}
+	if c.account == "" {
+		return nil, fmt.Errorf("AZURE_STORAGE_ACCOUNT or AZURE_STORAGE_CONNECTION_STRING must be set to use Azure Blob Storage")
+	}
+	if settings["AccountKey"] != "" {
+		key, err := base64.StdEncoding.DecodeString(settings["AccountKey"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid Azure storage account key: %w", err)
+		}
+		c.key = key
+	}
+	if c.key == nil && c.sasToken == "" {
+		return nil, fmt.Errorf("AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN must be set to use Azure Blob Storage")
+	}
+	endpoint := settings["BlobEndpoint"]
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", c.account)
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Azure blob endpoint %s: %w", endpoint, err)
+	}
+	c.endpoint = u
+	return c, nil
+}
+
+func (c *azureContainer) Get(name string) (io.ReadCloser, bool, error) {
+	resp, err := c.do(http.MethodGet, name, nil, nil, -1, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		_ = resp.Body.Close()
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, azureError(resp)
+	}
+	return resp.Body, true, nil
+}
+
+func (c *azureContainer) Put(name string, r io.Reader, size int64, contentType string) error {
+	headers := map[string]string{"x-ms-blob-type": "BlockBlob"}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	resp, err := c.do(http.MethodPut, name, nil, r, size, headers)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return azureError(resp)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+func (c *azureContainer) Delete(name string) error {
+	resp, err := c.do(http.MethodDelete, name, nil, nil, -1, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNotFound {
+		return azureError(resp)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+func (c *azureContainer) List(prefix string) ([]Object, error) {
+	var objects []Object
+	query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefix}}
+	for {
+		resp, err := c.do(http.MethodGet, "", query, nil, -1, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, azureError(resp)
+		}
+		var result struct {
+			Blobs []struct {
+				Name         string `xml:"Name"`
+				LastModified string `xml:"Properties>Last-Modified"`
+			} `xml:"Blobs>Blob"`
+			NextMarker string `xml:"NextMarker"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the Azure container %s: %w", c.container, err)
+		}
+		for _, blob := range result.Blobs {
+			modified, _ := time.Parse(http.TimeFormat, blob.LastModified)
+			objects = append(objects, Object{Name: blob.Name, Modified: modified})
+		}
+		if result.NextMarker == "" {
+			return objects, nil
+		}
+		query.Set("marker", result.NextMarker)
+	}
+}
+
+func (c *azureContainer) Url(name string) string {
+	return c.blobUrl(name).String()
+}
+
+// SignedUrl returns the URL of the blob with a read-only service SAS, signing requires the account key.
+func (c *azureContainer) SignedUrl(name string, expiry time.Duration) (string, error) {
+	if c.key == nil {
+		return "", ErrSigningUnsupported
+	}
+	se := c.now().UTC().Add(expiry).Format("2006-01-02T15:04:05Z")
+	resource := "/blob/" + c.account + "/" + c.container + "/" + name
+	// sp, st, se, canonicalizedResource, si, sip, spr, sv, sr, snapshot, ses, rscc, rscd, rsce, rscl, rsct
+	stringToSign := strings.Join(
+		[]string{"r", "", se, resource, "", "", "https,http", azureVersion, "b", "", "", "", "", "", "", ""},
+		"\n",
+	)
+	query := url.Values{
+		"sp":  {"r"},
+		"se":  {se},
+		"spr": {"https,http"},
+		"sv":  {azureVersion},
+		"sr":  {"b"},
+		"sig": {c.sign(stringToSign)},
+	}
+	u := c.blobUrl(name)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+func (c *azureContainer) blobUrl(name string) *url.URL {
+	u := *c.endpoint
+	p := "/" + c.container
+	if name != "" {
+		p += "/" + name
+	}
+	u.Path += p
+	u.RawPath = u.EscapedPath()
+	return &u
+}
+
+func (c *azureContainer) do(method string, name string, query url.Values, body io.Reader, size int64, headers map[string]string) (*http.Response, error) {
+	u := c.blobUrl(name)
+	u.RawQuery = query.Encode()
+	if c.key == nil {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += c.sasToken
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("x-ms-date", c.now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureVersion)
+	if c.key != nil {
+		req.Header.Set("Authorization", "SharedKey "+c.account+":"+c.sign(c.stringToSign(req, query)))
+	}
+	return newClient().Do(req)
+}
+
+// stringToSign returns the Shared Key string to sign of the request.
+func (c *azureContainer) stringToSign(req *http.Request, query url.Values) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+	var msHeaders []string
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-ms-") {
+			msHeaders = append(msHeaders, lower+":"+strings.TrimSpace(req.Header.Get(name)))
+		}
+	}
+	sort.Strings(msHeaders)
+	resource := "/" + c.account + req.URL.EscapedPath()
+	var params []string
+	for key, values := range query {
+		params = append(params, strings.ToLower(key)+":"+strings.Join(values, ","))
+	}
+	sort.Strings(params)
+	for _, param := range params {
+		resource += "\n" + param
+	}
+	return strings.Join(
+		[]string{
+			req.Method,
+			req.Header.Get("Content-Encoding"),
+			req.Header.Get("Content-Language"),
+			contentLength,
+			req.Header.Get("Content-MD5"),
+			req.Header.Get("Content-Type"),
+			"", // Date, x-ms-date is used instead
+			req.Header.Get("If-Modified-Since"),
+			req.Header.Get("If-Match"),
+			req.Header.Get("If-None-Match"),
+			req.Header.Get("If-Unmodified-Since"),
+			req.Header.Get("Range"),
+			strings.Join(msHeaders, "\n"),
+			resource,
+		}, "\n",
+	)
+}
+
+func (c *azureContainer) sign(stringToSign string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func azureError(resp *http.Response) error {
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+	var azErr struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&azErr); err == nil && azErr.Code != "" {
+		return fmt.Errorf("Azure Blob Storage responded with %s: %s", resp.Status, azErr.Code)
+	}
+	return fmt.Errorf("Azure Blob Storage responded with %s", resp.Status)
+}
@@ -0,0 +1,353 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdstorage
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gcsEndpoint         = "https://storage.googleapis.com"
+	gcsScope            = "https://www.googleapis.com/auth/devstorage.read_write"
+	gcsMetadataTokenUrl = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	gcsAlgorithm        = "GOOG4-RSA-SHA256"
+	jwtBearerGrantType  = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	maxGcsUrlExpiry     = 7 * 24 * time.Hour
+)
+
+// gcsBucket is a Google Cloud Storage bucket. The access token is taken from GOOGLE_OAUTH_ACCESS_TOKEN,
+// the service account key in GOOGLE_APPLICATION_CREDENTIALS or the metadata server of the GCE instance,
+// STORAGE_EMULATOR_HOST overrides the endpoint.
+type gcsBucket struct {
+	bucket   string
+	endpoint string
+	now      func() time.Time
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// serviceAccountKey is the JSON key of a Google Cloud service account.
+type serviceAccountKey struct {
+	Type        string `json:"type"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenUri    string `json:"token_uri"`
+}
+
+func newGcsBucket(bucket string) (*gcsBucket, error) {
+	endpoint := gcsEndpoint
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		endpoint = host
+		if !strings.Contains(host, "://") {
+			endpoint = "http://" + host
+		}
+	}
+	return &gcsBucket{bucket: bucket, endpoint: strings.TrimSuffix(endpoint, "/"), now: time.Now}, nil
+}
+
+func (b *gcsBucket) Get(name string) (io.ReadCloser, bool, error) {
+	u := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media", b.endpoint, url.PathEscape(b.bucket), url.PathEscape(name))
+	resp, err := b.do(http.MethodGet, u, nil, -1, "")
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		_ = resp.Body.Close()
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, gcsError(resp)
+	}
+	return resp.Body, true, nil
+}
+
+func (b *gcsBucket) Put(name string, r io.Reader, size int64, contentType string) error {
+	u := fmt.Sprintf(
+		"%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s",
+		b.endpoint,
+		url.PathEscape(b.bucket),
+		url.QueryEscape(name),
+	)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	resp, err := b.do(http.MethodPost, u, r, size, contentType)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return gcsError(resp)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+func (b *gcsBucket) Delete(name string) error {
+	u := fmt.Sprintf("%s/storage/v1/b/%s/o/%s", b.endpoint, url.PathEscape(b.bucket), url.PathEscape(name))
+	resp, err := b.do(http.MethodDelete, u, nil, -1, "")
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return gcsError(resp)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+func (b *gcsBucket) List(prefix string) ([]Object, error) {
+	var objects []Object
+	query := url.Values{"prefix": {prefix}, "fields": {"items(name,updated),nextPageToken"}}
+	for {
+		u := fmt.Sprintf("%s/storage/v1/b/%s/o?%s", b.endpoint, url.PathEscape(b.bucket), query.Encode())
+		resp, err := b.do(http.MethodGet, u, nil, -1, "")
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, gcsError(resp)
+		}
+		var result struct {
+			Items []struct {
+				Name    string    `json:"name"`
+				Updated time.Time `json:"updated"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the GCS bucket %s: %w", b.bucket, err)
+		}
+		for _, item := range result.Items {
+			objects = append(objects, Object{Name: item.Name, Modified: item.Updated})
+		}
+		if result.NextPageToken == "" {
+			return objects, nil
+		}
+		query.Set("pageToken", result.NextPageToken)
+	}
+}
+
+func (b *gcsBucket) Url(name string) string {
+	return gcsEndpoint + "/" + b.bucket + "/" + uriEncode(name, false)
+}
+
+// SignedUrl returns the V4 signed URL of the object, signing requires the service account key
+// in GOOGLE_APPLICATION_CREDENTIALS. GCS limits the expiry to 7 days.
+func (b *gcsBucket) SignedUrl(name string, expiry time.Duration) (string, error) {
+	credentials := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if credentials == "" {
+		return "", ErrSigningUnsupported
+	}
+	key, privateKey, err := loadServiceAccountKey(credentials)
+	if err != nil {
+		return "", err
+	}
+	if expiry > maxGcsUrlExpiry {
+		expiry = maxGcsUrlExpiry
+	}
+	now := b.now().UTC()
+	date := now.Format(amzDateFormat)
+	scope := date[:8] + "/auto/storage/goog4_request"
+	query := url.Values{
+		"X-Goog-Algorithm":     {gcsAlgorithm},
+		"X-Goog-Credential":    {key.ClientEmail + "/" + scope},
+		"X-Goog-Date":          {date},
+		"X-Goog-Expires":       {strconv.Itoa(int(expiry.Seconds()))},
+		"X-Goog-SignedHeaders": {"host"},
+	}
+	host := strings.TrimPrefix(gcsEndpoint, "https://")
+	path := "/" + b.bucket + "/" + uriEncode(name, false)
+	canonicalRequest := strings.Join(
+		[]string{http.MethodGet, path, canonicalQuery(query), "host:" + host + "\n", "host", unsignedPayload},
+		"\n",
+	)
+	stringToSign := gcsAlgorithm + "\n" + date + "\n" + scope + "\n" + sha256Hex(canonicalRequest)
+	hash := sha256.Sum256([]byte(stringToSign))
+	signature, err := rsa.SignPKCS1v15(nil, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return gcsEndpoint + path + "?" + canonicalQuery(query) + "&X-Goog-Signature=" + hex.EncodeToString(signature), nil
+}
+
+func (b *gcsBucket) do(method string, u string, body io.Reader, size int64, contentType string) (*http.Response, error) {
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	token, err := b.accessToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return newClient().Do(req)
+}
+
+// accessToken returns the OAuth 2.0 access token for the storage, refreshed shortly before it expires.
+func (b *gcsBucket) accessToken() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	if os.Getenv("STORAGE_EMULATOR_HOST") != "" {
+		return "", nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.token != "" && time.Now().Before(b.tokenExpiry) {
+		return b.token, nil
+	}
+	var resp *http.Response
+	var err error
+	if credentials := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); credentials != "" {
+		resp, err = requestServiceAccountToken(credentials)
+	} else {
+		req, _ := http.NewRequest(http.MethodGet, gcsMetadataTokenUrl, nil)
+		req.Header.Set("Metadata-Flavor", "Google")
+		resp, err = (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get the Google Cloud access token: %w", err)
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get the Google Cloud access token: %s", resp.Status)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to get the Google Cloud access token: %w", err)
+	}
+	b.token = token.AccessToken
+	b.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return b.token, nil
+}
+
+func loadServiceAccountKey(path string) (serviceAccountKey, *rsa.PrivateKey, error) {
+	var key serviceAccountKey
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return key, nil, err
+	}
+	if err = json.Unmarshal(data, &key); err != nil {
+		return key, nil, err
+	}
+	if key.Type != "service_account" {
+		return key, nil, fmt.Errorf("%s is not a service account key, set GOOGLE_OAUTH_ACCESS_TOKEN instead", path)
+	}
+	privateKey, err := parseRsaPrivateKey(key.PrivateKey)
+	return key, privateKey, err
+}
+
+// requestServiceAccountToken exchanges a JWT signed with the service account key for an access token.
+func requestServiceAccountToken(credentialsPath string) (*http.Response, error) {
+	key, privateKey, err := loadServiceAccountKey(credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+	assertion, err := signJwt(key.ClientEmail, key.TokenUri, privateKey, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return (&http.Client{Timeout: 30 * time.Second}).PostForm(
+		key.TokenUri,
+		url.Values{"grant_type": {jwtBearerGrantType}, "assertion": {assertion}},
+	)
+}
+
+func parseRsaPrivateKey(privateKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid service account private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("service account private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// signJwt creates the RS256 signed JWT asserting the service account identity.
+func signJwt(email string, audience string, privateKey *rsa.PrivateKey, now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(
+		map[string]any{
+			"iss":   email,
+			"scope": gcsScope,
+			"aud":   audience,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		},
+	)
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(nil, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func gcsError(resp *http.Response) error {
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+	var gcsErr struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&gcsErr); err == nil && gcsErr.Error.Message != "" {
+		return fmt.Errorf("GCS responded with %s: %s", resp.Status, gcsErr.Error.Message)
+	}
+	return fmt.Errorf("GCS responded with %s", resp.Status)
+}
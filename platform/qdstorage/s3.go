@@ -0,0 +1,338 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdstorage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	amzDateFormat   = "20060102T150405Z"
+	s3Algorithm     = "AWS4-HMAC-SHA256"
+	maxS3UrlExpiry  = 7 * 24 * time.Hour
+)
+
+// s3Bucket is an S3 (or S3-compatible) bucket. It is configured with the standard
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN, AWS_REGION and AWS_ENDPOINT_URL_S3 variables.
+type s3Bucket struct {
+	bucket       string
+	region       string
+	endpoint     *url.URL
+	pathStyle    bool
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	now          func() time.Time
+}
+
+func newS3Bucket(bucket string) (*s3Bucket, error) {
+	b := &s3Bucket{
+		bucket:       bucket,
+		region:       firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		now:          time.Now,
+	}
+	if b.region == "" {
+		b.region = "us-east-1"
+	}
+	if b.accessKey == "" || b.secretKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set to use S3")
+	}
+	endpoint := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, b.region)
+	} else {
+		b.pathStyle = true // S3-compatible storages (MinIO, Ceph) are addressed by path
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint %s: %w", endpoint, err)
+	}
+	b.endpoint = u
+	return b, nil
+}
+
+func (b *s3Bucket) Get(name string) (io.ReadCloser, bool, error) {
+	resp, err := b.do(http.MethodGet, name, nil, nil, -1, "")
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		_ = resp.Body.Close()
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, s3Error(resp)
+	}
+	return resp.Body, true, nil
+}
+
+func (b *s3Bucket) Put(name string, r io.Reader, size int64, contentType string) error {
+	resp, err := b.do(http.MethodPut, name, nil, r, size, contentType)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+func (b *s3Bucket) Delete(name string) error {
+	resp, err := b.do(http.MethodDelete, name, nil, nil, -1, "")
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp)
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+func (b *s3Bucket) List(prefix string) ([]Object, error) {
+	var objects []Object
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	for {
+		resp, err := b.do(http.MethodGet, "", query, nil, -1, "")
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, s3Error(resp)
+		}
+		var result struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the S3 bucket %s: %w", b.bucket, err)
+		}
+		for _, object := range result.Contents {
+			objects = append(objects, Object{Name: object.Key, Modified: object.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+func (b *s3Bucket) Url(name string) string {
+	return b.objectUrl(name, nil).String()
+}
+
+// SignedUrl returns the presigned URL of the object, S3 limits the expiry to 7 days.
+func (b *s3Bucket) SignedUrl(name string, expiry time.Duration) (string, error) {
+	if expiry > maxS3UrlExpiry {
+		expiry = maxS3UrlExpiry
+	}
+	now := b.now().UTC()
+	amzDate := now.Format(amzDateFormat)
+	query := url.Values{
+		"X-Amz-Algorithm":     {s3Algorithm},
+		"X-Amz-Credential":    {b.accessKey + "/" + b.scope(amzDate)},
+		"X-Amz-Date":          {amzDate},
+		"X-Amz-Expires":       {strconv.Itoa(int(expiry.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	if b.sessionToken != "" {
+		query.Set("X-Amz-Security-Token", b.sessionToken)
+	}
+	u := b.objectUrl(name, query)
+	signature := b.signature(http.MethodGet, u, map[string]string{"host": u.Host}, amzDate)
+	u.RawQuery += "&X-Amz-Signature=" + signature
+	return u.String(), nil
+}
+
+func (b *s3Bucket) objectUrl(name string, query url.Values) *url.URL {
+	u := *b.endpoint
+	p := "/" + name
+	if b.pathStyle {
+		p = "/" + b.bucket + p
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + p
+	u.RawPath = strings.TrimSuffix(u.EscapedPath(), "/") + uriEncode(p, false)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+func (b *s3Bucket) do(method string, name string, query url.Values, body io.Reader, size int64, contentType string) (*http.Response, error) {
+	req, err := http.NewRequest(method, b.objectUrl(name, query).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	b.sign(req)
+	return newClient().Do(req)
+}
+
+// sign signs the request with AWS Signature Version 4, the payload is not signed as it is sent over TLS.
+func (b *s3Bucket) sign(req *http.Request) {
+	amzDate := b.now().UTC().Format(amzDateFormat)
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", unsignedPayload)
+	if b.sessionToken != "" {
+		req.Header.Set("x-amz-security-token", b.sessionToken)
+	}
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+	signature := b.signature(req.Method, req.URL, headers, amzDate)
+	req.Header.Set(
+		"Authorization",
+		fmt.Sprintf(
+			"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+			s3Algorithm,
+			b.accessKey,
+			b.scope(amzDate),
+			signedHeaders(headers),
+			signature,
+		),
+	)
+}
+
+func (b *s3Bucket) scope(amzDate string) string {
+	return amzDate[:8] + "/" + b.region + "/s3/aws4_request"
+}
+
+func (b *s3Bucket) signature(method string, u *url.URL, headers map[string]string, amzDate string) string {
+	names := strings.Split(signedHeaders(headers), ";")
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	canonicalRequest := strings.Join(
+		[]string{
+			method,
+			u.EscapedPath(),
+			u.RawQuery,
+			canonicalHeaders.String(),
+			strings.Join(names, ";"),
+			unsignedPayload,
+		}, "\n",
+	)
+	stringToSign := s3Algorithm + "\n" + amzDate + "\n" + b.scope(amzDate) + "\n" + sha256Hex(canonicalRequest)
+	key := hmacSha256([]byte("AWS4"+b.secretKey), amzDate[:8])
+	for _, part := range []string{b.region, "s3", "aws4_request"} {
+		key = hmacSha256(key, part)
+	}
+	return hex.EncodeToString(hmacSha256(key, stringToSign))
+}
+
+func signedHeaders(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ";")
+}
+
+// canonicalQuery encodes the query as required by AWS Signature Version 4.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode encodes everything but the unreserved characters, slashes are kept unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for _, c := range []byte(s) {
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' && !encodeSlash {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+	return sb.String()
+}
+
+func s3Error(resp *http.Response) error {
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&s3Err); err == nil && s3Err.Code != "" {
+		return fmt.Errorf("S3 responded with %s: %s: %s", resp.Status, s3Err.Code, s3Err.Message)
+	}
+	return fmt.Errorf("S3 responded with %s", resp.Status)
+}
+
+func sha256Hex(s string) string {
+	hash := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdstorage reads and writes objects in S3 (and S3-compatible), Google Cloud Storage
+// and Azure Blob Storage buckets using their REST APIs, configured with the standard environment variables of each cloud.
+package qdstorage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const transferTimeout = 30 * time.Minute
+
+// ErrSigningUnsupported is returned by SignedUrl when the credentials can't sign URLs.
+var ErrSigningUnsupported = errors.New("signed URLs are not supported with the configured credentials")
+
+// Object is an object stored in a bucket.
+type Object struct {
+	Name     string
+	Modified time.Time
+}
+
+// Bucket is a bucket (or a container) of an object storage.
+type Bucket interface {
+	// Get opens the object, ok is false if there is no such object.
+	Get(name string) (r io.ReadCloser, ok bool, err error)
+	// Put stores the object of size bytes read from r, replacing the existing one.
+	Put(name string, r io.Reader, size int64, contentType string) error
+	// List returns the objects with names starting with prefix.
+	List(prefix string) ([]Object, error)
+	// Delete deletes the object, deleting a missing object is not an error.
+	Delete(name string) error
+	// Url returns the URL of the object, accessible if the bucket allows public reads.
+	Url(name string) string
+	// SignedUrl returns the URL of the object readable without credentials until it expires.
+	SignedUrl(name string, expiry time.Duration) (string, error)
+}
+
+// IsLocation checks if location is an object storage URL supported by Open.
+func IsLocation(location string) bool {
+	for _, scheme := range []string{"s3://", "gs://", "az://"} {
+		if strings.HasPrefix(location, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Open opens the bucket of the object storage location s3://bucket[/prefix], gs://bucket[/prefix]
+// or az://container[/prefix], and returns the prefix of the location without slashes around it.
+func Open(location string) (Bucket, string, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid storage location %s: %w", location, err)
+	}
+	if u.Host == "" {
+		return nil, "", fmt.Errorf("invalid storage location %s: no bucket", location)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	var bucket Bucket
+	switch u.Scheme {
+	case "s3":
+		bucket, err = newS3Bucket(u.Host)
+	case "gs":
+		bucket, err = newGcsBucket(u.Host)
+	case "az":
+		bucket, err = newAzureContainer(u.Host)
+	default:
+		err = fmt.Errorf("unsupported storage location %s, use s3://, gs:// or az://", location)
+	}
+	return bucket, prefix, err
+}
+
+// Join joins the prefix and the name of the object.
+func Join(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
+
+func newClient() *http.Client {
+	return &http.Client{Timeout: transferTimeout, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}}
+}
@@ -0,0 +1,352 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdstore keeps the large files of the results directories in a content-addressed store on the machine:
+// the results of different runs share the identical files, e.g. the report bundle, as hard links to the same object.
+//
+// The store keeps the objects by their SHA-256 hashes and a ref for every deduplicated results directory, listing
+// its linked files. A results directory is unshared before a run writes to it again, so the objects are never modified,
+// and the objects no results directory links to anymore are removed by GC.
+package qdstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	// MinSize is the size of the smallest deduplicated file, the smaller files don't save enough to be linked.
+	MinSize = 16 * 1024
+
+	objectsDir = "objects"
+	refsDir    = "refs"
+	refExt     = ".json"
+	linkSuffix = ".qodana-link"
+)
+
+// Dir returns the store in the Qodana system directory.
+func Dir(systemDir string) string {
+	return filepath.Join(systemDir, "store")
+}
+
+// ref lists the files of a results directory linked to the objects, by their slash-separated relative paths.
+type ref struct {
+	Dir   string            `json:"dir"`
+	Files map[string]string `json:"files"`
+}
+
+// Stats is the result of deduplicating a results directory.
+type Stats struct {
+	// Files is the number of the files linked to the store.
+	Files int
+	// Shared is the size of the files whose content was already in the store.
+	Shared int64
+}
+
+// GcStats is the result of collecting the store.
+type GcStats struct {
+	// Removed is the number of the removed objects.
+	Removed int
+	// Freed is the size of the removed objects.
+	Freed int64
+	// Kept is the number of the objects still linked from the results directories.
+	Kept int
+	// Dirs is the number of the results directories linking to the store.
+	Dirs int
+}
+
+// Dedup replaces the files of at least MinSize bytes in dir with hard links to the objects in store, adding the new
+// contents to it. The store must be on the same file system as dir.
+func Dedup(store string, dir string) (Stats, error) {
+	var stats Stats
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return stats, err
+	}
+	r := ref{Dir: dir, Files: map[string]string{}}
+	err = filepath.WalkDir(
+		dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() || strings.HasSuffix(path, linkSuffix) {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil || info.Size() < MinSize {
+				return err
+			}
+			hash, err := hashFile(path)
+			if err != nil {
+				return err
+			}
+			shared, err := link(store, path, info, hash)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			r.Files[filepath.ToSlash(rel)] = hash
+			stats.Files++
+			if shared {
+				stats.Shared += info.Size()
+			}
+			return nil
+		},
+	)
+	if len(r.Files) > 0 {
+		if refErr := writeRef(store, r); refErr != nil && err == nil {
+			err = refErr
+		}
+	}
+	return stats, err
+}
+
+// link makes the file at path a hard link to the object of hash, it returns whether the object already existed.
+func link(store string, path string, info fs.FileInfo, hash string) (bool, error) {
+	object := objectPath(store, hash)
+	objectInfo, err := os.Stat(object)
+	switch {
+	case err == nil && os.SameFile(info, objectInfo):
+		return true, nil
+	case err == nil && objectInfo.Size() != info.Size():
+		// a damaged object is replaced with the file
+		if err = os.Remove(object); err != nil {
+			return false, err
+		}
+	case err == nil:
+		tmp := path + linkSuffix
+		_ = os.Remove(tmp)
+		if err = os.Link(object, tmp); err != nil {
+			return false, fmt.Errorf("failed to link %s to the store %s: %w", path, store, err)
+		}
+		return true, os.Rename(tmp, path)
+	case !errors.Is(err, fs.ErrNotExist):
+		return false, err
+	}
+	if err = os.MkdirAll(filepath.Dir(object), os.ModePerm); err != nil {
+		return false, err
+	}
+	if err = os.Link(path, object); errors.Is(err, fs.ErrExist) {
+		// added by a concurrent run
+		return link(store, path, info, hash)
+	} else if err != nil {
+		return false, fmt.Errorf("failed to link %s to the store %s: %w", path, store, err)
+	}
+	// the objects are read-only, so a run writing to a results directory that wasn't unshared fails instead of
+	// changing the files of the other runs; Windows doesn't remove read-only files
+	if runtime.GOOS != "windows" {
+		if err = os.Chmod(object, info.Mode().Perm()&^0o222); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// Unshare replaces the files of dir linked to the store with their own writable copies, so a run can write to dir
+// without changing the store, it returns the number of the copied files.
+func Unshare(store string, dir string) (int, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return 0, err
+	}
+	r, err := readRef(refPath(store, dir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	copied := 0
+	for rel := range r.Files {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		info, err := os.Lstat(path)
+		if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.Mode().IsRegular()) {
+			continue
+		}
+		if err != nil {
+			return copied, err
+		}
+		if err = copyFile(path, path+linkSuffix, info.Mode().Perm()|0o200); err != nil {
+			return copied, err
+		}
+		if err = os.Rename(path+linkSuffix, path); err != nil {
+			return copied, err
+		}
+		copied++
+	}
+	return copied, os.Remove(refPath(store, dir))
+}
+
+// GC removes the refs of the results directories removed or unshared since they were deduplicated and the objects
+// no results directory links to, with dryRun only the stats are computed. Objects added by a running scan before
+// it writes its ref may be removed too, its results then keep their own files.
+func GC(store string, dryRun bool) (GcStats, error) {
+	var stats GcStats
+	live := map[string]bool{}
+	refs, err := os.ReadDir(filepath.Join(store, refsDir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return stats, err
+	}
+	for _, entry := range refs {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != refExt {
+			continue
+		}
+		path := filepath.Join(store, refsDir, entry.Name())
+		r, err := readRef(path)
+		if err != nil {
+			return stats, err
+		}
+		linked := liveFiles(store, r)
+		for _, hash := range linked {
+			live[hash] = true
+		}
+		switch {
+		case dryRun:
+		case len(linked) == 0:
+			err = os.Remove(path)
+		case len(linked) != len(r.Files):
+			err = writeRef(store, ref{Dir: r.Dir, Files: linked})
+		}
+		if err != nil {
+			return stats, err
+		}
+		if len(linked) > 0 {
+			stats.Dirs++
+		}
+	}
+	objects := filepath.Join(store, objectsDir)
+	err = filepath.WalkDir(
+		objects, func(path string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) && path == objects {
+				return nil
+			}
+			if err != nil || d.IsDir() {
+				return err
+			}
+			if live[d.Name()] {
+				stats.Kept++
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if !dryRun {
+				if err = os.Remove(path); err != nil {
+					return err
+				}
+				_ = os.Remove(filepath.Dir(path)) // removed only if empty
+			}
+			stats.Removed++
+			stats.Freed += info.Size()
+			return nil
+		},
+	)
+	return stats, err
+}
+
+// liveFiles returns the files of the ref still linked to their objects.
+func liveFiles(store string, r ref) map[string]string {
+	linked := map[string]string{}
+	for rel, hash := range r.Files {
+		info, err := os.Lstat(filepath.Join(r.Dir, filepath.FromSlash(rel)))
+		if err != nil {
+			continue
+		}
+		objectInfo, err := os.Stat(objectPath(store, hash))
+		if err == nil && os.SameFile(info, objectInfo) {
+			linked[rel] = hash
+		}
+	}
+	return linked
+}
+
+func objectPath(store string, hash string) string {
+	return filepath.Join(store, objectsDir, hash[:2], hash)
+}
+
+func refPath(store string, dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	return filepath.Join(store, refsDir, hex.EncodeToString(sum[:])+refExt)
+}
+
+func readRef(path string) (ref, error) {
+	var r ref
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	if err = json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// writeRef replaces the ref of the directory atomically, a concurrent GC reads either the old or the new one.
+func writeRef(store string, r ref) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := refPath(store, r.Dir)
+	if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	if err = os.WriteFile(path+linkSuffix, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+linkSuffix, path)
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func copyFile(source string, target string, mode os.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	_ = os.Remove(target)
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
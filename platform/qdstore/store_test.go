@@ -0,0 +1,111 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdstore
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeResults writes the results directory of a run with a report bundle shared by the runs and a small SARIF.
+func writeResults(t *testing.T, dir string, sarif string) {
+	if err := os.MkdirAll(filepath.Join(dir, "report"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	bundle := bytes.Repeat([]byte("report bundle\n"), MinSize)
+	if err := os.WriteFile(filepath.Join(dir, "report", "bundle.js"), bundle, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "qodana.sarif.json"), []byte(sarif), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sameFile(t *testing.T, a string, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return os.SameFile(aInfo, bInfo)
+}
+
+func TestDedupUnshareGC(t *testing.T) {
+	root := t.TempDir()
+	store := Dir(filepath.Join(root, "system"))
+	first := filepath.Join(root, "results-1")
+	second := filepath.Join(root, "results-2")
+	writeResults(t, first, `{"runs": 1}`)
+	writeResults(t, second, `{"runs": 2}`)
+	bundleSize := int64(len("report bundle\n") * MinSize)
+
+	if stats, err := Dedup(store, first); err != nil || stats != (Stats{Files: 1}) {
+		t.Fatalf("unexpected stats of the first run %+v: %v", stats, err)
+	}
+	if stats, err := Dedup(store, second); err != nil || stats != (Stats{Files: 1, Shared: bundleSize}) {
+		t.Fatalf("unexpected stats of the second run %+v: %v", stats, err)
+	}
+	firstBundle := filepath.Join(first, "report", "bundle.js")
+	secondBundle := filepath.Join(second, "report", "bundle.js")
+	if !sameFile(t, firstBundle, secondBundle) {
+		t.Errorf("expected the report bundles to be linked")
+	}
+	if sameFile(t, filepath.Join(first, "qodana.sarif.json"), filepath.Join(second, "qodana.sarif.json")) {
+		t.Errorf("expected the small files to be kept as is")
+	}
+
+	if copied, err := Unshare(store, first); err != nil || copied != 1 {
+		t.Fatalf("expected the bundle to be copied, got %d: %v", copied, err)
+	}
+	if err := os.WriteFile(firstBundle, []byte("changed"), 0o644); err != nil {
+		t.Fatalf("expected the unshared bundle to be writable: %v", err)
+	}
+	if data, err := os.ReadFile(secondBundle); err != nil || int64(len(data)) != bundleSize {
+		t.Errorf("expected the bundle of the second run to be kept: %v", err)
+	}
+
+	if stats, err := GC(store, false); err != nil || stats != (GcStats{Kept: 1, Dirs: 1}) {
+		t.Fatalf("expected the linked object to be kept, got %+v: %v", stats, err)
+	}
+	if err := os.RemoveAll(second); err != nil {
+		t.Fatal(err)
+	}
+	expected := GcStats{Removed: 1, Freed: bundleSize}
+	if stats, err := GC(store, true); err != nil || stats != expected {
+		t.Fatalf("expected the dry run to report the unused object, got %+v: %v", stats, err)
+	}
+	if stats, err := GC(store, false); err != nil || stats != expected {
+		t.Fatalf("expected the unused object to be removed, got %+v: %v", stats, err)
+	}
+	if stats, err := GC(store, false); err != nil || stats != (GcStats{}) {
+		t.Errorf("expected an empty store, got %+v: %v", stats, err)
+	}
+}
+
+func TestGCWithoutStore(t *testing.T) {
+	if stats, err := GC(filepath.Join(t.TempDir(), "store"), false); err != nil || stats != (GcStats{}) {
+		t.Errorf("expected nothing to collect, got %+v: %v", stats, err)
+	}
+	if copied, err := Unshare(filepath.Join(t.TempDir(), "store"), t.TempDir()); err != nil || copied != 0 {
+		t.Errorf("expected nothing to unshare, got %d: %v", copied, err)
+	}
+}
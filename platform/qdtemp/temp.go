@@ -0,0 +1,176 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdtemp keeps the temporary files of a run, e.g. the extracted tools, the archives and the patches,
+// in a directory of its own under the temp root, so they are removed when the run exits, or with `qodana clean --temp`
+// if it didn't exit cleanly.
+package qdtemp
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/shirou/gopsutil/v3/process"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// runPrefix starts the names of the run directories, followed by the process id: run-<pid>-<random>.
+const runPrefix = "run-"
+
+var (
+	mu     sync.Mutex
+	root   string
+	runDir string
+)
+
+// SetRoot sets the temp root from --temp-dir, the QODANA_TMP environment variable is used if it's empty.
+func SetRoot(dir string) {
+	mu.Lock()
+	defer mu.Unlock()
+	root = dir
+}
+
+// Root returns the temp root: --temp-dir, QODANA_TMP or the qodana directory in the system temp directory.
+func Root() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return rootLocked()
+}
+
+func rootLocked() string {
+	if root != "" {
+		return root
+	}
+	if dir := os.Getenv(qdenv.QodanaTmpEnv); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "qodana")
+}
+
+// Configured reports whether the temp root is set explicitly with --temp-dir or QODANA_TMP.
+func Configured() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return root != "" || os.Getenv(qdenv.QodanaTmpEnv) != ""
+}
+
+// Dir returns the temp directory of the run, it's created on the first call.
+func Dir() (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if runDir != "" {
+		return runDir, nil
+	}
+	base := rootLocked()
+	if err := os.MkdirAll(base, 0o700); err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp(base, runPrefix+strconv.Itoa(os.Getpid())+"-")
+	if err != nil {
+		return "", err
+	}
+	runDir = dir
+	return runDir, nil
+}
+
+// MkdirTemp creates a new directory in the temp directory of the run, see os.MkdirTemp.
+func MkdirTemp(pattern string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(dir, pattern)
+}
+
+// CreateTemp creates a new file in the temp directory of the run, see os.CreateTemp.
+func CreateTemp(pattern string) (*os.File, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(dir, pattern)
+}
+
+// Cleanup removes the temp directory of the run, it's created again if needed.
+func Cleanup() {
+	mu.Lock()
+	defer mu.Unlock()
+	if runDir != "" {
+		_ = os.RemoveAll(runDir)
+		runDir = ""
+	}
+}
+
+// Clean removes the temp directories of the runs that are no longer running and returns their number and size.
+func Clean() (int, int64, error) {
+	base := Root()
+	entries, err := os.ReadDir(base)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, err
+	}
+	removed, size := 0, int64(0)
+	for _, entry := range entries {
+		pid, ok := runPid(entry.Name())
+		if !entry.IsDir() || !ok || pid == os.Getpid() || isRunning(pid) {
+			continue
+		}
+		dir := filepath.Join(base, entry.Name())
+		dirSize := sizeOf(dir)
+		if err = os.RemoveAll(dir); err != nil {
+			return removed, size, err
+		}
+		removed++
+		size += dirSize
+	}
+	return removed, size, nil
+}
+
+// runPid returns the process id of the run directory name.
+func runPid(name string) (int, bool) {
+	rest, found := strings.CutPrefix(name, runPrefix)
+	if !found {
+		return 0, false
+	}
+	pid, _, _ := strings.Cut(rest, "-")
+	value, err := strconv.Atoi(pid)
+	return value, err == nil
+}
+
+// isRunning is replaced in tests.
+var isRunning = func(pid int) bool {
+	exists, err := process.PidExists(int32(pid))
+	return err != nil || exists
+}
+
+func sizeOf(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(
+		dir, func(_ string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				if info, err := d.Info(); err == nil {
+					size += info.Size()
+				}
+			}
+			return nil
+		},
+	)
+	return size
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdtemp
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRoot(t *testing.T) {
+	t.Cleanup(func() { SetRoot("") })
+	t.Setenv(qdenv.QodanaTmpEnv, "")
+	if Root() != filepath.Join(os.TempDir(), "qodana") || Configured() {
+		t.Errorf("the default root is %s", Root())
+	}
+	t.Setenv(qdenv.QodanaTmpEnv, "/tmp/env")
+	if Root() != "/tmp/env" || !Configured() {
+		t.Errorf("the root is %s, want the %s value", Root(), qdenv.QodanaTmpEnv)
+	}
+	SetRoot("/tmp/flag")
+	if Root() != "/tmp/flag" {
+		t.Errorf("the root is %s, want the --temp-dir value", Root())
+	}
+}
+
+func TestRunDir(t *testing.T) {
+	root := t.TempDir()
+	SetRoot(root)
+	t.Cleanup(func() { SetRoot("") })
+	file, err := CreateTemp("qodana-fixes-*.patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+	dir, err := MkdirTemp("qodana-platform")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runDir, _ := Dir()
+	if filepath.Dir(runDir) != root || !strings.HasPrefix(filepath.Base(runDir), runPrefix+strconv.Itoa(os.Getpid())+"-") {
+		t.Errorf("unexpected run directory %s", runDir)
+	}
+	if filepath.Dir(file.Name()) != runDir || filepath.Dir(dir) != runDir {
+		t.Errorf("%s and %s are not in the run directory %s", file.Name(), dir, runDir)
+	}
+	Cleanup()
+	if _, err = os.Stat(runDir); !os.IsNotExist(err) {
+		t.Errorf("%s is not removed", runDir)
+	}
+	again, err := Dir()
+	if err != nil || again == runDir {
+		t.Errorf("a new run directory is expected after the cleanup, got %s %v", again, err)
+	}
+	Cleanup()
+}
+
+func TestClean(t *testing.T) {
+	root := t.TempDir()
+	SetRoot(root)
+	t.Cleanup(func() { SetRoot("") })
+	running := isRunning
+	t.Cleanup(func() { isRunning = running })
+	isRunning = func(pid int) bool { return pid == 2 }
+	for _, name := range []string{"run-1-abc", "run-2-def", "run-" + strconv.Itoa(os.Getpid()) + "-ghi", "run-x-jkl", "other"} {
+		if err := os.MkdirAll(filepath.Join(root, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "run-1-abc", "archive"), make([]byte, 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	removed, size, err := Clean()
+	if err != nil || removed != 1 || size != 100 {
+		t.Errorf("Clean() = %d, %d, %v, want 1 run of 100 bytes removed", removed, size, err)
+	}
+	for name, expected := range map[string]bool{
+		"run-1-abc": false,
+		"run-2-def": true,
+		"run-" + strconv.Itoa(os.Getpid()) + "-ghi": true,
+		"run-x-jkl": true,
+		"other":     true,
+	} {
+		if _, err = os.Stat(filepath.Join(root, name)); (err == nil) != expected {
+			t.Errorf("%s is kept: %v, want %v", name, err == nil, expected)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdtiming reads the time the IDE spent running each inspection, to find the inspections slowing
+// the analysis down.
+package qdtiming
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// IdeProperty makes the IDE record the time of each inspection to TimingsFileName in the results directory.
+	IdeProperty = "qodana.inspection.timings"
+	// TimingsFileName is the file the IDE records the timings to.
+	TimingsFileName = "inspection-timings.json"
+	// ProfileFileName is the sorted profile written to the results directory.
+	ProfileFileName = "inspection-profile.json"
+)
+
+// ErrNoTimings is returned by Load if the linter has not recorded the timings.
+var ErrNoTimings = errors.New("the linter has not recorded the inspection timings")
+
+// Timing is the time the inspection ran for, in total over the files it inspected.
+type Timing struct {
+	Inspection string `json:"inspection"`
+	TimeMs     int64  `json:"timeMs"`
+	Files      int    `json:"files,omitempty"`
+	// Share is the percentage of the time of all the inspections.
+	Share float64 `json:"share"`
+}
+
+// Load reads the timings recorded by the IDE in resultsDir or in its log directory, sorted from the slowest
+// inspection, with the share of each one computed.
+func Load(resultsDir string) ([]Timing, error) {
+	for _, path := range []string{
+		filepath.Join(resultsDir, TimingsFileName),
+		filepath.Join(resultsDir, "log", TimingsFileName),
+	} {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var timings []Timing
+		if err = json.Unmarshal(data, &timings); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return profile(timings), nil
+	}
+	return nil, ErrNoTimings
+}
+
+// profile merges the timings of the same inspection, sorts them and computes the shares.
+func profile(timings []Timing) []Timing {
+	merged := map[string]*Timing{}
+	var total int64
+	for _, t := range timings {
+		m, ok := merged[t.Inspection]
+		if !ok {
+			m = &Timing{Inspection: t.Inspection}
+			merged[t.Inspection] = m
+		}
+		m.TimeMs += t.TimeMs
+		m.Files += t.Files
+		total += t.TimeMs
+	}
+	result := make([]Timing, 0, len(merged))
+	for _, t := range merged {
+		if total > 0 {
+			t.Share = float64(t.TimeMs) * 100 / float64(total)
+		}
+		result = append(result, *t)
+	}
+	sort.Slice(
+		result, func(i, j int) bool {
+			if result[i].TimeMs != result[j].TimeMs {
+				return result[i].TimeMs > result[j].TimeMs
+			}
+			return result[i].Inspection < result[j].Inspection
+		},
+	)
+	return result
+}
+
+// Write writes the profile to inspection-profile.json in dir.
+func Write(timings []Timing, dir string) (string, error) {
+	data, err := json.MarshalIndent(timings, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, ProfileFileName)
+	return path, os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdtiming
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load(dir); !errors.Is(err, ErrNoTimings) {
+		t.Errorf("expected ErrNoTimings, got %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "log"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	timings := `[
+		{"inspection": "UnusedDeclaration", "timeMs": 600, "files": 10},
+		{"inspection": "SpellCheckingInspection", "timeMs": 300, "files": 40},
+		{"inspection": "UnusedDeclaration", "timeMs": 100, "files": 2},
+		{"inspection": "ConstantValue", "timeMs": 0}
+	]`
+	if err := os.WriteFile(filepath.Join(dir, "log", TimingsFileName), []byte(timings), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Timing{
+		{Inspection: "UnusedDeclaration", TimeMs: 700, Files: 12, Share: 70},
+		{Inspection: "SpellCheckingInspection", TimeMs: 300, Files: 40, Share: 30},
+		{Inspection: "ConstantValue"},
+	}
+	if !reflect.DeepEqual(profile, expected) {
+		t.Errorf("got %+v, want %+v", profile, expected)
+	}
+
+	path, err := Write(profile, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,318 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdtrace records the stages of a Qodana run as OpenTelemetry spans
+// and exports them via OTLP/HTTP (JSON encoding) when OTEL_EXPORTER_OTLP_ENDPOINT is set.
+// The recorded spans are also used for the stage timing summary of the run.
+package qdtrace
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultServiceName = "qodana-cli"
+	exportTimeout      = 10 * time.Second
+	tracesPath         = "/v1/traces"
+)
+
+// Span is a single timed stage of the run. A nil *Span is valid and does nothing,
+// so callers don't need to check whether tracing is enabled.
+type Span struct {
+	traceId      string
+	spanId       string
+	parentSpanId string
+	name         string
+	start        time.Time
+	end          time.Time
+	attributes   map[string]string
+	err          error
+}
+
+var (
+	mu       sync.Mutex
+	root     *Span
+	finished []*Span
+)
+
+// Init starts the root span of the run, continuing the trace from TRACEPARENT if it is set.
+func Init(name string) *Span {
+	mu.Lock()
+	defer mu.Unlock()
+	if root != nil {
+		return root
+	}
+	root = &Span{traceId: randomHex(16), spanId: randomHex(8), name: name, start: time.Now()}
+	if traceId, parentSpanId, ok := parseTraceParent(os.Getenv(qdenv.TraceParentEnv)); ok {
+		root.traceId = traceId
+		root.parentSpanId = parentSpanId
+	}
+	return root
+}
+
+// Start starts a new span as a child of the root span.
+func Start(name string) *Span {
+	mu.Lock()
+	r := root
+	mu.Unlock()
+	return r.StartChild(name)
+}
+
+// StartChild starts a new span as a child of the span.
+func (s *Span) StartChild(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	return &Span{traceId: s.traceId, spanId: randomHex(8), parentSpanId: s.spanId, name: name, start: time.Now()}
+}
+
+// SetAttribute sets the attribute of the span.
+func (s *Span) SetAttribute(key string, value string) {
+	if s == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if s.attributes == nil {
+		s.attributes = map[string]string{}
+	}
+	s.attributes[key] = value
+}
+
+// End finishes the span, non-nil err marks the span as failed.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !s.end.IsZero() {
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+	finished = append(finished, s)
+}
+
+// TraceParent returns the W3C traceparent of the root span to continue the trace in child processes.
+// It is empty if the traces are not exported.
+func TraceParent() string {
+	mu.Lock()
+	defer mu.Unlock()
+	if root == nil || tracesEndpoint() == "" {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", root.traceId, root.spanId)
+}
+
+// TimeoutError ends the span of a stage that reached its timeout.
+type TimeoutError struct {
+	// Stage is the name of the timeout: bootstrap, indexing, inspection or analysis for the timeout of the whole run.
+	Stage   string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s reached timeout %s", e.Stage, e.Timeout)
+}
+
+// Stage is the timing of a finished span.
+type Stage struct {
+	Name     string        `json:"name"`
+	Parent   string        `json:"parent,omitempty"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"-"`
+	Failed   bool          `json:"failed,omitempty"`
+	TimedOut bool          `json:"timedOut,omitempty"`
+}
+
+// MarshalJSON writes the duration in milliseconds.
+func (s Stage) MarshalJSON() ([]byte, error) {
+	type stage Stage
+	return json.Marshal(
+		struct {
+			stage
+			Duration int64 `json:"durationMs"`
+		}{stage(s), s.Duration.Milliseconds()},
+	)
+}
+
+// UnmarshalJSON reads the duration in milliseconds.
+func (s *Stage) UnmarshalJSON(data []byte) error {
+	type stage Stage
+	var v struct {
+		stage
+		Duration int64 `json:"durationMs"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = Stage(v.stage)
+	s.Duration = time.Duration(v.Duration) * time.Millisecond
+	return nil
+}
+
+// Stages returns the timings of the finished stages ordered by their start, the root span is not included.
+func Stages() []Stage {
+	mu.Lock()
+	defer mu.Unlock()
+	names := map[string]string{}
+	for _, s := range finished {
+		names[s.spanId] = s.name
+	}
+	var stages []Stage
+	for _, s := range finished {
+		if s == root {
+			continue
+		}
+		var timeoutErr *TimeoutError
+		stages = append(
+			stages,
+			Stage{
+				Name:     s.name,
+				Parent:   names[s.parentSpanId],
+				Start:    s.start,
+				Duration: s.end.Sub(s.start),
+				Failed:   s.err != nil,
+				TimedOut: errors.As(s.err, &timeoutErr),
+			},
+		)
+	}
+	sort.SliceStable(stages, func(i, j int) bool { return stages[i].Start.Before(stages[j].Start) })
+	return stages
+}
+
+// TimedOut returns the timeout reached by the first finished span ended with a *TimeoutError.
+func TimedOut() (*TimeoutError, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, s := range finished {
+		var timeoutErr *TimeoutError
+		if errors.As(s.err, &timeoutErr) {
+			return timeoutErr, true
+		}
+	}
+	return nil, false
+}
+
+// Elapsed returns the time passed since the root span has started.
+func Elapsed() time.Duration {
+	mu.Lock()
+	defer mu.Unlock()
+	if root == nil {
+		return 0
+	}
+	return time.Since(root.start)
+}
+
+// Shutdown finishes the root span and exports all finished spans if OTEL_EXPORTER_OTLP_ENDPOINT is set.
+func Shutdown() {
+	mu.Lock()
+	r := root
+	mu.Unlock()
+	if r == nil {
+		return
+	}
+	r.End(nil)
+
+	mu.Lock()
+	spans := finished
+	root, finished = nil, nil
+	mu.Unlock()
+	endpoint := tracesEndpoint()
+	if endpoint == "" {
+		return
+	}
+	if err := export(endpoint, spans); err != nil {
+		log.Warnf("Failed to export traces: %v", err)
+	}
+}
+
+// tracesEndpoint returns the OTLP/HTTP traces endpoint configured with the standard OpenTelemetry variables.
+func tracesEndpoint() string {
+	if e := os.Getenv(qdenv.OtelExporterOtlpTracesEndpointEnv); e != "" {
+		return e
+	}
+	if e := os.Getenv(qdenv.OtelExporterOtlpEndpointEnv); e != "" {
+		return strings.TrimSuffix(e, "/") + tracesPath
+	}
+	return ""
+}
+
+func export(endpoint string, spans []*Span) error {
+	body, err := marshalSpans(spans)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, header := range strings.Split(os.Getenv(qdenv.OtelExporterOtlpHeadersEnv), ",") {
+		if key, value, ok := strings.Cut(header, "="); ok {
+			req.Header.Set(strings.TrimSpace(key), strings.TrimSpace(value))
+		}
+	}
+	client := &http.Client{Timeout: exportTimeout, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded with %s", endpoint, resp.Status)
+	}
+	return nil
+}
+
+func serviceName() string {
+	if name := os.Getenv(qdenv.OtelServiceNameEnv); name != "" {
+		return name
+	}
+	return defaultServiceName
+}
+
+// parseTraceParent parses W3C traceparent header value.
+func parseTraceParent(value string) (traceId string, spanId string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	if _, err := hex.DecodeString(parts[1] + parts[2]); err != nil {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
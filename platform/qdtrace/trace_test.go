@@ -0,0 +1,167 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdtrace
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotExportedWithoutEndpoint(t *testing.T) {
+	t.Setenv(qdenv.OtelExporterOtlpEndpointEnv, "")
+	t.Setenv(qdenv.OtelExporterOtlpTracesEndpointEnv, "")
+	Init("qodana scan")
+	Start("stage").End(nil)
+	if TraceParent() != "" {
+		t.Errorf("expected empty traceparent")
+	}
+	if len(Stages()) != 1 {
+		t.Errorf("expected stages to be recorded without the endpoint")
+	}
+	Shutdown()
+	if len(Stages()) != 0 {
+		t.Errorf("expected stages to be reset after shutdown")
+	}
+}
+
+func TestNilSpan(t *testing.T) {
+	var span *Span
+	span.SetAttribute("key", "value")
+	span.StartChild("child").End(nil)
+	span.End(nil)
+	if Start("stage") != nil {
+		t.Errorf("expected nil span without the root span")
+	}
+}
+
+func TestStages(t *testing.T) {
+	t.Setenv(qdenv.OtelExporterOtlpEndpointEnv, "")
+	t.Setenv(qdenv.OtelExporterOtlpTracesEndpointEnv, "")
+	Init("qodana scan")
+	defer Shutdown()
+	run := Start("container run")
+	indexing := run.StartChild("indexing")
+	time.Sleep(time.Millisecond)
+	indexing.End(nil)
+	run.End(errors.New("failed"))
+
+	stages := Stages()
+	if len(stages) != 2 {
+		t.Fatalf("expected 2 stages, got %d", len(stages))
+	}
+	if stages[0].Name != "container run" || !stages[0].Failed || stages[0].Parent != "" {
+		t.Errorf("unexpected stage %+v", stages[0])
+	}
+	if stages[1].Name != "indexing" || stages[1].Parent != "container run" || stages[1].Duration <= 0 {
+		t.Errorf("unexpected stage %+v", stages[1])
+	}
+	data, err := json.Marshal(stages[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	_ = json.Unmarshal(data, &decoded)
+	if _, ok := decoded["durationMs"].(float64); !ok {
+		t.Errorf("expected durationMs in %s", data)
+	}
+	var stage Stage
+	if err = json.Unmarshal([]byte(`{"name":"indexing","parent":"container run","durationMs":1500}`), &stage); err != nil {
+		t.Fatal(err)
+	}
+	if stage.Name != "indexing" || stage.Parent != "container run" || stage.Duration != 1500*time.Millisecond {
+		t.Errorf("unexpected decoded stage %+v", stage)
+	}
+}
+
+func TestTimedOut(t *testing.T) {
+	t.Setenv(qdenv.OtelExporterOtlpEndpointEnv, "")
+	t.Setenv(qdenv.OtelExporterOtlpTracesEndpointEnv, "")
+	Init("qodana scan")
+	defer Shutdown()
+	run := Start("ide run")
+	if _, ok := TimedOut(); ok {
+		t.Fatalf("expected no timeout before the stage ended")
+	}
+	run.StartChild("indexing").End(&TimeoutError{Stage: "indexing", Timeout: time.Minute})
+	run.End(errors.New("failed"))
+
+	timeoutErr, ok := TimedOut()
+	if !ok || timeoutErr.Stage != "indexing" || timeoutErr.Error() != "indexing reached timeout 1m0s" {
+		t.Errorf("unexpected timeout %v", timeoutErr)
+	}
+	for _, stage := range Stages() {
+		if stage.TimedOut != (stage.Name == "indexing") {
+			t.Errorf("unexpected stage %+v", stage)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	var received otlpTraces
+	var headers http.Header
+	svr := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tracesPath {
+					t.Errorf("expected %s, got %s", tracesPath, r.URL.Path)
+				}
+				headers = r.Header
+				body, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(body, &received); err != nil {
+					t.Error(err)
+				}
+			},
+		),
+	)
+	defer svr.Close()
+	t.Setenv(qdenv.OtelExporterOtlpEndpointEnv, svr.URL+"/")
+	t.Setenv(qdenv.OtelExporterOtlpTracesEndpointEnv, "")
+	t.Setenv(qdenv.OtelExporterOtlpHeadersEnv, "x-api-key=secret")
+	t.Setenv(qdenv.TraceParentEnv, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	root := Init("qodana scan")
+	root.SetAttribute("qodana.exit_code", "0")
+	Start("image pull").End(nil)
+	Start("container run").End(errors.New("failed"))
+	Shutdown()
+
+	if headers.Get("x-api-key") != "secret" {
+		t.Errorf("expected OTEL_EXPORTER_OTLP_HEADERS to be sent")
+	}
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	rootSpan := spans[2]
+	if rootSpan.Name != "qodana scan" || rootSpan.TraceId != "0af7651916cd43dd8448eb211c80319c" || rootSpan.ParentSpanId != "b7ad6b7169203331" {
+		t.Errorf("unexpected root span %+v", rootSpan)
+	}
+	for _, span := range spans[:2] {
+		if span.TraceId != rootSpan.TraceId || span.ParentSpanId != rootSpan.SpanId {
+			t.Errorf("span %s is not a child of the root span", span.Name)
+		}
+	}
+	if spans[1].Status.Code != statusCodeError || spans[1].Status.Message != "failed" {
+		t.Errorf("expected failed status, got %+v", spans[1].Status)
+	}
+}
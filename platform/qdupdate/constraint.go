@@ -0,0 +1,136 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdupdate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraint is the versions of the CLI a project requires, e.g. ">=2024.2 <2025": all the comparisons must hold.
+// A version without an operator matches the versions it is a prefix of, e.g. 2024.2 matches 2024.2.6.
+type Constraint struct {
+	spec        string
+	comparisons []comparison
+}
+
+type comparison struct {
+	op      string
+	version []int
+}
+
+var operators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseConstraint parses the space or comma-separated comparisons of the versions.
+func ParseConstraint(spec string) (Constraint, error) {
+	c := Constraint{spec: strings.TrimSpace(spec)}
+	for _, field := range strings.FieldsFunc(spec, func(r rune) bool { return r == ' ' || r == ',' }) {
+		op := ""
+		for _, o := range operators {
+			if strings.HasPrefix(field, o) {
+				op = o
+				break
+			}
+		}
+		version, err := parseVersion(strings.TrimPrefix(field, op))
+		if err != nil {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q: %w", spec, err)
+		}
+		c.comparisons = append(c.comparisons, comparison{op: op, version: version})
+	}
+	if len(c.comparisons) == 0 {
+		return Constraint{}, fmt.Errorf("empty version constraint")
+	}
+	return c, nil
+}
+
+// String returns the constraint as written.
+func (c Constraint) String() string {
+	return c.spec
+}
+
+// Match returns true if the version satisfies all the comparisons, false for a version that can't be parsed.
+func (c Constraint) Match(version string) bool {
+	v, err := parseVersion(version)
+	if err != nil {
+		return false
+	}
+	for _, comparison := range c.comparisons {
+		if !comparison.match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c comparison) match(v []int) bool {
+	if c.op == "" {
+		return len(v) >= len(c.version) && compareVersions(v[:len(c.version)], c.version) == 0
+	}
+	result := compareVersions(v, c.version)
+	switch c.op {
+	case ">=":
+		return result >= 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	case "<":
+		return result < 0
+	case "!=":
+		return result != 0
+	}
+	return result == 0
+}
+
+// parseVersion returns the numbers of the version, e.g. [2024 3 1] for v2024.3.1-eap, the suffix is ignored.
+func parseVersion(version string) ([]int, error) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	var numbers []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%q is not a version", version)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+// compareVersions compares the versions, the missing numbers are zeros.
+func compareVersions(a []int, b []int) int {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
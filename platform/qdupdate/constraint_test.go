@@ -0,0 +1,52 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdupdate
+
+import "testing"
+
+func TestConstraint(t *testing.T) {
+	for _, tc := range []struct {
+		spec     string
+		version  string
+		expected bool
+	}{
+		{">=2024.2 <2025", "2024.2.6", true},
+		{">=2024.2 <2025", "2024.3", true},
+		{">=2024.2 <2025", "2024.1.9", false},
+		{">=2024.2 <2025", "2025.1.0", false},
+		{">=2024.2, <2025", "v2024.3.1-eap", true},
+		{"2024.2", "2024.2.6", true},
+		{"2024.2", "2024.3.0", false},
+		{"=2024.2.6", "2024.2.6", true},
+		{"!=2024.2.6 >2024", "2024.2.6", false},
+		{"<=2024.2", "2024.2.0", true},
+		{">=2024.2", "dev", false},
+	} {
+		c, err := ParseConstraint(tc.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := c.Match(tc.version); actual != tc.expected {
+			t.Errorf("%s matching %s: expected %v, got %v", tc.version, tc.spec, tc.expected, actual)
+		}
+	}
+	for _, spec := range []string{"", ">=", ">=2024.x", "~2024"} {
+		if _, err := ParseConstraint(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdupdate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CheckInterval is how often the releases are checked and a new release is noticed.
+const CheckInterval = 24 * time.Hour
+
+// state is the last check of the releases, kept between the runs so the notice is shown at most once per interval.
+type state struct {
+	CheckedAt  time.Time `json:"checkedAt"`
+	Latest     string    `json:"latest"`
+	NotifiedAt time.Time `json:"notifiedAt,omitempty"`
+}
+
+// Notice returns the latest version of the channel to notify about if it differs from the current version,
+// an empty string if there is nothing to notify about. The releases are checked and the same version is noticed
+// at most once per CheckInterval, the state of the checks is stored in stateFile.
+func (u *Updater) Notice(currentVersion string, channel string, stateFile string, now time.Time) string {
+	s := readState(stateFile)
+	if now.Sub(s.CheckedAt) >= CheckInterval {
+		release, err := u.Latest(channel)
+		if err != nil {
+			return ""
+		}
+		s.CheckedAt, s.Latest = now, release.Version()
+	}
+	notice := ""
+	if s.Latest != "" && s.Latest != currentVersion && now.Sub(s.NotifiedAt) >= CheckInterval {
+		notice = s.Latest
+		s.NotifiedAt = now
+	}
+	writeState(stateFile, s)
+	return notice
+}
+
+func readState(path string) state {
+	var s state
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &s)
+	}
+	return s
+}
+
+func writeState(path string, s state) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+		_ = os.WriteFile(path, data, 0o644)
+	}
+}
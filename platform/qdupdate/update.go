@@ -0,0 +1,286 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package qdupdate updates the CLI binary to the latest release of a channel and notifies about the new releases.
+package qdupdate
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdsign"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultReleasesUrl is the GitHub API endpoint of the CLI releases.
+	DefaultReleasesUrl = "https://api.github.com/repos/JetBrains/qodana-cli/releases"
+	// ChannelStable is the channel of the latest release.
+	ChannelStable = "stable"
+	// ChannelEap is the channel of the latest release or pre-release, whichever is newer.
+	ChannelEap = "eap"
+
+	checksumsAsset = "checksums.txt"
+)
+
+// Channels are the release channels accepted by --channel.
+var Channels = []string{ChannelStable, ChannelEap}
+
+// Release is a published release of the CLI.
+type Release struct {
+	TagName    string  `json:"tag_name"`
+	Prerelease bool    `json:"prerelease"`
+	Draft      bool    `json:"draft"`
+	Assets     []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	Url  string `json:"browser_download_url"`
+}
+
+// Version returns the version of the release without the v prefix of the tag.
+func (r Release) Version() string {
+	return strings.TrimPrefix(r.TagName, "v")
+}
+
+func (r Release) asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Updater downloads the CLI releases for the platform.
+type Updater struct {
+	Url    string
+	Os     string
+	Arch   string
+	Client *http.Client
+}
+
+// NewUpdater returns the updater of the CLI for the current platform.
+func NewUpdater() *Updater {
+	return &Updater{
+		Url:    DefaultReleasesUrl,
+		Os:     runtime.GOOS,
+		Arch:   runtime.GOARCH,
+		Client: &http.Client{Timeout: 10 * time.Minute, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment}},
+	}
+}
+
+// Latest returns the latest release of the channel.
+func (u *Updater) Latest(channel string) (Release, error) {
+	switch channel {
+	case ChannelStable:
+		var release Release
+		return release, u.get(u.Url+"/latest", &release)
+	case ChannelEap:
+		var releases []Release
+		if err := u.get(u.Url+"?per_page=20", &releases); err != nil {
+			return Release{}, err
+		}
+		// the releases are listed newest first
+		for _, release := range releases {
+			if !release.Draft {
+				return release, nil
+			}
+		}
+		return Release{}, fmt.Errorf("no releases found at %s", u.Url)
+	}
+	return Release{}, fmt.Errorf("unknown channel %q, expected one of %s", channel, strings.Join(Channels, ", "))
+}
+
+// Required returns the newest release satisfying the constraint, a pre-release only if no release satisfies it.
+func (u *Updater) Required(c Constraint) (Release, error) {
+	var releases []Release
+	if err := u.get(u.Url+"?per_page=100", &releases); err != nil {
+		return Release{}, err
+	}
+	var prerelease *Release
+	// the releases are listed newest first
+	for i, release := range releases {
+		if release.Draft || !c.Match(release.Version()) {
+			continue
+		}
+		if !release.Prerelease {
+			return release, nil
+		}
+		if prerelease == nil {
+			prerelease = &releases[i]
+		}
+	}
+	if prerelease != nil {
+		return *prerelease, nil
+	}
+	return Release{}, fmt.Errorf("no release satisfies %s", c)
+}
+
+func (u *Updater) get(url string, v any) error {
+	response, err := u.Client.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", url, err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, response.Status)
+	}
+	if err = json.NewDecoder(response.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", url, err)
+	}
+	return nil
+}
+
+// AssetName returns the name of the binary of the platform attached to the releases, e.g. qodana_linux_x86_64.
+func (u *Updater) AssetName() string {
+	arch := u.Arch
+	if arch == "amd64" {
+		arch = "x86_64"
+	}
+	name := fmt.Sprintf("qodana_%s_%s", u.Os, arch)
+	if u.Os == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Download downloads the binary of the release for the platform next to target, verifies it with the checksums
+// of the release and, if the verification of the signatures is enabled, the signature of the checksums,
+// and returns the path of the downloaded binary.
+func (u *Updater) Download(release Release, target string) (string, error) {
+	binary, ok := release.asset(u.AssetName())
+	if !ok {
+		return "", fmt.Errorf("release %s has no binary for %s/%s", release.TagName, u.Os, u.Arch)
+	}
+	checksums, ok := release.asset(checksumsAsset)
+	if !ok {
+		return "", fmt.Errorf("release %s has no %s", release.TagName, checksumsAsset)
+	}
+	dir := filepath.Dir(target)
+	checksumsPath := filepath.Join(dir, "."+filepath.Base(target)+"."+checksumsAsset)
+	defer func() { _ = os.Remove(checksumsPath) }()
+	if err := u.download(checksums.Url, checksumsPath); err != nil {
+		return "", err
+	}
+	if err := qdsign.VerifyDownload(checksumsPath, checksums.Url); err != nil {
+		return "", err
+	}
+	expected, err := checksum(checksumsPath, binary.Name)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "."+filepath.Base(target)+".new")
+	if err = u.download(binary.Url, path); err != nil {
+		return "", err
+	}
+	actual, err := sha256File(path)
+	if err == nil && actual != expected {
+		err = fmt.Errorf("the checksum of %s doesn't match %s of release %s", binary.Name, checksumsAsset, release.TagName)
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+func (u *Updater) download(url string, path string) error {
+	response, err := u.Client.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, response.Status)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, response.Body); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	return f.Close()
+}
+
+// checksum returns the SHA-256 of the file in the checksums file, in the sha256sum format.
+func checksum(checksumsPath string, name string) (string, error) {
+	f, err := os.Open(checksumsPath)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no checksum of %s in %s", name, checksumsAsset)
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Replace replaces the target binary with the downloaded one by renaming it over the target, so the target is
+// either the old or the new binary at any moment. The running binary can't be replaced on Windows, it is moved
+// aside to target.old first and removed on the next update.
+func Replace(target string, downloaded string) error {
+	if runtime.GOOS == "windows" {
+		old := target + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(target, old); err != nil {
+			return err
+		}
+		if err := os.Rename(downloaded, target); err != nil {
+			_ = os.Rename(old, target)
+			return err
+		}
+		return nil
+	}
+	if info, err := os.Stat(target); err == nil {
+		if err = os.Chmod(downloaded, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return os.Rename(downloaded, target)
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdupdate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestUpdater(t *testing.T, binary string, checksum string) *Updater {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	assets := fmt.Sprintf(
+		`[{"name": "qodana_linux_x86_64", "browser_download_url": "%[1]s/qodana"}, {"name": "checksums.txt", "browser_download_url": "%[1]s/checksums.txt"}]`,
+		server.URL,
+	)
+	mux.HandleFunc(
+		"/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, `{"tag_name": "v2024.3.1", "assets": %s}`, assets)
+		},
+	)
+	mux.HandleFunc(
+		"/releases", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, `[{"tag_name": "v2024.3.2-eap", "prerelease": true, "assets": %s}, {"tag_name": "v2024.3.1"}]`, assets)
+		},
+	)
+	mux.HandleFunc(
+		"/qodana", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(binary))
+		},
+	)
+	mux.HandleFunc(
+		"/checksums.txt", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, "0000  qodana_darwin_arm64\n%s  qodana_linux_x86_64\n", checksum)
+		},
+	)
+	return &Updater{Url: server.URL + "/releases", Os: "linux", Arch: "amd64", Client: server.Client()}
+}
+
+func TestUpdate(t *testing.T) {
+	sum := sha256.Sum256([]byte("new binary"))
+	u := newTestUpdater(t, "new binary", hex.EncodeToString(sum[:]))
+
+	stable, err := u.Latest(ChannelStable)
+	if err != nil || stable.Version() != "2024.3.1" {
+		t.Fatalf("unexpected stable release %+v: %v", stable, err)
+	}
+	eap, err := u.Latest(ChannelEap)
+	if err != nil || eap.Version() != "2024.3.2-eap" {
+		t.Fatalf("unexpected EAP release %+v: %v", eap, err)
+	}
+	below, _ := ParseConstraint("<2024.3.2")
+	required, err := u.Required(below)
+	if err != nil || required.Version() != "2024.3.1" {
+		t.Fatalf("unexpected required release %+v: %v", required, err)
+	}
+	above, _ := ParseConstraint(">2024.3.1")
+	if required, err = u.Required(above); err != nil || !required.Prerelease {
+		t.Fatalf("expected the pre-release, got %+v: %v", required, err)
+	}
+	if _, err = u.Latest("nightly"); err == nil {
+		t.Error("expected an error for an unknown channel")
+	}
+
+	target := filepath.Join(t.TempDir(), "qodana")
+	if err = os.WriteFile(target, []byte("old binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	downloaded, err := u.Download(stable, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = Replace(target, downloaded); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "new binary" {
+		t.Errorf("expected the binary to be replaced, got %q", content)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(target)); len(entries) != 1 {
+		t.Errorf("expected only the binary left, got %v", entries)
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	u := newTestUpdater(t, "tampered binary", "0123")
+	release, err := u.Latest(ChannelStable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(t.TempDir(), "qodana")
+	if _, err = u.Download(release, target); err == nil {
+		t.Fatal("expected the checksum mismatch")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(target)); len(entries) != 0 {
+		t.Errorf("expected the download to be removed, got %v", entries)
+	}
+	u.Arch = "riscv64"
+	if _, err = u.Download(release, target); err == nil {
+		t.Error("expected an error for a platform without a binary")
+	}
+}
+
+func TestNotice(t *testing.T) {
+	u := newTestUpdater(t, "", "")
+	stateFile := filepath.Join(t.TempDir(), "update-check.json")
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if notice := u.Notice("2024.3.0", ChannelStable, stateFile, now); notice != "2024.3.1" {
+		t.Fatalf("expected the notice of 2024.3.1, got %q", notice)
+	}
+	if notice := u.Notice("2024.3.0", ChannelStable, stateFile, now.Add(time.Hour)); notice != "" {
+		t.Errorf("expected no notice within the interval, got %q", notice)
+	}
+	u.Url = "http://127.0.0.1:0"
+	if notice := u.Notice("2024.3.0", ChannelStable, stateFile, now.Add(CheckInterval)); notice != "" {
+		t.Errorf("expected no notice if the releases can't be checked, got %q", notice)
+	}
+	if notice := u.Notice("2024.3.1", ChannelStable, filepath.Join(t.TempDir(), "state.json"), now); notice != "" {
+		t.Errorf("expected no notice for an unreachable server, got %q", notice)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdyaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"time"
+)
+
+// Bootstrap is the bootstrap of qodana.yaml: either a single command
+//
+//	bootstrap: npm ci
+//
+// or a list of named steps run one after another
+//
+//	bootstrap:
+//	  - name: dependencies
+//	    run: npm ci
+//	    timeout: 10m
+//	  - name: codegen
+//	    run: npm run generate
+//	    workingDir: packages/api
+//	    env:
+//	      NODE_ENV: production
+type Bootstrap []BootstrapStep
+
+// BootstrapStep is a step of the bootstrap.
+//
+//goland:noinspection GoUnnecessarilyExportedIdentifiers
+type BootstrapStep struct {
+	// Name of the step, shown in the output and used for the name of its log file.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Run is the command of the step.
+	Run string `yaml:"run" json:"run"`
+
+	// Timeout of the step, e.g. 10m, no timeout if not set.
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// WorkingDir is the directory to run the command in, relative to the project.
+	WorkingDir string `yaml:"workingDir,omitempty" json:"workingDir,omitempty"`
+
+	// Env is the environment variables set for the command.
+	Env map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+}
+
+// Command returns the command of a bootstrap written as a single command.
+func (b Bootstrap) Command() (string, bool) {
+	if len(b) != 1 {
+		return "", false
+	}
+	step := b[0]
+	if step.Name != "" || step.Timeout != 0 || step.WorkingDir != "" || len(step.Env) > 0 {
+		return "", false
+	}
+	return step.Run, true
+}
+
+// String returns the command of a single command bootstrap, otherwise the steps as JSON,
+// so the bootstrap can be compared with the one of a previous run.
+func (b Bootstrap) String() string {
+	if command, ok := b.Command(); ok {
+		return command
+	}
+	if len(b) == 0 {
+		return ""
+	}
+	data, err := json.Marshal([]BootstrapStep(b))
+	if err != nil {
+		return fmt.Sprint([]BootstrapStep(b))
+	}
+	return string(data)
+}
+
+// UnmarshalYAML reads a single command or a list of steps.
+func (b *Bootstrap) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var command string
+		if err := value.Decode(&command); err != nil {
+			return err
+		}
+		*b = nil
+		if command != "" {
+			*b = Bootstrap{{Run: command}}
+		}
+		return nil
+	}
+	var steps []BootstrapStep
+	if err := value.Decode(&steps); err != nil {
+		return err
+	}
+	for i, step := range steps {
+		if step.Run == "" {
+			return fmt.Errorf("bootstrap step %d has no run command", i+1)
+		}
+	}
+	*b = steps
+	return nil
+}
+
+// MarshalYAML writes a single command bootstrap as a string.
+func (b Bootstrap) MarshalYAML() (interface{}, error) {
+	if command, ok := b.Command(); ok {
+		return command, nil
+	}
+	return []BootstrapStep(b), nil
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdyaml
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the file with the paths excluded from the analysis by every linter, in the gitignore syntax.
+const IgnoreFileName = ".qodanaignore"
+
+// LoadIgnoreFile reads the .qodanaignore patterns of the project as the paths of the exclude configuration.
+func LoadIgnoreFile(project string) ([]string, error) {
+	f, err := os.Open(filepath.Join(project, IgnoreFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var paths []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if path, ok := ignorePatternToPath(scanner.Text()); ok {
+			paths = append(paths, path)
+		}
+	}
+	return paths, scanner.Err()
+}
+
+// ExcludeIgnored excludes the paths from the analysis by all the checks.
+func (q *QodanaYaml) ExcludeIgnored(paths []string) {
+	for _, path := range paths {
+		q.Exclude("All", path)
+	}
+}
+
+// ignorePatternToPath converts a gitignore pattern: anchored patterns become paths relative to the project root,
+// the others match at any depth.
+func ignorePatternToPath(line string) (string, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", false
+	}
+	if strings.HasPrefix(line, "!") {
+		log.Warnf("Negated pattern %s in %s is not supported, ignoring it", line, IgnoreFileName)
+		return "", false
+	}
+	line = strings.TrimPrefix(line, `\`) // escaped leading '#' or '!'
+	pattern := strings.TrimSuffix(line, "/")
+	if pattern == "" {
+		return "", false
+	}
+	if strings.Contains(pattern, "/") {
+		return strings.TrimPrefix(pattern, "/"), true
+	}
+	return "**/" + pattern, true
+}
+
+// WriteIgnoredConfig writes the configuration of the project to path with the ignored paths excluded,
+// keeping the settings unknown to QodanaYaml as they are.
+func WriteIgnoredConfig(project string, filename string, ignored []string, path string) error {
+	data, err := os.ReadFile(GetQodanaYamlPathWithProject(project, filename))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	config := map[string]interface{}{}
+	q := QodanaYaml{}
+	if err = yaml.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("not a valid qodana.yaml: %w", err)
+	}
+	if err = yaml.Unmarshal(data, &q); err != nil {
+		return fmt.Errorf("not a valid qodana.yaml: %w", err)
+	}
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	if _, ok := config["version"]; !ok {
+		config["version"] = "1.0"
+	}
+	q.ExcludeIgnored(ignored)
+	config["exclude"] = q.Excludes
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(config); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b.Bytes(), 0o600)
+}
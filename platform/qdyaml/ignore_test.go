@@ -0,0 +1,66 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package qdyaml
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadIgnoreFile(t *testing.T) {
+	project := t.TempDir()
+	paths, err := LoadIgnoreFile(project)
+	assert.NoError(t, err)
+	assert.Empty(t, paths)
+
+	content := "# generated code\n\nbuild/\n/gen/proto\n*.min.js\n!keep.min.js\n\\#hash\nsrc/**/generated\n/\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(project, IgnoreFileName), []byte(content), 0o600))
+	paths, err = LoadIgnoreFile(project)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"**/build", "gen/proto", "**/*.min.js", "**/#hash", "src/**/generated"}, paths)
+}
+
+func TestWriteIgnoredConfig(t *testing.T) {
+	project := t.TempDir()
+	assert.NoError(
+		t,
+		os.WriteFile(
+			filepath.Join(project, "custom.yaml"),
+			[]byte("version: \"1.0\"\nlinter: jetbrains/qodana-jvm\nunknownSetting: 42\nexclude:\n  - name: All\n    paths:\n      - out\n  - name: UnusedImport\n"),
+			0o600,
+		),
+	)
+	target := filepath.Join(project, ".qodana", "qodana.ignore.yaml")
+	assert.NoError(t, WriteIgnoredConfig(project, "custom.yaml", []string{"**/build", "out"}, target))
+
+	q := LoadQodanaYamlByFullPath(target)
+	assert.Equal(t, "jetbrains/qodana-jvm", q.Linter)
+	assert.Equal(t, []Clude{{Name: "All", Paths: []string{"out", "**/build"}}, {Name: "UnusedImport"}}, q.Excludes)
+	data, err := os.ReadFile(target)
+	assert.NoError(t, err)
+	raw := map[string]interface{}{}
+	assert.NoError(t, yaml.Unmarshal(data, &raw))
+	assert.Equal(t, 42, raw["unknownSetting"])
+
+	assert.NoError(t, WriteIgnoredConfig(t.TempDir(), "", []string{"dist"}, target))
+	q = LoadQodanaYamlByFullPath(target)
+	assert.Equal(t, "1.0", q.Version)
+	assert.Equal(t, []Clude{{Name: "All", Paths: []string{"dist"}}}, q.Excludes)
+}
@@ -95,12 +95,18 @@ type QodanaYaml struct {
 	// IDE to run.
 	Ide string `yaml:"ide,omitempty"`
 
-	// Bootstrap contains a command to run in the container before the analysis starts.
-	Bootstrap string `yaml:"bootstrap,omitempty"`
+	// Bootstrap contains a command, or a list of steps, to run in the container before the analysis starts.
+	Bootstrap Bootstrap `yaml:"bootstrap,omitempty"`
+
+	// BootstrapCache declares the inputs and outputs of the bootstrap command, to skip it when the inputs haven't changed.
+	BootstrapCache BootstrapCache `yaml:"bootstrapCache,omitempty"`
 
 	// Properties property to override IDE properties.
 	Properties map[string]string `yaml:"properties,omitempty"`
 
+	// VmOptions are the VM options of the IDE, e.g. -Xmx8g, overriding the default ones.
+	VmOptions []string `yaml:"vmoptions,omitempty"`
+
 	// LicenseRules contains a list of license rules to apply for license checks.
 	LicenseRules []LicenseRule `yaml:"licenseRules,omitempty"`
 
@@ -163,6 +169,27 @@ type QodanaYaml struct {
 
 	// RaiseLicenseProblems property to show license problems like other inspections.
 	RaiseLicenseProblems bool `yaml:"raiseLicenseProblems,omitempty"`
+
+	// Cloud is the configuration of the Qodana Cloud (or self-hosted Qodana) instance to use.
+	Cloud Cloud `yaml:"cloud,omitempty"`
+
+	// SeverityMapping remaps the severities of the problems before the quality gates, the baseline and the report.
+	SeverityMapping SeverityMapping `yaml:"severityMapping,omitempty"`
+
+	// NewCode limits the quality gates to the code changed since a date or a commit, independent of the baseline.
+	NewCode NewCode `yaml:"newCode,omitempty"`
+
+	// Remediation estimates the time to fix the problems, shown in the summary and written to outcome.json.
+	Remediation Remediation `yaml:"remediation,omitempty"`
+
+	// Logs bounds the size of the log directory of the results on the long-lived agents.
+	Logs Logs `yaml:"logs,omitempty"`
+
+	// CliVersion is the constraint of the CLI versions the project requires, e.g. ">=2024.2 <2025".
+	CliVersion string `yaml:"cliVersion,omitempty"`
+
+	// CliVersionCheck is what happens when the CLI doesn't satisfy CliVersion: warn (default) or fail.
+	CliVersionCheck string `yaml:"cliVersionCheck,omitempty"`
 }
 
 // WriteConfig writes QodanaYaml to the given path.
@@ -190,6 +217,28 @@ type Profile struct {
 
 	// Path profile path to use.
 	Path string `yaml:"path,omitempty"`
+
+	// Inline overrides the inspections of the profile, the CLI passes the result to the linter as a generated profile.
+	Inline *InlineProfile `yaml:"inline,omitempty"`
+}
+
+// InlineProfile Inspection overrides defined in qodana.yaml instead of an inspection profile file.
+//
+//goland:noinspection GoUnnecessarilyExportedIdentifiers
+type InlineProfile struct {
+	// Enable the inspections with these ids.
+	Enable []string `yaml:"enable,omitempty"`
+
+	// Disable the inspections with these ids.
+	Disable []string `yaml:"disable,omitempty"`
+
+	// Severity enables the inspections with the given severity: ERROR, WARNING, WEAK WARNING, INFORMATION or TYPO.
+	Severity map[string]string `yaml:"severity,omitempty"`
+}
+
+// IsEmpty checks if the inline profile overrides no inspection.
+func (p *InlineProfile) IsEmpty() bool {
+	return p == nil || (len(p.Enable) == 0 && len(p.Disable) == 0 && len(p.Severity) == 0)
 }
 
 // Clude A check id to enable/disable for include/exclude YAML field.
@@ -209,6 +258,12 @@ type Clude struct {
 type Plugin struct {
 	// Id plugin id to install.
 	Id string `yaml:"id"`
+
+	// Version of the plugin to install, the linter installs the latest compatible one if empty.
+	Version string `yaml:"version,omitempty"`
+
+	// Archive is the plugin .zip or .jar to install instead of downloading it, relative to the project root.
+	Archive string `yaml:"archive,omitempty"`
 }
 
 // DependencyIgnore is a dependency to ignore for license checks in Qodana
@@ -288,6 +343,13 @@ type DotNet struct {
 	// Project is the name of a .NET project inside the Qodana project.
 	Project string `yaml:"project,omitempty"`
 
+	// Solutions are the .NET solutions inside the Qodana project analyzed in one run by qodana-cdnet,
+	// the results of the solutions are merged into one report.
+	Solutions []string `yaml:"solutions,omitempty"`
+
+	// Projects are the .NET projects inside the Qodana project analyzed in one run by qodana-cdnet.
+	Projects []string `yaml:"projects,omitempty"`
+
 	// Configuration is the configuration in which .NET project should be opened by Qodana.
 	Configuration string `yaml:"configuration,omitempty"`
 
@@ -298,6 +360,45 @@ type DotNet struct {
 	Frameworks string `yaml:"frameworks,omitempty"`
 }
 
+// SeverityMapping remaps the severities of the problems of the inspections or of whole categories,
+// e.g. to downgrade noisy inspections without disabling them.
+type SeverityMapping struct {
+	// Inspections maps the inspection IDs to the severities of their problems.
+	Inspections map[string]string `yaml:"inspections,omitempty"`
+
+	// Categories maps the inspection categories to the severities, the inspections mapping takes precedence.
+	Categories map[string]string `yaml:"categories,omitempty"`
+}
+
+// NewCode defines the new code as the lines changed since the cutoff, the older lines are blamed on the boundary commit.
+type NewCode struct {
+	// Since is the date (YYYY-MM-DD or RFC 3339) or the commit the new code is changed after.
+	Since string `yaml:"since,omitempty"`
+}
+
+// Remediation is the effort to fix a problem as a duration, e.g. 15m or 1h30m, by its severity or inspection.
+// Setting any of the efforts enables the estimation.
+type Remediation struct {
+	// Severities maps the severities to the effort of their problems, the unset ones keep the defaults.
+	Severities map[string]string `yaml:"severities,omitempty"`
+
+	// Inspections maps the inspection IDs to the effort of their problems, taking precedence over the severities.
+	Inspections map[string]string `yaml:"inspections,omitempty"`
+}
+
+// Logs configures the rotation of the logs written by the CLI and how many previous runs keep their logs.
+// The unset values keep the defaults.
+type Logs struct {
+	// MaxSizeMb is the size in megabytes a log file is rotated at, 10 by default.
+	MaxSizeMb int `yaml:"maxSizeMb,omitempty"`
+
+	// MaxFiles is the number of the rotated files kept of each log, 3 by default.
+	MaxFiles int `yaml:"maxFiles,omitempty"`
+
+	// KeepRuns is the number of the previous runs whose logs are kept in log/runs of the results directory, 5 by default.
+	KeepRuns int `yaml:"keepRuns,omitempty"`
+}
+
 type FailureConditions struct {
 	// SeverityThresholds corresponds to the JSON schema field "severityThresholds".
 	SeverityThresholds *SeverityThresholds `yaml:"severityThresholds,omitempty"`
@@ -305,6 +406,22 @@ type FailureConditions struct {
 	// TestCoverageThresholds corresponds to the JSON schema field
 	// "testCoverageThresholds".
 	TestCoverageThresholds *CoverageThresholds `yaml:"testCoverageThresholds,omitempty"`
+
+	// PathThresholds are the failure conditions of the problems in the given paths, checked in addition
+	// to SeverityThresholds, e.g. to ratchet the quality of new code while legacy code tolerates more problems.
+	PathThresholds []PathThresholds `yaml:"pathThresholds,omitempty"`
+
+	// DuplicationThreshold is the maximum percentage of the duplicated lines of the project, e.g. 5 or 2.5.
+	DuplicationThreshold *float64 `yaml:"duplicationThreshold,omitempty"`
+}
+
+// PathThresholds configures maximum thresholds for the problems in the paths.
+type PathThresholds struct {
+	// Paths are CODEOWNERS-like globs relative to the project directory, e.g. src/ or /legacy/**/*.java.
+	Paths []string `yaml:"paths"`
+
+	// SeverityThresholds are the thresholds of the problems in the paths.
+	SeverityThresholds *SeverityThresholds `yaml:"severityThresholds"`
 }
 
 // SeverityThresholds Configures maximum thresholds for different problem severities. Absent properties are not checked. If a baseline is given, only new results are counted
@@ -350,13 +467,49 @@ type Script struct {
 
 // IsEmpty checks whether the .NET configuration is empty or not.
 func (d DotNet) IsEmpty() bool {
-	return d.Solution == "" && d.Project == ""
+	return d.Solution == "" && d.Project == "" && len(d.Solutions) == 0 && len(d.Projects) == 0
+}
+
+// BootstrapCache is the configuration of the bootstrap cache: the bootstrap is skipped when the command
+// and the contents of Inputs are the same as in a previous run, Outputs are restored from the cache instead.
+//
+//goland:noinspection GoUnnecessarilyExportedIdentifiers
+type BootstrapCache struct {
+	// Inputs are glob patterns of the files the bootstrap depends on, relative to the project, e.g. package-lock.json.
+	Inputs []string `yaml:"inputs,omitempty"`
+
+	// Outputs are the directories created by the bootstrap, relative to the project, e.g. node_modules.
+	Outputs []string `yaml:"outputs,omitempty"`
+}
+
+// IsEmpty checks whether the bootstrap cache is configured or not.
+func (b BootstrapCache) IsEmpty() bool {
+	return len(b.Inputs) == 0 && len(b.Outputs) == 0
+}
+
+// Cloud is the Qodana Cloud connection configuration.
+//
+//goland:noinspection GoUnnecessarilyExportedIdentifiers
+type Cloud struct {
+	// Endpoint is the URL of the Qodana Cloud instance, e.g. a self-hosted one.
+	Endpoint string `yaml:"endpoint,omitempty"`
+
+	// CaCertificate is the path to a PEM file with additional CA certificates to trust when connecting to Endpoint.
+	CaCertificate string `yaml:"caCertificate,omitempty"`
+}
+
+// IsEmpty checks whether the cloud configuration is empty or not.
+func (c Cloud) IsEmpty() bool {
+	return c.Endpoint == "" && c.CaCertificate == ""
 }
 
 //goland:noinspection GoUnnecessarilyExportedIdentifiers
 type Php struct {
 	// Version is the PHP version to use for the analysis.
 	Version string `yaml:"version,omitempty"`
+
+	// Versions are the PHP versions to analyse the project for one after another, the results are merged.
+	Versions []string `yaml:"versions,omitempty"`
 }
 
 // FindDefaultQodanaYaml checks whether qodana.yaml exists or not
@@ -400,6 +553,23 @@ func LoadQodanaYamlByFullPath(fullPath string) QodanaYaml {
 	return *q
 }
 
+// ReadQodanaYaml reads the configuration at fullPath, an empty one if the file doesn't exist.
+// Unlike LoadQodanaYamlByFullPath, it returns the errors instead of exiting.
+func ReadQodanaYaml(fullPath string) (QodanaYaml, error) {
+	q := QodanaYaml{}
+	yamlFile, err := os.ReadFile(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return q, err
+	}
+	if err = yaml.Unmarshal(yamlFile, &q); err != nil {
+		return q, fmt.Errorf("failed to parse %s: %w", fullPath, err)
+	}
+	return q, nil
+}
+
 // Sort makes QodanaYaml prettier.
 func (q *QodanaYaml) Sort() *QodanaYaml {
 	sort.Slice(
@@ -487,3 +657,60 @@ func SetQodanaDotNet(path string, dotNet *DotNet, filename string) bool {
 	}
 	return true
 }
+
+// AddExclude excludes the check from the analysis of the path (relative to the project root) in the qodana.yaml file,
+// the whole project is excluded if path is empty.
+func AddExclude(project string, filename string, name string, path string) error {
+	q := LoadQodanaYaml(project, filename)
+	if q.Version == "" {
+		q.Version = "1.0"
+	}
+	q.Exclude(name, path)
+	return q.Sort().WriteConfig(GetQodanaYamlPathWithProject(project, filename))
+}
+
+// Exclude excludes the check from the analysis of the path, the whole project is excluded if path is empty.
+func (q *QodanaYaml) Exclude(name string, path string) {
+	excluded := false
+	for i, exclude := range q.Excludes {
+		if exclude.Name != name {
+			continue
+		}
+		excluded = true
+		switch {
+		case path == "":
+			q.Excludes[i].Paths = nil
+		case len(exclude.Paths) > 0 && !utils.Contains(exclude.Paths, path):
+			q.Excludes[i].Paths = append(exclude.Paths, path)
+		}
+	}
+	if !excluded {
+		exclude := Clude{Name: name}
+		if path != "" {
+			exclude.Paths = []string{path}
+		}
+		q.Excludes = append(q.Excludes, exclude)
+	}
+}
+
+// AddPlugin adds the plugin to the plugins to install, replacing the plugin with the same id.
+func (q *QodanaYaml) AddPlugin(plugin Plugin) {
+	for i, p := range q.Plugins {
+		if p.Id == plugin.Id {
+			q.Plugins[i] = plugin
+			return
+		}
+	}
+	q.Plugins = append(q.Plugins, plugin)
+}
+
+// RemovePlugin removes the plugin with the id from the plugins to install, it returns false if there is none.
+func (q *QodanaYaml) RemovePlugin(id string) bool {
+	for i, p := range q.Plugins {
+		if p.Id == id {
+			q.Plugins = append(q.Plugins[:i], q.Plugins[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
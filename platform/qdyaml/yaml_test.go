@@ -19,9 +19,11 @@ package qdyaml
 import (
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func setupTestFile(fileName string, content string) {
@@ -158,3 +160,100 @@ script:
 		)
 	}
 }
+
+func TestAddExclude(t *testing.T) {
+	project := t.TempDir()
+	err := os.WriteFile(
+		filepath.Join(project, "qodana.yaml"),
+		[]byte("version: \"1.0\"\nlinter: jetbrains/qodana-jvm\nexclude:\n  - name: All\n    paths:\n      - build\n"),
+		0o600,
+	)
+	assert.NoError(t, err)
+
+	assert.NoError(t, AddExclude(project, "", "UnusedImport", "src/A.java"))
+	assert.NoError(t, AddExclude(project, "", "UnusedImport", "src/B.java"))
+	assert.NoError(t, AddExclude(project, "", "UnusedImport", "src/A.java"))
+	assert.NoError(t, AddExclude(project, "", "All", "out"))
+	q := LoadQodanaYaml(project, "")
+	assert.Equal(t, "jetbrains/qodana-jvm", q.Linter)
+	assert.Equal(
+		t,
+		[]Clude{{Name: "All", Paths: []string{"build", "out"}}, {Name: "UnusedImport", Paths: []string{"src/A.java", "src/B.java"}}},
+		q.Excludes,
+	)
+
+	assert.NoError(t, AddExclude(project, "", "UnusedImport", ""))
+	assert.NoError(t, AddExclude(project, "", "UnusedImport", "src/C.java"))
+	q = LoadQodanaYaml(project, "")
+	assert.Equal(t, Clude{Name: "UnusedImport"}, q.Excludes[1])
+}
+
+func TestReadQodanaYaml(t *testing.T) {
+	project := t.TempDir()
+	q, err := ReadQodanaYaml(filepath.Join(project, "qodana.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, QodanaYaml{}, q)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(project, "qodana.yaml"), []byte("linter: jetbrains/qodana-jvm\n"), 0o600))
+	q, err = ReadQodanaYaml(filepath.Join(project, "qodana.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "jetbrains/qodana-jvm", q.Linter)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(project, "qodana.yaml"), []byte("linter: [\n"), 0o600))
+	_, err = ReadQodanaYaml(filepath.Join(project, "qodana.yaml"))
+	assert.Error(t, err)
+}
+
+func TestAddPlugin(t *testing.T) {
+	q := QodanaYaml{Plugins: []Plugin{{Id: "detekt", Version: "2.3.0"}}}
+	q.AddPlugin(Plugin{Id: "org.intellij.scala"})
+	q.AddPlugin(Plugin{Id: "detekt", Version: "2.4.0"})
+	assert.Equal(t, []Plugin{{Id: "detekt", Version: "2.4.0"}, {Id: "org.intellij.scala"}}, q.Plugins)
+
+	assert.True(t, q.RemovePlugin("detekt"))
+	assert.False(t, q.RemovePlugin("detekt"))
+	assert.Equal(t, []Plugin{{Id: "org.intellij.scala"}}, q.Plugins)
+}
+
+func TestBootstrap(t *testing.T) {
+	var q QodanaYaml
+	assert.NoError(t, yaml.Unmarshal([]byte("bootstrap: npm ci"), &q))
+	command, ok := q.Bootstrap.Command()
+	assert.True(t, ok)
+	assert.Equal(t, "npm ci", command)
+	assert.Equal(t, "npm ci", q.Bootstrap.String())
+
+	content := `bootstrap:
+  - name: dependencies
+    run: npm ci
+    timeout: 10m
+  - run: npm run generate
+    workingDir: packages/api
+    env:
+      NODE_ENV: production
+`
+	q = QodanaYaml{}
+	assert.NoError(t, yaml.Unmarshal([]byte(content), &q))
+	assert.Equal(
+		t,
+		Bootstrap{
+			{Name: "dependencies", Run: "npm ci", Timeout: 10 * time.Minute},
+			{Run: "npm run generate", WorkingDir: "packages/api", Env: map[string]string{"NODE_ENV": "production"}},
+		},
+		q.Bootstrap,
+	)
+	_, ok = q.Bootstrap.Command()
+	assert.False(t, ok)
+
+	out, err := yaml.Marshal(QodanaYaml{Bootstrap: Bootstrap{{Run: "npm ci"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "bootstrap: npm ci\n", string(out))
+
+	assert.Error(t, yaml.Unmarshal([]byte("bootstrap:\n  - name: empty\n"), &QodanaYaml{}))
+}
+
+func TestPhpVersions(t *testing.T) {
+	var q QodanaYaml
+	assert.NoError(t, yaml.Unmarshal([]byte("php:\n  version: 8.1\n  versions: [8.1, 8.10]\n"), &q))
+	assert.Equal(t, Php{Version: "8.1", Versions: []string{"8.1", "8.10"}}, q.Php)
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdredact"
+	"os"
+)
+
+// RedactResults applies the redaction rules of rulesFile, the built-in ones if empty, to the SARIF report, the logs
+// and the HTML report in the results and the report directories.
+func RedactResults(rulesFile string, projectDir string, resultsDir string, reportDir string) error {
+	rules, err := qdredact.LoadRules(rulesFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the redaction rules: %w", err)
+	}
+	redactor, err := qdredact.New(rules, projectDir)
+	if err != nil {
+		return err
+	}
+	changed := 0
+	for _, dir := range []string{resultsDir, reportDir} {
+		// the report directory is usually inside the results one, redacting it again changes nothing
+		if _, err = os.Stat(dir); dir == "" || os.IsNotExist(err) {
+			continue
+		}
+		n, err := redactor.RedactDir(dir)
+		if err != nil {
+			return fmt.Errorf("failed to redact %s: %w", dir, err)
+		}
+		changed += n
+	}
+	msg.SuccessMessage("Redacted %d files of the results", changed)
+	return nil
+}
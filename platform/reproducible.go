@@ -0,0 +1,172 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"github.com/google/uuid"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reportIdDate is the date ReportId appends to the report identifier.
+var reportIdDate = regexp.MustCompile(`/\d{4}-\d{2}-\d{2}$`)
+
+// ReproducibleAnalysisId returns the analysis identifier of --reproducible runs, the same for every run of the linter
+// on the revision.
+func ReproducibleAnalysisId(linter string, revision string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("qodana:"+linter+"@"+revision)).String()
+}
+
+// MakeReproducible rewrites the SARIF report so that the runs on the same commit produce identical reports: the results
+// are sorted, the timestamps, the machine details and the absolute paths under roots are removed, and the run GUID
+// is set to analysisId.
+func MakeReproducible(sarifPath string, analysisId string, roots ...string) error {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the SARIF report: %w", err)
+	}
+	prefixes := uriPrefixes(roots)
+	for i := range report.Runs {
+		makeRunReproducible(&report.Runs[i], analysisId, prefixes)
+	}
+	return WriteReport(sarifPath, report)
+}
+
+func makeRunReproducible(run *sarif.Run, analysisId string, prefixes []string) {
+	if run.AutomationDetails != nil {
+		run.AutomationDetails.Guid = analysisId
+		run.AutomationDetails.Id = reportIdDate.ReplaceAllString(run.AutomationDetails.Id, "")
+		run.AutomationDetails.Properties = nil
+	}
+	run.OriginalUriBaseIds = nil
+	for i, invocation := range run.Invocations {
+		run.Invocations[i] = sarif.Invocation{
+			ExecutionSuccessful:            invocation.ExecutionSuccessful,
+			ExitCode:                       invocation.ExitCode,
+			ExitCodeDescription:            invocation.ExitCodeDescription,
+			ToolConfigurationNotifications: reproducibleNotifications(invocation.ToolConfigurationNotifications),
+			ToolExecutionNotifications:     reproducibleNotifications(invocation.ToolExecutionNotifications),
+		}
+	}
+	for i := range run.Artifacts {
+		relativizeUri(run.Artifacts[i].Location, prefixes)
+	}
+	for i := range run.Results {
+		result := &run.Results[i]
+		if result.Provenance != nil {
+			result.Provenance.FirstDetectionTimeUtc = time.Time{}
+			result.Provenance.LastDetectionTimeUtc = time.Time{}
+		}
+		relativizeUri(result.AnalysisTarget, prefixes)
+		for _, locations := range [][]sarif.Location{result.Locations, result.RelatedLocations} {
+			for _, location := range locations {
+				if location.PhysicalLocation != nil {
+					relativizeUri(location.PhysicalLocation.ArtifactLocation, prefixes)
+				}
+			}
+		}
+		if result.Properties != nil {
+			sort.Strings(result.Properties.Tags)
+		}
+	}
+	sortResults(run.Results)
+}
+
+// sortResults orders the results by the rule, the location, the message and the fingerprints.
+func sortResults(results []sarif.Result) {
+	type keyedResult struct {
+		key    string
+		result sarif.Result
+	}
+	keyed := make([]keyedResult, len(results))
+	for i := range results {
+		keyed[i] = keyedResult{key: resultSortKey(&results[i]), result: results[i]}
+	}
+	sort.SliceStable(
+		keyed, func(i, j int) bool {
+			return keyed[i].key < keyed[j].key
+		},
+	)
+	for i := range keyed {
+		results[i] = keyed[i].result
+	}
+}
+
+func reproducibleNotifications(notifications []sarif.Notification) []sarif.Notification {
+	for i := range notifications {
+		notifications[i].TimeUtc = time.Time{}
+		notifications[i].ThreadId = 0
+	}
+	return notifications
+}
+
+// uriPrefixes returns the prefixes of the absolute URIs of the files under roots, both file:// and plain paths,
+// including file:///C:/ for the Windows drives and file://server/share/ for the UNC roots.
+func uriPrefixes(roots []string) []string {
+	var prefixes []string
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		root = utils.NormalizeWindowsPath(root)
+		if utils.HasDriveLetter(root) || utils.IsUncPath(root) {
+			root = strings.ReplaceAll(root, `\`, "/")
+		}
+		root = strings.TrimSuffix(filepath.ToSlash(root), "/") + "/"
+		if utils.HasDriveLetter(root) {
+			prefixes = append(prefixes, "file:///"+root)
+		}
+		prefixes = append(prefixes, "file://"+root, "file:"+root, root)
+	}
+	return prefixes
+}
+
+func relativizeUri(location *sarif.ArtifactLocation, prefixes []string) {
+	if location == nil {
+		return
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(location.Uri, prefix) {
+			location.Uri = strings.TrimPrefix(location.Uri, prefix)
+			return
+		}
+	}
+}
+
+func resultSortKey(r *sarif.Result) string {
+	path, line, column := problemLocation(r)
+	fingerprints := make([]string, 0, len(r.PartialFingerprints))
+	for key, value := range r.PartialFingerprints {
+		fingerprints = append(fingerprints, key+"="+value)
+	}
+	sort.Strings(fingerprints)
+	return fmt.Sprintf(
+		"%s\x00%s\x00%010d\x00%010d\x00%s\x00%s",
+		r.RuleId,
+		path,
+		line,
+		column,
+		problemMessage(r),
+		strings.Join(fingerprints, ","),
+	)
+}
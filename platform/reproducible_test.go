@@ -0,0 +1,129 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"bytes"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMakeReproducible(t *testing.T) {
+	result := func(path string, line int64) sarif.Result {
+		return sarif.Result{
+			RuleId:     "Rule",
+			Message:    &sarif.Message{Text: "problem"},
+			Provenance: &sarif.ResultProvenance{FirstDetectionTimeUtc: time.Now()},
+			Locations: []sarif.Location{
+				{
+					PhysicalLocation: &sarif.PhysicalLocation{
+						ArtifactLocation: &sarif.ArtifactLocation{Uri: path},
+						Region:           &sarif.Region{StartLine: line},
+					},
+				},
+			},
+		}
+	}
+	report := func(start time.Time, results ...sarif.Result) *sarif.Report {
+		return &sarif.Report{
+			Version: "2.1.0",
+			Runs: []sarif.Run{
+				{
+					Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "QDJVM"}},
+					AutomationDetails: &sarif.RunAutomationDetails{
+						Guid: start.String(),
+						Id:   "project/qodana/" + start.Format("2006-01-02"),
+					},
+					Invocations: []sarif.Invocation{
+						{ExecutionSuccessful: true, StartTimeUtc: start, Machine: start.String(), CommandLine: "qodana"},
+					},
+					Results: results,
+				},
+			},
+		}
+	}
+	dir := t.TempDir()
+	analysisId := ReproducibleAnalysisId("jetbrains/qodana-jvm", "abc")
+	var written [][]byte
+	for i, r := range []*sarif.Report{
+		report(time.Now(), result("file:///data/project/src/B.java", 1), result("src/A.java", 2)),
+		report(time.Now().Add(-48*time.Hour), result("src/A.java", 2), result("/data/project/src/B.java", 1)),
+	} {
+		path := filepath.Join(dir, "run"+string(rune('1'+i))+".sarif.json")
+		if err := WriteReport(path, r); err != nil {
+			t.Fatal(err)
+		}
+		if err := MakeReproducible(path, analysisId, "/data/project"); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		written = append(written, data)
+	}
+	if !bytes.Equal(written[0], written[1]) {
+		t.Fatalf("the reports differ:\n%s\n%s", written[0], written[1])
+	}
+
+	r, err := ReadReport(filepath.Join(dir, "run1.sarif.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := r.Runs[0]
+	if run.AutomationDetails.Guid != analysisId || run.AutomationDetails.Id != "project/qodana" {
+		t.Errorf("unexpected automation details %+v", run.AutomationDetails)
+	}
+	if uri := run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.Uri; uri != "src/A.java" {
+		t.Errorf("first result is in %s, want src/A.java", uri)
+	}
+	if uri := run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation.Uri; uri != "src/B.java" {
+		t.Errorf("second result is in %s, want src/B.java", uri)
+	}
+	if ReproducibleAnalysisId("jetbrains/qodana-jvm", "abc") != analysisId || ReproducibleAnalysisId("jetbrains/qodana-jvm", "def") == analysisId {
+		t.Error("the analysis id must depend only on the linter and the revision")
+	}
+}
+
+func TestRelativizeWindowsUris(t *testing.T) {
+	project := `C:\work\` + strings.Repeat(`very long directory name\`, 12) + "project"
+	if len(project) <= 260 {
+		t.Fatalf("the project directory is %d characters long, want more than 260", len(project))
+	}
+	share := `\\server\share\` + strings.Repeat(`nested\`, 40) + "project"
+	for _, tc := range []struct {
+		root string
+		uri  string
+	}{
+		{project, "file:///" + strings.ReplaceAll(project, `\`, "/") + "/src/A.java"},
+		{project, strings.ReplaceAll(project, `\`, "/") + "/src/A.java"},
+		{`\\?\` + project, "file:///" + strings.ReplaceAll(project, `\`, "/") + "/src/A.java"},
+		{share, "file:" + strings.ReplaceAll(share, `\`, "/") + "/src/A.java"},
+		{`\\?\UNC\` + share[2:], "file:" + strings.ReplaceAll(share, `\`, "/") + "/src/A.java"},
+		{share, strings.ReplaceAll(share, `\`, "/") + "/src/A.java"},
+	} {
+		location := &sarif.ArtifactLocation{Uri: tc.uri}
+		relativizeUri(location, uriPrefixes([]string{tc.root}))
+		if location.Uri != "src/A.java" {
+			t.Errorf("%s under %s is relativized to %s, want src/A.java", tc.uri, tc.root, location.Uri)
+		}
+	}
+}
@@ -17,33 +17,49 @@
 package platform
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/cloud"
 	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
 	"github.com/JetBrains/qodana-cli/v2024/platform/commoncontext"
 	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcodemetrics"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdcrypt"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtrace"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
 	"github.com/JetBrains/qodana-cli/v2024/platform/thirdpartyscan"
 	"github.com/JetBrains/qodana-cli/v2024/platform/tokenloader"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	"github.com/JetBrains/qodana-cli/v2024/tooling"
+	"github.com/pterm/pterm"
 	log "github.com/sirupsen/logrus"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 func RunThirdPartyLinterAnalysis(
+	ctx context.Context,
 	cliOptions platformcmd.CliOptions,
 	linter ThirdPartyLinter,
 	linterInfo thirdpartyscan.LinterInfo,
 ) (int, error) {
 	var err error
+	start := time.Now()
 	resultDir := cliOptions.ResultsDir
 	defer changeResultDirPermissionsInContainer(resultDir)
+	cloud.LicenseCacheTtl = cliOptions.LicenseCacheTtl
+	encryption, err := qdcrypt.Load(cliOptions.EncryptionKeyFile, cliOptions.EncryptionRecipient)
+	if err != nil {
+		return 1, fmt.Errorf("invalid encryption options: %w", err)
+	}
+	qdtrace.Init("qodana scan")
+	defer qdtrace.Shutdown()
+	configSpan := qdtrace.Start("preparation")
 
 	commonCtx := commoncontext.Compute(
 		cliOptions.Linter,
@@ -51,7 +67,7 @@ func RunThirdPartyLinterAnalysis(
 		cliOptions.CacheDir,
 		cliOptions.ResultsDir,
 		cliOptions.ReportDir,
-		GetEnvWithOsEnv(cliOptions, qdenv.QodanaToken),
+		tokenloader.ResolveCloudToken(cliOptions.Auth, GetEnvWithOsEnv(cliOptions, qdenv.QodanaToken)),
 		GetEnvWithOsEnv(cliOptions, qdenv.QodanaLicenseOnlyToken),
 		cliOptions.ClearCache,
 		cliOptions.ProjectDir,
@@ -63,7 +79,33 @@ func RunThirdPartyLinterAnalysis(
 		return 1, err
 	}
 	resultDir = commonCtx.ResultsDir
+	PrepareLogDir(
+		commonCtx.LogDir(),
+		qdyaml.LoadQodanaYamlByFullPath(
+			qdyaml.GetQodanaYamlPathWithProject(commonCtx.ProjectDir, cliOptions.ConfigName),
+		).Logs,
+		cliOptions.Resume,
+	)
+	if cliOptions.BaselinesDir != "" {
+		if baseline := BaselineOfBranch(cliOptions.BaselinesDir, commonCtx.ProjectDir, commonCtx.LogDir()); baseline != "" {
+			cliOptions.Baseline = baseline
+		}
+	}
 
+	RecordCacheHit(commonCtx.CacheDir)
+	remoteCache, err := NewRemoteCache(
+		cliOptions.CacheRemote,
+		cliOptions.CacheKey,
+		cliOptions.CacheRestoreKeys,
+		linterInfo.ProductCode,
+		commonCtx.ProjectDir,
+		commonCtx.LogDir(),
+	)
+	if err != nil {
+		msg.ErrorMessage(err.Error())
+		return 1, err
+	}
+	remoteCache.Restore(commonCtx.CacheDir)
 	thirdPartyCloudData := checkLinterLicense(commonCtx)
 	isCommunity := thirdPartyCloudData.LicensePlan == cloud.CommunityLicensePlan
 
@@ -79,10 +121,44 @@ func RunThirdPartyLinterAnalysis(
 
 	qodanaYamlPath := qdyaml.GetQodanaYamlPathWithProject(commonCtx.ProjectDir, cliOptions.ConfigName)
 	yaml := qdyaml.LoadQodanaYamlByFullPath(qodanaYamlPath)
+	ignored, err := qdyaml.LoadIgnoreFile(commonCtx.ProjectDir)
+	if err != nil {
+		return 1, fmt.Errorf("failed to read %s: %w", qdyaml.IgnoreFileName, err)
+	}
+	yaml.ExcludeIgnored(ignored)
+	if err = CheckCliVersion(yaml); err != nil {
+		return 1, err
+	}
+	if err = EnforcePolicy(cliOptions, commonCtx, yaml); err != nil {
+		msg.ErrorMessage(err.Error())
+		return 1, err
+	}
+	if err = EnableSignatureVerification(cliOptions); err != nil {
+		msg.ErrorMessage(err.Error())
+		return 1, err
+	}
+	severityMapping, err := NewSeverityMapping(yaml.SeverityMapping, cliOptions.SeverityMap)
+	if err != nil {
+		msg.ErrorMessage(err.Error())
+		return 1, err
+	}
+	remediation, err := NewRemediation(yaml.Remediation, cliOptions.EstimateDebt)
+	if err != nil {
+		msg.ErrorMessage(err.Error())
+		return 1, err
+	}
+	metricConditions, err := qdcodemetrics.ParseConditions(cliOptions.FailOnMetric)
+	if err != nil {
+		msg.ErrorMessage(err.Error())
+		return 1, err
+	}
 
 	context := thirdpartyscan.ComputeContext(cliOptions, commonCtx, linterInfo, mountInfo, thirdPartyCloudData, yaml)
+	AuditContext(commonCtx, context.AnalysisId())
 
 	LogContext(&context)
+	configSpan.SetAttribute("qodana.linter", linterInfo.LinterName)
+	configSpan.End(nil)
 
 	events := make([]tooling.FuserEvent, 0)
 	eventsCh := createFuserEventChannel(&events)
@@ -90,37 +166,138 @@ func RunThirdPartyLinterAnalysis(
 	projectIdHash := thirdPartyCloudData.ProjectIdHash
 	defer func() {
 		logProjectClose(eventsCh, linterInfo, projectIdHash)
-		sendFuserEvents(eventsCh, &events, context, GetDeviceIdSalt()[0])
+		sendFuserEvents(ctx, eventsCh, &events, context, GetDeviceIdSalt()[0])
 	}()
 	logOs(eventsCh, linterInfo, projectIdHash)
 	logProjectOpen(eventsCh, linterInfo, projectIdHash)
 
-	if err = linter.RunAnalysis(context); err != nil {
+	runSpan := qdtrace.Start("linter run")
+	err = linter.RunAnalysis(context)
+	runSpan.End(err)
+	if err != nil {
 		msg.ErrorMessage(err.Error())
 		return 1, err
 	}
 	log.Debugf("Java executable path: %s", mountInfo.JavaPath)
 
-	thresholds := getFailureThresholds(context)
-	var analysisResult int
-	if analysisResult, err = computeBaselinePrintResults(context, thresholds); err != nil {
+	analysisResult, err := processSarif(ctx, context, severityMapping)
+	if err != nil {
 		msg.ErrorMessage(err.Error())
 		return 1, err
 	}
-	if err = copySarifToReportPath(context.ResultsDir()); err != nil {
-		msg.ErrorMessage(err.Error())
-		return 1, err
+	if cliOptions.CodeMetricsEnabled() {
+		exceeded, err := CollectCodeMetrics(context.ProjectDir(), context.ResultsDir(), metricConditions)
+		if err != nil {
+			msg.ErrorMessage(err.Error())
+			return 1, err
+		}
+		if exceeded && analysisResult == utils.QodanaSuccessExitCode {
+			analysisResult = utils.QodanaFailThresholdExitCode
+		}
 	}
-	if err = convertReportToCloudFormat(context); err != nil {
-		msg.ErrorMessage(err.Error())
-		return 1, err
+	RecordRunMetrics(context.ResultsDir(), context.ProjectDir(), start, analysisResult, cliOptions.MetricsPushgateway)
+	if cliOptions.OwnersEnabled() {
+		if err = SummarizeOwners(GetSarifPath(context.ResultsDir()), cliOptions.OwnersFile, context.ProjectDir()); err != nil {
+			msg.WarningMessage("Unable to summarize the problems by owner: %s", err)
+		}
+	}
+	if remediation != nil {
+		if err = EstimateDebt(GetSarifPath(context.ResultsDir()), remediation); err != nil {
+			msg.WarningMessage("Unable to estimate the remediation time: %s", err)
+		}
 	}
 	resultsPath := ReportResultsPath(context.ResultsDir())
 	if err = copyQodanaYamlToReportPath(qodanaYamlPath, resultsPath); err != nil {
 		msg.ErrorMessage(err.Error())
 		return 1, err
 	}
-	sendReportToQodanaServer(context)
+	if cliOptions.RedactionEnabled() {
+		if err = RedactResults(cliOptions.RedactionRules, context.ProjectDir(), context.ResultsDir(), ""); err != nil {
+			msg.ErrorMessage(err.Error())
+			return 1, err
+		}
+	}
+	sendReportToQodanaServer(ctx, context)
+	remoteCache.Save(context.CacheDir())
+	if encryption != nil {
+		archive, err := EncryptResults(encryption, context.ResultsDir())
+		if err != nil {
+			msg.ErrorMessage(err.Error())
+			return 1, err
+		}
+		msg.SuccessMessage("Results are encrypted to %s", archive)
+	}
+	PublishResults(
+		cliOptions.PublishTo,
+		context.ResultsDir(),
+		"",
+		context.AnalysisId(),
+		cliOptions.PublishRetention,
+		cliOptions.PublishUrlExpiry,
+	)
+	FinishRun(context.ResultsDir(), analysisResult)
+	return analysisResult, nil
+}
+
+// processSarif checks the problems against the quality gates and prepares the report for Qodana Cloud.
+func processSarif(ctx context.Context, c thirdpartyscan.Context, severityMapping *SeverityMapping) (int, error) {
+	span := qdtrace.Start("sarif processing")
+	analysisResult, err := checkSarif(ctx, c, severityMapping)
+	if err == nil {
+		err = copySarifToReportPath(c.ResultsDir())
+	}
+	if err == nil {
+		err = convertReportToCloudFormat(ctx, c)
+	}
+	span.End(err)
+	return analysisResult, err
+}
+
+// checkSarif remaps the severities, computes the baseline, prints the results and checks them against the thresholds,
+// of the new code only if newCode is set, and the path thresholds.
+func checkSarif(ctx context.Context, c thirdpartyscan.Context, severityMapping *SeverityMapping) (int, error) {
+	sarifPath := GetSarifPath(c.ResultsDir())
+	if severityMapping != nil {
+		if _, err := ApplySeverityMapping(sarifPath, severityMapping); err != nil {
+			return -1, err
+		}
+	}
+	thresholds := getFailureThresholds(c)
+	since := c.QodanaYaml().NewCode.Since
+	if since != "" {
+		// baseline-cli counts all the problems, the thresholds are checked once the old code is known
+		thresholds = map[string]string{}
+	}
+	analysisResult, err := computeBaselinePrintResults(ctx, c, thresholds)
+	if err != nil {
+		return -1, err
+	}
+	if since != "" && analysisResult == utils.QodanaSuccessExitCode {
+		if _, err = ClassifyNewCode(ctx, sarifPath, c.ProjectDir(), since, c.LogDir()); err != nil {
+			return -1, fmt.Errorf("failed to find the new code since %s: %w", since, err)
+		}
+		thresholdCounts, err := parseThresholds(getFailureThresholds(c))
+		if err != nil {
+			return -1, err
+		}
+		exceeded, err := ThresholdsExceeded(sarifPath, thresholdCounts)
+		if err != nil {
+			return -1, err
+		}
+		if exceeded {
+			analysisResult = utils.QodanaFailThresholdExitCode
+		}
+	}
+	if pathThresholds := c.QodanaYaml().FailureConditions.PathThresholds; len(pathThresholds) > 0 &&
+		analysisResult == utils.QodanaSuccessExitCode {
+		exceeded, err := PathThresholdsExceeded(sarifPath, pathThresholds)
+		if err != nil {
+			return -1, err
+		}
+		if exceeded {
+			analysisResult = utils.QodanaFailThresholdExitCode
+		}
+	}
 	return analysisResult, nil
 }
 
@@ -186,9 +363,9 @@ func printLinterLicense(licensePlan string, linterInfo thirdpartyscan.LinterInfo
 	msg.SuccessMessage("Qodana license plan: %s", licenseString)
 }
 
-func sendReportToQodanaServer(c thirdpartyscan.Context) {
+func sendReportToQodanaServer(ctx context.Context, c thirdpartyscan.Context) {
 	if cloud.Token.IsAllowedToSendReports() {
-		fmt.Println("Publishing report ...")
+		pterm.Println("Publishing report ...")
 		publisher := Publisher{
 			ResultsDir: c.ResultsDir(),
 			ProjectDir: c.ProjectDir(),
@@ -196,13 +373,14 @@ func sendReportToQodanaServer(c thirdpartyscan.Context) {
 			AnalysisId: c.AnalysisId(),
 		}
 		SendReport(
+			ctx,
 			publisher,
 			cloud.Token.Token,
 			utils.QuoteForWindows(filepath.Join(c.CacheDir(), PublisherJarName)),
 			utils.QuoteForWindows(c.MountInfo().JavaPath),
 		)
 	} else {
-		fmt.Println("Skipping report publishing")
+		pterm.Println("Skipping report publishing")
 	}
 }
 
@@ -217,11 +395,11 @@ func copyQodanaYamlToReportPath(qodanaYamlFullPath string, reportResultsPath str
 	return nil
 }
 
-func convertReportToCloudFormat(c thirdpartyscan.Context) error {
+func convertReportToCloudFormat(ctx context.Context, c thirdpartyscan.Context) error {
 	reportResultsPath := ReportResultsPath(c.ResultsDir())
 	log.Debugf("Generating report to %s...", reportResultsPath)
 	args := converterArgs(c)
-	stdout, _, res, err := utils.LaunchAndLog(c.LogDir(), "converter", args...)
+	stdout, _, res, err := utils.LaunchAndLog(ctx, c.LogDir(), "converter", args...)
 	if res != 0 {
 		return fmt.Errorf("converter exited with non-zero status code: %d", res)
 	}
@@ -268,6 +446,9 @@ func converterArgs(c thirdpartyscan.Context) []string {
 }
 
 func printQodanaLogo(logDir string, cacheDir string, linterInfo thirdpartyscan.LinterInfo) {
+	if msg.IsQuiet() {
+		return
+	}
 	fmt.Println("\nLog directory: " + logDir)
 	fmt.Println("Cache directory: " + cacheDir)
 	fmt.Print(qodanaLogo(linterInfo.LinterName, linterInfo.LinterVersion, linterInfo.IsEap))
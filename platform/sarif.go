@@ -17,6 +17,7 @@
 package platform
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
@@ -28,9 +29,11 @@ import (
 	log "github.com/sirupsen/logrus"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // https://www.jetbrains.com/help/qodana/qodana-sarif-output.html
@@ -38,6 +41,7 @@ const (
 	baselineStateEmpty     = ""          // baselineStateEmpty default baseline state (not set)
 	baselineStateNew       = "new"       // baselineStateNew new baseline state
 	baselineStateUnchanged = "unchanged" // baselineStateUnchanged unchanged baseline state
+	baselineStateAbsent    = "absent"    // baselineStateAbsent baseline state of the problems fixed since the baseline
 	extension              = ".sarif.json"
 	qodanaCritical         = "Critical"
 	qodanaHigh             = "High"
@@ -225,14 +229,27 @@ func findSarifFiles(root string) ([]string, error) {
 	return files, nil
 }
 
+// collectReports reads the SARIF files in parallel and sends the reports to ch in the order of files.
 func collectReports(files []string, ch chan<- *sarif.Report) {
-	for _, file := range files {
-		r, err := ReadReport(file)
-		if err != nil {
-			fmt.Printf("Error reading SARIF %s: %s\n", file, err)
-			continue
+	reports := make([]chan *sarif.Report, len(files))
+	workers := make(chan struct{}, sarifWorkers())
+	for i, file := range files {
+		reports[i] = make(chan *sarif.Report, 1)
+		go func(file string, out chan<- *sarif.Report) {
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			r, err := ReadReport(file)
+			if err != nil {
+				fmt.Printf("Error reading SARIF %s: %s\n", file, err)
+				r = nil
+			}
+			out <- r
+		}(file, reports[i])
+	}
+	for _, report := range reports {
+		if r := <-report; r != nil {
+			ch <- r
 		}
-		ch <- r
 	}
 	close(ch)
 }
@@ -294,6 +311,90 @@ func mergeReports(ch <-chan *sarif.Report) (*sarif.Report, error) {
 	return finalReport, nil
 }
 
+// CombineReports merges the first runs of the reports, e.g. of several analyzed solutions, into the first run
+// of the first report: the results and artifacts are appended, the rules and taxa are added if not present yet,
+// and the duplicated results are removed.
+func CombineReports(reports []*sarif.Report) (*sarif.Report, error) {
+	if len(reports) == 0 || len(reports[0].Runs) == 0 {
+		return nil, fmt.Errorf("no SARIF runs to combine")
+	}
+	finalReport := reports[0]
+	run := &finalReport.Runs[0]
+	var driver *sarif.ToolComponent
+	if run.Tool != nil {
+		driver = run.Tool.Driver
+	}
+	rules := map[string]bool{}
+	taxa := map[string]bool{}
+	if driver != nil {
+		for _, rule := range driver.Rules {
+			rules[rule.Id] = true
+		}
+		for _, taxon := range driver.Taxa {
+			taxa[taxon.Id] = true
+		}
+	}
+	for _, report := range reports[1:] {
+		for _, other := range report.Runs {
+			run.Results = append(run.Results, other.Results...)
+			run.Artifacts = append(run.Artifacts, other.Artifacts...)
+			if driver == nil || other.Tool == nil || other.Tool.Driver == nil {
+				continue
+			}
+			for _, rule := range other.Tool.Driver.Rules {
+				if !rules[rule.Id] {
+					rules[rule.Id] = true
+					driver.Rules = append(driver.Rules, rule)
+				}
+			}
+			for _, taxon := range other.Tool.Driver.Taxa {
+				if !taxa[taxon.Id] {
+					taxa[taxon.Id] = true
+					driver.Taxa = append(driver.Taxa, taxon)
+				}
+			}
+		}
+	}
+	run.Results = removeDuplicates(run.Results)
+	return finalReport, nil
+}
+
+// TagReports sets the property of every result of the reports to the tags of the reports reporting the same problem,
+// e.g. the PHP versions it is found for, so the results stay distinguishable once the reports are combined.
+func TagReports(reports []*sarif.Report, tags []string, property string) {
+	found := map[string][]string{}
+	for i, report := range reports {
+		for _, run := range report.Runs {
+			for _, result := range run.Results {
+				if result.PartialFingerprints != nil {
+					fingerprint := getFingerprint(&result)
+					if !slices.Contains(found[fingerprint], tags[i]) {
+						found[fingerprint] = append(found[fingerprint], tags[i])
+					}
+				}
+			}
+		}
+	}
+	for i, report := range reports {
+		for _, run := range report.Runs {
+			for j := range run.Results {
+				result := &run.Results[j]
+				resultTags := []string{tags[i]}
+				if result.PartialFingerprints != nil {
+					resultTags = found[getFingerprint(result)]
+				}
+				if result.Properties == nil {
+					result.Properties = &sarif.PropertyBag{}
+				}
+				if result.Properties.AdditionalProperties == nil {
+					result.Properties.AdditionalProperties = map[string]interface{}{}
+				}
+				result.Properties.AdditionalProperties[property] = resultTags
+			}
+		}
+	}
+}
+
 func RunGUID() string {
 	runGUID := os.Getenv("QODANA_AUTOMATION_GUID")
 	if runGUID == "" {
@@ -323,9 +424,9 @@ func JobUrl() string {
 	return os.Getenv("QODANA_JOB_URL")
 }
 
-func getRuleDescription(report *sarif.Report, ruleId string) string {
-	for _, run := range report.Runs {
-		for _, extension := range run.Tool.Extensions {
+func getRuleDescription(tools []*sarif.Tool, ruleId string) string {
+	for _, tool := range tools {
+		for _, extension := range tool.Extensions {
 			for _, rule := range extension.Rules {
 				if rule.Id == ruleId {
 					return rule.ShortDescription.Text
@@ -337,58 +438,142 @@ func getRuleDescription(report *sarif.Report, ruleId string) string {
 }
 
 // ProcessSarif concludes the result of analysis based on provided SARIF file
-// - can print problems to the output
+// - can print problems to the output if problems is set
 // - can create GitLab CodeQuality issues report
 // - can submit problems to BitBucket Code Insights
-func ProcessSarif(sarifPath, analysisId, reportUrl string, printProblems, codeClimate, codeInsights bool) {
-	newProblems := 0
-	s, err := ReadReport(sarifPath)
-	if err != nil {
-		log.Fatal(err)
+//
+// It returns the number of the new problems.
+//
+// The report is streamed result by result through a pool of workers, so large reports are processed
+// in parallel without being loaded into memory.
+func ProcessSarif(sarifPath, analysisId, reportUrl string, problems *ProblemsOutput, codeClimate, codeInsights bool) int {
+	type processedResult struct {
+		result     *sarif.Result
+		ruleId     string
+		isNew      bool
+		reportable bool
+		issue      CCIssue
+		annotation bbapi.ReportAnnotation
+	}
+
+	var codeClimateWriter *glCodeQualityWriter
+	if codeClimate {
+		w, err := newGlCodeQualityWriter(sarifPath)
+		if err != nil {
+			log.Warnf("Problems writing GitLab CodeQuality report: %v", err)
+		} else {
+			codeClimateWriter = w
+		}
 	}
-	var codeClimateIssues = make([]CCIssue, 0)
 	var codeInsightIssues = make([]bbapi.ReportAnnotation, 0)
-	rulesDescriptions := make(map[string]string)
-	if printProblems {
-		msg.EmptyMessage()
-	}
-	for _, run := range s.Runs {
-		for _, r := range run.Results {
-			ruleId := r.RuleId
-			message := r.Message.Text
+	var codeInsightRuleIds []string
+	codeInsightProblems := 0
+	newProblems := 0
+	var printer problemsPrinter
+	if problems != nil {
+		p, err := newProblemsPrinter(*problems, os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printer = p
+		if _, text := printer.(*textProblemsPrinter); text {
+			msg.EmptyMessage()
+		}
+	}
+
+	results := make(chan sarifResultItem, sarifPipelineBuffer)
+	var tools []*sarif.Tool
+	var readErr error
+	read := make(chan struct{})
+	go func() {
+		tools, readErr = streamSarifResults(sarifPath, results)
+		close(read)
+	}()
+	processInParallel(
+		results,
+		sarifWorkers(),
+		func(item *sarifResultItem) processedResult {
+			r := &item.result
 			baselineState := baselineStateEmpty
 			if r.BaselineState != nil {
 				baselineState = r.BaselineState.(string)
 			}
-			if baselineState == baselineStateNew || baselineState == baselineStateEmpty {
+			p := processedResult{
+				ruleId:     r.RuleId,
+				isNew:      baselineState == baselineStateNew || baselineState == baselineStateEmpty,
+				reportable: len(r.Locations) > 0 && baselineState != baselineStateUnchanged,
+			}
+			if !p.reportable {
+				return p
+			}
+			if codeClimate {
+				p.issue = sarifResultToCodeClimate(r)
+			}
+			if codeInsights {
+				// rule descriptions are set once the whole report is read, the tool may follow the results
+				p.annotation = buildAnnotation(r, "", reportUrl)
+			}
+			if printer != nil && problems.accepts(getSeverity(r)) {
+				p.result = r
+			}
+			return p
+		},
+		func(p processedResult) {
+			if p.isNew {
 				newProblems++
 			}
-			if len(r.Locations) > 0 && baselineState != baselineStateUnchanged {
-				if codeClimate {
-					codeClimateIssues = append(codeClimateIssues, sarifResultToCodeClimate(&r))
+			if !p.reportable {
+				return
+			}
+			if codeClimateWriter != nil {
+				if err := codeClimateWriter.Write(p.issue); err != nil {
+					log.Warnf("Problems writing GitLab CodeQuality report: %v", err)
 				}
-				if codeInsights {
-					ruleDescription, ok := rulesDescriptions[ruleId]
-					if !ok {
-						ruleDescription = getRuleDescription(s, ruleId)
-						rulesDescriptions[ruleId] = ruleDescription
-					}
-					codeInsightIssues = append(codeInsightIssues, buildAnnotation(&r, ruleDescription, reportUrl))
+			}
+			if codeInsights {
+				codeInsightProblems++
+				if len(codeInsightIssues) < bitBucketAnnotationLimit {
+					codeInsightIssues = append(codeInsightIssues, p.annotation)
+					codeInsightRuleIds = append(codeInsightRuleIds, p.ruleId)
 				}
-				if printProblems {
-					printSarifProblem(&r, ruleId, message)
+			}
+			if p.result != nil {
+				if err := printer.print(p.result); err != nil {
+					log.Warnf("Problems printing the problems: %v", err)
 				}
 			}
+		},
+	)
+	<-read
+	if readErr != nil {
+		log.Fatal(readErr)
+	}
+	if printer != nil {
+		if err := printer.close(); err != nil {
+			log.Warnf("Problems printing the problems: %v", err)
 		}
 	}
-	if codeClimate {
-		err = writeGlCodeQualityReport(codeClimateIssues, sarifPath)
-		if err != nil {
+
+	if codeClimateWriter != nil {
+		if err := codeClimateWriter.Close(); err != nil {
 			log.Warnf("Problems writing GitLab CodeQuality report: %v", err)
 		}
 	}
 	if codeInsights {
-		err = sendBitBucketReport(codeInsightIssues, s.Runs[0].Tool.Driver.FullName, reportUrl, "qodana-"+analysisId)
+		rulesDescriptions := make(map[string]string)
+		for i, ruleId := range codeInsightRuleIds {
+			ruleDescription, ok := rulesDescriptions[ruleId]
+			if !ok {
+				ruleDescription = getRuleDescription(tools, ruleId)
+				rulesDescriptions[ruleId] = ruleDescription
+			}
+			codeInsightIssues[i].SetDetails(ruleDescription)
+		}
+		toolName := ""
+		if len(tools) > 0 && tools[0].Driver != nil {
+			toolName = tools[0].Driver.FullName
+		}
+		err := sendBitBucketReport(codeInsightIssues, codeInsightProblems, toolName, reportUrl, "qodana-"+analysisId)
 		if err != nil {
 			log.Warnf("Problems sending BitBucket Code Insights report: %v", err)
 		}
@@ -400,32 +585,90 @@ func ProcessSarif(sarifPath, analysisId, reportUrl string, printProblems, codeCl
 			msg.ErrorMessage(msg.GetProblemsFoundMessage(newProblems))
 		}
 	}
+	return newProblems
 }
 
-func printSarifProblem(r *sarif.Result, ruleId, message string) {
-	if r.Locations[0].PhysicalLocation != nil {
-		msg.PrintProblem(
-			ruleId,
-			getSeverity(r),
-			message,
-			r.Locations[0].PhysicalLocation.ArtifactLocation.Uri,
-			int(r.Locations[0].PhysicalLocation.Region.StartLine),
-			int(r.Locations[0].PhysicalLocation.Region.StartColumn),
-			int(r.Locations[0].PhysicalLocation.ContextRegion.StartLine),
-			r.Locations[0].PhysicalLocation.ContextRegion.Snippet.Text,
-		)
-	} else {
-		msg.PrintProblem(
-			ruleId,
-			getSeverity(r),
-			message,
-			"",
-			0,
-			0,
-			0,
-			"",
-		)
+// printSarifProblem prints the problem with its code, taken from the SARIF report or read from projectDir.
+func printSarifProblem(r *sarif.Result, ruleId, message string, projectDir string) {
+	path, problem, contextLine, context := "", msg.ProblemRange{}, 0, ""
+	if len(r.Locations) > 0 && r.Locations[0].PhysicalLocation != nil {
+		location := r.Locations[0].PhysicalLocation
+		if location.ArtifactLocation != nil {
+			path = location.ArtifactLocation.Uri
+		}
+		if location.ContextRegion != nil && location.ContextRegion.Snippet != nil {
+			contextLine, context = int(location.ContextRegion.StartLine), location.ContextRegion.Snippet.Text
+		}
+		if location.Region != nil {
+			if context == "" && projectDir != "" && path != "" {
+				endLine := max(location.Region.EndLine, location.Region.StartLine)
+				contextLine, context = readSourceLines(
+					filepath.Join(projectDir, filepath.FromSlash(path)),
+					int(location.Region.StartLine)-snippetContextLines,
+					int(endLine)+snippetContextLines,
+				)
+			}
+			if context == "" && location.Region.Snippet != nil {
+				contextLine, context = int(location.Region.StartLine), location.Region.Snippet.Text
+			}
+			problem = problemRange(location.Region, context, contextLine)
+		}
+	}
+	msg.PrintProblem(ruleId, getSeverity(r), message, path, problem, contextLine, context)
+}
+
+// problemRange returns the range of the region, the end of the region of charLength is found in the code starting at contextLine.
+func problemRange(region *sarif.Region, context string, contextLine int) msg.ProblemRange {
+	r := msg.ProblemRange{
+		StartLine:   int(region.StartLine),
+		StartColumn: int(region.StartColumn),
+		EndLine:     int(region.EndLine),
+		EndColumn:   int(region.EndColumn),
+	}
+	if r.EndLine > 0 || r.EndColumn > 0 || region.CharLength <= 0 || r.StartLine <= 0 {
+		return r
+	}
+	lines := strings.Split(strings.TrimSuffix(context, "\n"), "\n")
+	line, column, remaining := r.StartLine, max(r.StartColumn, 1), int(region.CharLength)
+	for remaining > 0 {
+		i := line - contextLine
+		if i < 0 || i >= len(lines) {
+			// the end is out of the code, the range continues to the end of the line
+			r.EndLine = line
+			return r
+		}
+		available := utf8.RuneCountInString(strings.TrimSuffix(lines[i], "\r")) - column + 1
+		if remaining <= available {
+			column += remaining
+			break
+		}
+		remaining -= available + 1
+		line, column = line+1, 1
+	}
+	r.EndLine, r.EndColumn = line, column
+	return r
+}
+
+// readSourceLines reads the lines from..to of the file, returning the number of the first read line and the lines.
+func readSourceLines(path string, from int, to int) (int, string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Debugf("Failed to read the code of the problem: %v", err)
+		return 0, ""
+	}
+	defer func() { _ = f.Close() }()
+	from = max(from, 1)
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for line := 1; line <= to && scanner.Scan(); line++ {
+		if line >= from {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	if len(lines) == 0 {
+		return 0, ""
 	}
+	return from, strings.Join(lines, "\n") + "\n"
 }
 
 // getFingerprint returns the fingerprint of the Qodana (or not) SARIF result.
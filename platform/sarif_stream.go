@@ -0,0 +1,216 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"io"
+	"os"
+	"runtime"
+)
+
+// sarifPipelineBuffer is the number of results buffered between the stages of the SARIF processing pipeline.
+const sarifPipelineBuffer = 1024
+
+// sarifResultItem is a SARIF result read from the report with its position in the report.
+type sarifResultItem struct {
+	index  int
+	result sarif.Result
+}
+
+// sarifWorkers returns the number of workers to process SARIF results with.
+func sarifWorkers() int {
+	return runtime.NumCPU()
+}
+
+// streamSarifResults reads the SARIF report at path result by result and sends the results to ch,
+// so the whole report is never held in memory. It returns the tools of the runs of the report.
+// ch is closed when the report is read.
+func streamSarifResults(path string, ch chan<- sarifResultItem) ([]*sarif.Tool, error) {
+	defer close(ch)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	dec := json.NewDecoder(f)
+	var tools []*sarif.Tool
+	index := 0
+	err = readObject(
+		dec, func(key string) error {
+			if key != "runs" {
+				return skipValue(dec)
+			}
+			return readArray(
+				dec, func() error {
+					return readObject(
+						dec, func(key string) error {
+							switch key {
+							case "tool":
+								var tool sarif.Tool
+								if err := dec.Decode(&tool); err != nil {
+									return err
+								}
+								tools = append(tools, &tool)
+								return nil
+							case "results":
+								return readArray(
+									dec, func() error {
+										item := sarifResultItem{index: index}
+										if err := dec.Decode(&item.result); err != nil {
+											return err
+										}
+										index++
+										ch <- item
+										return nil
+									},
+								)
+							default:
+								return skipValue(dec)
+							}
+						},
+					)
+				},
+			)
+		},
+	)
+	if err != nil {
+		return tools, fmt.Errorf("failed to read SARIF %s: %w", path, err)
+	}
+	return tools, nil
+}
+
+// readObject reads a JSON object calling readValue for each key, readValue must consume the value.
+func readObject(dec *json.Decoder, readValue func(key string) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := t.(string)
+		if !ok {
+			return fmt.Errorf("unexpected token %v", t)
+		}
+		if err = readValue(key); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// readArray reads a JSON array calling readElement for each element, readElement must consume the element.
+func readArray(dec *json.Decoder, readElement func() error) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		if err := readElement(); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %v, got %v", delim, t)
+	}
+	return nil
+}
+
+// skipValue skips the next JSON value without decoding it.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := t.(json.Delim); ok {
+			switch d {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// processInParallel applies process to the items from in with a pool of workers
+// and calls collect for the processed items in the original order.
+func processInParallel[T any](
+	in <-chan sarifResultItem,
+	workers int,
+	process func(item *sarifResultItem) T,
+	collect func(processed T),
+) {
+	type processedItem struct {
+		index int
+		value T
+	}
+	out := make(chan processedItem, sarifPipelineBuffer)
+	done := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for item := range in {
+				out <- processedItem{index: item.index, value: process(&item)}
+			}
+		}()
+	}
+	go func() {
+		for i := 0; i < workers; i++ {
+			<-done
+		}
+		close(out)
+	}()
+
+	// results are processed out of order, keep the ones that are ahead until their turn comes
+	pending := make(map[int]T)
+	next := 0
+	for p := range out {
+		pending[p.index] = p.value
+		for {
+			value, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			collect(value)
+			next++
+		}
+	}
+}
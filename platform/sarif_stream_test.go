@@ -0,0 +1,113 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"encoding/json"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestStreamSarifResults(t *testing.T) {
+	expected, err := ReadReport(filepath.Join("testdata", "merged.qodana.sarif.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan sarifResultItem, sarifPipelineBuffer)
+	var results []sarifResultItem
+	done := make(chan struct{})
+	go func() {
+		for item := range ch {
+			results = append(results, item)
+		}
+		close(done)
+	}()
+	tools, err := streamSarifResults(filepath.Join("testdata", "merged.qodana.sarif.json"), ch)
+	<-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 1 || tools[0].Driver.FullName != expected.Runs[0].Tool.Driver.FullName {
+		t.Errorf("unexpected tools %v", tools)
+	}
+	if len(results) != len(expected.Runs[0].Results) {
+		t.Fatalf("expected %d results, got %d", len(expected.Runs[0].Results), len(results))
+	}
+	for i, item := range results {
+		if item.index != i || getFingerprint(&item.result) != getFingerprint(&expected.Runs[0].Results[i]) {
+			t.Errorf("unexpected result %d: %+v", i, item)
+		}
+	}
+}
+
+func TestStreamSarifResultsMalformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	if err := os.WriteFile(path, []byte(`{"runs": [{"results": [{"ruleId": "a"}, `), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan sarifResultItem, sarifPipelineBuffer)
+	if _, err := streamSarifResults(path, ch); err == nil {
+		t.Errorf("expected an error for a truncated report")
+	}
+}
+
+func TestProcessSarifCodeClimate(t *testing.T) {
+	report := &sarif.Report{Version: "2.1.0", Runs: []sarif.Run{{Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "QDTEST"}}}}}
+	for i := 0; i < 5000; i++ {
+		report.Runs[0].Results = append(
+			report.Runs[0].Results, sarif.Result{
+				RuleId:              "Rule" + strconv.Itoa(i),
+				Message:             &sarif.Message{Text: "problem"},
+				PartialFingerprints: map[string]string{"equalIndicator/v1": strconv.Itoa(i)},
+				Locations: []sarif.Location{
+					{
+						PhysicalLocation: &sarif.PhysicalLocation{
+							ArtifactLocation: &sarif.ArtifactLocation{Uri: "main.go"},
+							Region:           &sarif.Region{StartLine: int64(i + 1)},
+						},
+					},
+				},
+			},
+		)
+	}
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	if err := WriteReport(sarifPath, report); err != nil {
+		t.Fatal(err)
+	}
+
+	ProcessSarif(sarifPath, "", "", nil, true, false)
+
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(sarifPath), glCodeQualityReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issues []CCIssue
+	if err = json.Unmarshal(data, &issues); err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 5000 {
+		t.Fatalf("expected 5000 issues, got %d", len(issues))
+	}
+	for i, issue := range issues {
+		if issue.Fingerprint != strconv.Itoa(i) || issue.Location.Lines.Begin != i+1 {
+			t.Fatalf("issue %d is out of order: %+v", i, issue)
+		}
+	}
+}
@@ -19,8 +19,10 @@ package platform
 import (
 	"github.com/JetBrains/qodana-cli/v2024/platform/thirdpartyscan"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -129,3 +131,62 @@ func TestMergeSarifReports(t *testing.T) {
 func normalize(s string) string {
 	return strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(s)
 }
+
+func TestCombineReports(t *testing.T) {
+	report := func(rules []string, results ...string) *sarif.Report {
+		run := sarif.Run{Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "QDNET"}}}
+		for _, rule := range rules {
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarif.ReportingDescriptor{Id: rule})
+		}
+		for _, fingerprint := range results {
+			run.Results = append(run.Results, sarif.Result{PartialFingerprints: map[string]string{"equalIndicator/v2": fingerprint}})
+		}
+		return &sarif.Report{Runs: []sarif.Run{run}}
+	}
+	combined, err := CombineReports(
+		[]*sarif.Report{
+			report([]string{"UnusedVariable"}, "a", "b"),
+			report([]string{"UnusedVariable", "RedundantUsing"}, "b", "c"),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := combined.Runs[0]
+	if len(run.Results) != 3 {
+		t.Errorf("expected 3 results without the duplicate, got %d", len(run.Results))
+	}
+	if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[1].Id != "RedundantUsing" {
+		t.Errorf("expected the rules of both reports once, got %v", run.Tool.Driver.Rules)
+	}
+	if _, err = CombineReports(nil); err == nil {
+		t.Error("expected an error for no reports")
+	}
+}
+
+func TestTagReports(t *testing.T) {
+	report := func(results ...string) *sarif.Report {
+		run := sarif.Run{}
+		for _, fingerprint := range results {
+			run.Results = append(run.Results, sarif.Result{PartialFingerprints: map[string]string{"equalIndicator/v2": fingerprint}})
+		}
+		return &sarif.Report{Runs: []sarif.Run{run}}
+	}
+	reports := []*sarif.Report{report("a", "b"), report("b", "c")}
+	TagReports(reports, []string{"8.1", "8.3"}, "phpVersions")
+	combined, err := CombineReports(reports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{"a": {"8.1"}, "b": {"8.1", "8.3"}, "c": {"8.3"}}
+	results := combined.Runs[0].Results
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+	for _, result := range results {
+		fingerprint := getFingerprint(&result)
+		if tags := result.Properties.AdditionalProperties["phpVersions"]; !reflect.DeepEqual(tags, expected[fingerprint]) {
+			t.Errorf("expected %s to be tagged with %v, got %v", fingerprint, expected[fingerprint], tags)
+		}
+	}
+}
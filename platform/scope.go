@@ -0,0 +1,138 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdscope"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"sort"
+	"strings"
+)
+
+// ApplyScope drops the problems outside the scope from the SARIF report
+// and checks the problems left against the thresholds, returning true if any is exceeded.
+func ApplyScope(sarifPath string, scope *qdscope.Scope, thresholds map[string]int) (bool, error) {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the SARIF report: %w", err)
+	}
+	if len(report.Runs) == 0 {
+		return false, fmt.Errorf("failed to read the SARIF report %s: no runs found", sarifPath)
+	}
+	results := report.Runs[0].Results
+	kept := results[:0]
+	counts := map[string]int{}
+	for _, r := range results {
+		path, _, _ := problemLocation(&r)
+		if path == "" || !scope.Contains(strings.TrimPrefix(path, "file://")) {
+			continue
+		}
+		kept = append(kept, r)
+		countNewProblem(counts, &r)
+	}
+	msg.SuccessMessage("Scope %s: %d of %d problems are in the scope", scope.Name, len(kept), len(results))
+	report.Runs[0].Results = kept
+	if err = WriteReport(sarifPath, report); err != nil {
+		return false, err
+	}
+	return thresholdsExceeded(counts, thresholds, "scope threshold"), nil
+}
+
+// ThresholdsExceeded checks the new problems of the SARIF report against the thresholds, returning true if any is exceeded.
+func ThresholdsExceeded(sarifPath string, thresholds map[string]int) (bool, error) {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the SARIF report: %w", err)
+	}
+	counts := map[string]int{}
+	for _, run := range report.Runs {
+		for i := range run.Results {
+			countNewProblem(counts, &run.Results[i])
+		}
+	}
+	return thresholdsExceeded(counts, thresholds, "threshold"), nil
+}
+
+// PathThresholdsExceeded checks the new problems of the SARIF report in the paths of each condition against its thresholds,
+// returning true if any is exceeded.
+func PathThresholdsExceeded(sarifPath string, conditions []qdyaml.PathThresholds) (bool, error) {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the SARIF report: %w", err)
+	}
+	exceeded := false
+	for i, condition := range conditions {
+		if len(condition.Paths) == 0 || condition.SeverityThresholds == nil {
+			return false, fmt.Errorf("failureConditions.pathThresholds[%d] must have paths and severityThresholds", i)
+		}
+		paths := &qdscope.Scope{Paths: condition.Paths}
+		counts := map[string]int{}
+		for _, run := range report.Runs {
+			for j := range run.Results {
+				path, _, _ := problemLocation(&run.Results[j])
+				if path != "" && paths.Contains(strings.TrimPrefix(path, "file://")) {
+					countNewProblem(counts, &run.Results[j])
+				}
+			}
+		}
+		name := fmt.Sprintf("%s threshold", strings.Join(condition.Paths, ", "))
+		if thresholdsExceeded(counts, qdscope.Thresholds(nil, condition.SeverityThresholds), name) {
+			exceeded = true
+		}
+	}
+	return exceeded, nil
+}
+
+// countNewProblem counts the problem by its severity if it's new, see isNewProblem.
+func countNewProblem(counts map[string]int, r *sarif.Result) {
+	if isNewProblem(r) {
+		counts[severityAny]++
+		counts[strings.ToLower(getSeverity(r))]++
+	}
+}
+
+// isNewProblem checks if the problem is counted by the quality gates: not in the baseline and not in the old code.
+func isNewProblem(r *sarif.Result) bool {
+	if inOldCode(r) {
+		return false
+	}
+	return r.BaselineState == nil || (r.BaselineState != baselineStateUnchanged && r.BaselineState != baselineStateAbsent)
+}
+
+func thresholdsExceeded(counts map[string]int, thresholds map[string]int, name string) bool {
+	severities := make([]string, 0, len(thresholds))
+	for severity := range thresholds {
+		severities = append(severities, severity)
+	}
+	sort.Strings(severities)
+	exceeded := false
+	for _, severity := range severities {
+		if counts[severity] <= thresholds[severity] {
+			continue
+		}
+		if severity == severityAny {
+			msg.ErrorMessage("%d problems exceed the %s %d", counts[severity], name, thresholds[severity])
+		} else {
+			msg.ErrorMessage("%d %s problems exceed the %s %d", counts[severity], severity, name, thresholds[severity])
+		}
+		exceeded = true
+	}
+	return exceeded
+}
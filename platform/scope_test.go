@@ -0,0 +1,158 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdscope"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyScope(t *testing.T) {
+	result := func(path string, severity string, baselineState interface{}) sarif.Result {
+		return sarif.Result{
+			RuleId:        "Rule",
+			Message:       &sarif.Message{Text: "problem"},
+			BaselineState: baselineState,
+			Properties:    &sarif.PropertyBag{AdditionalProperties: map[string]interface{}{"qodanaSeverity": severity}},
+			Locations: []sarif.Location{
+				{PhysicalLocation: &sarif.PhysicalLocation{ArtifactLocation: &sarif.ArtifactLocation{Uri: path}}},
+			},
+		}
+	}
+	report := &sarif.Report{
+		Version: "2.1.0",
+		Runs: []sarif.Run{
+			{
+				Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "QDTEST"}},
+				Results: []sarif.Result{
+					result("backend/a.go", qodanaCritical, nil),
+					result("backend/b.go", qodanaHigh, baselineStateUnchanged),
+					result("frontend/c.ts", qodanaCritical, nil),
+					{RuleId: "ProjectLevel", Message: &sarif.Message{Text: "no location"}},
+				},
+			},
+		},
+	}
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	if err := WriteReport(sarifPath, report); err != nil {
+		t.Fatal(err)
+	}
+	scope := &qdscope.Scope{Name: "backend", Paths: []string{"backend/"}}
+
+	exceeded, err := ApplyScope(sarifPath, scope, map[string]int{"critical": 1, "any": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exceeded {
+		t.Error("the unchanged problem should not count towards the thresholds")
+	}
+	filtered, err := ReadReport(sarifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered.Runs[0].Results) != 2 {
+		t.Errorf("expected the 2 backend problems to be kept, got %+v", filtered.Runs[0].Results)
+	}
+
+	exceeded, err = ApplyScope(sarifPath, scope, map[string]int{"critical": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exceeded {
+		t.Error("expected the critical threshold to be exceeded")
+	}
+}
+
+func TestPathThresholdsExceeded(t *testing.T) {
+	result := func(path string, severity string, baselineState interface{}) sarif.Result {
+		return sarif.Result{
+			RuleId:        "Rule",
+			Message:       &sarif.Message{Text: "problem"},
+			BaselineState: baselineState,
+			Properties:    &sarif.PropertyBag{AdditionalProperties: map[string]interface{}{"qodanaSeverity": severity}},
+			Locations: []sarif.Location{
+				{PhysicalLocation: &sarif.PhysicalLocation{ArtifactLocation: &sarif.ArtifactLocation{Uri: path}}},
+			},
+		}
+	}
+	report := &sarif.Report{
+		Version: "2.1.0",
+		Runs: []sarif.Run{
+			{
+				Tool: &sarif.Tool{Driver: &sarif.ToolComponent{Name: "QDTEST"}},
+				Results: []sarif.Result{
+					result("src/a.go", qodanaHigh, baselineStateUnchanged),
+					result("src/b.go", qodanaModerate, nil),
+					result("legacy/c.go", qodanaHigh, nil),
+					result("legacy/d.go", qodanaHigh, nil),
+				},
+			},
+		},
+	}
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	if err := WriteReport(sarifPath, report); err != nil {
+		t.Fatal(err)
+	}
+	zero, two := 0, 2
+
+	for _, tc := range []struct {
+		name       string
+		conditions []qdyaml.PathThresholds
+		exceeded   bool
+	}{
+		{
+			name: "ratcheted new code",
+			conditions: []qdyaml.PathThresholds{
+				{Paths: []string{"src/"}, SeverityThresholds: &qdyaml.SeverityThresholds{High: &zero}},
+				{Paths: []string{"legacy/"}, SeverityThresholds: &qdyaml.SeverityThresholds{High: &two}},
+			},
+		},
+		{
+			name: "new problems in new code",
+			conditions: []qdyaml.PathThresholds{
+				{Paths: []string{"/src/**/*.go"}, SeverityThresholds: &qdyaml.SeverityThresholds{Any: &zero}},
+			},
+			exceeded: true,
+		},
+		{
+			name: "legacy code",
+			conditions: []qdyaml.PathThresholds{
+				{Paths: []string{"legacy/"}, SeverityThresholds: &qdyaml.SeverityThresholds{High: &zero}},
+			},
+			exceeded: true,
+		},
+	} {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				exceeded, err := PathThresholdsExceeded(sarifPath, tc.conditions)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if exceeded != tc.exceeded {
+					t.Errorf("expected exceeded to be %v", tc.exceeded)
+				}
+			},
+		)
+	}
+
+	if _, err := PathThresholdsExceeded(sarifPath, []qdyaml.PathThresholds{{Paths: []string{"src/"}}}); err == nil {
+		t.Error("expected the condition without thresholds to be invalid")
+	}
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"strings"
+)
+
+// categoryPrefix marks the --severity-map entries remapping a category instead of an inspection.
+const categoryPrefix = "category:"
+
+// sarifLevels are the SARIF levels of the Qodana severities.
+var sarifLevels = map[string]string{
+	qodanaCritical: sarifError,
+	qodanaHigh:     sarifError,
+	qodanaModerate: sarifWarning,
+	qodanaLow:      sarifNote,
+	qodanaInfo:     sarifNote,
+}
+
+// SeverityMapping is the severity mapping of qodana.yaml merged with the --severity-map entries.
+type SeverityMapping struct {
+	inspections map[string]string
+	// categories are keyed by the lower-cased category IDs and names
+	categories map[string]string
+}
+
+// NewSeverityMapping returns the mapping of qodana.yaml overridden by the INSPECTION=SEVERITY
+// and category:CATEGORY=SEVERITY entries, nil if nothing is remapped.
+func NewSeverityMapping(config qdyaml.SeverityMapping, entries []string) (*SeverityMapping, error) {
+	mapping := &SeverityMapping{inspections: map[string]string{}, categories: map[string]string{}}
+	for inspection, severity := range config.Inspections {
+		if err := mapping.add(inspection, severity, false); err != nil {
+			return nil, err
+		}
+	}
+	for category, severity := range config.Categories {
+		if err := mapping.add(category, severity, true); err != nil {
+			return nil, err
+		}
+	}
+	for _, entry := range entries {
+		key, severity, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid severity mapping %q, expected INSPECTION=SEVERITY or category:CATEGORY=SEVERITY", entry)
+		}
+		category, isCategory := strings.CutPrefix(key, categoryPrefix)
+		if isCategory {
+			key = category
+		}
+		if err := mapping.add(key, severity, isCategory); err != nil {
+			return nil, err
+		}
+	}
+	if len(mapping.inspections) == 0 && len(mapping.categories) == 0 {
+		return nil, nil
+	}
+	return mapping, nil
+}
+
+func (m *SeverityMapping) add(key string, severity string, isCategory bool) error {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return fmt.Errorf("empty inspection or category in the severity mapping")
+	}
+	normalized, err := normalizeSeverity(severity)
+	if err != nil {
+		return fmt.Errorf("invalid severity mapping of %s: %w", key, err)
+	}
+	if isCategory {
+		m.categories[strings.ToLower(key)] = normalized
+	} else {
+		m.inspections[key] = normalized
+	}
+	return nil
+}
+
+// normalizeSeverity returns the Qodana severity in the case used in the SARIF reports, e.g. "Moderate".
+func normalizeSeverity(severity string) (string, error) {
+	for qodanaSeverity := range sarifLevels {
+		if strings.EqualFold(strings.TrimSpace(severity), qodanaSeverity) {
+			return qodanaSeverity, nil
+		}
+	}
+	return "", fmt.Errorf("unknown severity %q, expected one of critical, high, moderate, low, info", severity)
+}
+
+// severity returns the severity the problems of the inspection are remapped to,
+// the mapping of the inspection takes precedence over the mapping of its categories.
+func (m *SeverityMapping) severity(ruleId string, categories []string) (string, bool) {
+	if severity, ok := m.inspections[ruleId]; ok {
+		return severity, true
+	}
+	for _, category := range categories {
+		if severity, ok := m.categories[strings.ToLower(category)]; ok {
+			return severity, true
+		}
+	}
+	return "", false
+}
+
+// ApplySeverityMapping rewrites the severities of the problems in the SARIF report and returns the number
+// of the problems remapped.
+func ApplySeverityMapping(sarifPath string, mapping *SeverityMapping) (int, error) {
+	report, err := ReadReport(sarifPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the SARIF report: %w", err)
+	}
+	remapped := 0
+	for i := range report.Runs {
+		run := &report.Runs[i]
+		categories := ruleCategories(run.Tool)
+		for j := range run.Results {
+			r := &run.Results[j]
+			severity, ok := mapping.severity(r.RuleId, categories[r.RuleId])
+			if !ok || getSeverity(r) == severity {
+				continue
+			}
+			if r.Properties == nil {
+				r.Properties = &sarif.PropertyBag{}
+			}
+			if r.Properties.AdditionalProperties == nil {
+				r.Properties.AdditionalProperties = map[string]interface{}{}
+			}
+			r.Properties.AdditionalProperties["qodanaSeverity"] = severity
+			r.Level = sarifLevels[severity]
+			remapped++
+		}
+	}
+	if remapped == 0 {
+		return 0, nil
+	}
+	msg.SuccessMessage("Severity mapping: %d problems are remapped", remapped)
+	return remapped, WriteReport(sarifPath, report)
+}
+
+// ruleCategories returns the IDs and the names of the categories (taxa) of the rules of the tool.
+func ruleCategories(tool *sarif.Tool) map[string][]string {
+	categories := map[string][]string{}
+	if tool == nil {
+		return categories
+	}
+	components := tool.Extensions
+	if tool.Driver != nil {
+		components = append([]sarif.ToolComponent{*tool.Driver}, components...)
+	}
+	taxa := map[string]string{}
+	for _, component := range components {
+		for _, taxon := range component.Taxa {
+			taxa[taxon.Id] = taxon.Name
+		}
+	}
+	for _, component := range components {
+		for _, rule := range component.Rules {
+			for _, relationship := range rule.Relationships {
+				if relationship.Target == nil || relationship.Target.Id == "" {
+					continue
+				}
+				id := relationship.Target.Id
+				categories[rule.Id] = append(categories[rule.Id], id)
+				if name := taxa[id]; name != "" {
+					categories[rule.Id] = append(categories[rule.Id], name)
+				}
+			}
+		}
+	}
+	return categories
+}
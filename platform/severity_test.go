@@ -0,0 +1,146 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"github.com/JetBrains/qodana-cli/v2024/sarif"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewSeverityMapping(t *testing.T) {
+	mapping, err := NewSeverityMapping(qdyaml.SeverityMapping{}, nil)
+	if err != nil || mapping != nil {
+		t.Errorf("expected no mapping, got %+v, %v", mapping, err)
+	}
+	for _, entries := range [][]string{{"JavaDoc"}, {"JavaDoc=trivial"}, {"=low"}} {
+		if _, err = NewSeverityMapping(qdyaml.SeverityMapping{}, entries); err == nil {
+			t.Errorf("expected %v to be invalid", entries)
+		}
+	}
+	mapping, err = NewSeverityMapping(
+		qdyaml.SeverityMapping{
+			Inspections: map[string]string{"JavaDoc": "info", "UnusedImport": "low"},
+			Categories:  map[string]string{"Code style issues": "LOW"},
+		},
+		[]string{"JavaDoc=moderate", "category:Security=critical"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		ruleId     string
+		categories []string
+		expected   string
+	}{
+		{"JavaDoc", []string{"code style issues"}, qodanaModerate},
+		{"UnusedImport", nil, qodanaLow},
+		{"Other", []string{"Code style issues"}, qodanaLow},
+		{"Other", []string{"security"}, qodanaCritical},
+		{"Other", nil, ""},
+	} {
+		if severity, _ := mapping.severity(tc.ruleId, tc.categories); severity != tc.expected {
+			t.Errorf("%s %v: expected %q, got %q", tc.ruleId, tc.categories, tc.expected, severity)
+		}
+	}
+}
+
+func TestApplySeverityMapping(t *testing.T) {
+	result := func(ruleId string, severity string, baselineState interface{}) sarif.Result {
+		return sarif.Result{
+			RuleId:        ruleId,
+			Message:       &sarif.Message{Text: "problem"},
+			Level:         sarifError,
+			BaselineState: baselineState,
+			Properties:    &sarif.PropertyBag{AdditionalProperties: map[string]interface{}{"qodanaSeverity": severity}},
+		}
+	}
+	report := &sarif.Report{
+		Version: "2.1.0",
+		Runs: []sarif.Run{
+			{
+				Tool: &sarif.Tool{
+					Driver: &sarif.ToolComponent{
+						Name: "QDTEST",
+						Taxa: []sarif.ReportingDescriptor{{Id: "Java/Javadoc", Name: "Javadoc"}},
+					},
+					Extensions: []sarif.ToolComponent{
+						{
+							Name: "org.jetbrains.java",
+							Rules: []sarif.ReportingDescriptor{
+								{
+									Id: "MissingJavadoc",
+									Relationships: []sarif.ReportingDescriptorRelationship{
+										{Target: &sarif.ReportingDescriptorReference{Id: "Java/Javadoc"}},
+									},
+								},
+							},
+						},
+					},
+				},
+				Results: []sarif.Result{
+					result("MissingJavadoc", qodanaHigh, nil),
+					result("MissingJavadoc", qodanaHigh, baselineStateUnchanged),
+					result("NullPointer", qodanaCritical, nil),
+				},
+			},
+		},
+	}
+	sarifPath := filepath.Join(t.TempDir(), "qodana.sarif.json")
+	if err := WriteReport(sarifPath, report); err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := NewSeverityMapping(qdyaml.SeverityMapping{Categories: map[string]string{"Javadoc": "info"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remapped, err := ApplySeverityMapping(sarifPath, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remapped != 2 {
+		t.Errorf("expected the 2 problems of MissingJavadoc to be remapped, got %d", remapped)
+	}
+	remappedReport, err := ReadReport(sarifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := remappedReport.Runs[0].Results
+	if getSeverity(&results[0]) != qodanaInfo || results[0].Level != sarifNote {
+		t.Errorf("expected MissingJavadoc to be remapped to Info, got %+v", results[0])
+	}
+	if getSeverity(&results[2]) != qodanaCritical {
+		t.Errorf("expected NullPointer to keep its severity, got %+v", results[2])
+	}
+
+	exceeded, err := ThresholdsExceeded(sarifPath, map[string]int{"high": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exceeded {
+		t.Error("expected no high problems left after the remapping")
+	}
+	exceeded, err = ThresholdsExceeded(sarifPath, map[string]int{"any": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exceeded {
+		t.Error("expected the 2 new problems to exceed the threshold")
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"errors"
+	"github.com/JetBrains/qodana-cli/v2024/platform/cmd"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdpolicy"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdsign"
+	"github.com/JetBrains/qodana-cli/v2024/platform/userconfig"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+)
+
+// TrustedKeysPath returns the path to the default trusted keys file.
+func TrustedKeysPath() string {
+	return filepath.Join(userconfig.Dir(), "trusted-keys.pub")
+}
+
+// EnableSignatureVerification enables the verification of the signatures with the trusted keys if --verify-signatures
+// is set or the policy requires it, and verifies the policy and the user config right away.
+func EnableSignatureVerification(options platformcmd.CliOptions) error {
+	policy, err := qdpolicy.Load()
+	if err != nil {
+		return err
+	}
+	if !options.VerifySignatures && (policy == nil || !policy.VerifySignatures) {
+		return nil
+	}
+	keys := options.TrustedKeys
+	if keys == "" {
+		keys = TrustedKeysPath()
+	}
+	verifier, err := qdsign.LoadVerifier(keys)
+	if err != nil {
+		return err
+	}
+	qdsign.Enable(verifier)
+	configs := []string{userconfig.Path()}
+	if policy != nil {
+		configs = append(configs, qdpolicy.Path())
+	}
+	for _, config := range configs {
+		if _, err = os.Stat(config); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err = qdsign.VerifyFile(config); err != nil {
+			return err
+		}
+	}
+	log.Debugf("Signatures are verified with the keys of %s", keys)
+	return nil
+}
@@ -17,9 +17,11 @@
 package platform
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/cloud"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
 	"github.com/JetBrains/qodana-cli/v2024/platform/thirdpartyscan"
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	"github.com/JetBrains/qodana-cli/v2024/tooling"
@@ -28,6 +30,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -50,6 +53,7 @@ func createFuserEventChannel(events *[]tooling.FuserEvent) chan tooling.FuserEve
 }
 
 func sendFuserEvents(
+	ctx context.Context,
 	ch chan tooling.FuserEvent,
 	events *[]tooling.FuserEvent,
 	c thirdpartyscan.Context,
@@ -107,7 +111,7 @@ func sendFuserEvents(
 	if os.Getenv("GO_TESTING") == "true" {
 		args = append(args, "true")
 	}
-	_, _, _, _ = utils.LaunchAndLog(c.LogDir(), "fuser", args...)
+	_, _, _, _ = utils.LaunchAndLog(ctx, c.LogDir(), "fuser", args...)
 }
 
 func currentTimestamp() int64 {
@@ -122,36 +126,31 @@ func commonEventData(linterInfo thirdpartyscan.LinterInfo, projectIdHash string)
 	return eventData
 }
 
-func logProjectOpen(ch chan tooling.FuserEvent, linterInfo thirdpartyscan.LinterInfo, projectIdHash string) {
-	wg.Add(1)
-	eventData := commonEventData(linterInfo, projectIdHash)
-	ch <- tooling.FuserEvent{
+func projectOpenedEvent(linterInfo thirdpartyscan.LinterInfo, projectIdHash string) tooling.FuserEvent {
+	return tooling.FuserEvent{
 		GroupId:   "qd.cl.lifecycle",
 		EventName: "project.opened",
-		EventData: eventData,
+		EventData: commonEventData(linterInfo, projectIdHash),
 		Time:      currentTimestamp(),
 		State:     false,
 	}
 }
 
-func logProjectClose(ch chan tooling.FuserEvent, linterInfo thirdpartyscan.LinterInfo, projectIdHash string) {
-	wg.Add(1)
-	eventData := commonEventData(linterInfo, projectIdHash)
-	ch <- tooling.FuserEvent{
+func projectClosedEvent(linterInfo thirdpartyscan.LinterInfo, projectIdHash string) tooling.FuserEvent {
+	return tooling.FuserEvent{
 		GroupId:   "qd.cl.lifecycle",
 		EventName: "project.closed",
-		EventData: eventData,
+		EventData: commonEventData(linterInfo, projectIdHash),
 		Time:      currentTimestamp(),
 		State:     false,
 	}
 }
 
-func logOs(ch chan tooling.FuserEvent, linterInfo thirdpartyscan.LinterInfo, projectIdHash string) {
-	wg.Add(1)
+func osEvent(linterInfo thirdpartyscan.LinterInfo, projectIdHash string) tooling.FuserEvent {
 	eventData := commonEventData(linterInfo, projectIdHash)
 	eventData["name"] = runtime.GOOS
 	eventData["arch"] = runtime.GOARCH
-	ch <- tooling.FuserEvent{
+	return tooling.FuserEvent{
 		GroupId:   "qd.cl.system.os",
 		EventName: "os.name",
 		EventData: eventData,
@@ -159,3 +158,58 @@ func logOs(ch chan tooling.FuserEvent, linterInfo thirdpartyscan.LinterInfo, pro
 		State:     true,
 	}
 }
+
+func logProjectOpen(ch chan tooling.FuserEvent, linterInfo thirdpartyscan.LinterInfo, projectIdHash string) {
+	wg.Add(1)
+	ch <- projectOpenedEvent(linterInfo, projectIdHash)
+}
+
+func logProjectClose(ch chan tooling.FuserEvent, linterInfo thirdpartyscan.LinterInfo, projectIdHash string) {
+	wg.Add(1)
+	ch <- projectClosedEvent(linterInfo, projectIdHash)
+}
+
+func logOs(ch chan tooling.FuserEvent, linterInfo thirdpartyscan.LinterInfo, projectIdHash string) {
+	wg.Add(1)
+	ch <- osEvent(linterInfo, projectIdHash)
+}
+
+// TelemetryPreview describes the anonymous usage statistics sent by Qodana.
+type TelemetryPreview struct {
+	// Enabled is false if the statistics are not sent at all.
+	Enabled bool `json:"enabled"`
+	// DisabledReason explains why the statistics are disabled.
+	DisabledReason string `json:"disabledReason,omitempty"`
+	// ProductCode and LinterVersion are sent along with the events.
+	ProductCode   string `json:"productCode"`
+	LinterVersion string `json:"linterVersion"`
+	// IdeProperties are passed to the IDE-based linters, which report their feature usage statistics themselves.
+	IdeProperties map[string]string `json:"ideProperties"`
+	// Events are sent by the CLI for third-party linters (e.g. qodana-cdnet, qodana-clang).
+	Events []tooling.FuserEvent `json:"events"`
+}
+
+// GetTelemetryPreview returns the usage statistics that would be sent by the run with the given linter.
+func GetTelemetryPreview(linterInfo thirdpartyscan.LinterInfo, projectIdHash string, noStatistics bool) TelemetryPreview {
+	preview := TelemetryPreview{ProductCode: linterInfo.ProductCode, LinterVersion: linterInfo.LinterVersion}
+	if noStatistics {
+		preview.DisabledReason = fmt.Sprintf("disabled with --no-telemetry or %s=%s", qdenv.QodanaTelemetryEnv, qdenv.TelemetryOff)
+	} else if !cloud.Token.IsAllowedToSendFUS() {
+		preview.DisabledReason = "disabled for license-only tokens"
+	}
+	deviceIdSalt := GetDeviceIdSalt()
+	preview.Enabled = preview.DisabledReason == ""
+	preview.IdeProperties = map[string]string{
+		"idea.headless.enable.statistics":    strconv.FormatBool(preview.Enabled),
+		"idea.headless.statistics.device.id": deviceIdSalt[0],
+		"idea.headless.statistics.salt":      deviceIdSalt[1],
+	}
+	if preview.Enabled {
+		preview.Events = []tooling.FuserEvent{
+			osEvent(linterInfo, projectIdHash),
+			projectOpenedEvent(linterInfo, projectIdHash),
+			projectClosedEvent(linterInfo, projectIdHash),
+		}
+	}
+	return preview
+}
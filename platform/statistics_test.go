@@ -0,0 +1,49 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/cloud"
+	"github.com/JetBrains/qodana-cli/v2024/platform/thirdpartyscan"
+	"testing"
+)
+
+func TestGetTelemetryPreview(t *testing.T) {
+	cloud.Token = cloud.LicenseToken{Token: "token"}
+	defer func() { cloud.Token = cloud.LicenseToken{} }()
+	linterInfo := thirdpartyscan.LinterInfo{ProductCode: "QDNET", LinterVersion: "2024.3.1"}
+
+	preview := GetTelemetryPreview(linterInfo, "hash", false)
+	if !preview.Enabled || len(preview.Events) != 3 {
+		t.Fatalf("expected enabled telemetry with 3 events, got %+v", preview)
+	}
+	for _, event := range preview.Events {
+		if event.EventData["version"] != "2024.3" || event.EventData[qodanaProjectId] != "hash" {
+			t.Errorf("unexpected event data %v", event.EventData)
+		}
+	}
+
+	preview = GetTelemetryPreview(linterInfo, "hash", true)
+	if preview.Enabled || len(preview.Events) != 0 || preview.IdeProperties["idea.headless.enable.statistics"] != "false" {
+		t.Errorf("expected disabled telemetry, got %+v", preview)
+	}
+
+	cloud.Token = cloud.LicenseToken{Token: "token", LicenseOnly: true}
+	if preview = GetTelemetryPreview(linterInfo, "", false); preview.Enabled {
+		t.Errorf("expected telemetry to be disabled for license-only tokens")
+	}
+}
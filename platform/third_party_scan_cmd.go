@@ -30,6 +30,9 @@ import (
 // NewThirdPartyScanCommand returns a new instance of the scan command.
 func NewThirdPartyScanCommand(linter ThirdPartyLinter, linterInfo thirdpartyscan.LinterInfo) *cobra.Command {
 	cliOptions := &platformcmd.CliOptions{}
+	var quiet, porcelain bool
+	var lang string
+	var ascii, screenReader bool
 	cmd := &cobra.Command{
 		Use:   "scan",
 		Short: "Scan project with Qodana",
@@ -38,11 +41,20 @@ func NewThirdPartyScanCommand(linter ThirdPartyLinter, linterInfo thirdpartyscan
 
 Note that most options can be configured via qodana.yaml (https://www.jetbrains.com/help/qodana/qodana-yaml.html) file.
 But you can always override qodana.yaml options with the following command-line options.
+Every option can be set with the QODANA_<OPTION> environment variable as well, e.g. QODANA_FAIL_THRESHOLD=10 for --fail-threshold 10:
+the command-line options take precedence over the environment variables, which take precedence over qodana.yaml.
 `, linterInfo.LinterName,
 		),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			log.SetFormatter(&log.TextFormatter{DisableQuote: true, DisableTimestamp: true})
-			exitCode, err := RunThirdPartyLinterAnalysis(*cliOptions, linter, linterInfo)
+			msg.SetOutputMode(platformcmd.OutputMode(quiet, porcelain))
+			platformcmd.ApplyAccessibility(ascii, screenReader)
+			if err := msg.SetLanguage(lang); err != nil {
+				msg.WarningMessage("%s", err)
+			}
+			StartAudit(cliOptions.AuditLog, cmd)
+			EnableResultsManifest(*cliOptions)
+			exitCode, err := RunThirdPartyLinterAnalysis(cmd.Context(), *cliOptions, linter, linterInfo)
 
 			log.Debug("exitCode: ", exitCode)
 			if exitCode == utils.QodanaFailThresholdExitCode {
@@ -58,6 +70,9 @@ But you can always override qodana.yaml options with the following command-line
 	if err != nil {
 		log.Fatal("Error while computing flags")
 	}
+	platformcmd.AddOutputFlags(cmd.Flags(), &quiet, &porcelain)
+	platformcmd.AddLanguageFlag(cmd.Flags(), &lang)
+	platformcmd.AddAccessibilityFlags(cmd.Flags(), &ascii, &screenReader)
 
 	return cmd
 }
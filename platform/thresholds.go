@@ -70,3 +70,16 @@ func thresholdsToArgs(thresholds map[string]string) []string {
 	}
 	return args
 }
+
+// parseThresholds converts the thresholds of getFailureThresholds to numbers.
+func parseThresholds(thresholds map[string]string) (map[string]int, error) {
+	ret := make(map[string]int, len(thresholds))
+	for severity, value := range thresholds {
+		threshold, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s threshold %q: %w", severity, value, err)
+		}
+		ret[severity] = threshold
+	}
+	return ret, nil
+}
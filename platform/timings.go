@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdtiming"
+	"github.com/pterm/pterm"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+// profiledInspections is the number of the slowest inspections printed after the run.
+const profiledInspections = 20
+
+// ReportInspectionTimings writes the profile of the inspections recorded by the IDE to the results directory
+// and prints the slowest ones.
+func ReportInspectionTimings(resultsDir string) {
+	timings, err := qdtiming.Load(resultsDir)
+	if errors.Is(err, qdtiming.ErrNoTimings) {
+		msg.WarningMessage("The inspection timings are not available, the linter has not recorded them")
+		return
+	}
+	if err != nil {
+		msg.WarningMessage("Unable to read the inspection timings: %s", err)
+		return
+	}
+	path, err := qdtiming.Write(timings, resultsDir)
+	if err != nil {
+		msg.WarningMessage("Unable to write the inspection profile: %s", err)
+		return
+	}
+	printInspectionTimings(timings)
+	msg.SuccessMessage("Inspection profile is written to %s", path)
+}
+
+func printInspectionTimings(timings []qdtiming.Timing) {
+	if len(timings) == 0 {
+		return
+	}
+	tableData := pterm.TableData{
+		{msg.PrimaryBold("Inspection"), msg.PrimaryBold("Time"), msg.PrimaryBold("Share"), msg.PrimaryBold("Files")},
+	}
+	for i, t := range timings {
+		if i == profiledInspections {
+			break
+		}
+		tableData = append(
+			tableData,
+			[]string{
+				t.Inspection,
+				(time.Duration(t.TimeMs) * time.Millisecond).Round(time.Millisecond).String(),
+				fmt.Sprintf("%.1f%%", t.Share),
+				strconv.Itoa(t.Files),
+			},
+		)
+	}
+
+	msg.EmptyMessage()
+	table := pterm.DefaultTable.WithData(tableData)
+	table.HeaderRowSeparator = ""
+	table.Separator = " "
+	table.Boxed = true
+	if err := table.Render(); err != nil {
+		log.Debugf("Failed to print the inspection timings: %v", err)
+	}
+}
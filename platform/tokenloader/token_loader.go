@@ -17,6 +17,7 @@
 package tokenloader
 
 import (
+	"context"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/cloud"
 	"github.com/JetBrains/qodana-cli/v2024/platform/git"
@@ -26,12 +27,16 @@ import (
 	"github.com/JetBrains/qodana-cli/v2024/platform/utils"
 	"github.com/pterm/pterm"
 	log "github.com/sirupsen/logrus"
-	"github.com/zalando/go-keyring"
 	"os"
 	"strings"
 )
 
-const keyringDefaultService = "qodana-cli"
+const (
+	// AuthToken uses the long-lived token from QODANA_TOKEN or the token storage.
+	AuthToken = "token"
+	// AuthOidc exchanges the CI OIDC ID token for a short-lived Qodana Cloud token.
+	AuthOidc = "oidc"
+)
 
 type CloudTokenLoader interface {
 	GetQodanaToken() string
@@ -73,6 +78,28 @@ func IsCloudTokenRequired(tokenLoader CloudTokenLoader, forceIsCommunityOrEap bo
 	return false
 }
 
+// ResolveCloudToken returns the Qodana Cloud token to use for the given --auth method.
+func ResolveCloudToken(auth string, token string) string {
+	switch auth {
+	case AuthToken, "":
+		return token
+	case AuthOidc:
+		idToken, err := cloud.GetOidcIdToken()
+		if err != nil {
+			log.Fatal(err)
+		}
+		exchanged, err := cloud.GetCloudApiEndpoints().ExchangeOidcToken(idToken)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Debug("Obtained short-lived Qodana Cloud token via OIDC token exchange")
+		return exchanged
+	default:
+		log.Fatalf("Unknown authentication method '%s', supported are '%s' and '%s'", auth, AuthToken, AuthOidc)
+		return ""
+	}
+}
+
 func LoadCloudToken(tokenLoader CloudTokenLoader, refresh bool, requiresToken bool, interactive bool) string {
 	tokenFetchers := []func(bool) string{
 		func(_ bool) string { return tokenLoader.GetQodanaToken() },
@@ -115,30 +142,10 @@ func ValidateTokenPrintProject(token string) {
 	}
 }
 
-// saveCloudToken saves token to the system keyring
-func saveCloudToken(id string, token string) error {
-	err := keyring.Set(keyringDefaultService, id, token)
-	if err != nil {
-		return err
-	}
-	log.Debugf("Saved token to the system keyring with id %s", id)
-	return nil
-}
-
-// getCloudToken returns token from the system keyring
-func getCloudToken(id string) (string, error) {
-	secret, err := keyring.Get(keyringDefaultService, id)
-	if err != nil {
-		return "", err
-	}
-	log.Debugf("Got token from the system keyring with id %s", id)
-	return secret, nil
-}
-
 func setupToken(path string, id string, logdir string) string {
 	openCloud := msg.AskUserConfirm("Do you want to open the team page to get the token?")
 	if openCloud {
-		origin, err := git.RemoteUrl(path, logdir)
+		origin, err := git.RemoteUrl(context.Background(), path, logdir)
 		if err != nil {
 			msg.ErrorMessage("%s", err)
 			return ""
@@ -169,7 +176,7 @@ func setupToken(path string, id string, logdir string) string {
 			msg.ErrorMessage("Invalid token, try again")
 			return ""
 		}
-		err = saveCloudToken(id, token)
+		_, err = SaveCloudToken(id, token)
 		if err != nil {
 			msg.ErrorMessage("Failed to save credentials: %s", err)
 			return ""
@@ -190,21 +197,22 @@ func getTokenFromEnv() string {
 func getTokenFromKeychain(refresh bool, id string) string {
 	log.Debugf("project id: %s", id)
 	if refresh || os.Getenv(qdenv.QodanaClearKeyring) != "" {
-		err := keyring.Delete(keyringDefaultService, id)
+		err := DeleteCloudToken(id)
 		if err != nil {
-			log.Debugf("Failed to delete token from the system keyring: %s", err)
+			log.Debugf("Failed to delete the saved token: %s", err)
 		}
 		return ""
 	}
-	tokenFromKeychain, err := getCloudToken(id)
+	tokenFromKeychain, storage, err := GetSavedCloudToken(id)
 	if err == nil && tokenFromKeychain != "" {
 		msg.WarningMessage(
-			"Got %s from the system keyring, declare %s env variable or run %s to override it",
+			"Got %s from the %s, declare %s env variable or run %s to override it",
 			msg.PrimaryBold(qdenv.QodanaToken),
+			storage,
 			msg.PrimaryBold(qdenv.QodanaToken),
-			msg.PrimaryBold("qodana init -f"),
+			msg.PrimaryBold("qodana auth login"),
 		)
-		log.Debugf("Loaded token from the system keyring with id %s", id)
+		log.Debugf("Loaded token from the %s with id %s", storage, id)
 		return tokenFromKeychain
 	}
 	return ""
@@ -226,3 +234,26 @@ func getTokenFromUserInput(projectDir string, id string, logDir string) string {
 	}
 	return ""
 }
+
+// LoginCloudToken validates the given token and saves it to the token storage. If the token is empty, the user is asked for it.
+func LoginCloudToken(tokenLoader CloudTokenLoader, token string) error {
+	if token == "" {
+		if !msg.IsInteractive() {
+			return fmt.Errorf("no token provided, pass it via stdin with --with-token")
+		}
+		if getTokenFromUserInput(tokenLoader.GetProjectDir(), tokenLoader.GetId(), tokenLoader.GetLogDir()) == "" {
+			return fmt.Errorf("login cancelled")
+		}
+		return nil
+	}
+	client := cloud.GetCloudApiEndpoints().NewCloudApiClient(token)
+	if _, err := client.RequestProjectName(); err != nil {
+		return fmt.Errorf("%s: %w", cloud.InvalidTokenMessage, err)
+	}
+	storage, err := SaveCloudToken(tokenLoader.GetId(), token)
+	if err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+	log.Debugf("Saved token to the %s", storage)
+	return nil
+}
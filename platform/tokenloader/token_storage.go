@@ -0,0 +1,148 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tokenloader
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/JetBrains/qodana-cli/v2024/platform/userconfig"
+	log "github.com/sirupsen/logrus"
+	"github.com/zalando/go-keyring"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+)
+
+const (
+	keyringDefaultService = "qodana-cli"
+
+	// StorageKeyring is the name of the token storage backed by the OS keychain (Keychain/DPAPI/secret-service).
+	StorageKeyring = "system keyring"
+	// StorageEncryptedFile is the name of the fallback token storage used when the OS keychain is not available.
+	StorageEncryptedFile = "encrypted file"
+)
+
+// SaveCloudToken saves the token to the system keyring, falling back to an encrypted file if the keyring is unavailable.
+func SaveCloudToken(id string, token string) (string, error) {
+	err := keyring.Set(keyringDefaultService, id, token)
+	if err == nil {
+		log.Debugf("Saved token to the system keyring with id %s", id)
+		return StorageKeyring, nil
+	}
+	log.Debugf("Failed to save token to the system keyring: %s, falling back to an encrypted file", err)
+	if err = saveEncryptedToken(id, token); err != nil {
+		return "", err
+	}
+	log.Debugf("Saved token to %s", encryptedTokenPath(id))
+	return StorageEncryptedFile, nil
+}
+
+// GetSavedCloudToken returns the token saved by SaveCloudToken and the storage it was loaded from.
+func GetSavedCloudToken(id string) (string, string, error) {
+	secret, err := keyring.Get(keyringDefaultService, id)
+	if err == nil && secret != "" {
+		log.Debugf("Got token from the system keyring with id %s", id)
+		return secret, StorageKeyring, nil
+	}
+	secret, fileErr := loadEncryptedToken(id)
+	if fileErr != nil {
+		if errors.Is(fileErr, os.ErrNotExist) && err != nil {
+			return "", "", err
+		}
+		return "", "", fileErr
+	}
+	log.Debugf("Got token from %s", encryptedTokenPath(id))
+	return secret, StorageEncryptedFile, nil
+}
+
+// DeleteCloudToken removes the token from both the system keyring and the encrypted file storage.
+func DeleteCloudToken(id string) error {
+	keyringErr := keyring.Delete(keyringDefaultService, id)
+	if errors.Is(keyringErr, keyring.ErrNotFound) {
+		keyringErr = nil
+	}
+	fileErr := os.Remove(encryptedTokenPath(id))
+	if errors.Is(fileErr, os.ErrNotExist) {
+		fileErr = nil
+	}
+	return errors.Join(keyringErr, fileErr)
+}
+
+// encryptedTokenPath returns the path of the encrypted token file for the given project id.
+func encryptedTokenPath(id string) string {
+	return filepath.Join(userconfig.Dir(), "credentials", id)
+}
+
+// encryptionKey derives the file encryption key from the current user and host, so the file is useless elsewhere.
+func encryptionKey(id string) []byte {
+	hostname, _ := os.Hostname()
+	username := ""
+	if u, err := user.Current(); err == nil {
+		username = u.Uid + u.Username
+	}
+	key := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%s:%s", keyringDefaultService, hostname, username, id)))
+	return key[:]
+}
+
+func saveEncryptedToken(id string, token string) error {
+	gcm, err := newGcm(id)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	path := encryptedTokenPath(id)
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, gcm.Seal(nonce, nonce, []byte(token), nil), 0o600)
+}
+
+func loadEncryptedToken(id string) (string, error) {
+	data, err := os.ReadFile(encryptedTokenPath(id))
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGcm(id)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("malformed encrypted token file")
+	}
+	nonce, cipherText := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	token, err := gcm.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the token: %w", err)
+	}
+	return string(token), nil
+}
+
+func newGcm(id string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(encryptionKey(id))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
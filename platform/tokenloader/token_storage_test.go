@@ -0,0 +1,50 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tokenloader
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEncryptedTokenStorage(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+
+	if err := saveEncryptedToken("project-id", "secret-token"); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(encryptedTokenPath("project-id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) == "secret-token" {
+		t.Fatal("token is stored in plaintext")
+	}
+	token, err := loadEncryptedToken("project-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "secret-token" {
+		t.Fatalf("expected secret-token, got %s", token)
+	}
+	if _, err = loadEncryptedToken("other-project-id"); err == nil {
+		t.Fatal("expected error for unknown project id")
+	}
+}
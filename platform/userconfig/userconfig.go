@@ -0,0 +1,104 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package userconfig
+
+import (
+	"errors"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UserConfig is the user-wide Qodana CLI configuration, stored in <userConfigDir>/JetBrains/Qodana/config.yaml.
+type UserConfig struct {
+	// Cloud is the default Qodana Cloud connection configuration.
+	Cloud qdyaml.Cloud `yaml:"cloud,omitempty"`
+
+	// Projects contains per-project overrides of the configuration.
+	Projects []ProjectConfig `yaml:"projects,omitempty"`
+}
+
+// ProjectConfig overrides the user configuration for the projects located in Path.
+type ProjectConfig struct {
+	// Path is the absolute path to the project directory.
+	Path string `yaml:"path"`
+
+	// Cloud is the Qodana Cloud connection configuration for the project.
+	Cloud qdyaml.Cloud `yaml:"cloud,omitempty"`
+}
+
+// Dir returns the directory where the user-wide Qodana CLI configuration and data are stored.
+func Dir() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		configDir = os.TempDir()
+	}
+	return filepath.Join(configDir, "JetBrains", "Qodana")
+}
+
+// Path returns the path to the user configuration file.
+func Path() string {
+	return filepath.Join(Dir(), "config.yaml")
+}
+
+// Load reads the user configuration, returning an empty one if it does not exist or is invalid.
+func Load() UserConfig {
+	c := UserConfig{}
+	data, err := os.ReadFile(Path())
+	if errors.Is(err, os.ErrNotExist) {
+		return c
+	}
+	if err != nil {
+		log.Warnf("Failed to read %s: %v", Path(), err)
+		return c
+	}
+	if err = yaml.Unmarshal(data, &c); err != nil {
+		log.Warnf("Failed to parse %s: %v", Path(), err)
+		return UserConfig{}
+	}
+	return c
+}
+
+// project returns the most specific project override for the given project directory.
+func (c UserConfig) project(projectDir string) *ProjectConfig {
+	absDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return nil
+	}
+	var found *ProjectConfig
+	for i, p := range c.Projects {
+		path := filepath.Clean(p.Path)
+		if absDir != path && !strings.HasPrefix(absDir, path+string(filepath.Separator)) {
+			continue
+		}
+		if found == nil || len(path) > len(filepath.Clean(found.Path)) {
+			found = &c.Projects[i]
+		}
+	}
+	return found
+}
+
+// CloudFor returns the Qodana Cloud configuration for the given project directory.
+func (c UserConfig) CloudFor(projectDir string) qdyaml.Cloud {
+	if p := c.project(projectDir); p != nil && !p.Cloud.IsEmpty() {
+		return p.Cloud
+	}
+	return c.Cloud
+}
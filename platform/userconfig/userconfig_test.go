@@ -0,0 +1,48 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package userconfig
+
+import (
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdyaml"
+	"path/filepath"
+	"testing"
+)
+
+func TestCloudFor(t *testing.T) {
+	root := t.TempDir()
+	c := UserConfig{
+		Cloud: qdyaml.Cloud{Endpoint: "https://qodana.cloud"},
+		Projects: []ProjectConfig{
+			{Path: filepath.Join(root, "work"), Cloud: qdyaml.Cloud{Endpoint: "https://qodana.work.example"}},
+			{Path: filepath.Join(root, "work", "secure"), Cloud: qdyaml.Cloud{Endpoint: "https://qodana.secure.example"}},
+		},
+	}
+	for _, tc := range []struct {
+		dir      string
+		expected string
+	}{
+		{filepath.Join(root, "home"), "https://qodana.cloud"},
+		{filepath.Join(root, "work"), "https://qodana.work.example"},
+		{filepath.Join(root, "work", "app"), "https://qodana.work.example"},
+		{filepath.Join(root, "workspace"), "https://qodana.cloud"},
+		{filepath.Join(root, "work", "secure", "app"), "https://qodana.secure.example"},
+	} {
+		if actual := c.CloudFor(tc.dir).Endpoint; actual != tc.expected {
+			t.Errorf("CloudFor(%s) = %s, expected %s", tc.dir, actual, tc.expected)
+		}
+	}
+}
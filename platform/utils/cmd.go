@@ -18,6 +18,7 @@ package utils
 
 import (
 	bt "bytes"
+	"context"
 	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
@@ -41,13 +42,18 @@ const (
 	QodanaOutOfMemoryExitCode = 137
 	// QodanaEapLicenseExpiredExitCode reports an expired license.
 	QodanaEapLicenseExpiredExitCode = 7
+	// QodanaCancelledExitCode reports a run cancelled with SIGINT or SIGTERM.
+	QodanaCancelledExitCode = 130
 	// QodanaTimeoutExitCodePlaceholder is not a real exit code (it is not obtained from IDE process! and not returned from CLI)
 	QodanaTimeoutExitCodePlaceholder = 1000
 	// Placeholder used to identify the case when the analysis reached timeout
 )
 
-// Bootstrap takes the given command (from CLI or qodana.yaml) and runs it.
-func Bootstrap(command string, project string) {
+// terminationGracePeriod is the time the subprocess has to exit after SIGTERM before it's killed.
+var terminationGracePeriod = 30 * time.Second
+
+// Bootstrap takes the given command (from CLI or qodana.yaml) and runs it, the command is terminated when ctx is done.
+func Bootstrap(ctx context.Context, command string, project string) {
 	if command != "" {
 		var executor string
 		var flag string
@@ -60,7 +66,11 @@ func Bootstrap(command string, project string) {
 			flag = "-c"
 		}
 
-		if res, err := RunCmd(project, executor, flag, "\""+command+"\""); res > 0 || err != nil {
+		res, err := RunCmdContext(ctx, project, executor, flag, "\""+command+"\"")
+		if ctx.Err() != nil {
+			return
+		}
+		if res > 0 || err != nil {
 			log.Printf("Provided bootstrap command finished with error: %d. Exiting...", res)
 			os.Exit(res)
 		}
@@ -69,11 +79,32 @@ func Bootstrap(command string, project string) {
 
 // RunCmd executes subprocess with forwarding of signals, and returns its exit code.
 func RunCmd(cwd string, args ...string) (int, error) {
-	return RunCmdWithTimeout(cwd, os.Stdout, os.Stderr, time.Duration(math.MaxInt64), 1, args...)
+	return RunCmdContext(context.Background(), cwd, args...)
+}
+
+// RunCmdContext is RunCmd terminating the subprocess when ctx is done, see RunCmdUntil.
+func RunCmdContext(ctx context.Context, cwd string, args ...string) (int, error) {
+	return RunCmdUntil(ctx, cwd, os.Stdout, os.Stderr, time.Duration(math.MaxInt64), 1, nil, args...)
 }
 
 // RunCmdWithTimeout executes subprocess with forwarding of signals, and returns its exit code.
 func RunCmdWithTimeout(cwd string, stdout *os.File, stderr *os.File, timeout time.Duration, timeoutExitCode int, args ...string) (int, error) {
+	return RunCmdUntil(context.Background(), cwd, stdout, stderr, timeout, timeoutExitCode, nil, args...)
+}
+
+// RunCmdUntil is RunCmdWithTimeout also terminating the subprocess with timeoutExitCode when stop is closed
+// or the deadline of ctx is reached. When ctx is cancelled, the subprocess is terminated and ctx.Err() is returned
+// with QodanaCancelledExitCode. The subprocess is sent SIGTERM and killed if it doesn't exit in time.
+func RunCmdUntil(
+	ctx context.Context,
+	cwd string,
+	stdout *os.File,
+	stderr *os.File,
+	timeout time.Duration,
+	timeoutExitCode int,
+	stop <-chan struct{},
+	args ...string,
+) (int, error) {
 	log.Debugf("Running command: %v", args)
 	cmd := exec.Command("bash", "-c", strings.Join(args, " ")) // TODO : Viktor told about set -e
 	var stdoutPipe, stderrPipe io.ReadCloser
@@ -112,7 +143,7 @@ func RunCmdWithTimeout(cwd string, stdout *os.File, stderr *os.File, timeout tim
 		go readAndWrite(stdoutPipe, stdout)
 		go readAndWrite(stderrPipe, stderr)
 	}
-	return handleSignals(cmd, waitCh, timeout, timeoutExitCode)
+	return handleSignals(ctx, cmd, waitCh, timeout, timeoutExitCode, stop)
 }
 
 // closePipe closes the pipe
@@ -125,6 +156,11 @@ func closePipe(file *os.File) {
 
 // RunCmdRedirectOutput executes subprocess with forwarding of signals, returns stdout, stderr and exit code.
 func RunCmdRedirectOutput(cwd string, args ...string) (string, string, int, error) {
+	return RunCmdRedirectOutputContext(context.Background(), cwd, args...)
+}
+
+// RunCmdRedirectOutputContext is RunCmdRedirectOutput terminating the subprocess when ctx is done.
+func RunCmdRedirectOutputContext(ctx context.Context, cwd string, args ...string) (string, string, int, error) {
 	outReader, outWriter, err := os.Pipe()
 	if err != nil {
 		return "", "", -1, fmt.Errorf("failed to create stdout pipe: %w", err)
@@ -142,7 +178,7 @@ func RunCmdRedirectOutput(cwd string, args ...string) (string, string, int, erro
 	go copyToChannel(outReader, outChannel)
 	go copyToChannel(errReader, errChannel)
 
-	res, err := RunCmdWithTimeout(cwd, outWriter, errWriter, time.Duration(math.MaxInt64), 1, args...)
+	res, err := RunCmdUntil(ctx, cwd, outWriter, errWriter, time.Duration(math.MaxInt64), 1, nil, args...)
 	closePipes(outWriter, errWriter)
 	stdout := <-outChannel
 	stderr := <-errChannel
@@ -185,7 +221,14 @@ func getCwdPath(cwd string) (string, error) {
 }
 
 // handleSignals handles the signals from the subprocess
-func handleSignals(cmd *exec.Cmd, waitCh <-chan error, timeout time.Duration, timeoutExitCode int) (int, error) {
+func handleSignals(
+	ctx context.Context,
+	cmd *exec.Cmd,
+	waitCh <-chan error,
+	timeout time.Duration,
+	timeoutExitCode int,
+	stop <-chan struct{},
+) (int, error) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan)
 	defer func() {
@@ -202,11 +245,17 @@ func handleSignals(cmd *exec.Cmd, waitCh <-chan error, timeout time.Duration, ti
 				log.Error("Error sending signal: ", sig, err)
 			}
 		case <-timeoutCh:
-			if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
-				log.Fatal("failed to kill process on timeout: ", err)
-			}
-			_, _ = cmd.Process.Wait()
+			terminate(cmd, waitCh)
 			return timeoutExitCode, nil
+		case <-stop:
+			terminate(cmd, waitCh)
+			return timeoutExitCode, nil
+		case <-ctx.Done():
+			terminate(cmd, waitCh)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return timeoutExitCode, nil
+			}
+			return QodanaCancelledExitCode, ctx.Err()
 		case ret := <-waitCh:
 			var exitError *exec.ExitError
 			if errors.As(ret, &exitError) {
@@ -226,6 +275,41 @@ func handleSignals(cmd *exec.Cmd, waitCh <-chan error, timeout time.Duration, ti
 	}
 }
 
+// CommandContext is exec.CommandContext sending SIGTERM to the command when ctx is done,
+// the command is killed if it doesn't exit within the grace period.
+func CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+			// SIGTERM is not supported on Windows
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = terminationGracePeriod
+	return cmd
+}
+
+// terminate sends SIGTERM to the subprocess and waits for it to exit, it's killed after terminationGracePeriod.
+func terminate(cmd *exec.Cmd, waitCh <-chan error) {
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		// SIGTERM is not supported on Windows
+		log.Debugf("Failed to send SIGTERM, killing the process: %v", err)
+		if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			log.Fatal("failed to kill process: ", err)
+		}
+	}
+	select {
+	case <-waitCh:
+	case <-time.After(terminationGracePeriod):
+		log.Warnf("The process didn't exit in %s after SIGTERM, killing it", terminationGracePeriod)
+		if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			log.Fatal("failed to kill process: ", err)
+		}
+		<-waitCh
+	}
+}
+
 func readAndWrite(pipe io.ReadCloser, output *os.File) {
 	buf := make([]byte, 1024)
 	for {
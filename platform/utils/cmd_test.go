@@ -0,0 +1,70 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRunCmdUntil(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the subprocess is stopped with SIGTERM")
+	}
+	stop := make(chan struct{})
+	time.AfterFunc(100*time.Millisecond, func() { close(stop) })
+	start := time.Now()
+	code, err := RunCmdUntil(context.Background(), "", os.Stdout, os.Stderr, time.Minute, QodanaTimeoutExitCodePlaceholder, stop, "sleep", "30")
+	if err != nil || code != QodanaTimeoutExitCodePlaceholder {
+		t.Fatalf("expected the timeout exit code, got %d %v", code, err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the command to be stopped, it ran for %s", elapsed)
+	}
+
+	code, err = RunCmdUntil(context.Background(), "", os.Stdout, os.Stderr, time.Minute, QodanaTimeoutExitCodePlaceholder, nil, "exit", "3")
+	if err != nil || code != 3 {
+		t.Errorf("expected the exit code of the command, got %d %v", code, err)
+	}
+}
+
+func TestRunCmdContext(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the subprocess is stopped with SIGTERM")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	code, err := RunCmdContext(ctx, "", "sleep", "30")
+	if !errors.Is(err, context.Canceled) || code != QodanaCancelledExitCode {
+		t.Fatalf("expected the cancelled exit code, got %d %v", code, err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the command to be stopped, it ran for %s", elapsed)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	code, err = RunCmdUntil(ctx, "", os.Stdout, os.Stderr, time.Minute, QodanaTimeoutExitCodePlaceholder, nil, "sleep", "30")
+	if err != nil || code != QodanaTimeoutExitCodePlaceholder {
+		t.Errorf("expected the timeout exit code at the deadline, got %d %v", code, err)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCpuLimit(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		files    map[string]string
+		expected int
+	}{
+		{name: "no cgroup", expected: 8},
+		{name: "cgroup v2 without quota", files: map[string]string{"cpu.max": "max 100000\n"}, expected: 8},
+		{name: "cgroup v2 quota", files: map[string]string{"cpu.max": "150000 100000\n"}, expected: 2},
+		{name: "cgroup v2 quota above the CPUs", files: map[string]string{"cpu.max": "1600000 100000\n"}, expected: 8},
+		{name: "cgroup v2 small quota", files: map[string]string{"cpu.max": "10000 100000\n"}, expected: 1},
+		{
+			name:     "cgroup v1 quota",
+			files:    map[string]string{"cpu,cpuacct/cpu.cfs_quota_us": "400000\n", "cpu,cpuacct/cpu.cfs_period_us": "100000\n"},
+			expected: 4,
+		},
+		{
+			name:     "cgroup v1 without quota",
+			files:    map[string]string{"cpu/cpu.cfs_quota_us": "-1\n", "cpu/cpu.cfs_period_us": "100000\n"},
+			expected: 8,
+		},
+	} {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				root := t.TempDir()
+				for name, content := range tt.files {
+					path := filepath.Join(root, filepath.FromSlash(name))
+					if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if actual := cpuLimit(root, 8); actual != tt.expected {
+					t.Errorf("cpuLimit() = %d, want %d", actual, tt.expected)
+				}
+			},
+		)
+	}
+}
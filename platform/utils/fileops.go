@@ -17,7 +17,10 @@
 package utils
 
 import (
+	"archive/zip"
+	"fmt"
 	log "github.com/sirupsen/logrus"
+	"io"
 	"os"
 	"path/filepath"
 )
@@ -83,3 +86,62 @@ func CopyDir(src string, dst string) error {
 	}
 	return nil
 }
+
+// ExtractZip unpacks the zip archive read from r into dir, entries pointing outside dir are rejected.
+// The archive is saved to dir first, as zip archives need random access.
+func ExtractZip(r io.Reader, dir string) error {
+	archive := filepath.Join(dir, ".archive.zip")
+	out, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, r)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(archive) }()
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = reader.Close() }()
+	for _, f := range reader.File {
+		target := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if rel, err := filepath.Rel(dir, target); err != nil || !filepath.IsLocal(rel) {
+			return fmt.Errorf("archive entry %s points outside %s", f.Name, dir)
+		}
+		if f.FileInfo().IsDir() {
+			if err = os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = extractZipFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractZipFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode()|0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	return dst.Close()
+}
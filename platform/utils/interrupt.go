@@ -0,0 +1,63 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"sort"
+	"sync"
+)
+
+var (
+	interruptMu       sync.Mutex
+	interruptCleanups = map[int]func(){}
+	interruptNextId   int
+)
+
+// OnInterrupt registers cleanup to run if the CLI is interrupted, the returned function unregisters it.
+func OnInterrupt(cleanup func()) func() {
+	interruptMu.Lock()
+	defer interruptMu.Unlock()
+	id := interruptNextId
+	interruptNextId++
+	interruptCleanups[id] = cleanup
+	return func() {
+		interruptMu.Lock()
+		defer interruptMu.Unlock()
+		delete(interruptCleanups, id)
+	}
+}
+
+// RunInterruptCleanups runs the registered cleanups, the most recently registered first, and returns the exit code:
+// QodanaCancelledExitCode if a run was in progress, i.e. there were cleanups registered, and 0 otherwise.
+func RunInterruptCleanups() int {
+	interruptMu.Lock()
+	ids := make([]int, 0, len(interruptCleanups))
+	for id := range interruptCleanups {
+		ids = append(ids, id)
+	}
+	cleanups := interruptCleanups
+	interruptCleanups = map[int]func(){}
+	interruptMu.Unlock()
+	if len(ids) == 0 {
+		return QodanaSuccessExitCode
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	for _, id := range ids {
+		cleanups[id]()
+	}
+	return QodanaCancelledExitCode
+}
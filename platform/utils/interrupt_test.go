@@ -0,0 +1,43 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRunInterruptCleanups(t *testing.T) {
+	if code := RunInterruptCleanups(); code != QodanaSuccessExitCode {
+		t.Fatalf("expected %d without a run in progress, got %d", QodanaSuccessExitCode, code)
+	}
+	var ran []string
+	OnInterrupt(func() { ran = append(ran, "outcome") })
+	unregister := OnInterrupt(func() { ran = append(ran, "finished") })
+	OnInterrupt(func() { ran = append(ran, "git") })
+	unregister()
+
+	if code := RunInterruptCleanups(); code != QodanaCancelledExitCode {
+		t.Fatalf("expected %d, got %d", QodanaCancelledExitCode, code)
+	}
+	if !reflect.DeepEqual(ran, []string{"git", "outcome"}) {
+		t.Fatalf("unexpected cleanups %v", ran)
+	}
+	if code := RunInterruptCleanups(); code != QodanaSuccessExitCode {
+		t.Fatalf("expected the cleanups to run once, got %d", code)
+	}
+}
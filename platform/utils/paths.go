@@ -0,0 +1,90 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"strings"
+)
+
+// The prefixes of the Windows paths: the extended-length ones lift the MAX_PATH (260 characters) limit, Go adds them
+// to the long paths itself, the UNC ones point to the network shares, e.g. \\server\share\project.
+const (
+	longPathPrefix    = `\\?\`
+	longUncPathPrefix = `\\?\UNC\`
+	uncPathPrefix     = `\\`
+)
+
+// NormalizeWindowsPath returns the path without the extended-length prefix, the form docker and the report URIs
+// expect: \\?\C:\project becomes C:\project and \\?\UNC\server\share becomes \\server\share.
+func NormalizeWindowsPath(path string) string {
+	if strings.HasPrefix(path, longUncPathPrefix) {
+		return uncPathPrefix + path[len(longUncPathPrefix):]
+	}
+	return strings.TrimPrefix(path, longPathPrefix)
+}
+
+// IsUncPath reports whether the path points to a network share, with either backslashes or slashes.
+func IsUncPath(path string) bool {
+	path = NormalizeWindowsPath(path)
+	if len(path) < 3 || !isSeparator(path[0]) || !isSeparator(path[1]) {
+		return false
+	}
+	return !isSeparator(path[2]) && path[2] != '?' && path[2] != '.'
+}
+
+// HasDriveLetter reports whether the path starts with a Windows drive, e.g. C: or \\?\c:, regardless of the current OS.
+func HasDriveLetter(path string) bool {
+	path = NormalizeWindowsPath(path)
+	if len(path) < 2 || path[1] != ':' {
+		return false
+	}
+	letter := path[0] | 0x20
+	return letter >= 'a' && letter <= 'z'
+}
+
+func isSeparator(c byte) bool {
+	return c == '\\' || c == '/'
+}
+
+// quoteWindowsArg quotes the argument for cmd /C and CommandLineToArgvW: the arguments with spaces, tabs, quotes or
+// the cmd.exe operators are quoted, the quotes inside are escaped, and so are the backslashes before them and
+// before the closing quote, e.g. a directory ending with a backslash.
+func quoteWindowsArg(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"&|<>^()") {
+		return s
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	backslashes := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			backslashes++
+		case '"':
+			b.WriteString(strings.Repeat(`\`, 2*backslashes+1))
+			b.WriteByte(c)
+			backslashes = 0
+		default:
+			b.WriteString(strings.Repeat(`\`, backslashes))
+			b.WriteByte(c)
+			backslashes = 0
+		}
+	}
+	b.WriteString(strings.Repeat(`\`, 2*backslashes))
+	b.WriteByte('"')
+	return b.String()
+}
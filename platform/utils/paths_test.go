@@ -0,0 +1,88 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// longDir is a Windows directory longer than MAX_PATH.
+var longDir = `C:\work\` + strings.Repeat(`very long directory name\`, 12) + "project"
+
+func TestLongDirExceedsMaxPath(t *testing.T) {
+	if len(longDir) <= 260 {
+		t.Fatalf("the test directory is %d characters long, want more than 260", len(longDir))
+	}
+}
+
+func TestNormalizeWindowsPath(t *testing.T) {
+	for path, expected := range map[string]string{
+		`\\?\` + longDir:                      longDir,
+		`\\?\UNC\server\share\` + longDir[3:]: `\\server\share\` + longDir[3:],
+		`\\server\share\project`:              `\\server\share\project`,
+		longDir:                               longDir,
+		"/home/user/project":                  "/home/user/project",
+	} {
+		if actual := NormalizeWindowsPath(path); actual != expected {
+			t.Errorf("NormalizeWindowsPath(%q) = %q, want %q", path, actual, expected)
+		}
+	}
+}
+
+func TestWindowsPathKinds(t *testing.T) {
+	for _, tc := range []struct {
+		path           string
+		unc            bool
+		hasDriveLetter bool
+	}{
+		{longDir, false, true},
+		{`\\?\` + longDir, false, true},
+		{`c:/project`, false, true},
+		{`\\server\share\` + longDir[3:], true, false},
+		{`\\?\UNC\server\share\project`, true, false},
+		{"//server/share/project", true, false},
+		{`\\.\pipe\docker_engine`, false, false},
+		{"/home/user/project", false, false},
+		{"project", false, false},
+	} {
+		if actual := IsUncPath(tc.path); actual != tc.unc {
+			t.Errorf("IsUncPath(%q) = %v, want %v", tc.path, actual, tc.unc)
+		}
+		if actual := HasDriveLetter(tc.path); actual != tc.hasDriveLetter {
+			t.Errorf("HasDriveLetter(%q) = %v, want %v", tc.path, actual, tc.hasDriveLetter)
+		}
+	}
+}
+
+func TestQuoteWindowsArg(t *testing.T) {
+	for arg, expected := range map[string]string{
+		"":                                "\"\"",
+		`C:\qodana\java.exe`:              `C:\qodana\java.exe`,
+		`C:\Program Files\java.exe`:       `"C:\Program Files\java.exe"`,
+		`C:\my project\`:                  `"C:\my project\\"`,
+		`C:\a&b\project`:                  `"C:\a&b\project"`,
+		`say "hi"`:                        `"say \"hi\""`,
+		`C:\dir\"quoted"`:                 `"C:\dir\\\"quoted\""`,
+		`\\?\` + longDir:                  `"\\?\` + longDir + `"`,
+		`\\server\share\project (backup)`: `"\\server\share\project (backup)"`,
+	} {
+		if actual := quoteWindowsArg(arg); actual != expected {
+			t.Errorf("quoteWindowsArg(%q) = %q, want %q", arg, actual, expected)
+		}
+	}
+}
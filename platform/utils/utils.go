@@ -17,9 +17,11 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"github.com/JetBrains/qodana-cli/v2024/platform/msg"
 	"github.com/JetBrains/qodana-cli/v2024/platform/qdenv"
+	"github.com/JetBrains/qodana-cli/v2024/platform/qdlogs"
 	"github.com/pterm/pterm"
 	"github.com/shirou/gopsutil/v3/process"
 	"io"
@@ -108,14 +110,13 @@ func QuoteIfSpace(s string) string {
 	}
 }
 
-// QuoteForWindows wraps in '"' if '`s`' contains space on windows.
+// QuoteForWindows quotes and escapes '`s`' for the cmd /C command line on windows, see quoteWindowsArg.
 func QuoteForWindows(s string) string {
 	if //goland:noinspection GoBoolExpressions
-	strings.Contains(s, " ") && runtime.GOOS == "windows" {
-		return "\"" + s + "\""
-	} else {
-		return s
+	runtime.GOOS == "windows" {
+		return quoteWindowsArg(s)
 	}
+	return s
 }
 
 func GetJavaExecutablePath() (string, error) {
@@ -158,21 +159,22 @@ func GetJavaExecutablePath() (string, error) {
 	return javaExecutablePath, nil
 }
 
-// LaunchAndLog launches a process and logs its output.
-func LaunchAndLog(logDir string, executable string, args ...string) (string, string, int, error) {
-	stdout, stderr, ret, err := RunCmdRedirectOutput("", args...)
+// LaunchAndLog launches a process and logs its output to the rotated logs in logDir, the process is terminated
+// when ctx is done.
+func LaunchAndLog(ctx context.Context, logDir string, executable string, args ...string) (string, string, int, error) {
+	stdout, stderr, ret, err := RunCmdRedirectOutputContext(ctx, "", args...)
 	if err != nil {
 		log.Error(fmt.Errorf("failed to run %s: %w", executable, err))
 		return "", "", ret, err
 	}
-	fmt.Println(stdout)
+	pterm.Println(stdout)
 	if stderr != "" {
 		_, _ = fmt.Fprintln(os.Stderr, stderr)
 	}
-	if err := AppendToFile(filepath.Join(logDir, executable+"-out.log"), stdout); err != nil {
+	if err := qdlogs.Append(filepath.Join(logDir, executable+"-out.log"), stdout); err != nil {
 		log.Error(err)
 	}
-	if err := AppendToFile(filepath.Join(logDir, executable+"-err.log"), stderr); err != nil {
+	if err := qdlogs.Append(filepath.Join(logDir, executable+"-err.log"), stderr); err != nil {
 		log.Error(err)
 	}
 	return stdout, stderr, ret, nil
@@ -340,3 +342,17 @@ func OpenBrowser(url string) error {
 	args = append(args, url)
 	return exec.Command(cmd, args...).Start()
 }
+
+// FormatSize formats the size in bytes with a binary unit, e.g. 1.5 GiB.
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
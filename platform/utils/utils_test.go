@@ -0,0 +1,31 @@
+/*
+ * Copyright 2021-2024 JetBrains s.r.o.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import "testing"
+
+func TestFormatSize(t *testing.T) {
+	for size, expected := range map[int64]string{
+		512:                    "512 B",
+		1536:                   "1.5 KiB",
+		3 * 1024 * 1024 * 1024: "3.0 GiB",
+	} {
+		if actual := FormatSize(size); actual != expected {
+			t.Errorf("FormatSize(%d) = %s, want %s", size, actual, expected)
+		}
+	}
+}